@@ -0,0 +1,155 @@
+package macho
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/blacktop/go-macho/pkg/demangle"
+)
+
+type breakpadFunc struct {
+	addr uint64
+	size uint64
+	name string
+	fn   *funcRange // DWARF subprogram (or nil)
+}
+
+// breakpadName returns the demangled symbol name without the leading underscore
+func breakpadName(name string) string {
+	if dn := demangle.Filter(name); dn != name {
+		return dn
+	}
+	return strings.TrimPrefix(name, "_")
+}
+
+// WriteBreakpadSym writes the Google Breakpad/Crashpad symbol file of the MachO to w; FUNC and LINE records
+// are generated from the DWARF debug info (if any) and LC_FUNCTION_STARTS, and PUBLIC records from the symbol table
+func (f *File) WriteBreakpadSym(w io.Writer, name string) error {
+	base := f.GetBaseAddress()
+
+	var idx *lineIndex
+	if f.Section("__DWARF", "__debug_info") != nil {
+		var err error
+		if idx, err = f.getLineIndex(); err != nil {
+			return err
+		}
+	}
+
+	var funcs []breakpadFunc
+	sortFuncs := func() {
+		sort.SliceStable(funcs, func(i, j int) bool { return funcs[i].addr < funcs[j].addr })
+	}
+	// covered returns true if addr is in one of the (sorted) funcs
+	covered := func(addr uint64) bool {
+		i := sort.Search(len(funcs), func(i int) bool { return funcs[i].addr > addr })
+		return i > 0 && addr < funcs[i-1].addr+funcs[i-1].size
+	}
+	if idx != nil {
+		for i, fn := range idx.funcs {
+			if fn.depth == 0 && fn.high > fn.low {
+				funcs = append(funcs, breakpadFunc{addr: fn.low, size: fn.high - fn.low, name: fn.name, fn: &idx.funcs[i]})
+			}
+		}
+		sortFuncs()
+	}
+	var starts []breakpadFunc
+	for _, fn := range f.GetFunctions() {
+		if fn.EndAddr <= fn.StartAddr || covered(fn.StartAddr) {
+			continue
+		}
+		name := fn.Name
+		if name == "" {
+			name = f.functionName(fn.StartAddr)
+		}
+		if name != "" {
+			starts = append(starts, breakpadFunc{addr: fn.StartAddr, size: fn.EndAddr - fn.StartAddr, name: breakpadName(name)})
+		}
+	}
+	funcs = append(funcs, starts...)
+	sortFuncs()
+
+	publics := make(map[uint64]string)
+	if f.Symtab != nil {
+		for _, sym := range f.symbols() {
+			if sym.Name == "" || sym.Type.IsDebugSym() || !sym.Type.IsDefinedInSection() || sym.Value < base {
+				continue
+			}
+			if _, dup := publics[sym.Value]; dup || covered(sym.Value) {
+				continue
+			}
+			publics[sym.Value] = breakpadName(sym.Name)
+		}
+	}
+	var paddrs []uint64
+	for addr := range publics {
+		paddrs = append(paddrs, addr)
+	}
+	sort.Slice(paddrs, func(i, j int) bool { return paddrs[i] < paddrs[j] })
+
+	bw := bufio.NewWriter(w)
+
+	var id string
+	if u := f.UUID(); u != nil {
+		id = strings.ToUpper(fmt.Sprintf("%x", u.UUID[:]))
+	} else {
+		id = strings.Repeat("0", 32)
+	}
+	fmt.Fprintf(bw, "MODULE mac %s %s0 %s\n", f.Architecture(), id, name)
+	if f.UUID() != nil {
+		fmt.Fprintf(bw, "INFO CODE_ID %s\n", id)
+	}
+
+	files := make(map[string]int)
+	if idx != nil {
+		var names []string
+		for _, row := range idx.rows {
+			if _, ok := files[row.file]; !ok && row.file != "" {
+				files[row.file] = 0
+				names = append(names, row.file)
+			}
+		}
+		sort.Strings(names)
+		for i, file := range names {
+			files[file] = i
+			fmt.Fprintf(bw, "FILE %d %s\n", i, file)
+		}
+	}
+
+	for _, fn := range funcs {
+		fmt.Fprintf(bw, "FUNC %x %x 0 %s\n", fn.addr-base, fn.size, fn.name)
+		if fn.fn == nil {
+			continue
+		}
+		end := fn.addr + fn.size
+		i := sort.Search(len(idx.rows), func(i int) bool { return idx.rows[i].addr > fn.addr })
+		if i > 0 && !idx.rows[i-1].endSeq {
+			i-- // row covering the function start
+		}
+		for ; i < len(idx.rows) && idx.rows[i].addr < end; i++ {
+			row := idx.rows[i]
+			if row.endSeq || i+1 == len(idx.rows) {
+				continue
+			}
+			start, stop := row.addr, idx.rows[i+1].addr
+			if start < fn.addr {
+				start = fn.addr
+			}
+			if stop > end {
+				stop = end
+			}
+			if stop <= start {
+				continue
+			}
+			fmt.Fprintf(bw, "%x %x %d %d\n", start-base, stop-start, row.line, files[row.file])
+		}
+	}
+
+	for _, addr := range paddrs {
+		fmt.Fprintf(bw, "PUBLIC %x 0 %s\n", addr-base, publics[addr])
+	}
+
+	return bw.Flush()
+}
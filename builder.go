@@ -0,0 +1,525 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// BuilderSection is a section (and its contents) emitted by a Builder
+type BuilderSection struct {
+	Seg   string
+	Name  string
+	Data  []byte
+	Size  uint64 // size of a zerofill section (otherwise len(Data) is used)
+	Align uint32 // power of 2
+	Flags types.SectionFlag
+
+	// assigned by Build
+	Addr   uint64
+	Offset uint32
+}
+
+func (s *BuilderSection) isZerofill() bool {
+	return s.Flags.IsZerofill()
+}
+
+func (s *BuilderSection) size() uint64 {
+	if s.isZerofill() {
+		return s.Size
+	}
+	return uint64(len(s.Data))
+}
+
+// BuilderSegment is a segment emitted by a Builder
+type BuilderSegment struct {
+	Name     string
+	Maxprot  types.VmProtection
+	Prot     types.VmProtection
+	Sections []*BuilderSection
+
+	// assigned by Build
+	Addr   uint64
+	Memsz  uint64
+	Offset uint64
+	Filesz uint64
+}
+
+// BuilderSymbol is a symbol defined at an offset into a section
+type BuilderSymbol struct {
+	Name     string
+	Seg      string
+	Sect     string
+	Offset   uint64
+	External bool
+}
+
+// Builder constructs a 64-bit MH_EXECUTE or MH_DYLIB MachO from scratch
+type Builder struct {
+	Type   types.HeaderFileType
+	CPU    types.CPU
+	SubCPU types.CPUSubtype
+
+	InstallName    string        // LC_ID_DYLIB name (MH_DYLIB only)
+	CurrentVersion types.Version // LC_ID_DYLIB current version
+	CompatVersion  types.Version // LC_ID_DYLIB compatibility version
+	Dylinker       string        // LC_LOAD_DYLINKER path (MH_EXECUTE only)
+
+	Platform types.Platform // LC_BUILD_VERSION is only emitted if set
+	MinOS    types.Version
+	SDK      types.Version
+
+	HeaderPad uint32 // extra space after the load commands (for later load command edits)
+
+	segments []*BuilderSegment
+	symbols  []BuilderSymbol
+	dylibs   []string
+	entry    *BuilderSymbol
+}
+
+// NewBuilder returns a Builder for a MachO of type typ (MH_EXECUTE or MH_DYLIB)
+func NewBuilder(typ types.HeaderFileType, cpu types.CPU, subcpu types.CPUSubtype) *Builder {
+	return &Builder{
+		Type:     typ,
+		CPU:      cpu,
+		SubCPU:   subcpu,
+		Dylinker: "/usr/lib/dyld",
+	}
+}
+
+// Segment returns the segment named name or nil
+func (b *Builder) Segment(name string) *BuilderSegment {
+	for _, seg := range b.segments {
+		if seg.Name == name {
+			return seg
+		}
+	}
+	return nil
+}
+
+// AddSegment adds a segment (segments are laid out in the order they are added, after __TEXT)
+func (b *Builder) AddSegment(name string, maxprot, prot types.VmProtection) *BuilderSegment {
+	if seg := b.Segment(name); seg != nil {
+		seg.Maxprot, seg.Prot = maxprot, prot
+		return seg
+	}
+	seg := &BuilderSegment{Name: name, Maxprot: maxprot, Prot: prot}
+	b.segments = append(b.segments, seg)
+	return seg
+}
+
+// AddSection adds a section with contents data to segment seg (creating the segment if needed)
+func (b *Builder) AddSection(seg, name string, data []byte, flags types.SectionFlag) *BuilderSection {
+	sg := b.Segment(seg)
+	if sg == nil {
+		switch seg {
+		case "__TEXT":
+			sg = b.AddSegment(seg, 5, 5) // r-x
+		default:
+			sg = b.AddSegment(seg, 3, 3) // rw-
+		}
+	}
+	sect := &BuilderSection{Seg: seg, Name: name, Data: data, Flags: flags}
+	if flags.IsPureInstructions() || flags.IsSomeInstructions() {
+		sect.Align = 2
+	}
+	sg.Sections = append(sg.Sections, sect)
+	return sect
+}
+
+// AddSymbol adds a symbol at offset into section seg,sect to the symbol table
+func (b *Builder) AddSymbol(name, seg, sect string, offset uint64, external bool) {
+	b.symbols = append(b.symbols, BuilderSymbol{
+		Name:     name,
+		Seg:      seg,
+		Sect:     sect,
+		Offset:   offset,
+		External: external,
+	})
+}
+
+// AddDylib adds a LC_LOAD_DYLIB dependency
+func (b *Builder) AddDylib(path string) {
+	b.dylibs = append(b.dylibs, path)
+}
+
+// SetEntryPoint sets the LC_MAIN entry point to offset into section seg,sect (MH_EXECUTE only)
+func (b *Builder) SetEntryPoint(seg, sect string, offset uint64) {
+	b.entry = &BuilderSymbol{Seg: seg, Sect: sect, Offset: offset}
+}
+
+// builderSection returns the section seg,sect of segs and its (1-based) section ordinal
+func builderSection(segs []*BuilderSegment, seg, sect string) (*BuilderSection, int) {
+	idx := 0
+	for _, sg := range segs {
+		for _, s := range sg.Sections {
+			idx++
+			if s.Seg == seg && s.Name == sect {
+				return s, idx
+			}
+		}
+	}
+	return nil, 0
+}
+
+func (b *Builder) pageSize() uint64 {
+	if b.CPU == types.CPUArm64 {
+		return 0x4000
+	}
+	return 0x1000
+}
+
+func builderName(name string) (out [16]byte) {
+	copy(out[:], name)
+	return out
+}
+
+// Build lays out and emits the MachO
+func (b *Builder) Build() ([]byte, error) {
+	if b.CPU&types.CPUArch64 == 0 {
+		return nil, fmt.Errorf("builder only supports 64-bit CPUs (got %s)", b.CPU)
+	}
+	switch b.Type {
+	case types.MH_EXECUTE:
+		if b.entry == nil {
+			return nil, fmt.Errorf("MH_EXECUTE requires an entry point")
+		}
+	case types.MH_DYLIB:
+		if b.InstallName == "" {
+			return nil, fmt.Errorf("MH_DYLIB requires an install name")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported file type %s", b.Type)
+	}
+
+	pageSize := b.pageSize()
+	bo := binary.LittleEndian
+
+	// __TEXT always comes first as it maps the header and load commands
+	text := b.Segment("__TEXT")
+	if text == nil {
+		text = &BuilderSegment{Name: "__TEXT", Maxprot: 5, Prot: 5}
+	}
+	segs := []*BuilderSegment{text} // section ordinals follow this order (b.segments is left as is)
+	for _, seg := range b.segments {
+		if seg.Name == "__LINKEDIT" || seg.Name == "__PAGEZERO" {
+			return nil, fmt.Errorf("segment %s is created by the builder", seg.Name)
+		}
+		if seg != text {
+			segs = append(segs, seg)
+		}
+	}
+
+	var nsects uint32
+	for _, seg := range segs {
+		nsects += uint32(len(seg.Sections))
+	}
+
+	// load commands size
+	segCmdSize := uint32(binary.Size(types.Segment64{}))
+	sectSize := uint32(binary.Size(types.Section64{}))
+	cstrSize := func(hdr int, s string) uint32 {
+		return uint32(pageAlign(uint64(hdr+len(s)+1), 8))
+	}
+	ncmds := uint32(len(segs) + 1) // + __LINKEDIT
+	sizeofcmds := uint32(len(segs)+1)*segCmdSize + nsects*sectSize
+	if b.Type == types.MH_EXECUTE {
+		ncmds += 3 // __PAGEZERO, LC_LOAD_DYLINKER, LC_MAIN
+		sizeofcmds += segCmdSize + cstrSize(binary.Size(types.DylinkerCmd{}), b.Dylinker) + uint32(binary.Size(types.EntryPointCmd{}))
+	} else {
+		ncmds++ // LC_ID_DYLIB
+		sizeofcmds += cstrSize(binary.Size(types.DylibCmd{}), b.InstallName)
+	}
+	ncmds += 2 // LC_SYMTAB, LC_DYSYMTAB
+	sizeofcmds += uint32(binary.Size(types.SymtabCmd{}) + binary.Size(types.DysymtabCmd{}))
+	if b.Platform != 0 {
+		ncmds++
+		sizeofcmds += uint32(binary.Size(types.BuildVersionCmd{}))
+	}
+	for _, dylib := range b.dylibs {
+		ncmds++
+		sizeofcmds += cstrSize(binary.Size(types.DylibCmd{}), dylib)
+	}
+
+	// segment and section layout
+	var baseAddr uint64
+	if b.Type == types.MH_EXECUTE {
+		baseAddr = 0x100000000
+	}
+	addr := baseAddr
+	off := uint64(types.FileHeaderSize64) + uint64(sizeofcmds) + uint64(b.HeaderPad)
+	for _, seg := range segs {
+		seg.Addr = addr
+		start := off
+		if seg == text {
+			start = 0
+		}
+		// file backed sections first, then zerofill
+		for _, sect := range seg.Sections {
+			if sect.isZerofill() {
+				continue
+			}
+			off = pageAlign(off, 1<<sect.Align)
+			sect.Offset = uint32(off)
+			sect.Addr = seg.Addr + off - start
+			off += sect.size()
+		}
+		seg.Filesz = pageAlign(off-start, pageSize)
+		vmoff := off - start
+		for _, sect := range seg.Sections {
+			if !sect.isZerofill() {
+				continue
+			}
+			vmoff = pageAlign(vmoff, 1<<sect.Align)
+			sect.Offset = 0
+			sect.Addr = seg.Addr + vmoff
+			vmoff += sect.size()
+		}
+		seg.Memsz = pageAlign(vmoff, pageSize)
+		if seg.Memsz == 0 {
+			seg.Memsz = pageSize
+		}
+		seg.Offset = start
+		off = start + seg.Filesz
+		if seg.Filesz == 0 {
+			seg.Offset = 0
+		}
+		addr += seg.Memsz
+	}
+	off = pageAlign(off, pageSize)
+
+	// symbol table (locals, then external symbols sorted by name)
+	syms := make([]BuilderSymbol, len(b.symbols))
+	copy(syms, b.symbols)
+	sort.SliceStable(syms, func(i, j int) bool {
+		if syms[i].External != syms[j].External {
+			return !syms[i].External
+		}
+		if syms[i].External {
+			return syms[i].Name < syms[j].Name
+		}
+		return false
+	})
+	var nlocal uint32
+	var strtab bytes.Buffer
+	strtab.WriteString(" \x00")
+	var symtab bytes.Buffer
+	for _, sym := range syms {
+		sect, idx := builderSection(segs, sym.Seg, sym.Sect)
+		if sect == nil {
+			return nil, fmt.Errorf("symbol %s: section %s.%s not found", sym.Name, sym.Seg, sym.Sect)
+		}
+		n := types.Nlist64{
+			Nlist: types.Nlist{
+				Name: uint32(strtab.Len()),
+				Type: types.N_SECT,
+				Sect: uint8(idx),
+			},
+			Value: sect.Addr + sym.Offset,
+		}
+		if sym.External {
+			n.Type |= types.N_EXT
+		} else {
+			nlocal++
+		}
+		strtab.WriteString(sym.Name + "\x00")
+		if err := binary.Write(&symtab, bo, n); err != nil {
+			return nil, fmt.Errorf("failed to write symbol %s: %v", sym.Name, err)
+		}
+	}
+	for strtab.Len()%8 != 0 {
+		strtab.WriteByte(0)
+	}
+
+	linkedit := &BuilderSegment{
+		Name:    "__LINKEDIT",
+		Maxprot: 1,
+		Prot:    1,
+		Addr:    addr,
+		Offset:  off,
+		Filesz:  uint64(symtab.Len() + strtab.Len()),
+	}
+	linkedit.Memsz = pageAlign(linkedit.Filesz, pageSize)
+	if linkedit.Memsz == 0 {
+		linkedit.Memsz = pageSize
+	}
+
+	// load commands
+	var cmds bytes.Buffer
+	writeSeg := func(seg *BuilderSegment) error {
+		if err := binary.Write(&cmds, bo, types.Segment64{
+			LoadCmd: types.LC_SEGMENT_64,
+			Len:     segCmdSize + uint32(len(seg.Sections))*sectSize,
+			Name:    builderName(seg.Name),
+			Addr:    seg.Addr,
+			Memsz:   seg.Memsz,
+			Offset:  seg.Offset,
+			Filesz:  seg.Filesz,
+			Maxprot: seg.Maxprot,
+			Prot:    seg.Prot,
+			Nsect:   uint32(len(seg.Sections)),
+		}); err != nil {
+			return fmt.Errorf("failed to write segment %s: %v", seg.Name, err)
+		}
+		for _, sect := range seg.Sections {
+			if err := binary.Write(&cmds, bo, types.Section64{
+				Name:   builderName(sect.Name),
+				Seg:    builderName(sect.Seg),
+				Addr:   sect.Addr,
+				Size:   sect.size(),
+				Offset: sect.Offset,
+				Align:  sect.Align,
+				Flags:  sect.Flags,
+			}); err != nil {
+				return fmt.Errorf("failed to write section %s.%s: %v", sect.Seg, sect.Name, err)
+			}
+		}
+		return nil
+	}
+	writeDylib := func(cmd types.LoadCmd, name string, cur, compat types.Version) error {
+		hdr := types.DylibCmd{
+			LoadCmd:        cmd,
+			Len:            cstrSize(binary.Size(types.DylibCmd{}), name),
+			NameOffset:     uint32(binary.Size(types.DylibCmd{})),
+			Timestamp:      2,
+			CurrentVersion: cur,
+			CompatVersion:  compat,
+		}
+		start := cmds.Len()
+		if err := binary.Write(&cmds, bo, hdr); err != nil {
+			return fmt.Errorf("failed to write %s %s: %v", cmd, name, err)
+		}
+		cmds.WriteString(name + "\x00")
+		for (cmds.Len()-start)%8 != 0 {
+			cmds.WriteByte(0)
+		}
+		return nil
+	}
+
+	var err error
+	if b.Type == types.MH_EXECUTE {
+		err = writeSeg(&BuilderSegment{Name: "__PAGEZERO", Memsz: baseAddr})
+	}
+	for _, seg := range segs {
+		if err == nil {
+			err = writeSeg(seg)
+		}
+	}
+	if err == nil {
+		err = writeSeg(linkedit)
+	}
+	if err == nil && b.Type == types.MH_DYLIB {
+		err = writeDylib(types.LC_ID_DYLIB, b.InstallName, b.CurrentVersion, b.CompatVersion)
+	}
+	if err == nil && b.Type == types.MH_EXECUTE {
+		err = binary.Write(&cmds, bo, types.DylinkerCmd{
+			LoadCmd:    types.LC_LOAD_DYLINKER,
+			Len:        cstrSize(binary.Size(types.DylinkerCmd{}), b.Dylinker),
+			NameOffset: uint32(binary.Size(types.DylinkerCmd{})),
+		})
+		start := cmds.Len() - binary.Size(types.DylinkerCmd{})
+		cmds.WriteString(b.Dylinker + "\x00")
+		for (cmds.Len()-start)%8 != 0 {
+			cmds.WriteByte(0)
+		}
+	}
+	if err == nil {
+		err = binary.Write(&cmds, bo, types.SymtabCmd{
+			LoadCmd: types.LC_SYMTAB,
+			Len:     uint32(binary.Size(types.SymtabCmd{})),
+			Symoff:  uint32(linkedit.Offset),
+			Nsyms:   uint32(len(syms)),
+			Stroff:  uint32(linkedit.Offset) + uint32(symtab.Len()),
+			Strsize: uint32(strtab.Len()),
+		})
+	}
+	if err == nil {
+		err = binary.Write(&cmds, bo, types.DysymtabCmd{
+			LoadCmd:    types.LC_DYSYMTAB,
+			Len:        uint32(binary.Size(types.DysymtabCmd{})),
+			Nlocalsym:  nlocal,
+			Iextdefsym: nlocal,
+			Nextdefsym: uint32(len(syms)) - nlocal,
+			Iundefsym:  uint32(len(syms)),
+		})
+	}
+	if err == nil && b.Platform != 0 {
+		err = binary.Write(&cmds, bo, types.BuildVersionCmd{
+			LoadCmd:  types.LC_BUILD_VERSION,
+			Len:      uint32(binary.Size(types.BuildVersionCmd{})),
+			Platform: b.Platform,
+			Minos:    b.MinOS,
+			Sdk:      b.SDK,
+		})
+	}
+	for _, dylib := range b.dylibs {
+		if err == nil {
+			err = writeDylib(types.LC_LOAD_DYLIB, dylib, 0x10000, 0x10000)
+		}
+	}
+	if err == nil && b.Type == types.MH_EXECUTE {
+		sect, _ := builderSection(segs, b.entry.Seg, b.entry.Sect)
+		if sect == nil || sect.isZerofill() {
+			return nil, fmt.Errorf("entry point section %s.%s not found", b.entry.Seg, b.entry.Sect)
+		}
+		err = binary.Write(&cmds, bo, types.EntryPointCmd{
+			LoadCmd:     types.LC_MAIN,
+			Len:         uint32(binary.Size(types.EntryPointCmd{})),
+			EntryOffset: uint64(sect.Offset) + b.entry.Offset,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write load commands: %v", err)
+	}
+	if uint32(cmds.Len()) != sizeofcmds {
+		return nil, fmt.Errorf("load commands size mismatch: wrote %#x, expected %#x", cmds.Len(), sizeofcmds)
+	}
+
+	// header
+	flags := types.NoUndefs | types.DyldLink | types.TwoLevel
+	if b.Type == types.MH_EXECUTE {
+		flags |= types.PIE
+	}
+	hdr := types.FileHeader{
+		Magic:        types.Magic64,
+		CPU:          b.CPU,
+		SubCPU:       b.SubCPU,
+		Type:         b.Type,
+		NCommands:    ncmds,
+		SizeCommands: sizeofcmds,
+		Flags:        flags,
+	}
+
+	out := make([]byte, linkedit.Offset+linkedit.Filesz)
+	var hbuf bytes.Buffer
+	if err := hdr.Write(&hbuf, bo); err != nil {
+		return nil, err
+	}
+	copy(out, hbuf.Bytes())
+	copy(out[types.FileHeaderSize64:], cmds.Bytes())
+	for _, seg := range segs {
+		for _, sect := range seg.Sections {
+			if !sect.isZerofill() {
+				copy(out[sect.Offset:], sect.Data)
+			}
+		}
+	}
+	copy(out[linkedit.Offset:], symtab.Bytes())
+	copy(out[linkedit.Offset+uint64(symtab.Len()):], strtab.Bytes())
+
+	return out, nil
+}
+
+// WriteTo writes the built MachO to w
+func (b *Builder) WriteTo(w io.Writer) (int64, error) {
+	dat, err := b.Build()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(dat)
+	return int64(n), err
+}
@@ -0,0 +1,415 @@
+// Command gomacho is a small Mach-O inspection tool built on the go-macho package.
+//
+// Usage:
+//
+//	gomacho <command> [flags] <macho>
+//
+// The commands are:
+//
+//	info     print the header and a summary of the load commands
+//	lc       print the load commands (like `otool -l`)
+//	symbols  print the symbol table
+//	exports  print the exported symbols
+//	objc     print the Objective-C classes, categories and protocols
+//	sign     print (or verify) the code signature
+//	deps     print the dylib dependency tree
+//	lipo     print, extract (thin) or create universal binaries
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/blacktop/go-macho"
+	"github.com/blacktop/go-macho/pkg/deps"
+	"github.com/blacktop/go-macho/types"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, w io.Writer) error
+}
+
+var commands = []command{
+	{"info", "print the header and a summary of the load commands", runInfo},
+	{"lc", "print the load commands (like `otool -l`)", runLoadCommands},
+	{"symbols", "print the symbol table", runSymbols},
+	{"exports", "print the exported symbols", runExports},
+	{"objc", "print the Objective-C classes, categories and protocols", runObjC},
+	{"sign", "print (or verify) the code signature", runSign},
+	{"deps", "print the dylib dependency tree", runDeps},
+	{"lipo", "print, extract (thin) or create universal binaries", runLipo},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: gomacho <command> [flags] <macho>\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+// run runs the gomacho command line args writing the output to w
+func run(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		usage(w)
+		return fmt.Errorf("missing command")
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], w)
+		}
+	}
+	usage(w)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func main() {
+	macho.SetDiagnosticHandler(func(d macho.Diagnostic) {
+		fmt.Fprintf(os.Stderr, "gomacho: warning: %s\n", d)
+	})
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "gomacho: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// newFlagSet returns the flag set of a command with the common -arch flag
+func newFlagSet(name string, w io.Writer) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(w)
+	arch := fs.String("arch", "", "only use the `arch` slice of a universal binary (i.e. arm64e)")
+	return fs, arch
+}
+
+// parseFile parses the command flags and returns the single MachO path argument
+func parseFile(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("%s: expected a single MachO path", fs.Name())
+	}
+	return fs.Arg(0), nil
+}
+
+// forEach calls fn with each slice of the (universal) MachO, printing a header per slice of universal binaries
+func forEach(w io.Writer, path, arch string, config macho.FileConfig, fn func(m *macho.File) error) error {
+	slices, closer, err := openSlices(path, arch, config)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	for _, s := range slices {
+		if s.fat {
+			fmt.Fprintf(w, "%s (for architecture %s):\n", path, s.arch)
+		}
+		if err := fn(s.File); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runInfo(args []string, w io.Writer) error {
+	fs, arch := newFlagSet("info", w)
+	asJSON := fs.Bool("json", false, "print as JSON")
+	path, err := parseFile(fs, args)
+	if err != nil {
+		return err
+	}
+	return forEach(w, path, *arch, macho.FileConfig{}, func(m *macho.File) error {
+		if *asJSON {
+			dat, err := json.MarshalIndent(m, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal MachO: %v", err)
+			}
+			fmt.Fprintln(w, string(dat))
+			return nil
+		}
+		fmt.Fprintln(w, m.FileTOC.String())
+		return nil
+	})
+}
+
+func runLoadCommands(args []string, w io.Writer) error {
+	fs, arch := newFlagSet("lc", w)
+	path, err := parseFile(fs, args)
+	if err != nil {
+		return err
+	}
+	return forEach(w, path, *arch, macho.FileConfig{}, func(m *macho.File) error {
+		fmt.Fprint(w, m.String())
+		return nil
+	})
+}
+
+func runSymbols(args []string, w io.Writer) error {
+	fs, arch := newFlagSet("symbols", w)
+	demangle := fs.Bool("demangle", false, "demangle Swift and C++ symbol names")
+	var q macho.SymbolQuery
+	fs.StringVar(&q.Prefix, "prefix", "", "only print the symbols with this name prefix")
+	fs.StringVar(&q.Glob, "glob", "", "only print the symbols with names matching this glob pattern")
+	regex := fs.String("regex", "", "only print the symbols with names matching this regular expression")
+	fs.BoolVar(&q.Defined, "defined", false, "only print the defined symbols")
+	fs.BoolVar(&q.Undefined, "undefined", false, "only print the undefined symbols")
+	fs.BoolVar(&q.External, "external", false, "only print the external symbols")
+	path, err := parseFile(fs, args)
+	if err != nil {
+		return err
+	}
+	if *regex != "" {
+		if q.Regexp, err = regexp.Compile(*regex); err != nil {
+			return fmt.Errorf("failed to compile -regex: %v", err)
+		}
+	}
+	return forEach(w, path, *arch, macho.FileConfig{DemangleSymbols: *demangle}, func(m *macho.File) error {
+		if m.Symtab == nil {
+			return fmt.Errorf("MachO has no LC_SYMTAB")
+		}
+		syms, err := m.FindSymbols(q)
+		if err != nil {
+			return err
+		}
+		for _, sym := range syms {
+			fmt.Fprintln(w, sym.String(m))
+		}
+		return nil
+	})
+}
+
+func runExports(args []string, w io.Writer) error {
+	fs, arch := newFlagSet("exports", w)
+	path, err := parseFile(fs, args)
+	if err != nil {
+		return err
+	}
+	return forEach(w, path, *arch, macho.FileConfig{}, func(m *macho.File) error {
+		if m.DyldExportsTrie() != nil {
+			exps, err := m.DyldExports()
+			if err != nil {
+				return err
+			}
+			for _, exp := range exps {
+				fmt.Fprintln(w, exp.String())
+			}
+			return nil
+		}
+		exps, err := m.GetExports()
+		if err != nil {
+			if !errors.Is(err, macho.ErrMachODyldInfoNotFound) {
+				return err
+			}
+			// no exports trie so fallback to the external symbols
+			if m.Symtab != nil {
+				for _, sym := range m.Symtab.Syms {
+					if sym.Type.IsExternalSym() && sym.Type.IsDefinedInSection() && !sym.Type.IsPrivateExternalSym() {
+						fmt.Fprintln(w, sym.String(m))
+					}
+				}
+			}
+			return nil
+		}
+		for _, exp := range exps {
+			fmt.Fprintln(w, exp.String())
+		}
+		return nil
+	})
+}
+
+func runObjC(args []string, w io.Writer) error {
+	fs, arch := newFlagSet("objc", w)
+	verbose := fs.Bool("v", false, "print the class methods, properties and ivars")
+	path, err := parseFile(fs, args)
+	if err != nil {
+		return err
+	}
+	return forEach(w, path, *arch, macho.FileConfig{}, func(m *macho.File) error {
+		if !m.HasObjC() {
+			fmt.Fprintln(w, "no Objective-C runtime info")
+			return nil
+		}
+		protos, err := m.GetObjCProtocols()
+		if err != nil {
+			return fmt.Errorf("failed to get protocols: %v", err)
+		}
+		for _, p := range protos {
+			fmt.Fprintln(w, p.String())
+		}
+		classes, err := m.GetObjCClasses()
+		if err != nil {
+			return fmt.Errorf("failed to get classes: %v", err)
+		}
+		for _, c := range classes {
+			if *verbose {
+				fmt.Fprintln(w, c.Verbose())
+			} else {
+				fmt.Fprintln(w, c.String())
+			}
+		}
+		cats, err := m.GetObjCCategories()
+		if err != nil {
+			return fmt.Errorf("failed to get categories: %v", err)
+		}
+		for _, c := range cats {
+			fmt.Fprintln(w, c.String())
+		}
+		return nil
+	})
+}
+
+func runSign(args []string, w io.Writer) error {
+	fs, arch := newFlagSet("sign", w)
+	asJSON := fs.Bool("json", false, "print as JSON")
+	verify := fs.Bool("verify", false, "verify the code page and special slot hashes")
+	path, err := parseFile(fs, args)
+	if err != nil {
+		return err
+	}
+	return forEach(w, path, *arch, macho.FileConfig{}, func(m *macho.File) error {
+		cs := m.CodeSignature()
+		if cs == nil {
+			return fmt.Errorf("MachO has no LC_CODE_SIGNATURE")
+		}
+		if *verify {
+			mismatches, err := m.VerifyCodeSignaturePages()
+			if err != nil {
+				return err
+			}
+			for _, mm := range mismatches {
+				fmt.Fprintln(w, mm.String())
+			}
+			if len(mismatches) > 0 {
+				return fmt.Errorf("code signature is invalid: %d hash mismatches", len(mismatches))
+			}
+			fmt.Fprintln(w, "code signature is valid")
+			return nil
+		}
+		if *asJSON {
+			dat, err := json.MarshalIndent(cs.CodeSignature, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal code signature: %v", err)
+			}
+			fmt.Fprintln(w, string(dat))
+			return nil
+		}
+		for _, cd := range cs.CodeDirectories {
+			fmt.Fprintf(w, "Identifier:  %s\n", cd.ID)
+			if cd.TeamID != "" {
+				fmt.Fprintf(w, "TeamID:      %s\n", cd.TeamID)
+			}
+			fmt.Fprintf(w, "CDHash:      %s\n", cd.CDHash)
+			fmt.Fprintf(w, "Hash Type:   %s\n", cd.Header.HashType)
+			fmt.Fprintf(w, "Code Slots:  %d\n", len(cd.CodeSlots))
+		}
+		if len(cs.Entitlements) > 0 {
+			fmt.Fprintf(w, "Entitlements:\n%s\n", strings.TrimSpace(cs.Entitlements))
+		}
+		return nil
+	})
+}
+
+func runDeps(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("deps", flag.ContinueOnError)
+	fs.SetOutput(w)
+	roots := fs.String("root", "", "colon separated `dirs` to look up the absolute install names in (i.e. an extracted filesystem)")
+	fallback := fs.String("fallback", "", "colon separated `dirs` to look up the libraries that aren't found in by leaf name")
+	path, err := parseFile(fs, args)
+	if err != nil {
+		return err
+	}
+	var conf deps.Config
+	if *roots != "" {
+		conf.Roots = strings.Split(*roots, ":")
+	}
+	if *fallback != "" {
+		conf.FallbackPaths = strings.Split(*fallback, ":")
+	}
+	g, err := deps.Walk(path, conf)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, g.Root.Path)
+	printDeps(w, g.Root, 1, map[*deps.Node]bool{g.Root: true})
+	return nil
+}
+
+// printDeps prints the dependency tree of n (the dependencies of the images already printed aren't repeated)
+func printDeps(w io.Writer, n *deps.Node, depth int, seen map[*deps.Node]bool) {
+	indent := strings.Repeat("  ", depth)
+	for _, dep := range n.Deps {
+		var kind string
+		if dep.Kind != types.LC_LOAD_DYLIB {
+			kind = fmt.Sprintf(" [%s]", dep.Kind)
+		}
+		if dep.Node == nil {
+			fmt.Fprintf(w, "%s%s%s (not found: %v)\n", indent, dep.Path, kind, dep.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%s%s%s => %s\n", indent, dep.Path, kind, dep.Node.Path)
+		if !seen[dep.Node] {
+			seen[dep.Node] = true
+			printDeps(w, dep.Node, depth+1, seen)
+		}
+	}
+}
+
+func runLipo(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("lipo", flag.ContinueOnError)
+	fs.SetOutput(w)
+	info := fs.Bool("info", false, "print the architectures of the MachO")
+	thin := fs.String("thin", "", "extract the `arch` slice of a universal binary to -output")
+	create := fs.Bool("create", false, "create a universal binary from the MachOs in -output")
+	output := fs.String("output", "", "output `path`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("lipo: expected a MachO path")
+	}
+
+	switch {
+	case *create:
+		if *output == "" {
+			return fmt.Errorf("lipo: -create requires -output")
+		}
+		ff, err := macho.CreateFat(*output, fs.Args()...)
+		if err != nil {
+			return err
+		}
+		return ff.Close()
+	case *thin != "":
+		if *output == "" || fs.NArg() != 1 {
+			return fmt.Errorf("lipo: -thin requires -output and a single universal binary")
+		}
+		return extractSlice(fs.Arg(0), *thin, *output)
+	case *info:
+		for _, path := range fs.Args() {
+			slices, closer, err := openSlices(path, "", macho.FileConfig{})
+			if err != nil {
+				return err
+			}
+			closer.Close()
+			var arches []string
+			for _, s := range slices {
+				arches = append(arches, s.arch)
+			}
+			if slices[0].fat {
+				fmt.Fprintf(w, "Architectures in the fat file: %s are: %s\n", path, strings.Join(arches, " "))
+			} else {
+				fmt.Fprintf(w, "Non-fat file: %s is architecture: %s\n", path, arches[0])
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("lipo: one of -info, -thin or -create is required")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blacktop/go-macho/internal/obscuretestdata"
+)
+
+func decodeTestdata(t *testing.T, name string) string {
+	t.Helper()
+	path, err := obscuretestdata.DecodeToTempFile(filepath.Join("..", "..", "internal", "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(path) })
+	return path
+}
+
+func TestRun(t *testing.T) {
+	exec := decodeTestdata(t, "clang-amd64-darwin-exec-with-rpath.base64")
+	fat := decodeTestdata(t, "fat-gcc-386-amd64-darwin-exec.base64")
+	thin := filepath.Join(t.TempDir(), "thin")
+	created := filepath.Join(t.TempDir(), "fat")
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"info", exec}, []string{"LC_RPATH", "/my/rpath"}},
+		{[]string{"lc", exec}, []string{"Load command 13\n          cmd LC_RPATH\n"}},
+		{[]string{"symbols", exec}, []string{"_main", "_printf"}},
+		{[]string{"symbols", "-undefined", "-regex", "^_p", exec}, []string{"_printf"}},
+		{[]string{"exports", exec}, []string{"_main"}},
+		{[]string{"objc", exec}, []string{"no Objective-C runtime info"}},
+		{[]string{"deps", "-root", t.TempDir(), exec}, []string{exec + "\n", "  /usr/lib/libSystem.B.dylib (not found: library not found)"}},
+		{[]string{"lc", "-arch", "i386", fat}, []string{"(for architecture i386)", "LC_SEGMENT\n"}},
+		{[]string{"lipo", "-info", fat, exec}, []string{
+			"Architectures in the fat file: " + fat + " are: i386 x86_64",
+			"Non-fat file: " + exec + " is architecture: x86_64",
+		}},
+		{[]string{"lipo", "-thin", "x86_64", "-output", thin, fat}, nil},
+		{[]string{"lipo", "-info", thin}, []string{"Non-fat file: " + thin + " is architecture: x86_64"}},
+		{[]string{"lipo", "-create", "-output", created, exec}, nil},
+		{[]string{"lipo", "-info", created}, []string{"are: x86_64"}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := run(tt.args, &buf); err != nil {
+			t.Fatalf("run(%q) = %v", tt.args, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("run(%q) is missing %q:\n%s", tt.args, want, buf.String())
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := run([]string{"sign", exec}, &buf); err == nil {
+		t.Errorf("run(sign) of an unsigned MachO should fail")
+	}
+	if err := run([]string{"nope"}, &buf); err == nil {
+		t.Errorf("run(nope) should fail")
+	}
+}
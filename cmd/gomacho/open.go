@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/blacktop/go-macho"
+)
+
+// slice is a MachO (or one of the architectures of a universal binary)
+type slice struct {
+	*macho.File
+	arch   string
+	fat    bool
+	offset int64
+	size   int64
+}
+
+// openSlices opens the MachO at path returning its slices (only the arch one if arch is set)
+func openSlices(path, arch string, config macho.FileConfig) ([]slice, io.Closer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var slices []slice
+	ff, err := macho.NewFatFile(f)
+	if err != nil {
+		if !errors.Is(err, macho.ErrNotFat) {
+			f.Close()
+			return nil, nil, fmt.Errorf("failed to parse MachO %s: %v", path, err)
+		}
+		m, err := macho.NewFile(f, config)
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("failed to parse MachO %s: %v", path, err)
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		slices = append(slices, slice{File: m, arch: m.Architecture(), size: fi.Size()})
+	} else {
+		for _, fa := range ff.Arches {
+			// reparse the slice so the file config is honored
+			sr := io.NewSectionReader(f, int64(fa.Offset), int64(fa.Size))
+			m, err := macho.NewFile(sr, config)
+			if err != nil {
+				f.Close()
+				return nil, nil, fmt.Errorf("failed to parse MachO %s slice %s: %v", path, fa.CPU, err)
+			}
+			slices = append(slices, slice{
+				File:   m,
+				arch:   fa.Architecture(),
+				fat:    true,
+				offset: int64(fa.Offset),
+				size:   int64(fa.Size),
+			})
+		}
+	}
+
+	if arch != "" {
+		for _, s := range slices {
+			if s.arch == arch {
+				return []slice{s}, f, nil
+			}
+		}
+		f.Close()
+		return nil, nil, fmt.Errorf("MachO %s does not contain architecture %s", path, arch)
+	}
+
+	return slices, f, nil
+}
+
+// extractSlice writes the arch slice of the universal binary at path to output
+func extractSlice(path, arch, output string) error {
+	slices, closer, err := openSlices(path, arch, macho.FileConfig{})
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	s := slices[0]
+	if !s.fat {
+		return fmt.Errorf("MachO %s is not a universal binary", path)
+	}
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %v", output, err)
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, io.NewSectionReader(closer.(io.ReaderAt), s.offset, s.size)); err != nil {
+		return fmt.Errorf("failed to write %s slice to %s: %v", arch, output, err)
+	}
+	return out.Close()
+}
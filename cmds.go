@@ -3,15 +3,18 @@ package macho
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"path/filepath"
 	"strings"
+	"time"
 	"unsafe"
 
 	"github.com/blacktop/go-macho/internal/saferio"
 	"github.com/blacktop/go-macho/pkg/codesign"
+	"github.com/blacktop/go-macho/pkg/demangle"
 	"github.com/blacktop/go-macho/types"
 )
 
@@ -35,7 +38,21 @@ type LoadCmdBytes struct {
 }
 
 func (s LoadCmdBytes) String() string {
-	return s.LoadCmd.String() + ": " + s.LoadBytes.String()
+	return fmt.Sprintf(
+		"      cmd %s\n"+
+			"  cmdsize %d",
+		s.LoadCmd, len(s.LoadBytes))
+}
+func (s LoadCmdBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		LoadCmd string `json:"load_cmd"`
+		Len     int    `json:"length"`
+		Data    string `json:"data,omitempty"`
+	}{
+		LoadCmd: s.LoadCmd.String(),
+		Len:     len(s.LoadBytes),
+		Data:    hex.EncodeToString(s.LoadBytes),
+	})
 }
 func (s LoadCmdBytes) Copy() LoadCmdBytes {
 	return LoadCmdBytes{LoadCmd: s.LoadCmd, LoadBytes: s.LoadBytes.Copy()}
@@ -62,10 +79,10 @@ func (b LoadBytes) String() string {
 func (b LoadBytes) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		LoadCmd string `json:"load_cmd"`
-		Data    []byte `json:"data,omitempty"`
+		Data    string `json:"data,omitempty"`
 	}{
 		LoadCmd: "unknown",
-		Data:    b,
+		Data:    hex.EncodeToString(b),
 	})
 }
 func (b LoadBytes) Raw() []byte      { return b }
@@ -206,11 +223,40 @@ func (s *Segment) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 
 // Data reads and returns the contents of the segment.
 func (s *Segment) Data() ([]byte, error) {
+	if s.sr == nil {
+		return nil, fmt.Errorf("segment %s has no reader", s.Name)
+	}
 	return saferio.ReadDataAt(s.sr, s.Filesz, 0)
 }
 
-// Open returns a new ReadSeeker reading the segment.
-func (s *Segment) Open() io.ReadSeeker { return io.NewSectionReader(s.sr, 0, 1<<63-1) }
+// Sections returns the sections of the segment's load command (in MH_OBJECT files they all are in a single
+// unnamed segment whatever their segment name)
+func (s *Segment) Sections() []*types.Section {
+	return s.sections
+}
+
+// Section returns the section of the segment with the given name, or nil if no such section exists.
+func (s *Segment) Section(name string) *types.Section {
+	for _, sec := range s.sections {
+		if sec.Name == name {
+			return sec
+		}
+	}
+	return nil
+}
+
+// Open returns a new ReadSeeker reading the segment (reads fail if the segment has no reader).
+func (s *Segment) Open() io.ReadSeeker {
+	if s.sr == nil {
+		return io.NewSectionReader(errReaderAt{fmt.Errorf("segment %s has no reader", s.Name)}, 0, 1<<63-1)
+	}
+	return io.NewSectionReader(s.sr, 0, 1<<63-1)
+}
+
+// errReaderAt is an io.ReaderAt that fails every read with err
+type errReaderAt struct{ err error }
+
+func (r errReaderAt) ReadAt([]byte, int64) (int, error) { return 0, r.err }
 
 // UncompressedSize returns the size of the segment with its sections uncompressed, ignoring
 // its offset within the file.  The returned size is rounded up to the power of two in align.
@@ -248,17 +294,29 @@ func (s *Segment) LoadSize() uint32 {
 }
 
 func (s *Segment) String() string {
-	return fmt.Sprintf("%s sz=0x%08x off=0x%08x-0x%08x addr=0x%09x-0x%09x %s/%s   %-18s%s",
-		s.Command(),
-		s.Filesz,
-		s.Offset,
-		s.Offset+s.Filesz,
-		s.Addr,
-		s.Addr+s.Memsz,
-		s.Prot,
-		s.Maxprot,
-		s.Name,
-		s.Flag)
+	var sb strings.Builder
+	is64 := s.Command() == types.LC_SEGMENT_64
+	fmt.Fprintf(&sb, "      cmd %s\n", s.Command())
+	fmt.Fprintf(&sb, "  cmdsize %d\n", s.Len)
+	fmt.Fprintf(&sb, "  segname %s\n", s.Name)
+	if is64 {
+		fmt.Fprintf(&sb, "   vmaddr 0x%016x\n", s.Addr)
+		fmt.Fprintf(&sb, "   vmsize 0x%016x\n", s.Memsz)
+	} else {
+		fmt.Fprintf(&sb, "   vmaddr 0x%08x\n", s.Addr)
+		fmt.Fprintf(&sb, "   vmsize 0x%08x\n", s.Memsz)
+	}
+	fmt.Fprintf(&sb, "  fileoff %d\n", s.Offset)
+	fmt.Fprintf(&sb, " filesize %d\n", s.Filesz)
+	fmt.Fprintf(&sb, "  maxprot 0x%08x\n", uint32(s.Maxprot))
+	fmt.Fprintf(&sb, " initprot 0x%08x\n", uint32(s.Prot))
+	fmt.Fprintf(&sb, "   nsects %d\n", s.Nsect)
+	fmt.Fprintf(&sb, "    flags 0x%x", uint32(s.Flag))
+	for _, sec := range s.sections {
+		sb.WriteString("\n")
+		sb.WriteString(otoolSection(sec, is64))
+	}
+	return sb.String()
 }
 
 func (s *Segment) MarshalJSON() ([]byte, error) {
@@ -343,10 +401,14 @@ func (s *Symtab) Search(name string) (*Symbol, error) {
 	return nil, fmt.Errorf("%s not found in symtab", name)
 }
 func (s *Symtab) String() string {
-	if s.Nsyms == 0 && s.Strsize == 0 {
-		return "Symbols stripped"
-	}
-	return fmt.Sprintf("Symbol offset=0x%08X, Num Syms: %d, String offset=0x%08X-0x%08X", s.Symoff, s.Nsyms, s.Stroff, s.Stroff+s.Strsize)
+	return fmt.Sprintf(
+		"     cmd %s\n"+
+			" cmdsize %d\n"+
+			"  symoff %d\n"+
+			"   nsyms %d\n"+
+			"  stroff %d\n"+
+			" strsize %d",
+		s.Command(), s.Len, s.Symoff, s.Nsyms, s.Stroff, s.Strsize)
 }
 func (s *Symtab) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
@@ -375,6 +437,16 @@ type Symbol struct {
 	Sect  uint8
 	Desc  types.NDescType
 	Value uint64
+
+	demangled string // set when the File was opened with FileConfig.DemangleSymbols
+}
+
+// Demangled returns the demangled Swift or C++ name of the symbol (or its name if it isn't mangled)
+func (s Symbol) Demangled() string {
+	if s.demangled != "" {
+		return s.demangled
+	}
+	return demangle.Filter(s.Name)
 }
 
 func (s Symbol) GetType(m *File) string {
@@ -407,8 +479,8 @@ func (s Symbol) GetType(m *File) string {
 	} else if s.Type.IsIndirectSym() {
 		typ += "(indirect) "
 	} else if s.Type.IsDefinedInSection() {
-		if s.Sect != types.NO_SECT && int(s.Sect) <= len(m.Sections) {
-			typ += fmt.Sprintf("(%s,%s) ", m.Sections[s.Sect-1].Seg, m.Sections[s.Sect-1].Name)
+		if sec := s.Section(m); sec != nil {
+			typ += fmt.Sprintf("(%s,%s) ", sec.Seg, sec.Name)
 		}
 	} else if s.Type.IsDebugSym() {
 		typ += "(debug) "
@@ -470,6 +542,21 @@ func (s Symbol) GetType(m *File) string {
 
 	return strings.TrimSpace(typ)
 }
+
+// IsThumb returns whether the symbol is a 32-bit ARM Thumb function (its n_desc has N_ARM_THUMB_DEF set)
+func (s Symbol) IsThumb() bool {
+	return !s.Type.IsDebugSym() && s.Desc.IsArmThumbDefintion()
+}
+
+// Section returns the section the N_SECT symbol is defined in (its n_sect is the 1-based index of the section
+// across all of the segments), or nil if it isn't defined in a section of m
+func (s Symbol) Section(m *File) *types.Section {
+	if s.Type.IsDebugSym() || !s.Type.IsDefinedInSection() || s.Sect == types.NO_SECT || int(s.Sect) > len(m.Sections) {
+		return nil
+	}
+	return m.Sections[s.Sect-1]
+}
+
 func (s Symbol) GetLib(m *File) string {
 	if (m.Flags.TwoLevel() && s.Type.IsUndefinedSym() && s.Value == 0) || s.Type.IsPreboundUndefinedSym() {
 		if s.Desc.GetLibraryOrdinal() > types.SELF_LIBRARY_ORDINAL {
@@ -490,22 +577,31 @@ func (s Symbol) GetLib(m *File) string {
 	return ""
 }
 func (s Symbol) String(m *File) string {
-	return fmt.Sprintf("0x%016X\t%s\t%s%s", s.Value, s.GetType(m), s.Name, s.GetLib(m))
+	name := s.Name
+	if m.demangleSymbols {
+		name = s.Demangled()
+	}
+	return fmt.Sprintf("0x%016X\t%s\t%s%s", s.Value, s.GetType(m), name, s.GetLib(m))
 }
 func (s *Symbol) MarshalJSON() ([]byte, error) {
-	return json.Marshal(&struct {
-		Name  string `json:"name"`
-		Type  string `json:"type"`
-		Sect  uint8  `json:"sect"`
-		Desc  string `json:"desc"`
-		Value uint64 `json:"value"`
+	sym := &struct {
+		Name      string `json:"name"`
+		Type      string `json:"type"`
+		Sect      uint8  `json:"sect"`
+		Desc      string `json:"desc"`
+		Value     uint64 `json:"value"`
+		Demangled string `json:"demangled,omitempty"`
 	}{
 		Name:  s.Name,
 		Type:  s.Type.String(fmt.Sprintf("sect_num=%d", s.Sect)),
 		Sect:  s.Sect,
 		Desc:  s.Desc.String(),
 		Value: s.Value,
-	})
+	}
+	if s.demangled != s.Name {
+		sym.Demangled = s.demangled
+	}
+	return json.Marshal(sym)
 }
 
 /*******************************************************************************
@@ -516,6 +612,7 @@ func (s *Symbol) MarshalJSON() ([]byte, error) {
 type SymSeg struct {
 	LoadBytes
 	types.SymsegCmd
+	sr *io.SectionReader
 }
 
 func (s *SymSeg) LoadSize() uint32 {
@@ -527,8 +624,30 @@ func (s *SymSeg) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	}
 	return nil
 }
+
+// Data reads and returns the contents of the gdb symbol segment.
+func (s *SymSeg) Data() ([]byte, error) {
+	if s.sr == nil {
+		return nil, fmt.Errorf("%s has no reader", s.Command())
+	}
+	return saferio.ReadDataAt(s.sr, uint64(s.Size), 0)
+}
+
+// Open returns a new ReadSeeker reading the gdb symbol segment (reads fail if it has no reader).
+func (s *SymSeg) Open() io.ReadSeeker {
+	if s.sr == nil {
+		return io.NewSectionReader(errReaderAt{fmt.Errorf("%s has no reader", s.Command())}, 0, int64(s.Size))
+	}
+	return io.NewSectionReader(s.sr, 0, int64(s.Size))
+}
+
 func (s *SymSeg) String() string {
-	return fmt.Sprintf("offset=0x%08x-0x%08x size=%5d", s.Offset, s.Offset+s.Size, s.Size)
+	return fmt.Sprintf(
+		"     cmd %s\n"+
+			" cmdsize %d\n"+
+			"  offset %d\n"+
+			"    size %d",
+		s.Command(), s.Len, s.Offset, s.Size)
 }
 func (s *SymSeg) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
@@ -577,14 +696,14 @@ func (t *Thread) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (t *Thread) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "        cmd %s\n", t.Command())
+	fmt.Fprintf(&sb, "    cmdsize %d", t.Len)
 	for _, thread := range t.Threads {
-		if thread.Flavor == types.ARM_THREAD_STATE64 {
-			regs := make([]uint64, thread.Count/2)
-			binary.Read(bytes.NewReader(thread.Data), t.bo, &regs)
-			return fmt.Sprintf("Threads: %d, ARM64 EntryPoint: %#016x", len(t.Threads), regs[len(regs)-2])
-		}
+		fmt.Fprintf(&sb, "\n     flavor %d\n", uint32(thread.Flavor))
+		fmt.Fprintf(&sb, "      count %d", thread.Count)
 	}
-	return fmt.Sprintf("Threads: %d", len(t.Threads))
+	return sb.String()
 }
 func (t *Thread) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
@@ -637,7 +756,13 @@ func (l *LoadFvmlib) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (l *LoadFvmlib) String() string {
-	return fmt.Sprintf("%s (%s), Header Addr: %#08x", l.Name, l.MinorVersion, l.HeaderAddr)
+	return fmt.Sprintf(
+		"           cmd %s\n"+
+			"       cmdsize %d\n"+
+			"          name %s (offset %d)\n"+
+			" minor version %d\n"+
+			"   header addr 0x%08x",
+		l.Command(), l.Len, l.Name, l.NameOffset, uint32(l.MinorVersion), l.HeaderAddr)
 }
 func (l *LoadFvmlib) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
@@ -681,7 +806,7 @@ func (i *Ident) LoadSize() uint32 {
 		sz += uint32(len(str) + 1)
 	}
 	if (sz % 4) != 0 {
-		sz = 4 - (sz % 4)
+		sz += 4 - (sz % 4)
 	}
 	return sz
 }
@@ -703,7 +828,13 @@ func (i *Ident) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (i *Ident) String() string {
-	return fmt.Sprintf("str_count=%d", len(i.StrTable))
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "          cmd %s\n", i.Command())
+	fmt.Fprintf(&sb, "      cmdsize %d", i.Len)
+	for _, s := range i.StrTable {
+		fmt.Fprintf(&sb, "\n %s", s)
+	}
+	return sb.String()
 }
 func (i *Ident) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
@@ -747,7 +878,12 @@ func (l *FvmFile) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (l *FvmFile) String() string {
-	return fmt.Sprintf("%s, Header Addr: %#08x", l.Name, l.HeaderAddr)
+	return fmt.Sprintf(
+		"           cmd %s\n"+
+			"       cmdsize %d\n"+
+			"          name %s (offset %d)\n"+
+			"   header addr 0x%08x",
+		l.Command(), l.Len, l.Name, l.NameOffset, l.HeaderAddr)
 }
 func (l *FvmFile) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
@@ -783,7 +919,10 @@ func (c *Prepage) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (c *Prepage) String() string {
-	return fmt.Sprintf("size=%d", c.Len)
+	return fmt.Sprintf(
+		"      cmd %s\n"+
+			"  cmdsize %d",
+		c.Command(), c.Len)
 }
 func (c *Prepage) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
@@ -804,6 +943,21 @@ type Dysymtab struct {
 	LoadBytes
 	types.DysymtabCmd
 	IndirectSyms []uint32 // indices into Symtab.Syms
+	// relocations of the pre-LC_DYLD_INFO fixups (and of kexts); their Addr is relative to the address of the
+	// first segment (or of the first writable segment for x86_64)
+	ExternalRelocs []types.Reloc // relocations to undefined symbols (Value is the symbol index)
+	LocalRelocs    []types.Reloc // rebases of local pointers
+	// tables of old-style (pre-two-level namespace) dylibs
+	TOC        []types.DylibTableOfContents
+	Modules    []DylibModule
+	ExtRefSyms []types.DylibReference // the symbols referenced by each module (see DylibModule.Irefsym)
+}
+
+// A DylibModule is an entry of the module table of the LC_DYSYMTAB (32-bit entries are widened to the
+// 64-bit layout)
+type DylibModule struct {
+	Name string
+	types.DylibModule64
 }
 
 func (d *Dysymtab) LoadSize() uint32 {
@@ -816,57 +970,37 @@ func (d *Dysymtab) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (d *Dysymtab) String() string {
-	var tocStr, modStr, extSymStr, indirSymStr, extRelStr, locRelStr string
-	if d.Ntoc == 0 {
-		tocStr = "No"
-	} else {
-		tocStr = fmt.Sprintf("%d at 0x%08x", d.Ntoc, d.Tocoffset)
-	}
-	if d.Nmodtab == 0 {
-		modStr = "No"
-	} else {
-		modStr = fmt.Sprintf("%d at 0x%08x", d.Nmodtab, d.Modtaboff)
-	}
-	if d.Nextrefsyms == 0 {
-		extSymStr = "None"
-	} else {
-		extSymStr = fmt.Sprintf("%d at 0x%08x", d.Nextrefsyms, d.Extrefsymoff)
-	}
-	if d.Nindirectsyms == 0 {
-		indirSymStr = "None"
-	} else {
-		indirSymStr = fmt.Sprintf("%d at 0x%08x", d.Nindirectsyms, d.Indirectsymoff)
-	}
-	if d.Nextrel == 0 {
-		extRelStr = "None"
-	} else {
-		extRelStr = fmt.Sprintf("%d at 0x%08x", d.Nextrel, d.Extreloff)
-	}
-	if d.Nlocrel == 0 {
-		locRelStr = "None"
-	} else {
-		locRelStr = fmt.Sprintf("%d at 0x%08x", d.Nlocrel, d.Locreloff)
-	}
 	return fmt.Sprintf(
-		"\n"+
-			"\t             Local Syms: %d at %d\n"+
-			"\t          External Syms: %d at %d\n"+
-			"\t         Undefined Syms: %d at %d\n"+
-			"\t                    TOC: %s\n"+
-			"\t                 Modtab: %s\n"+
-			"\tExternal symtab Entries: %s\n"+
-			"\tIndirect symtab Entries: %s\n"+
-			"\t External Reloc Entries: %s\n"+
-			"\t    Local Reloc Entries: %s",
-		d.Nlocalsym, d.Ilocalsym,
-		d.Nextdefsym, d.Iextdefsym,
-		d.Nundefsym, d.Iundefsym,
-		tocStr,
-		modStr,
-		extSymStr,
-		indirSymStr,
-		extRelStr,
-		locRelStr)
+		"            cmd %s\n"+
+			"        cmdsize %d\n"+
+			"      ilocalsym %d\n"+
+			"      nlocalsym %d\n"+
+			"     iextdefsym %d\n"+
+			"     nextdefsym %d\n"+
+			"      iundefsym %d\n"+
+			"      nundefsym %d\n"+
+			"         tocoff %d\n"+
+			"           ntoc %d\n"+
+			"      modtaboff %d\n"+
+			"        nmodtab %d\n"+
+			"   extrefsymoff %d\n"+
+			"    nextrefsyms %d\n"+
+			" indirectsymoff %d\n"+
+			"  nindirectsyms %d\n"+
+			"      extreloff %d\n"+
+			"        nextrel %d\n"+
+			"      locreloff %d\n"+
+			"        nlocrel %d",
+		d.Command(), d.Len,
+		d.Ilocalsym, d.Nlocalsym,
+		d.Iextdefsym, d.Nextdefsym,
+		d.Iundefsym, d.Nundefsym,
+		d.Tocoffset, d.Ntoc,
+		d.Modtaboff, d.Nmodtab,
+		d.Extrefsymoff, d.Nextrefsyms,
+		d.Indirectsymoff, d.Nindirectsyms,
+		d.Extreloff, d.Nextrel,
+		d.Locreloff, d.Nlocrel)
 }
 func (d *Dysymtab) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -959,11 +1093,11 @@ type PreboundDylib struct {
 	LoadBytes
 	types.PreboundDylibCmd
 	Name                   string
-	LinkedModulesBitVector string
+	LinkedModulesBitVector []byte
 }
 
 func (d *PreboundDylib) LoadSize() uint32 {
-	return pointerAlign(uint32(binary.Size(d.PreboundDylibCmd) + len(d.Name) + 1 + len(d.LinkedModulesBitVector) + 1))
+	return pointerAlign(uint32(binary.Size(d.PreboundDylibCmd) + len(d.Name) + 1 + len(d.LinkedModulesBitVector)))
 }
 func (d *PreboundDylib) Put(b []byte, o binary.ByteOrder) int {
 	o.PutUint32(b[0*4:], uint32(d.LoadCmd))
@@ -980,8 +1114,8 @@ func (d *PreboundDylib) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	if _, err := buf.WriteString(d.Name + "\x00"); err != nil {
 		return fmt.Errorf("failed to write %s to %s buffer: %v", d.Name, d.Command(), err)
 	}
-	if _, err := buf.WriteString(d.LinkedModulesBitVector + "\x00"); err != nil {
-		return fmt.Errorf("failed to write %s to %s buffer: %v", d.LinkedModulesBitVector, d.Command(), err)
+	if _, err := buf.Write(d.LinkedModulesBitVector); err != nil {
+		return fmt.Errorf("failed to write linked modules bit vector to %s buffer: %v", d.Command(), err)
 	}
 	if (buf.Len() % 8) != 0 {
 		pad := 8 - (buf.Len() % 8)
@@ -991,22 +1125,57 @@ func (d *PreboundDylib) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	}
 	return nil
 }
+
+// IsModuleLinked returns true if the Nth module of the library was bound when prebinding
+func (d *PreboundDylib) IsModuleLinked(n uint32) bool {
+	if n >= d.NumModules || int(n/8) >= len(d.LinkedModulesBitVector) {
+		return false
+	}
+	return (d.LinkedModulesBitVector[n/8]>>(n%8))&1 == 1
+}
+
+// LinkedModules returns the decoded linked modules bit vector (one entry per module)
+func (d *PreboundDylib) LinkedModules() []bool {
+	mods := make([]bool, d.NumModules)
+	for i := range mods {
+		mods[i] = d.IsModuleLinked(uint32(i))
+	}
+	return mods
+}
+
+// LinkedModuleCount returns the number of modules that were bound when prebinding
+func (d *PreboundDylib) LinkedModuleCount() int {
+	var count int
+	for _, linked := range d.LinkedModules() {
+		if linked {
+			count++
+		}
+	}
+	return count
+}
+
 func (d *PreboundDylib) String() string {
-	return fmt.Sprintf("%s, NumModules=%d, LinkedModulesBitVector=%v", d.Name, d.NumModules, []byte(d.LinkedModulesBitVector))
+	return fmt.Sprintf(
+		"            cmd %s\n"+
+			"        cmdsize %d\n"+
+			"           name %s (offset %d)\n"+
+			"       nmodules %d\n"+
+			" linked_modules (offset %d)",
+		d.Command(), d.Len, d.Name, d.NameOffset, d.NumModules, d.LinkedModulesOffset)
 }
 func (d *PreboundDylib) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		LoadCmd                string `json:"load_cmd"`
-		Len                    uint32 `json:"length"`
-		Name                   string `json:"name"`
-		NumModules             uint32 `json:"num_modules"`
-		LinkedModulesBitVector string `json:"linked_modules"`
+		LoadCmd       string `json:"load_cmd"`
+		Len           uint32 `json:"length"`
+		Name          string `json:"name"`
+		NumModules    uint32 `json:"num_modules"`
+		LinkedModules []bool `json:"linked_modules"`
 	}{
-		LoadCmd:                d.Command().String(),
-		Len:                    d.Len,
-		Name:                   d.Name,
-		NumModules:             d.NumModules,
-		LinkedModulesBitVector: d.LinkedModulesBitVector,
+		LoadCmd:       d.Command().String(),
+		Len:           d.Len,
+		Name:          d.Name,
+		NumModules:    d.NumModules,
+		LinkedModules: d.LinkedModules(),
 	})
 }
 
@@ -1030,7 +1199,19 @@ func (l *Routines) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (l *Routines) String() string {
-	return fmt.Sprintf("Address: %#08x, Module: %d", l.InitAddress, l.InitModule)
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			" init_address 0x%08x\n"+
+			"  init_module %d\n"+
+			"    reserved1 %d\n"+
+			"    reserved2 %d\n"+
+			"    reserved3 %d\n"+
+			"    reserved4 %d\n"+
+			"    reserved5 %d\n"+
+			"    reserved6 %d",
+		l.Command(), l.Len, l.InitAddress, l.InitModule,
+		l.Reserved1, l.Reserved2, l.Reserved3, l.Reserved4, l.Reserved5, l.Reserved6)
 }
 func (l *Routines) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1075,7 +1256,13 @@ func (l *SubFramework) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	}
 	return nil
 }
-func (l *SubFramework) String() string { return l.Framework }
+func (l *SubFramework) String() string {
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			"     umbrella %s (offset %d)",
+		l.Command(), l.Len, l.Framework, l.FrameworkOffset)
+}
 func (l *SubFramework) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		LoadCmd   string `json:"load_cmd"`
@@ -1117,7 +1304,13 @@ func (l *SubUmbrella) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	}
 	return nil
 }
-func (l *SubUmbrella) String() string { return l.Umbrella }
+func (l *SubUmbrella) String() string {
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			" sub_umbrella %s (offset %d)",
+		l.Command(), l.Len, l.Umbrella, l.UmbrellaOffset)
+}
 func (l *SubUmbrella) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		LoadCmd  string `json:"load_cmd"`
@@ -1160,7 +1353,11 @@ func (l *SubClient) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (l *SubClient) String() string {
-	return l.Name
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			"       client %s (offset %d)",
+		l.Command(), l.Len, l.Name, l.ClientOffset)
 }
 func (l *SubClient) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1203,7 +1400,13 @@ func (l *SubLibrary) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	}
 	return nil
 }
-func (l *SubLibrary) String() string { return l.Library }
+func (l *SubLibrary) String() string {
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			"  sub_library %s (offset %d)",
+		l.Command(), l.Len, l.Library, l.LibraryOffset)
+}
 func (l *SubLibrary) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		LoadCmd string `json:"load_cmd"`
@@ -1228,19 +1431,21 @@ type TwolevelHints struct {
 }
 
 func (l *TwolevelHints) LoadSize() uint32 {
-	return uint32(binary.Size(l.TwolevelHintsCmd) + binary.Size(l.Hints))
+	return uint32(binary.Size(l.TwolevelHintsCmd))
 }
 func (l *TwolevelHints) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	if err := binary.Write(buf, o, l.TwolevelHintsCmd); err != nil {
 		return fmt.Errorf("failed to write %s to buffer: %v", l.Command(), err)
 	}
-	if err := binary.Write(buf, o, l.Hints); err != nil {
-		return fmt.Errorf("failed to write hints to %s buffer: %v", l.Command(), err)
-	}
 	return nil
 }
 func (l *TwolevelHints) String() string {
-	return fmt.Sprintf("Offset: %#08x, Num of Hints: %d", l.Offset, len(l.Hints))
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			"       offset %d\n"+
+			"       nhints %d",
+		l.Command(), l.Len, l.Offset, l.NumHints)
 }
 func (l *TwolevelHints) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1276,7 +1481,11 @@ func (l *PrebindCheckSum) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (l *PrebindCheckSum) String() string {
-	return fmt.Sprintf("CheckSum: %#08x", l.CheckSum)
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			"        cksum %d",
+		l.Command(), l.Len, l.CheckSum)
 }
 func (l *PrebindCheckSum) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1319,7 +1528,19 @@ func (l *Routines64) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (l *Routines64) String() string {
-	return fmt.Sprintf("Address: %#016x, Module: %d", l.InitAddress, l.InitModule)
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			" init_address 0x%016x\n"+
+			"  init_module %d\n"+
+			"    reserved1 %d\n"+
+			"    reserved2 %d\n"+
+			"    reserved3 %d\n"+
+			"    reserved4 %d\n"+
+			"    reserved5 %d\n"+
+			"    reserved6 %d",
+		l.Command(), l.Len, l.InitAddress, l.InitModule,
+		l.Reserved1, l.Reserved2, l.Reserved3, l.Reserved4, l.Reserved5, l.Reserved6)
 }
 func (l *Routines64) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1355,7 +1576,11 @@ func (l *UUID) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (l *UUID) String() string {
-	return l.UUID.String()
+	return fmt.Sprintf(
+		"     cmd %s\n"+
+			" cmdsize %d\n"+
+			"    uuid %s",
+		l.Command(), l.Len, l.UUID)
 }
 func (l *UUID) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1399,7 +1624,11 @@ func (r *Rpath) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (r *Rpath) String() string {
-	return r.Path
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			"         path %s (offset %d)",
+		r.Command(), r.Len, r.Path, r.PathOffset)
 }
 func (r *Rpath) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1433,8 +1662,8 @@ func (l *CodeSignature) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	}
 	return nil
 }
-func (l *CodeSignature) String() string { // TODO: add more info
-	return fmt.Sprintf("offset=0x%09x  size=%#x", l.Offset, l.Size)
+func (l *CodeSignature) String() string {
+	return otoolLinkEditData(types.LinkEditDataCmd(l.CodeSignatureCmd))
 }
 func (l *CodeSignature) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1473,13 +1702,7 @@ func (l *SplitInfo) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (s *SplitInfo) String() string {
-	version := "format=v1"
-	if s.Version == types.DYLD_CACHE_ADJ_V2_FORMAT {
-		version = "format=v2"
-	} else {
-		version = fmt.Sprintf("kind=%#x", s.Version)
-	}
-	return fmt.Sprintf("offset=0x%08x-0x%08x size=%5d, %s", s.Offset, s.Offset+s.Size, s.Size, version)
+	return otoolLinkEditData(types.LinkEditDataCmd(s.SegmentSplitInfoCmd))
 }
 func (l *SplitInfo) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1544,10 +1767,13 @@ func (l *EncryptionInfo) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (e *EncryptionInfo) String() string {
-	if e.CryptID == 0 {
-		return fmt.Sprintf("offset=%#x size=%#x (not-encrypted yet)", e.Offset, e.Size)
-	}
-	return fmt.Sprintf("offset=%#x size=%#x CryptID: %#x", e.Offset, e.Size, e.CryptID)
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			"     cryptoff %d\n"+
+			"    cryptsize %d\n"+
+			"      cryptid %d",
+		e.Command(), e.Len, e.Offset, e.Size, uint32(e.CryptID))
 }
 func (l *EncryptionInfo) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1602,17 +1828,24 @@ func (l *DyldInfo) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 
 func (d *DyldInfo) String() string {
 	return fmt.Sprintf(
-		"\n"+
-			"\t\tRebase info: %5d bytes at offset:  0x%08X -> 0x%08X\n"+
-			"\t\tBind info:   %5d bytes at offset:  0x%08X -> 0x%08X\n"+
-			"\t\tWeak info:   %5d bytes at offset:  0x%08X -> 0x%08X\n"+
-			"\t\tLazy info:   %5d bytes at offset:  0x%08X -> 0x%08X\n"+
-			"\t\tExport info: %5d bytes at offset:  0x%08X -> 0x%08X",
-		d.RebaseSize, d.RebaseOff, d.RebaseOff+d.RebaseSize,
-		d.BindSize, d.BindOff, d.BindOff+d.BindSize,
-		d.WeakBindSize, d.WeakBindOff, d.WeakBindOff+d.WeakBindSize,
-		d.LazyBindSize, d.LazyBindOff, d.LazyBindOff+d.LazyBindSize,
-		d.ExportSize, d.ExportOff, d.ExportOff+d.ExportSize,
+		"            cmd %s\n"+
+			"        cmdsize %d\n"+
+			"     rebase_off %d\n"+
+			"    rebase_size %d\n"+
+			"       bind_off %d\n"+
+			"      bind_size %d\n"+
+			"  weak_bind_off %d\n"+
+			" weak_bind_size %d\n"+
+			"  lazy_bind_off %d\n"+
+			" lazy_bind_size %d\n"+
+			"     export_off %d\n"+
+			"    export_size %d",
+		d.Command(), d.Len,
+		d.RebaseOff, d.RebaseSize,
+		d.BindOff, d.BindSize,
+		d.WeakBindOff, d.WeakBindSize,
+		d.LazyBindOff, d.LazyBindSize,
+		d.ExportOff, d.ExportSize,
 	)
 }
 func (l *DyldInfo) MarshalJSON() ([]byte, error) {
@@ -1726,7 +1959,12 @@ func (e *EntryPoint) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (e *EntryPoint) String() string {
-	return fmt.Sprintf("Entry Point: 0x%016x, Stack Size: %#x", e.EntryOffset, e.StackSize)
+	return fmt.Sprintf(
+		"       cmd %s\n"+
+			"   cmdsize %d\n"+
+			"  entryoff %d\n"+
+			" stacksize %d",
+		e.Command(), e.Len, e.EntryOffset, e.StackSize)
 }
 func (e *EntryPoint) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1763,17 +2001,7 @@ func (l *DataInCode) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (d *DataInCode) String() string {
-	var ents string
-	if len(d.Entries) > 0 {
-		ents = "\n"
-	}
-	for _, e := range d.Entries {
-		ents += fmt.Sprintf("\toffset: %#08x length: %d kind: %s\n", e.Offset, e.Length, e.Kind)
-	}
-	ents = strings.TrimSuffix(ents, "\n")
-	return fmt.Sprintf(
-		"offset=0x%08x-0x%08x size=%5d entries=%d%s",
-		d.Offset, d.Offset+d.Size, d.Size, len(d.Entries), ents)
+	return otoolLinkEditData(types.LinkEditDataCmd(d.DataInCodeCmd))
 }
 func (l *DataInCode) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1831,7 +2059,11 @@ func (s *SourceVersion) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (s *SourceVersion) String() string {
-	return s.Version.String()
+	return fmt.Sprintf(
+		"      cmd %s\n"+
+			"  cmdsize %d\n"+
+			"  version %s",
+		s.Command(), s.Len, otoolSourceVersion(s.Version))
 }
 func (s *SourceVersion) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1851,6 +2083,26 @@ func (s *SourceVersion) MarshalJSON() ([]byte, error) {
 
 type DylibCodeSignDrs struct {
 	LinkEditData
+	Requirements []codesign.DylibRequirement
+}
+
+func (l *DylibCodeSignDrs) String() string {
+	return otoolLinkEditData(types.LinkEditDataCmd(l.LinkEditDataCmd))
+}
+func (l *DylibCodeSignDrs) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		LoadCmd      string                      `json:"load_cmd"`
+		Len          uint32                      `json:"length"`
+		Offset       uint32                      `json:"offset"`
+		Size         uint32                      `json:"size"`
+		Requirements []codesign.DylibRequirement `json:"requirements,omitempty"`
+	}{
+		LoadCmd:      l.Command().String(),
+		Len:          l.Len,
+		Offset:       l.Offset,
+		Size:         l.Size,
+		Requirements: l.Requirements,
+	})
 }
 
 /*******************************************************************************
@@ -1883,10 +2135,14 @@ func (e *EncryptionInfo64) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (e *EncryptionInfo64) String() string {
-	if e.CryptID == 0 {
-		return fmt.Sprintf("offset=0x%09x  size=%#x (not-encrypted yet)", e.Offset, e.Size)
-	}
-	return fmt.Sprintf("offset=0x%09x  size=%#x CryptID: %#x", e.Offset, e.Size, e.CryptID)
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			"     cryptoff %d\n"+
+			"    cryptsize %d\n"+
+			"      cryptid %d\n"+
+			"          pad %d",
+		e.Command(), e.Len, e.Offset, e.Size, uint32(e.CryptID), e.Pad)
 }
 func (e *EncryptionInfo64) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1936,7 +2192,14 @@ func (l *LinkerOption) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (l *LinkerOption) String() string {
-	return fmt.Sprintf("Options=%s", strings.Join(l.Options, ","))
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "     cmd %s\n", l.Command())
+	fmt.Fprintf(&sb, " cmdsize %d\n", l.Len)
+	fmt.Fprintf(&sb, "   count %d", l.Count)
+	for i, opt := range l.Options {
+		fmt.Fprintf(&sb, "\n  string #%d %s", i+1, opt)
+	}
+	return sb.String()
 }
 func (l *LinkerOption) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -1996,7 +2259,13 @@ func (n *Note) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (n *Note) String() string {
-	return fmt.Sprintf("DataOwner=%s, offset=0x%08x-0x%08x size=%5d", string(n.DataOwner[:]), n.Offset, n.Offset+n.Size, n.Size)
+	return fmt.Sprintf(
+		"       cmd %s\n"+
+			"   cmdsize %d\n"+
+			"data_owner %s\n"+
+			"    offset %d\n"+
+			"      size %d",
+		n.Command(), n.Len, strings.TrimRight(string(n.DataOwner[:]), "\x00"), n.Offset, n.Size)
 }
 func (n *Note) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -2008,7 +2277,7 @@ func (n *Note) MarshalJSON() ([]byte, error) {
 	}{
 		LoadCmd:   n.Command().String(),
 		Len:       n.Len,
-		DataOwner: string(n.DataOwner[:]),
+		DataOwner: strings.TrimRight(string(n.DataOwner[:]), "\x00"),
 		Offset:    n.Offset,
 		Size:      n.Size,
 	})
@@ -2029,6 +2298,7 @@ func (b *BuildVersion) LoadSize() uint32 {
 	return uint32(binary.Size(b.BuildVersionCmd) + binary.Size(b.Tools))
 }
 func (b *BuildVersion) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
+	b.NumTools = uint32(len(b.Tools))
 	if err := binary.Write(buf, o, b.BuildVersionCmd); err != nil {
 		return fmt.Errorf("failed to write %s to buffer: %v", b.Command(), err)
 	}
@@ -2038,27 +2308,18 @@ func (b *BuildVersion) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (b *BuildVersion) String() string {
-	if b.NumTools > 0 {
-		if b.NumTools == 1 {
-			return fmt.Sprintf("Platform: %s, MinOS: %s, SDK: %s, Tool: %s (%s)",
-				b.Platform,
-				b.Minos,
-				b.Sdk,
-				b.Tools[0].Tool,
-				b.Tools[0].Version)
-		} else {
-			var tools []string
-			for _, t := range b.Tools {
-				tools = append(tools, fmt.Sprintf("%s (%s)", t.Tool, t.Version))
-			}
-			return fmt.Sprintf("Platform: %s, MinOS: %s, SDK: %s, Tools: [%s]",
-				b.Platform,
-				b.Minos,
-				b.Sdk,
-				strings.Join(tools, ", "))
-		}
-	}
-	return fmt.Sprintf("Platform: %s, SDK: %s", b.Platform, b.Sdk)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "       cmd %s\n", b.Command())
+	fmt.Fprintf(&sb, "   cmdsize %d\n", b.Len)
+	fmt.Fprintf(&sb, "  platform %d\n", uint32(b.Platform))
+	fmt.Fprintf(&sb, "     minos %s\n", otoolVersion(b.Minos, false))
+	fmt.Fprintf(&sb, "       sdk %s\n", otoolSDK(b.Sdk))
+	fmt.Fprintf(&sb, "    ntools %d", b.NumTools)
+	for _, tool := range b.Tools {
+		fmt.Fprintf(&sb, "\n      tool %d\n", uint32(tool.Tool))
+		fmt.Fprintf(&sb, "   version %s", otoolVersion(tool.Version, false))
+	}
+	return sb.String()
 }
 func (b *BuildVersion) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -2128,7 +2389,14 @@ func (l *FilesetEntry) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (f *FilesetEntry) String() string {
-	return fmt.Sprintf("offset=0x%09x addr=0x%016x %s", f.FileOffset, f.Addr, f.EntryID)
+	return fmt.Sprintf(
+		"         cmd %s\n"+
+			"     cmdsize %d\n"+
+			"      vmaddr 0x%016x\n"+
+			"     fileoff %d\n"+
+			"    entry_id %s (offset %d)\n"+
+			"    reserved %d",
+		f.Command(), f.Len, f.Addr, f.FileOffset, f.EntryID, f.EntryIdOffset, f.Reserved)
 }
 func (l *FilesetEntry) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -2193,7 +2461,17 @@ func (d *Dylib) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (d *Dylib) String() string {
-	return fmt.Sprintf("%s (%s)", d.Name, d.CurrentVersion)
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			"         name %s (offset %d)\n"+
+			"   time stamp %d %s\n"+
+			"      current version %s\n"+
+			"compatibility version %s",
+		d.Command(), d.Len, d.Name, d.NameOffset,
+		d.Timestamp, time.Unix(int64(d.Timestamp), 0).UTC().Format("Mon Jan _2 15:04:05 2006"),
+		otoolVersion(d.CurrentVersion, true),
+		otoolVersion(d.CompatVersion, true))
 }
 func (d *Dylib) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -2245,7 +2523,11 @@ func (d *Dylinker) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (d *Dylinker) String() string {
-	return d.Name
+	return fmt.Sprintf(
+		"          cmd %s\n"+
+			"      cmdsize %d\n"+
+			"         name %s (offset %d)",
+		d.Command(), d.Len, d.Name, d.NameOffset)
 }
 func (d *Dylinker) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -2275,7 +2557,12 @@ func (v *VersionMin) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (v *VersionMin) String() string {
-	return fmt.Sprintf("Version=%s, SDK=%s", v.Version, v.Sdk)
+	return fmt.Sprintf(
+		"      cmd %s\n"+
+			"  cmdsize %d\n"+
+			"  version %s\n"+
+			"      sdk %s",
+		v.Command(), v.Len, otoolVersion(v.Version, false), otoolSDK(v.Sdk))
 }
 func (v *VersionMin) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -2309,7 +2596,7 @@ func (l *LinkEditData) Write(buf *bytes.Buffer, o binary.ByteOrder) error {
 	return nil
 }
 func (l *LinkEditData) String() string {
-	return fmt.Sprintf("offset=0x%09x  size=%#x", l.Offset, l.Size)
+	return otoolLinkEditData(types.LinkEditDataCmd(l.LinkEditDataCmd))
 }
 func (l *LinkEditData) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -0,0 +1,215 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// CoreSegment is a memory region captured in a MH_CORE corefile
+type CoreSegment struct {
+	Name    string
+	Addr    uint64
+	Memsz   uint64
+	Offset  uint64
+	Filesz  uint64
+	Maxprot types.VmProtection
+	Prot    types.VmProtection
+}
+
+func (s CoreSegment) String() string {
+	return fmt.Sprintf("%#016x-%#016x %s/%s off=%#08x filesz=%#x %s",
+		s.Addr, s.Addr+s.Memsz, s.Prot, s.Maxprot, s.Offset, s.Filesz, s.Name)
+}
+
+// CoreThread is a LC_THREAD from a MH_CORE corefile with its register sets decoded
+type CoreThread struct {
+	States []types.ThreadState
+	// Regs holds the decoded register set for each entry in States
+	// (*RegsARM64, *RegsARM, *RegsAMD64 or *Regs386) or nil if the flavor is not supported
+	Regs []any
+}
+
+// PC returns the program counter of the thread's general purpose register set
+func (t CoreThread) PC() (uint64, bool) {
+	for _, regs := range t.Regs {
+		switch r := regs.(type) {
+		case *RegsARM64:
+			return r.PC, true
+		case *RegsAMD64:
+			return r.IP, true
+		case *RegsARM:
+			return uint64(r.PC), true
+		case *Regs386:
+			return uint64(r.IP), true
+		}
+	}
+	return 0, false
+}
+
+// IsCore returns true if the file is a MH_CORE corefile
+func (f *File) IsCore() bool {
+	return f.Type == types.MH_CORE
+}
+
+// CoreSegments returns the memory regions of the corefile sorted by address
+func (f *File) CoreSegments() []CoreSegment {
+	var segs []CoreSegment
+	for _, seg := range f.Segments() {
+		if seg.Memsz == 0 {
+			continue
+		}
+		segs = append(segs, CoreSegment{
+			Name:    seg.Name,
+			Addr:    seg.Addr,
+			Memsz:   seg.Memsz,
+			Offset:  seg.Offset,
+			Filesz:  seg.Filesz,
+			Maxprot: seg.Maxprot,
+			Prot:    seg.Prot,
+		})
+	}
+	sort.Slice(segs, func(i, j int) bool {
+		return segs[i].Addr < segs[j].Addr
+	})
+	return segs
+}
+
+// CoreThreads returns all the LC_THREAD/LC_UNIXTHREAD commands with their register sets decoded
+func (f *File) CoreThreads() ([]CoreThread, error) {
+	var threads []CoreThread
+	for _, l := range f.Loads {
+		var t *Thread
+		switch lc := l.(type) {
+		case *Thread:
+			t = lc
+		case *UnixThread:
+			t = &lc.Thread
+		default:
+			continue
+		}
+		ct := CoreThread{States: t.Threads}
+		for _, state := range t.Threads {
+			regs, err := DecodeThreadState(f.CPU, f.ByteOrder, state)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode %s thread state: %v", t.Command(), err)
+			}
+			ct.Regs = append(ct.Regs, regs)
+		}
+		threads = append(threads, ct)
+	}
+	return threads, nil
+}
+
+// DecodeThreadState decodes the general purpose register set of a thread state for the given CPU
+// NOTE: it returns nil (and no error) for flavors that are not general purpose register sets
+func DecodeThreadState(cpu types.CPU, bo binary.ByteOrder, state types.ThreadState) (any, error) {
+	var regs any
+
+	flavor := state.Flavor
+	data := state.Data
+
+	switch cpu {
+	case types.CPUArm64:
+		if flavor == types.ARM_UNIFIED_THREAD_STATE { // arm_unified_thread_state_t
+			if len(data) < 8 {
+				return nil, fmt.Errorf("thread state too small for arm_state_hdr: %d", len(data))
+			}
+			flavor = types.ThreadFlavor(bo.Uint32(data[0:]))
+			data = data[8:]
+		}
+		switch flavor {
+		case types.ARM_THREAD_STATE64:
+			regs = new(RegsARM64)
+		case types.ARM_THREAD_STATE32:
+			regs = new(RegsARM)
+		}
+	case types.CPUArm:
+		switch flavor {
+		case types.ARM_THREAD_STATE, types.ARM_THREAD_STATE32:
+			regs = new(RegsARM)
+		}
+	case types.CPUAmd64, types.CPUI386:
+		if flavor == types.X86_THREAD_STATE { // x86_thread_state_t
+			if len(data) < 8 {
+				return nil, fmt.Errorf("thread state too small for x86_state_hdr: %d", len(data))
+			}
+			flavor = types.ThreadFlavor(bo.Uint32(data[0:]))
+			data = data[8:]
+		}
+		switch flavor {
+		case types.X86_THREAD_STATE64:
+			regs = new(RegsAMD64)
+		case types.X86_THREAD_STATE32:
+			regs = new(Regs386)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported CPU %s", cpu)
+	}
+
+	if regs == nil {
+		return nil, nil
+	}
+
+	if len(data) < binary.Size(regs) {
+		return nil, fmt.Errorf("thread state data too small for %T: got %d, want %d", regs, len(data), binary.Size(regs))
+	}
+
+	if err := binary.Read(bytes.NewReader(data), bo, regs); err != nil {
+		return nil, fmt.Errorf("failed to read %T: %v", regs, err)
+	}
+
+	return regs, nil
+}
+
+// ReadMemory reads size bytes of the corefile's memory at the given virtual address
+// NOTE: the read may span multiple contiguous segments and any part of a segment beyond its file size reads as zeros
+func (f *File) ReadMemory(addr, size uint64) ([]byte, error) {
+	if size == 0 {
+		return []byte{}, nil
+	}
+	if addr+size < addr {
+		return nil, fmt.Errorf("address range %#x+%#x overflows", addr, size)
+	}
+
+	out := make([]byte, size)
+
+	next := addr
+	end := addr + size
+	for _, seg := range f.CoreSegments() {
+		if next >= end {
+			break
+		}
+		if seg.Addr+seg.Memsz <= next {
+			continue
+		}
+		if seg.Addr > next {
+			return nil, fmt.Errorf("address %#x not within any segment's address range", next)
+		}
+		chunk := end - next
+		if segEnd := seg.Addr + seg.Memsz; segEnd < end {
+			chunk = segEnd - next
+		}
+		segOff := next - seg.Addr
+		if segOff < seg.Filesz {
+			n := chunk
+			if seg.Filesz-segOff < n {
+				n = seg.Filesz - segOff
+			}
+			dst := out[next-addr : next-addr+n]
+			if _, err := f.cr.ReadAt(dst, int64(seg.Offset+segOff)); err != nil {
+				return nil, fmt.Errorf("failed to read segment %s data at offset %#x: %v", seg.Name, seg.Offset+segOff, err)
+			}
+		}
+		next += chunk
+	}
+
+	if next < end {
+		return nil, fmt.Errorf("address %#x not within any segment's address range", next)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,58 @@
+package macho
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// A Diagnostic is an anomaly found while parsing a MachO (e.g. an unknown load command)
+type Diagnostic struct {
+	Offset  int64         // file offset of the load command (or of the anomalous data)
+	Cmd     types.LoadCmd // load command type (the segment command for anomalies in section data)
+	Err     error         // the anomaly (e.g. ErrUnknownLoadCmd or a LoadCmdError recovered in permissive mode)
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s at offset %#x: %s", d.Cmd, d.Offset, d.Message)
+}
+
+var (
+	diagMu      sync.RWMutex
+	diagHandler func(Diagnostic)
+)
+
+// SetDiagnosticHandler sets the package-level handler of the diagnostics of the Files that don't set a
+// FileConfig.DiagnosticHandler (diagnostics are dropped by default)
+func SetDiagnosticHandler(h func(Diagnostic)) {
+	diagMu.Lock()
+	defer diagMu.Unlock()
+	diagHandler = h
+}
+
+// diagnose reports the diagnostic d to the File's (or else the package-level) diagnostic handler
+func (f *File) diagnose(d Diagnostic) {
+	h := f.diagHandler
+	if h == nil {
+		diagMu.RLock()
+		h = diagHandler
+		diagMu.RUnlock()
+	}
+	if h != nil {
+		h(d)
+	}
+}
+
+// diagnoseAddr reports the anomaly err found in the data at the virtual address addr
+func (f *File) diagnoseAddr(addr uint64, err error) {
+	d := Diagnostic{Err: err, Message: err.Error()}
+	if seg := f.FindSegmentForVMAddr(addr); seg != nil {
+		d.Cmd = seg.Command()
+	}
+	if off, oerr := f.GetOffset(addr); oerr == nil {
+		d.Offset = int64(off)
+	}
+	f.diagnose(d)
+}
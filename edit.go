@@ -0,0 +1,426 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/blacktop/go-macho/pkg/codesign"
+	"github.com/blacktop/go-macho/types"
+)
+
+// loadCommandsSpace returns the space available for load commands (the header padding
+// runs from the end of the load commands up to the first section's data)
+func (f *File) loadCommandsSpace() uint64 {
+	end := uint64(0)
+	for _, sect := range f.Sections {
+		if sect.Offset > 0 && !sect.Flags.IsZerofill() && (end == 0 || uint64(sect.Offset) < end) {
+			end = uint64(sect.Offset)
+		}
+	}
+	if end == 0 {
+		if text := f.Segment("__TEXT"); text != nil {
+			end = text.Offset + text.Filesz
+		}
+	}
+	if end < uint64(f.HdrSize()) {
+		return 0
+	}
+	return end - uint64(f.HdrSize())
+}
+
+// growLoadCommands checks the load commands still fit in the header padding after growing by delta bytes
+func (f *File) growLoadCommands(delta int64) error {
+	if delta <= 0 {
+		return nil
+	}
+	if need, space := uint64(int64(f.SizeCommands)+delta), f.loadCommandsSpace(); need > space {
+		return fmt.Errorf("not enough header padding for the load commands (need %#x bytes, have %#x): relink with -headerpad", need, space)
+	}
+	return nil
+}
+
+// sameLoad reports whether a and b are the same load command
+// (unknown load commands are LoadCmdBytes values which can't be compared with ==)
+func sameLoad(a, b Load) bool {
+	ab, aok := a.(LoadCmdBytes)
+	bb, bok := b.(LoadCmdBytes)
+	if aok || bok {
+		return aok && bok && ab.LoadCmd == bb.LoadCmd && len(ab.LoadBytes) == len(bb.LoadBytes) &&
+			(len(ab.LoadBytes) == 0 || &ab.LoadBytes[0] == &bb.LoadBytes[0])
+	}
+	return a == b
+}
+
+// InsertLoad inserts the load command l at index idx of the load commands, updating the
+// header's ncmds and sizeofcmds and checking the load commands still fit in the header padding
+// NOTE: call Save or WriteTo to write out the modified MachO (which will need to be re-signed)
+func (f *File) InsertLoad(idx int, l Load) error {
+	if idx < 0 || idx > len(f.Loads) {
+		return fmt.Errorf("invalid load command index %d (there are %d load commands)", idx, len(f.Loads))
+	}
+	dat, err := f.encodeLoad(l)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %v", l.Command(), err)
+	}
+	if err := f.growLoadCommands(int64(len(dat))); err != nil {
+		return err
+	}
+	f.Loads = append(f.Loads[:idx], append([]Load{l}, f.Loads[idx:]...)...)
+	f.NCommands++
+	f.SizeCommands += uint32(len(dat))
+	return nil
+}
+
+// AddLoad appends the load command l (see InsertLoad)
+func (f *File) AddLoad(l Load) error {
+	return f.InsertLoad(len(f.Loads), l)
+}
+
+// RemoveLoad removes the load command l, updating the header's ncmds and sizeofcmds
+// NOTE: call Save or WriteTo to write out the modified MachO (which will need to be re-signed)
+func (f *File) RemoveLoad(l Load) error {
+	for i, load := range f.Loads {
+		if !sameLoad(load, l) {
+			continue
+		}
+		dat, err := f.encodeLoad(load)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %v", load.Command(), err)
+		}
+		f.Loads = append(f.Loads[:i], f.Loads[i+1:]...)
+		f.NCommands--
+		f.SizeCommands -= uint32(len(dat))
+		return nil
+	}
+	return fmt.Errorf("%s load command not found", l.Command())
+}
+
+func (f *File) setDylibName(d *Dylib, name string) error {
+	hdrSize := uint32(binary.Size(d.DylibCmd))
+	newLen := pointerAlign(hdrSize + uint32(len(name)) + 1)
+	if err := f.growLoadCommands(int64(newLen) - int64(d.Len)); err != nil {
+		return err
+	}
+	f.SizeCommands = f.SizeCommands - d.Len + newLen
+	d.Name = name
+	d.Len = newLen
+	d.NameOffset = hdrSize
+	return nil
+}
+
+// SetDylibID changes the install name of the LC_ID_DYLIB load command (like install_name_tool -id)
+// NOTE: call Save or WriteTo to write out the modified MachO (which will need to be re-signed)
+func (f *File) SetDylibID(name string) error {
+	id := f.DylibID()
+	if id == nil {
+		return fmt.Errorf("no LC_ID_DYLIB found")
+	}
+	return f.setDylibName(&id.Dylib, name)
+}
+
+// ChangeDependency changes the install name of every dependent dylib load command named old to new (like install_name_tool -change)
+// NOTE: call Save or WriteTo to write out the modified MachO (which will need to be re-signed)
+func (f *File) ChangeDependency(old, new string) error {
+	var found bool
+	for _, l := range f.Loads {
+		var d *Dylib
+		switch l := l.(type) {
+		case *LoadDylib:
+			d = &l.Dylib
+		case *WeakDylib:
+			d = &l.Dylib
+		case *ReExportDylib:
+			d = &l.Dylib
+		case *LazyLoadDylib:
+			d = &l.Dylib
+		case *UpwardDylib:
+			d = &l.Dylib
+		default:
+			continue
+		}
+		if d.Name != old {
+			continue
+		}
+		if err := f.setDylibName(d, new); err != nil {
+			return err
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("no dependent dylib named %s found", old)
+	}
+	return nil
+}
+
+// AddRpath adds a LC_RPATH load command (like install_name_tool -add_rpath)
+// NOTE: call Save or WriteTo to write out the modified MachO (which will need to be re-signed)
+func (f *File) AddRpath(path string) error {
+	for _, r := range f.Rpaths() {
+		if r.Path == path {
+			return fmt.Errorf("LC_RPATH %s already exists", path)
+		}
+	}
+	rpath := &Rpath{Path: path}
+	rpath.LoadCmd = types.LC_RPATH
+	rpath.PathOffset = uint32(binary.Size(rpath.RpathCmd))
+	rpath.Len = rpath.LoadSize()
+	return f.AddLoad(rpath)
+}
+
+// RemoveRpath removes the LC_RPATH load command for path (like install_name_tool -delete_rpath)
+// NOTE: call Save or WriteTo to write out the modified MachO (which will need to be re-signed)
+func (f *File) RemoveRpath(path string) error {
+	for _, r := range f.Rpaths() {
+		if r.Path == path {
+			return f.RemoveLoad(r)
+		}
+	}
+	return fmt.Errorf("LC_RPATH %s not found", path)
+}
+
+// InsertDylib appends a LC_LOAD_DYLIB (or LC_LOAD_WEAK_DYLIB if weak) load command for path into the
+// header padding (like insert_dylib). If the MachO is signed it is re-signed with resign (e.g. an ad-hoc
+// codesign.Config); resign may only be nil for unsigned MachOs (use RemoveSignature first to leave it unsigned).
+// NOTE: call Save or WriteTo to write out the modified MachO
+func (f *File) InsertDylib(path string, weak bool, resign *codesign.Config) error {
+	for _, lib := range f.ImportedLibraries() {
+		if lib == path {
+			return fmt.Errorf("dylib %s is already loaded", path)
+		}
+	}
+	if f.CodeSignature() != nil && resign == nil {
+		return fmt.Errorf("inserting a dylib invalidates the code signature: a codesign.Config is required to re-sign it")
+	}
+
+	d := Dylib{Name: path}
+	d.LoadCmd = types.LC_LOAD_DYLIB
+	if weak {
+		d.LoadCmd = types.LC_LOAD_WEAK_DYLIB
+	}
+	d.NameOffset = uint32(binary.Size(d.DylibCmd))
+	d.Timestamp = 2
+	d.Len = d.LoadSize()
+
+	var l Load
+	if weak {
+		l = &WeakDylib{Dylib: d}
+	} else {
+		l = &LoadDylib{Dylib: d}
+	}
+
+	// insert it after the last dylib load command so the existing library ordinals are unchanged
+	idx := len(f.Loads)
+	for i, load := range f.Loads {
+		switch load.(type) {
+		case *LoadDylib, *WeakDylib, *ReExportDylib, *LazyLoadDylib, *UpwardDylib:
+			idx = i + 1
+		}
+	}
+	if err := f.InsertLoad(idx, l); err != nil {
+		return err
+	}
+
+	if f.CodeSignature() != nil {
+		if err := f.CodeSign(resign); err != nil {
+			return fmt.Errorf("failed to re-sign: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (f *File) pageSize() uint64 {
+	if f.has16KPages() {
+		return 0x4000
+	}
+	return 0x1000
+}
+
+// AddSegment adds a new segment containing data (with maxprot and initprot of prot) just before
+// the __LINKEDIT segment, moving __LINKEDIT (and all the blobs in it) up to make room
+// NOTE: call Save or WriteTo to write out the modified MachO (which will need to be re-signed)
+func (f *File) AddSegment(name string, prot types.VmProtection, data []byte) (*Segment, error) {
+	if f.Segment(name) != nil {
+		return nil, fmt.Errorf("segment %s already exists", name)
+	}
+	if len(name) > 16 {
+		return nil, fmt.Errorf("segment name %s is longer than 16 characters", name)
+	}
+	linkedit := f.Segment("__LINKEDIT")
+	if linkedit == nil {
+		return nil, fmt.Errorf("failed to find __LINKEDIT segment")
+	}
+
+	if err := f.cacheLinkedit(); err != nil {
+		return nil, err
+	}
+
+	pageSize := f.pageSize()
+	content := make([]byte, pageAlign(uint64(len(data)), pageSize))
+	copy(content, data)
+
+	seg := &Segment{
+		SegmentHeader: SegmentHeader{
+			LoadCmd: types.LC_SEGMENT,
+			Len:     uint32(binary.Size(types.Segment32{})),
+			Name:    name,
+			Addr:    linkedit.Addr,
+			Memsz:   uint64(len(content)),
+			Offset:  pageAlign(linkedit.Offset, pageSize),
+			Filesz:  uint64(len(content)),
+			Maxprot: prot,
+			Prot:    prot,
+		},
+	}
+	if f.Magic == types.Magic64 {
+		seg.LoadCmd = types.LC_SEGMENT_64
+		seg.Len = uint32(binary.Size(types.Segment64{}))
+	}
+	if seg.Memsz == 0 {
+		seg.Memsz = pageSize
+	}
+	seg.Firstsect = uint32(len(f.Sections))
+	seg.sr = io.NewSectionReader(bytes.NewReader(content), 0, int64(len(content)))
+	seg.ReaderAt = seg.sr
+
+	idx := len(f.Loads)
+	for i, l := range f.Loads {
+		if l == Load(linkedit) {
+			idx = i
+			break
+		}
+	}
+	if err := f.InsertLoad(idx, seg); err != nil {
+		return nil, err
+	}
+	if err := f.moveLinkedit(seg.Offset+seg.Filesz-linkedit.Offset, seg.Memsz); err != nil {
+		return nil, err
+	}
+	if f.segdata == nil {
+		f.segdata = make(map[string][]byte)
+	}
+	f.segdata[name] = content
+
+	return seg, nil
+}
+
+// AddSection adds a new section containing data to segment seg, which must be the last
+// segment before __LINKEDIT (the segment is created if it doesn't exist). The segment is
+// grown to make room, moving __LINKEDIT (and all the blobs in it) up.
+// NOTE: call Save or WriteTo to write out the modified MachO (which will need to be re-signed)
+func (f *File) AddSection(segname, sectname string, data []byte, flags types.SectionFlag) (*types.Section, error) {
+	if f.Section(segname, sectname) != nil {
+		return nil, fmt.Errorf("section %s.%s already exists", segname, sectname)
+	}
+	if len(sectname) > 16 {
+		return nil, fmt.Errorf("section name %s is longer than 16 characters", sectname)
+	}
+	linkedit := f.Segment("__LINKEDIT")
+	if linkedit == nil {
+		return nil, fmt.Errorf("failed to find __LINKEDIT segment")
+	}
+
+	seg := f.Segment(segname)
+	if seg == nil {
+		var err error
+		if seg, err = f.AddSegment(segname, 3, nil); err != nil { // rw-
+			return nil, err
+		}
+	}
+	if seg.Addr+seg.Memsz != linkedit.Addr || seg.Offset+seg.Filesz != linkedit.Offset {
+		return nil, fmt.Errorf("segment %s must be the last segment before __LINKEDIT", segname)
+	}
+
+	sectSize := uint32(binary.Size(types.Section32{}))
+	if seg.Command() == types.LC_SEGMENT_64 {
+		sectSize = uint32(binary.Size(types.Section64{}))
+	}
+	if err := f.growLoadCommands(int64(sectSize)); err != nil {
+		return nil, err
+	}
+	if err := f.cacheLinkedit(); err != nil {
+		return nil, err
+	}
+
+	content, ok := f.segdata[segname]
+	if !ok {
+		content = make([]byte, seg.Filesz)
+		if _, err := f.vmr.ReadAtVMAddr(content, seg.Addr); err != nil {
+			return nil, fmt.Errorf("failed to read segment %s data: %v", segname, err)
+		}
+	}
+
+	// place the new section after the segment's existing data and sections
+	align := uint32(3)
+	vmoff := uint64(len(content))
+	if len(seg.sections) > 0 {
+		vmoff = 0
+		for _, sect := range seg.sections {
+			if end := sect.Addr + sect.Size - seg.Addr; end > vmoff {
+				vmoff = end
+			}
+		}
+	}
+	vmoff = pageAlign(vmoff, 1<<align)
+
+	sect := &types.Section{SectionHeader: types.SectionHeader{
+		Name:  sectname,
+		Seg:   segname,
+		Addr:  seg.Addr + vmoff,
+		Size:  uint64(len(data)),
+		Align: align,
+		Flags: flags,
+		Type:  32,
+	}}
+	if seg.Command() == types.LC_SEGMENT_64 {
+		sect.Type = 64
+	}
+	sect.SetReaders(bytes.NewReader(data), io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data))))
+
+	pageSize := f.pageSize()
+	filesz := seg.Filesz
+	if !flags.IsZerofill() {
+		sect.Offset = uint32(seg.Offset + vmoff)
+		filesz = pageAlign(vmoff+uint64(len(data)), pageSize)
+		grown := make([]byte, filesz)
+		copy(grown, content)
+		copy(grown[vmoff:], data)
+		content = grown
+	}
+	memsz := seg.Memsz
+	if end := pageAlign(vmoff+uint64(len(data)), pageSize); end > memsz {
+		memsz = end
+	}
+
+	if err := f.moveLinkedit(filesz-seg.Filesz, memsz-seg.Memsz); err != nil {
+		return nil, err
+	}
+	seg.Filesz = filesz
+	seg.Memsz = memsz
+	seg.sr = io.NewSectionReader(bytes.NewReader(content), 0, int64(len(content)))
+	seg.ReaderAt = seg.sr
+	if f.segdata == nil {
+		f.segdata = make(map[string][]byte)
+	}
+	f.segdata[segname] = content
+
+	// keep the sections in load command order (section ordinals)
+	idx := int(seg.Firstsect + seg.Nsect)
+	if idx > len(f.Sections) {
+		idx = len(f.Sections)
+	}
+	f.Sections = append(f.Sections[:idx], append([]*types.Section{sect}, f.Sections[idx:]...)...)
+	for _, s := range f.Segments() {
+		if s != seg && s.Firstsect >= uint32(idx) && s.Nsect > 0 {
+			s.Firstsect++
+		}
+	}
+	seg.sections = append(seg.sections, sect)
+	seg.Nsect++
+	seg.Len += sectSize
+	f.SizeCommands += sectSize
+
+	return sect, nil
+}
@@ -3,7 +3,9 @@ package macho
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -137,7 +139,7 @@ func (f *File) Export(path string, dcf *fixupchains.DyldChainedFixups, baseAddre
 			switch seg.Name {
 			case "__TEXT":
 				dat := make([]byte, seg.Filesz)
-				if _, err := f.cr.ReadAtAddr(dat, seg.Addr); err != nil {
+				if _, err := f.vmr.ReadAtVMAddr(dat, seg.Addr); err != nil {
 					return fmt.Errorf("failed to read segment %s data: %v", seg.Name, err)
 				}
 				if _, err := buf.Write(dat[endOfLoadsOffset:]); err != nil {
@@ -151,7 +153,7 @@ func (f *File) Export(path string, dcf *fixupchains.DyldChainedFixups, baseAddre
 					}
 				} else {
 					dat := make([]byte, seg.Filesz)
-					if _, err := f.cr.ReadAtAddr(dat, seg.Addr); err != nil {
+					if _, err := f.vmr.ReadAtVMAddr(dat, seg.Addr); err != nil {
 						return fmt.Errorf("failed to read segment %s data: %v", seg.Name, err)
 					}
 					if _, err := buf.Write(dat); err != nil {
@@ -160,7 +162,7 @@ func (f *File) Export(path string, dcf *fixupchains.DyldChainedFixups, baseAddre
 				}
 			default:
 				dat := make([]byte, seg.Filesz)
-				if _, err := f.cr.ReadAtAddr(dat, seg.Addr); err != nil {
+				if _, err := f.vmr.ReadAtVMAddr(dat, seg.Addr); err != nil {
 					return fmt.Errorf("failed to read segment %s data: %v", seg.Name, err)
 				}
 				if _, err := buf.Write(dat); err != nil {
@@ -317,7 +319,9 @@ func (f *File) CodeSign(config *codesign.Config) error {
 		}
 		cs.Offset = pointerAlign(uint32(linkedit.Offset + linkedit.Filesz))
 		// add NEW codesignature load command
-		f.AddLoad(cs)
+		if err := f.AddLoad(cs); err != nil {
+			return fmt.Errorf("failed to add LC_CODE_SIGNATURE: %v", err)
+		}
 		// refresh
 		cs = f.CodeSignature()
 	}
@@ -325,10 +329,12 @@ func (f *File) CodeSign(config *codesign.Config) error {
 	config.CodeSize = uint64(cs.Offset)
 
 	// cache __LINKEDIT data (up to but not including any existing code signature) for saving later
-	ledata := make([]byte, uint64(cs.Offset) - linkedit.Offset)
-	if _, err := f.cr.ReadAtAddr(ledata, linkedit.Addr); err != nil {
-		return fmt.Errorf("failed to read __LINKEDIT data: %v", err)
+	dat, err := f.linkeditData()
+	if err != nil {
+		return err
 	}
+	ledata := make([]byte, uint64(cs.Offset)-linkedit.Offset)
+	copy(ledata, dat)
 	f.ledata = bytes.NewBuffer(ledata)
 
 	// update __LINKEDIT segment sizes
@@ -337,20 +343,15 @@ func (f *File) CodeSign(config *codesign.Config) error {
 	// update LC_CODE_SIGNATURE size
 	cs.Size = uint32((linkedit.Offset + linkedit.Filesz) - uint64(cs.Offset))
 
-	// read data to be signed
-	data := make([]byte, cs.Offset)
-	if _, err := f.ReadAt(data, 0); err != nil {
-		return fmt.Errorf("failed to read codesign data: %v", err)
-	}
-	// write modified file header and load commands (including __LINKEDIT and CodeSignature), since they are covered by hashes
-	var buf bytes.Buffer
-	if err := f.FileHeader.Write(&buf, f.ByteOrder); err != nil {
-		return fmt.Errorf("failed to write updated header: %v", err)
+	// data to be signed (including the modified file header and load commands, since they are covered by hashes)
+	data, err := f.serialize()
+	if err != nil {
+		return fmt.Errorf("failed to serialize codesign data: %v", err)
 	}
-	if err := f.writeLoadCommands(&buf); err != nil {
-		return fmt.Errorf("failed to write updated load commands: %v", err)
+	if uint64(len(data)) < uint64(cs.Offset) {
+		return fmt.Errorf("codesign data is smaller than the code signature offset %#x", cs.Offset)
 	}
-	copy(data, buf.Bytes())
+	data = data[:cs.Offset]
 
 	// sign data and add it to the new LINKEDIT segment
 	csdata, err := codesign.Sign(bytes.NewReader(data), config)
@@ -372,57 +373,195 @@ func (f *File) CodeSign(config *codesign.Config) error {
 	return nil
 }
 
-func (f *File) Save(outpath string) error {
-	var buf bytes.Buffer
+// RemoveSignature removes the LC_CODE_SIGNATURE load command and its data from the end of
+// the __LINKEDIT segment (call Save to write out the unsigned MachO)
+func (f *File) RemoveSignature() error {
+	cs := f.CodeSignature()
+	if cs == nil {
+		return fmt.Errorf("no LC_CODE_SIGNATURE found")
+	}
 
-	if err := f.FileHeader.Write(&buf, f.ByteOrder); err != nil {
-		return fmt.Errorf("failed to write file header to buffer: %v", err)
+	linkedit := f.Segment("__LINKEDIT")
+	if linkedit == nil {
+		return fmt.Errorf("failed to find __LINKEDIT segment")
+	}
+	if uint64(cs.Offset) < linkedit.Offset || uint64(cs.Offset)+uint64(cs.Size) > linkedit.Offset+linkedit.Filesz {
+		return fmt.Errorf("code signature (offset=%#x, size=%#x) is not within the __LINKEDIT segment", cs.Offset, cs.Size)
+	}
+	// truncating __LINKEDIT at the signature would drop anything that follows it
+	if uint64(cs.Offset)+uint64(cs.Size) != linkedit.Offset+linkedit.Filesz {
+		return fmt.Errorf("code signature (offset=%#x, size=%#x) is not at the end of the __LINKEDIT segment (ends at %#x)",
+			cs.Offset, cs.Size, linkedit.Offset+linkedit.Filesz)
 	}
 
-	if err := f.writeLoadCommands(&buf); err != nil {
-		return fmt.Errorf("failed to write load commands: %v", err)
+	// cache __LINKEDIT data (up to but not including the code signature) for saving later
+	dat, err := f.linkeditData()
+	if err != nil {
+		return err
 	}
+	ledata := make([]byte, uint64(cs.Offset)-linkedit.Offset)
+	copy(ledata, dat)
+	f.ledata = bytes.NewBuffer(ledata)
 
-	endOfLoadsOffset := uint64(buf.Len())
+	// update __LINKEDIT segment sizes
+	pageSize := uint64(0x1000)
+	if f.has16KPages() {
+		pageSize = 0x4000
+	}
+	linkedit.Filesz = uint64(len(ledata))
+	linkedit.Memsz = pageAlign(linkedit.Filesz, pageSize)
 
-	// Write out segment data to buffer
-	for _, seg := range f.Segments() {
-		if seg.Filesz > 0 {
-			switch seg.Name {
-			case "__TEXT":
-				dat := make([]byte, seg.Filesz)
-				if _, err := f.cr.ReadAtAddr(dat, seg.Addr); err != nil {
-					return fmt.Errorf("failed to read segment %s data: %v", seg.Name, err)
-				}
-				if _, err := buf.Write(dat[endOfLoadsOffset:]); err != nil {
-					return fmt.Errorf("failed to write segment %s to export buffer: %v", seg.Name, err)
-				}
-			case "__LINKEDIT":
-				if f.ledata != nil && f.ledata.Len() > 0 && f.CodeSignature() != nil {
-					if _, err := buf.Write(f.ledata.Bytes()); err != nil {
-						return fmt.Errorf("failed to write segment %s to export buffer: %v", seg.Name, err)
-					}
-				} else {
-					dat := make([]byte, seg.Filesz)
-					if _, err := f.cr.ReadAtAddr(dat, seg.Addr); err != nil {
-						return fmt.Errorf("failed to read segment %s data: %v", seg.Name, err)
-					}
-					if _, err := buf.Write(dat); err != nil {
-						return fmt.Errorf("failed to write segment %s to export buffer: %v", seg.Name, err)
+	return f.RemoveLoad(cs)
+}
+
+// dataEnd returns the end file offset of all the data referenced by the header, load commands and segments
+func (f *File) dataEnd(loads []byte) uint64 {
+	end := uint64(f.HdrSize()) + uint64(len(loads))
+	extend := func(off, size uint64) {
+		if size > 0 && off+size > end {
+			end = off + size
+		}
+	}
+
+	for _, l := range f.Loads {
+		switch l := l.(type) {
+		case *Segment:
+			extend(l.Offset, l.Filesz)
+			for _, sect := range f.Sections {
+				if sect.Seg == l.Name {
+					if sect.Offset > 0 && !sect.Flags.IsZerofill() {
+						extend(uint64(sect.Offset), sect.Size)
 					}
+					extend(uint64(sect.Reloff), uint64(sect.Nreloc)*8)
 				}
-			default:
-				dat := make([]byte, seg.Filesz)
-				if _, err := f.cr.ReadAtAddr(dat, seg.Addr); err != nil {
-					return fmt.Errorf("failed to read segment %s data: %v", seg.Name, err)
+			}
+		case *Symtab:
+			nlistSize := uint64(binary.Size(types.Nlist32{}))
+			if f.Magic == types.Magic64 {
+				nlistSize = uint64(binary.Size(types.Nlist64{}))
+			}
+			extend(uint64(l.Symoff), uint64(l.Nsyms)*nlistSize)
+			extend(uint64(l.Stroff), uint64(l.Strsize))
+		case *Dysymtab:
+			extend(uint64(l.Tocoffset), uint64(l.Ntoc)*8)
+			if f.Magic == types.Magic64 {
+				extend(uint64(l.Modtaboff), uint64(l.Nmodtab)*56)
+			} else {
+				extend(uint64(l.Modtaboff), uint64(l.Nmodtab)*52)
+			}
+			extend(uint64(l.Extrefsymoff), uint64(l.Nextrefsyms)*4)
+			extend(uint64(l.Indirectsymoff), uint64(l.Nindirectsyms)*4)
+			extend(uint64(l.Extreloff), uint64(l.Nextrel)*8)
+			extend(uint64(l.Locreloff), uint64(l.Nlocrel)*8)
+		default:
+			var buf bytes.Buffer
+			if err := l.Write(&buf, f.ByteOrder); err != nil {
+				continue
+			}
+			dat := buf.Bytes()
+			switch l.Command() {
+			case types.LC_CODE_SIGNATURE, types.LC_SEGMENT_SPLIT_INFO, types.LC_FUNCTION_STARTS,
+				types.LC_DATA_IN_CODE, types.LC_DYLIB_CODE_SIGN_DRS, types.LC_LINKER_OPTIMIZATION_HINT,
+				types.LC_DYLD_EXPORTS_TRIE, types.LC_DYLD_CHAINED_FIXUPS, types.LC_ATOM_INFO:
+				if len(dat) >= 16 {
+					extend(uint64(f.ByteOrder.Uint32(dat[8:])), uint64(f.ByteOrder.Uint32(dat[12:])))
 				}
-				if _, err := buf.Write(dat); err != nil {
-					return fmt.Errorf("failed to write segment %s to export buffer: %v", seg.Name, err)
+			case types.LC_DYLD_INFO, types.LC_DYLD_INFO_ONLY:
+				for i := 8; i+8 <= len(dat) && i < 48; i += 8 { // rebase, bind, weak bind, lazy bind and export
+					extend(uint64(f.ByteOrder.Uint32(dat[i:])), uint64(f.ByteOrder.Uint32(dat[i+4:])))
 				}
 			}
 		}
 	}
 
+	return end
+}
+
+// WriteTo writes the MachO to w (re-encoding the header and load commands and copying the
+// segment data, including any __LINKEDIT modifications, to their file offsets)
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	out, err := f.serialize()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(out)
+	return int64(n), err
+}
+
+func (f *File) serialize() ([]byte, error) {
+	var loads bytes.Buffer
+	if err := f.writeLoadCommands(&loads); err != nil {
+		return nil, fmt.Errorf("failed to write load commands: %v", err)
+	}
+
+	var linkedit *Segment
+	if f.ledata != nil && f.ledata.Len() > 0 {
+		if linkedit = f.Segment("__LINKEDIT"); linkedit == nil {
+			return nil, fmt.Errorf("failed to find __LINKEDIT segment")
+		}
+	}
+
+	end := f.dataEnd(loads.Bytes())
+	if linkedit != nil && linkedit.Offset+uint64(f.ledata.Len()) > end {
+		end = linkedit.Offset + uint64(f.ledata.Len())
+	}
+
+	out := make([]byte, end)
+	if n, err := f.sr.ReadAt(out, 0); err != nil && !(errors.Is(err, io.EOF) && n > 0) {
+		return nil, fmt.Errorf("failed to read MachO data: %v", err)
+	}
+
+	// modified segment data first (__TEXT's data includes the header and load commands)
+	for _, seg := range f.Segments() {
+		if dat, ok := f.segdata[seg.Name]; ok && seg.Filesz > 0 {
+			copy(out[seg.Offset:], dat)
+		}
+	}
+
+	// clear any stale load commands between the new end of the load commands and the first section
+	lcEnd := uint64(f.HdrSize()) + uint64(loads.Len())
+	gapEnd := end
+	for _, sect := range f.Sections {
+		if sect.Offset > 0 && !sect.Flags.IsZerofill() && uint64(sect.Offset) < gapEnd {
+			gapEnd = uint64(sect.Offset)
+		}
+	}
+	if len(f.Sections) == 0 {
+		gapEnd = lcEnd
+	}
+	if lcEnd > gapEnd {
+		return nil, fmt.Errorf("load commands (%#x bytes) overlap the first section at offset %#x", loads.Len(), gapEnd)
+	}
+	for i := lcEnd; i < gapEnd; i++ {
+		out[i] = 0
+	}
+
+	fh := f.FileHeader
+	fh.NCommands = uint32(len(f.Loads))
+	fh.SizeCommands = uint32(loads.Len())
+
+	var hdr bytes.Buffer
+	if err := fh.Write(&hdr, f.ByteOrder); err != nil {
+		return nil, fmt.Errorf("failed to write file header to buffer: %v", err)
+	}
+	copy(out, hdr.Bytes()[:f.HdrSize()]) // 32-bit headers don't have the reserved field
+	copy(out[f.HdrSize():], loads.Bytes())
+
+	if linkedit != nil {
+		copy(out[linkedit.Offset:], f.ledata.Bytes())
+	}
+
+	return out, nil
+}
+
+// Save writes the MachO to the file outpath
+func (f *File) Save(outpath string) error {
+	var buf bytes.Buffer
+
+	if _, err := f.WriteTo(&buf); err != nil {
+		return err
+	}
+
 	os.MkdirAll(filepath.Dir(outpath), os.ModePerm)
 
 	if err := os.WriteFile(outpath, buf.Bytes(), 0755); err != nil {
@@ -770,6 +909,9 @@ func (f *File) optimizeLinkedit(locals []Symbol) (*bytes.Buffer, error) {
 	if linkedit == nil {
 		return nil, fmt.Errorf("unable to find __LINKEDIT segment")
 	}
+	if _, err := f.IndirectSymbols(); err != nil {
+		return nil, err
+	}
 
 	// TODO: LC_DYLD_CHAINED_FIXUPS
 
@@ -787,7 +929,7 @@ func (f *File) optimizeLinkedit(locals []Symbol) (*bytes.Buffer, error) {
 		if _, err := lebuf.Write(dat); err != nil {
 			return nil, fmt.Errorf("failed to write LC_DYLD_EXPORTS_TRIE data: %v", err)
 		}
-		pad := linkedit.Offset + (uint64(lebuf.Len()) % f.pointerSize())
+		pad := pageAlign(uint64(lebuf.Len()), f.pointerSize()) - uint64(lebuf.Len())
 		if _, err := lebuf.Write(make([]byte, pad)); err != nil {
 			return nil, fmt.Errorf("failed to write LC_DYLD_EXPORTS_TRIE padding: %v", err)
 		}
@@ -802,7 +944,7 @@ func (f *File) optimizeLinkedit(locals []Symbol) (*bytes.Buffer, error) {
 		if _, err := lebuf.Write(dat); err != nil {
 			return nil, fmt.Errorf("failed to write LC_DATA_IN_CODE data: %v", err)
 		}
-		pad := linkedit.Offset + (uint64(lebuf.Len()) % f.pointerSize())
+		pad := pageAlign(uint64(lebuf.Len()), f.pointerSize()) - uint64(lebuf.Len())
 		if _, err := lebuf.Write(make([]byte, pad)); err != nil {
 			return nil, fmt.Errorf("failed to write LC_DATA_IN_CODE padding: %v", err)
 		}
@@ -822,7 +964,7 @@ func (f *File) optimizeLinkedit(locals []Symbol) (*bytes.Buffer, error) {
 		if _, err := lebuf.Write(dat); err != nil {
 			return nil, fmt.Errorf("failed to write LC_FUNCTION_STARTS data: %v", err)
 		}
-		pad := linkedit.Offset + (uint64(lebuf.Len()) % f.pointerSize())
+		pad := pageAlign(uint64(lebuf.Len()), f.pointerSize()) - uint64(lebuf.Len())
 		if _, err := lebuf.Write(make([]byte, pad)); err != nil {
 			return nil, fmt.Errorf("failed to write LC_FUNCTION_STARTS padding: %v", err)
 		}
@@ -931,7 +1073,7 @@ func (f *File) optimizeLinkedit(locals []Symbol) (*bytes.Buffer, error) {
 				return nil, fmt.Errorf("failed to write %s export data: %v", dionly.LoadCmd, err)
 			}
 		}
-		pad := linkedit.Offset + (uint64(lebuf.Len()) % f.pointerSize())
+		pad := pageAlign(uint64(lebuf.Len()), f.pointerSize()) - uint64(lebuf.Len())
 		if _, err := lebuf.Write(make([]byte, pad)); err != nil {
 			return nil, fmt.Errorf("failed to write LC_DYLD_INFO|LC_DYLD_INFO_ONLY padding: %v", err)
 		}
@@ -964,7 +1106,7 @@ func (f *File) optimizeLinkedit(locals []Symbol) (*bytes.Buffer, error) {
 	// now start copying symbol table from start of externs instead of start of locals
 	// for _, sym := range f.Symtab.Syms[f.Dysymtab.Iextdefsym:] {
 	if f.Symtab != nil {
-		for _, sym := range f.Symtab.Syms {
+		for _, sym := range f.symbols() {
 			if sym.Name == "<redacted>" {
 				continue
 			}
@@ -1013,7 +1155,7 @@ func (f *File) optimizeLinkedit(locals []Symbol) (*bytes.Buffer, error) {
 		}
 	}
 
-	pad := linkedit.Offset + (uint64(lebuf.Len()) % f.pointerSize())
+	pad := pageAlign(uint64(lebuf.Len()), f.pointerSize()) - uint64(lebuf.Len())
 	if _, err := lebuf.Write(make([]byte, pad)); err != nil {
 		return nil, fmt.Errorf("failed to write symtab padding: %v", err)
 	}
@@ -1034,7 +1176,7 @@ func (f *File) optimizeLinkedit(locals []Symbol) (*bytes.Buffer, error) {
 		return nil, fmt.Errorf("failed to write indirect symbol table to NEW linkedit data: %v", err)
 	}
 
-	pad = linkedit.Offset + (uint64(lebuf.Len()) % f.pointerSize())
+	pad = pageAlign(uint64(lebuf.Len()), f.pointerSize()) - uint64(lebuf.Len())
 	if _, err := lebuf.Write(make([]byte, pad)); err != nil {
 		return nil, fmt.Errorf("failed to write indirect symtab padding: %v", err)
 	}
@@ -1078,26 +1220,74 @@ func (f *File) optimizeLinkedit(locals []Symbol) (*bytes.Buffer, error) {
 	return &lebuf, nil
 }
 
-func (f *File) writeLoadCommands(buf *bytes.Buffer) error {
-	for _, l := range f.Loads {
-		switch l.Command() {
-		case types.LC_SEGMENT:
-			fallthrough
-		case types.LC_SEGMENT_64:
-			seg := l.(*Segment)
-			if err := seg.Write(buf, f.ByteOrder); err != nil {
-				return err
+// encodeLoad returns the encoded load command l (agreeing with its cmdsize)
+func (f *File) encodeLoad(l Load) ([]byte, error) {
+	var lbuf bytes.Buffer
+	switch l.Command() {
+	case types.LC_SEGMENT:
+		fallthrough
+	case types.LC_SEGMENT_64:
+		seg := l.(*Segment)
+		if err := seg.Write(&lbuf, f.ByteOrder); err != nil {
+			return nil, err
+		}
+		for _, sect := range seg.sections {
+			if err := f.Section(sect.Seg, sect.Name).Write(&lbuf, f.ByteOrder); err != nil {
+				return nil, err
 			}
-			for _, sect := range seg.sections {
-				if err := f.Section(sect.Seg, sect.Name).Write(buf, f.ByteOrder); err != nil {
-					return err
-				}
+		}
+	default:
+		if err := l.Write(&lbuf, f.ByteOrder); err != nil {
+			return nil, err
+		}
+	}
+	// make the encoded load command agree with its cmdsize
+	// (encoders pad to 8 bytes, but 32-bit MachOs only pad load commands to 4 bytes)
+	dat := lbuf.Bytes()
+	if len(dat) >= 8 {
+		cmdsize := uint64(f.ByteOrder.Uint32(dat[4:]))
+		switch {
+		case uint64(len(dat)) < cmdsize:
+			dat = append(dat, make([]byte, cmdsize-uint64(len(dat)))...)
+		case uint64(len(dat)) > cmdsize:
+			if bytes.Count(dat[cmdsize:], []byte{0}) == len(dat[cmdsize:]) {
+				dat = dat[:cmdsize] // only padding
+			} else { // the load command grew
+				f.ByteOrder.PutUint32(dat[4:], uint32(len(dat)))
 			}
-		default:
-			if err := l.Write(buf, f.ByteOrder); err != nil {
-				return err
+		}
+	}
+	return dat, nil
+}
+
+// snapshotLoads records the encoding of the parsed load commands so writeLoadCommands can tell which were modified
+func (f *File) snapshotLoads() {
+	f.loadenc = make(map[Load][]byte, len(f.Loads))
+	for _, l := range f.Loads {
+		if _, ok := l.(LoadCmdBytes); ok {
+			continue // written from its raw bytes anyway
+		}
+		if dat, err := f.encodeLoad(l); err == nil {
+			f.loadenc[l] = dat
+		}
+	}
+}
+
+// writeLoadCommands writes the load commands to buf; unmodified ones are copied from their raw bytes
+func (f *File) writeLoadCommands(buf *bytes.Buffer) error {
+	for _, l := range f.Loads {
+		dat, err := f.encodeLoad(l)
+		if err != nil {
+			return err
+		}
+		if _, ok := l.(LoadCmdBytes); !ok {
+			if orig, ok := f.loadenc[l]; ok && bytes.Equal(dat, orig) && len(l.Raw()) == len(dat) {
+				dat = l.Raw()
 			}
 		}
+		if _, err := buf.Write(dat); err != nil {
+			return fmt.Errorf("failed to write %s to load commands buffer: %v", l.Command(), err)
+		}
 	}
 	return nil
 }
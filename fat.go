@@ -41,6 +41,11 @@ type FatArchHeader struct {
 	Align  uint32
 }
 
+// Architecture returns the Apple architecture name of the slice (i.e. arm64e or x86_64)
+func (h *FatArchHeader) Architecture() string {
+	return h.SubCPU.ArchName(h.CPU)
+}
+
 const fatArchHeaderSize = 5 * 4
 
 // A FatArch is a Mach-O File inside a FatFile.
@@ -6,13 +6,15 @@ import (
 	"bufio"
 	"bytes"
 	"compress/zlib"
+	"debug/gosym"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -20,8 +22,11 @@ import (
 
 	"github.com/blacktop/go-macho/internal/saferio"
 	"github.com/blacktop/go-macho/pkg/codesign"
+	ctypes "github.com/blacktop/go-macho/pkg/codesign/types"
+	"github.com/blacktop/go-macho/pkg/demangle"
 	"github.com/blacktop/go-macho/pkg/fixupchains"
 	"github.com/blacktop/go-macho/pkg/trie"
+	"github.com/blacktop/go-macho/pkg/unwind"
 	"github.com/blacktop/go-macho/pkg/xar"
 	"github.com/blacktop/go-macho/types"
 )
@@ -38,15 +43,36 @@ type File struct {
 	exp         []trie.TrieExport
 	exptrieData []byte
 	binds       types.Binds
+	lines       *lineIndex                       // sorted DWARF line tables (see SourceLine)
+	gotab       *gosym.Table                     // Go pclntab symbols (see GoSymbols)
+	addrs       *addrIndex                       // segments and sections sorted by address
+	syms        *symbolIndex                     // symbols by name and sorted by address
+	fixups      map[uint64]fixupchains.DCPtrKind // chained fixup file offsets and their segment's pointer format
 	objc        map[uint64]any
 	swift       map[uint64]any
-	ledata      *bytes.Buffer // tmp storage of linkedit data
+	ledata      *bytes.Buffer     // tmp storage of linkedit data
+	segdata     map[string][]byte // tmp storage of added/modified segment data
+	loadenc     map[Load][]byte   // encoding of the load commands as parsed (see writeLoadCommands)
 
 	sharedCacheRelativeSelectorBaseVMAddress uint64 // objc_opt version 16
+	demangleSymbols                          bool
+	inMemory                                 bool // the image is mapped in memory (see NewFileFromMemory)
+	lazy                                     bool // the tables below are read on first access (see FileConfig.Lazy)
+	lazySymtab                               bool
+	lazyIndirectSyms                         bool
+	lazyDysymRelocs                          bool
+	lazyDylibTables                          bool
+	lazyMu                                   sync.Mutex
+	sectionCache                             *sectionCache // see FileConfig.SectionCacheSize
+	warnings                                 []*LoadCmdError
+	diagHandler                              func(Diagnostic)
+	limits                                   Limits
+	size                                     int64 // file size (-1 if unknown)
 
 	mu     sync.Mutex
 	sr     types.MachoReader
 	cr     types.MachoReader
+	vmr    types.VMReader
 	closer io.Closer
 }
 
@@ -56,6 +82,8 @@ type File struct {
 
 var ErrMachOSectionNotFound = errors.New("MachO missing required section")
 var ErrMachODyldInfoNotFound = errors.New("LC_DYLD_INFO(_ONLY) not found")
+var ErrIndirectSymbolLocal = errors.New("indirect symbol is INDIRECT_SYMBOL_LOCAL or INDIRECT_SYMBOL_ABS")
+var ErrUnknownLoadCmd = errors.New("unknown load command")
 
 // FormatError is returned by some operations if the data does
 // not have the correct format for an object file.
@@ -74,6 +102,21 @@ func (e *FormatError) Error() string {
 	return msg
 }
 
+// LoadCmdError is returned by NewFile for a load command that failed to parse (or recorded as a warning
+// in permissive mode, see File.ParseWarnings)
+type LoadCmdError struct {
+	Index  int           // index of the load command
+	Offset int64         // file offset of the load command
+	Cmd    types.LoadCmd // load command type
+	Err    error
+}
+
+func (e *LoadCmdError) Error() string {
+	return fmt.Sprintf("failed to parse load command %d (%s) at offset %#x: %v", e.Index, e.Cmd, e.Offset, e.Err)
+}
+
+func (e *LoadCmdError) Unwrap() error { return e.Err }
+
 func loadInSlice(c types.LoadCmd, list []types.LoadCmd) bool {
 	for _, b := range list {
 		if b == c {
@@ -92,6 +135,14 @@ type FileConfig struct {
 	SectionReader        types.MachoReader
 	CacheReader          types.MachoReader
 	RelativeSelectorBase uint64
+	DemangleSymbols      bool             // demangle Swift and C++ symbol names (see Symbol.Demangled)
+	Lazy                 bool             // defer reading the symbols, indirect symbols and relocations until they are first accessed (see File.Symbols and File.ExternalRelocations)
+	NoSections           bool             // don't parse the section headers of the segments (see WithoutSections)
+	NoSymbols            bool             // don't read the symbols of the LC_SYMTAB (see WithoutSymbols)
+	SectionCacheSize     int              // cache the data of the last SectionCacheSize sections read (see WithSectionCache)
+	Permissive           bool             // record the load commands that fail to parse as warnings and keep going (see File.ParseWarnings)
+	DiagnosticHandler    func(Diagnostic) // report the parsing anomalies to this handler (see SetDiagnosticHandler)
+	Limits               Limits           // cap the sizes of the tables read (see WithLimits)
 }
 
 // Open opens the named file using os.Open and prepares it for use as a Mach-O binary.
@@ -109,6 +160,24 @@ func Open(name string) (*File, error) {
 	return ff, nil
 }
 
+// MarshalJSON returns the JSON of the MachO header, load commands (including the sections and their relocations)
+// and symbols
+func (f *File) MarshalJSON() ([]byte, error) {
+	var syms []Symbol
+	if f.Symtab != nil {
+		syms = f.symbols()
+	}
+	return json.Marshal(&struct {
+		Header  types.FileHeader `json:"header"`
+		Loads   loads            `json:"loads"`
+		Symbols []Symbol         `json:"symbols,omitempty"`
+	}{
+		Header:  f.FileHeader,
+		Loads:   f.Loads,
+		Symbols: syms,
+	})
+}
+
 // Close closes the File.
 // If the File was created using NewFile directly instead of Open,
 // Close has no effect.
@@ -123,28 +192,26 @@ func (f *File) Close() error {
 
 // NewFile creates a new File for accessing a Mach-O binary in an underlying reader.
 // The Mach-O binary is expected to start at position 0 in the ReaderAt.
-func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
-	var loadIncluding []types.LoadCmd
-	var loadExcluding []types.LoadCmd
+// The opts (a FileConfig or functional options like WithoutSymbols) tune what the parser does.
+func NewFile(r io.ReaderAt, opts ...Option) (*File, error) {
+	var config FileConfig
+	for _, opt := range opts {
+		opt.apply(&config)
+	}
 
 	f := new(File)
 
 	f.objc = make(map[uint64]any)
 	f.swift = make(map[uint64]any)
 
-	if config != nil {
-		if config[0].SectionReader != nil {
-			f.sr = config[0].SectionReader
-			f.sr.Seek(config[0].Offset, io.SeekStart)
-			f.cr = f.sr
-		}
-		if config[0].CacheReader != nil {
-			f.cr = config[0].CacheReader
+	if config.SectionReader != nil {
+		f.sr = config.SectionReader
+		f.sr.Seek(config.Offset, io.SeekStart)
+		f.cr = f.sr
+		if config.CacheReader != nil {
+			f.cr = config.CacheReader
 		}
-		f.vma = &config[0].VMAddrConverter
-		loadIncluding = config[0].LoadIncluding
-		loadExcluding = config[0].LoadExcluding
-		f.sharedCacheRelativeSelectorBaseVMAddress = config[0].RelativeSelectorBase
+		f.vma = &config.VMAddrConverter
 	} else {
 		f.vma = &types.VMAddrConverter{
 			Converter:    f.convertToVMAddr,
@@ -154,6 +221,20 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 		f.sr = types.NewCustomSectionReader(r, f.vma, 0, 1<<63-1)
 		f.cr = f.sr
 	}
+	f.size = -1
+	if config.SectionReader == nil { // otherwise the file offsets are not offsets into r
+		f.size = readerSize(r)
+	}
+	loadIncluding := config.LoadIncluding
+	loadExcluding := config.LoadExcluding
+	f.sharedCacheRelativeSelectorBaseVMAddress = config.RelativeSelectorBase
+	f.demangleSymbols = config.DemangleSymbols
+	f.lazy = config.Lazy
+	f.diagHandler = config.DiagnosticHandler
+	f.limits = config.Limits
+	if config.SectionCacheSize > 0 {
+		f.sectionCache = newSectionCache(f.cr, config.SectionCacheSize)
+	}
 
 	// Read and decode Mach magic to determine byte order, size.
 	// Magic32 and Magic64 differ only in the bottom bit.
@@ -178,12 +259,16 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 	if err := binary.Read(f.sr, f.ByteOrder, &f.FileHeader); err != nil {
 		return nil, fmt.Errorf("failed to parse header: %v", err)
 	}
+	f.vmr = types.NewFileVMReader(f.cr, f.ByteOrder, int(f.pointerSize()))
 
 	// Then load commands.
 	offset := int64(types.FileHeaderSize32)
 	if f.Magic == types.Magic64 {
 		offset = types.FileHeaderSize64
 	}
+	if err := f.checkTable("load commands", uint64(offset), uint64(f.SizeCommands), 1); err != nil {
+		return nil, err
+	}
 	dat, err := saferio.ReadDataAt(r, uint64(f.SizeCommands), offset)
 	if err != nil {
 		return nil, err
@@ -204,12 +289,14 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 		if siz < 8 || siz > uint32(len(dat)) {
 			return nil, &FormatError{offset, "invalid command block size", nil}
 		}
+		if f.limits.MaxCommandSize > 0 && siz > f.limits.MaxCommandSize {
+			return nil, &FormatError{offset, "command block too large", siz}
+		}
 
 		var cmddat []byte
 		cmddat, dat = dat[0:siz], dat[siz:]
 		f.LoadOffsets = append(f.LoadOffsets, offset)
 		offset += int64(siz)
-		var s *Segment
 
 		// skip unwanted load commands
 		if len(loadIncluding) > 0 && !loadInSlice(cmd, loadIncluding) {
@@ -218,1045 +305,1128 @@ func NewFile(r io.ReaderAt, config ...FileConfig) (*File, error) {
 			continue
 		}
 
-		switch cmd {
+		nloads := len(f.Loads)
+		if err := f.parseLoadCmd(r, &config, cmd, cmddat, offset); err != nil {
+			lerr := &LoadCmdError{Index: int(i), Offset: offset - int64(siz), Cmd: cmd, Err: err}
+			if !config.Permissive {
+				return nil, lerr
+			}
+			f.warnings = append(f.warnings, lerr)
+			f.diagnose(Diagnostic{Offset: lerr.Offset, Cmd: cmd, Err: lerr, Message: err.Error()})
+			if len(f.Loads) == nloads { // keep the raw load command
+				f.Loads = append(f.Loads, LoadCmdBytes{cmd, LoadBytes(cmddat)})
+			}
+		}
+	}
+	f.snapshotLoads()
+	return f, nil
+}
+
+// parseLoadCmd parses the load command cmd (with the data cmddat) and adds it to f.Loads
+func (f *File) parseLoadCmd(r io.ReaderAt, config *FileConfig, cmd types.LoadCmd, cmddat []byte, offset int64) error {
+	bo := f.ByteOrder
+	siz := uint32(len(cmddat))
+	var s *Segment
+	var err error
+
+	if isLinkEditDataCmd(cmd) && len(cmddat) >= 16 {
+		if err := f.checkTable(cmd.String()+" data", uint64(bo.Uint32(cmddat[8:])), uint64(bo.Uint32(cmddat[12:])), 1); err != nil {
+			return err
+		}
+	}
+
+	switch cmd {
+	default:
+		f.diagnose(Diagnostic{
+			Offset:  offset - int64(siz),
+			Cmd:     cmd,
+			Err:     ErrUnknownLoadCmd,
+			Message: "found NEW load command (please let the author know via https://github.com/blacktop/go-macho/issues)",
+		})
+		if config.Permissive {
+			f.warnings = append(f.warnings, &LoadCmdError{Index: len(f.LoadOffsets) - 1, Offset: offset - int64(siz), Cmd: cmd, Err: ErrUnknownLoadCmd})
+		}
+		f.Loads = append(f.Loads, LoadCmdBytes{types.LoadCmd(cmd), LoadBytes(cmddat)})
+	case types.LC_SEGMENT:
+		var seg32 types.Segment32
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &seg32); err != nil {
+			return fmt.Errorf("failed to read LC_SEGMENT: %v", err)
+		}
+		s = new(Segment)
+		s.LoadBytes = cmddat
+		s.LoadCmd = cmd
+		s.Len = siz
+		s.Name = cstring(seg32.Name[0:])
+		s.Addr = uint64(seg32.Addr)
+		s.Memsz = uint64(seg32.Memsz)
+		s.Offset = uint64(seg32.Offset)
+		s.Filesz = uint64(seg32.Filesz)
+		s.Maxprot = seg32.Maxprot
+		s.Prot = seg32.Prot
+		s.Nsect = seg32.Nsect
+		s.Flag = seg32.Flag
+		s.Firstsect = uint32(len(f.Sections))
+		for i := 0; i < int(s.Nsect) && !config.NoSections; i++ {
+			var sh32 types.Section32
+			if err := binary.Read(b, bo, &sh32); err != nil {
+				return fmt.Errorf("failed to read Section32: %v", err)
+			}
+			sh := new(types.Section)
+			sh.Type = 32
+			sh.Name = cstring(sh32.Name[0:])
+			sh.Seg = cstring(sh32.Seg[0:])
+			sh.Addr = uint64(sh32.Addr)
+			sh.Size = uint64(sh32.Size)
+			sh.Offset = sh32.Offset
+			sh.Align = sh32.Align
+			sh.Reloff = sh32.Reloff
+			sh.Nreloc = sh32.Nreloc
+			sh.Flags = sh32.Flags
+			sh.Reserved1 = sh32.Reserve1
+			sh.Reserved2 = sh32.Reserve2
+			sh.SetReaders(f.cr, f.sectionReader(int64(sh32.Offset), int64(sh32.Size)))
+			if err := f.pushSection(sh, f.cr); err != nil {
+				return fmt.Errorf("failed to pushSection32: %v", err)
+			}
+			s.sections = append(s.sections, sh)
+		}
+		f.Loads = append(f.Loads, s)
+	case types.LC_SEGMENT_64:
+		var seg64 types.Segment64
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &seg64); err != nil {
+			return fmt.Errorf("failed to read LC_SEGMENT_64: %v", err)
+		}
+		s = new(Segment)
+		s.LoadBytes = cmddat
+		s.LoadCmd = cmd
+		s.Len = siz
+		s.Name = cstring(seg64.Name[0:])
+		s.Addr = seg64.Addr
+		s.Memsz = seg64.Memsz
+		s.Offset = seg64.Offset
+		s.Filesz = seg64.Filesz
+		s.Maxprot = seg64.Maxprot
+		s.Prot = seg64.Prot
+		s.Nsect = seg64.Nsect
+		s.Flag = seg64.Flag
+		s.Firstsect = uint32(len(f.Sections))
+		for i := 0; i < int(s.Nsect) && !config.NoSections; i++ {
+			var sh64 types.Section64
+			if err := binary.Read(b, bo, &sh64); err != nil {
+				return fmt.Errorf("failed to read Section64: %v", err)
+			}
+			sh := new(types.Section)
+			sh.Type = 64
+			sh.Name = cstring(sh64.Name[0:])
+			sh.Seg = cstring(sh64.Seg[0:])
+			sh.Addr = sh64.Addr
+			sh.Size = sh64.Size
+			sh.Offset = sh64.Offset
+			sh.Align = sh64.Align
+			sh.Reloff = sh64.Reloff
+			sh.Nreloc = sh64.Nreloc
+			sh.Flags = sh64.Flags
+			sh.Reserved1 = sh64.Reserve1
+			sh.Reserved2 = sh64.Reserve2
+			sh.Reserved3 = sh64.Reserve3
+			sh.SetReaders(f.cr, f.sectionReader(int64(sh64.Offset), int64(sh64.Size)))
+			if err := f.pushSection(sh, f.cr); err != nil {
+				return fmt.Errorf("failed to pushSection64: %v", err)
+			}
+			s.sections = append(s.sections, sh)
+		}
+		f.Loads = append(f.Loads, s)
+	case types.LC_SYMTAB:
+		var hdr types.SymtabCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_SYMTAB: %v", err)
+		}
+		st := &Symtab{LoadBytes: cmddat, SymtabCmd: hdr}
+		switch {
+		case config.NoSymbols:
+		case f.lazy:
+			f.lazySymtab = true
 		default:
-			log.Printf("found NEW load command: %s (please let the author know via https://github.com/blacktop/go-macho/issues)", cmd)
-			f.Loads = append(f.Loads, LoadCmdBytes{types.LoadCmd(cmd), LoadBytes(cmddat)})
-		case types.LC_SEGMENT:
-			var seg32 types.Segment32
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &seg32); err != nil {
-				return nil, fmt.Errorf("failed to read LC_SEGMENT: %v", err)
-			}
-			s = new(Segment)
-			s.LoadBytes = cmddat
-			s.LoadCmd = cmd
-			s.Len = siz
-			s.Name = cstring(seg32.Name[0:])
-			s.Addr = uint64(seg32.Addr)
-			s.Memsz = uint64(seg32.Memsz)
-			s.Offset = uint64(seg32.Offset)
-			s.Filesz = uint64(seg32.Filesz)
-			s.Maxprot = seg32.Maxprot
-			s.Prot = seg32.Prot
-			s.Nsect = seg32.Nsect
-			s.Flag = seg32.Flag
-			s.Firstsect = uint32(len(f.Sections))
-			for i := 0; i < int(s.Nsect); i++ {
-				var sh32 types.Section32
-				if err := binary.Read(b, bo, &sh32); err != nil {
-					return nil, fmt.Errorf("failed to read Section32: %v", err)
-				}
-				sh := new(types.Section)
-				sh.Type = 32
-				sh.Name = cstring(sh32.Name[0:])
-				sh.Seg = cstring(sh32.Seg[0:])
-				sh.Addr = uint64(sh32.Addr)
-				sh.Size = uint64(sh32.Size)
-				sh.Offset = sh32.Offset
-				sh.Align = sh32.Align
-				sh.Reloff = sh32.Reloff
-				sh.Nreloc = sh32.Nreloc
-				sh.Flags = sh32.Flags
-				sh.Reserved1 = sh32.Reserve1
-				sh.Reserved2 = sh32.Reserve2
-				sh.SetReaders(f.cr, io.NewSectionReader(f.cr, int64(sh32.Offset), int64(sh32.Size)))
-				if err := f.pushSection(sh, f.cr); err != nil {
-					return nil, fmt.Errorf("failed to pushSection32: %v", err)
-				}
-				s.sections = append(s.sections, sh)
-			}
-			f.Loads = append(f.Loads, s)
-		case types.LC_SEGMENT_64:
-			var seg64 types.Segment64
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &seg64); err != nil {
-				return nil, fmt.Errorf("failed to read LC_SEGMENT_64: %v", err)
-			}
-			s = new(Segment)
-			s.LoadBytes = cmddat
-			s.LoadCmd = cmd
-			s.Len = siz
-			s.Name = cstring(seg64.Name[0:])
-			s.Addr = seg64.Addr
-			s.Memsz = seg64.Memsz
-			s.Offset = seg64.Offset
-			s.Filesz = seg64.Filesz
-			s.Maxprot = seg64.Maxprot
-			s.Prot = seg64.Prot
-			s.Nsect = seg64.Nsect
-			s.Flag = seg64.Flag
-			s.Firstsect = uint32(len(f.Sections))
-			for i := 0; i < int(s.Nsect); i++ {
-				var sh64 types.Section64
-				if err := binary.Read(b, bo, &sh64); err != nil {
-					return nil, fmt.Errorf("failed to read Section64: %v", err)
-				}
-				sh := new(types.Section)
-				sh.Type = 64
-				sh.Name = cstring(sh64.Name[0:])
-				sh.Seg = cstring(sh64.Seg[0:])
-				sh.Addr = sh64.Addr
-				sh.Size = sh64.Size
-				sh.Offset = sh64.Offset
-				sh.Align = sh64.Align
-				sh.Reloff = sh64.Reloff
-				sh.Nreloc = sh64.Nreloc
-				sh.Flags = sh64.Flags
-				sh.Reserved1 = sh64.Reserve1
-				sh.Reserved2 = sh64.Reserve2
-				sh.Reserved3 = sh64.Reserve3
-				sh.SetReaders(f.cr, io.NewSectionReader(f.cr, int64(sh64.Offset), int64(sh64.Size)))
-				if err := f.pushSection(sh, f.cr); err != nil {
-					return nil, fmt.Errorf("failed to pushSection64: %v", err)
-				}
-				s.sections = append(s.sections, sh)
+			if st.Syms, err = f.readSymbols(&hdr, offset); err != nil {
+				return err
 			}
-			f.Loads = append(f.Loads, s)
-		case types.LC_SYMTAB:
-			var hdr types.SymtabCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_SYMTAB: %v", err)
+		}
+		st.LoadBytes = cmddat
+		st.LoadCmd = cmd
+		st.Len = siz
+		f.Loads = append(f.Loads, st)
+		f.Symtab = st
+	case types.LC_SYMSEG:
+		var led types.SymsegCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &led); err != nil {
+			return fmt.Errorf("failed to read LC_SYMSEG: %v", err)
+		}
+
+		l := new(SymSeg)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = led.Offset
+		l.Size = led.Size
+		l.sr = io.NewSectionReader(f.cr, int64(led.Offset), int64(led.Size))
+		f.Loads = append(f.Loads, l)
+	case types.LC_THREAD:
+		var t types.ThreadCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &t); err != nil {
+			return fmt.Errorf("failed to read LC_THREAD: %v", err)
+		}
+		l := new(Thread)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.bo = bo
+		for {
+			var thread types.ThreadState
+			err := binary.Read(b, bo, &thread.Flavor)
+			if err == io.EOF {
+				break
 			}
-			strtab, err := saferio.ReadDataAt(f.cr, uint64(hdr.Strsize), int64(hdr.Stroff))
 			if err != nil {
-				return nil, fmt.Errorf("failed to read data at Stroff=%#x; %v", int64(hdr.Stroff), err)
+				return fmt.Errorf("failed to read LC_THREAD flavor: %v", err)
 			}
-			var symsz int
-			if f.Magic == types.Magic64 {
-				symsz = 16
-			} else {
-				symsz = 12
+			if err := binary.Read(b, bo, &thread.Count); err != nil {
+				return fmt.Errorf("failed to read LC_THREAD count: %v", err)
+			}
+			if uint64(thread.Count)*4 > uint64(b.Len()) {
+				return &FormatError{offset, "invalid thread state count", thread.Count}
+			}
+			thread.Data = make([]byte, thread.Count*uint32(binary.Size(uint32(0))))
+			if err := binary.Read(b, bo, &thread.Data); err != nil {
+				return fmt.Errorf("failed to read LC_THREAD state struct data: %v", err)
+			}
+			l.Threads = append(l.Threads, thread)
+		}
+		f.Loads = append(f.Loads, l)
+	case types.LC_UNIXTHREAD:
+		var ut types.UnixThreadCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &ut); err != nil {
+			return fmt.Errorf("failed to read LC_UNIXTHREAD: %v", err)
+		}
+		l := new(UnixThread)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.bo = bo
+		for {
+			var thread types.ThreadState
+			err := binary.Read(b, bo, &thread.Flavor)
+			if err == io.EOF {
+				break
 			}
-			symdat, err := saferio.ReadDataAt(r, uint64(hdr.Nsyms)*uint64(symsz), int64(hdr.Symoff))
 			if err != nil {
-				return nil, fmt.Errorf("failed to read data at Symoff=%#x; %v", int64(hdr.Symoff), err)
+				return fmt.Errorf("failed to read LC_UNIXTHREAD flavor: %v", err)
+			}
+			if err := binary.Read(b, bo, &thread.Count); err != nil {
+				return fmt.Errorf("failed to read LC_UNIXTHREAD count: %v", err)
+			}
+			if uint64(thread.Count)*4 > uint64(b.Len()) {
+				return &FormatError{offset, "invalid thread state count", thread.Count}
+			}
+			thread.Data = make([]byte, thread.Count*uint32(binary.Size(uint32(0))))
+			if err := binary.Read(b, bo, &thread.Data); err != nil {
+				return fmt.Errorf("failed to read LC_UNIXTHREAD state struct data: %v", err)
+			}
+			l.Threads = append(l.Threads, thread)
+		}
+		f.Loads = append(f.Loads, l)
+	case types.LC_LOADFVMLIB:
+		var hdr types.LoadFvmLibCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_LOADFVMLIB: %v", err)
+		}
+		l := new(LoadFvmlib)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		l.MinorVersion = hdr.MinorVersion
+		l.HeaderAddr = hdr.HeaderAddr
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in LC_LOADFVMLIB command", hdr.NameOffset}
+		}
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_IDFVMLIB:
+		var hdr types.IDFvmLibCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_IDFVMLIB: %v", err)
+		}
+		l := new(IDFvmlib)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		l.MinorVersion = hdr.MinorVersion
+		l.HeaderAddr = hdr.HeaderAddr
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in LC_IDFVMLIB command", hdr.NameOffset}
+		}
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_IDENT:
+		var hdr types.IdentCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_IDENT: %v", err)
+		}
+		l := new(Ident)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		// the strings follow the command and are padded out to a 4-byte boundary
+		for _, str := range bytes.Split(cmddat[binary.Size(hdr):], []byte{0}) {
+			if len(str) > 0 {
+				l.StrTable = append(l.StrTable, string(str))
 			}
-			st, err := f.parseSymtab(symdat, strtab, cmddat, &hdr, offset)
+		}
+		f.Loads = append(f.Loads, l)
+	case types.LC_FVMFILE:
+		var hdr types.FvmFileCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_FVMFILE: %v", err)
+		}
+		l := new(FvmFile)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		l.HeaderAddr = hdr.HeaderAddr
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in LC_FVMFILE command", hdr.NameOffset}
+		}
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_PREPAGE:
+		var hdr types.PrePageCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_PREPAGE: %v", err)
+		}
+		l := new(Prepage)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		f.Loads = append(f.Loads, l)
+	case types.LC_DYSYMTAB:
+		var hdr types.DysymtabCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_DYSYMTAB: %v", err)
+		}
+		if f.Symtab == nil {
+			return &FormatError{offset, "dynamic symbol table seen before any ordinary symbol table", nil}
+		} else if hdr.Iundefsym > f.Symtab.Nsyms {
+			return &FormatError{offset, fmt.Sprintf(
+				"undefined symbols index in dynamic symbol table command is greater than symbol table length (%d > %d)",
+				hdr.Iundefsym, f.Symtab.Nsyms), nil}
+		} else if uint64(hdr.Iundefsym)+uint64(hdr.Nundefsym) > uint64(f.Symtab.Nsyms) {
+			return &FormatError{offset, fmt.Sprintf(
+				"number of undefined symbols after index in dynamic symbol table command is greater than symbol table length (%d > %d)",
+				uint64(hdr.Iundefsym)+uint64(hdr.Nundefsym), f.Symtab.Nsyms), nil}
+		} else if uint64(hdr.Ilocalsym)+uint64(hdr.Nlocalsym) > uint64(f.Symtab.Nsyms) {
+			return &FormatError{offset, "local symbols in dynamic symbol table command extend past the symbol table", hdr.Ilocalsym}
+		} else if uint64(hdr.Iextdefsym)+uint64(hdr.Nextdefsym) > uint64(f.Symtab.Nsyms) {
+			return &FormatError{offset, "external symbols in dynamic symbol table command extend past the symbol table", hdr.Iextdefsym}
+		}
+		var x []uint32
+		if f.lazy {
+			f.lazyIndirectSyms = true
+		} else if x, err = f.readIndirectSymbols(&hdr); err != nil {
+			return err
+		}
+		st := new(Dysymtab)
+		st.LoadBytes = cmddat
+		st.LoadCmd = cmd
+		st.Len = siz
+		st.DysymtabCmd = hdr
+		st.IndirectSyms = x
+		if f.lazy {
+			f.lazyDysymRelocs = true
+		} else if err := f.readDysymtabRelocs(st); err != nil {
+			return err
+		}
+		if f.lazy {
+			f.lazyDylibTables = true
+		} else if err := f.readDylibTables(st); err != nil {
+			return err
+		}
+		f.Loads = append(f.Loads, st)
+		f.Dysymtab = st
+	case types.LC_LOAD_DYLIB:
+		var hdr types.DylibCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_LOAD_DYLIB: %v", err)
+		}
+		l := new(LoadDylib)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in dynamic library command", hdr.NameOffset}
+		}
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		l.Timestamp = hdr.Timestamp
+		l.CurrentVersion = hdr.CurrentVersion
+		l.CompatVersion = hdr.CompatVersion
+		f.Loads = append(f.Loads, l)
+	case types.LC_ID_DYLIB:
+		var hdr types.DylibCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_ID_DYLIB: %v", err)
+		}
+		l := new(IDDylib)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in dynamic library ident command", hdr.NameOffset}
+		}
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		l.Timestamp = hdr.Timestamp
+		l.CurrentVersion = hdr.CurrentVersion
+		l.CompatVersion = hdr.CompatVersion
+		f.Loads = append(f.Loads, l)
+	case types.LC_LOAD_DYLINKER:
+		var hdr types.DylinkerCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_LOAD_DYLINKER: %v", err)
+		}
+		l := new(LoadDylinker)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in load dylinker command", hdr.NameOffset}
+		}
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_ID_DYLINKER:
+		var hdr types.IDDylinkerCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_ID_DYLINKER: %v", err)
+		}
+		l := new(DylinkerID)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in load dylinker command", hdr.NameOffset}
+		}
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_PREBOUND_DYLIB:
+		var hdr types.PreboundDylibCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_PREBOUND_DYLIB: %v", err)
+		}
+		l := new(PreboundDylib)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in LC_PREBOUND_DYLIB command", hdr.NameOffset}
+		}
+		l.NumModules = hdr.NumModules
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		l.LinkedModulesOffset = hdr.LinkedModulesOffset
+		if hdr.LinkedModulesOffset >= uint32(len(cmddat)) ||
+			uint64(hdr.LinkedModulesOffset)+(uint64(hdr.NumModules)+7)/8 > uint64(len(cmddat)) {
+			return &FormatError{offset, "invalid linked modules in LC_PREBOUND_DYLIB command", hdr.LinkedModulesOffset}
+		}
+		l.LinkedModulesBitVector = make([]byte, (hdr.NumModules+7)/8)
+		copy(l.LinkedModulesBitVector, cmddat[hdr.LinkedModulesOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_ROUTINES:
+		var rt types.RoutinesCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &rt); err != nil {
+			return fmt.Errorf("failed to read LC_ROUTINES: %v", err)
+		}
+		l := new(Routines)
+		l.LoadBytes = cmddat
+		l.RoutinesCmd = rt
+		f.Loads = append(f.Loads, l)
+	case types.LC_SUB_FRAMEWORK:
+		var sf types.SubFrameworkCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &sf); err != nil {
+			return fmt.Errorf("failed to read LC_SUB_FRAMEWORK: %v", err)
+		}
+		l := new(SubFramework)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.FrameworkOffset = sf.FrameworkOffset
+		if sf.FrameworkOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid framework in sub-framework command", sf.FrameworkOffset}
+		}
+		l.Framework = cstring(cmddat[sf.FrameworkOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_SUB_UMBRELLA:
+		var su types.SubUmbrellaCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &su); err != nil {
+			return fmt.Errorf("failed to read LC_SUB_UMBRELLA: %v", err)
+		}
+		l := new(SubUmbrella)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.UmbrellaOffset = su.UmbrellaOffset
+		if su.UmbrellaOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid umbrella in sub-umbrella command", su.UmbrellaOffset}
+		}
+		l.Umbrella = cstring(cmddat[su.UmbrellaOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_SUB_CLIENT:
+		var sc types.SubClientCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &sc); err != nil {
+			return fmt.Errorf("failed to read LC_SUB_CLIENT: %v", err)
+		}
+		l := new(SubClient)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.ClientOffset = sc.ClientOffset
+		if sc.ClientOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid path in sub client command", sc.ClientOffset}
+		}
+		l.Name = cstring(cmddat[sc.ClientOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_SUB_LIBRARY:
+		var s types.SubLibraryCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &s); err != nil {
+			return fmt.Errorf("failed to read LC_SUB_LIBRARY: %v", err)
+		}
+		l := new(SubLibrary)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.LibraryOffset = s.LibraryOffset
+		if s.LibraryOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid library in sub-library command", s.LibraryOffset}
+		}
+		l.Library = cstring(cmddat[s.LibraryOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_TWOLEVEL_HINTS:
+		var t types.TwolevelHintsCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &t); err != nil {
+			return fmt.Errorf("failed to read LC_TWOLEVEL_HINTS: %v", err)
+		}
+		l := new(TwolevelHints)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = t.Offset
+		l.NumHints = t.NumHints
+		hdat, err := saferio.ReadDataAt(f.cr, uint64(t.NumHints)*uint64(binary.Size(types.TwolevelHint(0))), int64(t.Offset))
+		if err != nil {
+			return fmt.Errorf("failed to read LC_TWOLEVEL_HINTS hint table data: %v", err)
+		}
+		l.Hints = make([]types.TwolevelHint, t.NumHints)
+		for i := range l.Hints {
+			l.Hints[i] = types.NewTwolevelHint(bo.Uint32(hdat[i*4:]), bo)
+		}
+		f.Loads = append(f.Loads, l)
+
+	case types.LC_PREBIND_CKSUM:
+		var p types.PrebindCksumCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &p); err != nil {
+			return fmt.Errorf("failed to read LC_PREBIND_CKSUM: %v", err)
+		}
+		l := new(PrebindCheckSum)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.CheckSum = p.CheckSum
+		f.Loads = append(f.Loads, l)
+	case types.LC_LOAD_WEAK_DYLIB:
+		var hdr types.DylibCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_LOAD_WEAK_DYLIB: %v", err)
+		}
+		l := new(WeakDylib)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in weak dynamic library command", hdr.NameOffset}
+		}
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		l.Timestamp = hdr.Timestamp
+		l.CurrentVersion = hdr.CurrentVersion
+		l.CompatVersion = hdr.CompatVersion
+		f.Loads = append(f.Loads, l)
+	case types.LC_ROUTINES_64:
+		var r64 types.Routines64Cmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &r64); err != nil {
+			return fmt.Errorf("failed to read LC_ROUTINES_64: %v", err)
+		}
+		l := new(Routines64)
+		l.LoadBytes = cmddat
+		l.Routines64Cmd = r64
+		f.Loads = append(f.Loads, l)
+	case types.LC_UUID:
+		var u types.UUIDCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &u); err != nil {
+			return fmt.Errorf("failed to read LC_UUID: %v", err)
+		}
+		l := new(UUID)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.UUID = u.UUID
+		f.Loads = append(f.Loads, l)
+	case types.LC_RPATH:
+		var hdr types.RpathCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_RPATH: %v", err)
+		}
+		l := new(Rpath)
+		if hdr.PathOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid path in rpath command", hdr.PathOffset}
+		}
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.PathOffset = hdr.PathOffset
+		if hdr.PathOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid path in rpath command", hdr.PathOffset}
+		}
+		l.Path = cstring(cmddat[hdr.PathOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_CODE_SIGNATURE:
+		var hdr types.CodeSignatureCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_CODE_SIGNATURE: %v", err)
+		}
+
+		l := new(CodeSignature)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = hdr.Offset
+		l.Size = hdr.Size
+		csdat, err := saferio.ReadDataAt(f.cr, uint64(hdr.Size), int64(hdr.Offset))
+		if err != nil {
+			return fmt.Errorf("failed to read CS data at offset=%#x; %v", int64(hdr.Offset), err)
+		}
+		cs, err := codesign.ParseCodeSignature(csdat)
+		if err != nil {
+			return fmt.Errorf("failed to ParseCodeSignature: %v", err)
+		}
+		for _, e := range cs.Errors {
+			f.diagnose(Diagnostic{Offset: offset - int64(siz), Cmd: cmd, Err: e, Message: e.Error()})
+		}
+		l.CodeSignature = *cs
+		f.Loads = append(f.Loads, l)
+	case types.LC_SEGMENT_SPLIT_INFO:
+		var hdr types.SegmentSplitInfoCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO: %v", err)
+		}
+		l := new(SplitInfo)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = hdr.Offset
+		l.Size = hdr.Size
+		if l.Size > 0 {
+			ldat, err := saferio.ReadDataAt(f.cr, uint64(l.Size), int64(l.Offset))
 			if err != nil {
-				return nil, fmt.Errorf("failed to read parseSymtab: %v", err)
-			}
-			st.LoadBytes = cmddat
-			st.LoadCmd = cmd
-			st.Len = siz
-			f.Loads = append(f.Loads, st)
-			f.Symtab = st
-		case types.LC_SYMSEG:
-			var led types.SymsegCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &led); err != nil {
-				return nil, fmt.Errorf("failed to read LC_SYMSEG: %v", err)
-			}
-
-			l := new(SymSeg)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = led.Offset
-			l.Size = led.Size
-			f.Loads = append(f.Loads, l)
-		case types.LC_THREAD:
-			var t types.ThreadCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &t); err != nil {
-				return nil, fmt.Errorf("failed to read LC_THREAD: %v", err)
-			}
-			l := new(Thread)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			for {
-				var thread types.ThreadState
-				err := binary.Read(b, bo, &thread.Flavor)
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					return nil, fmt.Errorf("failed to read LC_THREAD flavor: %v", err)
-				}
-				if err := binary.Read(b, bo, &thread.Count); err != nil {
-					return nil, fmt.Errorf("failed to read LC_THREAD count: %v", err)
-				}
-				thread.Data = make([]byte, thread.Count*uint32(binary.Size(uint32(0))))
-				if err := binary.Read(b, bo, &thread.Data); err != nil {
-					return nil, fmt.Errorf("failed to read LC_THREAD state struct data: %v", err)
-				}
-				l.Threads = append(l.Threads, thread)
-			}
-			f.Loads = append(f.Loads, l)
-		case types.LC_UNIXTHREAD:
-			var ut types.UnixThreadCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &ut); err != nil {
-				return nil, fmt.Errorf("failed to read LC_UNIXTHREAD: %v", err)
-			}
-			l := new(UnixThread)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.bo = bo
-			for {
-				var thread types.ThreadState
-				err := binary.Read(b, bo, &thread.Flavor)
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					return nil, fmt.Errorf("failed to read LC_UNIXTHREAD flavor: %v", err)
-				}
-				if err := binary.Read(b, bo, &thread.Count); err != nil {
-					return nil, fmt.Errorf("failed to read LC_UNIXTHREAD count: %v", err)
-				}
-				thread.Data = make([]byte, thread.Count*uint32(binary.Size(uint32(0))))
-				if err := binary.Read(b, bo, &thread.Data); err != nil {
-					return nil, fmt.Errorf("failed to read LC_UNIXTHREAD state struct data: %v", err)
-				}
-				l.Threads = append(l.Threads, thread)
-			}
-			f.Loads = append(f.Loads, l)
-		case types.LC_LOADFVMLIB:
-			var hdr types.LoadFvmLibCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_LOADFVMLIB: %v", err)
-			}
-			l := new(LoadFvmlib)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			l.MinorVersion = hdr.MinorVersion
-			l.HeaderAddr = hdr.HeaderAddr
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in LC_LOADFVMLIB command", hdr.NameOffset}
-			}
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_IDFVMLIB:
-			var hdr types.IDFvmLibCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_IDFVMLIB: %v", err)
-			}
-			l := new(IDFvmlib)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			l.MinorVersion = hdr.MinorVersion
-			l.HeaderAddr = hdr.HeaderAddr
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in LC_IDFVMLIB command", hdr.NameOffset}
-			}
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_IDENT:
-			var hdr types.IdentCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_IDENT: %v", err)
-			}
-			l := new(Ident)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			br := bufio.NewReader(b)
-			for {
-				o, err := br.ReadString('\x00')
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					return nil, fmt.Errorf("failed to read LC_IDENT options: %v", err)
-				}
-				l.StrTable = append(l.StrTable, o)
-			}
-			f.Loads = append(f.Loads, l)
-		case types.LC_FVMFILE:
-			var hdr types.FvmFileCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_FVMFILE: %v", err)
-			}
-			l := new(FvmFile)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			l.HeaderAddr = hdr.HeaderAddr
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in LC_FVMFILE command", hdr.NameOffset}
-			}
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_PREPAGE:
-			var hdr types.PrePageCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_PREPAGE: %v", err)
-			}
-			l := new(Prepage)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			f.Loads = append(f.Loads, l)
-		case types.LC_DYSYMTAB:
-			var hdr types.DysymtabCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_DYSYMTAB: %v", err)
-			}
-			if f.Symtab == nil {
-				return nil, &FormatError{offset, "dynamic symbol table seen before any ordinary symbol table", nil}
-			} else if hdr.Iundefsym > uint32(len(f.Symtab.Syms)) {
-				return nil, &FormatError{offset, fmt.Sprintf(
-					"undefined symbols index in dynamic symbol table command is greater than symbol table length (%d > %d)",
-					hdr.Iundefsym, len(f.Symtab.Syms)), nil}
-			} else if hdr.Iundefsym+hdr.Nundefsym > uint32(len(f.Symtab.Syms)) {
-				return nil, &FormatError{offset, fmt.Sprintf(
-					"number of undefined symbols after index in dynamic symbol table command is greater than symbol table length (%d > %d)",
-					hdr.Iundefsym+hdr.Nundefsym, len(f.Symtab.Syms)), nil}
-			}
-			dat, err := saferio.ReadDataAt(r, uint64(hdr.Nindirectsyms)*4, int64(hdr.Indirectsymoff))
+				return fmt.Errorf("failed to read SplitInfo data at offset=%#x; %v", int64(hdr.Offset), err)
+			}
+			fsr := bytes.NewReader(ldat)
+			if err := binary.Read(fsr, bo, &l.Version); err != nil {
+				return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO Version: %v", err)
+			}
+		}
+		f.Loads = append(f.Loads, l)
+	case types.LC_REEXPORT_DYLIB:
+		var hdr types.ReExportDylibCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_REEXPORT_DYLIB: %v", err)
+		}
+		l := new(ReExportDylib)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in dynamic library command", hdr.NameOffset}
+		}
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		l.Timestamp = hdr.Timestamp
+		l.CurrentVersion = hdr.CurrentVersion
+		l.CompatVersion = hdr.CompatVersion
+		f.Loads = append(f.Loads, l)
+	case types.LC_LAZY_LOAD_DYLIB:
+		var hdr types.LazyLoadDylibCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_LAZY_LOAD_DYLIB: %v", err)
+		}
+		l := new(LazyLoadDylib)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in lazy load dylib command", hdr.NameOffset}
+		}
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		l.Timestamp = hdr.Timestamp
+		l.CurrentVersion = hdr.CurrentVersion
+		l.CompatVersion = hdr.CompatVersion
+		f.Loads = append(f.Loads, l)
+	case types.LC_ENCRYPTION_INFO:
+		var ei types.EncryptionInfoCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &ei); err != nil {
+			return fmt.Errorf("failed to read LC_ENCRYPTION_INFO: %v", err)
+		}
+
+		l := new(EncryptionInfo)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = ei.Offset
+		l.Size = ei.Size
+		l.CryptID = ei.CryptID
+		f.Loads = append(f.Loads, l)
+	case types.LC_DYLD_INFO:
+		var info types.DyldInfoCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &info); err != nil {
+			return fmt.Errorf("failed to read LC_DYLD_INFO: %v", err)
+		}
+		l := new(DyldInfo)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.RebaseOff = info.RebaseOff
+		l.RebaseSize = info.RebaseSize
+		l.BindOff = info.BindOff
+		l.BindSize = info.BindSize
+		l.WeakBindOff = info.WeakBindOff
+		l.WeakBindSize = info.WeakBindSize
+		l.LazyBindOff = info.LazyBindOff
+		l.LazyBindSize = info.LazyBindSize
+		l.ExportOff = info.ExportOff
+		l.ExportSize = info.ExportSize
+		f.Loads = append(f.Loads, l)
+	case types.LC_DYLD_INFO_ONLY:
+		var info types.DyldInfoOnlyCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &info); err != nil {
+			return fmt.Errorf("failed to read LC_DYLD_INFO_ONLY: %v", err)
+		}
+		l := new(DyldInfoOnly)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.RebaseOff = info.RebaseOff
+		l.RebaseSize = info.RebaseSize
+		l.BindOff = info.BindOff
+		l.BindSize = info.BindSize
+		l.WeakBindOff = info.WeakBindOff
+		l.WeakBindSize = info.WeakBindSize
+		l.LazyBindOff = info.LazyBindOff
+		l.LazyBindSize = info.LazyBindSize
+		l.ExportOff = info.ExportOff
+		l.ExportSize = info.ExportSize
+		f.Loads = append(f.Loads, l)
+	case types.LC_LOAD_UPWARD_DYLIB:
+		var hdr types.LoadUpwardDylibCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_LOAD_UPWARD_DYLIB: %v", err)
+		}
+		l := new(UpwardDylib)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in load upwardl dylib command", hdr.NameOffset}
+		}
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		l.Timestamp = hdr.Timestamp
+		l.CurrentVersion = hdr.CurrentVersion
+		l.CompatVersion = hdr.CompatVersion
+		f.Loads = append(f.Loads, l)
+	case types.LC_VERSION_MIN_MACOSX:
+		var verMin types.VersionMinMacOSCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &verMin); err != nil {
+			return fmt.Errorf("failed to read LC_VERSION_MIN_MACOSX: %v", err)
+		}
+		l := new(VersionMinMacOSX)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Version = verMin.Version
+		l.Sdk = verMin.Sdk
+		f.Loads = append(f.Loads, l)
+	case types.LC_VERSION_MIN_IPHONEOS:
+		var verMin types.VersionMinIPhoneOSCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &verMin); err != nil {
+			return fmt.Errorf("failed to read LC_VERSION_MIN_IPHONEOS: %v", err)
+		}
+		l := new(VersionMiniPhoneOS)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Version = verMin.Version
+		l.Sdk = verMin.Sdk
+		f.Loads = append(f.Loads, l)
+	case types.LC_FUNCTION_STARTS:
+		var led types.LinkEditDataCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &led); err != nil {
+			return fmt.Errorf("failed to read LC_FUNCTION_STARTS: %v", err)
+		}
+
+		l := new(FunctionStarts)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = led.Offset
+		l.Size = led.Size
+		f.Loads = append(f.Loads, l)
+	case types.LC_DYLD_ENVIRONMENT:
+		var hdr types.DyldEnvironmentCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_DYLD_ENVIRONMENT: %v", err)
+		}
+		l := new(DyldEnvironment)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.NameOffset = hdr.NameOffset
+		if hdr.NameOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in dyld environment command", hdr.NameOffset}
+		}
+		l.Name = cstring(cmddat[hdr.NameOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_MAIN:
+		var hdr types.EntryPointCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_MAIN: %v", err)
+		}
+		l := new(EntryPoint)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.EntryOffset = hdr.EntryOffset
+		l.StackSize = hdr.StackSize
+		f.Loads = append(f.Loads, l)
+	case types.LC_DATA_IN_CODE:
+		var led types.LinkEditDataCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &led); err != nil {
+			return fmt.Errorf("failed to read LC_DATA_IN_CODE: %v", err)
+		}
+		l := new(DataInCode)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = led.Offset
+		l.Size = led.Size
+		ldat, err := saferio.ReadDataAt(f.cr, uint64(l.Size), int64(l.Offset))
+		if err != nil {
+			return fmt.Errorf("failed to read DataInCode data at offset=%#x; %v", int64(led.Offset), err)
+		}
+		l.Entries = make([]types.DataInCodeEntry, len(ldat)/binary.Size(types.DataInCodeEntry{}))
+		if err := binary.Read(bytes.NewReader(ldat), bo, &l.Entries); err != nil {
+			return fmt.Errorf("failed to read LC_DATA_IN_CODE entries: %v", err)
+		}
+		f.Loads = append(f.Loads, l)
+	case types.LC_SOURCE_VERSION:
+		var sv types.SourceVersionCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &sv); err != nil {
+			return fmt.Errorf("failed to read LC_SOURCE_VERSION: %v", err)
+		}
+		l := new(SourceVersion)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Version = sv.Version
+		f.Loads = append(f.Loads, l)
+	case types.LC_DYLIB_CODE_SIGN_DRS:
+		var led types.LinkEditDataCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &led); err != nil {
+			return fmt.Errorf("failed to read LC_DYLIB_CODE_SIGN_DRS: %v", err)
+		}
+
+		l := new(DylibCodeSignDrs)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = led.Offset
+		l.Size = led.Size
+		if led.Size > 0 {
+			ldat, err := saferio.ReadDataAt(f.cr, uint64(led.Size), int64(led.Offset))
 			if err != nil {
-				return nil, fmt.Errorf("failed to read data at Indirectsymoff @ %#x: %w", int64(hdr.Indirectsymoff), err)
-			}
-			x := make([]uint32, hdr.Nindirectsyms)
-			if err := binary.Read(bytes.NewReader(dat), bo, x); err != nil {
-				return nil, fmt.Errorf("failed to read Nindirectsyms: %v", err)
-			}
-			// TODO: parse DylibTableOfContents if Ntoc > 0
-			// TODO: parse DylibModule if Nmodtab > 0
-			// TODO: parse DylibReference if Nextrefsyms > 0
-			// TODO: parse RelocInfo if Nlocrel > 0
-			st := new(Dysymtab)
-			st.LoadBytes = cmddat
-			st.LoadCmd = cmd
-			st.Len = siz
-			st.DysymtabCmd = hdr
-			st.IndirectSyms = x
-			f.Loads = append(f.Loads, st)
-			f.Dysymtab = st
-		case types.LC_LOAD_DYLIB:
-			var hdr types.DylibCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_LOAD_DYLIB: %v", err)
-			}
-			l := new(LoadDylib)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in dynamic library command", hdr.NameOffset}
-			}
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			l.Timestamp = hdr.Timestamp
-			l.CurrentVersion = hdr.CurrentVersion
-			l.CompatVersion = hdr.CompatVersion
-			f.Loads = append(f.Loads, l)
-		case types.LC_ID_DYLIB:
-			var hdr types.DylibCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_ID_DYLIB: %v", err)
-			}
-			l := new(IDDylib)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in dynamic library ident command", hdr.NameOffset}
-			}
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			l.Timestamp = hdr.Timestamp
-			l.CurrentVersion = hdr.CurrentVersion
-			l.CompatVersion = hdr.CompatVersion
-			f.Loads = append(f.Loads, l)
-		case types.LC_LOAD_DYLINKER:
-			var hdr types.DylinkerCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_LOAD_DYLINKER: %v", err)
-			}
-			l := new(LoadDylinker)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in load dylinker command", hdr.NameOffset}
-			}
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_ID_DYLINKER:
-			var hdr types.IDDylinkerCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_ID_DYLINKER: %v", err)
-			}
-			l := new(DylinkerID)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in load dylinker command", hdr.NameOffset}
-			}
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_PREBOUND_DYLIB:
-			var hdr types.PreboundDylibCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_PREBOUND_DYLIB: %v", err)
-			}
-			l := new(PreboundDylib)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in LC_PREBOUND_DYLIB command", hdr.NameOffset}
-			}
-			l.NumModules = hdr.NumModules
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			if hdr.LinkedModulesOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid linked modules in LC_PREBOUND_DYLIB command", hdr.NameOffset}
-			}
-			l.LinkedModulesBitVector = cstring(cmddat[hdr.LinkedModulesOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_ROUTINES:
-			var rt types.RoutinesCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &rt); err != nil {
-				return nil, fmt.Errorf("failed to read LC_ROUTINES: %v", err)
-			}
-			l := new(Routines)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.InitAddress = rt.InitAddress
-			l.InitModule = rt.InitModule
-			f.Loads = append(f.Loads, l)
-		case types.LC_SUB_FRAMEWORK:
-			var sf types.SubFrameworkCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &sf); err != nil {
-				return nil, fmt.Errorf("failed to read LC_SUB_FRAMEWORK: %v", err)
-			}
-			l := new(SubFramework)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.FrameworkOffset = sf.FrameworkOffset
-			if sf.FrameworkOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid framework in sub-framework command", sf.FrameworkOffset}
-			}
-			l.Framework = cstring(cmddat[sf.FrameworkOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_SUB_UMBRELLA:
-			var su types.SubUmbrellaCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &su); err != nil {
-				return nil, fmt.Errorf("failed to read LC_SUB_UMBRELLA: %v", err)
-			}
-			l := new(SubUmbrella)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.UmbrellaOffset = su.UmbrellaOffset
-			if su.UmbrellaOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid framework in sub-umbrella command", su.UmbrellaOffset}
-			}
-			l.Umbrella = cstring(cmddat[su.UmbrellaOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_SUB_CLIENT:
-			var sc types.SubClientCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &sc); err != nil {
-				return nil, fmt.Errorf("failed to read LC_SUB_CLIENT: %v", err)
-			}
-			l := new(SubClient)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.ClientOffset = sc.ClientOffset
-			if sc.ClientOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid path in sub client command", sc.ClientOffset}
-			}
-			l.Name = cstring(cmddat[sc.ClientOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_SUB_LIBRARY:
-			var s types.SubLibraryCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &s); err != nil {
-				return nil, fmt.Errorf("failed to read LC_SUB_LIBRARY: %v", err)
-			}
-			l := new(SubLibrary)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.LibraryOffset = s.LibraryOffset
-			if s.LibraryOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid framework in sub-library command", s.LibraryOffset}
-			}
-			l.Library = cstring(cmddat[s.LibraryOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_TWOLEVEL_HINTS:
-			var t types.TwolevelHintsCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &t); err != nil {
-				return nil, fmt.Errorf("failed to read LC_TWOLEVEL_HINTS: %v", err)
-			}
-			l := new(TwolevelHints)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = t.Offset
-			l.Hints = make([]types.TwolevelHint, t.NumHints)
-			if err := binary.Read(b, bo, &l.Hints); err != nil {
-				return nil, fmt.Errorf("failed to read hints data: %v", err)
-			}
-			f.Loads = append(f.Loads, l)
-
-		case types.LC_PREBIND_CKSUM:
-			var p types.PrebindCksumCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &p); err != nil {
-				return nil, fmt.Errorf("failed to read LC_PREBIND_CKSUM: %v", err)
-			}
-			l := new(PrebindCheckSum)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.CheckSum = p.CheckSum
-			f.Loads = append(f.Loads, l)
-		case types.LC_LOAD_WEAK_DYLIB:
-			var hdr types.DylibCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_LOAD_WEAK_DYLIB: %v", err)
-			}
-			l := new(WeakDylib)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in weak dynamic library command", hdr.NameOffset}
-			}
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			l.Timestamp = hdr.Timestamp
-			l.CurrentVersion = hdr.CurrentVersion
-			l.CompatVersion = hdr.CompatVersion
-			f.Loads = append(f.Loads, l)
-		case types.LC_ROUTINES_64:
-			var r64 types.Routines64Cmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &r64); err != nil {
-				return nil, fmt.Errorf("failed to read LC_ROUTINES_64: %v", err)
-			}
-			l := new(Routines64)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.InitAddress = r64.InitAddress
-			l.InitModule = r64.InitModule
-			f.Loads = append(f.Loads, l)
-		case types.LC_UUID:
-			var u types.UUIDCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &u); err != nil {
-				return nil, fmt.Errorf("failed to read LC_UUID: %v", err)
-			}
-			l := new(UUID)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.UUID = u.UUID
-			f.Loads = append(f.Loads, l)
-		case types.LC_RPATH:
-			var hdr types.RpathCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_RPATH: %v", err)
-			}
-			l := new(Rpath)
-			if hdr.PathOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid path in rpath command", hdr.PathOffset}
-			}
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.PathOffset = hdr.PathOffset
-			if hdr.PathOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid path in rpath command", hdr.PathOffset}
-			}
-			l.Path = cstring(cmddat[hdr.PathOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_CODE_SIGNATURE:
-			var hdr types.CodeSignatureCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_CODE_SIGNATURE: %v", err)
-			}
-
-			l := new(CodeSignature)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = hdr.Offset
-			l.Size = hdr.Size
-			csdat := make([]byte, hdr.Size)
-			if _, err := f.cr.ReadAt(csdat, int64(hdr.Offset)); err != nil {
-				return nil, fmt.Errorf("failed to read CS data at offset=%#x; %v", int64(hdr.Offset), err)
-			}
-			cs, err := codesign.ParseCodeSignature(csdat)
+				return fmt.Errorf("failed to read LC_DYLIB_CODE_SIGN_DRS data at offset=%#x; %v", led.Offset, err)
+			}
+			// the requirements are informational, keep the load command (without them) if they don't decode
+			if l.Requirements, err = codesign.ParseDylibCodeSignDRs(ldat); err != nil {
+				lerr := &LoadCmdError{Index: len(f.LoadOffsets) - 1, Offset: offset - int64(siz), Cmd: cmd, Err: fmt.Errorf("failed to parse LC_DYLIB_CODE_SIGN_DRS: %v", err)}
+				f.warnings = append(f.warnings, lerr)
+				f.diagnose(Diagnostic{Offset: lerr.Offset, Cmd: cmd, Err: lerr, Message: lerr.Err.Error()})
+			}
+		}
+		f.Loads = append(f.Loads, l)
+	case types.LC_ENCRYPTION_INFO_64:
+		var ei types.EncryptionInfo64Cmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &ei); err != nil {
+			return fmt.Errorf("failed to read LC_ENCRYPTION_INFO_64: %v", err)
+		}
+		l := new(EncryptionInfo64)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = ei.Offset
+		l.Size = ei.Size
+		l.CryptID = ei.CryptID
+		f.Loads = append(f.Loads, l)
+	case types.LC_LINKER_OPTION:
+		var lo types.LinkerOptionCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &lo); err != nil {
+			return fmt.Errorf("failed to read LC_LINKER_OPTION: %v", err)
+		}
+		l := new(LinkerOption)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		for i := 0; i < int(lo.Count); i++ {
+			o, err := bufio.NewReader(b).ReadString('\x00')
 			if err != nil {
-				return nil, fmt.Errorf("failed to ParseCodeSignature: %v", err)
-			}
-			l.CodeSignature = *cs
-			f.Loads = append(f.Loads, l)
-		case types.LC_SEGMENT_SPLIT_INFO:
-			var hdr types.SegmentSplitInfoCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO: %v", err)
-			}
-			l := new(SplitInfo)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = hdr.Offset
-			l.Size = hdr.Size
-			if l.Size > 0 {
-				ldat := make([]byte, l.Size)
-				if _, err := f.cr.ReadAt(ldat, int64(l.Offset)); err != nil {
-					return nil, fmt.Errorf("failed to read SplitInfo data at offset=%#x; %v", int64(hdr.Offset), err)
-				}
-				fsr := bytes.NewReader(ldat)
-				if err := binary.Read(fsr, bo, &l.Version); err != nil {
-					return nil, fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO Version: %v", err)
-				}
+				break // FIXME: should this error?
 			}
-			f.Loads = append(f.Loads, l)
-		case types.LC_REEXPORT_DYLIB:
-			var hdr types.ReExportDylibCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_REEXPORT_DYLIB: %v", err)
-			}
-			l := new(ReExportDylib)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in dynamic library command", hdr.NameOffset}
-			}
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			l.Timestamp = hdr.Timestamp
-			l.CurrentVersion = hdr.CurrentVersion
-			l.CompatVersion = hdr.CompatVersion
-			f.Loads = append(f.Loads, l)
-		case types.LC_LAZY_LOAD_DYLIB:
-			var hdr types.LazyLoadDylibCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_LAZY_LOAD_DYLIB: %v", err)
-			}
-			l := new(LazyLoadDylib)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in load upwardl dylib command", hdr.NameOffset}
-			}
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			l.Timestamp = hdr.Timestamp
-			l.CurrentVersion = hdr.CurrentVersion
-			l.CompatVersion = hdr.CompatVersion
-			f.Loads = append(f.Loads, l)
-		case types.LC_ENCRYPTION_INFO:
-			var ei types.EncryptionInfoCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &ei); err != nil {
-				return nil, fmt.Errorf("failed to read LC_ENCRYPTION_INFO: %v", err)
-			}
-
-			l := new(EncryptionInfo)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = ei.Offset
-			l.Size = ei.Size
-			l.CryptID = ei.CryptID
-			f.Loads = append(f.Loads, l)
-		case types.LC_DYLD_INFO:
-			var info types.DyldInfoCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &info); err != nil {
-				return nil, fmt.Errorf("failed to read LC_DYLD_INFO: %v", err)
-			}
-			l := new(DyldInfo)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.RebaseOff = info.RebaseOff
-			l.RebaseSize = info.RebaseSize
-			l.BindOff = info.BindOff
-			l.BindSize = info.BindSize
-			l.WeakBindOff = info.WeakBindOff
-			l.WeakBindSize = info.WeakBindSize
-			l.LazyBindOff = info.LazyBindOff
-			l.LazyBindSize = info.LazyBindSize
-			l.ExportOff = info.ExportOff
-			l.ExportSize = info.ExportSize
-			f.Loads = append(f.Loads, l)
-		case types.LC_DYLD_INFO_ONLY:
-			var info types.DyldInfoOnlyCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &info); err != nil {
-				return nil, fmt.Errorf("failed to read LC_DYLD_INFO_ONLY: %v", err)
-			}
-			l := new(DyldInfoOnly)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.RebaseOff = info.RebaseOff
-			l.RebaseSize = info.RebaseSize
-			l.BindOff = info.BindOff
-			l.BindSize = info.BindSize
-			l.WeakBindOff = info.WeakBindOff
-			l.WeakBindSize = info.WeakBindSize
-			l.LazyBindOff = info.LazyBindOff
-			l.LazyBindSize = info.LazyBindSize
-			l.ExportOff = info.ExportOff
-			l.ExportSize = info.ExportSize
-			f.Loads = append(f.Loads, l)
-		case types.LC_LOAD_UPWARD_DYLIB:
-			var hdr types.LoadUpwardDylibCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_LOAD_UPWARD_DYLIB: %v", err)
-			}
-			l := new(UpwardDylib)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in load upwardl dylib command", hdr.NameOffset}
-			}
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			l.Timestamp = hdr.Timestamp
-			l.CurrentVersion = hdr.CurrentVersion
-			l.CompatVersion = hdr.CompatVersion
-			f.Loads = append(f.Loads, l)
-		case types.LC_VERSION_MIN_MACOSX:
-			var verMin types.VersionMinMacOSCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &verMin); err != nil {
-				return nil, fmt.Errorf("failed to read LC_VERSION_MIN_MACOSX: %v", err)
-			}
-			l := new(VersionMinMacOSX)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Version = verMin.Version
-			l.Sdk = verMin.Sdk
-			f.Loads = append(f.Loads, l)
-		case types.LC_VERSION_MIN_IPHONEOS:
-			var verMin types.VersionMinIPhoneOSCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &verMin); err != nil {
-				return nil, fmt.Errorf("failed to read LC_VERSION_MIN_IPHONEOS: %v", err)
-			}
-			l := new(VersionMiniPhoneOS)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Version = verMin.Version
-			l.Sdk = verMin.Sdk
-			f.Loads = append(f.Loads, l)
-		case types.LC_FUNCTION_STARTS:
-			var led types.LinkEditDataCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &led); err != nil {
-				return nil, fmt.Errorf("failed to read LC_FUNCTION_STARTS: %v", err)
-			}
-
-			l := new(FunctionStarts)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = led.Offset
-			l.Size = led.Size
-			f.Loads = append(f.Loads, l)
-		case types.LC_DYLD_ENVIRONMENT:
-			var hdr types.DyldEnvironmentCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_DYLD_ENVIRONMENT: %v", err)
-			}
-			l := new(DyldEnvironment)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.NameOffset = hdr.NameOffset
-			if hdr.NameOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in dyld environment command", hdr.NameOffset}
-			}
-			l.Name = cstring(cmddat[hdr.NameOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_MAIN:
-			var hdr types.EntryPointCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_MAIN: %v", err)
-			}
-			l := new(EntryPoint)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.EntryOffset = hdr.EntryOffset
-			l.StackSize = hdr.StackSize
-			f.Loads = append(f.Loads, l)
-		case types.LC_DATA_IN_CODE:
-			var led types.LinkEditDataCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &led); err != nil {
-				return nil, fmt.Errorf("failed to read LC_DATA_IN_CODE: %v", err)
-			}
-			l := new(DataInCode)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = led.Offset
-			l.Size = led.Size
-			ldat := make([]byte, l.Size)
-			if _, err := f.cr.ReadAt(ldat, int64(l.Offset)); err != nil {
-				return nil, fmt.Errorf("failed to read DataInCode data at offset=%#x; %v", int64(led.Offset), err)
-			}
-			l.Entries = make([]types.DataInCodeEntry, len(ldat)/binary.Size(types.DataInCodeEntry{}))
-			if err := binary.Read(bytes.NewReader(ldat), bo, &l.Entries); err != nil {
-				return nil, fmt.Errorf("failed to read LC_DATA_IN_CODE entries: %v", err)
-			}
-			f.Loads = append(f.Loads, l)
-		case types.LC_SOURCE_VERSION:
-			var sv types.SourceVersionCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &sv); err != nil {
-				return nil, fmt.Errorf("failed to read LC_SOURCE_VERSION: %v", err)
-			}
-			l := new(SourceVersion)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Version = sv.Version
-			f.Loads = append(f.Loads, l)
-		case types.LC_DYLIB_CODE_SIGN_DRS:
-			var led types.LinkEditDataCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &led); err != nil {
-				return nil, fmt.Errorf("failed to read LC_DYLIB_CODE_SIGN_DRS: %v", err)
-			}
-
-			l := new(DylibCodeSignDrs)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = led.Offset
-			l.Size = led.Size
-			f.Loads = append(f.Loads, l)
-		case types.LC_ENCRYPTION_INFO_64:
-			var ei types.EncryptionInfo64Cmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &ei); err != nil {
-				return nil, fmt.Errorf("failed to read LC_ENCRYPTION_INFO_64: %v", err)
-			}
-			l := new(EncryptionInfo64)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = ei.Offset
-			l.Size = ei.Size
-			l.CryptID = ei.CryptID
-			f.Loads = append(f.Loads, l)
-		case types.LC_LINKER_OPTION:
-			var lo types.LinkerOptionCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &lo); err != nil {
-				return nil, fmt.Errorf("failed to read LC_LINKER_OPTION: %v", err)
-			}
-			l := new(LinkerOption)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			for i := 0; i < int(lo.Count); i++ {
-				o, err := bufio.NewReader(b).ReadString('\x00')
-				if err != nil {
-					break // FIXME: should this error?
-				}
-				l.Options = append(l.Options, o)
-			}
-			f.Loads = append(f.Loads, l)
-		case types.LC_LINKER_OPTIMIZATION_HINT:
-			var led types.LinkEditDataCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &led); err != nil {
-				return nil, fmt.Errorf("failed to read LC_LINKER_OPTIMIZATION_HINT: %v", err)
-			}
-
-			l := new(LinkerOptimizationHint)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = led.Offset
-			l.Size = led.Size
-			f.Loads = append(f.Loads, l)
-		case types.LC_VERSION_MIN_TVOS:
-			var verMin types.VersionMinMacOSCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &verMin); err != nil {
-				return nil, fmt.Errorf("failed to read LC_VERSION_MIN_TVOS: %v", err)
-			}
-			l := new(VersionMinTvOS)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Version = verMin.Version
-			l.Sdk = verMin.Sdk
-			f.Loads = append(f.Loads, l)
-		case types.LC_VERSION_MIN_WATCHOS:
-			var verMin types.VersionMinWatchOSCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &verMin); err != nil {
-				return nil, fmt.Errorf("failed to read LC_VERSION_MIN_WATCHOS: %v", err)
-			}
-			l := new(VersionMinWatchOS)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Version = verMin.Version
-			l.Sdk = verMin.Sdk
-			f.Loads = append(f.Loads, l)
-		case types.LC_NOTE:
-			var n types.NoteCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &n); err != nil {
-				return nil, fmt.Errorf("failed to read LC_NOTE: %v", err)
-			}
-			l := new(Note)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.DataOwner = n.DataOwner
-			l.Offset = n.Offset
-			l.Size = n.Size
-			f.Loads = append(f.Loads, l)
-		case types.LC_BUILD_VERSION:
-			var build types.BuildVersionCmd
-			var buildTool types.BuildVersionTool
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &build); err != nil {
-				return nil, fmt.Errorf("failed to read LC_BUILD_VERSION: %v", err)
-			}
-			l := new(BuildVersion)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Platform = build.Platform
-			l.Minos = build.Minos
-			l.Sdk = build.Sdk
-			l.NumTools = build.NumTools
-			for i := uint32(0); i < build.NumTools; i++ {
-				if err := binary.Read(b, bo, &buildTool); err != nil {
-					return nil, fmt.Errorf("failed to read LC_BUILD_VERSION buildTool: %v", err)
-				}
-				l.Tools = append(l.Tools, types.BuildVersionTool{
-					Tool:    buildTool.Tool,
-					Version: buildTool.Version,
-				})
-			}
-			f.Loads = append(f.Loads, l)
-		case types.LC_DYLD_EXPORTS_TRIE:
-			var led types.LinkEditDataCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &led); err != nil {
-				return nil, fmt.Errorf("failed to read LC_DYLD_EXPORTS_TRIE: %v", err)
-			}
-
-			l := new(DyldExportsTrie)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = led.Offset
-			l.Size = led.Size
-			f.Loads = append(f.Loads, l)
-		case types.LC_DYLD_CHAINED_FIXUPS:
-			var led types.DyldChainedFixupsCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &led); err != nil {
-				return nil, fmt.Errorf("failed to read LC_DYLD_CHAINED_FIXUPS: %v", err)
-			}
-
-			l := new(DyldChainedFixups)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = led.Offset
-			l.Size = led.Size
-			f.Loads = append(f.Loads, l)
-		case types.LC_FILESET_ENTRY:
-			var hdr types.FilesetEntryCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &hdr); err != nil {
-				return nil, fmt.Errorf("failed to read LC_FILESET_ENTRY: %v", err)
-			}
-			l := new(FilesetEntry)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Addr = hdr.Addr
-			l.FileOffset = hdr.FileOffset
-			l.EntryIdOffset = hdr.EntryIdOffset
-			if hdr.EntryIdOffset >= uint32(len(cmddat)) {
-				return nil, &FormatError{offset, "invalid name in load fileset entry command", hdr.EntryIdOffset}
-			}
-			l.EntryID = cstring(cmddat[hdr.EntryIdOffset:])
-			f.Loads = append(f.Loads, l)
-		case types.LC_ATOM_INFO:
-			var led types.LinkEditDataCmd
-			b := bytes.NewReader(cmddat)
-			if err := binary.Read(b, bo, &led); err != nil {
-				return nil, fmt.Errorf("failed to read LC_ATOM_INFO: %v", err)
-			}
-			l := new(AtomInfo)
-			l.LoadBytes = cmddat
-			l.LoadCmd = cmd
-			l.Len = siz
-			l.Offset = led.Offset
-			l.Size = led.Size
-			f.Loads = append(f.Loads, l)
-		}
-		if s != nil {
-			if int64(s.Offset) < 0 {
-				return nil, &FormatError{offset, "invalid section offset", s.Offset}
-			}
-			if int64(s.Filesz) < 0 {
-				return nil, &FormatError{offset, "invalid section file size", s.Filesz}
-			}
-			// s.sr = io.NewSectionReader(r, int64(s.Offset), int64(s.Filesz))
-			s.ReaderAt = f.sr
+			l.Options = append(l.Options, o)
+		}
+		f.Loads = append(f.Loads, l)
+	case types.LC_LINKER_OPTIMIZATION_HINT:
+		var led types.LinkEditDataCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &led); err != nil {
+			return fmt.Errorf("failed to read LC_LINKER_OPTIMIZATION_HINT: %v", err)
+		}
+
+		l := new(LinkerOptimizationHint)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = led.Offset
+		l.Size = led.Size
+		f.Loads = append(f.Loads, l)
+	case types.LC_VERSION_MIN_TVOS:
+		var verMin types.VersionMinMacOSCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &verMin); err != nil {
+			return fmt.Errorf("failed to read LC_VERSION_MIN_TVOS: %v", err)
+		}
+		l := new(VersionMinTvOS)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Version = verMin.Version
+		l.Sdk = verMin.Sdk
+		f.Loads = append(f.Loads, l)
+	case types.LC_VERSION_MIN_WATCHOS:
+		var verMin types.VersionMinWatchOSCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &verMin); err != nil {
+			return fmt.Errorf("failed to read LC_VERSION_MIN_WATCHOS: %v", err)
+		}
+		l := new(VersionMinWatchOS)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Version = verMin.Version
+		l.Sdk = verMin.Sdk
+		f.Loads = append(f.Loads, l)
+	case types.LC_NOTE:
+		var n types.NoteCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &n); err != nil {
+			return fmt.Errorf("failed to read LC_NOTE: %v", err)
+		}
+		l := new(Note)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.DataOwner = n.DataOwner
+		l.Offset = n.Offset
+		l.Size = n.Size
+		f.Loads = append(f.Loads, l)
+	case types.LC_BUILD_VERSION:
+		var build types.BuildVersionCmd
+		var buildTool types.BuildVersionTool
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &build); err != nil {
+			return fmt.Errorf("failed to read LC_BUILD_VERSION: %v", err)
+		}
+		l := new(BuildVersion)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Platform = build.Platform
+		l.Minos = build.Minos
+		l.Sdk = build.Sdk
+		l.NumTools = build.NumTools
+		if uint64(build.NumTools)*uint64(binary.Size(buildTool)) > uint64(b.Len()) {
+			return &FormatError{offset, "invalid number of tools in LC_BUILD_VERSION command", build.NumTools}
 		}
+		for i := uint32(0); i < build.NumTools; i++ {
+			if err := binary.Read(b, bo, &buildTool); err != nil {
+				return fmt.Errorf("failed to read LC_BUILD_VERSION buildTool: %v", err)
+			}
+			l.Tools = append(l.Tools, types.BuildVersionTool{
+				Tool:    buildTool.Tool,
+				Version: buildTool.Version,
+			})
+		}
+		f.Loads = append(f.Loads, l)
+	case types.LC_DYLD_EXPORTS_TRIE:
+		var led types.LinkEditDataCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &led); err != nil {
+			return fmt.Errorf("failed to read LC_DYLD_EXPORTS_TRIE: %v", err)
+		}
+
+		l := new(DyldExportsTrie)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = led.Offset
+		l.Size = led.Size
+		f.Loads = append(f.Loads, l)
+	case types.LC_DYLD_CHAINED_FIXUPS:
+		var led types.DyldChainedFixupsCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &led); err != nil {
+			return fmt.Errorf("failed to read LC_DYLD_CHAINED_FIXUPS: %v", err)
+		}
+
+		l := new(DyldChainedFixups)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = led.Offset
+		l.Size = led.Size
+		f.Loads = append(f.Loads, l)
+	case types.LC_FILESET_ENTRY:
+		var hdr types.FilesetEntryCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &hdr); err != nil {
+			return fmt.Errorf("failed to read LC_FILESET_ENTRY: %v", err)
+		}
+		l := new(FilesetEntry)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Addr = hdr.Addr
+		l.FileOffset = hdr.FileOffset
+		l.EntryIdOffset = hdr.EntryIdOffset
+		if hdr.EntryIdOffset >= uint32(len(cmddat)) {
+			return &FormatError{offset, "invalid name in load fileset entry command", hdr.EntryIdOffset}
+		}
+		l.EntryID = cstring(cmddat[hdr.EntryIdOffset:])
+		f.Loads = append(f.Loads, l)
+	case types.LC_ATOM_INFO:
+		var led types.LinkEditDataCmd
+		b := bytes.NewReader(cmddat)
+		if err := binary.Read(b, bo, &led); err != nil {
+			return fmt.Errorf("failed to read LC_ATOM_INFO: %v", err)
+		}
+		l := new(AtomInfo)
+		l.LoadBytes = cmddat
+		l.LoadCmd = cmd
+		l.Len = siz
+		l.Offset = led.Offset
+		l.Size = led.Size
+		f.Loads = append(f.Loads, l)
 	}
-	return f, nil
+	if s != nil {
+		if err := f.checkSegment(s, offset); err != nil {
+			// drop the half-parsed segment (it has no reader), in permissive mode its raw load command is kept instead
+			f.Loads = f.Loads[:len(f.Loads)-1]
+			return err
+		}
+		s.sr = io.NewSectionReader(f.cr, int64(s.Offset), int64(s.Filesz))
+		s.ReaderAt = f.sr
+	}
+	return nil
+}
+
+// checkSegment checks that the file range of segment s and of its sections lie within the file
+func (f *File) checkSegment(s *Segment, offset int64) error {
+	if int64(s.Offset) < 0 {
+		return &FormatError{offset, "invalid section offset", s.Offset}
+	}
+	if int64(s.Filesz) < 0 {
+		return &FormatError{offset, "invalid section file size", s.Filesz}
+	}
+	if s.Filesz > 0 {
+		if err := f.checkTable("segment "+s.Name, s.Offset, s.Filesz, 1); err != nil {
+			return err
+		}
+		for _, sec := range s.sections {
+			if sec.IsZerofill() || sec.Size == 0 {
+				continue
+			}
+			if err := f.checkTable("section "+sec.Seg+"."+sec.Name, uint64(sec.Offset), sec.Size, 1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 func (f *File) parseSymtab(symdat, strtab, cmddat []byte, hdr *types.SymtabCmd, offset int64) (*Symtab, error) {
@@ -1292,13 +1462,17 @@ func (f *File) parseSymtab(symdat, strtab, cmddat []byte, hdr *types.SymtabCmd,
 				name = name[1:]
 			}
 		}
-		symtab = append(symtab, Symbol{
+		sym := Symbol{
 			Name:  name,
 			Type:  n.Type,
 			Sect:  n.Sect,
 			Desc:  n.Desc,
 			Value: n.Value,
-		})
+		}
+		if f.demangleSymbols {
+			sym.demangled = demangle.Filter(name)
+		}
+		symtab = append(symtab, sym)
 	}
 	st := new(Symtab)
 	st.LoadBytes = LoadBytes(cmddat)
@@ -1313,55 +1487,180 @@ func (f *File) parseSymtab(symdat, strtab, cmddat []byte, hdr *types.SymtabCmd,
 
 func (f *File) pushSection(sh *types.Section, r io.ReaderAt) error {
 	f.Sections = append(f.Sections, sh)
+	if f.lazy {
+		return nil
+	}
+	return f.readRelocs(sh, r)
+}
 
+// readRelocs reads the relocations of the section sh
+func (f *File) readRelocs(sh *types.Section, r io.ReaderAt) error {
 	if sh.Nreloc > 0 {
-		reldat, err := saferio.ReadDataAt(r, uint64(sh.Nreloc)*8, int64(sh.Reloff))
+		relocs, err := f.parseRelocs(r, sh.Reloff, sh.Nreloc)
 		if err != nil {
-			return fmt.Errorf("failed to read data at Reloff @ %#x: %w", int64(sh.Reloff), err)
+			return err
 		}
-		b := bytes.NewReader(reldat)
+		sh.Relocs = relocs
+	}
+	return nil
+}
+
+// parseRelocs reads the n relocation entries at the file offset off
+func (f *File) parseRelocs(r io.ReaderAt, off, n uint32) ([]types.Reloc, error) {
+	if err := checkLimit("relocations", int64(off), n, f.limits.MaxRelocs); err != nil {
+		return nil, err
+	}
+	if err := f.checkTable("relocations", uint64(off), uint64(n), 8); err != nil {
+		return nil, err
+	}
+	reldat, err := saferio.ReadDataAt(r, uint64(n)*8, int64(off))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data at Reloff @ %#x: %w", int64(off), err)
+	}
+	b := bytes.NewReader(reldat)
+
+	bo := f.ByteOrder
 
-		bo := f.ByteOrder
+	relocs := make([]types.Reloc, n)
+	for i := range relocs {
+		rel := &relocs[i]
 
-		sh.Relocs = make([]types.Reloc, sh.Nreloc)
-		for i := range sh.Relocs {
-			rel := &sh.Relocs[i]
+		var ri types.RelocInfo
+		if err := binary.Read(b, bo, &ri); err != nil {
+			return nil, fmt.Errorf("failed to read types.RelocInfo: %w", err)
+		}
 
-			var ri types.RelocInfo
-			if err := binary.Read(b, bo, &ri); err != nil {
-				return fmt.Errorf("failed to read types.RelocInfo: %w", err)
+		if ri.Addr&(1<<31) != 0 { // scattered
+			rel.Addr = ri.Addr & (1<<24 - 1)
+			rel.Type = uint8((ri.Addr >> 24) & (1<<4 - 1))
+			rel.Len = uint8((ri.Addr >> 28) & (1<<2 - 1))
+			rel.Pcrel = ri.Addr&(1<<30) != 0
+			rel.Value = ri.Symnum
+			rel.Scattered = true
+		} else {
+			switch bo {
+			case binary.LittleEndian:
+				rel.Addr = ri.Addr
+				rel.Value = ri.Symnum & (1<<24 - 1)
+				rel.Pcrel = ri.Symnum&(1<<24) != 0
+				rel.Len = uint8((ri.Symnum >> 25) & (1<<2 - 1))
+				rel.Extern = ri.Symnum&(1<<27) != 0
+				rel.Type = uint8((ri.Symnum >> 28) & (1<<4 - 1))
+			case binary.BigEndian:
+				rel.Addr = ri.Addr
+				rel.Value = ri.Symnum >> 8
+				rel.Pcrel = ri.Symnum&(1<<7) != 0
+				rel.Len = uint8((ri.Symnum >> 5) & (1<<2 - 1))
+				rel.Extern = ri.Symnum&(1<<4) != 0
+				rel.Type = uint8(ri.Symnum & (1<<4 - 1))
+			default:
+				panic("unreachable")
 			}
+		}
+	}
+
+	return relocs, nil
+}
+
+// readDysymtabRelocs reads the external and local relocations of the LC_DYSYMTAB st
+func (f *File) readDysymtabRelocs(st *Dysymtab) error {
+	var err error
+	if st.Nextrel > 0 {
+		if st.ExternalRelocs, err = f.parseRelocs(f.cr, st.Extreloff, st.Nextrel); err != nil {
+			return fmt.Errorf("failed to read external relocations: %v", err)
+		}
+	}
+	if st.Nlocrel > 0 {
+		if st.LocalRelocs, err = f.parseRelocs(f.cr, st.Locreloff, st.Nlocrel); err != nil {
+			return fmt.Errorf("failed to read local relocations: %v", err)
+		}
+	}
+	return nil
+}
 
-			if ri.Addr&(1<<31) != 0 { // scattered
-				rel.Addr = ri.Addr & (1<<24 - 1)
-				rel.Type = uint8((ri.Addr >> 24) & (1<<4 - 1))
-				rel.Len = uint8((ri.Addr >> 28) & (1<<2 - 1))
-				rel.Pcrel = ri.Addr&(1<<30) != 0
-				rel.Value = ri.Symnum
-				rel.Scattered = true
+// readDylibTables reads the table of contents, module table and reference symbol table of the LC_DYSYMTAB
+// of an old-style dylib
+func (f *File) readDylibTables(st *Dysymtab) error {
+	if st.Ntoc > 0 {
+		if err := f.checkTable("table of contents", uint64(st.Tocoffset), uint64(st.Ntoc), 8); err != nil {
+			return err
+		}
+		dat, err := saferio.ReadDataAt(f.cr, uint64(st.Ntoc)*8, int64(st.Tocoffset))
+		if err != nil {
+			return fmt.Errorf("failed to read data at Tocoffset @ %#x: %w", int64(st.Tocoffset), err)
+		}
+		st.TOC = make([]types.DylibTableOfContents, st.Ntoc)
+		if err := binary.Read(bytes.NewReader(dat), f.ByteOrder, st.TOC); err != nil {
+			return fmt.Errorf("failed to read table of contents: %v", err)
+		}
+	}
+	if st.Nmodtab > 0 {
+		size := uint64(binary.Size(types.DylibModule{}))
+		if f.is64bit() {
+			size = uint64(binary.Size(types.DylibModule64{}))
+		}
+		if err := f.checkTable("module table", uint64(st.Modtaboff), uint64(st.Nmodtab), size); err != nil {
+			return err
+		}
+		dat, err := saferio.ReadDataAt(f.cr, uint64(st.Nmodtab)*size, int64(st.Modtaboff))
+		if err != nil {
+			return fmt.Errorf("failed to read data at Modtaboff @ %#x: %w", int64(st.Modtaboff), err)
+		}
+		b := bytes.NewReader(dat)
+		st.Modules = make([]DylibModule, st.Nmodtab)
+		for i := range st.Modules {
+			mod := &st.Modules[i]
+			if f.is64bit() {
+				if err := binary.Read(b, f.ByteOrder, &mod.DylibModule64); err != nil {
+					return fmt.Errorf("failed to read module table entry %d: %v", i, err)
+				}
 			} else {
-				switch bo {
-				case binary.LittleEndian:
-					rel.Addr = ri.Addr
-					rel.Value = ri.Symnum & (1<<24 - 1)
-					rel.Pcrel = ri.Symnum&(1<<24) != 0
-					rel.Len = uint8((ri.Symnum >> 25) & (1<<2 - 1))
-					rel.Extern = ri.Symnum&(1<<27) != 0
-					rel.Type = uint8((ri.Symnum >> 28) & (1<<4 - 1))
-				case binary.BigEndian:
-					rel.Addr = ri.Addr
-					rel.Value = ri.Symnum >> 8
-					rel.Pcrel = ri.Symnum&(1<<7) != 0
-					rel.Len = uint8((ri.Symnum >> 5) & (1<<2 - 1))
-					rel.Extern = ri.Symnum&(1<<4) != 0
-					rel.Type = uint8(ri.Symnum & (1<<4 - 1))
-				default:
-					panic("unreachable")
+				var m32 types.DylibModule
+				if err := binary.Read(b, f.ByteOrder, &m32); err != nil {
+					return fmt.Errorf("failed to read module table entry %d: %v", i, err)
+				}
+				mod.DylibModule64 = types.DylibModule64{
+					ModuleName:         m32.ModuleName,
+					Iextdefsym:         m32.Iextdefsym,
+					Nextdefsym:         m32.Nextdefsym,
+					Irefsym:            m32.Irefsym,
+					Nrefsym:            m32.Nrefsym,
+					Ilocalsym:          m32.Ilocalsym,
+					Nlocalsym:          m32.Nlocalsym,
+					Iextrel:            m32.Iextrel,
+					Nextrel:            m32.Nextrel,
+					IinitIterm:         m32.IinitIterm,
+					NinitNterm:         m32.NinitNterm,
+					ObjcModuleInfoSize: m32.ObjcModuleInfoSize,
+					ObjcModuleInfoAddr: uint64(m32.ObjcModuleInfoAddr),
+				}
+			}
+			if f.Symtab != nil && mod.ModuleName < f.Symtab.Strsize {
+				if mod.Name, err = f.GetCStringAtOffset(int64(f.Symtab.Stroff) + int64(mod.ModuleName)); err != nil {
+					return fmt.Errorf("failed to read module table entry %d name: %v", i, err)
 				}
 			}
 		}
 	}
-
+	if st.Nextrefsyms > 0 {
+		if err := f.checkTable("reference symbol table", uint64(st.Extrefsymoff), uint64(st.Nextrefsyms), 4); err != nil {
+			return err
+		}
+		dat, err := saferio.ReadDataAt(f.cr, uint64(st.Nextrefsyms)*4, int64(st.Extrefsymoff))
+		if err != nil {
+			return fmt.Errorf("failed to read data at Extrefsymoff @ %#x: %w", int64(st.Extrefsymoff), err)
+		}
+		st.ExtRefSyms = make([]types.DylibReference, st.Nextrefsyms)
+		if err := binary.Read(bytes.NewReader(dat), f.ByteOrder, st.ExtRefSyms); err != nil {
+			return fmt.Errorf("failed to read reference symbol table: %v", err)
+		}
+		if f.ByteOrder == binary.LittleEndian {
+			// the isym:24 bitfield is in the low bits of little-endian entries
+			for i, ref := range st.ExtRefSyms {
+				st.ExtRefSyms[i] = ref<<8 | ref>>24
+			}
+		}
+	}
 	return nil
 }
 
@@ -1373,10 +1672,37 @@ func cstring(b []byte) string {
 	return string(b[0:i])
 }
 
-func readString(r io.Reader) (string, error) {
-	var b byte
-	var str string
-
+var cksumTable = func() (t [256]uint32) {
+	for i := range t {
+		c := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if c&0x80000000 != 0 {
+				c = (c << 1) ^ 0x04c11db7
+			} else {
+				c <<= 1
+			}
+		}
+		t[i] = c
+	}
+	return
+}()
+
+// cksum computes the POSIX cksum(1) CRC of b
+func cksum(b []byte) uint32 {
+	var crc uint32
+	for _, c := range b {
+		crc = (crc << 8) ^ cksumTable[byte(crc>>24)^c]
+	}
+	for n := uint64(len(b)); n != 0; n >>= 8 {
+		crc = (crc << 8) ^ cksumTable[byte(crc>>24)^byte(n)]
+	}
+	return ^crc
+}
+
+func readString(r io.Reader) (string, error) {
+	var b byte
+	var str string
+
 	for {
 		err := binary.Read(r, binary.BigEndian, &b)
 
@@ -1442,6 +1768,9 @@ func (f *File) GetOffset(address uint64) (uint64, error) {
 func (f *File) getOffset(address uint64) (uint64, error) {
 	for _, seg := range f.Segments() {
 		if seg.Addr <= address && address < seg.Addr+seg.Memsz {
+			if address-seg.Addr >= seg.Filesz {
+				return 0, fmt.Errorf("address %#x is in the zerofill part of segment %s (not backed by the file)", address, seg.Name)
+			}
 			return (address - seg.Addr) + seg.Offset, nil
 		}
 	}
@@ -1462,6 +1791,17 @@ func (f *File) getVMAddress(offset uint64) (uint64, error) {
 	return 0, fmt.Errorf("offset %#x not within any segment's file offset range", offset)
 }
 
+// VMReader returns the reader of the MachO's virtual memory (at its unslid vmaddrs)
+func (f *File) VMReader() types.VMReader {
+	return f.vmr
+}
+
+// ParseWarnings returns the load commands that failed to parse when the File was opened in permissive mode
+// (see FileConfig.Permissive), as well as the LC_DYLIB_CODE_SIGN_DRS requirements that failed to decode
+func (f *File) ParseWarnings() []*LoadCmdError {
+	return f.warnings
+}
+
 // GetBaseAddress returns the MachO's preferred load address
 func (f *File) GetBaseAddress() uint64 {
 	return f.preferredLoadAddress()
@@ -1479,18 +1819,72 @@ func (f *File) GetPointer(offset uint64) (uint64, error) {
 	return f.vma.Convert(ptr), nil
 }
 
-// GetPointerAtAddress returns pointer at a given virtual address
+// GetPointerAtAddress returns the pointer at a given virtual address with its fixup applied. Chained fixups
+// are decoded with the pointer format of the slot's segment: rebases (including arm64e authenticated ones)
+// return the target address, binds to the MachO itself the symbol's address and other binds the raw
+// pointer (see GetBindName); slots that aren't part of a fixup chain are returned as is.
 func (f *File) GetPointerAtAddress(address uint64) (uint64, error) {
-	if err := f.cr.SeekToAddr(address); err != nil {
-		return 0, fmt.Errorf("failed to Seek to address %#x: %v", address, err)
+	ptr, err := f.vmr.ReadPointer(address)
+	if err != nil {
+		return 0, err
 	}
-	var ptr uint64
-	if err := binary.Read(f.cr, binary.LittleEndian, &ptr); err != nil {
-		return 0, fmt.Errorf("failed to read pointer @ %#x: %v", address, err)
+	// the pointers of an in-memory image are already fixed up
+	if f.HasDyldChainedFixups() && !f.inMemory {
+		if target, ok := f.chainedPointer(address, ptr); ok {
+			return target, nil
+		}
 	}
 	return f.vma.Convert(ptr), nil
 }
 
+// chainedFixupFormat returns the pointer format of the chained fixup at file offset off (if there is one)
+func (f *File) chainedFixupFormat(off uint64) (fixupchains.DCPtrKind, bool, error) {
+	dcf, err := f.DyldChainedFixups()
+	if err != nil {
+		return 0, false, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fixups == nil {
+		f.fixups = make(map[uint64]fixupchains.DCPtrKind)
+		for _, start := range dcf.Starts {
+			for _, fixup := range start.Fixups {
+				f.fixups[fixup.Offset()] = start.PointerFormat
+			}
+		}
+	}
+	format, ok := f.fixups[off]
+	return format, ok, nil
+}
+
+// chainedPointer applies the chained fixup (if any) of the pointer slot at address (returns false if the
+// chained fixups can't be parsed)
+func (f *File) chainedPointer(address, ptr uint64) (uint64, bool) {
+	off, err := f.GetOffset(address)
+	if err != nil {
+		return 0, false
+	}
+	format, ok, err := f.chainedFixupFormat(off)
+	if err != nil {
+		return 0, false
+	}
+	if !ok {
+		return ptr, true // not a fixup
+	}
+	dcf := *f.dcf
+	dcf.PointerFormat = format
+	base := f.preferredLoadAddress()
+	if target, ok := dcf.IsRebase(ptr, base); ok {
+		return target + base, true
+	}
+	if bind, addend, ok := dcf.IsBind(ptr); ok && bind.Import.LibOrdinal() == types.BIND_SPECIAL_DYLIB_SELF {
+		if addr, err := f.FindSymbolAddress(bind.Name); err == nil {
+			return uint64(int64(addr) + addend), true
+		}
+	}
+	return ptr, true
+}
+
 // SlidePointer returns slid or un-chained pointer
 func (f *File) SlidePointer(ptr uint64) uint64 {
 	return f.vma.Convert(ptr)
@@ -1572,20 +1966,7 @@ func (f *File) GetBindName(pointer uint64) (string, error) {
 
 // GetCString returns a c-string at a given virtual address in the MachO
 func (f *File) GetCString(addr uint64) (string, error) {
-	if err := f.cr.SeekToAddr(addr); err != nil {
-		return "", fmt.Errorf("failed to Seek to address %#x: %v", addr, err)
-	}
-
-	s, err := bufio.NewReader(f.cr).ReadString('\x00')
-	if err != nil {
-		return "", fmt.Errorf("failed to read strubg at address %#x, %v", addr, err)
-	}
-
-	if len(s) > 0 {
-		return strings.Trim(s, "\x00"), nil
-	}
-
-	return "", fmt.Errorf("string not found at address %#x", addr)
+	return f.vmr.ReadCString(addr)
 }
 
 // GetCStringAtOffset returns a c-string at a given offset into the MachO
@@ -1594,16 +1975,12 @@ func (f *File) GetCStringAtOffset(strOffset int64) (string, error) {
 		return "", fmt.Errorf("failed to Seek to offset %#x: %v", strOffset, err)
 	}
 
-	s, err := bufio.NewReader(f.cr).ReadString('\x00')
+	s, err := types.ReadCString(f.cr)
 	if err != nil {
 		return "", fmt.Errorf("failed to ReadString as offset %#x, %v", strOffset, err)
 	}
 
-	if len(s) > 0 {
-		return strings.Trim(s, "\x00"), nil
-	}
-
-	return "", fmt.Errorf("string not found at offset %#x", strOffset)
+	return s, nil
 }
 
 // IsCString returns cstring at given virtual address if is in a CstringLiterals section
@@ -1656,16 +2033,8 @@ func (f *File) Segments() Segments {
 
 // GetSectionsForSegment returns all the segment's sections or nil if it doesn't have any
 func (f *File) GetSectionsForSegment(name string) []*types.Section {
-	var secs []*types.Section
-	if seg := f.Segment(name); seg != nil {
-		if seg.Nsect > 0 {
-			for i := uint32(0); i < seg.Nsect; i++ {
-				if int(i+seg.Firstsect) < len(f.Sections) {
-					secs = append(secs, f.Sections[i+seg.Firstsect])
-				}
-			}
-			return secs
-		}
+	if seg := f.Segment(name); seg != nil && len(seg.Sections()) > 0 {
+		return seg.Sections()
 	}
 	return nil
 }
@@ -1673,6 +2042,12 @@ func (f *File) GetSectionsForSegment(name string) []*types.Section {
 // Section returns the section with the given name in the given segment,
 // or nil if no such section exists.
 func (f *File) Section(segment, section string) *types.Section {
+	if seg := f.Segment(segment); seg != nil {
+		if sec := seg.Section(section); sec != nil {
+			return sec
+		}
+	}
+	// i.e. MH_OBJECT sections are all in a single unnamed segment
 	for _, sec := range f.Sections {
 		if sec.Seg == segment && sec.Name == section {
 			return sec
@@ -1681,11 +2056,62 @@ func (f *File) Section(segment, section string) *types.Section {
 	return nil
 }
 
+// addrIndex is the segments and sections sorted by address (see FindSegmentForVMAddr and FindSectionForVMAddr)
+type addrIndex struct {
+	segs []*Segment
+	secs []*types.Section
+}
+
+// getAddrIndex returns the (cached) address index, rebuilding it if segments or sections were added or removed
+func (f *File) getAddrIndex() *addrIndex {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	nsegs := 0
+	for _, l := range f.Loads {
+		if _, ok := l.(*Segment); ok {
+			nsegs++
+		}
+	}
+	if f.addrs != nil && len(f.addrs.segs) == nsegs && len(f.addrs.secs) == len(f.Sections) {
+		return f.addrs
+	}
+
+	idx := &addrIndex{
+		segs: make([]*Segment, 0, nsegs),
+		secs: make([]*types.Section, 0, len(f.Sections)),
+	}
+	for _, l := range f.Loads {
+		if seg, ok := l.(*Segment); ok {
+			idx.segs = append(idx.segs, seg)
+		}
+	}
+	idx.secs = append(idx.secs, f.Sections...)
+	// keep the load command order for segments/sections at the same address (i.e. empty sections)
+	sort.SliceStable(idx.segs, func(i, j int) bool { return idx.segs[i].Addr < idx.segs[j].Addr })
+	sort.SliceStable(idx.secs, func(i, j int) bool { return idx.secs[i].Addr < idx.secs[j].Addr })
+	f.addrs = idx
+
+	return f.addrs
+}
+
+// resetAddrIndex drops the cached address index (call after moving segments or sections)
+func (f *File) resetAddrIndex() {
+	f.mu.Lock()
+	f.addrs = nil
+	f.mu.Unlock()
+}
+
 // FindSegmentForVMAddr returns the segment containing a given virtual memory ddress.
 func (f *File) FindSegmentForVMAddr(vmAddr uint64) *Segment {
-	for _, seg := range f.Segments() {
-		if seg.Addr <= vmAddr && vmAddr < seg.Addr+seg.Memsz {
-			return seg
+	segs := f.getAddrIndex().segs
+	// the last segment starting at or before vmAddr (skipping back over any empty ones)
+	for i := sort.Search(len(segs), func(i int) bool { return segs[i].Addr > vmAddr }) - 1; i >= 0; i-- {
+		if vmAddr < segs[i].Addr+segs[i].Memsz {
+			return segs[i]
+		}
+		if segs[i].Memsz > 0 {
+			break
 		}
 	}
 	return nil
@@ -1693,9 +2119,14 @@ func (f *File) FindSegmentForVMAddr(vmAddr uint64) *Segment {
 
 // FindSectionForVMAddr returns the section containing a given virtual memory ddress.
 func (f *File) FindSectionForVMAddr(vmAddr uint64) *types.Section {
-	for _, sec := range f.Sections {
-		if sec.Addr <= vmAddr && vmAddr < sec.Addr+sec.Size {
-			return sec
+	secs := f.getAddrIndex().secs
+	// the last section starting at or before vmAddr (skipping back over any empty ones)
+	for i := sort.Search(len(secs), func(i int) bool { return secs[i].Addr > vmAddr }) - 1; i >= 0; i-- {
+		if vmAddr < secs[i].Addr+secs[i].Size {
+			return secs[i]
+		}
+		if secs[i].Size > 0 {
+			break
 		}
 	}
 	return nil
@@ -1778,6 +2209,180 @@ func (f *File) VersionMin() *VersionMin {
 	return nil
 }
 
+// Platforms returns the platforms of the LC_BUILD_VERSION (or legacy LC_VERSION_MIN_*) load commands in load
+// command order, i.e. both macOS and Mac Catalyst for zippered dylibs
+func (f *File) Platforms() []types.Platform {
+	simulator := f.CPU == types.CPUI386 || f.CPU == types.CPUAmd64
+	var platforms []types.Platform
+	for _, l := range f.Loads {
+		switch v := l.(type) {
+		case *BuildVersion:
+			platforms = append(platforms, v.Platform)
+		case *VersionMinMacOSX, *VersionMiniPhoneOS, *VersionMinTvOS, *VersionMinWatchOS:
+			platforms = append(platforms, types.VersionMinPlatform(l.Command(), simulator))
+		}
+	}
+	return platforms
+}
+
+// Platform returns the (first) platform the MachO was built for, or types.Unknown if it doesn't have any
+// build version load command
+func (f *File) Platform() types.Platform {
+	if platforms := f.Platforms(); len(platforms) > 0 {
+		return platforms[0]
+	}
+	return types.Unknown
+}
+
+// IsSimulator returns true if the MachO was built for one of the simulators
+func (f *File) IsSimulator() bool {
+	return f.Platform().IsSimulator()
+}
+
+// IsMacCatalyst returns true if the MachO was built for Mac Catalyst (including zippered macOS dylibs)
+func (f *File) IsMacCatalyst() bool {
+	for _, p := range f.Platforms() {
+		if p.IsMacCatalyst() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDriverKit returns true if the MachO was built for DriverKit
+func (f *File) IsDriverKit() bool {
+	return f.Platform().IsDriverKit()
+}
+
+// SubFramework returns the umbrella framework name this library is a sub-framework of, or "" if it isn't one.
+func (f *File) SubFramework() string {
+	for _, l := range f.Loads {
+		if s, ok := l.(*SubFramework); ok {
+			return s.Framework
+		}
+	}
+	return ""
+}
+
+// SubUmbrellas returns the names of the sub-umbrella frameworks this umbrella framework re-exports.
+func (f *File) SubUmbrellas() []string {
+	var names []string
+	for _, l := range f.Loads {
+		if s, ok := l.(*SubUmbrella); ok {
+			names = append(names, s.Umbrella)
+		}
+	}
+	return names
+}
+
+// SubLibraries returns the names of the sub-libraries this umbrella framework re-exports.
+func (f *File) SubLibraries() []string {
+	var names []string
+	for _, l := range f.Loads {
+		if s, ok := l.(*SubLibrary); ok {
+			names = append(names, s.Library)
+		}
+	}
+	return names
+}
+
+// SubClients returns the names of the clients allowed to link directly against this sub-framework.
+func (f *File) SubClients() []string {
+	var names []string
+	for _, l := range f.Loads {
+		if s, ok := l.(*SubClient); ok {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+// Rpaths returns the rpath load commands.
+func (f *File) Rpaths() []*Rpath {
+	var rpaths []*Rpath
+	for _, l := range f.Loads {
+		if r, ok := l.(*Rpath); ok {
+			rpaths = append(rpaths, r)
+		}
+	}
+	return rpaths
+}
+
+// PrebindCheckSum returns the prebind checksum load command, or nil if no prebind checksum exists.
+func (f *File) PrebindCheckSum() *PrebindCheckSum {
+	for _, l := range f.Loads {
+		if p, ok := l.(*PrebindCheckSum); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// ComputePrebindCheckSum computes the LC_PREBIND_CKSUM value the way redo_prebinding(1) does for an image
+// whose check sum is still zero: a cksum(1) style CRC over the whole image with the load command's cksum field zeroed.
+func (f *File) ComputePrebindCheckSum() (uint32, error) {
+	size := f.FileSize()
+	if hsz := uint64(f.HdrSize()) + uint64(f.SizeCommands); hsz > size {
+		size = hsz
+	}
+
+	dat, err := saferio.ReadDataAt(f.cr, size, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read image data: %v", err)
+	}
+
+	// zero out the LC_PREBIND_CKSUM cksum field
+	off := uint64(f.HdrSize())
+	for i := uint32(0); i < f.NCommands && off+8 <= uint64(len(dat)); i++ {
+		cmd, siz := types.LoadCmd(f.ByteOrder.Uint32(dat[off:])), f.ByteOrder.Uint32(dat[off+4:])
+		if siz < 8 {
+			return 0, &FormatError{int64(off), "invalid command block size", nil}
+		}
+		if cmd == types.LC_PREBIND_CKSUM && off+12 <= uint64(len(dat)) {
+			f.ByteOrder.PutUint32(dat[off+8:], 0)
+		}
+		off += uint64(siz)
+	}
+
+	return cksum(dat), nil
+}
+
+// VerifyPrebindCheckSum returns true if the LC_PREBIND_CKSUM value matches the checksum of original.
+//
+// redo_prebinding(1) stores the checksum of the image as it was before its prebinding was first redone, so
+// it can't be checked against the (prebound) image itself; original is that unprebound image (for example
+// the output of redo_prebinding -u).
+func (f *File) VerifyPrebindCheckSum(original *File) (bool, error) {
+	if original == nil {
+		return false, fmt.Errorf("no original (unprebound) image to verify the LC_PREBIND_CKSUM against")
+	}
+	p := f.PrebindCheckSum()
+	if p == nil {
+		return false, fmt.Errorf("LC_PREBIND_CKSUM load command not found")
+	}
+	if p.CheckSum == 0 {
+		return false, fmt.Errorf("LC_PREBIND_CKSUM has no check sum recorded (prebinding was never redone)")
+	}
+	sum, err := original.ComputePrebindCheckSum()
+	if err != nil {
+		return false, err
+	}
+	return sum == p.CheckSum, nil
+}
+
+// InitRoutine returns the address and module index of the LC_ROUTINES/LC_ROUTINES_64 initialization routine.
+func (f *File) InitRoutine() (addr uint64, module uint64, ok bool) {
+	for _, l := range f.Loads {
+		switch r := l.(type) {
+		case *Routines:
+			return uint64(r.InitAddress), uint64(r.InitModule), true
+		case *Routines64:
+			return r.InitAddress, r.InitModule, true
+		}
+	}
+	return 0, 0, false
+}
+
 // FileSets returns an array of Fileset entries.
 func (f *File) FileSets() []*FilesetEntry {
 	var fsets []*FilesetEntry
@@ -1794,22 +2399,28 @@ func (f *File) GetFileSetFileByName(name string) (*File, error) {
 	for _, l := range f.Loads {
 		if fs, ok := l.(*FilesetEntry); ok {
 			if strings.EqualFold(fs.EntryID, name) || strings.HasSuffix(strings.ToLower(fs.EntryID), strings.ToLower(name)) {
-				return NewFile(io.NewSectionReader(f.sr, int64(fs.FileOffset), 1<<63-1), FileConfig{
-					Offset:        int64(fs.FileOffset),
-					SectionReader: f.sr,
-					CacheReader:   f.cr,
-					VMAddrConverter: types.VMAddrConverter{
-						Converter:    f.convertToVMAddr,
-						VMAddr2Offet: f.GetOffset,
-						Offet2VMAddr: f.GetVMAddress,
-					},
-				})
+				return f.fileAt(fs.FileOffset)
 			}
 		}
 	}
 	return nil, fmt.Errorf("fileset does NOT contain %s", name)
 }
 
+// fileAt returns the MachO embedded at the file offset off (whose segment file offsets are relative to f)
+func (f *File) fileAt(off uint64) (*File, error) {
+	return NewFile(io.NewSectionReader(f.sr, int64(off), 1<<63-1), FileConfig{
+		Offset:        int64(off),
+		SectionReader: f.sr,
+		CacheReader:   f.cr,
+		VMAddrConverter: types.VMAddrConverter{
+			Converter:    f.convertToVMAddr,
+			VMAddr2Offet: f.GetOffset,
+			Offet2VMAddr: f.GetVMAddress,
+		},
+		DiagnosticHandler: f.diagHandler,
+	})
+}
+
 // DataInCode returns the LC_DATA_IN_CODE, or nil if none exists.
 func (f *File) DataInCode() *DataInCode {
 	for _, l := range f.Loads {
@@ -1874,19 +2485,24 @@ func (f *File) GetFunctions(data ...byte) []types.Function {
 			return nil
 		}
 
+		start, thumb := f.thumbAddr(startVMA)
+		end, _ := f.thumbAddr(startVMA + offset)
 		funcs = append(funcs, types.Function{
-			StartAddr: startVMA,
-			EndAddr:   startVMA + offset,
+			StartAddr: start,
+			EndAddr:   end,
+			Thumb:     thumb,
 		})
 
 		startVMA += offset
 	}
 
 	// get last function
-	if s := f.FindSectionForVMAddr(startVMA); s != nil {
+	start, thumb := f.thumbAddr(startVMA)
+	if s := f.FindSectionForVMAddr(start); s != nil {
 		funcs = append(funcs, types.Function{
-			StartAddr: startVMA,
+			StartAddr: start,
 			EndAddr:   s.Addr + s.Size,
+			Thumb:     thumb,
 		})
 	}
 
@@ -1896,6 +2512,15 @@ func (f *File) GetFunctions(data ...byte) []types.Function {
 	return funcs
 }
 
+// thumbAddr returns the code address of the address addr of a 32-bit ARM MachO (function starts and exports have
+// the low bit set for Thumb code) and whether it is Thumb code
+func (f *File) thumbAddr(addr uint64) (uint64, bool) {
+	if f.CPU != types.CPUArm {
+		return addr, false
+	}
+	return addr &^ 1, addr&1 != 0
+}
+
 // GetFunctionForVMAddr returns the function containing a given virual address
 func (f *File) GetFunctionForVMAddr(addr uint64) (types.Function, error) {
 	for _, fn := range f.GetFunctions() {
@@ -1919,12 +2544,190 @@ func (f *File) GetFunctionsForRange(start, end uint64) ([]types.Function, error)
 
 func (f *File) GetFunctionData(fn types.Function) ([]byte, error) {
 	data := make([]byte, fn.EndAddr-fn.StartAddr)
-	if _, err := f.cr.ReadAtAddr(data, fn.StartAddr); err != nil {
+	if _, err := f.vmr.ReadAtVMAddr(data, fn.StartAddr); err != nil {
 		return nil, fmt.Errorf("failed to read data at address %#x: %v", fn.StartAddr, err)
 	}
 	return data, nil
 }
 
+// RecoverFunctions returns the functions found by merging LC_FUNCTION_STARTS, the symbol table, the export trie,
+// the compact unwind info and the __eh_frame FDEs (along with which of them each function was found in)
+func (f *File) RecoverFunctions() ([]types.RecoveredFunction, error) {
+	type candidate struct {
+		sources types.FunctionSource
+		end     uint64 // exact end (from an FDE)
+		thumb   bool
+	}
+	candidates := make(map[uint64]*candidate)
+	names := make(map[uint64]string)
+
+	add := func(addr uint64, src types.FunctionSource, thumb bool) *candidate {
+		addr, lowBit := f.thumbAddr(addr)
+		sec := f.FindSectionForVMAddr(addr)
+		if sec == nil || !sec.HasInstructions() {
+			return nil
+		}
+		c, ok := candidates[addr]
+		if !ok {
+			c = &candidate{}
+			candidates[addr] = c
+		}
+		c.sources |= src
+		c.thumb = c.thumb || thumb || lowBit
+		return c
+	}
+
+	for _, fn := range f.GetFunctions() {
+		add(fn.StartAddr, types.FunctionSourceFunctionStarts, fn.Thumb)
+	}
+	if f.Symtab != nil {
+		for _, sym := range f.symbols() {
+			if sym.Type.IsDebugSym() || !sym.Type.IsDefinedInSection() {
+				continue
+			}
+			if c := add(sym.Value, types.FunctionSourceSymtab, sym.IsThumb()); c != nil && sym.Name != "" {
+				addr, _ := f.thumbAddr(sym.Value)
+				if _, ok := names[addr]; !ok || sym.Type.IsExternalSym() {
+					names[addr] = sym.Name
+				}
+			}
+		}
+	}
+	if f.DyldExportsTrie() != nil && f.DyldExportsTrie().Size > 0 {
+		if exports, err := f.DyldExports(); err == nil {
+			for _, exp := range exports {
+				if exp.Flags.ReExport() || exp.Flags.StubAndResolver() {
+					continue
+				}
+				if c := add(exp.Address, types.FunctionSourceExports, false); c != nil {
+					addr, _ := f.thumbAddr(exp.Address)
+					if _, ok := names[addr]; !ok {
+						names[addr] = exp.Name
+					}
+				}
+			}
+		}
+	}
+	if entries, err := f.GetUnwindEntries(); err == nil {
+		for _, e := range entries {
+			if !e.Encoding.IsNotFunctionStart() {
+				add(e.Start, types.FunctionSourceCompactUnwind, false)
+			}
+		}
+	} else if !errors.Is(err, ErrMachOSectionNotFound) {
+		return nil, err
+	}
+	if eh, err := f.GetEHFrame(); err == nil {
+		for _, fde := range eh.FDEs {
+			if c := add(fde.PCBegin, types.FunctionSourceEHFrame, false); c != nil && fde.PCEnd > fde.PCBegin {
+				c.end = fde.PCEnd
+			}
+		}
+	} else if !errors.Is(err, ErrMachOSectionNotFound) {
+		return nil, err
+	}
+
+	starts := make([]uint64, 0, len(candidates))
+	for addr := range candidates {
+		starts = append(starts, addr)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	funcs := make([]types.RecoveredFunction, 0, len(starts))
+	for i, start := range starts {
+		c := candidates[start]
+		sec := f.FindSectionForVMAddr(start)
+		end := sec.Addr + sec.Size
+		if i+1 < len(starts) && starts[i+1] < end {
+			end = starts[i+1]
+		}
+		if c.end != 0 && c.end < end {
+			end = c.end
+		}
+		funcs = append(funcs, types.RecoveredFunction{
+			Function: types.Function{Name: names[start], StartAddr: start, EndAddr: end, Thumb: c.thumb},
+			Sources:  c.sources,
+		})
+	}
+
+	return funcs, nil
+}
+
+// FunctionForAddress returns the function containing a given virtual address along with its best-known name.
+// It uses LC_FUNCTION_STARTS when present and falls back to the symbol table.
+func (f *File) FunctionForAddress(addr uint64) (*types.Function, error) {
+	if fn, err := f.GetFunctionForVMAddr(addr); err == nil {
+		if fn.Name == "" {
+			fn.Name = f.functionName(fn.StartAddr)
+		}
+		return &fn, nil
+	}
+
+	if fn, ok := f.goSymbolForAddress(addr); ok {
+		return &types.Function{Name: fn.Name, StartAddr: fn.Entry, EndAddr: fn.End}, nil
+	}
+
+	if f.Symtab == nil {
+		return nil, fmt.Errorf("address %#016x not in any function", addr)
+	}
+
+	sec := f.FindSectionForVMAddr(addr)
+	if sec == nil {
+		return nil, fmt.Errorf("address %#016x not within any section", addr)
+	}
+
+	var fn *types.Function
+	end := sec.Addr + sec.Size
+	for _, sym := range f.symbols() {
+		if sym.Type.IsDebugSym() || !sym.Type.IsDefinedInSection() || sym.Value < sec.Addr || sym.Value >= end {
+			continue
+		}
+		if sym.Value <= addr {
+			if fn == nil || sym.Value > fn.StartAddr || (sym.Value == fn.StartAddr && fn.Name == "") {
+				fn = &types.Function{Name: sym.Name, StartAddr: sym.Value, Thumb: sym.IsThumb()}
+			}
+		}
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("address %#016x not in any function", addr)
+	}
+	fn.EndAddr = end
+	for _, sym := range f.symbols() {
+		if sym.Type.IsDebugSym() || !sym.Type.IsDefinedInSection() {
+			continue
+		}
+		if sym.Value > fn.StartAddr && sym.Value < fn.EndAddr {
+			fn.EndAddr = sym.Value
+		}
+	}
+
+	return fn, nil
+}
+
+// functionName returns the name of the symbol or export defined at the given address, or "" if there isn't one
+func (f *File) functionName(addr uint64) string {
+	if f.Symtab != nil {
+		for _, sym := range f.symbols() {
+			if sym.Value == addr && sym.Name != "" && !sym.Type.IsDebugSym() && sym.Type.IsDefinedInSection() {
+				return sym.Name
+			}
+		}
+	}
+	if f.DyldExportsTrie() != nil && f.DyldExportsTrie().Size > 0 {
+		if exports, err := f.DyldExports(); err == nil {
+			for _, exp := range exports {
+				if expAddr, _ := f.thumbAddr(exp.Address); expAddr == addr {
+					return exp.Name
+				}
+			}
+		}
+	}
+	if fn, ok := f.goSymbolForAddress(addr); ok && fn.Entry == addr {
+		return fn.Name
+	}
+	return ""
+}
+
 // CodeSignature returns the code signature, or nil if none exists.
 func (f *File) CodeSignature() *CodeSignature {
 	for _, l := range f.Loads {
@@ -1935,6 +2738,48 @@ func (f *File) CodeSignature() *CodeSignature {
 	return nil
 }
 
+// Entitlements returns the XML plist entitlements embedded in the code signature.
+func (f *File) Entitlements() (string, error) {
+	cs := f.CodeSignature()
+	if cs == nil {
+		return "", fmt.Errorf("no LC_CODE_SIGNATURE found")
+	}
+	if len(cs.Entitlements) == 0 {
+		return "", fmt.Errorf("no entitlements found in code signature")
+	}
+	return cs.Entitlements, nil
+}
+
+// DEREntitlements returns the ASN.1 DER entitlements embedded in the code signature decoded into a map.
+func (f *File) DEREntitlements() (map[string]any, error) {
+	cs := f.CodeSignature()
+	if cs == nil {
+		return nil, fmt.Errorf("no LC_CODE_SIGNATURE found")
+	}
+	if len(cs.EntitlementsDER) == 0 {
+		return nil, fmt.Errorf("no DER entitlements found in code signature")
+	}
+	return ctypes.ParseEntitlementsDER(cs.EntitlementsDER)
+}
+
+// LaunchConstraints returns the decoded launch and library constraints embedded in the code signature.
+func (f *File) LaunchConstraints() (*codesign.LaunchConstraints, error) {
+	cs := f.CodeSignature()
+	if cs == nil {
+		return nil, fmt.Errorf("no LC_CODE_SIGNATURE found")
+	}
+	return cs.LaunchConstraints()
+}
+
+// NotarizationTicket returns the notarization ticket stapled into the code signature.
+func (f *File) NotarizationTicket() (*codesign.NotarizationTicket, error) {
+	cs := f.CodeSignature()
+	if cs == nil {
+		return nil, fmt.Errorf("no LC_CODE_SIGNATURE found")
+	}
+	return cs.NotarizationTicket()
+}
+
 // DyldExportsTrie returns the dyld export trie load command, or nil if no dyld info exists.
 func (f *File) DyldExportsTrie() *DyldExportsTrie {
 	for _, l := range f.Loads {
@@ -2027,118 +2872,530 @@ func (f *File) DyldChainedFixups() (*fixupchains.DyldChainedFixups, error) {
 			if _, err := f.cr.ReadAt(data, int64(dcfLC.Offset)); err != nil {
 				return nil, fmt.Errorf("failed to read DyldChainedFixups data at offset=%#x; %v", int64(dcfLC.Offset), err)
 			}
-			dcf := fixupchains.NewChainedFixups(bytes.NewReader(data), &f.cr, f.ByteOrder)
-			if err := dcf.ParseStarts(); err != nil {
-				return nil, fmt.Errorf("failed to parse dyld chained fixup starts: %v", err)
+			dcf := fixupchains.NewChainedFixups(bytes.NewReader(data), &f.cr, f.ByteOrder)
+			if err := dcf.ParseStarts(); err != nil {
+				return nil, fmt.Errorf("failed to parse dyld chained fixup starts: %v", err)
+			}
+			segs := f.Segments()
+			for idx, start := range dcf.Starts {
+				if start.PageStarts != nil {
+					// Replacing SegmentOffset(vmaddr) with FileOffset
+					// (for static analysis of binaries with split segs
+					// since we aren't actually loading the MachO
+					// ref: void Adjustor<P>::adjustChainedFixups() in
+					// dyld-750.6/dyld3/shared-cache/AdjustDylibSegments.cpp
+					dcf.Starts[idx].SegmentOffset = segs[idx].Offset
+				}
+			}
+
+			dcf, err := dcf.Parse()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse dyld chained fixups: %v", err)
+			}
+
+			f.dcf = dcf // cache
+
+			return dcf, nil
+		}
+	}
+	return nil, fmt.Errorf("macho does not contain LC_DYLD_CHAINED_FIXUPS")
+}
+
+func (f *File) ForEachV2SplitSegReference(handler func(fromSectionIndex, fromSectionOffset, toSectionIndex, toSectionOffset uint64, kind types.SplitInfoKind)) error {
+	for _, l := range f.Loads {
+		if si, ok := l.(*SplitInfo); ok {
+			if si.Size == 0 {
+				return nil
+			}
+			data := make([]byte, si.Size)
+			if _, err := f.cr.ReadAt(data, int64(si.Offset)); err != nil {
+				return fmt.Errorf("failed to read %s data at offset=%#x; %v", types.LC_SEGMENT_SPLIT_INFO, int64(si.Offset), err)
+			}
+
+			r := bytes.NewReader(data)
+
+			var version uint8
+			if err := binary.Read(r, f.ByteOrder, &version); err != nil {
+				return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO Version: %v", err)
+			}
+			if version != types.DYLD_CACHE_ADJ_V2_FORMAT {
+				return nil
+			}
+
+			sectionCount, err := trie.ReadUleb128(r)
+			if err != nil {
+				return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO SectionCount: %v", err)
+			}
+
+			for i := uint64(0); i < sectionCount; i++ {
+				fromSectionIndex, err := trie.ReadUleb128(r)
+				if err != nil {
+					return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO fromSectionIndex: %v", err)
+				}
+				toSectionIndex, err := trie.ReadUleb128(r)
+				if err != nil {
+					return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO toSectionIndex: %v", err)
+				}
+				toOffsetCount, err := trie.ReadUleb128(r)
+				if err != nil {
+					return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO toOffsetCount: %v", err)
+				}
+
+				var toSectionOffset uint64
+				for j := uint64(0); j < toOffsetCount; j++ {
+					toSectionDelta, err := trie.ReadUleb128(r)
+					if err != nil {
+						return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO toSectionDelta: %v", err)
+					}
+					fromOffsetCount, err := trie.ReadUleb128(r)
+					if err != nil {
+						return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO fromOffsetCount: %v", err)
+					}
+
+					toSectionOffset += toSectionDelta
+					for k := uint64(0); k < fromOffsetCount; k++ {
+						kind, err := trie.ReadUleb128(r)
+						if err != nil {
+							return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO kind: %v", err)
+						}
+						if kind > 13 {
+							return fmt.Errorf("invalid LC_SEGMENT_SPLIT_INFO kind: %d", kind)
+						}
+
+						fromSectDeltaCount, err := trie.ReadUleb128(r)
+						if err != nil {
+							return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO fromSectDeltaCount: %v", err)
+						}
+
+						var fromSectionOffset uint64
+						for l := uint64(0); l < fromSectDeltaCount; l++ {
+							delta, err := trie.ReadUleb128(r)
+							if err != nil {
+								return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO delta: %v", err)
+							}
+
+							fromSectionOffset += delta
+
+							handler(fromSectionIndex, fromSectionOffset, toSectionIndex, toSectionOffset, types.SplitInfoKind(kind))
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// GetInitializers returns the addresses of the initializers (i.e. C++ static constructors) that dyld runs,
+// read from the __mod_init_func pointer sections and the __init_offsets sections of 32-bit offsets from the image base
+func (f *File) GetInitializers() ([]uint64, error) {
+	var inits []uint64
+	for _, sec := range f.Sections {
+		if !sec.Flags.IsModInitFuncPointers() && !sec.Flags.IsInitFuncOffsets() {
+			continue
+		}
+		dat := make([]byte, sec.Size)
+		if _, err := f.vmr.ReadAtVMAddr(dat, sec.Addr); err != nil {
+			return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+		}
+		if sec.Flags.IsInitFuncOffsets() {
+			for i := 0; i+4 <= len(dat); i += 4 {
+				inits = append(inits, f.preferredLoadAddress()+uint64(f.ByteOrder.Uint32(dat[i:])))
+			}
+			continue
+		}
+		ptrSize := int(f.pointerSize())
+		for i := 0; i+ptrSize <= len(dat); i += ptrSize {
+			var ptr uint64
+			if ptrSize == 8 {
+				ptr = f.ByteOrder.Uint64(dat[i:])
+			} else {
+				ptr = uint64(f.ByteOrder.Uint32(dat[i:]))
+			}
+			inits = append(inits, f.vma.Convert(ptr))
+		}
+	}
+	return inits, nil
+}
+
+// symbolBinder looks up the symbols bound to pointers
+type symbolBinder struct {
+	f     *File
+	binds map[uint64]types.Bind // classic dyld info binds by address
+}
+
+func (f *File) newSymbolBinder() (*symbolBinder, error) {
+	sb := &symbolBinder{f: f, binds: make(map[uint64]types.Bind)}
+	if f.HasDyldChainedFixups() {
+		if f.dcf == nil {
+			var err error
+			if f.dcf, err = f.DyldChainedFixups(); err != nil {
+				return nil, fmt.Errorf("failed to parse dyld chained fixups: %v", err)
+			}
+		}
+	} else if f.DyldInfo() != nil || f.DyldInfoOnly() != nil {
+		binds, err := f.GetBindInfo()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bind info: %v", err)
+		}
+		for _, bind := range binds {
+			if _, ok := sb.binds[bind.Start+bind.Offset]; !ok || bind.Kind != types.WEAK_KIND {
+				sb.binds[bind.Start+bind.Offset] = bind
+			}
+		}
+	}
+	return sb, nil
+}
+
+// lookup returns the name and dylib of the symbol bound to the pointer at address addr (whose raw value is ptr)
+func (sb *symbolBinder) lookup(addr, ptr uint64) (string, string, bool) {
+	f := sb.f
+	if f.dcf != nil {
+		if bind, _, ok := f.dcf.IsBind(ptr); ok {
+			return bind.Name, f.LibraryOrdinalName(bind.Import.LibOrdinal()), true
+		}
+		return "", "", false
+	}
+	if bind, ok := sb.binds[addr]; ok {
+		return bind.Name, bind.Dylib, true
+	}
+	// external relocations of object files
+	if sec := f.FindSectionForVMAddr(addr); sec != nil && f.Symtab != nil {
+		relocs, _ := f.Relocations(sec)
+		syms := f.symbols()
+		for _, r := range relocs {
+			if !r.Scattered && r.Extern && sec.Addr+uint64(r.Addr) == addr && int(r.Value) < len(syms) {
+				return syms[r.Value].Name, "", true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// indirectSymbol returns the symbol of the i-th entry of the indirect symbol table
+// (or false for INDIRECT_SYMBOL_LOCAL and INDIRECT_SYMBOL_ABS entries)
+func (f *File) indirectSymbol(i uint64) (Symbol, bool) {
+	if f.Dysymtab == nil || f.Symtab == nil {
+		return Symbol{}, false
+	}
+	indirectSyms, syms := f.indirectSymbols(), f.symbols()
+	if i >= uint64(len(indirectSyms)) {
+		return Symbol{}, false
+	}
+	idx := indirectSyms[i]
+	if idx&(types.INDIRECT_SYMBOL_LOCAL|types.INDIRECT_SYMBOL_ABS) != 0 || idx >= uint32(len(syms)) {
+		return Symbol{}, false
+	}
+	return syms[idx], true
+}
+
+// indirectEntrySize returns the size of the entries of a section that uses the indirect symbol table (or 0)
+func (f *File) indirectEntrySize(sec *types.Section) uint64 {
+	switch {
+	case sec.Flags.IsSymbolStubs():
+		return uint64(sec.Reserved2)
+	case sec.IsSymbolPointers():
+		return f.pointerSize()
+	}
+	return 0
+}
+
+// IndirectSymbolName returns the name of the symbol of the index-th stub or pointer of the
+// symbol stub or pointer section at address sectionAddr
+func (f *File) IndirectSymbolName(sectionAddr, index uint64) (string, error) {
+	if f.Dysymtab == nil || f.Symtab == nil {
+		return "", &FormatError{0, "missing symbol table", nil}
+	}
+	indirectSyms, err := f.IndirectSymbols()
+	if err != nil {
+		return "", err
+	}
+	for _, sec := range f.Sections {
+		if sec.Addr != sectionAddr {
+			continue
+		}
+		size := f.indirectEntrySize(sec)
+		if size == 0 {
+			continue
+		}
+		if index >= sec.Size/size {
+			return "", fmt.Errorf("index %d is past the end of %s.%s", index, sec.Seg, sec.Name)
+		}
+		i := uint64(sec.Reserved1) + index
+		if i >= uint64(len(indirectSyms)) {
+			return "", fmt.Errorf("indirect symbol %d is past the end of the indirect symbol table", i)
+		}
+		if sym, ok := f.indirectSymbol(i); ok {
+			return sym.Name, nil
+		}
+		if indirectSyms[i]&(types.INDIRECT_SYMBOL_LOCAL|types.INDIRECT_SYMBOL_ABS) != 0 {
+			return "", ErrIndirectSymbolLocal
+		}
+		return "", fmt.Errorf("indirect symbol %d has a bad symbol index %d", i, indirectSyms[i])
+	}
+	return "", fmt.Errorf("no symbol stub or pointer section at address %#x", sectionAddr)
+}
+
+// GetIndirectSymbols returns the indirect symbol table entries of all the symbol stub and pointer sections
+func (f *File) GetIndirectSymbols() ([]types.IndirectSymbol, error) {
+	if f.Dysymtab == nil || f.Symtab == nil {
+		return nil, &FormatError{0, "missing symbol table", nil}
+	}
+	indirectSyms, err := f.IndirectSymbols()
+	if err != nil {
+		return nil, err
+	}
+	var syms []types.IndirectSymbol
+	for _, sec := range f.Sections {
+		size := f.indirectEntrySize(sec)
+		if size == 0 {
+			continue
+		}
+		for i := uint64(0); i < sec.Size/size; i++ {
+			idx := uint64(sec.Reserved1) + i
+			if idx >= uint64(len(indirectSyms)) {
+				return nil, fmt.Errorf("%s.%s indirect symbol %d is past the end of the indirect symbol table", sec.Seg, sec.Name, idx)
+			}
+			sym := types.IndirectSymbol{
+				Address: sec.Addr + i*size,
+				Section: sec.Name,
+				Index:   indirectSyms[idx],
 			}
-			segs := f.Segments()
-			for idx, start := range dcf.Starts {
-				if start.PageStarts != nil {
-					// Replacing SegmentOffset(vmaddr) with FileOffset
-					// (for static analysis of binaries with split segs
-					// since we aren't actually loading the MachO
-					// ref: void Adjustor<P>::adjustChainedFixups() in
-					// dyld-750.6/dyld3/shared-cache/AdjustDylibSegments.cpp
-					dcf.Starts[idx].SegmentOffset = segs[idx].Offset
-				}
+			if s, ok := f.indirectSymbol(idx); ok {
+				sym.Name = s.Name
 			}
+			syms = append(syms, sym)
+		}
+	}
+	return syms, nil
+}
 
-			dcf, err := dcf.Parse()
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse dyld chained fixups: %v", err)
-			}
+// symbolDylib returns the name of the dylib an undefined symbol is imported from (for two-level namespace MachOs)
+func (f *File) symbolDylib(sym Symbol) string {
+	if !sym.Type.IsUndefinedSym() || !f.Flags.TwoLevel() {
+		return ""
+	}
+	return f.LibraryOrdinalName(symbolOrdinal(sym))
+}
 
-			f.dcf = dcf // cache
+// SectionForSymbol returns the section the N_SECT symbol sym is defined in (see Symbol.Section)
+func (f *File) SectionForSymbol(sym Symbol) *types.Section {
+	return sym.Section(f)
+}
 
-			return dcf, nil
-		}
+// symbolOrdinal returns the (bind) library ordinal of an undefined symbol of a two-level namespace MachO
+func symbolOrdinal(sym Symbol) int {
+	switch ord := sym.Desc.GetLibraryOrdinal(); ord {
+	case types.SELF_LIBRARY_ORDINAL:
+		return types.BIND_SPECIAL_DYLIB_SELF
+	case types.DYNAMIC_LOOKUP_ORDINAL:
+		return types.BIND_SPECIAL_DYLIB_FLAT_LOOKUP
+	case types.EXECUTABLE_ORDINAL:
+		return types.BIND_SPECIAL_DYLIB_MAIN_EXECUTABLE
+	default:
+		return int(ord)
 	}
-	return nil, fmt.Errorf("macho does not contain LC_DYLD_CHAINED_FIXUPS")
 }
 
-func (f *File) ForEachV2SplitSegReference(handler func(fromSectionIndex, fromSectionOffset, toSectionIndex, toSectionOffset uint64, kind types.SplitInfoKind)) error {
-	for _, l := range f.Loads {
-		if si, ok := l.(*SplitInfo); ok {
-			if si.Size == 0 {
-				return nil
-			}
-			data := make([]byte, si.Size)
-			if _, err := f.cr.ReadAt(data, int64(si.Offset)); err != nil {
-				return fmt.Errorf("failed to read %s data at offset=%#x; %v", types.LC_SEGMENT_SPLIT_INFO, int64(si.Offset), err)
+// GetSymbolPointers returns the slots of the non-lazy (i.e. __got) and lazy (i.e. __la_symbol_ptr) symbol pointer sections
+// and the symbols they are bound to
+func (f *File) GetSymbolPointers() ([]types.SymbolPointer, error) {
+	if f.Dysymtab == nil || f.Symtab == nil {
+		return nil, &FormatError{0, "missing symbol table", nil}
+	}
+	var sb *symbolBinder
+	var ptrs []types.SymbolPointer
+	for _, sec := range f.Sections {
+		if !sec.IsSymbolPointers() {
+			continue
+		}
+		ptrSize := f.pointerSize()
+		for i := uint64(0); i < sec.Size/ptrSize; i++ {
+			ptr := types.SymbolPointer{
+				Address: sec.Addr + i*ptrSize,
+				Section: sec.Name,
+				Lazy:    !sec.Flags.IsNonLazySymbolPointers(),
+			}
+			if sym, ok := f.indirectSymbol(uint64(sec.Reserved1) + i); ok {
+				ptr.Name = sym.Name
+				ptr.Dylib = f.symbolDylib(sym)
+			}
+			if ptr.Name == "" || ptr.Dylib == "" {
+				// fall back to the binds (i.e. for stripped indirect symbols)
+				if sb == nil {
+					var err error
+					if sb, err = f.newSymbolBinder(); err != nil {
+						return nil, err
+					}
+				}
+				var raw uint64
+				if f.dcf != nil {
+					var err error
+					if raw, err = f.readRawPointer(ptr.Address); err != nil {
+						return nil, err
+					}
+				}
+				if name, dylib, ok := sb.lookup(ptr.Address, raw); ok && (ptr.Name == "" || ptr.Name == name) {
+					ptr.Name = name
+					ptr.Dylib = dylib
+				}
 			}
+			ptrs = append(ptrs, ptr)
+		}
+	}
+	return ptrs, nil
+}
 
-			r := bytes.NewReader(data)
-
-			var version uint8
-			if err := binary.Read(r, f.ByteOrder, &version); err != nil {
-				return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO Version: %v", err)
+// GetStubs returns the entries of the symbol stub sections (i.e. __stubs and __auth_stubs) and the __stub_helper
+// lazy binding entries along with the symbols they ultimately call
+func (f *File) GetStubs() ([]types.Stub, error) {
+	if f.Dysymtab == nil || f.Symtab == nil {
+		return nil, &FormatError{0, "missing symbol table", nil}
+	}
+	var stubs []types.Stub
+	for _, sec := range f.Sections {
+		size := f.indirectEntrySize(sec)
+		if !sec.Flags.IsSymbolStubs() || size == 0 {
+			continue
+		}
+		for i := uint64(0); i < sec.Size/size; i++ {
+			stub := types.Stub{
+				Address: sec.Addr + i*size,
+				Size:    size,
+				Section: sec.Name,
 			}
-			if version != types.DYLD_CACHE_ADJ_V2_FORMAT {
-				return nil
+			if sym, ok := f.indirectSymbol(uint64(sec.Reserved1) + i); ok {
+				stub.Name = sym.Name
+				stub.Dylib = f.symbolDylib(sym)
 			}
+			stubs = append(stubs, stub)
+		}
+	}
 
-			sectionCount, err := trie.ReadUleb128(r)
+	// the lazy symbol pointers initially point to their __stub_helper entries
+	if helper := f.Section("__TEXT", "__stub_helper"); helper != nil && !f.HasDyldChainedFixups() {
+		ptrs, err := f.GetSymbolPointers()
+		if err != nil {
+			return nil, err
+		}
+		var helpers []types.Stub
+		for _, ptr := range ptrs {
+			if !ptr.Lazy {
+				continue
+			}
+			addr, err := f.readRawPointer(ptr.Address)
 			if err != nil {
-				return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO SectionCount: %v", err)
+				return nil, err
 			}
+			if addr < helper.Addr || addr >= helper.Addr+helper.Size {
+				continue
+			}
+			helpers = append(helpers, types.Stub{
+				Address: addr,
+				Section: helper.Name,
+				Name:    ptr.Name,
+				Dylib:   ptr.Dylib,
+			})
+		}
+		sort.Slice(helpers, func(i, j int) bool { return helpers[i].Address < helpers[j].Address })
+		for i := range helpers {
+			if i+1 < len(helpers) {
+				helpers[i].Size = helpers[i+1].Address - helpers[i].Address
+			} else {
+				helpers[i].Size = helper.Addr + helper.Size - helpers[i].Address
+			}
+		}
+		stubs = append(stubs, helpers...)
+	}
 
-			for i := uint64(0); i < sectionCount; i++ {
-				fromSectionIndex, err := trie.ReadUleb128(r)
-				if err != nil {
-					return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO fromSectionIndex: %v", err)
-				}
-				toSectionIndex, err := trie.ReadUleb128(r)
-				if err != nil {
-					return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO toSectionIndex: %v", err)
-				}
-				toOffsetCount, err := trie.ReadUleb128(r)
-				if err != nil {
-					return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO toOffsetCount: %v", err)
-				}
-
-				var toSectionOffset uint64
-				for j := uint64(0); j < toOffsetCount; j++ {
-					toSectionDelta, err := trie.ReadUleb128(r)
-					if err != nil {
-						return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO toSectionDelta: %v", err)
-					}
-					fromOffsetCount, err := trie.ReadUleb128(r)
-					if err != nil {
-						return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO fromOffsetCount: %v", err)
-					}
-
-					toSectionOffset += toSectionDelta
-					for k := uint64(0); k < fromOffsetCount; k++ {
-						kind, err := trie.ReadUleb128(r)
-						if err != nil {
-							return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO kind: %v", err)
-						}
-						if kind > 13 {
-							return fmt.Errorf("invalid LC_SEGMENT_SPLIT_INFO kind: %d", kind)
-						}
-
-						fromSectDeltaCount, err := trie.ReadUleb128(r)
-						if err != nil {
-							return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO fromSectDeltaCount: %v", err)
-						}
-
-						var fromSectionOffset uint64
-						for l := uint64(0); l < fromSectDeltaCount; l++ {
-							delta, err := trie.ReadUleb128(r)
-							if err != nil {
-								return fmt.Errorf("failed to read LC_SEGMENT_SPLIT_INFO delta: %v", err)
-							}
+	return stubs, nil
+}
 
-							fromSectionOffset += delta
+// readRawPointer reads the (unslid and unchained) pointer at the virtual address addr
+func (f *File) readRawPointer(addr uint64) (uint64, error) {
+	dat := make([]byte, f.pointerSize())
+	if _, err := f.vmr.ReadAtVMAddr(dat, addr); err != nil {
+		return 0, fmt.Errorf("failed to read pointer at address %#x: %v", addr, err)
+	}
+	if len(dat) == 8 {
+		return f.ByteOrder.Uint64(dat), nil
+	}
+	return uint64(f.ByteOrder.Uint32(dat)), nil
+}
 
-							handler(fromSectionIndex, fromSectionOffset, toSectionIndex, toSectionOffset, types.SplitInfoKind(kind))
-						}
-					}
-				}
+// GetInterposing returns the replacement/replacee function pairs of the __interpose sections
+func (f *File) GetInterposing() ([]types.Interpose, error) {
+	var interposes []types.Interpose
+	for _, sec := range f.Sections {
+		if !sec.Flags.IsInterposing() && sec.Name != "__interpose" {
+			continue
+		}
+		dat := make([]byte, sec.Size)
+		if _, err := f.vmr.ReadAtVMAddr(dat, sec.Addr); err != nil {
+			return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+		}
+		sb, err := f.newSymbolBinder()
+		if err != nil {
+			return nil, err
+		}
+		ptrSize := f.pointerSize()
+		readPtr := func(off uint64) uint64 {
+			if ptrSize == 8 {
+				return f.ByteOrder.Uint64(dat[off:])
+			}
+			return uint64(f.ByteOrder.Uint32(dat[off:]))
+		}
+		for off := uint64(0); off+2*ptrSize <= uint64(len(dat)); off += 2 * ptrSize {
+			i := types.Interpose{
+				Address:     sec.Addr + off,
+				Replacement: f.vma.Convert(readPtr(off)),
+			}
+			replacee := readPtr(off + ptrSize)
+			if name, dylib, ok := sb.lookup(i.Address+ptrSize, replacee); ok {
+				i.Name = name
+				i.Dylib = dylib
+			} else {
+				i.Replacee = f.vma.Convert(replacee)
+				i.Name = f.functionName(i.Replacee)
 			}
+			interposes = append(interposes, i)
 		}
 	}
-	return nil
+	return interposes, nil
+}
+
+// GetUnwindEntries returns the compact unwind info of the functions from the __TEXT,__unwind_info section
+func (f *File) GetUnwindEntries() ([]unwind.Entry, error) {
+	sec := f.Section("__TEXT", "__unwind_info")
+	if sec == nil {
+		return nil, fmt.Errorf("no __TEXT.__unwind_info section: %w", ErrMachOSectionNotFound)
+	}
+	dat := make([]byte, sec.Size)
+	if _, err := f.vmr.ReadAtVMAddr(dat, sec.Addr); err != nil {
+		return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+	}
+	info, err := unwind.ParseCompactUnwind(dat, f.ByteOrder, f.GetBaseAddress())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse compact unwind info: %v", err)
+	}
+	return info.Entries, nil
+}
+
+// GetEHFrame returns the DWARF call frame information CIEs and FDEs from the __TEXT,__eh_frame section
+func (f *File) GetEHFrame() (*unwind.EHFrame, error) {
+	sec := f.Section("__TEXT", "__eh_frame")
+	if sec == nil {
+		return nil, fmt.Errorf("no __TEXT.__eh_frame section: %w", ErrMachOSectionNotFound)
+	}
+	dat := make([]byte, sec.Size)
+	if _, err := f.vmr.ReadAtVMAddr(dat, sec.Addr); err != nil {
+		return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+	}
+	eh, err := unwind.ParseEHFrame(dat, f.ByteOrder, sec.Addr, int(f.pointerSize()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse eh_frame: %v", err)
+	}
+	return eh, nil
 }
 
 func (f *File) GetEmbeddedInfoPlist() ([]byte, error) {
@@ -2207,14 +3464,24 @@ func (f *File) GetEmbeddedLLVMBitcode() (*xar.Reader, error) {
 // DWARF returns the DWARF debug information for the Mach-O file.
 func (f *File) DWARF() (*dwarf.Data, error) {
 	dwarfSuffix := func(s *types.Section) string {
+		var suffix string
 		switch {
 		case strings.HasPrefix(s.Name, "__debug_"):
-			return s.Name[8:]
+			suffix = s.Name[8:]
 		case strings.HasPrefix(s.Name, "__zdebug_"):
-			return s.Name[9:]
+			suffix = s.Name[9:]
 		default:
 			return ""
 		}
+		// section names are truncated to 16 characters (i.e. __debug_str_offsets is __debug_str_offs)
+		if len(s.Name) == 16 {
+			for _, name := range []string{"addr", "line_str", "str_offsets", "rnglists", "loclists"} {
+				if strings.HasPrefix(name, suffix) {
+					return name
+				}
+			}
+		}
+		return suffix
 	}
 	appleSuffix := func(s *types.Section) string {
 		switch {
@@ -2272,7 +3539,8 @@ func (f *File) DWARF() (*dwarf.Data, error) {
 		return nil, err
 	}
 
-	// Look for DWARF4 .debug_types sections and DWARF5 sections.
+	// Look for DWARF4 .debug_types sections and DWARF5 (.debug_addr, .debug_line_str, .debug_str_offsets,
+	// .debug_rnglists and .debug_loclists) sections.
 	for i, s := range f.Sections {
 		suffix := dwarfSuffix(s)
 		if suffix == "" {
@@ -2323,6 +3591,92 @@ func (f *File) DWARF() (*dwarf.Data, error) {
 	return d, nil
 }
 
+// ErrDSYMNotFound is returned from FindDSYM or DWARFWithDSYM when no dSYM with a matching UUID is found
+var ErrDSYMNotFound = errors.New("no dSYM found with a matching UUID")
+
+// FindDSYM returns the path of the dSYM DWARF file matching uuid, where path is either
+// a .dSYM bundle, a DWARF file inside a bundle or the binary the dSYM was generated for
+// (in which case path.dSYM and any other .dSYM bundles next to it are searched); only MH_DSYM files match
+func FindDSYM(path string, uuid types.UUID) (string, error) {
+	var candidates []string
+	bundle := func(dir string) {
+		matches, _ := filepath.Glob(filepath.Join(dir, "Contents", "Resources", "DWARF", "*"))
+		candidates = append(candidates, matches...)
+	}
+
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		bundle(path)
+	} else {
+		if err == nil {
+			candidates = append(candidates, path)
+		}
+		bundle(path + ".dSYM")
+		bundles, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.dSYM"))
+		for _, b := range bundles {
+			if b != path+".dSYM" {
+				bundle(b)
+			}
+		}
+	}
+
+	for _, c := range candidates {
+		m, closer, err := openUUID(c, uuid)
+		if err != nil {
+			continue
+		}
+		isDSYM := m.Type == types.MH_DSYM
+		closer.Close()
+		// the binary itself (or a copy of it) shares the dSYM's UUID, so only accept dSYM companion files
+		if isDSYM {
+			return c, nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to find dSYM for %s in %s: %w", uuid, path, ErrDSYMNotFound)
+}
+
+// openUUID opens the MachO (or the slice of the universal MachO) at path with the given UUID
+func openUUID(path string, uuid types.UUID) (*File, io.Closer, error) {
+	if ff, err := OpenFat(path); err == nil {
+		for _, arch := range ff.Arches {
+			if u := arch.UUID(); u != nil && u.UUID == uuid {
+				return arch.File, ff, nil
+			}
+		}
+		ff.Close()
+		return nil, nil, ErrDSYMNotFound
+	}
+	m, err := Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u := m.UUID(); u == nil || u.UUID != uuid {
+		m.Close()
+		return nil, nil, ErrDSYMNotFound
+	}
+	return m, m, nil
+}
+
+// DWARFWithDSYM returns the DWARF debug information from the dSYM companion of the Mach-O file
+// (see FindDSYM for how path is searched); the dSYM's LC_UUID must match the Mach-O's
+func (f *File) DWARFWithDSYM(path string) (*dwarf.Data, error) {
+	u := f.UUID()
+	if u == nil {
+		return nil, fmt.Errorf("failed to find dSYM: MachO has no LC_UUID")
+	}
+	dsym, err := FindDSYM(path, u.UUID)
+	if err != nil {
+		return nil, err
+	}
+	m, c, err := openUUID(dsym, u.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dSYM %s: %v", dsym, err)
+	}
+	defer c.Close()
+
+	return m.DWARF()
+}
+
 func (f *File) GetBindInfo() (types.Binds, error) {
 	if f.binds != nil {
 		return f.binds, nil
@@ -2478,19 +3832,22 @@ func (f *File) parseBinds(r *bytes.Reader, kind types.BindKind) ([]types.Bind, e
 			}
 			bind = types.Bind{Kind: kind}
 		case types.BIND_OPCODE_SET_DYLIB_ORDINAL_IMM:
-			bind.Dylib = f.LibraryOrdinalName(int(imm))
+			bind.Ordinal = int(imm)
+			bind.Dylib = f.LibraryOrdinalName(bind.Ordinal)
 		case types.BIND_OPCODE_SET_DYLIB_ORDINAL_ULEB:
 			i, err := trie.ReadUleb128(r)
 			if err != nil {
 				return nil, err
 			}
-			bind.Dylib = f.LibraryOrdinalName(int(i))
+			bind.Ordinal = int(i)
+			bind.Dylib = f.LibraryOrdinalName(bind.Ordinal)
 		case types.BIND_OPCODE_SET_DYLIB_SPECIAL_IMM:
 			if imm == 0 {
-				bind.Dylib = f.LibraryOrdinalName(int(imm))
+				bind.Ordinal = types.BIND_SPECIAL_DYLIB_SELF
 			} else {
-				bind.Dylib = f.LibraryOrdinalName(int(types.BIND_OPCODE_MASK | imm))
+				bind.Ordinal = int(int8(types.BIND_OPCODE_MASK | imm)) // sign extended
 			}
+			bind.Dylib = f.LibraryOrdinalName(bind.Ordinal)
 		case types.BIND_OPCODE_SET_SYMBOL_TRAILING_FLAGS_IMM:
 			s, err := readString(r)
 			if err != nil {
@@ -2746,10 +4103,16 @@ func (f *File) ImportedSymbols() ([]Symbol, error) {
 		return nil, &FormatError{0, "missing symbol table", nil}
 	}
 
-	st := f.Symtab
+	syms, err := f.Symbols()
+	if err != nil {
+		return nil, err
+	}
 	dt := f.Dysymtab
+	if uint64(dt.Iundefsym)+uint64(dt.Nundefsym) > uint64(len(syms)) {
+		return nil, &FormatError{0, "undefined symbols extend past the symbol table", dt.Iundefsym}
+	}
 	var all []Symbol
-	all = append(all, st.Syms[dt.Iundefsym:dt.Iundefsym+dt.Nundefsym]...)
+	all = append(all, syms[dt.Iundefsym:dt.Iundefsym+dt.Nundefsym]...)
 	return all, nil
 }
 
@@ -2777,31 +4140,42 @@ func (f *File) ImportedSymbolNames() ([]string, error) {
 func (f *File) ImportedLibraries() []string {
 	var all []string
 	for _, l := range f.Loads {
-		switch v := l.(type) {
-		case *LoadDylib:
-			all = append(all, v.Name)
-		case *WeakDylib:
-			all = append(all, v.Name)
-		case *ReExportDylib:
-			all = append(all, v.Name)
-		case *UpwardDylib:
-			all = append(all, v.Name)
-		case *LazyLoadDylib:
+		if d := dependentDylib(l); d != nil {
+			all = append(all, d.Name)
+		}
+	}
+	return all
+}
+
+// LazyLoadedLibraries returns the paths of all libraries referred to by LC_LAZY_LOAD_DYLIB load commands.
+func (f *File) LazyLoadedLibraries() []string {
+	var all []string
+	for _, l := range f.Loads {
+		if v, ok := l.(*LazyLoadDylib); ok {
 			all = append(all, v.Name)
 		}
 	}
 	return all
 }
 
-// LibraryOrdinalName returns the depancy library oridinal's name
+// LibraryOrdinalName returns the name of the dylib with the library ordinal (1 for the first dylib dependency) or
+// of the special BIND_SPECIAL_DYLIB_* ordinals of binds. The DYNAMIC_LOOKUP_ORDINAL and EXECUTABLE_ORDINAL of
+// symbol n_desc library ordinals (see NDescType.GetLibraryOrdinal) are also handled unless f has that many dylibs.
 func (f *File) LibraryOrdinalName(libraryOrdinal int) string {
 	dylibs := f.ImportedLibraries()
 
 	if libraryOrdinal > 0 {
-		if libraryOrdinal > len(dylibs) {
+		if libraryOrdinal <= len(dylibs) {
+			return filepath.Base(dylibs[libraryOrdinal-1])
+		}
+		switch libraryOrdinal {
+		case types.DYNAMIC_LOOKUP_ORDINAL:
+			libraryOrdinal = types.BIND_SPECIAL_DYLIB_FLAT_LOOKUP
+		case types.EXECUTABLE_ORDINAL:
+			libraryOrdinal = types.BIND_SPECIAL_DYLIB_MAIN_EXECUTABLE
+		default:
 			return "ordinal-too-large"
 		}
-		return filepath.Base(dylibs[libraryOrdinal-1])
 	}
 
 	switch libraryOrdinal {
@@ -2818,13 +4192,93 @@ func (f *File) LibraryOrdinalName(libraryOrdinal int) string {
 	}
 }
 
-func (f *File) FindSymbolAddress(symbol string) (uint64, error) {
+// symbolIndex is the symbols by name and sorted by address (see FindSymbolAddress and FindAddressSymbols)
+type symbolIndex struct {
+	syms    []Symbol
+	byName  map[string]int // index of the first symbol with the name
+	byAddr  []int          // symbol indices sorted by address
+	defined []int          // indices of the (non-debug) symbols defined in sections sorted by address
+}
+
+// getSymbolIndex returns the (cached) symbol index, rebuilding it if the symbols were reloaded
+func (f *File) getSymbolIndex() *symbolIndex {
+	syms := f.symbols()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.syms != nil && len(f.syms.syms) == len(syms) && (len(syms) == 0 || &f.syms.syms[0] == &syms[0]) {
+		return f.syms
+	}
+
+	idx := &symbolIndex{
+		syms:   syms,
+		byName: make(map[string]int, len(syms)),
+		byAddr: make([]int, len(syms)),
+	}
+	for i, sym := range syms {
+		if _, ok := idx.byName[sym.Name]; !ok {
+			idx.byName[sym.Name] = i
+		}
+		idx.byAddr[i] = i
+		if !sym.Type.IsDebugSym() && sym.Type.IsDefinedInSection() {
+			idx.defined = append(idx.defined, i)
+		}
+	}
+	// keep the symtab order for symbols at the same address
+	sort.SliceStable(idx.byAddr, func(i, j int) bool { return syms[idx.byAddr[i]].Value < syms[idx.byAddr[j]].Value })
+	sort.SliceStable(idx.defined, func(i, j int) bool { return syms[idx.defined[i]].Value < syms[idx.defined[j]].Value })
+	f.syms = idx
+
+	return f.syms
+}
+
+// resetSymbolIndex drops the cached symbol index (call after changing symbol values)
+func (f *File) resetSymbolIndex() {
+	f.mu.Lock()
+	f.syms = nil
+	f.mu.Unlock()
+}
+
+// LookupOption configures a symbol lookup (see FindSymbolAddress)
+type LookupOption func(*lookupConfig)
+
+type lookupConfig struct {
+	fold bool
+}
+
+// FoldCase matches symbol names case-insensitively (with a linear scan of the symbols)
+func FoldCase() LookupOption {
+	return func(c *lookupConfig) { c.fold = true }
+}
+
+// FindSymbolAddress returns the address of the symbol (from the symtab, the exports or the Go pclntab)
+func (f *File) FindSymbolAddress(symbol string, opts ...LookupOption) (uint64, error) {
+	var cfg lookupConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	match := func(name string) bool { return name == symbol }
+	if cfg.fold {
+		match = func(name string) bool { return strings.EqualFold(name, symbol) }
+	}
+
 	if f.Symtab == nil {
+		if fn, ok := f.goSymbolByName(symbol); ok {
+			return fn.Entry, nil
+		}
 		return 0, &FormatError{0, "missing symbol table", nil}
 	}
-	for _, sym := range f.Symtab.Syms {
-		if strings.EqualFold(sym.Name, symbol) {
-			return sym.Value, nil
+	if cfg.fold {
+		for _, sym := range f.symbols() {
+			if match(sym.Name) {
+				return sym.Value, nil
+			}
+		}
+	} else {
+		idx := f.getSymbolIndex()
+		if i, ok := idx.byName[symbol]; ok {
+			return idx.syms[i].Value, nil
 		}
 	}
 	exports, err := f.GetExports()
@@ -2832,22 +4286,32 @@ func (f *File) FindSymbolAddress(symbol string) (uint64, error) {
 		return 0, fmt.Errorf("failed to get exports: %v", err)
 	}
 	for _, sym := range exports {
-		if strings.EqualFold(sym.Name, symbol) {
+		if match(sym.Name) {
 			return sym.Address, nil
 		}
 	}
+	if fn, ok := f.goSymbolByName(symbol); ok {
+		return fn.Entry, nil
+	}
 	return 0, fmt.Errorf("symbol not found in macho symtab")
 }
 
+// FindAddressSymbols returns the symbols at the address addr (from the symtab, the exports or the Go pclntab)
 func (f *File) FindAddressSymbols(addr uint64) ([]Symbol, error) {
 	if f.Symtab == nil {
+		if fn, ok := f.goSymbolForAddress(addr); ok && fn.Entry == addr {
+			return []Symbol{{Name: fn.Name, Type: types.N_SECT | types.N_EXT, Value: fn.Entry}}, nil
+		}
 		return nil, &FormatError{0, "missing symbol table", nil}
 	}
 	var syms []Symbol
-	for _, sym := range f.Symtab.Syms {
-		if sym.Value == addr {
-			syms = append(syms, sym)
+	idx := f.getSymbolIndex()
+	for i := sort.Search(len(idx.byAddr), func(i int) bool { return idx.syms[idx.byAddr[i]].Value >= addr }); i < len(idx.byAddr); i++ {
+		sym := idx.syms[idx.byAddr[i]]
+		if sym.Value != addr {
+			break
 		}
+		syms = append(syms, sym)
 	}
 	if f.DyldExportsTrie() != nil && f.DyldExportsTrie().Size > 0 {
 		exports, err := f.DyldExports()
@@ -2856,12 +4320,50 @@ func (f *File) FindAddressSymbols(addr uint64) ([]Symbol, error) {
 		}
 		for _, sym := range exports {
 			if sym.Address == addr {
-				syms = append(syms, Symbol{Name: sym.Name, Value: sym.Address})
+				s := Symbol{Name: sym.Name, Value: sym.Address}
+				if f.demangleSymbols {
+					s.demangled = demangle.Filter(sym.Name)
+				}
+				syms = append(syms, s)
 			}
 		}
 	}
 	if len(syms) > 0 {
 		return syms, nil
 	}
+	if fn, ok := f.goSymbolForAddress(addr); ok && fn.Entry == addr {
+		return []Symbol{{Name: fn.Name, Type: types.N_SECT | types.N_EXT, Value: fn.Entry}}, nil
+	}
 	return nil, fmt.Errorf("symbol(s) not found in macho symtab for addr %#x", addr)
 }
+
+// NearestSymbol returns the symbol containing the address addr (the closest symbol defined at or before addr
+// in the same section, ignoring absolute, undefined and debug symbols) and the offset of addr from it
+func (f *File) NearestSymbol(addr uint64) (Symbol, uint64, error) {
+	if f.Symtab == nil {
+		if fn, ok := f.goSymbolForAddress(addr); ok {
+			return Symbol{Name: fn.Name, Type: types.N_SECT | types.N_EXT, Value: fn.Entry}, addr - fn.Entry, nil
+		}
+		return Symbol{}, 0, &FormatError{0, "missing symbol table", nil}
+	}
+	sec := f.FindSectionForVMAddr(addr)
+	if sec == nil {
+		return Symbol{}, 0, fmt.Errorf("address %#x is not in a section", addr)
+	}
+	idx := f.getSymbolIndex()
+	// the first symbol at the closest address before (or at) addr
+	i := sort.Search(len(idx.defined), func(i int) bool { return idx.syms[idx.defined[i]].Value > addr }) - 1
+	if i >= 0 {
+		value := idx.syms[idx.defined[i]].Value
+		for i > 0 && idx.syms[idx.defined[i-1]].Value == value {
+			i--
+		}
+		if sym := idx.syms[idx.defined[i]]; sym.Value >= sec.Addr && sym.Section(f) == sec {
+			return sym, addr - sym.Value, nil
+		}
+	}
+	if fn, ok := f.goSymbolForAddress(addr); ok {
+		return Symbol{Name: fn.Name, Type: types.N_SECT | types.N_EXT, Value: fn.Entry}, addr - fn.Entry, nil
+	}
+	return Symbol{}, 0, fmt.Errorf("no symbol found for address %#x", addr)
+}
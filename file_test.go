@@ -6,20 +6,30 @@ package macho
 
 import (
 	"bytes"
+	"debug/buildinfo"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"testing"
 
 	"github.com/blacktop/go-dwarf"
 	"github.com/blacktop/go-macho/internal/obscuretestdata"
+	"github.com/blacktop/go-macho/pkg/codesign"
 	cstypes "github.com/blacktop/go-macho/pkg/codesign/types"
+	"github.com/blacktop/go-macho/pkg/fixupchains"
+	"github.com/blacktop/go-macho/pkg/trie"
 	"github.com/blacktop/go-macho/types"
+	"github.com/blacktop/go-macho/types/objc"
 )
 
 type fileTest struct {
@@ -423,6 +433,15 @@ func TestTypeString(t *testing.T) {
 	}
 }
 
+func TestSplitInfoKindString(t *testing.T) {
+	if got := types.DYLD_CACHE_ADJ_V2_DELTA_64.String(); got != "delta_64" {
+		t.Errorf("got %v, want %v", got, "delta_64")
+	}
+	if got := types.SplitInfoKind(0x7f).String(); got != "unknown kind 0x7f" {
+		t.Errorf("got %v, want %v", got, "unknown kind 0x7f")
+	}
+}
+
 func TestOpenBadDysymCmd(t *testing.T) {
 	_, err := openObscured("internal/testdata/gcc-amd64-darwin-exec-with-bad-dysym.base64")
 	if err == nil {
@@ -430,508 +449,4800 @@ func TestOpenBadDysymCmd(t *testing.T) {
 	}
 }
 
-var fname string
-
-func init() {
-	flag.StringVar(&fname, "file", "", "MachO file to test")
+func TestFunctionForAddress(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn, err := f.FunctionForAddress(0x100000f70)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn.Name != "_main" || fn.StartAddr != 0x100000f60 {
+		t.Errorf("got %s@%#x, want _main@0x100000f60", fn.Name, fn.StartAddr)
+	}
+	if _, err := f.FunctionForAddress(0x100000f20); err == nil {
+		t.Error("FunctionForAddress did not fail for an address outside of any function")
+	}
 }
 
-func TestNewFile(t *testing.T) {
-	f, err := os.Open(fname)
-
+func TestVerifyCodeSignaturePages(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			t.Skipf("file %s not found", fname)
-		}
+		t.Fatal(err)
+	}
+	if err := f.CodeSign(&codesign.Config{ID: "com.example.test", Flags: cstypes.ADHOC}); err != nil {
+		t.Fatal(err)
+	}
+	signed := filepath.Join(t.TempDir(), "signed")
+	if err := f.Save(signed); err != nil {
 		t.Fatal(err)
 	}
 
-	got, err := NewFile(f)
+	sf, err := Open(signed)
 	if err != nil {
-		t.Fatalf("NewFile() error = %v", err)
-		return
+		t.Fatal(err)
+	}
+	defer sf.Close()
+	mismatches, err := sf.VerifyCodeSignaturePages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) > 0 {
+		t.Errorf("VerifyCodeSignaturePages: got %v, want no mismatches", mismatches)
 	}
 
-	fmt.Println(got.FileTOC.String())
+	// tamper with the first code page
+	dat, err := os.ReadFile(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dat[0x800] ^= 0xff
+	tf, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mismatches, err = tf.VerifyCodeSignaturePages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Special || mismatches[0].Slot != 0 {
+		t.Errorf("VerifyCodeSignaturePages: got %v, want a single mismatch for page 0", mismatches)
+	}
+}
 
-	cs := got.CodeSignature()
-	if cs != nil {
-		fmt.Println(cs.Requirements[0].Detail)
+func TestVerifyPrebindCheckSum(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-386-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
 	}
-	if len(cs.LaunchConstraintsSelf) > 0 {
-		os.WriteFile("lc_self.bin", cs.LaunchConstraintsSelf, 0644)
-		lc, err := cstypes.ParseLaunchContraints(cs.LaunchConstraintsSelf)
-		if err != nil {
-			t.Fatalf("ParseLaunchContraints() error = %v", err)
-		}
-		dat, err := json.MarshalIndent(lc, "", "  ")
-		if err != nil {
-			t.Fatalf("json.MarshalIndent() error = %v", err)
-		}
-		fmt.Println(string(dat))
+	cksum := &PrebindCheckSum{PrebindCksumCmd: types.PrebindCksumCmd{LoadCmd: types.LC_PREBIND_CKSUM, Len: 12}}
+	if err := f.AddLoad(cksum); err != nil {
+		t.Fatal(err)
 	}
-	if len(cs.LaunchConstraintsParent) > 0 {
-		os.WriteFile("lc_parent.bin", cs.LaunchConstraintsParent, 0644)
-		lc, err := cstypes.ParseLaunchContraints(cs.LaunchConstraintsParent)
-		if err != nil {
-			t.Fatalf("ParseLaunchContraints() error = %v", err)
-		}
-		dat, err := json.MarshalIndent(lc, "", "  ")
-		if err != nil {
-			t.Fatalf("json.MarshalIndent() error = %v", err)
-		}
-		fmt.Println(string(dat))
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
 	}
-	if len(cs.LaunchConstraintsResponsible) > 0 {
-		os.WriteFile("lc_responsible.bin", cs.LaunchConstraintsResponsible, 0644)
-		lc, err := cstypes.ParseLaunchContraints(cs.LaunchConstraintsResponsible)
-		if err != nil {
-			t.Fatalf("ParseLaunchContraints() error = %v", err)
-		}
-		dat, err := json.MarshalIndent(lc, "", "  ")
-		if err != nil {
-			t.Fatalf("json.MarshalIndent() error = %v", err)
-		}
-		fmt.Println(string(dat))
+	original, err := NewFile(bytes.NewReader(append([]byte(nil), buf.Bytes()...)))
+	if err != nil {
+		t.Fatal(err)
 	}
-	if len(cs.LibraryConstraints) > 0 {
-		os.WriteFile("lib_constraints.bin", cs.LibraryConstraints, 0644)
-		lc, err := cstypes.ParseLaunchContraints(cs.LibraryConstraints)
-		if err != nil {
-			t.Fatalf("ParseLaunchContraints() error = %v", err)
-		}
-		dat, err := json.MarshalIndent(lc, "", "  ")
-		if err != nil {
-			t.Fatalf("json.MarshalIndent() error = %v", err)
-		}
-		fmt.Println(string(dat))
+	if _, err := original.VerifyPrebindCheckSum(original); err == nil {
+		t.Error("VerifyPrebindCheckSum() succeeded without a recorded check sum")
 	}
 
-	d, err := got.DWARF()
+	// redoing the prebinding records the original's check sum and changes the image
+	sum, err := original.ComputePrebindCheckSum()
 	if err != nil {
-		t.Fatalf("DWARF() error = %v", err)
+		t.Fatal(err)
+	}
+	cksum.CheckSum = sum
+	buf.Reset()
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dat := buf.Bytes()
+	dat[f.Section("__TEXT", "__text").Offset] ^= 0xff // stand-in for the rewritten prebinding
+	prebound, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prebound.PrebindCheckSum().CheckSum != sum {
+		t.Fatalf("PrebindCheckSum() = %#x, want %#x", prebound.PrebindCheckSum().CheckSum, sum)
+	}
+	if ok, err := prebound.VerifyPrebindCheckSum(original); err != nil || !ok {
+		t.Errorf("VerifyPrebindCheckSum(original) = %v, %v; want true", ok, err)
+	}
+	if now, _ := prebound.ComputePrebindCheckSum(); now == sum {
+		t.Error("ComputePrebindCheckSum() of the prebound image matches the original")
 	}
 
-	r := d.Reader()
-
-	debugStrSec := got.Section("__DWARF", "__debug_str")
-	if debugStrSec == nil {
-		t.Fatalf("Section() error = section __DWARF.__debug_str not found")
+	other, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := prebound.VerifyPrebindCheckSum(other); err != nil || ok {
+		t.Errorf("VerifyPrebindCheckSum(other) = %v, %v; want false", ok, err)
 	}
+	if _, err := prebound.VerifyPrebindCheckSum(nil); err == nil {
+		t.Error("VerifyPrebindCheckSum(nil) succeeded")
+	}
+}
 
-	names, err := d.DumpNames()
+func TestSymSeg(t *testing.T) {
+	b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.HeaderPad = 0x100
+	b.AddSection("__TEXT", "__text", []byte{0xc3}, types.PURE_INSTRUCTIONS)
+	b.SetEntryPoint("__TEXT", "__text", 0)
+	dat, err := b.Build()
 	if err != nil {
-		t.Errorf("DWARF.DumpNames() error = %v", err)
+		t.Fatal(err)
 	}
-	for _, name := range names {
-		nameStr, err := got.GetCString(debugStrSec.Addr + uint64(name.StrOffset))
-		if err != nil {
-			t.Errorf("GetCString() error = %v", err)
+	want := []byte("gdb symbol segment")
+	f, err := NewFile(bytes.NewReader(addLinkEditData(dat, types.LC_SYMSEG, want)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seg *SymSeg
+	for _, l := range f.Loads {
+		if s, ok := l.(*SymSeg); ok {
+			seg = s
 		}
+	}
+	if seg == nil {
+		t.Fatal("LC_SYMSEG not found")
+	}
+	if got, err := seg.Data(); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("Data() = %q, %v; want %q", got, err, want)
+	}
+	if got, err := io.ReadAll(seg.Open()); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("Open() read %q, %v; want %q", got, err, want)
+	}
 
-		for _, hdata := range name.HashData {
-			r.Seek(*hdata[0].(*dwarf.Offset))
-			entry, err := r.Next()
-			if err != nil {
-				t.Fatalf("DWARF.Reader().Next() error = %v", err)
-			}
-
-			switch entry.Tag {
-			case dwarf.TagInlinedSubroutine:
-			case dwarf.TagSubroutineType, dwarf.TagSubprogram:
-				ty, _ := d.Type(entry.Offset)
-				if ty.(*dwarf.FuncType).FileIndex > 0 {
-					fs, err := d.FilesForEntry(entry)
-					if err != nil {
-						t.Fatalf("DWARF.FilesForEntry() error = %v", err)
-					}
-					fmt.Printf("TAG: %s, NAME: %s, TYPE: %s\nFILE: %s\n", entry.Tag, nameStr, ty, fs[ty.(*dwarf.FuncType).FileIndex].Name)
-				}
-			default:
-				ty, _ := d.Type(entry.Offset)
-				declFile, dOK := entry.Val(dwarf.AttrDeclFile).(int64)
-				callFile, cOK := entry.Val(dwarf.AttrCallFile).(int64)
-				if dOK || cOK {
-					fs, err := d.FilesForEntry(entry)
-					if err != nil {
-						t.Fatalf("DWARF.FilesForEntry() error = %v", err)
-					}
-					fmt.Printf("TAG: %s, NAME: %s, TYPE: %s\nFILE: %s\n", entry.Tag, nameStr, ty, fs[declFile+callFile].Name)
-				} else {
-					fmt.Printf("TAG: %s, NAME: %s, TYPE: %s\n", entry.Tag, nameStr, ty)
-				}
-			}
-		}
+	empty := &SymSeg{SymsegCmd: types.SymsegCmd{LoadCmd: types.LC_SYMSEG, Size: 4}}
+	if _, err := empty.Data(); err == nil {
+		t.Error("Data() succeeded without a reader")
+	}
+	if _, err := empty.Open().Read(make([]byte, 4)); err == nil {
+		t.Error("Open().Read() succeeded without a reader")
 	}
+}
 
-	nameSpaces, err := d.DumpNamespaces()
+func TestDylibCodeSignDrs(t *testing.T) {
+	b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.HeaderPad = 0x100
+	b.AddSection("__TEXT", "__text", []byte{0xc3}, types.PURE_INSTRUCTIONS)
+	b.AddDylib("/usr/lib/libSystem.B.dylib")
+	b.AddDylib("/usr/lib/libfoo.dylib")
+	b.SetEntryPoint("__TEXT", "__text", 0)
+	dat, err := b.Build()
 	if err != nil {
-		t.Fatalf("DWARF.DumpNamespaces() error = %v", err)
+		t.Fatal(err)
 	}
-	for _, ns := range nameSpaces {
-		nsStr, err := got.GetCString(debugStrSec.Addr + uint64(ns.StrOffset))
-		if err != nil {
-			t.Errorf("GetCString() error = %v", err)
-		}
-		for _, hdata := range ns.HashData {
-			r.Seek(*hdata[0].(*dwarf.Offset))
-			entry, err := r.Next()
-			if err != nil {
-				t.Fatalf("DWARF.Reader().Next() error = %v", err)
-			}
 
-			typ, err := d.Type(entry.Offset)
-			if err != nil {
-				t.Fatalf("DWARF.Type() error = %v", err)
-			}
-			fmt.Printf("TAG: %s, NAME: %s, TYPE: %s\n", entry.Tag, nsStr, typ)
+	// a library dependency blob with the designated requirement `identifier "a"` for dylib 0 and none for dylib 1
+	var blob bytes.Buffer
+	expr := []uint32{2, 1, 'a' << 24} // opIdent "a"
+	binary.Write(&blob, binary.BigEndian, cstypes.SbHeader{Magic: cstypes.MAGIC_LIBRARY_DEPENDENCY_BLOB, Length: 48, Count: 2})
+	binary.Write(&blob, binary.BigEndian, []cstypes.BlobIndex{{Type: 0, Offset: 28}, {Type: 1, Offset: 0}})
+	binary.Write(&blob, binary.BigEndian, cstypes.RequirementsBlob{Magic: cstypes.MAGIC_REQUIREMENT, Length: 24, Data: 1})
+	binary.Write(&blob, binary.BigEndian, expr)
+
+	f, err := NewFile(bytes.NewReader(addLinkEditData(dat, types.LC_DYLIB_CODE_SIGN_DRS, blob.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var drs *DylibCodeSignDrs
+	for _, l := range f.Loads {
+		if d, ok := l.(*DylibCodeSignDrs); ok {
+			drs = d
 		}
 	}
+	if drs == nil {
+		t.Fatal("LC_DYLIB_CODE_SIGN_DRS not found")
+	}
+	want := []codesign.DylibRequirement{{Index: 0, Detail: `identifier "a"`}, {Index: 1}}
+	if !reflect.DeepEqual(drs.Requirements, want) {
+		t.Errorf("Requirements = %v, want %v", drs.Requirements, want)
+	}
 
-	objc, err := d.DumpObjC()
+	// requirements that don't decode are a warning, not a failure to open the file
+	bad := append([]byte(nil), blob.Bytes()...)
+	binary.BigEndian.PutUint32(bad, 0xdeadbeef)
+	f, err = NewFile(bytes.NewReader(addLinkEditData(dat, types.LC_DYLIB_CODE_SIGN_DRS, bad)))
 	if err != nil {
-		t.Fatalf("DWARF.DumpObjC() error = %v", err)
+		t.Fatalf("NewFile() with undecodable LC_DYLIB_CODE_SIGN_DRS error = %v", err)
 	}
-	for _, oc := range objc {
-		nsStr, err := got.GetCString(debugStrSec.Addr + uint64(oc.StrOffset))
-		if err != nil {
-			t.Errorf("GetCString() error = %v", err)
+	if ws := f.ParseWarnings(); len(ws) != 1 || ws[0].Cmd != types.LC_DYLIB_CODE_SIGN_DRS {
+		t.Errorf("ParseWarnings() = %v, want a LC_DYLIB_CODE_SIGN_DRS warning", ws)
+	}
+	for _, l := range f.Loads {
+		if d, ok := l.(*DylibCodeSignDrs); ok && d.Requirements != nil {
+			t.Errorf("Requirements = %v, want none", d.Requirements)
 		}
-		fmt.Println(nsStr)
 	}
+}
 
-	types, err := d.DumpTypes()
+func TestRemoveSignature(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
 	if err != nil {
-		t.Fatalf("DWARF.LookDumpTypesupType() error = %v", err)
+		t.Fatal(err)
 	}
-	for _, typ := range types {
-		typStr, err := got.GetCString(debugStrSec.Addr + uint64(typ.StrOffset))
-		if err != nil {
-			t.Errorf("GetCString() error = %v", err)
-		}
-		fmt.Println(typStr)
+	ncmds := f.NCommands
+	if err := f.CodeSign(&codesign.Config{ID: "com.example.test", Flags: cstypes.ADHOC}); err != nil {
+		t.Fatal(err)
+	}
+	signed := filepath.Join(t.TempDir(), "signed")
+	if err := f.Save(signed); err != nil {
+		t.Fatal(err)
 	}
 
-	off, err := d.LookupType("thread")
+	// data after the signature must not be silently dropped
+	tf, err := Open(signed)
 	if err != nil {
-		t.Fatalf("DWARF.LookupType() error = %v", err)
+		t.Fatal(err)
+	}
+	defer tf.Close()
+	tf.Segment("__LINKEDIT").Filesz += 0x10
+	if err := tf.RemoveSignature(); err == nil || !strings.Contains(err.Error(), "not at the end of the __LINKEDIT segment") {
+		t.Errorf("RemoveSignature() with data after the signature error = %v", err)
 	}
 
-	r.Seek(off)
+	sf, err := Open(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Close()
+	if err := sf.RemoveSignature(); err != nil {
+		t.Fatal(err)
+	}
+	unsigned := filepath.Join(t.TempDir(), "unsigned")
+	if err := sf.Save(unsigned); err != nil {
+		t.Fatal(err)
+	}
 
-	entry, err := r.Next()
+	uf, err := Open(unsigned)
 	if err != nil {
-		t.Fatalf("DWARF.Reader().Next() error = %v", err)
+		t.Fatal(err)
+	}
+	defer uf.Close()
+	if uf.CodeSignature() != nil {
+		t.Error("RemoveSignature: LC_CODE_SIGNATURE still present")
+	}
+	if uf.NCommands != ncmds {
+		t.Errorf("RemoveSignature: got %d load commands, want %d", uf.NCommands, ncmds)
 	}
+	fi, err := os.Stat(unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linkedit := uf.Segment("__LINKEDIT"); linkedit.Offset+linkedit.Filesz != uint64(fi.Size()) {
+		t.Errorf("RemoveSignature: __LINKEDIT ends at %#x, want %#x", linkedit.Offset+linkedit.Filesz, fi.Size())
+	}
+}
 
-	if entry.Tag == dwarf.TagStructType {
-		typ, err := d.Type(entry.Offset)
+func TestWriteToRoundTrip(t *testing.T) {
+	for _, name := range []string{
+		"clang-386-darwin-exec-with-rpath",
+		"clang-386-darwin.obj",
+		"clang-amd64-darwin-exec-with-rpath",
+		"clang-amd64-darwin.obj",
+		"gcc-386-darwin-exec",
+		"gcc-amd64-darwin-exec",
+		"gcc-amd64-darwin-exec-debug",
+	} {
+		orig, err := obscuretestdata.ReadFile("internal/testdata/" + name + ".base64")
 		if err != nil {
-			t.Errorf("DWARF entry.Type() error = %v", err)
+			t.Fatal(err)
 		}
-		if t1, ok := typ.(*dwarf.StructType); ok {
-			if strings.EqualFold(t1.StructName, "thread") {
-				if !t1.Incomplete {
-					fmt.Println(t1.Defn(false))
-				}
-			}
+		f, err := NewFile(bytes.NewReader(orig))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := f.WriteTo(&buf); err != nil {
+			t.Fatalf("%s: WriteTo: %v", name, err)
+		}
+		if !bytes.Equal(buf.Bytes(), orig) {
+			t.Errorf("%s: WriteTo output (%d bytes) differs from the original (%d bytes)", name, buf.Len(), len(orig))
 		}
 	}
 }
 
-func TestNewFileWithSwift(t *testing.T) {
-	f, err := os.Open(fname)
-
+func TestWriteToGrownLoad(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			t.Skipf("file %s not found", fname)
-		}
 		t.Fatal(err)
 	}
+	rpath := f.GetLoadsByName("LC_RPATH")[0].(*Rpath)
 
-	got, err := NewFile(f)
+	// grows into the header padding
+	rpath.Path = "/" + strings.Repeat("a", 63)
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	nf, err := NewFile(bytes.NewReader(buf.Bytes()))
 	if err != nil {
-		t.Fatalf("NewFile() error = %v", err)
-		return
+		t.Fatalf("failed to parse the grown MachO: %v", err)
 	}
-
-	if entry, err := got.GetSwiftEntry(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetSwiftEntry() error = %v", err)
-	} else {
-		fmt.Println("GetSwiftEntry" + strings.Repeat("-", 80))
-		fmt.Printf("%#x: entry\n", entry)
+	if nf.SizeCommands != f.SizeCommands+56 || nf.NCommands != f.NCommands {
+		t.Errorf("WriteTo: header has %d load commands (%#x bytes), want %d (%#x bytes)", nf.NCommands, nf.SizeCommands, f.NCommands, f.SizeCommands+56)
 	}
-
-	if bins, err := got.GetSwiftBuiltinTypes(); err != nil {
-		if !errors.Is(err, ErrSwiftSectionError) {
-			t.Fatalf("GetSwiftBuiltinTypes() error = %v", err)
-		}
-	} else {
-		fmt.Println("GetSwiftBuiltinTypes" + strings.Repeat("-", 80))
-		for _, bin := range bins {
-			fmt.Println(bin.Verbose())
-		}
+	if r := nf.GetLoadsByName("LC_RPATH"); len(r) != 1 || r[0].(*Rpath).Path != rpath.Path {
+		t.Errorf("WriteTo: bad LC_RPATH %v", r)
 	}
 
-	if refStrs, err := got.GetSwiftReflectionStrings(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetSwiftReflectionStrings() error = %v", err)
-	} else {
-		fmt.Println("GetSwiftReflectionStrings" + strings.Repeat("-", 80))
-		for addr, refstr := range refStrs {
-			fmt.Printf("%#x: %s\n", addr, refstr)
-		}
+	// overlaps __TEXT.__text
+	rpath.Path = "/" + strings.Repeat("a", int(f.Sections[0].Offset))
+	if _, err := f.WriteTo(&buf); err == nil {
+		t.Error("WriteTo: expected an error when the load commands overlap the first section")
 	}
+}
 
-	if fds, err := got.GetSwiftFields(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetSwiftFields() error = %v", err)
-	} else {
-		fmt.Println("GetSwiftFields" + strings.Repeat("-", 80))
-		for _, f := range fds {
-			fmt.Println(f.Verbose())
-		}
+func TestBuilder(t *testing.T) {
+	code := []byte{0x31, 0xc0, 0xc3} // xor eax, eax; ret
+
+	b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.AddSection("__TEXT", "__text", code, types.PURE_INSTRUCTIONS|types.SOME_INSTRUCTIONS)
+	b.AddSection("__DATA", "__data", []byte("hello\x00"), types.Regular)
+	b.AddSection("__DATA", "__bss", nil, types.Zerofill).Size = 0x100
+	b.AddSymbol("_main", "__TEXT", "__text", 0, true)
+	b.AddSymbol("_greeting", "__DATA", "__data", 0, false)
+	b.AddDylib("/usr/lib/libSystem.B.dylib")
+	b.SetEntryPoint("__TEXT", "__text", 0)
+
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to parse built MachO: %v", err)
 	}
 
-	if atyps, err := got.GetSwiftAssociatedTypes(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetSwiftAssociatedTypes() error = %v", err)
-	} else {
-		fmt.Println("GetSwiftAssociatedTypes" + strings.Repeat("-", 80))
-		for _, at := range atyps {
-			fmt.Println(at.Verbose())
-		}
+	text := f.Section("__TEXT", "__text")
+	if text == nil {
+		t.Fatal("Builder: missing __TEXT.__text")
+	}
+	if dat, err := text.Data(); err != nil || !bytes.Equal(dat, code) {
+		t.Errorf("Builder: __TEXT.__text = %x (%v), want %x", dat, err, code)
+	}
+	if bss := f.Section("__DATA", "__bss"); bss == nil || bss.Size != 0x100 || bss.Offset != 0 {
+		t.Errorf("Builder: bad __DATA.__bss %v", bss)
+	}
+	if addr, err := f.FindSymbolAddress("_main"); err != nil || addr != text.Addr {
+		t.Errorf("Builder: _main = %#x (%v), want %#x", addr, err, text.Addr)
+	}
+	if main := f.GetLoadsByName("LC_MAIN"); len(main) != 1 || main[0].(*EntryPoint).EntryOffset != uint64(text.Offset) {
+		t.Errorf("Builder: bad LC_MAIN %v", main)
+	}
+	if libs := f.ImportedLibraries(); len(libs) != 1 || libs[0] != "/usr/lib/libSystem.B.dylib" {
+		t.Errorf("Builder: ImportedLibraries = %v", libs)
+	}
+	if linkedit := f.Segment("__LINKEDIT"); linkedit == nil || linkedit.Offset+linkedit.Filesz != uint64(buf.Len()) {
+		t.Errorf("Builder: bad __LINKEDIT %v", linkedit)
 	}
 
-	if typs, err := got.GetSwiftColocateTypeDescriptors(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetColocateTypeDescriptors() error = %v", err)
-	} else {
-		fmt.Println("GetColocateTypeDescriptors" + strings.Repeat("-", 80))
-		for _, typ := range typs {
-			fmt.Println(typ.Verbose())
-		}
+	d := NewBuilder(types.MH_DYLIB, types.CPUArm64, types.CPUSubtypeArm64All)
+	d.InstallName = "@rpath/libtest.dylib"
+	d.AddSection("__TEXT", "__text", []byte{0xc0, 0x03, 0x5f, 0xd6}, types.PURE_INSTRUCTIONS) // ret
+	d.AddSymbol("_test", "__TEXT", "__text", 0, true)
+	dat, err := d.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	df, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatalf("failed to parse built dylib: %v", err)
+	}
+	if id := df.DylibID(); id == nil || id.Name != "@rpath/libtest.dylib" {
+		t.Errorf("Builder: bad LC_ID_DYLIB %v", id)
 	}
 
-	if mdatas, err := got.GetSwiftColocateMetadata(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetColocateMetadata() error = %v", err)
-	} else {
-		fmt.Println("GetColocateMetadata" + strings.Repeat("-", 80))
-		for _, mdat := range mdatas {
-			fmt.Println(mdat.Verbose())
-		}
+	// __TEXT is laid out first without reordering the builder's segments
+	o := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	o.AddSection("__DATA", "__data", []byte{1}, types.Regular)
+	o.AddSection("__TEXT", "__text", code, types.PURE_INSTRUCTIONS)
+	o.SetEntryPoint("__TEXT", "__text", 0)
+	first, err := o.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.segments[0].Name != "__DATA" || o.segments[1].Name != "__TEXT" {
+		t.Errorf("Build() reordered the segments to %s, %s", o.segments[0].Name, o.segments[1].Name)
+	}
+	if o.Segment("__TEXT").Addr >= o.Segment("__DATA").Addr {
+		t.Errorf("Build() laid out __TEXT at %#x after __DATA at %#x", o.Segment("__TEXT").Addr, o.Segment("__DATA").Addr)
+	}
+	if second, err := o.Build(); err != nil || !bytes.Equal(first, second) {
+		t.Errorf("Build() isn't repeatable (%v)", err)
 	}
+}
 
-	// if refStrs, err := got.GetSwiftTypeRefs(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-	// 	t.Fatalf("GetSwiftTypeRefs() error = %v", err)
-	// } else {
-	// 	for addr, refstr := range refStrs {
-	// 		fmt.Printf("%#x: %s\n", addr, refstr)
-	// 	}
-	// }
+func TestInstallNameTool(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.ChangeDependency("/usr/lib/libSystem.B.dylib", "@rpath/libSystem.B.dylib"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddRpath("@loader_path/../Frameworks"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddRpath("@loader_path/../Frameworks"); err == nil {
+		t.Error("AddRpath: expected an error adding a duplicate rpath")
+	}
+	if err := f.RemoveRpath("/my/rpath"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetDylibID("libfoo.dylib"); err == nil {
+		t.Error("SetDylibID: expected an error for an executable")
+	}
 
-	if typs, err := got.GetSwiftTypes(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetSwiftTypes() error = %v", err)
-	} else {
-		fmt.Println("GetSwiftTypes" + strings.Repeat("-", 80))
-		for _, t := range typs {
-			fmt.Println(t)
-		}
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	nf, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to parse modified MachO: %v", err)
+	}
+	if libs := nf.ImportedLibraries(); !reflect.DeepEqual(libs, []string{"@rpath/libSystem.B.dylib"}) {
+		t.Errorf("ChangeDependency: got %v", libs)
+	}
+	var rpaths []string
+	for _, r := range nf.Rpaths() {
+		rpaths = append(rpaths, r.Path)
+	}
+	if !reflect.DeepEqual(rpaths, []string{"@loader_path/../Frameworks"}) {
+		t.Errorf("AddRpath/RemoveRpath: got rpaths %v", rpaths)
 	}
+}
 
-	if prots, err := got.GetSwiftProtocols(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetSwiftProtocols() error = %v", err)
-	} else {
-		fmt.Println("GetSwiftProtocols" + strings.Repeat("-", 80))
-		for _, prot := range prots {
-			fmt.Println(prot.Verbose())
-		}
+func TestInsertDylib(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.CodeSign(&codesign.Config{ID: "com.example.test", Flags: cstypes.ADHOC}); err != nil {
+		t.Fatal(err)
 	}
+	signed := filepath.Join(t.TempDir(), "signed")
+	if err := f.Save(signed); err != nil {
+		t.Fatal(err)
+	}
+	sf, err := Open(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Close()
 
-	if protsconfs, err := got.GetSwiftProtocolConformances(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetSwiftProtocolConformances() error = %v", err)
-	} else {
-		fmt.Println("GetSwiftProtocolConformances" + strings.Repeat("-", 80))
-		for _, prot := range protsconfs {
-			fmt.Println(prot.Verbose())
-		}
+	// the signature isn't silently replaced
+	nloads := len(sf.Loads)
+	if err := sf.InsertDylib("@executable_path/libinject.dylib", true, nil); err == nil {
+		t.Fatal("InsertDylib: inserting into a signed MachO without a codesign.Config should fail")
+	}
+	if len(sf.Loads) != nloads {
+		t.Fatal("InsertDylib: a failed insert modified the load commands")
 	}
 
-	if mpenums, err := got.GetSwiftMultiPayloadEnums(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetMultiPayloadEnums() error = %v", err)
-	} else {
-		fmt.Println("GetMultiPayloadEnums" + strings.Repeat("-", 80))
-		for _, e := range mpenums {
-			fmt.Println(e)
-		}
+	if err := sf.InsertDylib("@executable_path/libinject.dylib", true, &codesign.Config{Flags: cstypes.ADHOC}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := sf.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	nf, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to parse modified MachO: %v", err)
+	}
+	if libs := nf.ImportedLibraries(); !reflect.DeepEqual(libs, []string{"/usr/lib/libSystem.B.dylib", "@executable_path/libinject.dylib"}) {
+		t.Errorf("InsertDylib: got %v", libs)
+	}
+	if _, ok := nf.Loads[len(nf.Loads)-1].(*CodeSignature); !ok {
+		t.Errorf("InsertDylib: LC_CODE_SIGNATURE is no longer the last load command")
+	}
+	if mismatches, err := nf.VerifyCodeSignaturePages(); err != nil || len(mismatches) > 0 {
+		t.Errorf("InsertDylib: re-signed MachO doesn't verify: %v %v", mismatches, err)
 	}
+}
 
-	if clos, err := got.GetSwiftClosures(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetSwiftClosures() error = %v", err)
-	} else {
-		fmt.Println("GetSwiftClosures" + strings.Repeat("-", 80))
-		for _, c := range clos {
-			fmt.Println(c)
-		}
+func TestAddRemoveLoad(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-386-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
 	}
+	ncmds, sizeofcmds := f.NCommands, f.SizeCommands
 
-	if rep, err := got.GetSwiftDynamicReplacementInfo(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetSwiftDynamicReplacementInfo() error = %v", err)
-	} else {
-		fmt.Println("GetSwiftDynamicReplacementInfo" + strings.Repeat("-", 80))
-		if rep != nil {
-			fmt.Println(rep)
-		}
+	unknown := LoadCmdBytes{LoadCmd: types.LoadCmd(0x7f), LoadBytes: LoadBytes{0x7f, 0, 0, 0, 12, 0, 0, 0, 0xde, 0xad, 0xbe, 0xef}}
+	if err := f.InsertLoad(1, unknown); err != nil {
+		t.Fatal(err)
+	}
+	if f.NCommands != ncmds+1 || f.SizeCommands != sizeofcmds+12 {
+		t.Errorf("InsertLoad: got ncmds=%d sizeofcmds=%#x, want %d, %#x", f.NCommands, f.SizeCommands, ncmds+1, sizeofcmds+12)
+	}
+	big := make(LoadBytes, 0x10000)
+	binary.LittleEndian.PutUint32(big[0:], 0x7f)
+	binary.LittleEndian.PutUint32(big[4:], uint32(len(big)))
+	if err := f.AddLoad(LoadCmdBytes{LoadCmd: types.LoadCmd(0x7f), LoadBytes: big}); err == nil {
+		t.Error("AddLoad: expected an error for load commands that don't fit in the header padding")
 	}
 
-	if rep, err := got.GetSwiftDynamicReplacementInfoForOpaqueTypes(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetSwiftDynamicReplacementInfoForOpaqueTypes() error = %v", err)
-	} else {
-		fmt.Println("GetSwiftDynamicReplacementInfoForOpaqueTypes" + strings.Repeat("-", 80))
-		if rep != nil {
-			fmt.Println(rep)
-		}
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	nf, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to parse modified MachO: %v", err)
+	}
+	if l, ok := nf.Loads[1].(LoadCmdBytes); !ok || !bytes.Equal(l.Raw(), unknown.Raw()) {
+		t.Errorf("InsertLoad: got %v, want %v", nf.Loads[1], unknown)
 	}
 
-	if afuncs, err := got.GetSwiftAccessibleFunctions(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
-		t.Fatalf("GetSwiftAccessibleFunctions() error = %v", err)
-	} else {
-		fmt.Println("GetSwiftAccessibleFunctions" + strings.Repeat("-", 80))
-		if afuncs != nil {
-			fmt.Println(afuncs)
-		}
+	if err := nf.RemoveLoad(nf.Loads[1]); err != nil {
+		t.Fatal(err)
+	}
+	if nf.NCommands != ncmds || nf.SizeCommands != sizeofcmds {
+		t.Errorf("RemoveLoad: got ncmds=%d sizeofcmds=%#x, want %d, %#x", nf.NCommands, nf.SizeCommands, ncmds, sizeofcmds)
+	}
+	orig, err := obscuretestdata.ReadFile("internal/testdata/gcc-386-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if _, err := nf.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), orig) {
+		t.Error("RemoveLoad: MachO differs from the original after removing the inserted load command")
 	}
 }
 
-func TestNewFileWithObjC(t *testing.T) {
-	f, err := os.Open(fname)
-
+func TestAddSegmentSection(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			t.Skipf("file %s not found", fname)
-		}
 		t.Fatal(err)
 	}
+	var syms []string
+	for _, sym := range f.Symtab.Syms {
+		syms = append(syms, fmt.Sprintf("%s@%#x", sym.Name, sym.Value))
+	}
 
-	got, err := NewFile(f)
-	if err != nil {
-		t.Fatalf("NewFile() error = %v", err)
-		return
+	payload := bytes.Repeat([]byte("payload!"), 0x300)
+	if _, err := f.AddSegment("__PAYLOAD", 1, payload); err != nil {
+		t.Fatal(err)
+	}
+	extra := []byte("extra section data")
+	if _, err := f.AddSection("__PAYLOAD", "__extra", extra, types.Regular); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.AddSection("__TEXT", "__extra", extra, types.Regular); err == nil {
+		t.Error("AddSection: expected an error adding a section to __TEXT")
 	}
 
-	if got.HasObjC() {
-		fmt.Println("HasPlusLoadMethod: ", got.HasPlusLoadMethod())
-		fmt.Println(got.GetObjCToc())
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	nf, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to parse modified MachO: %v", err)
+	}
+	seg := nf.Segment("__PAYLOAD")
+	if seg == nil {
+		t.Fatal("AddSegment: missing __PAYLOAD segment")
+	}
+	if !bytes.Equal(buf.Bytes()[seg.Offset:seg.Offset+uint64(len(payload))], payload) {
+		t.Error("AddSegment: bad __PAYLOAD segment data")
+	}
+	sect := nf.Section("__PAYLOAD", "__extra")
+	if sect == nil {
+		t.Fatal("AddSection: missing __PAYLOAD.__extra section")
+	}
+	if dat, err := sect.Data(); err != nil || !bytes.Equal(dat, extra) {
+		t.Errorf("AddSection: __PAYLOAD.__extra = %q (%v), want %q", dat, err, extra)
+	}
+	if linkedit := nf.Segment("__LINKEDIT"); linkedit.Addr < seg.Addr+seg.Memsz || linkedit.Offset < seg.Offset+seg.Filesz {
+		t.Errorf("AddSegment: __LINKEDIT %s overlaps __PAYLOAD %s", linkedit, seg)
+	}
+	var nsyms []string
+	for _, sym := range nf.Symtab.Syms {
+		nsyms = append(nsyms, fmt.Sprintf("%s@%#x", sym.Name, sym.Value))
+	}
+	if !reflect.DeepEqual(nsyms, syms) {
+		t.Errorf("AddSegment: symbols changed: got %v, want %v", nsyms, syms)
+	}
+}
 
-		info, err := got.GetObjCImageInfo()
-		if err != nil && !errors.Is(err, ErrObjcSectionNotFound) {
-			t.Fatalf("GetObjCImageInfo() error = %v", err)
+func TestLayoutLinkedit(t *testing.T) {
+	for _, name := range []string{
+		"internal/testdata/clang-amd64-darwin-exec-with-rpath.base64",
+		"internal/testdata/clang-386-darwin-exec-with-rpath.base64",
+	} {
+		f, err := openObscured(name)
+		if err != nil {
+			t.Fatal(err)
 		}
-		fmt.Printf("ObjCImageInfo:\n%s\n\n", info.Flags)
-
-		if cfstrs, err := got.GetCFStrings(); err == nil {
-			fmt.Println("CFStrings")
-			fmt.Println("---------")
-			for _, cfstr := range cfstrs {
-				fmt.Printf("%#x: %#v\n", cfstr.Address, cfstr.Name)
-			}
-		} else {
-			if !errors.Is(err, ErrObjcSectionNotFound) {
-				t.Fatalf(err.Error())
-			}
+		var syms []string
+		for _, sym := range f.Symtab.Syms {
+			syms = append(syms, fmt.Sprintf("%s@%#x", sym.Name, sym.Value))
 		}
 
-		if meths, err := got.GetObjCMethodLists(); err == nil {
-			fmt.Println("_OBJC_INSTANCE_METHODS")
-			fmt.Println("----------------------")
-			for _, m := range meths {
-				fmt.Printf("%#x: (%s) %s [%d]\n", m.ImpVMAddr, m.ReturnType(), m.Name, m.NumberOfArguments())
-			}
-		} else {
-			if !errors.Is(err, ErrObjcSectionNotFound) {
-				t.Fatalf(err.Error())
+		if err := f.LayoutLinkedit(); err != nil {
+			t.Fatalf("%s: LayoutLinkedit: %v", name, err)
+		}
+		linkedit := f.Segment("__LINKEDIT")
+		for _, blob := range f.linkeditBlobs() {
+			if uint64(*blob.Offset) < linkedit.Offset || uint64(*blob.Offset+blob.Size) > linkedit.Offset+linkedit.Filesz {
+				t.Errorf("%s: %s at %#x is outside of %s", name, blob.Name, *blob.Offset, linkedit)
 			}
 		}
 
-		if protos, err := got.GetObjCProtocols(); err == nil {
-			for _, proto := range protos {
-				fmt.Println(proto.String())
-			}
-		} else {
-			if !errors.Is(err, ErrObjcSectionNotFound) {
-				t.Fatalf(err.Error())
+		var buf bytes.Buffer
+		if _, err := f.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		nf, err := NewFile(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: failed to parse re-laid-out MachO: %v", name, err)
+		}
+		var nsyms []string
+		for _, sym := range nf.Symtab.Syms {
+			nsyms = append(nsyms, fmt.Sprintf("%s@%#x", sym.Name, sym.Value))
+		}
+		if !reflect.DeepEqual(nsyms, syms) {
+			t.Errorf("%s: symbols changed: got %v, want %v", name, nsyms, syms)
+		}
+	}
+}
+
+func TestLayoutLinkeditTwolevelHints(t *testing.T) {
+	// append a two-level hints table to the end of __LINKEDIT
+	dat := emptyMachO(t)
+	hoff := uint32(len(dat))
+	dat = append(dat, 2, 5, 0, 0, 0, 9, 0, 0)
+	for off := uint32(types.FileHeaderSize64); off < types.FileHeaderSize64+binary.LittleEndian.Uint32(dat[20:]); off += binary.LittleEndian.Uint32(dat[off+4:]) {
+		if types.LoadCmd(binary.LittleEndian.Uint32(dat[off:])) == types.LC_SEGMENT_64 && string(bytes.TrimRight(dat[off+8:off+24], "\x00")) == "__LINKEDIT" {
+			filesz := binary.LittleEndian.Uint64(dat[off+48:])
+			binary.LittleEndian.PutUint64(dat[off+48:], filesz+8)
+		}
+	}
+	dat = addLoadCmd(dat, loadCmd(types.LC_TWOLEVEL_HINTS, []uint32{hoff, 2}, nil))
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.LayoutLinkedit(); err != nil {
+		t.Fatalf("LayoutLinkedit: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	nf, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to parse re-laid-out MachO: %v", err)
+	}
+	for _, l := range nf.Loads {
+		if th, ok := l.(*TwolevelHints); ok {
+			if len(th.Hints) != 2 || th.Hints[0].TableOfContentsIndex() != 5 || th.Hints[1].TableOfContentsIndex() != 9 {
+				t.Errorf("TwolevelHints after LayoutLinkedit = %+v", th.Hints)
 			}
+			return
 		}
+	}
+	t.Error("missing LC_TWOLEVEL_HINTS after LayoutLinkedit")
+}
 
-		if classes, err := got.GetObjCClasses(); err == nil {
-			for _, class := range classes {
-				fmt.Println(class.Verbose())
+func TestStripSymbols(t *testing.T) {
+	for _, name := range []string{
+		"internal/testdata/gcc-amd64-darwin-exec.base64",
+		"internal/testdata/clang-amd64-darwin-exec-with-rpath.base64",
+		"internal/testdata/clang-386-darwin-exec-with-rpath.base64",
+	} {
+		for _, mode := range []StripMode{StripDebug, StripLocal, StripAllButExported} {
+			f, err := openObscured(name)
+			if err != nil {
+				t.Fatal(err)
 			}
-		} else {
-			if !errors.Is(err, ErrObjcSectionNotFound) {
-				t.Fatalf(err.Error())
+			var want []string
+			for _, sym := range f.Symtab.Syms {
+				if n := (types.Nlist64{Nlist: types.Nlist{Type: sym.Type, Desc: sym.Desc}}); mode.keep(n) {
+					want = append(want, fmt.Sprintf("%s@%#x", sym.Name, sym.Value))
+				}
 			}
-		}
 
-		if nlclasses, err := got.GetObjCNonLazyClasses(); err == nil {
-			for _, class := range nlclasses {
-				fmt.Println(class.String())
+			if err := f.StripSymbols(mode, nil); err != nil {
+				t.Fatalf("%s: StripSymbols(%s): %v", name, mode, err)
+			}
+			var buf bytes.Buffer
+			if _, err := f.WriteTo(&buf); err != nil {
+				t.Fatal(err)
+			}
+			nf, err := NewFile(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("%s: failed to parse stripped MachO: %v", name, err)
+			}
+			var got []string
+			for _, sym := range nf.Symtab.Syms {
+				got = append(got, fmt.Sprintf("%s@%#x", sym.Name, sym.Value))
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: StripSymbols(%s): got %v, want %v", name, mode, got, want)
+			}
+			if d := nf.Dysymtab; d != nil {
+				if d.Nlocalsym+d.Nextdefsym+d.Nundefsym != nf.Symtab.Nsyms ||
+					d.Iextdefsym != d.Ilocalsym+d.Nlocalsym || d.Iundefsym != d.Iextdefsym+d.Nextdefsym {
+					t.Errorf("%s: StripSymbols(%s): bad dysymtab symbol ranges %s", name, mode, d)
+				}
+				for _, idx := range d.IndirectSyms {
+					if idx&(types.INDIRECT_SYMBOL_LOCAL|types.INDIRECT_SYMBOL_ABS) == 0 && idx >= nf.Symtab.Nsyms {
+						t.Errorf("%s: StripSymbols(%s): bad indirect symbol index %d", name, mode, idx)
+					}
+				}
 			}
-		} else {
-			t.Fatalf(err.Error())
 		}
+	}
+}
 
-		if cats, err := got.GetObjCCategories(); err == nil {
-			for _, cat := range cats {
-				fmt.Println(cat.String())
-			}
-		} else {
-			t.Fatalf(err.Error())
+func TestStripSymbolsSigned(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.CodeSign(&codesign.Config{ID: "com.example.test", Flags: cstypes.ADHOC}); err != nil {
+		t.Fatal(err)
+	}
+	nsyms := f.Symtab.Nsyms
+	if err := f.StripSymbols(StripAllButExported, nil); err == nil {
+		t.Fatal("StripSymbols: stripping a signed MachO without a codesign.Config should fail")
+	}
+	if f.Symtab.Nsyms != nsyms {
+		t.Fatal("StripSymbols: a failed strip modified the symbol table")
+	}
+
+	if err := f.StripSymbols(StripAllButExported, &codesign.Config{ID: "com.example.stripped", Flags: cstypes.ADHOC}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	nf, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to parse stripped MachO: %v", err)
+	}
+	if cs := nf.CodeSignature(); cs == nil || len(cs.CodeDirectories) == 0 || cs.CodeDirectories[0].ID != "com.example.stripped" {
+		t.Errorf("StripSymbols: not re-signed with the given codesign.Config: %+v", cs)
+	}
+}
+
+func TestRebase(t *testing.T) {
+	const slide = 0x10000000
+	for _, name := range []string{
+		"internal/testdata/clang-amd64-darwin-exec-with-rpath.base64",
+		"internal/testdata/clang-386-darwin-exec-with-rpath.base64",
+		"internal/testdata/gcc-amd64-darwin-exec.base64",
+	} {
+		f, err := openObscured(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var addrs, syms []uint64
+		for _, seg := range f.Segments() {
+			addrs = append(addrs, seg.Addr)
 		}
+		for _, sym := range f.Symtab.Syms {
+			syms = append(syms, sym.Value)
+		}
+		rebases, _ := f.GetRebaseInfo()
 
-		if nlcats, err := got.GetObjCNonLazyCategories(); err == nil {
-			for _, cat := range nlcats {
-				fmt.Println(cat.String())
-			}
-		} else {
-			t.Fatalf(err.Error())
+		if err := f.Rebase(slide); err != nil {
+			t.Fatalf("%s: Rebase: %v", name, err)
+		}
+		var buf bytes.Buffer
+		if _, err := f.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		nf, err := NewFile(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: failed to parse rebased MachO: %v", name, err)
 		}
 
-		if selRefs, err := got.GetObjCProtoReferences(); err == nil {
-			fmt.Println("@proto refs")
-			for off, prot := range selRefs {
-				fmt.Printf("%#x -> %#x: %s\n", off, prot.Ptr, prot.Name)
+		for i, seg := range nf.Segments() {
+			want := addrs[i] + slide
+			if seg.Name == "__PAGEZERO" {
+				want = 0
 			}
-		} else {
-			t.Fatalf(err.Error())
-		}
-		if selRefs, err := got.GetObjCClassReferences(); err == nil {
-			fmt.Println("@class refs")
-			for off, sel := range selRefs {
-				fmt.Printf("%#x -> %#x: %s\n", off, sel.ClassPtr, sel.Name)
+			if seg.Addr != want {
+				t.Errorf("%s: Rebase: segment %s addr = %#x, want %#x", name, seg.Name, seg.Addr, want)
 			}
-		} else {
-			t.Fatalf(err.Error())
 		}
-		if selRefs, err := got.GetObjCSuperReferences(); err == nil {
-			fmt.Println("@super refs")
-			for off, sel := range selRefs {
-				fmt.Printf("%#x -> %#x: %s\n", off, sel.ClassPtr, sel.SuperClass)
+		for i, sym := range nf.Symtab.Syms {
+			if sym.Type.IsDefinedInSection() && sym.Value != syms[i]+slide {
+				t.Errorf("%s: Rebase: symbol %s = %#x, want %#x", name, sym.Name, sym.Value, syms[i]+slide)
 			}
-		} else {
-			t.Fatalf(err.Error())
 		}
-		if selRefs, err := got.GetObjCSelectorReferences(); err == nil {
-			fmt.Println("@selectors refs")
-			for off, sel := range selRefs {
-				fmt.Printf("%#x -> %#x: %s\n", off, sel.VMAddr, sel.Name)
+		nrebases, _ := nf.GetRebaseInfo()
+		for i, r := range nrebases {
+			ptr := make([]byte, nf.pointerSize())
+			nf.cr.ReadAt(ptr, int64(nf.Segment(r.Segment).Offset+r.Offset))
+			orig := make([]byte, f.pointerSize())
+			f.cr.ReadAt(orig, int64(f.Segment(r.Segment).Offset+rebases[i].Offset))
+			if got, want := binary.LittleEndian.Uint32(ptr), binary.LittleEndian.Uint32(orig)+slide; got != want {
+				t.Errorf("%s: Rebase: pointer at %s+%#x = %#x, want %#x", name, r.Segment, r.Offset, got, want)
 			}
-		} else {
-			t.Fatalf(err.Error())
 		}
-		if methods, err := got.GetObjCMethodNames(); err == nil {
-			fmt.Printf("\n@methods\n")
-			for vmaddr, method := range methods {
-				fmt.Printf("%#x: %s\n", vmaddr, method)
+		for _, l := range nf.Loads {
+			if ut, ok := l.(*UnixThread); ok {
+				if rip := binary.LittleEndian.Uint64(ut.Threads[0].Data[16*8:]); nf.FindSectionForVMAddr(rip) == nil {
+					t.Errorf("%s: Rebase: entry point %#x is not in a section", name, rip)
+				}
 			}
+		}
+	}
+}
+
+func TestObjCStubsARM64(t *testing.T) {
+	b := NewBuilder(types.MH_EXECUTE, types.CPUArm64, types.CPUSubtypeArm64All)
+	// small stubs: adrp x1, selref@PAGE; ldr x1, [x1, selref@PAGEOFF]; b _objc_msgSend
+	stubs := b.AddSection("__TEXT", "__objc_stubs", make([]byte, 2*12), types.PURE_INSTRUCTIONS|types.SOME_INSTRUCTIONS)
+	methname := b.AddSection("__TEXT", "__objc_methname", []byte("init\x00alloc\x00"), types.CstringLiterals)
+	selrefs := b.AddSection("__DATA", "__objc_selrefs", make([]byte, 2*8), types.LiteralPointers)
+	b.SetEntryPoint("__TEXT", "__objc_stubs", 0)
+	if _, err := b.Build(); err != nil { // lay out the sections
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint64(selrefs.Data[0:], methname.Addr)
+	binary.LittleEndian.PutUint64(selrefs.Data[8:], methname.Addr+5)
+	for i := uint64(0); i < 2; i++ {
+		pc := stubs.Addr + i*12
+		ref := selrefs.Addr + i*8
+		page := int64(ref&^0xfff-pc&^0xfff) >> 12
+		binary.LittleEndian.PutUint32(stubs.Data[i*12:], 0x90000001|uint32(page&3)<<29|uint32(page>>2&0x7ffff)<<5)
+		binary.LittleEndian.PutUint32(stubs.Data[i*12+4:], 0xf9400021|uint32(ref&0xfff/8)<<10)
+		binary.LittleEndian.PutUint32(stubs.Data[i*12+8:], 0x14000000)
+	}
+
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.GetObjCStubs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uint64]*objc.Stub{
+		stubs.Addr:      {Name: "init", SelectorRef: selrefs.Addr},
+		stubs.Addr + 12: {Name: "alloc", SelectorRef: selrefs.Addr + 8},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetObjCStubs: got %v, want %v", got, want)
+	}
+}
+
+func TestGetCFStrings(t *testing.T) {
+	b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.AddSection("__TEXT", "__text", []byte{0xc3}, types.PURE_INSTRUCTIONS|types.SOME_INSTRUCTIONS)
+	cstr := b.AddSection("__TEXT", "__cstring", []byte("hello\x00"), types.CstringLiterals)
+	ustr := b.AddSection("__TEXT", "__ustring", []byte{'h', 0, 0xe9, 0, 0x3d, 0xd8, 0x00, 0xde, 0, 0}, types.Regular) // "hé😀"
+	cfstring := b.AddSection("__DATA", "__cfstring", make([]byte, 2*32), types.Regular)
+	b.SetEntryPoint("__TEXT", "__text", 0)
+	if _, err := b.Build(); err != nil { // lay out the sections
+		t.Fatal(err)
+	}
+	for i, cf := range []objc.CFString64Type{
+		{Info: 0x7c8, Data: cstr.Addr, Length: 5},
+		{Info: 0x7d0, Data: ustr.Addr, Length: 4},
+	} {
+		binary.LittleEndian.PutUint64(cfstring.Data[i*32+8:], cf.Info)
+		binary.LittleEndian.PutUint64(cfstring.Data[i*32+16:], cf.Data)
+		binary.LittleEndian.PutUint64(cfstring.Data[i*32+24:], cf.Length)
+	}
+
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfstrs, err := f.GetCFStrings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfstrs) != 2 || cfstrs[0].Name != "hello" || cfstrs[1].Name != "hé😀" || cfstrs[1].Address != cfstring.Addr+32 {
+		t.Errorf("GetCFStrings: got %+v", cfstrs)
+	}
+
+	// a UTF-16 length running past the end of __ustring must not be allocated
+	binary.LittleEndian.PutUint64(cfstring.Data[32+24:], 1<<40)
+	buf.Reset()
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = NewFile(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.GetCFStrings(); err == nil {
+		t.Error("GetCFStrings() succeeded with a UTF-16 length past the end of its section")
+	}
+}
+
+func TestGetInitializers(t *testing.T) {
+	b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.AddSection("__TEXT", "__text", []byte{0xc3, 0xc3, 0xc3}, types.PURE_INSTRUCTIONS|types.SOME_INSTRUCTIONS)
+	initOffsets := b.AddSection("__TEXT", "__init_offsets", make([]byte, 4), types.InitFuncOffsets)
+	modInit := b.AddSection("__DATA", "__mod_init_func", make([]byte, 2*8), types.ModInitFuncPointers)
+	b.SetEntryPoint("__TEXT", "__text", 0)
+	if _, err := b.Build(); err != nil { // lay out the sections
+		t.Fatal(err)
+	}
+	text := b.Segment("__TEXT").Sections[0]
+	binary.LittleEndian.PutUint64(modInit.Data, text.Addr+1)
+	binary.LittleEndian.PutUint64(modInit.Data[8:], text.Addr+2)
+	binary.LittleEndian.PutUint32(initOffsets.Data, uint32(text.Addr-b.Segment("__TEXT").Addr))
+
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inits, err := f.GetInitializers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint64{text.Addr, text.Addr + 1, text.Addr + 2}; !reflect.DeepEqual(inits, want) {
+		t.Errorf("GetInitializers() = %#x, want %#x", inits, want)
+	}
+}
+
+func TestGetInterposing(t *testing.T) {
+	b := NewBuilder(types.MH_DYLIB, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.InstallName = "/usr/lib/libhook.dylib"
+	b.AddSection("__TEXT", "__text", []byte{0xc3, 0xc3}, types.PURE_INSTRUCTIONS|types.SOME_INSTRUCTIONS)
+	interpose := b.AddSection("__DATA", "__interpose", make([]byte, 2*8), types.Interposing)
+	b.AddSymbol("_my_open", "__TEXT", "__text", 0, true)
+	b.AddSymbol("_open_impl", "__TEXT", "__text", 1, false)
+	if _, err := b.Build(); err != nil { // lay out the sections
+		t.Fatal(err)
+	}
+	text := b.Segment("__TEXT").Sections[0]
+	binary.LittleEndian.PutUint64(interpose.Data, text.Addr)
+	binary.LittleEndian.PutUint64(interpose.Data[8:], text.Addr+1)
+
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	interposes, err := f.GetInterposing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.Interpose{{Address: interpose.Addr, Replacement: text.Addr, Replacee: text.Addr + 1, Name: "_open_impl"}}
+	if !reflect.DeepEqual(interposes, want) {
+		t.Errorf("GetInterposing() = %+v, want %+v", interposes, want)
+	}
+}
+
+func TestGetSymbolPointers(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ptrs, err := f.GetSymbolPointers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.SymbolPointer{
+		{Address: 0x100001000, Section: "__nl_symbol_ptr", Name: "dyld_stub_binder", Dylib: "libSystem.B.dylib"},
+		{Address: 0x100001008, Section: "__nl_symbol_ptr"}, // INDIRECT_SYMBOL_LOCAL
+		{Address: 0x100001010, Section: "__la_symbol_ptr", Name: "_printf", Dylib: "libSystem.B.dylib", Lazy: true},
+	}
+	if !reflect.DeepEqual(ptrs, want) {
+		t.Errorf("GetSymbolPointers() = %+v, want %+v", ptrs, want)
+	}
+}
+
+func TestGetStubs(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stubs, err := f.GetStubs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.Stub{
+		{Address: 0x100000f8a, Size: 6, Section: "__stubs", Name: "_printf", Dylib: "libSystem.B.dylib"},
+		{Address: 0x100000fa0, Size: 10, Section: "__stub_helper", Name: "_printf", Dylib: "libSystem.B.dylib"},
+	}
+	if !reflect.DeepEqual(stubs, want) {
+		t.Errorf("GetStubs() = %+v, want %+v", stubs, want)
+	}
+}
+
+func TestIndirectSymbols(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	syms, err := f.GetIndirectSymbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, sym := range syms {
+		if sym.IsAbsolute() {
+			names = append(names, "ABSOLUTE")
 		} else {
-			t.Fatalf(err.Error())
+			names = append(names, sym.Name)
 		}
 	}
+	if want := []string{"_printf", "dyld_stub_binder", "ABSOLUTE", "_printf"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("GetIndirectSymbols() = %v, want %v", names, want)
+	}
+	if name, err := f.IndirectSymbolName(f.Section("__DATA", "__la_symbol_ptr").Addr, 0); err != nil || name != "_printf" {
+		t.Errorf("IndirectSymbolName() = %s, %v, want _printf", name, err)
+	}
+	if _, err := f.IndirectSymbolName(f.Section("__DATA", "__nl_symbol_ptr").Addr, 1); err != ErrIndirectSymbolLocal {
+		t.Errorf("IndirectSymbolName() error = %v, want %v", err, ErrIndirectSymbolLocal)
+	}
+}
+
+func TestGetUnwindEntries(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := f.GetUnwindEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Start != 0x100000f60 || entries[0].End != 0x100000f8b || entries[0].Encoding.ModeString(f.CPU) != "frame" {
+		t.Errorf("GetUnwindEntries() = %v", entries)
+	}
+}
+
+func TestGetEHFrame(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eh, err := f.GetEHFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eh.CIEs) != 1 || eh.CIEs[0].Augmentation != "zR" || eh.CIEs[0].DataAlignmentFactor != -8 || eh.CIEs[0].ReturnAddressRegister != 16 {
+		t.Errorf("GetEHFrame() CIEs = %+v", eh.CIEs)
+	}
+	if len(eh.FDEs) != 1 || eh.FDEs[0].PCBegin != 0x100000f6a || eh.FDEs[0].PCEnd != 0x100000f81 {
+		t.Errorf("GetEHFrame() FDEs = %v", eh.FDEs)
+	}
+}
+
+func TestRecoverFunctions(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs, err := f.RecoverFunctions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.RecoveredFunction{
+		{Function: types.Function{Name: "start", StartAddr: 0x100000f14, EndAddr: 0x100000f50}, Sources: types.FunctionSourceSymtab},
+		{Function: types.Function{Name: "dyld_stub_binding_helper", StartAddr: 0x100000f50, EndAddr: 0x100000f64}, Sources: types.FunctionSourceSymtab},
+		{Function: types.Function{Name: "__dyld_func_lookup", StartAddr: 0x100000f64, EndAddr: 0x100000f6a}, Sources: types.FunctionSourceSymtab},
+		{Function: types.Function{Name: "_main", StartAddr: 0x100000f6a, EndAddr: 0x100000f81}, Sources: types.FunctionSourceSymtab | types.FunctionSourceEHFrame},
+	}
+	if !reflect.DeepEqual(funcs, want) {
+		t.Errorf("RecoverFunctions() = %+v, want %+v", funcs, want)
+	}
+	if funcs[3].Confidence() != 2 || funcs[3].Sources.String() != "symtab|eh_frame" {
+		t.Errorf("RecoverFunctions() _main sources = %s", funcs[3].Sources)
+	}
+}
+
+func TestDWARF5(t *testing.T) {
+	abbrev := []byte{
+		1, 0x11, 0, // abbrev 1: DW_TAG_compile_unit, no children
+		0x03, 0x25, // DW_AT_name: DW_FORM_strx1
+		0x72, 0x17, // DW_AT_str_offsets_base: DW_FORM_sec_offset
+		0x1b, 0x1f, // DW_AT_comp_dir: DW_FORM_line_strp
+		0, 0, 0,
+	}
+	info := []byte{
+		0, 0, 0, 0, // unit length
+		5, 0, // version
+		1,          // DW_UT_compile
+		8,          // address size
+		0, 0, 0, 0, // abbrev offset
+		1,          // abbrev 1
+		0,          // DW_AT_name: str_offsets[0]
+		8, 0, 0, 0, // DW_AT_str_offsets_base
+		0, 0, 0, 0, // DW_AT_comp_dir: .debug_line_str[0]
+	}
+	binary.LittleEndian.PutUint32(info, uint32(len(info)-4))
+	strOffsets := []byte{8, 0, 0, 0, 5, 0, 0, 0, 0, 0, 0, 0}
+
+	b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.AddSection("__TEXT", "__text", []byte{0xc3}, types.PURE_INSTRUCTIONS|types.SOME_INSTRUCTIONS)
+	b.SetEntryPoint("__TEXT", "__text", 0)
+	b.AddSection("__DWARF", "__debug_abbrev", abbrev, types.DEBUG)
+	b.AddSection("__DWARF", "__debug_info", info, types.DEBUG)
+	b.AddSection("__DWARF", "__debug_str", []byte("hello.c\x00"), types.DEBUG)
+	b.AddSection("__DWARF", "__debug_str_offs", strOffsets, types.DEBUG) // __debug_str_offsets truncated to 16 characters
+	b.AddSection("__DWARF", "__debug_line_str", []byte("/tmp\x00"), types.DEBUG)
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := f.DWARF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cu, err := d.Reader().Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := cu.Val(dwarf.AttrName).(string); name != "hello.c" {
+		t.Errorf("DW_AT_name = %v, want hello.c", cu.Val(dwarf.AttrName))
+	}
+	if dir, _ := cu.Val(dwarf.AttrCompDir).(string); dir != "/tmp" {
+		t.Errorf("DW_AT_comp_dir = %v, want /tmp", cu.Val(dwarf.AttrCompDir))
+	}
+}
+
+func TestDWARFWithDSYM(t *testing.T) {
+	dat, err := obscuretestdata.ReadFile("internal/testdata/gcc-amd64-darwin-exec-debug.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "hello")
+	dwarfDir := filepath.Join(bin+".dSYM", "Contents", "Resources", "DWARF")
+	if err := os.MkdirAll(dwarfDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dwarfDir, "hello"), dat, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{bin, bin + ".dSYM"} {
+		d, err := f.DWARFWithDSYM(path)
+		if err != nil {
+			t.Fatalf("DWARFWithDSYM(%s) error = %v", path, err)
+		}
+		e, err := d.Reader().Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Tag != dwarf.TagCompileUnit {
+			t.Errorf("DWARFWithDSYM(%s) first entry = %s, want compile unit", path, e.Tag)
+		}
+	}
+
+	// the binary shares its dSYM's UUID, the dSYM must still be picked over it
+	exe, err := obscuretestdata.ReadFile("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stripped, err := NewFile(bytes.NewReader(exe))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe = bytes.Replace(exe, stripped.UUID().UUID[:], f.UUID().UUID[:], 1)
+	if err := os.WriteFile(bin, exe, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if have, err := FindDSYM(bin, f.UUID().UUID); err != nil || have != filepath.Join(dwarfDir, "hello") {
+		t.Errorf("FindDSYM(%s) = %s, %v; want %s", bin, have, err, filepath.Join(dwarfDir, "hello"))
+	}
+	if d, err := f.DWARFWithDSYM(bin); err != nil {
+		t.Errorf("DWARFWithDSYM(%s) with a matching binary error = %v", bin, err)
+	} else if _, err := d.Reader().Next(); err != nil {
+		t.Error(err)
+	}
+
+	other, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.DWARFWithDSYM(bin); !errors.Is(err, ErrDSYMNotFound) {
+		t.Errorf("DWARFWithDSYM() with mismatched UUID error = %v, want ErrDSYMNotFound", err)
+	}
+}
+
+var fname string
+
+func init() {
+	flag.StringVar(&fname, "file", "", "MachO file to test")
+}
+
+func TestNewFile(t *testing.T) {
+	f, err := os.Open(fname)
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			t.Skipf("file %s not found", fname)
+		}
+		t.Fatal(err)
+	}
+
+	got, err := NewFile(f)
+	if err != nil {
+		t.Fatalf("NewFile() error = %v", err)
+		return
+	}
+
+	fmt.Println(got.FileTOC.String())
+
+	cs := got.CodeSignature()
+	if cs != nil {
+		fmt.Println(cs.Requirements[0].Detail)
+	}
+	if len(cs.LaunchConstraintsSelf) > 0 {
+		os.WriteFile("lc_self.bin", cs.LaunchConstraintsSelf, 0644)
+		lc, err := cstypes.ParseLaunchContraints(cs.LaunchConstraintsSelf)
+		if err != nil {
+			t.Fatalf("ParseLaunchContraints() error = %v", err)
+		}
+		dat, err := json.MarshalIndent(lc, "", "  ")
+		if err != nil {
+			t.Fatalf("json.MarshalIndent() error = %v", err)
+		}
+		fmt.Println(string(dat))
+	}
+	if len(cs.LaunchConstraintsParent) > 0 {
+		os.WriteFile("lc_parent.bin", cs.LaunchConstraintsParent, 0644)
+		lc, err := cstypes.ParseLaunchContraints(cs.LaunchConstraintsParent)
+		if err != nil {
+			t.Fatalf("ParseLaunchContraints() error = %v", err)
+		}
+		dat, err := json.MarshalIndent(lc, "", "  ")
+		if err != nil {
+			t.Fatalf("json.MarshalIndent() error = %v", err)
+		}
+		fmt.Println(string(dat))
+	}
+	if len(cs.LaunchConstraintsResponsible) > 0 {
+		os.WriteFile("lc_responsible.bin", cs.LaunchConstraintsResponsible, 0644)
+		lc, err := cstypes.ParseLaunchContraints(cs.LaunchConstraintsResponsible)
+		if err != nil {
+			t.Fatalf("ParseLaunchContraints() error = %v", err)
+		}
+		dat, err := json.MarshalIndent(lc, "", "  ")
+		if err != nil {
+			t.Fatalf("json.MarshalIndent() error = %v", err)
+		}
+		fmt.Println(string(dat))
+	}
+	if len(cs.LibraryConstraints) > 0 {
+		os.WriteFile("lib_constraints.bin", cs.LibraryConstraints, 0644)
+		lc, err := cstypes.ParseLaunchContraints(cs.LibraryConstraints)
+		if err != nil {
+			t.Fatalf("ParseLaunchContraints() error = %v", err)
+		}
+		dat, err := json.MarshalIndent(lc, "", "  ")
+		if err != nil {
+			t.Fatalf("json.MarshalIndent() error = %v", err)
+		}
+		fmt.Println(string(dat))
+	}
+
+	d, err := got.DWARF()
+	if err != nil {
+		t.Fatalf("DWARF() error = %v", err)
+	}
+
+	r := d.Reader()
+
+	debugStrSec := got.Section("__DWARF", "__debug_str")
+	if debugStrSec == nil {
+		t.Fatalf("Section() error = section __DWARF.__debug_str not found")
+	}
+
+	names, err := d.DumpNames()
+	if err != nil {
+		t.Errorf("DWARF.DumpNames() error = %v", err)
+	}
+	for _, name := range names {
+		nameStr, err := got.GetCString(debugStrSec.Addr + uint64(name.StrOffset))
+		if err != nil {
+			t.Errorf("GetCString() error = %v", err)
+		}
+
+		for _, hdata := range name.HashData {
+			r.Seek(*hdata[0].(*dwarf.Offset))
+			entry, err := r.Next()
+			if err != nil {
+				t.Fatalf("DWARF.Reader().Next() error = %v", err)
+			}
+
+			switch entry.Tag {
+			case dwarf.TagInlinedSubroutine:
+			case dwarf.TagSubroutineType, dwarf.TagSubprogram:
+				ty, _ := d.Type(entry.Offset)
+				if ty.(*dwarf.FuncType).FileIndex > 0 {
+					fs, err := d.FilesForEntry(entry)
+					if err != nil {
+						t.Fatalf("DWARF.FilesForEntry() error = %v", err)
+					}
+					fmt.Printf("TAG: %s, NAME: %s, TYPE: %s\nFILE: %s\n", entry.Tag, nameStr, ty, fs[ty.(*dwarf.FuncType).FileIndex].Name)
+				}
+			default:
+				ty, _ := d.Type(entry.Offset)
+				declFile, dOK := entry.Val(dwarf.AttrDeclFile).(int64)
+				callFile, cOK := entry.Val(dwarf.AttrCallFile).(int64)
+				if dOK || cOK {
+					fs, err := d.FilesForEntry(entry)
+					if err != nil {
+						t.Fatalf("DWARF.FilesForEntry() error = %v", err)
+					}
+					fmt.Printf("TAG: %s, NAME: %s, TYPE: %s\nFILE: %s\n", entry.Tag, nameStr, ty, fs[declFile+callFile].Name)
+				} else {
+					fmt.Printf("TAG: %s, NAME: %s, TYPE: %s\n", entry.Tag, nameStr, ty)
+				}
+			}
+		}
+	}
+
+	nameSpaces, err := d.DumpNamespaces()
+	if err != nil {
+		t.Fatalf("DWARF.DumpNamespaces() error = %v", err)
+	}
+	for _, ns := range nameSpaces {
+		nsStr, err := got.GetCString(debugStrSec.Addr + uint64(ns.StrOffset))
+		if err != nil {
+			t.Errorf("GetCString() error = %v", err)
+		}
+		for _, hdata := range ns.HashData {
+			r.Seek(*hdata[0].(*dwarf.Offset))
+			entry, err := r.Next()
+			if err != nil {
+				t.Fatalf("DWARF.Reader().Next() error = %v", err)
+			}
+
+			typ, err := d.Type(entry.Offset)
+			if err != nil {
+				t.Fatalf("DWARF.Type() error = %v", err)
+			}
+			fmt.Printf("TAG: %s, NAME: %s, TYPE: %s\n", entry.Tag, nsStr, typ)
+		}
+	}
+
+	objc, err := d.DumpObjC()
+	if err != nil {
+		t.Fatalf("DWARF.DumpObjC() error = %v", err)
+	}
+	for _, oc := range objc {
+		nsStr, err := got.GetCString(debugStrSec.Addr + uint64(oc.StrOffset))
+		if err != nil {
+			t.Errorf("GetCString() error = %v", err)
+		}
+		fmt.Println(nsStr)
+	}
+
+	types, err := d.DumpTypes()
+	if err != nil {
+		t.Fatalf("DWARF.LookDumpTypesupType() error = %v", err)
+	}
+	for _, typ := range types {
+		typStr, err := got.GetCString(debugStrSec.Addr + uint64(typ.StrOffset))
+		if err != nil {
+			t.Errorf("GetCString() error = %v", err)
+		}
+		fmt.Println(typStr)
+	}
+
+	off, err := d.LookupType("thread")
+	if err != nil {
+		t.Fatalf("DWARF.LookupType() error = %v", err)
+	}
+
+	r.Seek(off)
+
+	entry, err := r.Next()
+	if err != nil {
+		t.Fatalf("DWARF.Reader().Next() error = %v", err)
+	}
+
+	if entry.Tag == dwarf.TagStructType {
+		typ, err := d.Type(entry.Offset)
+		if err != nil {
+			t.Errorf("DWARF entry.Type() error = %v", err)
+		}
+		if t1, ok := typ.(*dwarf.StructType); ok {
+			if strings.EqualFold(t1.StructName, "thread") {
+				if !t1.Incomplete {
+					fmt.Println(t1.Defn(false))
+				}
+			}
+		}
+	}
+}
+
+func TestNewFileWithSwift(t *testing.T) {
+	f, err := os.Open(fname)
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			t.Skipf("file %s not found", fname)
+		}
+		t.Fatal(err)
+	}
+
+	got, err := NewFile(f)
+	if err != nil {
+		t.Fatalf("NewFile() error = %v", err)
+		return
+	}
+
+	if entry, err := got.GetSwiftEntry(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetSwiftEntry() error = %v", err)
+	} else {
+		fmt.Println("GetSwiftEntry" + strings.Repeat("-", 80))
+		fmt.Printf("%#x: entry\n", entry)
+	}
+
+	if bins, err := got.GetSwiftBuiltinTypes(); err != nil {
+		if !errors.Is(err, ErrSwiftSectionError) {
+			t.Fatalf("GetSwiftBuiltinTypes() error = %v", err)
+		}
+	} else {
+		fmt.Println("GetSwiftBuiltinTypes" + strings.Repeat("-", 80))
+		for _, bin := range bins {
+			fmt.Println(bin.Verbose())
+		}
+	}
+
+	if refStrs, err := got.GetSwiftReflectionStrings(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetSwiftReflectionStrings() error = %v", err)
+	} else {
+		fmt.Println("GetSwiftReflectionStrings" + strings.Repeat("-", 80))
+		for addr, refstr := range refStrs {
+			fmt.Printf("%#x: %s\n", addr, refstr)
+		}
+	}
+
+	if fds, err := got.GetSwiftFields(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetSwiftFields() error = %v", err)
+	} else {
+		fmt.Println("GetSwiftFields" + strings.Repeat("-", 80))
+		for _, f := range fds {
+			fmt.Println(f.Verbose())
+		}
+	}
+
+	if atyps, err := got.GetSwiftAssociatedTypes(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetSwiftAssociatedTypes() error = %v", err)
+	} else {
+		fmt.Println("GetSwiftAssociatedTypes" + strings.Repeat("-", 80))
+		for _, at := range atyps {
+			fmt.Println(at.Verbose())
+		}
+	}
+
+	if typs, err := got.GetSwiftColocateTypeDescriptors(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetColocateTypeDescriptors() error = %v", err)
+	} else {
+		fmt.Println("GetColocateTypeDescriptors" + strings.Repeat("-", 80))
+		for _, typ := range typs {
+			fmt.Println(typ.Verbose())
+		}
+	}
+
+	if mdatas, err := got.GetSwiftColocateMetadata(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetColocateMetadata() error = %v", err)
+	} else {
+		fmt.Println("GetColocateMetadata" + strings.Repeat("-", 80))
+		for _, mdat := range mdatas {
+			fmt.Println(mdat.Verbose())
+		}
+	}
+
+	// if refStrs, err := got.GetSwiftTypeRefs(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+	// 	t.Fatalf("GetSwiftTypeRefs() error = %v", err)
+	// } else {
+	// 	for addr, refstr := range refStrs {
+	// 		fmt.Printf("%#x: %s\n", addr, refstr)
+	// 	}
+	// }
+
+	if typs, err := got.GetSwiftTypes(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetSwiftTypes() error = %v", err)
+	} else {
+		fmt.Println("GetSwiftTypes" + strings.Repeat("-", 80))
+		for _, t := range typs {
+			fmt.Println(t)
+		}
+	}
+
+	if prots, err := got.GetSwiftProtocols(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetSwiftProtocols() error = %v", err)
+	} else {
+		fmt.Println("GetSwiftProtocols" + strings.Repeat("-", 80))
+		for _, prot := range prots {
+			fmt.Println(prot.Verbose())
+		}
+	}
+
+	if protsconfs, err := got.GetSwiftProtocolConformances(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetSwiftProtocolConformances() error = %v", err)
+	} else {
+		fmt.Println("GetSwiftProtocolConformances" + strings.Repeat("-", 80))
+		for _, prot := range protsconfs {
+			fmt.Println(prot.Verbose())
+		}
+	}
+
+	if mpenums, err := got.GetSwiftMultiPayloadEnums(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetMultiPayloadEnums() error = %v", err)
+	} else {
+		fmt.Println("GetMultiPayloadEnums" + strings.Repeat("-", 80))
+		for _, e := range mpenums {
+			fmt.Println(e)
+		}
+	}
+
+	if clos, err := got.GetSwiftClosures(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetSwiftClosures() error = %v", err)
+	} else {
+		fmt.Println("GetSwiftClosures" + strings.Repeat("-", 80))
+		for _, c := range clos {
+			fmt.Println(c)
+		}
+	}
+
+	if rep, err := got.GetSwiftDynamicReplacementInfo(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetSwiftDynamicReplacementInfo() error = %v", err)
+	} else {
+		fmt.Println("GetSwiftDynamicReplacementInfo" + strings.Repeat("-", 80))
+		if rep != nil {
+			fmt.Println(rep)
+		}
+	}
+
+	if rep, err := got.GetSwiftDynamicReplacementInfoForOpaqueTypes(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetSwiftDynamicReplacementInfoForOpaqueTypes() error = %v", err)
+	} else {
+		fmt.Println("GetSwiftDynamicReplacementInfoForOpaqueTypes" + strings.Repeat("-", 80))
+		if rep != nil {
+			fmt.Println(rep)
+		}
+	}
+
+	if afuncs, err := got.GetSwiftAccessibleFunctions(); err != nil && !errors.Is(err, ErrSwiftSectionError) {
+		t.Fatalf("GetSwiftAccessibleFunctions() error = %v", err)
+	} else {
+		fmt.Println("GetSwiftAccessibleFunctions" + strings.Repeat("-", 80))
+		if afuncs != nil {
+			fmt.Println(afuncs)
+		}
+	}
+}
+
+func TestNewFileWithObjC(t *testing.T) {
+	f, err := os.Open(fname)
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			t.Skipf("file %s not found", fname)
+		}
+		t.Fatal(err)
+	}
+
+	got, err := NewFile(f)
+	if err != nil {
+		t.Fatalf("NewFile() error = %v", err)
+		return
+	}
+
+	if got.HasObjC() {
+		fmt.Println("HasPlusLoadMethod: ", got.HasPlusLoadMethod())
+		fmt.Println(got.GetObjCToc())
+
+		info, err := got.GetObjCImageInfo()
+		if err != nil && !errors.Is(err, ErrObjcSectionNotFound) {
+			t.Fatalf("GetObjCImageInfo() error = %v", err)
+		}
+		fmt.Printf("ObjCImageInfo:\n%s\n\n", info.Flags)
+
+		if cfstrs, err := got.GetCFStrings(); err == nil {
+			fmt.Println("CFStrings")
+			fmt.Println("---------")
+			for _, cfstr := range cfstrs {
+				fmt.Printf("%#x: %#v\n", cfstr.Address, cfstr.Name)
+			}
+		} else {
+			if !errors.Is(err, ErrObjcSectionNotFound) {
+				t.Fatalf(err.Error())
+			}
+		}
+
+		if meths, err := got.GetObjCMethodLists(); err == nil {
+			fmt.Println("_OBJC_INSTANCE_METHODS")
+			fmt.Println("----------------------")
+			for _, m := range meths {
+				fmt.Printf("%#x: (%s) %s [%d]\n", m.ImpVMAddr, m.ReturnType(), m.Name, m.NumberOfArguments())
+			}
+		} else {
+			if !errors.Is(err, ErrObjcSectionNotFound) {
+				t.Fatalf(err.Error())
+			}
+		}
+
+		if protos, err := got.GetObjCProtocols(); err == nil {
+			for _, proto := range protos {
+				fmt.Println(proto.String())
+			}
+		} else {
+			if !errors.Is(err, ErrObjcSectionNotFound) {
+				t.Fatalf(err.Error())
+			}
+		}
+
+		if classes, err := got.GetObjCClasses(); err == nil {
+			for _, class := range classes {
+				fmt.Println(class.Verbose())
+			}
+		} else {
+			if !errors.Is(err, ErrObjcSectionNotFound) {
+				t.Fatalf(err.Error())
+			}
+		}
+
+		if nlclasses, err := got.GetObjCNonLazyClasses(); err == nil {
+			for _, class := range nlclasses {
+				fmt.Println(class.String())
+			}
+		} else {
+			t.Fatalf(err.Error())
+		}
+
+		if cats, err := got.GetObjCCategories(); err == nil {
+			for _, cat := range cats {
+				fmt.Println(cat.String())
+			}
+		} else {
+			t.Fatalf(err.Error())
+		}
+
+		if nlcats, err := got.GetObjCNonLazyCategories(); err == nil {
+			for _, cat := range nlcats {
+				fmt.Println(cat.String())
+			}
+		} else {
+			t.Fatalf(err.Error())
+		}
+
+		if selRefs, err := got.GetObjCProtoReferences(); err == nil {
+			fmt.Println("@proto refs")
+			for off, prot := range selRefs {
+				fmt.Printf("%#x -> %#x: %s\n", off, prot.Ptr, prot.Name)
+			}
+		} else {
+			t.Fatalf(err.Error())
+		}
+		if selRefs, err := got.GetObjCClassReferences(); err == nil {
+			fmt.Println("@class refs")
+			for off, sel := range selRefs {
+				fmt.Printf("%#x -> %#x: %s\n", off, sel.ClassPtr, sel.Name)
+			}
+		} else {
+			t.Fatalf(err.Error())
+		}
+		if selRefs, err := got.GetObjCSuperReferences(); err == nil {
+			fmt.Println("@super refs")
+			for off, sel := range selRefs {
+				fmt.Printf("%#x -> %#x: %s\n", off, sel.ClassPtr, sel.SuperClass)
+			}
+		} else {
+			t.Fatalf(err.Error())
+		}
+		if selRefs, err := got.GetObjCSelectorReferences(); err == nil {
+			fmt.Println("@selectors refs")
+			for off, sel := range selRefs {
+				fmt.Printf("%#x -> %#x: %s\n", off, sel.VMAddr, sel.Name)
+			}
+		} else {
+			t.Fatalf(err.Error())
+		}
+		if methods, err := got.GetObjCMethodNames(); err == nil {
+			fmt.Printf("\n@methods\n")
+			for vmaddr, method := range methods {
+				fmt.Printf("%#x: %s\n", vmaddr, method)
+			}
+		} else {
+			t.Fatalf(err.Error())
+		}
+	}
+}
+
+func TestSourceLine(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec-debug.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		addr uint64
+		line int
+	}{
+		{0x100000f6a, 3},
+		{0x100000f70, 4},
+		{0x100000f7a, 5},
+		{0x100000f80, 6},
+	}
+	for _, tt := range tests {
+		file, line, fn, err := f.SourceLine(tt.addr)
+		if err != nil {
+			t.Fatalf("SourceLine(%#x) error = %v", tt.addr, err)
+		}
+		if !strings.HasSuffix(file, "hello.c") || line != tt.line || fn != "main" {
+			t.Errorf("SourceLine(%#x) = %s:%d %s, want hello.c:%d main", tt.addr, file, line, fn, tt.line)
+		}
+	}
+	for _, addr := range []uint64{0x100000f69, 0x100000f81} {
+		if _, _, _, err := f.SourceLine(addr); !errors.Is(err, ErrNoSourceLine) {
+			t.Errorf("SourceLine(%#x) error = %v, want ErrNoSourceLine", addr, err)
+		}
+	}
+}
+
+func TestSourceFrames(t *testing.T) {
+	abbrev := []byte{
+		1, 0x11, 1, 0x03, 0x08, 0x10, 0x17, 0, 0, // DW_TAG_compile_unit: name, stmt_list
+		2, 0x2e, 1, 0x03, 0x08, 0x11, 0x01, 0x12, 0x06, 0, 0, // DW_TAG_subprogram: name, low_pc, high_pc
+		3, 0x2e, 0, 0x03, 0x08, 0x20, 0x0b, 0, 0, // abstract DW_TAG_subprogram: name, inline
+		4, 0x1d, 1, 0x31, 0x13, 0x11, 0x01, 0x12, 0x06, 0x58, 0x0b, 0x59, 0x0b, 0, 0, // DW_TAG_inlined_subroutine
+		5, 0x1d, 0, 0x31, 0x13, 0x11, 0x01, 0x12, 0x06, 0x58, 0x0b, 0x59, 0x0b, 0, 0,
+		0,
+	}
+	var info bytes.Buffer
+	u32 := func(v uint32) { info.Write(binary.LittleEndian.AppendUint32(nil, v)) }
+	u64 := func(v uint64) { info.Write(binary.LittleEndian.AppendUint64(nil, v)) }
+	info.Write([]byte{0, 0, 0, 0, 4, 0, 0, 0, 0, 0, 8}) // DWARF4 CU header
+	info.Write([]byte{1, 'h', 'e', 'l', 'l', 'o', '.', 'c', 0})
+	u32(0)
+	helper := info.Len()
+	info.Write([]byte{3, 'h', 'e', 'l', 'p', 'e', 'r', 0, 3})
+	leaf := info.Len()
+	info.Write([]byte{3, 'l', 'e', 'a', 'f', 0, 3})
+	info.Write([]byte{2, 'm', 'a', 'i', 'n', 0})
+	u64(0x100001000)
+	u32(0x20)
+	info.WriteByte(4) // helper inlined into main at hello.c:31
+	u32(uint32(helper))
+	u64(0x100001008)
+	u32(0x10)
+	info.Write([]byte{1, 31})
+	info.WriteByte(5) // leaf inlined into helper at hello.c:13
+	u32(uint32(leaf))
+	u64(0x100001010)
+	u32(0x8)
+	info.Write([]byte{1, 13})
+	info.Write([]byte{0, 0, 0}) // end of helper, main and CU children
+	dinfo := info.Bytes()
+	binary.LittleEndian.PutUint32(dinfo, uint32(len(dinfo)-4))
+
+	line := []byte{
+		0, 0, 0, 0, 2, 0, 0, 0, 0, 0, // unit length, version 2, header length
+		1, 1, 0xfb, 14, 13, 0, 1, 1, 1, 1, 0, 0, 0, 1, 0, 0, 1, // min_inst_length .. standard_opcode_lengths
+		0,                                             // include_directories
+		'h', 'e', 'l', 'l', 'o', '.', 'c', 0, 0, 0, 0, // file_names
+		0,
+	}
+	binary.LittleEndian.PutUint32(line[6:], uint32(len(line)-10))
+	line = append(line, 0, 9, 2)
+	line = binary.LittleEndian.AppendUint64(line, 0x100001000)
+	line = append(line,
+		3, 29, 1, // line 30
+		2, 8, 3, 0x6e, 1, // 0x100001008 line 12
+		2, 8, 3, 10, 1, // 0x100001010 line 22
+		2, 8, 3, 10, 1, // 0x100001018 line 32
+		2, 8, 0, 1, 1, // end_sequence at 0x100001020
+	)
+	binary.LittleEndian.PutUint32(line, uint32(len(line)-4))
+
+	b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.AddSection("__TEXT", "__text", []byte{0xc3}, types.PURE_INSTRUCTIONS|types.SOME_INSTRUCTIONS)
+	b.SetEntryPoint("__TEXT", "__text", 0)
+	b.AddSection("__DWARF", "__debug_abbrev", abbrev, types.DEBUG)
+	b.AddSection("__DWARF", "__debug_info", dinfo, types.DEBUG)
+	b.AddSection("__DWARF", "__debug_line", line, types.DEBUG)
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		addr uint64
+		want []types.SourceFrame
+	}{
+		{0x100001000, []types.SourceFrame{{Function: "main", File: "hello.c", Line: 30}}},
+		{0x100001008, []types.SourceFrame{
+			{Function: "helper", File: "hello.c", Line: 12, Inlined: true},
+			{Function: "main", File: "hello.c", Line: 31},
+		}},
+		{0x100001014, []types.SourceFrame{
+			{Function: "leaf", File: "hello.c", Line: 22, Inlined: true},
+			{Function: "helper", File: "hello.c", Line: 13, Inlined: true},
+			{Function: "main", File: "hello.c", Line: 31},
+		}},
+		{0x100001018, []types.SourceFrame{{Function: "main", File: "hello.c", Line: 32}}},
+	}
+	for _, tt := range tests {
+		got, err := f.SourceFrames(tt.addr)
+		if err != nil {
+			t.Fatalf("SourceFrames(%#x) error = %v", tt.addr, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SourceFrames(%#x) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+	if _, _, fn, err := f.SourceLine(0x100001014); err != nil || fn != "leaf" {
+		t.Errorf("SourceLine(0x100001014) function = %q, %v, want leaf", fn, err)
+	}
+}
+
+func TestWriteBreakpadSym(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec-debug.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.WriteBreakpadSym(&buf, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	want := `MODULE mac x86_64 220EFAD905598307F95E9F873725396F0 hello
+INFO CODE_ID 220EFAD905598307F95E9F873725396F
+FILE 0 /home/rsc/go/src/pkg/debug/macho/testdata/hello.c
+FUNC f6a 17 0 main
+f6a 4 3 0
+f6e c 4 0
+f7a 5 5 0
+f7f 2 6 0
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteBreakpadSym() =\n%s\nwant:\n%s", got, want)
+	}
+
+	f, err = openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := f.WriteBreakpadSym(&buf, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.Contains(got, "\nFUNC f60 2a 0 main\n") || !strings.Contains(got, "\nPUBLIC 0 0 _mh_execute_header\n") {
+		t.Errorf("WriteBreakpadSym() without DWARF =\n%s", got)
+	}
+}
+
+// buildGoBinary cross-compiles a stripped darwin/amd64 Go program (skipping the test if no go toolchain is available)
+func buildGoBinary(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping go build in short mode")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not found")
+	}
+	dir := t.TempDir()
+	src := "package main\n\nimport \"fmt\"\n\n//go:noinline\nfunc hello() { fmt.Println(\"hello\") }\n\nfunc main() { hello() }\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/hello\n\ngo 1.19\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(gobin, "build", "-ldflags=-s -w", "-o", "hello", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOOS=darwin", "GOARCH=amd64", "CGO_ENABLED=0", "GOFLAGS=", "GOTOOLCHAIN=local")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, out)
+	}
+	return filepath.Join(dir, "hello")
+}
+
+func TestGoSymbols(t *testing.T) {
+	f, err := Open(buildGoBinary(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.FindSymbolAddress("main.hello"); err == nil {
+		t.Fatal("main.hello should not be found in a stripped binary before calling GoSymbols")
+	}
+	syms, err := f.GoSymbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hello *Symbol
+	for i, sym := range syms {
+		if sym.Name == "main.hello" {
+			hello = &syms[i]
+		}
+	}
+	if hello == nil {
+		t.Fatalf("GoSymbols() did not recover main.hello (%d symbols)", len(syms))
+	}
+	if sec := f.Section("__TEXT", "__text"); hello.Value < sec.Addr || hello.Value >= sec.Addr+sec.Size {
+		t.Errorf("main.hello address %#x not in __TEXT.__text", hello.Value)
+	}
+	if addr, err := f.FindSymbolAddress("main.hello"); err != nil || addr != hello.Value {
+		t.Errorf("FindSymbolAddress(main.hello) = %#x, %v, want %#x", addr, err, hello.Value)
+	}
+	if fn, err := f.FunctionForAddress(hello.Value + 1); err != nil || fn.Name != "main.hello" || fn.StartAddr != hello.Value {
+		t.Errorf("FunctionForAddress(%#x) = %v, %v, want main.hello", hello.Value+1, fn, err)
+	}
+	gcc, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gcc.GoSymbols(); !errors.Is(err, ErrGoPclntabNotFound) {
+		t.Errorf("GoSymbols() on a C binary error = %v, want ErrGoPclntabNotFound", err)
+	}
+}
+
+func TestGoBuildInfo(t *testing.T) {
+	path := buildGoBinary(t)
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	bi, err := f.GoBuildInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := buildinfo.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bi.String() != want.String() {
+		t.Errorf("GoBuildInfo() =\n%s\nwant:\n%s", bi, want)
+	}
+	if bi.Path != "example.com/hello" || !strings.HasPrefix(bi.GoVersion, "go") {
+		t.Errorf("GoBuildInfo() path = %s, version = %s", bi.Path, bi.GoVersion)
+	}
+
+	gcc, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gcc.GoBuildInfo(); !errors.Is(err, ErrGoBuildInfoNotFound) {
+		t.Errorf("GoBuildInfo() on a C binary error = %v, want ErrGoBuildInfoNotFound", err)
+	}
+}
+
+func TestGoBuildInfoPointers(t *testing.T) {
+	sentinel := strings.Repeat("\xff", 16)
+	mod := sentinel + "path\texample.com/old\n" + sentinel
+	build := func(modLen uint64) []byte {
+		b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+		b.AddSection("__TEXT", "__text", []byte{0xc3}, types.PURE_INSTRUCTIONS)
+		b.SetEntryPoint("__TEXT", "__text", 0)
+		// pre-go1.18 build info: the header points at the Go string headers of the version and module info
+		data := make([]byte, 64)
+		data = append(data, "go1.16"+mod...)
+		sec := b.AddSection("__DATA", "__go_buildinfo", data, 0)
+		if _, err := b.Build(); err != nil {
+			t.Fatal(err)
+		}
+		bo := binary.LittleEndian
+		copy(data, goBuildInfoMagic)
+		data[14] = 8
+		bo.PutUint64(data[16:], sec.Addr+32)
+		bo.PutUint64(data[24:], sec.Addr+48)
+		bo.PutUint64(data[32:], sec.Addr+64)
+		bo.PutUint64(data[40:], 6)
+		bo.PutUint64(data[48:], sec.Addr+70)
+		bo.PutUint64(data[56:], modLen)
+		dat, err := b.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dat
+	}
+
+	f, err := NewFile(bytes.NewReader(build(uint64(len(mod)))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bi, err := f.GoBuildInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bi.GoVersion != "go1.16" || bi.Path != "example.com/old" {
+		t.Errorf("GoBuildInfo() version = %s, path = %s", bi.GoVersion, bi.Path)
+	}
+
+	// an untrusted string length must not be allocated
+	for _, n := range []uint64{1 << 62, 1 << 40, uint64(len(mod)) + 0x10000} {
+		f, err := NewFile(bytes.NewReader(build(n)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.GoBuildInfo(); err == nil || !strings.Contains(err.Error(), "extends past segment") {
+			t.Errorf("GoBuildInfo() with a %#x byte module string error = %v", n, err)
+		}
+	}
+}
+
+func TestWriteTBD(t *testing.T) {
+	newDylib := func(cpu types.CPU, subcpu types.CPUSubtype, extra bool) *File {
+		b := NewBuilder(types.MH_DYLIB, cpu, subcpu)
+		b.InstallName = "/usr/lib/libfoo.dylib"
+		b.CurrentVersion.Set("1.2.3")
+		b.CompatVersion.Set("1.0")
+		b.Platform, _ = types.GetPlatformByName("macos")
+		b.AddSection("__TEXT", "__text", make([]byte, 16), types.PURE_INSTRUCTIONS|types.SOME_INSTRUCTIONS)
+		b.AddSection("__DATA", "__data", make([]byte, 32), 0)
+		b.AddSymbol("_foo", "__TEXT", "__text", 0, true)
+		b.AddSymbol("_helper", "__TEXT", "__text", 4, false)
+		b.AddSymbol("_OBJC_CLASS_$_Foo", "__DATA", "__data", 0, true)
+		b.AddSymbol("_OBJC_METACLASS_$_Foo", "__DATA", "__data", 8, true)
+		b.AddSymbol("_OBJC_IVAR_$_Foo._bar", "__DATA", "__data", 16, true)
+		if extra {
+			b.AddSymbol("_bar", "__TEXT", "__text", 8, true)
+		}
+		var buf bytes.Buffer
+		if _, err := b.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		f, err := NewFile(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTBD(&buf, newDylib(types.CPUAmd64, types.CPUSubtypeX8664All, false), newDylib(types.CPUArm64, types.CPUSubtypeArm64E, true)); err != nil {
+		t.Fatal(err)
+	}
+	want := `--- !tapi-tbd
+tbd-version:     4
+targets:         [ x86_64-macos, arm64e-macos ]
+flags:           [ not_app_extension_safe ]
+install-name:    '/usr/lib/libfoo.dylib'
+current-version: 1.2.3
+exports:
+  - targets:         [ x86_64-macos, arm64e-macos ]
+    symbols:         [ _foo ]
+    objc-classes:    [ Foo ]
+    objc-ivars:      [ Foo._bar ]
+  - targets:         [ arm64e-macos ]
+    symbols:         [ _bar ]
+...
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteTBD() =\n%s\nwant:\n%s", got, want)
+	}
+
+	exe, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exe.WriteTBD(io.Discard); err == nil {
+		t.Error("WriteTBD() of an executable should fail")
+	}
+}
+
+func TestFileMarshalJSON(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Loads = append(f.Loads, LoadCmdBytes{LoadCmd: types.LoadCmd(0x7f), LoadBytes: LoadBytes{0x7f, 0, 0, 0, 12, 0, 0, 0, 0xde, 0xad, 0xbe, 0xef}})
+	dat, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Header struct {
+			Type string `json:"type"`
+		} `json:"header"`
+		Loads []struct {
+			LoadCmd  string `json:"load_cmd"`
+			Data     string `json:"data"`
+			Sections []struct {
+				Name   string            `json:"name"`
+				Relocs []json.RawMessage `json:"relocs"`
+			} `json:"sections"`
+		} `json:"loads"`
+		Symbols []struct {
+			Name string `json:"name"`
+		} `json:"symbols"`
+	}
+	if err := json.Unmarshal(dat, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Header.Type != "OBJECT" || len(out.Loads) != len(f.Loads) || len(out.Symbols) != 2 || out.Symbols[1].Name != "_printf" {
+		t.Fatalf("json.Marshal(File) = %s", dat)
+	}
+	if text := out.Loads[0].Sections[0]; text.Name != "__text" || len(text.Relocs) != 2 {
+		t.Errorf("__text section JSON = %+v, want 2 relocs", text)
+	}
+	if unknown := out.Loads[len(out.Loads)-1]; unknown.Data != "7f0000000c000000deadbeef" {
+		t.Errorf("unknown load command data = %q, want hex", unknown.Data)
+	}
+}
+
+func TestString(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := f.String()
+	for _, want := range []string{
+		"Mach header\n" +
+			"      magic  cputype cpusubtype  caps    filetype ncmds sizeofcmds      flags\n" +
+			" 0xfeedfacf 16777223          3  0x80           2    16       1224 0x00200085\n",
+		"Load command 1\n" +
+			"      cmd LC_SEGMENT_64\n" +
+			"  cmdsize 472\n" +
+			"  segname __TEXT\n" +
+			"   vmaddr 0x0000000100000000\n" +
+			"   vmsize 0x0000000000001000\n" +
+			"  fileoff 0\n" +
+			" filesize 4096\n" +
+			"  maxprot 0x00000007\n" +
+			" initprot 0x00000005\n" +
+			"   nsects 5\n" +
+			"    flags 0x0\n" +
+			"Section\n" +
+			"  sectname __text\n" +
+			"   segname __TEXT\n" +
+			"      addr 0x0000000100000f60\n" +
+			"      size 0x000000000000002a\n" +
+			"    offset 3936\n" +
+			"     align 2^4 (16)\n" +
+			"    reloff 0\n" +
+			"    nreloc 0\n" +
+			"     flags 0x80000400\n" +
+			" reserved1 0\n" +
+			" reserved2 0\n",
+		"Load command 9\n" +
+			"      cmd LC_VERSION_MIN_MACOSX\n" +
+			"  cmdsize 16\n" +
+			"  version 10.12\n" +
+			"      sdk 10.12\n",
+		"Load command 13\n" +
+			"          cmd LC_RPATH\n" +
+			"      cmdsize 24\n" +
+			"         path /my/rpath (offset 12)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("String() is missing:\n%s\ngot:\n%s", want, out)
+		}
+	}
+	if got, want := f.Loads[13].String(), "          cmd LC_RPATH\n"+
+		"      cmdsize 24\n"+
+		"         path /my/rpath (offset 12)"; got != want {
+		t.Errorf("Rpath.String() = %q, want %q", got, want)
+	}
+}
+
+func TestGetOffset(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		addr, off uint64
+	}{
+		{0x100000f60, 0xf60},  // __TEXT.__text
+		{0x100001010, 0x1010}, // __DATA.__la_symbol_ptr
+		{0x100002000, 0x2000}, // __LINKEDIT start
+		{0x1000020ef, 0x20ef}, // __LINKEDIT last byte
+	} {
+		off, err := f.GetOffset(tt.addr)
+		if err != nil || off != tt.off {
+			t.Errorf("GetOffset(%#x) = %#x, %v; want %#x", tt.addr, off, err, tt.off)
+		}
+		addr, err := f.GetVMAddress(tt.off)
+		if err != nil || addr != tt.addr {
+			t.Errorf("GetVMAddress(%#x) = %#x, %v; want %#x", tt.off, addr, err, tt.addr)
+		}
+	}
+	// __PAGEZERO and the end of __LINKEDIT are zerofill and 0x100003000 is past the last segment
+	for _, addr := range []uint64{0x1000, 0x1000020f0, 0x100003000} {
+		if off, err := f.GetOffset(addr); err == nil {
+			t.Errorf("GetOffset(%#x) = %#x; want error", addr, off)
+		}
+	}
+	if addr, err := f.GetVMAddress(0x3000); err == nil {
+		t.Errorf("GetVMAddress(0x3000) = %#x; want error", addr)
+	}
+}
+
+func TestFindForVMAddr(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		addr     uint64
+		seg, sec string
+	}{
+		{0x1000, "__PAGEZERO", ""},
+		{0x100000000, "__TEXT", ""}, // mach header
+		{0x100000f60, "__TEXT", "__text"},
+		{0x100000f89, "__TEXT", "__text"},
+		{0x100000f8a, "__TEXT", "__stubs"},
+		{0x100000fa9, "__TEXT", "__stub_helper"},
+		{0x100001010, "__DATA", "__la_symbol_ptr"},
+		{0x100001018, "__DATA", ""},
+		{0x100002000, "__LINKEDIT", ""},
+		{0x100003000, "", ""},
+	} {
+		var seg, sec string
+		if s := f.FindSegmentForVMAddr(tt.addr); s != nil {
+			seg = s.Name
+		}
+		if s := f.FindSectionForVMAddr(tt.addr); s != nil {
+			sec = s.Name
+		}
+		if seg != tt.seg || sec != tt.sec {
+			t.Errorf("FindSegmentForVMAddr/FindSectionForVMAddr(%#x) = %q, %q; want %q, %q", tt.addr, seg, sec, tt.seg, tt.sec)
+		}
+	}
+
+	// the index is rebuilt after adding segments
+	if _, err := f.AddSection("__MORE", "__more", []byte("data"), 0); err != nil {
+		t.Fatal(err)
+	}
+	more := f.Section("__MORE", "__more")
+	if sec := f.FindSectionForVMAddr(more.Addr); sec != more {
+		t.Errorf("FindSectionForVMAddr(%#x) = %v; want __MORE.__more", more.Addr, sec)
+	}
+	if seg := f.FindSegmentForVMAddr(f.Segment("__LINKEDIT").Addr); seg == nil || seg.Name != "__LINKEDIT" {
+		t.Errorf("FindSegmentForVMAddr(__LINKEDIT) = %v; want __LINKEDIT", seg)
+	}
+}
+
+func TestGetCString(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := f.GetCString(0x100000faa); err != nil || s != "hello, world\n" {
+		t.Errorf("GetCString(0x100000faa) = %q, %v; want %q", s, err, "hello, world\n")
+	}
+	if s, err := f.GetCStringAtOffset(0xfab); err != nil || s != "ello, world\n" {
+		t.Errorf("GetCStringAtOffset(0xfab) = %q, %v; want %q", s, err, "ello, world\n")
+	}
+	if s, err := f.GetCString(0x1000); err == nil {
+		t.Errorf("GetCString(0x1000) = %q; want error for __PAGEZERO", s)
+	}
+
+	if s, err := types.ReadCString(strings.NewReader(strings.Repeat("A", types.MaxCStringLen) + "\x00")); err != nil || len(s) != types.MaxCStringLen {
+		t.Errorf("readCString(%d bytes) = %d bytes, %v", types.MaxCStringLen, len(s), err)
+	}
+	if _, err := types.ReadCString(strings.NewReader(strings.Repeat("A", types.MaxCStringLen+1) + "\x00")); err == nil {
+		t.Errorf("readCString(%d bytes) should fail", types.MaxCStringLen+1)
+	}
+}
+
+// chainedImport is an import of a test LC_DYLD_CHAINED_FIXUPS blob
+type chainedImport struct {
+	name    string
+	ordinal uint8
+}
+
+// addChainedFixups adds a LC_DYLD_CHAINED_FIXUPS load command (in the header padding) to a Builder MachO
+// with a single fixup chain in segment segIdx (of nsegs) starting at pageStart in its first page
+func addChainedFixups(dat []byte, nsegs, segIdx int, format fixupchains.DCPtrKind, pageStart uint16, imports []chainedImport) []byte {
+	bo := binary.LittleEndian
+	var blob bytes.Buffer
+	// dyld_chained_starts_in_image at 0x20 (after the header) and the segment's starts at 0x18 in it
+	startsOffset := uint32(0x20)
+	segInfoOffset := uint32(0x18)
+	importsOffset := startsOffset + segInfoOffset + 24
+	symbolsOffset := importsOffset + 4*uint32(len(imports))
+	binary.Write(&blob, bo, fixupchains.DyldChainedFixupsHeader{
+		StartsOffset:  startsOffset,
+		ImportsOffset: importsOffset,
+		SymbolsOffset: symbolsOffset,
+		ImportsCount:  uint32(len(imports)),
+		ImportsFormat: fixupchains.DC_IMPORT,
+	})
+	blob.Write(make([]byte, int(startsOffset)-blob.Len()))
+	offsets := make([]uint32, nsegs)
+	offsets[segIdx] = segInfoOffset
+	binary.Write(&blob, bo, uint32(nsegs))
+	binary.Write(&blob, bo, offsets)
+	blob.Write(make([]byte, int(startsOffset+segInfoOffset)-blob.Len()))
+	binary.Write(&blob, bo, fixupchains.DyldChainedStartsInSegment{
+		Size:          24,
+		PageSize:      0x1000,
+		PointerFormat: format,
+		PageCount:     1,
+	})
+	binary.Write(&blob, bo, pageStart)
+	var names bytes.Buffer
+	for _, imp := range imports {
+		binary.Write(&blob, bo, uint32(imp.ordinal)|uint32(names.Len())<<9)
+		names.WriteString(imp.name + "\x00")
+	}
+	blob.Write(names.Bytes())
+
+	return addLinkEditData(dat, types.LC_DYLD_CHAINED_FIXUPS, blob.Bytes())
+}
+
+// addLinkEditData appends blob to a little endian 64-bit MachO and adds a linkedit_data_command of type cmd
+// for it (in the header padding)
+func addLinkEditData(dat []byte, cmd types.LoadCmd, blob []byte) []byte {
+	bo := binary.LittleEndian
+	out := append([]byte{}, dat...)
+	for len(out)%8 != 0 {
+		out = append(out, 0)
+	}
+	dataoff := uint32(len(out))
+	out = append(out, blob...)
+
+	lc := make([]byte, 16)
+	bo.PutUint32(lc[0:], uint32(cmd))
+	bo.PutUint32(lc[4:], 16)
+	bo.PutUint32(lc[8:], dataoff)
+	bo.PutUint32(lc[12:], uint32(len(blob)))
+
+	return addLoadCmd(out, lc)
+}
+
+// addLoadCmd adds the raw load command lc to a little endian 64-bit MachO (in the header padding)
+func addLoadCmd(dat []byte, lc []byte) []byte {
+	bo := binary.LittleEndian
+	ncmds, sizeofcmds := bo.Uint32(dat[16:]), bo.Uint32(dat[20:])
+	copy(dat[types.FileHeaderSize64+sizeofcmds:], lc)
+	bo.PutUint32(dat[16:], ncmds+1)
+	bo.PutUint32(dat[20:], sizeofcmds+uint32(len(lc)))
+	return dat
+}
+
+// loadCmd returns the load command cmd with the little endian fields and trailing data (padded to 8 bytes)
+func loadCmd(cmd types.LoadCmd, fields []uint32, data []byte) []byte {
+	lc := make([]byte, 8+4*len(fields), 8+4*len(fields)+len(data)+8)
+	for i, v := range fields {
+		binary.LittleEndian.PutUint32(lc[8+4*i:], v)
+	}
+	lc = append(lc, data...)
+	for len(lc)%8 != 0 {
+		lc = append(lc, 0)
+	}
+	binary.LittleEndian.PutUint32(lc[0:], uint32(cmd))
+	binary.LittleEndian.PutUint32(lc[4:], uint32(len(lc)))
+	return lc
+}
+
+// emptyMachO builds an x86_64 executable with room for extra load commands
+func emptyMachO(t *testing.T) []byte {
+	t.Helper()
+	b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.HeaderPad = 0x200
+	b.AddSection("__TEXT", "__text", []byte{0xc3}, types.PURE_INSTRUCTIONS)
+	b.SetEntryPoint("__TEXT", "__text", 0)
+	dat, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dat
+}
+
+// chainedFixupsMachO builds an x86_64 MachO whose __DATA.__data slots are a DYLD_CHAINED_PTR_64 fixup chain
+func chainedFixupsMachO(t *testing.T) (f *File, text uint64, data *BuilderSection, slots []uint64) {
+	t.Helper()
+	b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.HeaderPad = 0x100
+	b.AddSection("__TEXT", "__text", []byte{0x55, 0x48, 0x89, 0xe5, 0x5d, 0xc3, 0xc3, 0xc3}, types.PURE_INSTRUCTIONS)
+	data = b.AddSection("__DATA", "__data", make([]byte, 5*8), types.Regular)
+	data.Align = 3
+	b.AddSymbol("_main", "__TEXT", "__text", 0, true)
+	b.AddDylib("/usr/lib/libSystem.B.dylib")
+	b.SetEntryPoint("__TEXT", "__text", 0)
+	dat, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text = b.Segment("__TEXT").Sections[0].Addr
+
+	// DYLD_CHAINED_PTR_64 chain (next is in 4-byte strides) through the first 4 slots; the last slot isn't a fixup
+	const next = 2 << 51
+	const bind = 1 << 63
+	slots = []uint64{
+		(text + 4) | next,       // rebase
+		bind | 0 | next,         // bind to _printf
+		bind | 1 | 4<<24 | next, // bind to _main+4 (in this image)
+		(text + 6) | 0x12<<36,   // rebase with high8, end of chain
+		bind | 1,                // raw data
+	}
+	for i, slot := range slots {
+		binary.LittleEndian.PutUint64(dat[data.Offset+uint32(i*8):], slot)
+	}
+	dataSeg := b.Segment("__DATA")
+	dat = addChainedFixups(dat, 4, 2, fixupchains.DYLD_CHAINED_PTR_64, uint16(data.Addr-dataSeg.Addr), []chainedImport{
+		{"_printf", 1},
+		{"_main", 0}, // BIND_SPECIAL_DYLIB_SELF
+	})
+
+	f, err = NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, text, data, slots
+}
+
+func TestGetPointerAtAddress(t *testing.T) {
+	f, text, data, slots := chainedFixupsMachO(t)
+	for i, want := range []uint64{text + 4, slots[1], text + 4, 0x12<<56 | (text + 6), slots[4]} {
+		addr := data.Addr + uint64(i*8)
+		got, err := f.GetPointerAtAddress(addr)
+		if err != nil || got != want {
+			t.Errorf("GetPointerAtAddress(%#x) = %#x, %v; want %#x", addr, got, err, want)
+		}
+	}
+	if name, err := f.GetBindName(slots[1]); err != nil || name != "_printf" {
+		t.Errorf("GetBindName(%#x) = %q, %v; want _printf", slots[1], name, err)
+	}
+}
+
+func TestPtrAuth(t *testing.T) {
+	// auth-rebase: auth=1, bind=0, key=DA(2), addrDiv=1, diversity=0x1234, target=0x4000
+	ptr := uint64(1)<<63 | 2<<49 | 1<<48 | 0x1234<<32 | 0x4000
+	auth, ok := fixupchains.DcpArm64ePtrAuth(ptr)
+	if !ok || auth.KeyName() != "DA" || !auth.AddrDiv || auth.Diversity != 0x1234 {
+		t.Errorf("DcpArm64ePtrAuth(%#x) = %v, %t", ptr, auth, ok)
+	}
+	if rebase := (fixupchains.DyldChainedPtrArm64eAuthRebase{Pointer: ptr}); rebase.Key() != auth.Key || rebase.Target() != 0x4000 {
+		t.Errorf("DyldChainedPtrArm64eAuthRebase(%#x) = %s", ptr, rebase.String())
+	}
+	if _, ok := fixupchains.DcpArm64ePtrAuth(0x4000); ok {
+		t.Errorf("DcpArm64ePtrAuth(0x4000) should not be authenticated")
+	}
+
+	for _, tt := range []struct {
+		ptr, vaBits, want uint64
+	}{
+		{0x001f_0001_0000_3f40, 47, 0x0000_0001_0000_3f40}, // userland
+		{0x3a2f_0001_0000_3f40, 39, 0x0000_0001_0000_3f40}, // userland (with TBI tag)
+		{0xd98a_fff0_0700_4000, 47, 0xffff_fff0_0700_4000}, // kernel
+	} {
+		if got := types.StripPACBits(tt.ptr, uint(tt.vaBits)); got != tt.want {
+			t.Errorf("StripPACBits(%#x, %d) = %#x; want %#x", tt.ptr, tt.vaBits, got, tt.want)
+		}
+	}
+	if got := types.StripPAC(0x001f_0001_0000_3f40); got != 0x1_0000_3f40 {
+		t.Errorf("StripPAC() = %#x; want 0x100003f40", got)
+	}
+
+	for _, tt := range []struct {
+		cpu     types.CPU
+		sub     types.CPUSubtype
+		version uint8
+		kernel  bool
+		ok      bool
+	}{
+		{types.CPUArm64, types.CPUSubtypeArm64E | 0x80000000, 0, false, true},
+		{types.CPUArm64, types.CPUSubtypeArm64E | 0xc2000000, 2, true, true},
+		{types.CPUArm64, types.CPUSubtypeArm64E, 0, false, false},
+		{types.CPUArm64, types.CPUSubtypeArm64All, 0, false, false},
+		{types.CPUAmd64, types.CPUSubtypeArm64E | 0x80000000, 0, false, false},
+	} {
+		version, kernel, ok := tt.sub.PtrAuthABIVersion(tt.cpu)
+		if version != tt.version || kernel != tt.kernel || ok != tt.ok {
+			t.Errorf("%#x.PtrAuthABIVersion(%s) = %d, %t, %t; want %d, %t, %t", uint32(tt.sub), tt.cpu, version, kernel, ok, tt.version, tt.kernel, tt.ok)
+		}
+	}
+}
+
+func TestDecodePointer(t *testing.T) {
+	tests := []struct {
+		format fixupchains.DCPtrKind
+		ptr    uint64
+		want   fixupchains.Pointer
+	}{
+		// rebase: next=1, high8=0xab, target=0x100003f40
+		{fixupchains.DYLD_CHAINED_PTR_64, 1<<51 | 0xab<<36 | 0x1_0000_3f40,
+			fixupchains.Pointer{Target: 0xab00_0001_0000_3f40, VMAddr: true, Next: 4}},
+		{fixupchains.DYLD_CHAINED_PTR_64_OFFSET, 0x3f40, fixupchains.Pointer{Target: 0x3f40}},
+		// bind: ordinal=3, addend=8
+		{fixupchains.DYLD_CHAINED_PTR_64_OFFSET, 1<<63 | 2<<51 | 8<<24 | 3,
+			fixupchains.Pointer{IsBind: true, Ordinal: 3, Addend: 8, Next: 8}},
+		{fixupchains.DYLD_CHAINED_PTR_ARM64E, 1<<51 | 0x1_0000_3f40,
+			fixupchains.Pointer{Target: 0x1_0000_3f40, VMAddr: true, Next: 8}},
+		{fixupchains.DYLD_CHAINED_PTR_ARM64E_USERLAND, 0x3f40, fixupchains.Pointer{Target: 0x3f40}},
+		{fixupchains.DYLD_CHAINED_PTR_ARM64E_KERNEL, 3<<51 | 0x3f40, fixupchains.Pointer{Target: 0x3f40, Next: 12}},
+		{fixupchains.DYLD_CHAINED_PTR_ARM64E_FIRMWARE, 0x1_0000_3f40, fixupchains.Pointer{Target: 0x1_0000_3f40, VMAddr: true}},
+		// auth-rebase: key=DA, addrDiv=1, diversity=0x1234
+		{fixupchains.DYLD_CHAINED_PTR_ARM64E, 1<<63 | 2<<49 | 1<<48 | 0x1234<<32 | 0x3f40,
+			fixupchains.Pointer{Target: 0x3f40, Auth: &fixupchains.PtrAuth{Key: 2, Diversity: 0x1234, AddrDiv: true}}},
+		// bind: ordinal=2, addend=-4
+		{fixupchains.DYLD_CHAINED_PTR_ARM64E, 1<<62 | 0x7fffc<<32 | 2,
+			fixupchains.Pointer{IsBind: true, Ordinal: 2, Addend: -4}},
+		{fixupchains.DYLD_CHAINED_PTR_ARM64E_USERLAND24, 1<<62 | 0x12345,
+			fixupchains.Pointer{IsBind: true, Ordinal: 0x12345}},
+		{fixupchains.DYLD_CHAINED_PTR_ARM64E_USERLAND24, 1<<63 | 1<<62 | 0x12345,
+			fixupchains.Pointer{IsBind: true, Ordinal: 0x12345, Auth: &fixupchains.PtrAuth{}}},
+		{fixupchains.DYLD_CHAINED_PTR_ARM64E_SHARED_CACHE, 1<<52 | 0x2_0000_0000,
+			fixupchains.Pointer{Target: 0x2_0000_0000, Next: 8}},
+		{fixupchains.DYLD_CHAINED_PTR_ARM64E_SHARED_CACHE, 1<<63 | 1<<51 | 1<<50 | 0x1234<<34 | 0x3f40,
+			fixupchains.Pointer{Target: 0x3f40, Auth: &fixupchains.PtrAuth{Key: 2, Diversity: 0x1234, AddrDiv: true}}},
+		// kernel cache: cacheLevel=1, auth key=IB
+		{fixupchains.DYLD_CHAINED_PTR_64_KERNEL_CACHE, 1<<63 | 2<<51 | 1<<49 | 1<<30 | 0x3f40,
+			fixupchains.Pointer{Target: 0x3f40, CacheLevel: 1, Next: 8, Auth: &fixupchains.PtrAuth{Key: 1}}},
+		{fixupchains.DYLD_CHAINED_PTR_X86_64_KERNEL_CACHE, 5<<51 | 0x3f40, fixupchains.Pointer{Target: 0x3f40, Next: 5}},
+		{fixupchains.DYLD_CHAINED_PTR_32, 1<<26 | 0x3f40, fixupchains.Pointer{Target: 0x3f40, VMAddr: true, Next: 4}},
+		{fixupchains.DYLD_CHAINED_PTR_32, 1<<31 | 2<<20 | 7, fixupchains.Pointer{IsBind: true, Ordinal: 7, Addend: 2}},
+		{fixupchains.DYLD_CHAINED_PTR_32_CACHE, 3<<30 | 0x3f40, fixupchains.Pointer{Target: 0x3f40, Next: 12}},
+		{fixupchains.DYLD_CHAINED_PTR_32_FIRMWARE, 0x3f<<26 | 0x3f40, fixupchains.Pointer{Target: 0x3f40, VMAddr: true, Next: 0xfc}},
+	}
+	for _, tt := range tests {
+		got, err := fixupchains.DecodePointer(tt.format, tt.ptr)
+		if err != nil {
+			t.Fatalf("DecodePointer(%d, %#x) = %v", tt.format, tt.ptr, err)
+		}
+		tt.want.Format = tt.format
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DecodePointer(%d, %#x) = %s; want %s", tt.format, tt.ptr, got, tt.want)
+		}
+	}
+	if _, err := fixupchains.DecodePointer(0xff, 0); err == nil {
+		t.Errorf("DecodePointer() of an unknown format should fail")
+	}
+
+	dcf := fixupchains.DyldChainedFixups{PointerFormat: fixupchains.DYLD_CHAINED_PTR_64_OFFSET}
+	if target, ok := dcf.IsRebase(0x3f40, 0x1_0000_0000); !ok || target != 0x3f40 {
+		t.Errorf("IsRebase() = %#x, %t; want 0x3f40", target, ok)
+	}
+	dcf.PointerFormat = fixupchains.DYLD_CHAINED_PTR_64
+	if target, ok := dcf.IsRebase(0x1_0000_3f40, 0x1_0000_0000); !ok || target != 0x3f40 {
+		t.Errorf("IsRebase() = %#x, %t; want 0x3f40", target, ok)
+	}
+}
+
+func TestRebasedImage(t *testing.T) {
+	f, text, data, slots := chainedFixupsMachO(t)
+	const slide = 0x4000
+	image, err := f.RebasedImage(slide, func(name, dylib string, addend int64) uint64 {
+		if name == "_printf" && dylib == "libSystem.B.dylib" {
+			return 0x7fff_0000_1000 + uint64(addend)
+		}
+		return 0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := f.GetBaseAddress()
+	if uint64(len(image)) != f.Segment("__LINKEDIT").Addr+f.Segment("__LINKEDIT").Memsz-base {
+		t.Errorf("RebasedImage() is %#x bytes", len(image))
+	}
+	if !bytes.Equal(image[text-base:text-base+6], []byte{0x55, 0x48, 0x89, 0xe5, 0x5d, 0xc3}) {
+		t.Errorf("RebasedImage() __text = % x", image[text-base:text-base+6])
+	}
+	for i, want := range []uint64{text + 4 + slide, 0x7fff_0000_1000, 0, 0x12<<56 | (text + 6 + slide), slots[4]} {
+		if got := binary.LittleEndian.Uint64(image[data.Addr-base+uint64(i*8):]); got != want {
+			t.Errorf("RebasedImage() slot %d = %#x; want %#x", i, got, want)
+		}
+	}
+	// binds to this image are resolved by default
+	if image, err = f.RebasedImage(slide); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []uint64{text + 4 + slide, 0, text + 4 + slide} {
+		if got := binary.LittleEndian.Uint64(image[data.Addr-base+uint64(i*8):]); got != want {
+			t.Errorf("RebasedImage() slot %d = %#x; want %#x", i, got, want)
+		}
+	}
+
+	f, err = openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	binds, err := f.GetBindInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebases, err := f.GetRebaseInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err = f.RebasedImage(slide)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base = f.GetBaseAddress()
+	for _, r := range rebases {
+		ptr, err := f.GetPointerAtAddress(r.Start + r.Offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := binary.LittleEndian.Uint64(image[r.Start+r.Offset-base:]); got != ptr+slide {
+			t.Errorf("RebasedImage() rebase %s = %#x; want %#x", r, got, ptr+slide)
+		}
+	}
+	for _, b := range binds {
+		if b.Kind == types.BIND_KIND {
+			if got := binary.LittleEndian.Uint64(image[b.Start+b.Offset-base:]); got != 0 {
+				t.Errorf("RebasedImage() bind %s = %#x; want 0", b, got)
+			}
+		}
+	}
+	if len(rebases) == 0 || len(binds) == 0 {
+		t.Errorf("expected rebases and binds: %d, %d", len(rebases), len(binds))
+	}
+}
+
+func TestKexts(t *testing.T) {
+	b := NewBuilder(types.MH_EXECUTE, types.CPUArm64, types.CPUSubtypeArm64All)
+	b.HeaderPad = 0x100
+	b.AddSection("__TEXT", "__text", []byte{0xc0, 0x03, 0x5f, 0xd6}, types.PURE_INSTRUCTIONS)
+	// an IOKit flavored plist (with ID/IDREF attributes and a hex integer)
+	b.AddSection("__PRELINK_INFO", "__info", []byte(`<dict><key>_PrelinkInfoDictionary</key><array>`+
+		`<dict><key>CFBundleIdentifier</key><string ID="1">com.apple.kernel</string>`+
+		`<key>CFBundleVersion</key><string ID="2">23.0.0</string>`+
+		`<key>_PrelinkExecutableLoadAddr</key><integer size="64" ID="3">0x0000000000000000</integer></dict>`+
+		`<dict><key>CFBundleIdentifier</key><string ID="4">com.apple.driver.Codeless</string>`+
+		`<key>CFBundleVersion</key><string IDREF="2"/><key>OSBundleRequired</key><true/></dict>`+
+		`</array></dict>`+"\x00"), types.Regular)
+	b.SetEntryPoint("__TEXT", "__text", 0)
+	dat, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kernel := b.Segment("__TEXT").Addr
+	dat = bytes.Replace(dat, []byte("0x0000000000000000"), []byte(fmt.Sprintf("0x%016x", kernel)), 1)
+
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kexts, err := f.Kexts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kexts) != 2 ||
+		kexts[0].ID != "com.apple.kernel" || kexts[0].Version != "23.0.0" || kexts[0].LoadAddress != kernel ||
+		kexts[1].ID != "com.apple.driver.Codeless" || kexts[1].Version != "23.0.0" || kexts[1].LoadAddress != 0 {
+		t.Fatalf("Kexts() = %v", kexts)
+	}
+	m, err := f.GetKext("com.apple.kernel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := m.Section("__TEXT", "__text"); text == nil || text.Addr != b.Segment("__TEXT").Sections[0].Addr {
+		t.Errorf("GetKext() __text = %v", text)
+	}
+	if _, err := f.GetKext("com.apple.driver.Codeless"); err == nil {
+		t.Errorf("GetKext() of a codeless kext should fail")
+	}
+
+	// MH_FILESET kernelcaches list their LC_FILESET_ENTRYs
+	fse := &FilesetEntry{EntryID: "com.apple.kernel"}
+	fse.LoadCmd = types.LC_FILESET_ENTRY
+	fse.Len = fse.LoadSize()
+	fse.Addr = kernel
+	fse.EntryIdOffset = uint32(binary.Size(fse.FilesetEntryCmd))
+	if err := f.AddLoad(fse); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = NewFile(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	kexts, err = f.Kexts()
+	if err != nil || len(kexts) != 1 || kexts[0].ID != "com.apple.kernel" || kexts[0].Version != "23.0.0" || kexts[0].LoadAddress != kernel {
+		t.Fatalf("Kexts() = %v, %v", kexts, err)
+	}
+	if _, err := f.GetKext("com.apple.kernel"); err != nil {
+		t.Errorf("GetKext() = %v", err)
+	}
+}
+
+func TestNewFileFromMemory(t *testing.T) {
+	b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.AddSection("__TEXT", "__text", []byte{0x55, 0x48, 0x89, 0xe5, 0x5d, 0xc3, 0xc3, 0xc3}, types.PURE_INSTRUCTIONS)
+	data := b.AddSection("__DATA", "__data", make([]byte, 8), types.Regular)
+	data.Align = 3
+	// the zerofill makes the __LINKEDIT vmaddr (in memory) differ from its file offset
+	bss := b.AddSection("__DATA", "__bss", nil, types.Zerofill)
+	bss.Size = 0x10000
+	b.AddSymbol("_main", "__TEXT", "__text", 0, true)
+	b.SetEntryPoint("__TEXT", "__text", 0)
+	dat, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := f.Segment("__TEXT")
+	linkedit := f.Segment("__LINKEDIT")
+	if linkedit.Addr-text.Addr == linkedit.Offset {
+		t.Fatalf("__LINKEDIT is at the same memory and file offset %#x", linkedit.Offset)
+	}
+
+	// map the image as dyld would (with a runtime pointer in __data)
+	mem := make([]byte, linkedit.Addr+linkedit.Memsz-text.Addr)
+	for _, seg := range f.Segments() {
+		if seg.Filesz > 0 {
+			copy(mem[seg.Addr-text.Addr:], dat[seg.Offset:seg.Offset+seg.Filesz])
+		}
+	}
+	const slide = 0x4000
+	binary.LittleEndian.PutUint64(mem[data.Addr-text.Addr:], slide+text.Addr+4)
+
+	m, err := NewFileFromMemory(bytes.NewReader(mem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Symtab == nil || len(m.Symtab.Syms) != 1 || m.Symtab.Syms[0] != f.Symtab.Syms[0] {
+		t.Errorf("NewFileFromMemory() symbols = %v", m.Symtab.Syms)
+	}
+	sec, err := m.Section("__TEXT", "__text").Data()
+	if err != nil || !bytes.Equal(sec, []byte{0x55, 0x48, 0x89, 0xe5, 0x5d, 0xc3, 0xc3, 0xc3}) {
+		t.Errorf("NewFileFromMemory() __text = % x, %v", sec, err)
+	}
+	if ptr, err := m.GetPointerAtAddress(data.Addr); err != nil || ptr != slide+text.Addr+4 {
+		t.Errorf("GetPointerAtAddress(%#x) = %#x, %v; want %#x", data.Addr, ptr, err, slide+text.Addr+4)
+	}
+
+	// the pointers can be unslid with a converter
+	m, err = NewFileFromMemory(bytes.NewReader(mem), FileConfig{
+		VMAddrConverter: types.VMAddrConverter{Converter: func(ptr uint64) uint64 { return ptr - slide }},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptr, err := m.GetPointerAtAddress(data.Addr); err != nil || ptr != text.Addr+4 {
+		t.Errorf("GetPointerAtAddress(%#x) = %#x, %v; want %#x", data.Addr, ptr, err, text.Addr+4)
+	}
+
+	// a process (address space) with the image loaded at a slide
+	vmr := types.NewMemoryVMReader(bytes.NewReader(mem), text.Addr+slide, binary.LittleEndian, 8)
+	m, err = NewFileFromVMReader(vmr, text.Addr+slide)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Symtab == nil || len(m.Symtab.Syms) != 1 || m.Symtab.Syms[0] != f.Symtab.Syms[0] {
+		t.Errorf("NewFileFromVMReader() symbols = %v", m.Symtab.Syms)
+	}
+	if ptr, err := m.VMReader().ReadPointer(data.Addr); err != nil || ptr != slide+text.Addr+4 {
+		t.Errorf("ReadPointer(%#x) = %#x, %v; want %#x", data.Addr, ptr, err, slide+text.Addr+4)
+	}
+	if name, err := m.GetCString(linkedit.Addr + uint64(m.Symtab.Stroff) - linkedit.Offset + 2); err != nil || name != "_main" {
+		t.Errorf("GetCString() = %q, %v", name, err)
+	}
+}
+
+func TestLazy(t *testing.T) {
+	for _, name := range []string{
+		"internal/testdata/clang-amd64-darwin.obj.base64",
+		"internal/testdata/gcc-amd64-darwin-exec.base64",
+	} {
+		f, err := openObscured(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ra, err := readerAtFromObscured(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lf, err := NewFile(ra, FileConfig{Lazy: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if lf.Symtab.Syms != nil {
+			t.Errorf("%s: lazy Symtab.Syms parsed before first access", name)
+		}
+		syms, err := lf.Symbols()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(syms, f.Symtab.Syms) {
+			t.Errorf("%s: Symbols() =\n\t%v\nwant\n\t%v", name, syms, f.Symtab.Syms)
+		}
+		if f.Dysymtab != nil {
+			if f.Dysymtab.Nindirectsyms > 0 && lf.Dysymtab.IndirectSyms != nil {
+				t.Errorf("%s: lazy IndirectSyms parsed before first access", name)
+			}
+			x, err := lf.IndirectSymbols()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(x, f.Dysymtab.IndirectSyms) {
+				t.Errorf("%s: IndirectSymbols() = %v, want %v", name, x, f.Dysymtab.IndirectSyms)
+			}
+		}
+		for i, sec := range lf.Sections {
+			if sec.Nreloc > 0 && sec.Relocs != nil {
+				t.Errorf("%s: lazy %s.%s relocations parsed before first access", name, sec.Seg, sec.Name)
+			}
+			relocs, err := lf.Relocations(sec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(relocs, f.Sections[i].Relocs) {
+				t.Errorf("%s: Relocations(%s.%s) = %v, want %v", name, sec.Seg, sec.Name, relocs, f.Sections[i].Relocs)
+			}
+		}
+	}
+}
+
+func TestNewFileOptions(t *testing.T) {
+	const name = "internal/testdata/gcc-amd64-darwin-exec.base64"
+	f, err := openObscured(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ra, err := readerAtFromObscured(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewFile(ra, WithoutSections(), WithoutSymbols())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Sections) != 0 {
+		t.Errorf("WithoutSections() parsed %d sections", len(m.Sections))
+	}
+	if m.Symtab == nil || m.Symtab.Syms != nil {
+		t.Errorf("WithoutSymbols() Symtab = %v", m.Symtab)
+	}
+	if len(m.Loads) != len(f.Loads) {
+		t.Errorf("len(Loads) = %d, want %d", len(m.Loads), len(f.Loads))
+	}
+
+	m, err = NewFile(ra, OnlyLoadCommands(types.LC_SEGMENT_64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Loads) != len(f.Segments()) || m.Symtab != nil {
+		t.Errorf("OnlyLoadCommands(LC_SEGMENT_64) Loads = %v", m.Loads)
+	}
+
+	m, err = NewFile(ra, WithSectionCache(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		for j, sec := range m.Sections {
+			if sec.Flags.IsZerofill() {
+				continue
+			}
+			have, err := sec.Data()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := f.Sections[j].Data()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(have, want) {
+				t.Errorf("WithSectionCache(1) %s.%s data = % x, want % x", sec.Seg, sec.Name, have, want)
+			}
+		}
+	}
+}
+
+func TestPermissive(t *testing.T) {
+	const name = "internal/testdata/gcc-amd64-darwin-exec.base64"
+	f, err := openObscured(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ra, err := readerAtFromObscured(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dat := make([]byte, ra.(*bytes.Reader).Size())
+	if _, err := ra.ReadAt(dat, 0); err != nil {
+		t.Fatal(err)
+	}
+	// point the string table past the end of the file
+	var symtab int
+	for i, l := range f.Loads {
+		if l.Command() == types.LC_SYMTAB {
+			symtab = i
+		}
+	}
+	binary.LittleEndian.PutUint32(dat[f.LoadOffsets[symtab]+16:], 0x7fffffff)
+
+	_, err = NewFile(bytes.NewReader(dat))
+	var lerr *LoadCmdError
+	if !errors.As(err, &lerr) || lerr.Cmd != types.LC_SYMTAB || lerr.Index != symtab || lerr.Offset != f.LoadOffsets[symtab] {
+		t.Fatalf("NewFile() error = %v, want an LC_SYMTAB LoadCmdError", err)
+	}
+
+	m, err := NewFile(bytes.NewReader(dat), Permissive())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the LC_DYSYMTAB can't be parsed without the LC_SYMTAB either
+	warnings := m.ParseWarnings()
+	if len(warnings) != 2 || warnings[0].Cmd != types.LC_SYMTAB || warnings[1].Cmd != types.LC_DYSYMTAB {
+		t.Errorf("ParseWarnings() = %v", warnings)
+	}
+	if len(m.Loads) != len(f.Loads) || m.Symtab != nil || m.UUID() == nil {
+		t.Errorf("permissive NewFile() loads = %v", m.Loads)
+	}
+}
+
+func TestDiagnosticHandler(t *testing.T) {
+	const name = "internal/testdata/gcc-amd64-darwin-exec.base64"
+	f, err := openObscured(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ra, err := readerAtFromObscured(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dat := make([]byte, ra.(*bytes.Reader).Size())
+	if _, err := ra.ReadAt(dat, 0); err != nil {
+		t.Fatal(err)
+	}
+	// turn the LC_UUID into an unknown load command
+	var uuid int
+	for i, l := range f.Loads {
+		if l.Command() == types.LC_UUID {
+			uuid = i
+		}
+	}
+	binary.LittleEndian.PutUint32(dat[f.LoadOffsets[uuid]:], 0x7777)
+
+	var diags []Diagnostic
+	if _, err := NewFile(bytes.NewReader(dat), WithDiagnosticHandler(func(d Diagnostic) {
+		diags = append(diags, d)
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 1 || diags[0].Cmd != 0x7777 || diags[0].Offset != f.LoadOffsets[uuid] || !errors.Is(diags[0].Err, ErrUnknownLoadCmd) {
+		t.Errorf("diagnostics = %v", diags)
+	}
+
+	var pkgDiags []Diagnostic
+	SetDiagnosticHandler(func(d Diagnostic) { pkgDiags = append(pkgDiags, d) })
+	defer SetDiagnosticHandler(nil)
+	if _, err := NewFile(bytes.NewReader(dat)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pkgDiags, diags) {
+		t.Errorf("package-level diagnostics = %v, want %v", pkgDiags, diags)
+	}
+}
+
+func TestDiagnosticHandlerCodeSignature(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.CodeSign(&codesign.Config{ID: "com.example.test", Flags: cstypes.ADHOC}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dat := buf.Bytes()
+	// downgrade the code directory to an unsupported version
+	sig := f.CodeSignature()
+	cd := sig.Offset + binary.BigEndian.Uint32(dat[sig.Offset+16:]) // first blob index offset
+	binary.BigEndian.PutUint32(dat[cd+8:], 0x10000)
+
+	var diags []Diagnostic
+	if _, err := NewFile(bytes.NewReader(dat), WithDiagnosticHandler(func(d Diagnostic) {
+		diags = append(diags, d)
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 1 || diags[0].Cmd != types.LC_CODE_SIGNATURE || !strings.Contains(diags[0].Message, "too old") {
+		t.Errorf("diagnostics = %v", diags)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	const name = "internal/testdata/gcc-amd64-darwin-exec.base64"
+	f, err := openObscured(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ra, err := readerAtFromObscured(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		limits Limits
+		want   string
+	}{
+		{Limits{MaxSymbols: f.Symtab.Nsyms - 1}, "too many symbols"},
+		{Limits{MaxStringTable: f.Symtab.Strsize - 1}, "too many string table bytes"},
+		{Limits{MaxCommandSize: 8}, "command block too large"},
+	} {
+		if _, err := NewFile(ra, WithLimits(tt.limits)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("NewFile(%+v) error = %v, want %q", tt.limits, err, tt.want)
+		}
+	}
+	if _, err := NewFile(ra, WithLimits(Limits{MaxSymbols: f.Symtab.Nsyms, MaxStringTable: f.Symtab.Strsize})); err != nil {
+		t.Errorf("NewFile() at the limits error = %v", err)
+	}
+
+	// a huge symbol count is caught before it is allocated
+	dat := make([]byte, ra.(*bytes.Reader).Size())
+	if _, err := ra.ReadAt(dat, 0); err != nil {
+		t.Fatal(err)
+	}
+	for i, l := range f.Loads {
+		if l.Command() == types.LC_SYMTAB {
+			binary.LittleEndian.PutUint32(dat[f.LoadOffsets[i]+12:], 0xffffffff)
+		}
+	}
+	if _, err := NewFile(bytes.NewReader(dat)); err == nil || !strings.Contains(err.Error(), "symbol table extends past the end of the file") {
+		t.Errorf("NewFile() error = %v", err)
+	}
+}
+
+func TestBoundsChecks(t *testing.T) {
+	const name = "internal/testdata/gcc-amd64-darwin-exec.base64"
+	f, err := openObscured(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig, err := obscuretestdata.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadOffset := func(cmd types.LoadCmd) int64 {
+		for i, l := range f.Loads {
+			if l.Command() == cmd {
+				return f.LoadOffsets[i]
+			}
+		}
+		t.Fatalf("no %s", cmd)
+		return 0
+	}
+	var text int64
+	for i, l := range f.Loads {
+		if seg, ok := l.(*Segment); ok && seg.Name == "__TEXT" {
+			text = f.LoadOffsets[i]
+		}
+	}
+
+	for _, tt := range []struct {
+		name  string
+		patch func(dat []byte)
+		want  string
+	}{
+		{"segment past EOF", func(dat []byte) {
+			binary.LittleEndian.PutUint64(dat[text+48:], 1<<40) // filesize
+		}, "segment __TEXT extends past the end of the file"},
+		{"segment overflow", func(dat []byte) {
+			binary.LittleEndian.PutUint64(dat[text+40:], 1<<63-1) // fileoff
+		}, "segment __TEXT size overflows"},
+		{"local symbols overflow", func(dat []byte) {
+			binary.LittleEndian.PutUint32(dat[loadOffset(types.LC_DYSYMTAB)+8:], 0xffffffff)
+			binary.LittleEndian.PutUint32(dat[loadOffset(types.LC_DYSYMTAB)+12:], 2)
+		}, "local symbols in dynamic symbol table command extend past the symbol table"},
+		{"thread state count", func(dat []byte) {
+			binary.LittleEndian.PutUint32(dat[loadOffset(types.LC_UNIXTHREAD)+12:], 0xffffffff)
+		}, "invalid thread state count"},
+	} {
+		dat := append([]byte(nil), orig...)
+		tt.patch(dat)
+		if _, err := NewFile(bytes.NewReader(dat)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: NewFile() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestSegmentWithoutReader(t *testing.T) {
+	seg := &Segment{SegmentHeader: SegmentHeader{Name: "__TEXT", Filesz: 0x10}}
+	if _, err := seg.Data(); err == nil {
+		t.Error("Data() succeeded without a reader")
+	}
+	if _, err := seg.Open().Read(make([]byte, 4)); err == nil {
+		t.Error("Open().Read() succeeded without a reader")
+	}
+}
+
+func FuzzNewFile(f *testing.F) {
+	for _, name := range []string{
+		"internal/testdata/clang-386-darwin.obj.base64",
+		"internal/testdata/clang-amd64-darwin.obj.base64",
+		"internal/testdata/clang-amd64-darwin-exec-with-rpath.base64",
+		"internal/testdata/gcc-386-darwin-exec.base64",
+		"internal/testdata/gcc-amd64-darwin-exec.base64",
+	} {
+		dat, err := obscuretestdata.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(dat)
+	}
+	f.Fuzz(func(t *testing.T, dat []byte) {
+		for _, opts := range [][]Option{nil, {Permissive()}} {
+			m, err := NewFile(bytes.NewReader(dat), opts...)
+			if err != nil {
+				continue
+			}
+			for _, sec := range m.Sections {
+				sec.Data()
+			}
+			for _, seg := range m.Segments() {
+				seg.Data()
+			}
+			m.ImportedLibraries()
+			m.ImportedSymbols()
+			m.GetIndirectSymbols()
+		}
+	})
+}
+
+func TestSymbolIndex(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sym := range f.Symtab.Syms {
+		if sym.Name == "" || sym.Type.IsDebugSym() {
+			continue
+		}
+		addr, err := f.FindSymbolAddress(sym.Name)
+		if err != nil {
+			t.Errorf("FindSymbolAddress(%s) error = %v", sym.Name, err)
+			continue
+		}
+		var want []Symbol
+		for _, s := range f.Symtab.Syms {
+			if s.Value == addr {
+				want = append(want, s)
+			}
+		}
+		if have, err := f.FindAddressSymbols(addr); err != nil || !reflect.DeepEqual(have, want) {
+			t.Errorf("FindAddressSymbols(%#x) = %v, %v; want %v", addr, have, err, want)
+		}
+	}
+
+	if _, err := f.FindSymbolAddress("_MAIN"); err == nil {
+		t.Error("FindSymbolAddress(_MAIN) succeeded without FoldCase")
+	}
+	main, err := f.FindSymbolAddress("_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr, err := f.FindSymbolAddress("_MAIN", FoldCase()); err != nil || addr != main {
+		t.Errorf("FindSymbolAddress(_MAIN, FoldCase()) = %#x, %v; want %#x", addr, err, main)
+	}
+
+	// the index follows the symbols when they are slid
+	const slide = 0x1000
+	if err := f.slideSymbols(slide); err != nil {
+		t.Fatal(err)
+	}
+	if addr, err := f.FindSymbolAddress("_main"); err != nil || addr != main+slide {
+		t.Errorf("slid FindSymbolAddress(_main) = %#x, %v; want %#x", addr, err, main+slide)
+	}
+}
+
+func TestFindSymbols(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := func(syms []Symbol) []string {
+		var names []string
+		for _, sym := range syms {
+			names = append(names, sym.Name)
+		}
+		return names
+	}
+	var undefined, external []string
+	for _, sym := range f.Symtab.Syms {
+		if sym.Type.IsDebugSym() {
+			continue
+		}
+		if sym.Type.IsUndefinedSym() {
+			undefined = append(undefined, sym.Name)
+		}
+		if sym.Type.IsExternalSym() {
+			external = append(external, sym.Name)
+		}
+	}
+
+	for _, tt := range []struct {
+		q    SymbolQuery
+		want []string
+	}{
+		{SymbolQuery{Name: "_main"}, []string{"_main"}},
+		{SymbolQuery{Name: "_nope"}, nil},
+		{SymbolQuery{Prefix: "_ma"}, []string{"_main"}},
+		{SymbolQuery{Glob: "_*ai?"}, []string{"_main"}},
+		{SymbolQuery{Regexp: regexp.MustCompile(`^_m.in$`)}, []string{"_main"}},
+		{SymbolQuery{Name: "_main", Undefined: true}, nil},
+		{SymbolQuery{Undefined: true}, undefined},
+		{SymbolQuery{External: true}, external},
+	} {
+		have, err := f.FindSymbols(tt.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(names(have), tt.want) {
+			t.Errorf("FindSymbols(%+v) = %v, want %v", tt.q, names(have), tt.want)
+		}
+	}
+	if _, err := f.FindSymbols(SymbolQuery{Glob: "["}); err == nil {
+		t.Error("FindSymbols() with a bad glob succeeded")
+	}
+}
+
+func TestNearestSymbol(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	main, err := f.FindSymbolAddress("_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, off := range []uint64{0, 1, 5} {
+		sym, have, err := f.NearestSymbol(main + off)
+		if err != nil || sym.Name != "_main" || have != off {
+			t.Errorf("NearestSymbol(_main+%d) = %s+%d, %v", off, sym.Name, have, err)
+		}
+	}
+	// the symbols don't extend past their section
+	text := f.Section("__TEXT", "__text")
+	for _, sec := range f.Sections {
+		if sec.Addr > text.Addr && sec.Size > 0 {
+			if sym, _, err := f.NearestSymbol(sec.Addr + sec.Size - 1); err == nil && sym.Section(f) != sec {
+				t.Errorf("NearestSymbol(%s.%s) = %s in another section", sec.Seg, sec.Name, sym.Name)
+			}
+		}
+	}
+	if _, _, err := f.NearestSymbol(0); err == nil {
+		t.Error("NearestSymbol(0) succeeded")
+	}
+}
+
+func TestSymbolFilters(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := func(filters ...SymbolFilter) []string {
+		syms, err := f.Symbols(filters...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, sym := range syms {
+			names = append(names, sym.Name)
+		}
+		return names
+	}
+
+	if all := names(); len(all) != len(f.Symtab.Syms) {
+		t.Errorf("Symbols() = %v", all)
+	}
+	if have := names(InSection("__TEXT", "__text"), External()); !reflect.DeepEqual(have, []string{"_main", "start"}) {
+		t.Errorf("Symbols(InSection(__TEXT, __text), External()) = %v", have)
+	}
+	undefined := names(Undefined())
+	if len(undefined) == 0 {
+		t.Fatal("no undefined symbols")
+	}
+	// gcc links libgcc_s before libSystem
+	if have := names(LibraryOrdinal(2)); !reflect.DeepEqual(have, undefined) {
+		t.Errorf("Symbols(LibraryOrdinal(2)) = %v, want %v", have, undefined)
+	}
+	if have := names(LibraryOrdinal(1)); have != nil {
+		t.Errorf("Symbols(LibraryOrdinal(1)) = %v", have)
+	}
+	if have := names(Any(Undefined(), Not(Undefined()))); len(have) != len(f.Symtab.Syms) {
+		t.Errorf("Symbols(Any(Undefined(), Not(Undefined()))) = %v", have)
+	}
+	var ndebug int
+	for _, sym := range f.Symtab.Syms {
+		if sym.Type.IsDebugSym() {
+			ndebug++
+		}
+	}
+	if have := names(Debug()); len(have) != ndebug {
+		t.Errorf("Symbols(Debug()) = %v", have)
+	}
+	if have := names(WeakDefinition()); have != nil {
+		t.Errorf("Symbols(WeakDefinition()) = %v", have)
+	}
+}
+
+func TestImportedSymbolDetails(t *testing.T) {
+	tests := []struct {
+		file string
+		want []ImportedSymbol
+	}{
+		{"internal/testdata/gcc-amd64-darwin-exec.base64", []ImportedSymbol{
+			{Name: "_exit", Ordinal: 2, Dylib: "/usr/lib/libSystem.B.dylib", Binds: []uint64{0x100001058}},
+			{Name: "_puts", Ordinal: 2, Dylib: "/usr/lib/libSystem.B.dylib", Binds: []uint64{0x100001060}},
+		}},
+	}
+	for _, tt := range tests {
+		f, err := openObscured(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imps, err := f.ImportedSymbolDetails()
+		if err != nil {
+			t.Fatalf("%s: ImportedSymbolDetails: %v", tt.file, err)
+		}
+		if !reflect.DeepEqual(imps, tt.want) {
+			t.Errorf("%s: ImportedSymbolDetails() = %v, want %v", tt.file, imps, tt.want)
+		}
+	}
+
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imps, err := f.ImportedSymbolDetails()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, imp := range imps {
+		if imp.Name == "_printf" {
+			found = true
+			if imp.Ordinal != 1 || imp.Weak || !reflect.DeepEqual(imp.Binds, []uint64{0x100001010}) {
+				t.Errorf("ImportedSymbolDetails() _printf = %+v", imp)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("ImportedSymbolDetails() = %v, want _printf", imps)
+	}
+
+	// chained fixups
+	cf, _, data, _ := chainedFixupsMachO(t)
+	imps, err = cf.ImportedSymbolDetails()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ImportedSymbol{
+		{Name: "_printf", Ordinal: 1, Dylib: "/usr/lib/libSystem.B.dylib", Binds: []uint64{data.Addr + 8}},
+		{Name: "_main", Ordinal: types.BIND_SPECIAL_DYLIB_SELF, Dylib: "this-image", Binds: []uint64{data.Addr + 16}},
+	}
+	if !reflect.DeepEqual(imps, want) {
+		t.Errorf("ImportedSymbolDetails() = %+v, want %+v", imps, want)
+	}
+}
+
+func TestImportedSymbolDetailsOrdinals(t *testing.T) {
+	b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.HeaderPad = 0x100
+	b.AddSection("__TEXT", "__text", []byte{0xc3}, types.PURE_INSTRUCTIONS)
+	data := b.AddSection("__DATA", "__data", make([]byte, 3*8), types.Regular)
+	b.AddDylib("/usr/lib/libA.dylib")
+	b.AddDylib("/usr/lib/libB.dylib")
+	b.SetEntryPoint("__TEXT", "__text", 0)
+	dat, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var segIdx byte
+	for i, seg := range f.Segments() {
+		if seg.Name == "__DATA" {
+			segIdx = byte(i)
+		}
+	}
+
+	// _foo bound from both dylibs and _bar from the main executable (a special ordinal)
+	binds := []byte{
+		types.BIND_OPCODE_SET_TYPE_IMM | types.BIND_TYPE_POINTER,
+		types.BIND_OPCODE_SET_SEGMENT_AND_OFFSET_ULEB | segIdx, byte(data.Addr - b.Segment("__DATA").Addr),
+		types.BIND_OPCODE_SET_DYLIB_ORDINAL_IMM | 1,
+		types.BIND_OPCODE_SET_SYMBOL_TRAILING_FLAGS_IMM, '_', 'f', 'o', 'o', 0,
+		types.BIND_OPCODE_DO_BIND,
+		types.BIND_OPCODE_SET_DYLIB_ORDINAL_IMM | 2,
+		types.BIND_OPCODE_DO_BIND,
+		types.BIND_OPCODE_SET_DYLIB_SPECIAL_IMM | (types.BIND_SPECIAL_DYLIB_MAIN_EXECUTABLE & types.BIND_IMMEDIATE_MASK),
+		types.BIND_OPCODE_SET_SYMBOL_TRAILING_FLAGS_IMM, '_', 'b', 'a', 'r', 0,
+		types.BIND_OPCODE_DO_BIND,
+		types.BIND_OPCODE_DONE,
+	}
+	// add an LC_DYLD_INFO_ONLY for them (in the header padding)
+	bo := binary.LittleEndian
+	ncmds, sizeofcmds := bo.Uint32(dat[16:]), bo.Uint32(dat[20:])
+	lc := dat[types.FileHeaderSize64+sizeofcmds:]
+	bo.PutUint32(lc[0:], uint32(types.LC_DYLD_INFO_ONLY))
+	bo.PutUint32(lc[4:], 48)
+	bo.PutUint32(lc[16:], uint32(len(dat))) // bind_off
+	bo.PutUint32(lc[20:], uint32(len(binds)))
+	bo.PutUint32(dat[16:], ncmds+1)
+	bo.PutUint32(dat[20:], sizeofcmds+48)
+	dat = append(dat, binds...)
+
+	if f, err = NewFile(bytes.NewReader(dat)); err != nil {
+		t.Fatal(err)
+	}
+	imps, err := f.ImportedSymbolDetails()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ImportedSymbol{
+		{Name: "_foo", Ordinal: 1, Dylib: "/usr/lib/libA.dylib", Binds: []uint64{data.Addr}},
+		{Name: "_foo", Ordinal: 2, Dylib: "/usr/lib/libB.dylib", Binds: []uint64{data.Addr + 8}},
+		{Name: "_bar", Ordinal: types.BIND_SPECIAL_DYLIB_MAIN_EXECUTABLE, Dylib: "main-executable", Binds: []uint64{data.Addr + 16}},
+	}
+	if !reflect.DeepEqual(imps, want) {
+		t.Errorf("ImportedSymbolDetails() = %+v, want %+v", imps, want)
+	}
+}
+
+func TestImportedLibraryDetails(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.InsertDylib("@rpath/libweak.dylib", true, nil); err != nil {
+		t.Fatal(err)
+	}
+	libs := f.ImportedLibraryDetails()
+	want := []struct {
+		path    string
+		kind    types.LoadCmd
+		current string
+	}{
+		{"/usr/lib/libgcc_s.1.dylib", types.LC_LOAD_DYLIB, "1.0"},
+		{"/usr/lib/libSystem.B.dylib", types.LC_LOAD_DYLIB, "111.1.4"},
+		{"@rpath/libweak.dylib", types.LC_LOAD_WEAK_DYLIB, "0.0"},
+	}
+	if len(libs) != len(want) {
+		t.Fatalf("ImportedLibraryDetails() = %v", libs)
+	}
+	for i, w := range want {
+		l := libs[i]
+		if l.Path != w.path || l.Kind != w.kind || l.Ordinal != i+1 || l.CurrentVersion.String() != w.current {
+			t.Errorf("ImportedLibraryDetails()[%d] = %+v, want %s %s %s", i, l, w.path, w.kind, w.current)
+		}
+		if l.Weak() != (w.kind == types.LC_LOAD_WEAK_DYLIB) || l.ReExport() || l.Upward() || l.Lazy() {
+			t.Errorf("ImportedLibraryDetails()[%d] = %s has the wrong kind", i, l)
+		}
+	}
+	if paths := f.ImportedLibraries(); !reflect.DeepEqual(paths, []string{want[0].path, want[1].path, want[2].path}) {
+		t.Errorf("ImportedLibraries() = %v", paths)
+	}
+}
+
+// exportTrie encodes an export trie with a single level of terminal nodes for the export infos by name
+func exportTrie(infos map[string][]byte) []byte {
+	var names []string
+	for name := range infos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	size := 2 // root terminal size and child count
+	for _, name := range names {
+		size += len(name) + 2 // edge and 1 byte child offset
+	}
+	var root, nodes bytes.Buffer
+	root.Write([]byte{0, byte(len(names))})
+	for _, name := range names {
+		root.WriteString(name + "\x00")
+		trie.EncodeUleb128(&root, uint64(size+nodes.Len()))
+		nodes.WriteByte(byte(len(infos[name])))
+		nodes.Write(infos[name])
+		nodes.WriteByte(0) // no children
+	}
+	return append(root.Bytes(), nodes.Bytes()...)
+}
+
+func TestResolveExport(t *testing.T) {
+	build := func(id string, syms, dylibs []string) []byte {
+		b := NewBuilder(types.MH_DYLIB, types.CPUAmd64, types.CPUSubtypeX8664All)
+		b.InstallName = id
+		b.HeaderPad = 0x100
+		b.AddSection("__TEXT", "__text", bytes.Repeat([]byte{0xc3}, len(syms)+1), types.PURE_INSTRUCTIONS)
+		for i, sym := range syms {
+			b.AddSymbol(sym, "__TEXT", "__text", uint64(i), true)
+		}
+		for _, dylib := range dylibs {
+			b.AddDylib(dylib)
+		}
+		dat, err := b.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dat
+	}
+	open := func(dat []byte) *File {
+		m, err := NewFile(bytes.NewReader(dat))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	reexport := func(ordinal uint64, name string) []byte {
+		var buf bytes.Buffer
+		trie.EncodeUleb128(&buf, uint64(types.EXPORT_SYMBOL_FLAGS_REEXPORT))
+		trie.EncodeUleb128(&buf, ordinal)
+		buf.WriteString(name + "\x00")
+		return buf.Bytes()
+	}
+
+	images := map[string]*File{
+		"/usr/lib/libreal.dylib": open(build("/usr/lib/libreal.dylib", []string{"_real_impl", "_same"}, nil)),
+		"/usr/lib/libsub.dylib":  open(build("/usr/lib/libsub.dylib", []string{"_sub_func"}, nil)),
+	}
+	resolve := func(name string) (*File, error) {
+		if m, ok := images[name]; ok {
+			return m, nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	// libfront loads libreal and re-exports libsub (LC_REEXPORT_DYLIB)
+	dat := build("/usr/lib/libfront.dylib", nil, []string{"/usr/lib/libreal.dylib", "/usr/lib/libsub.dylib"})
+	bo := binary.LittleEndian
+	for off, i := uint32(types.FileHeaderSize64), uint32(0); i < bo.Uint32(dat[16:]); i++ {
+		cmd := dat[off:]
+		if types.LoadCmd(bo.Uint32(cmd)) == types.LC_LOAD_DYLIB && strings.HasPrefix(string(cmd[bo.Uint32(cmd[8:]):]), "/usr/lib/libsub.dylib") {
+			bo.PutUint32(cmd, uint32(types.LC_REEXPORT_DYLIB))
+		}
+		off += bo.Uint32(cmd[4:])
+	}
+	dat = addLinkEditData(dat, types.LC_DYLD_EXPORTS_TRIE, exportTrie(map[string][]byte{
+		"_foo":  reexport(1, "_real_impl"),
+		"_same": reexport(1, ""),
+		"_own":  {0, 0x80, 0x20}, // regular at 0x1000
+		"_loop": reexport(2, "_loop"),
+		"_gone": reexport(3, ""),
+	}))
+	front := open(dat)
+
+	addr := func(m *File, sym string) uint64 {
+		a, err := m.FindSymbolAddress(sym)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	tests := []struct {
+		symbol string
+		name   string
+		dylib  string
+		addr   uint64
+	}{
+		{"_foo", "_real_impl", "/usr/lib/libreal.dylib", addr(images["/usr/lib/libreal.dylib"], "_real_impl")},
+		{"_same", "_same", "/usr/lib/libreal.dylib", addr(images["/usr/lib/libreal.dylib"], "_same")},
+		{"_sub_func", "_sub_func", "/usr/lib/libsub.dylib", addr(images["/usr/lib/libsub.dylib"], "_sub_func")},
+		{"_own", "_own", "/usr/lib/libfront.dylib", front.GetBaseAddress() + 0x1000},
+	}
+	for _, tt := range tests {
+		exp, err := front.ResolveExport(tt.symbol, resolve)
+		if err != nil {
+			t.Errorf("ResolveExport(%s): %v", tt.symbol, err)
+			continue
+		}
+		if exp.Name != tt.name || exp.FoundInDylib != tt.dylib || exp.Address != tt.addr || exp.Flags.ReExport() {
+			t.Errorf("ResolveExport(%s) = %+v, want %s in %s at %#x", tt.symbol, exp, tt.name, tt.dylib, tt.addr)
+		}
+	}
+	for _, sym := range []string{"_nope", "_loop"} {
+		if exp, err := front.ResolveExport(sym, resolve); !errors.Is(err, ErrExportNotFound) {
+			t.Errorf("ResolveExport(%s) = %v, %v; want ErrExportNotFound", sym, exp, err)
+		}
+	}
+	if _, err := front.ResolveExport("_gone", resolve); err == nil || errors.Is(err, ErrExportNotFound) {
+		t.Errorf("ResolveExport(_gone) = %v; want invalid library ordinal error", err)
+	}
+}
+
+func TestLibraryOrdinalName(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		ordinal int
+		want    string
+	}{
+		{1, "libgcc_s.1.dylib"},
+		{2, "libSystem.B.dylib"},
+		{3, "ordinal-too-large"},
+		{types.BIND_SPECIAL_DYLIB_SELF, "this-image"},
+		{types.BIND_SPECIAL_DYLIB_MAIN_EXECUTABLE, "main-executable"},
+		{types.BIND_SPECIAL_DYLIB_FLAT_LOOKUP, "flat-namespace"},
+		{types.BIND_SPECIAL_DYLIB_WEAK_LOOKUP, "weak-coalesce"},
+		{-4, "unknown-ordinal"},
+		{types.DYNAMIC_LOOKUP_ORDINAL, "flat-namespace"},
+		{types.EXECUTABLE_ORDINAL, "main-executable"},
+	} {
+		if got := f.LibraryOrdinalName(tt.ordinal); got != tt.want {
+			t.Errorf("LibraryOrdinalName(%d) = %s, want %s", tt.ordinal, got, tt.want)
+		}
+	}
+	for _, sym := range f.Symtab.Syms {
+		if sym.Name == "_puts" {
+			if got := f.LibraryOrdinalName(int(sym.Desc.GetLibraryOrdinal())); got != "libSystem.B.dylib" {
+				t.Errorf("LibraryOrdinalName(_puts n_desc) = %s", got)
+			}
+		}
+	}
+}
+
+func TestSymbolSection(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sym := range f.Symtab.Syms {
+		sec := f.SectionForSymbol(sym)
+		switch {
+		case sym.Name == "_main":
+			if sec == nil || sec.Seg != "__TEXT" || sec.Name != "__text" {
+				t.Errorf("SectionForSymbol(_main) = %v, want __TEXT.__text", sec)
+			}
+		case sym.Type.IsUndefinedSym(), sym.Type.IsDebugSym():
+			if sec != nil {
+				t.Errorf("SectionForSymbol(%s) = %s.%s, want nil", sym.Name, sec.Seg, sec.Name)
+			}
+		case sym.Type.IsDefinedInSection():
+			if sec != f.Sections[sym.Sect-1] {
+				t.Errorf("SectionForSymbol(%s) = %v, want section %d", sym.Name, sec, sym.Sect)
+			}
+		}
+	}
+	if sec := (Symbol{Type: types.N_SECT, Sect: uint8(len(f.Sections) + 1)}).Section(f); sec != nil {
+		t.Errorf("Section() of an out of range n_sect = %v, want nil", sec)
+	}
+}
+
+func TestThumb(t *testing.T) {
+	// the Builder only emits 64-bit MachOs but the Thumb handling only depends on the CPU (patched to armv7 below)
+	b := NewBuilder(types.MH_DYLIB, types.CPUArm64, types.CPUSubtypeArm64All)
+	b.InstallName = "/usr/lib/libthumb.dylib"
+	b.HeaderPad = 0x100
+	text := b.AddSection("__TEXT", "__text", make([]byte, 16), types.PURE_INSTRUCTIONS|types.SOME_INSTRUCTIONS)
+	b.AddSymbol("_arm_func", "__TEXT", "__text", 0, true)
+	b.AddSymbol("_thumb_func", "__TEXT", "__text", 8, true)
+	dat, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var starts bytes.Buffer
+	trie.EncodeUleb128(&starts, text.Addr-b.Segment("__TEXT").Addr)
+	trie.EncodeUleb128(&starts, 8|1) // Thumb function starts have the low bit set
+	starts.WriteByte(0)
+	binary.LittleEndian.PutUint32(dat[4:], uint32(types.CPUArm))
+	binary.LittleEndian.PutUint32(dat[8:], uint32(types.CPUSubtypeArmV7))
+	f, err := NewFile(bytes.NewReader(addLinkEditData(dat, types.LC_FUNCTION_STARTS, starts.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, sym := range f.Symtab.Syms {
+		if sym.Name == "_thumb_func" {
+			f.Symtab.Syms[i].Desc |= types.ARM_THUMB_DEF
+		}
+	}
+
+	want := []types.Function{
+		{StartAddr: text.Addr, EndAddr: text.Addr + 8},
+		{StartAddr: text.Addr + 8, EndAddr: text.Addr + 16, Thumb: true},
+	}
+	if fns := f.GetFunctions(); !reflect.DeepEqual(fns, want) {
+		t.Errorf("GetFunctions() = %+v, want %+v", fns, want)
+	}
+	for _, sym := range f.Symtab.Syms {
+		if sym.IsThumb() != (sym.Name == "_thumb_func") {
+			t.Errorf("%s IsThumb() = %t", sym.Name, sym.IsThumb())
+		}
+	}
+	fns, err := f.RecoverFunctions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want[0].Name, want[1].Name = "_arm_func", "_thumb_func"
+	if len(fns) != 2 || fns[0].Function != want[0] || fns[1].Function != want[1] {
+		t.Errorf("RecoverFunctions() = %+v, want %+v", fns, want)
+	}
+	if fn, err := f.FunctionForAddress(text.Addr + 10); err != nil || *fn != want[1] {
+		t.Errorf("FunctionForAddress(%#x) = %+v, %v; want %+v", text.Addr+10, fn, err, want[1])
+	}
+}
+
+func TestRelocTypeStringByCPU(t *testing.T) {
+	for _, tt := range []struct {
+		file string
+		want []string
+	}{
+		{"internal/testdata/clang-amd64-darwin.obj.base64", []string{"X86_64_RELOC_BRANCH", "X86_64_RELOC_SIGNED"}},
+		{"internal/testdata/clang-386-darwin.obj.base64", []string{"GENERIC_RELOC_VANILLA", "GENERIC_RELOC_LOCAL_SECTDIFF", "GENERIC_RELOC_PAIR"}},
+	} {
+		f, err := openObscured(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		relocs, err := f.Relocations(f.Section("__TEXT", "__text"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range relocs {
+			got = append(got, r.TypeString(f.CPU))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: TypeString() = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+	for _, tt := range []struct {
+		cpu  types.CPU
+		typ  uint8
+		want string
+	}{
+		{types.CPUArm, 6, "ARM_THUMB_RELOC_BR22"},
+		{types.CPUArm64, 11, "ARM64_RELOC_AUTHENTICATED_POINTER"},
+		{types.CPUArm64, 12, "RelocTypeARM64(12)"},
+		{types.CPUPpc, 1, "GENERIC_RELOC_PAIR"},
+	} {
+		if got := (types.Reloc{Type: tt.typ}).TypeString(tt.cpu); got != tt.want {
+			t.Errorf("TypeString(%s) of type %d = %s, want %s", tt.cpu, tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestDysymtabRelocs(t *testing.T) {
+	dat, err := obscuretestdata.ReadFile("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := uint32(types.FileHeaderSize64)
+	for _, l := range f.Loads {
+		if _, ok := l.(*Dysymtab); ok {
+			break
+		}
+		off += uint32(len(l.Raw()))
+	}
+
+	// an external relocation of a pointer to symbol 3 and a local relocation of a pointer into section 2
+	bo := binary.LittleEndian
+	for len(dat)%8 != 0 {
+		dat = append(dat, 0)
+	}
+	relocs := make([]byte, 16)
+	bo.PutUint32(relocs[0:], 0x18)
+	bo.PutUint32(relocs[4:], 3|3<<25|1<<27)
+	bo.PutUint32(relocs[8:], 0x20)
+	bo.PutUint32(relocs[12:], 2|3<<25)
+	bo.PutUint32(dat[off+64:], uint32(len(dat))) // extreloff
+	bo.PutUint32(dat[off+68:], 1)                // nextrel
+	bo.PutUint32(dat[off+72:], uint32(len(dat))+8)
+	bo.PutUint32(dat[off+76:], 1)
+	dat = append(dat, relocs...)
+
+	f, err = NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []types.Reloc{{Addr: 0x18, Value: 3, Len: 3, Extern: true}}; !reflect.DeepEqual(f.Dysymtab.ExternalRelocs, want) {
+		t.Errorf("ExternalRelocs = %+v, want %+v", f.Dysymtab.ExternalRelocs, want)
+	}
+	if want := []types.Reloc{{Addr: 0x20, Value: 2, Len: 3}}; !reflect.DeepEqual(f.Dysymtab.LocalRelocs, want) {
+		t.Errorf("LocalRelocs = %+v, want %+v", f.Dysymtab.LocalRelocs, want)
+	}
+
+	// a lazy File reads them on first access
+	lf, err := NewFile(bytes.NewReader(dat), FileConfig{Lazy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lf.Dysymtab.ExternalRelocs != nil || lf.Dysymtab.LocalRelocs != nil {
+		t.Error("NewFile read the relocations of a lazy File")
+	}
+	if ext, err := lf.ExternalRelocations(); err != nil || !reflect.DeepEqual(ext, f.Dysymtab.ExternalRelocs) {
+		t.Errorf("ExternalRelocations() = %+v, %v, want %+v", ext, err, f.Dysymtab.ExternalRelocs)
+	}
+	if loc, err := lf.LocalRelocations(); err != nil || !reflect.DeepEqual(loc, f.Dysymtab.LocalRelocs) {
+		t.Errorf("LocalRelocations() = %+v, %v, want %+v", loc, err, f.Dysymtab.LocalRelocs)
+	}
+
+	bo.PutUint32(dat[off+68:], 0x1000) // past the end of the file
+	if _, err := NewFile(bytes.NewReader(dat)); err == nil {
+		t.Error("NewFile should fail for external relocations past the end of the file")
+	}
+	if lf, err = NewFile(bytes.NewReader(dat), FileConfig{Lazy: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lf.ExternalRelocations(); err == nil {
+		t.Error("ExternalRelocations() should fail for external relocations past the end of the file")
+	}
+}
+
+func TestDysymtabDylibTables(t *testing.T) {
+	dat, err := obscuretestdata.ReadFile("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := uint32(types.FileHeaderSize64)
+	for _, l := range f.Loads {
+		if _, ok := l.(*Dysymtab); ok {
+			break
+		}
+		off += uint32(len(l.Raw()))
+	}
+	name := bytes.Index(dat[f.Symtab.Stroff:f.Symtab.Stroff+f.Symtab.Strsize], []byte("_main\x00"))
+	if name < 0 {
+		t.Fatal("_main is not in the string table")
+	}
+
+	// a single module defining symbol 2 and referencing symbol 3 (lazily)
+	bo := binary.LittleEndian
+	for len(dat)%8 != 0 {
+		dat = append(dat, 0)
+	}
+	toc := make([]byte, 8)
+	bo.PutUint32(toc[0:], 2)
+	mod := make([]byte, 56)
+	bo.PutUint32(mod[0:], uint32(name))
+	bo.PutUint32(mod[4:], 2)  // iextdefsym
+	bo.PutUint32(mod[8:], 1)  // nextdefsym
+	bo.PutUint32(mod[16:], 1) // nrefsym
+	bo.PutUint32(mod[44:], 0x10)
+	bo.PutUint64(mod[48:], 0x2000)
+	lazy := uint8(types.REFERENCE_FLAG_UNDEFINED_LAZY)
+	ref := make([]byte, 4)
+	bo.PutUint32(ref, 3|uint32(lazy)<<24)
+	bo.PutUint32(dat[off+32:], uint32(len(dat))) // tocoff
+	bo.PutUint32(dat[off+36:], 1)                // ntoc
+	bo.PutUint32(dat[off+40:], uint32(len(dat))+8)
+	bo.PutUint32(dat[off+44:], 1)
+	bo.PutUint32(dat[off+48:], uint32(len(dat))+8+56)
+	bo.PutUint32(dat[off+52:], 1)
+	dat = append(dat, toc...)
+	dat = append(dat, mod...)
+	dat = append(dat, ref...)
+
+	f, err = NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []types.DylibTableOfContents{{SymbolIndex: 2}}; !reflect.DeepEqual(f.Dysymtab.TOC, want) {
+		t.Errorf("TOC = %+v, want %+v", f.Dysymtab.TOC, want)
+	}
+	want := []DylibModule{{Name: "_main", DylibModule64: types.DylibModule64{
+		ModuleName:         uint32(name),
+		Iextdefsym:         2,
+		Nextdefsym:         1,
+		Nrefsym:            1,
+		ObjcModuleInfoSize: 0x10,
+		ObjcModuleInfoAddr: 0x2000,
+	}}}
+	if !reflect.DeepEqual(f.Dysymtab.Modules, want) {
+		t.Errorf("Modules = %+v, want %+v", f.Dysymtab.Modules, want)
+	}
+	if len(f.Dysymtab.ExtRefSyms) != 1 {
+		t.Fatalf("ExtRefSyms = %v", f.Dysymtab.ExtRefSyms)
+	}
+	if r := f.Dysymtab.ExtRefSyms[0]; r.SymIndex() != 3 || r.Flags() != lazy {
+		t.Errorf("ExtRefSyms[0] = (%d, %#x), want (3, %#x)", r.SymIndex(), r.Flags(), lazy)
+	}
+
+	// a lazy File reads them on first access
+	lf, err := NewFile(bytes.NewReader(dat), FileConfig{Lazy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lf.Dysymtab.TOC != nil || lf.Dysymtab.Modules != nil || lf.Dysymtab.ExtRefSyms != nil {
+		t.Error("NewFile read the dylib tables of a lazy File")
+	}
+	if toc, err := lf.DylibTableOfContents(); err != nil || !reflect.DeepEqual(toc, f.Dysymtab.TOC) {
+		t.Errorf("DylibTableOfContents() = %+v, %v, want %+v", toc, err, f.Dysymtab.TOC)
+	}
+	if mods, err := lf.DylibModules(); err != nil || !reflect.DeepEqual(mods, want) {
+		t.Errorf("DylibModules() = %+v, %v, want %+v", mods, err, want)
+	}
+	if refs, err := lf.DylibReferences(); err != nil || !reflect.DeepEqual(refs, f.Dysymtab.ExtRefSyms) {
+		t.Errorf("DylibReferences() = %v, %v, want %v", refs, err, f.Dysymtab.ExtRefSyms)
+	}
+
+	bo.PutUint32(dat[off+44:], 0x1000) // past the end of the file
+	if _, err := NewFile(bytes.NewReader(dat)); err == nil {
+		t.Error("NewFile should fail for a module table past the end of the file")
+	}
+	if lf, err = NewFile(bytes.NewReader(dat), FileConfig{Lazy: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lf.DylibModules(); err == nil {
+		t.Error("DylibModules() should fail for a module table past the end of the file")
+	}
+}
+
+func TestSectionHeaderFlags(t *testing.T) {
+	tests := []struct {
+		flags                      types.SectionFlag
+		typ                        types.SectionFlag
+		zerofill, cstrings, stubs  bool
+		pointers, pure, hasInstrns bool
+	}{
+		{flags: types.Regular | types.PURE_INSTRUCTIONS | types.SOME_INSTRUCTIONS, typ: types.Regular, pure: true, hasInstrns: true},
+		{flags: types.SymbolStubs | types.PURE_INSTRUCTIONS | types.SOME_INSTRUCTIONS, typ: types.SymbolStubs, stubs: true, pure: true, hasInstrns: true},
+		{flags: types.CstringLiterals, typ: types.CstringLiterals, cstrings: true},
+		{flags: types.LazySymbolPointers, typ: types.LazySymbolPointers, pointers: true},
+		{flags: types.GbZerofill, typ: types.GbZerofill, zerofill: true},
+		{flags: types.ThreadLocalZerofill, typ: types.ThreadLocalZerofill, zerofill: true},
+		{flags: types.Regular | types.SOME_INSTRUCTIONS | types.LOC_RELOC, typ: types.Regular, hasInstrns: true},
+	}
+	for _, tt := range tests {
+		sh := types.SectionHeader{Flags: tt.flags}
+		if sh.SectionType() != tt.typ || sh.Attributes() != tt.flags&types.SectionAttributes {
+			t.Errorf("%#x: SectionType() = %#x, Attributes() = %#x", uint32(tt.flags), uint32(sh.SectionType()), uint32(sh.Attributes()))
+		}
+		if sh.IsZerofill() != tt.zerofill || sh.IsCstringLiterals() != tt.cstrings || sh.IsSymbolStubs() != tt.stubs ||
+			sh.IsSymbolPointers() != tt.pointers || sh.IsPureInstructions() != tt.pure || sh.HasInstructions() != tt.hasInstrns {
+			t.Errorf("%#x: zerofill=%t cstrings=%t stubs=%t pointers=%t pure=%t instructions=%t", uint32(tt.flags),
+				sh.IsZerofill(), sh.IsCstringLiterals(), sh.IsSymbolStubs(), sh.IsSymbolPointers(), sh.IsPureInstructions(), sh.HasInstructions())
+		}
+	}
+}
+
+func TestSegmentSections(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, sec := range f.Segment("__DATA").Sections() {
+		names = append(names, sec.Seg+"."+sec.Name)
+	}
+	if want := []string{"__DATA.__data", "__DATA.__dyld", "__DATA.__la_symbol_ptr"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("__DATA Sections() = %v, want %v", names, want)
+	}
+	if secs := f.Segment("__PAGEZERO").Sections(); secs != nil {
+		t.Errorf("__PAGEZERO Sections() = %v, want nil", secs)
+	}
+	if sec := f.Segment("__TEXT").Section("__cstring"); sec == nil || sec != f.Section("__TEXT", "__cstring") || sec.Seg != "__TEXT" {
+		t.Errorf("__TEXT Section(__cstring) = %v", sec)
+	}
+	if sec := f.Segment("__TEXT").Section("__data"); sec != nil {
+		t.Errorf("__TEXT Section(__data) = %v, want nil", sec)
+	}
+
+	// the sections of an object file are all in a single unnamed segment
+	obj, err := openObscured("internal/testdata/clang-amd64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if segs := obj.Segments(); len(segs) != 1 || len(segs[0].Sections()) != len(obj.Sections) {
+		t.Fatalf("Segments() = %v", segs)
+	}
+	if sec := obj.Section("__LD", "__compact_unwind"); sec == nil || sec != obj.Sections[2] {
+		t.Errorf("Section(__LD, __compact_unwind) = %v", sec)
+	}
+	if secs := obj.GetSectionsForSegment("__TEXT"); secs != nil {
+		t.Errorf("GetSectionsForSegment(__TEXT) = %v, want nil", secs)
+	}
+}
+
+func TestFileHeaderFlags(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.IsPIE() || !f.IsTwoLevel() || f.AllowsStackExecution() || f.HasNoReexportedDylibs() || f.IsDylibInCache() {
+		t.Fatalf("flags = %s", f.Flags)
+	}
+
+	f.SetPIE(false)
+	f.SetPIE(false) // clearing a cleared flag is a no-op
+	f.SetAllowStackExecution(true)
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.IsPIE() || !m.AllowsStackExecution() || !m.IsTwoLevel() {
+		t.Errorf("flags after SetPIE(false) and SetAllowStackExecution(true) = %s", m.Flags)
+	}
+	if want := types.NoUndefs | types.DyldLink | types.TwoLevel | types.AllowStackExecution; m.Flags != want {
+		t.Errorf("Flags = %s, want %s", m.Flags, want)
+	}
+}
+
+func TestCPUSubtypeCaps(t *testing.T) {
+	for _, tt := range []struct {
+		cpu   types.CPU
+		sub   types.CPUSubtype
+		name  string
+		caps  string
+		lib64 bool
+	}{
+		{types.CPUAmd64, types.CPUSubtypeX8664All | types.CpuSubtypeLib64, "x86_64", "LIB64", true},
+		{types.CPUAmd64, types.CPUSubtypeX86_64H, "x86_64 (Haswell)", "", false},
+		{types.CPUArm64, types.CPUSubtypeArm64E | 0x80000000, "ARM64e", "USR00", false},
+		{types.CPUArm64, types.CPUSubtypeArm64E | 0xc2000000, "ARM64e", "KER02", false},
+		{types.CPUArm64, types.CPUSubtypeArm64All, "ARM64", "", false},
+		{types.CPUArm, types.CPUSubtypeArmV7 | 0x01000000, "v7", "0x01", false},
+	} {
+		if got := tt.sub.String(tt.cpu); got != tt.name {
+			t.Errorf("%#x.String(%s) = %q; want %q", uint32(tt.sub), tt.cpu, got, tt.name)
+		}
+		if got := tt.sub.Capabilities(tt.cpu); got != tt.caps {
+			t.Errorf("%#x.Capabilities(%s) = %q; want %q", uint32(tt.sub), tt.cpu, got, tt.caps)
+		}
+		if got := tt.sub.IsLib64(tt.cpu); got != tt.lib64 {
+			t.Errorf("%#x.IsLib64(%s) = %t; want %t", uint32(tt.sub), tt.cpu, got, tt.lib64)
+		}
+		if tt.sub.Base()|tt.sub.Caps() != tt.sub || tt.sub.Base()&types.CpuSubtypeFeatureMask != 0 {
+			t.Errorf("%#x: Base() = %#x, Caps() = %#x", uint32(tt.sub), uint32(tt.sub.Base()), uint32(tt.sub.Caps()))
+		}
+	}
+
+	b := NewBuilder(types.MH_DYLIB, types.CPUArm64, types.CPUSubtypeArm64E|0x81000000)
+	b.InstallName = "/usr/lib/libfoo.dylib"
+	b.AddSection("__TEXT", "__text", []byte{0xc0, 0x03, 0x5f, 0xd6}, types.PURE_INSTRUCTIONS)
+	dat, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsArm64e() {
+		t.Error("IsArm64e() = false")
+	}
+	if version, kernel, ok := m.PtrAuthABIVersion(); version != 1 || kernel || !ok {
+		t.Errorf("PtrAuthABIVersion() = %d, %t, %t; want 1, false, true", version, kernel, ok)
+	}
+	if !strings.Contains(m.FileHeader.String(), "ARM64e caps: USR01") {
+		t.Errorf("FileHeader.String() = %s", m.FileHeader.String())
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := f.Platform(); p != types.Unknown || f.Platforms() != nil {
+		t.Errorf("Platform() = %s, want Unknown", p)
+	}
+
+	dat, err := obscuretestdata.ReadFile("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err = NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	macos, _ := types.GetPlatformByName("macos")
+	if p := f.Platform(); p != macos || f.IsSimulator() || f.IsMacCatalyst() || f.IsDriverKit() {
+		t.Errorf("Platform() = %s, want macOS", p)
+	}
+	// the legacy iOS load command of an x86_64 image is the simulator's
+	off := uint32(types.FileHeaderSize64)
+	for _, l := range f.Loads {
+		if l.Command() == types.LC_VERSION_MIN_MACOSX {
+			break
+		}
+		off += uint32(len(l.Raw()))
+	}
+	binary.LittleEndian.PutUint32(dat[off:], uint32(types.LC_VERSION_MIN_IPHONEOS))
+	if f, err = NewFile(bytes.NewReader(dat)); err != nil {
+		t.Fatal(err)
+	}
+	if sim, _ := types.GetPlatformByName("ios_simulator"); f.Platform() != sim || !f.IsSimulator() {
+		t.Errorf("Platform() = %s, want %s", f.Platform(), sim)
+	}
+	if targets := f.tbdTargets(); !reflect.DeepEqual(targets, []string{"x86_64-ios-simulator"}) {
+		t.Errorf("tbdTargets() = %v", targets)
+	}
+
+	for _, tt := range []struct {
+		platform         string
+		catalyst, drvkit bool
+	}{
+		{"maccatalyst", true, false},
+		{"driverkit", false, true},
+		{"ios", false, false},
+	} {
+		b := NewBuilder(types.MH_DYLIB, types.CPUArm64, types.CPUSubtypeArm64All)
+		b.InstallName = "/usr/lib/libfoo.dylib"
+		b.Platform, _ = types.GetPlatformByName(tt.platform)
+		b.AddSection("__TEXT", "__text", []byte{0xc0, 0x03, 0x5f, 0xd6}, types.PURE_INSTRUCTIONS)
+		dat, err := b.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := NewFile(bytes.NewReader(dat))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Platform() != b.Platform || m.IsMacCatalyst() != tt.catalyst || m.IsDriverKit() != tt.drvkit || m.IsSimulator() {
+			t.Errorf("%s: Platform() = %s, IsMacCatalyst() = %t, IsDriverKit() = %t, IsSimulator() = %t",
+				tt.platform, m.Platform(), m.IsMacCatalyst(), m.IsDriverKit(), m.IsSimulator())
+		}
+	}
+}
+
+func TestArchitecture(t *testing.T) {
+	for _, tt := range []struct {
+		cpu  types.CPU
+		sub  types.CPUSubtype
+		want string
+	}{
+		{types.CPUArm64, types.CPUSubtypeArm64All, "arm64"},
+		{types.CPUArm64, types.CPUSubtypeArm64E | 0x80000000, "arm64e"},
+		{types.CPUAmd64, types.CPUSubtypeX8664All | types.CpuSubtypeLib64, "x86_64"},
+		{types.CPUAmd64, types.CPUSubtypeX86_64H, "x86_64h"},
+		{types.CPUI386, types.CPUSubtypeX86All, "i386"},
+		{types.CPUArm, types.CPUSubtypeArmV7K, "armv7k"},
+		{types.CPUArm, types.CPUSubtypeArmV8, "armv8"},
+		{types.CPUArm6432, types.CPUSubtypeArm6432V8, "arm64_32"},
+	} {
+		h := types.FileHeader{CPU: tt.cpu, SubCPU: tt.sub}
+		if got := h.Architecture(); got != tt.want {
+			t.Errorf("%s/%#x Architecture() = %q; want %q", tt.cpu, uint32(tt.sub), got, tt.want)
+		}
+	}
+
+	ff, err := openFatObscured("internal/testdata/fat-gcc-386-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"i386", "x86_64"} {
+		if got := ff.Arches[i].Architecture(); got != want {
+			t.Errorf("Arches[%d].Architecture() = %q; want %q", i, got, want)
+		}
+		if got := ff.Arches[i].File.Architecture(); got != want {
+			t.Errorf("Arches[%d].File.Architecture() = %q; want %q", i, got, want)
+		}
+	}
+}
+
+func TestParseUUID(t *testing.T) {
+	f, err := openObscured("internal/testdata/clang-amd64-darwin-exec-with-rpath.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := f.UUID().UUID
+	for _, s := range []string{
+		want.String(),
+		strings.ToLower(want.String()),
+		strings.ReplaceAll(want.String(), "-", ""), // breakpad style
+	} {
+		u, err := types.ParseUUID(s)
+		if err != nil {
+			t.Fatalf("ParseUUID(%q) error = %v", s, err)
+		}
+		if !u.Equal(want) {
+			t.Errorf("ParseUUID(%q) = %s; want %s", s, u, want)
+		}
+	}
+	b := want.Bytes()
+	if !bytes.Equal(b, want[:]) {
+		t.Errorf("Bytes() = %x; want %x", b, want[:])
+	}
+	if b[0]++; b[0] == want[0] {
+		t.Error("Bytes() should return a copy")
+	}
+	for _, s := range []string{"", "not-a-uuid", want.String() + "0", strings.Replace(want.String(), want.String()[:2], "ZZ", 1)} {
+		if _, err := types.ParseUUID(s); err == nil {
+			t.Errorf("ParseUUID(%q) should fail", s)
+		}
+	}
+}
+
+func TestSrcVersion(t *testing.T) {
+	var sv types.SrcVersion
+	if err := sv.Set("1300.0.29.3"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (types.SrcVersionComponents{A: 1300, C: 29, D: 3}); sv.Components() != want {
+		t.Errorf("Components() = %+v; want %+v", sv.Components(), want)
+	}
+	if got := sv.String(); got != "1300.0.29.3.0" {
+		t.Errorf("String() = %s; want 1300.0.29.3.0", got)
+	}
+	if got := otoolSourceVersion(sv); got != "1300.0.29.3" {
+		t.Errorf("otoolSourceVersion() = %s; want 1300.0.29.3", got)
+	}
+	if v, err := sv.Components().Pack(); err != nil || v != sv {
+		t.Errorf("Pack() = %s, %v; want %s", v, err, sv)
+	}
+
+	var older, newer types.SrcVersion
+	if err := older.Set("1300"); err != nil {
+		t.Fatal(err)
+	}
+	if err := newer.Set("1300.1"); err != nil {
+		t.Fatal(err)
+	}
+	if sv.Compare(older) != 1 || sv.Compare(newer) != -1 || sv.Compare(sv) != 0 {
+		t.Errorf("Compare() of %s with %s and %s = %d, %d", sv, older, newer, sv.Compare(older), sv.Compare(newer))
+	}
+
+	for _, s := range []string{"", "1.2.3.4.5.6", "1.1024", "16777216", "1.a"} {
+		if err := sv.Set(s); err == nil {
+			t.Errorf("Set(%q) should fail", s)
+		}
+	}
+	if _, err := (types.SrcVersionComponents{A: 1, B: 1024}).Pack(); err == nil {
+		t.Error("Pack() of an out of range component should fail")
+	}
+}
+
+func TestCheckWX(t *testing.T) {
+	f, err := openObscured("internal/testdata/gcc-386-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, w, x := f.Segment("__TEXT").Perms(); !r || w || !x {
+		t.Errorf("__TEXT Perms() = %t, %t, %t; want true, false, true", r, w, x)
+	}
+	var got []string
+	for _, issue := range f.CheckWX() {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		"__TEXT: r-x (max rwx): max protection is writable and executable",
+		"__DATA: rw- (max rwx): max protection is writable and executable",
+		"__IMPORT: rwx (max rwx): writable and executable",
+		"__IMPORT.__jump_table: rwx (max rwx): writable and executable", // self-modifying i386 stubs
+		"__LINKEDIT: r-- (max rwx): max protection is writable and executable",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckWX() = %q\nwant %q", got, want)
+	}
+
+	b := NewBuilder(types.MH_DYLIB, types.CPUArm64, types.CPUSubtypeArm64All)
+	b.InstallName = "/usr/lib/libfoo.dylib"
+	b.AddSection("__TEXT", "__text", []byte{0xc0, 0x03, 0x5f, 0xd6}, types.PURE_INSTRUCTIONS)
+	b.AddSection("__DATA", "__data", make([]byte, 8), types.Regular)
+	dat, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := m.CheckWX(); len(issues) != 0 {
+		t.Errorf("CheckWX() = %v; want none", issues)
+	}
+	m.Segment("__DATA").Prot |= 4 // VM_PROT_EXECUTE
+	if issues := m.CheckWX(); len(issues) != 2 || issues[0].Kind != WritableExecutable || issues[1].Kind != ProtExceedsMax || issues[1].Segment != "__DATA" {
+		t.Errorf("CheckWX() = %v; want __DATA writable and executable and exceeding its max protection", issues)
+	}
+
+	obj, err := openObscured("internal/testdata/clang-amd64-darwin.obj.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := obj.CheckWX(); issues != nil {
+		t.Errorf("object file CheckWX() = %v; want nil", issues)
+	}
+}
+
+// coreMachO builds an x86_64 MH_CORE with two contiguous memory regions (the first one only partially
+// backed by the file), a third one after a gap and a LC_THREAD whose IP is 0x2008
+func coreMachO(t *testing.T) []byte {
+	t.Helper()
+	bo := binary.LittleEndian
+	regs := RegsAMD64{IP: 0x2008, SP: 0x1ff0}
+	const threadSize = 8 + 8 + 168
+	const cmdsSize = 3*72 + threadSize
+	dataOff := uint64(types.FileHeaderSize64 + cmdsSize)
+
+	var buf bytes.Buffer
+	binary.Write(&buf, bo, types.FileHeader{
+		Magic:        types.Magic64,
+		CPU:          types.CPUAmd64,
+		SubCPU:       types.CPUSubtypeX8664All,
+		Type:         types.MH_CORE,
+		NCommands:    4,
+		SizeCommands: cmdsSize,
+	})
+	for i, seg := range []struct{ addr, memsz, filesz uint64 }{
+		{0x1000, 0x1000, 0x10},
+		{0x2000, 0x10, 0x10},
+		{0x4000, 0x10, 0x10},
+	} {
+		binary.Write(&buf, bo, types.Segment64{
+			LoadCmd: types.LC_SEGMENT_64,
+			Len:     72,
+			Addr:    seg.addr,
+			Memsz:   seg.memsz,
+			Offset:  dataOff + uint64(i)*0x10,
+			Filesz:  seg.filesz,
+			Maxprot: types.VmProtection(7),
+			Prot:    types.VmProtection(3),
+		})
+	}
+	binary.Write(&buf, bo, []uint32{uint32(types.LC_THREAD), threadSize, uint32(types.X86_THREAD_STATE64), 42})
+	binary.Write(&buf, bo, regs)
+	for i := 0; i < 3*0x10; i++ {
+		buf.WriteByte(byte(i))
+	}
+	return buf.Bytes()
+}
+
+func TestCoreFile(t *testing.T) {
+	f, err := NewFile(bytes.NewReader(coreMachO(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.IsCore() {
+		t.Fatal("IsCore() = false")
+	}
+	if segs := f.CoreSegments(); len(segs) != 3 || segs[0].Addr != 0x1000 || segs[2].Addr != 0x4000 {
+		t.Errorf("CoreSegments() = %v", segs)
+	}
+
+	threads, err := f.CoreThreads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(threads) != 1 {
+		t.Fatalf("CoreThreads() = %v", threads)
+	}
+	if pc, ok := threads[0].PC(); !ok || pc != 0x2008 {
+		t.Errorf("PC() = %#x, %t, want 0x2008", pc, ok)
+	}
+	if regs, ok := threads[0].Regs[0].(*RegsAMD64); !ok || regs.SP != 0x1ff0 {
+		t.Errorf("Regs = %+v", threads[0].Regs)
+	}
+
+	tests := []struct {
+		addr, size uint64
+		want       []byte
+	}{
+		{0x1004, 4, []byte{4, 5, 6, 7}},
+		{0x100e, 4, []byte{0xe, 0xf, 0, 0}},   // past the file size of the region
+		{0x1ffe, 4, []byte{0, 0, 0x10, 0x11}}, // spans two regions
+		{0x4000, 2, []byte{0x20, 0x21}},       // after a gap
+		{0x1000, 0, []byte{}},                 // empty read
+		{0x2008, 8, []byte{0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f}},
+	}
+	for _, tt := range tests {
+		got, err := f.ReadMemory(tt.addr, tt.size)
+		if err != nil {
+			t.Errorf("ReadMemory(%#x, %d): %v", tt.addr, tt.size, err)
+		} else if !bytes.Equal(got, tt.want) {
+			t.Errorf("ReadMemory(%#x, %d) = %x, want %x", tt.addr, tt.size, got, tt.want)
+		}
+	}
+	for _, tt := range []struct{ addr, size uint64 }{
+		{0x800, 4},                 // before the first region
+		{0x200c, 8},                // into the gap
+		{0x4008, 0x10},             // past the last region
+		{0xfffffffffffffff0, 0x20}, // overflow
+	} {
+		if _, err := f.ReadMemory(tt.addr, tt.size); err == nil {
+			t.Errorf("ReadMemory(%#x, %d) should fail", tt.addr, tt.size)
+		}
+	}
+}
+
+func TestDecodeThreadState(t *testing.T) {
+	bo := binary.LittleEndian
+	var buf bytes.Buffer
+	binary.Write(&buf, bo, []uint32{uint32(types.ARM_THREAD_STATE64), 68}) // arm_state_hdr
+	binary.Write(&buf, bo, RegsARM64{PC: 0x100004000, LR: 0x100003000})
+	unified := types.ThreadState{Flavor: types.ARM_UNIFIED_THREAD_STATE, Data: buf.Bytes()}
+
+	regs, err := DecodeThreadState(types.CPUArm64, bo, unified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := regs.(*RegsARM64); !ok || r.PC != 0x100004000 || r.LR != 0x100003000 {
+		t.Errorf("DecodeThreadState() = %+v", regs)
+	}
+	if regs, err := DecodeThreadState(types.CPUArm64, bo, types.ThreadState{Flavor: types.ARM_EXCEPTION_STATE64, Data: make([]byte, 16)}); err != nil || regs != nil {
+		t.Errorf("DecodeThreadState() of an exception state = %v, %v, want nil", regs, err)
+	}
+	for _, state := range []types.ThreadState{
+		{Flavor: types.ARM_UNIFIED_THREAD_STATE, Data: make([]byte, 4)},   // truncated header
+		{Flavor: types.ARM_UNIFIED_THREAD_STATE, Data: buf.Bytes()[:100]}, // truncated registers
+		{Flavor: types.ARM_THREAD_STATE64, Data: make([]byte, 8)},
+	} {
+		if _, err := DecodeThreadState(types.CPUArm64, bo, state); err == nil {
+			t.Errorf("DecodeThreadState() of a %d byte %d state should fail", len(state.Data), state.Flavor)
+		}
+	}
+	if _, err := DecodeThreadState(types.CPUPpc, bo, unified); err == nil {
+		t.Error("DecodeThreadState() should fail for an unsupported CPU")
+	}
+}
+
+func TestDumpObjC(t *testing.T) {
+	b := NewBuilder(types.MH_EXECUTE, types.CPUAmd64, types.CPUSubtypeX8664All)
+	text := b.AddSection("__TEXT", "__text", []byte{0xc3, 0xc3, 0xc3}, types.PURE_INSTRUCTIONS|types.SOME_INSTRUCTIONS)
+	classname := b.AddSection("__TEXT", "__objc_classname", []byte("Foo\x00"), types.CstringLiterals)
+	methname := b.AddSection("__TEXT", "__objc_methname", []byte("bar\x00new\x00"), types.CstringLiterals)
+	methtype := b.AddSection("__TEXT", "__objc_methtype", []byte("v16@0:8\x00@16@0:8\x00"), types.CstringLiterals)
+	classlist := b.AddSection("__DATA", "__objc_classlist", make([]byte, 8), types.Regular)
+	data := b.AddSection("__DATA", "__objc_data", make([]byte, 2*48), types.Regular)
+	cnst := b.AddSection("__DATA", "__objc_const", make([]byte, 2*72+2*32), types.Regular)
+	b.AddSection("__DATA", "__objc_imageinfo", make([]byte, 8), types.Regular)
+	b.SetEntryPoint("__TEXT", "__text", 0)
+	if _, err := b.Build(); err != nil { // lay out the sections
+		t.Fatal(err)
+	}
+
+	// the root class Foo (-bar) and its metaclass (+new)
+	put := func(dat []byte, vals ...uint64) {
+		for i, v := range vals {
+			binary.LittleEndian.PutUint64(dat[i*8:], v)
+		}
+	}
+	class, meta := data.Addr, data.Addr+48
+	classRO, metaRO := cnst.Addr, cnst.Addr+72
+	classMethods, metaMethods := cnst.Addr+2*72, cnst.Addr+2*72+32
+	put(classlist.Data, class)
+	put(data.Data, meta, 0, 0, 0, classRO)
+	put(data.Data[48:], meta, class, 0, 0, metaRO)
+	for i, ro := range []struct {
+		flags   objc.ClassRoFlags
+		size    uint64
+		methods uint64
+	}{
+		{objc.RO_ROOT, 8, classMethods},
+		{objc.RO_ROOT | objc.RO_META, 40, metaMethods},
+	} {
+		binary.LittleEndian.PutUint32(cnst.Data[i*72:], uint32(ro.flags))
+		put(cnst.Data[i*72+8:], ro.size, 0, classname.Addr, ro.methods)
+	}
+	for i, m := range [][2]uint64{{methname.Addr, methtype.Addr}, {methname.Addr + 4, methtype.Addr + 8}} {
+		off := 2*72 + i*32
+		binary.LittleEndian.PutUint32(cnst.Data[off:], 24) // entsize
+		binary.LittleEndian.PutUint32(cnst.Data[off+4:], 1)
+		put(cnst.Data[off+8:], m[0], m[1], text.Addr+uint64(i))
+	}
+
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := f.DumpObjC(false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"@class Foo;\n", "@interface Foo : <ROOT>\n", "-[Foo bar];\n", "@end\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DumpObjC() = %q, want it to contain %q", out, want)
+		}
+	}
+	out, err = f.DumpObjC(true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "@class Foo;\n\n" +
+		"@interface Foo : <ROOT>\n" +
+		"/* class methods */\n" +
+		"+ (id)new;\n\n" +
+		"/* instance methods */\n" +
+		"- (void)bar;\n" +
+		"@end\n\n"
+	if out != want {
+		t.Errorf("DumpObjC(verbose) = %q, want %q", out, want)
+	}
+	if out, err = f.DumpObjC(true, true); err != nil || !strings.Contains(out, fmt.Sprintf("// %#x\n- (void)bar;", text.Addr)) {
+		t.Errorf("DumpObjC(addrs) = %q, %v", out, err)
+	}
+
+	nf, err := openObscured("internal/testdata/gcc-amd64-darwin-exec.base64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nf.DumpObjC(false, false); !errors.Is(err, ErrObjcSectionNotFound) {
+		t.Errorf("DumpObjC() without ObjC metadata error = %v, want %v", err, ErrObjcSectionNotFound)
+	}
+}
+
+func TestUmbrellaLoads(t *testing.T) {
+	dat := emptyMachO(t)
+	dat = addLoadCmd(dat, loadCmd(types.LC_SUB_FRAMEWORK, []uint32{12}, []byte("UIKit\x00")))
+	dat = addLoadCmd(dat, loadCmd(types.LC_SUB_UMBRELLA, []uint32{12}, []byte("UIKitCore\x00")))
+	dat = addLoadCmd(dat, loadCmd(types.LC_SUB_LIBRARY, []uint32{12}, []byte("libfoo\x00")))
+	dat = addLoadCmd(dat, loadCmd(types.LC_SUB_CLIENT, []uint32{12}, []byte("Foo\x00")))
+	dat = addLoadCmd(dat, loadCmd(types.LC_SUB_CLIENT, []uint32{12}, []byte("Bar\x00")))
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.SubFramework(); got != "UIKit" {
+		t.Errorf("SubFramework() = %q, want UIKit", got)
+	}
+	if got := f.SubUmbrellas(); !reflect.DeepEqual(got, []string{"UIKitCore"}) {
+		t.Errorf("SubUmbrellas() = %q", got)
+	}
+	if got := f.SubLibraries(); !reflect.DeepEqual(got, []string{"libfoo"}) {
+		t.Errorf("SubLibraries() = %q", got)
+	}
+	if got := f.SubClients(); !reflect.DeepEqual(got, []string{"Foo", "Bar"}) {
+		t.Errorf("SubClients() = %q", got)
+	}
+
+	nf, err := NewFile(bytes.NewReader(emptyMachO(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nf.SubFramework() != "" || nf.SubUmbrellas() != nil || nf.SubLibraries() != nil || nf.SubClients() != nil {
+		t.Error("umbrella accessors of a MachO without umbrella load commands should be empty")
+	}
+
+	dat = addLoadCmd(emptyMachO(t), loadCmd(types.LC_SUB_UMBRELLA, []uint32{0x40}, nil))
+	var fe *FormatError
+	if _, err := NewFile(bytes.NewReader(dat)); !errors.As(err, &fe) || !strings.Contains(err.Error(), "invalid umbrella") {
+		t.Errorf("NewFile() of an out of bounds sub-umbrella name error = %v", err)
+	}
+}
+
+func TestTwolevelHints(t *testing.T) {
+	dat := emptyMachO(t)
+	hints := make([]byte, 8)
+	binary.LittleEndian.PutUint32(hints[0:], 2|5<<8) // sub-image 2, table of contents entry 5
+	binary.LittleEndian.PutUint32(hints[4:], 0|9<<8)
+	hoff := uint32(len(dat))
+	dat = addLoadCmd(dat, loadCmd(types.LC_TWOLEVEL_HINTS, []uint32{hoff, 2}, nil))
+	dat = append(dat, hints...)
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var th *TwolevelHints
+	for _, l := range f.Loads {
+		if v, ok := l.(*TwolevelHints); ok {
+			th = v
+		}
+	}
+	if th == nil {
+		t.Fatal("missing LC_TWOLEVEL_HINTS")
+	}
+	if th.Offset != hoff || th.NumHints != 2 || len(th.Hints) != 2 {
+		t.Fatalf("TwolevelHints = %+v", th)
+	}
+	if h := th.Hints[0]; h.SubImageIndex() != 2 || h.TableOfContentsIndex() != 5 {
+		t.Errorf("Hints[0] = (%d, %d), want (2, 5)", h.SubImageIndex(), h.TableOfContentsIndex())
+	}
+	if h := th.Hints[1]; h.SubImageIndex() != 0 || h.TableOfContentsIndex() != 9 {
+		t.Errorf("Hints[1] = (%d, %d), want (0, 9)", h.SubImageIndex(), h.TableOfContentsIndex())
+	}
+	if th.LoadSize() != 16 {
+		t.Errorf("LoadSize() = %d, want 16 (the hints aren't part of the command)", th.LoadSize())
+	}
+
+	// on big endian (PPC) MachOs isub_image is the high byte
+	be := make([]byte, types.FileHeaderSize32+16, types.FileHeaderSize32+16+8)
+	for i, v := range []uint32{uint32(types.Magic32), uint32(types.CPUPpc), 0, uint32(types.MH_EXECUTE), 1, 16, 0} {
+		binary.BigEndian.PutUint32(be[i*4:], v)
+	}
+	for i, v := range []uint32{uint32(types.LC_TWOLEVEL_HINTS), 16, types.FileHeaderSize32 + 16, 2} {
+		binary.BigEndian.PutUint32(be[types.FileHeaderSize32+i*4:], v)
+	}
+	be = binary.BigEndian.AppendUint32(be, 2<<24|5)
+	be = binary.BigEndian.AppendUint32(be, 0<<24|9)
+	bf, err := NewFile(bytes.NewReader(be))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range bf.Loads {
+		if v, ok := l.(*TwolevelHints); ok {
+			th = v
+		}
+	}
+	if len(th.Hints) != 2 || th.Hints[0].SubImageIndex() != 2 || th.Hints[0].TableOfContentsIndex() != 5 ||
+		th.Hints[1].SubImageIndex() != 0 || th.Hints[1].TableOfContentsIndex() != 9 {
+		t.Errorf("big endian Hints = %+v, want [(2, 5) (0, 9)]", th.Hints)
+	}
+
+	// a hint table past the end of the file
+	dat = addLoadCmd(emptyMachO(t), loadCmd(types.LC_TWOLEVEL_HINTS, []uint32{hoff, 0x1000}, nil))
+	if _, err := NewFile(bytes.NewReader(dat)); err == nil {
+		t.Error("NewFile() should fail for a hint table past the end of the file")
+	}
+}
+
+func TestPreboundDylib(t *testing.T) {
+	// libfoo with 10 modules of which 0, 3 and 9 are linked
+	data := append([]byte("libfoo.dylib\x00\x00\x00\x00"), 0b00001001, 0b00000010)
+	dat := addLoadCmd(emptyMachO(t), loadCmd(types.LC_PREBOUND_DYLIB, []uint32{20, 10, 20 + 16}, data))
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pd *PreboundDylib
+	for _, l := range f.Loads {
+		if v, ok := l.(*PreboundDylib); ok {
+			pd = v
+		}
+	}
+	if pd == nil {
+		t.Fatal("missing LC_PREBOUND_DYLIB")
+	}
+	if pd.Name != "libfoo.dylib" || pd.NumModules != 10 {
+		t.Errorf("PreboundDylib = %+v", pd)
+	}
+	want := []bool{true, false, false, true, false, false, false, false, false, true}
+	if got := pd.LinkedModules(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LinkedModules() = %v, want %v", got, want)
+	}
+	if n := pd.LinkedModuleCount(); n != 3 {
+		t.Errorf("LinkedModuleCount() = %d, want 3", n)
+	}
+	if pd.IsModuleLinked(10) || pd.IsModuleLinked(15) {
+		t.Error("IsModuleLinked() should be false past the number of modules")
+	}
+
+	// a bit vector that extends past the command
+	dat = addLoadCmd(emptyMachO(t), loadCmd(types.LC_PREBOUND_DYLIB, []uint32{20, 100, 20 + 16}, data))
+	if _, err := NewFile(bytes.NewReader(dat)); err == nil {
+		t.Error("NewFile() should fail for a linked modules bit vector past the end of the command")
+	}
+}
+
+func TestInitRoutine(t *testing.T) {
+	f, err := NewFile(bytes.NewReader(emptyMachO(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := f.InitRoutine(); ok {
+		t.Error("InitRoutine() of a MachO without LC_ROUTINES_64 should not be ok")
+	}
+
+	// init routine at 0x100000f00 in module 3 (with a reserved field set)
+	dat := addLoadCmd(emptyMachO(t), loadCmd(types.LC_ROUTINES_64, []uint32{0x00000f00, 1, 3, 0, 0, 0, 0x1234, 0, 0, 0, 0, 0, 0, 0, 0, 0}, nil))
+	if f, err = NewFile(bytes.NewReader(dat)); err != nil {
+		t.Fatal(err)
+	}
+	if addr, mod, ok := f.InitRoutine(); !ok || addr != 0x100000f00 || mod != 3 {
+		t.Errorf("InitRoutine() = %#x, %d, %t, want 0x100000f00, 3, true", addr, mod, ok)
+	}
+	for _, l := range f.Loads {
+		if r, ok := l.(*Routines64); ok {
+			if r.Reserved2 != 0x1234 || r.Len != 72 {
+				t.Errorf("Routines64 = %+v, want the full command", r.Routines64Cmd)
+			}
+		}
+	}
+}
+
+func TestIdent(t *testing.T) {
+	lc := loadCmd(types.LC_IDENT, nil, []byte("foo\x00barbaz\x00"))
+	dat := addLoadCmd(emptyMachO(t), lc)
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var id *Ident
+	for _, l := range f.Loads {
+		if v, ok := l.(*Ident); ok {
+			id = v
+		}
+	}
+	if id == nil {
+		t.Fatal("missing LC_IDENT")
+	}
+	if want := []string{"foo", "barbaz"}; !reflect.DeepEqual(id.StrTable, want) {
+		t.Errorf("StrTable = %q, want %q", id.StrTable, want)
+	}
+	if id.LoadSize() != 20 {
+		t.Errorf("LoadSize() = %d, want 20", id.LoadSize())
+	}
+}
+
+func TestLazyLoadedLibraries(t *testing.T) {
+	dat := emptyMachO(t)
+	dat = addLoadCmd(dat, loadCmd(types.LC_LAZY_LOAD_DYLIB, []uint32{24, 2, 0x10000, 0x10000}, []byte("/usr/lib/libz.1.dylib\x00")))
+	dat = addLoadCmd(dat, loadCmd(types.LC_LOAD_DYLIB, []uint32{24, 2, 0x10000, 0x10000}, []byte("/usr/lib/libc++.1.dylib\x00")))
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.LazyLoadedLibraries(); !reflect.DeepEqual(got, []string{"/usr/lib/libz.1.dylib"}) {
+		t.Errorf("LazyLoadedLibraries() = %q", got)
+	}
+
+	dat = addLoadCmd(emptyMachO(t), loadCmd(types.LC_LAZY_LOAD_DYLIB, []uint32{0x100, 2, 0x10000, 0x10000}, nil))
+	if _, err := NewFile(bytes.NewReader(dat)); err == nil || !strings.Contains(err.Error(), "lazy load dylib") {
+		t.Errorf("NewFile() of an out of bounds lazy load dylib name error = %v", err)
+	}
+}
+
+func TestBuildVersionTools(t *testing.T) {
+	const minos, sdk, ld = 14 << 16, 14<<16 | 2<<8, 3
+	dat := addLoadCmd(emptyMachO(t), loadCmd(types.LC_BUILD_VERSION, []uint32{1, minos, sdk, 1, ld, 1015 << 16}, nil))
+	f, err := NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bv := f.BuildVersion()
+	if bv == nil {
+		t.Fatal("missing LC_BUILD_VERSION")
+	}
+	if want := []types.BuildVersionTool{{Tool: ld, Version: 1015 << 16}}; bv.NumTools != 1 || !reflect.DeepEqual(bv.Tools, want) {
+		t.Errorf("BuildVersion() tools = %d %v, want %v", bv.NumTools, bv.Tools, want)
+	}
+
+	// the tool count follows the tools when the command is written
+	bv.Tools = append(bv.Tools, types.BuildVersionTool{Tool: 1, Version: 1500 << 16})
+	var buf bytes.Buffer
+	if err := bv.Write(&buf, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	if n := binary.LittleEndian.Uint32(buf.Bytes()[20:]); n != 2 || bv.NumTools != 2 {
+		t.Errorf("written tool count = %d (NumTools = %d), want 2", n, bv.NumTools)
+	}
+	if uint32(buf.Len()) != bv.LoadSize() {
+		t.Errorf("written %d bytes, LoadSize() = %d", buf.Len(), bv.LoadSize())
+	}
+
+	// a tool count larger than the command
+	dat = addLoadCmd(emptyMachO(t), loadCmd(types.LC_BUILD_VERSION, []uint32{1, minos, sdk, 100, ld, 1015 << 16}, nil))
+	var fe *FormatError
+	if _, err := NewFile(bytes.NewReader(dat)); !errors.As(err, &fe) {
+		t.Errorf("NewFile() of an LC_BUILD_VERSION with too many tools error = %v, want a FormatError", err)
+	}
 }
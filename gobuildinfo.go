@@ -0,0 +1,142 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/blacktop/go-macho/internal/saferio"
+)
+
+// ErrGoBuildInfoNotFound is returned from GoBuildInfo when the MachO has no Go build info
+var ErrGoBuildInfoNotFound = errors.New("Go build info not found")
+
+var goBuildInfoMagic = []byte("\xff Go buildinf:")
+
+const (
+	goBuildInfoAlign      = 16
+	goBuildInfoHeaderSize = 32
+	goBuildInfoFlagBE     = 0x1 // pointers are big-endian
+	goBuildInfoFlagInline = 0x2 // version and module strings follow the header (go1.18+)
+)
+
+// findGoBuildInfo returns the Go build info blob (starting at the magic)
+func (f *File) findGoBuildInfo() ([]byte, error) {
+	search := func(dat []byte) []byte {
+		for off := 0; off+goBuildInfoHeaderSize <= len(dat); off += goBuildInfoAlign {
+			if bytes.HasPrefix(dat[off:], goBuildInfoMagic) {
+				return dat[off:]
+			}
+		}
+		return nil
+	}
+	if sec := f.Section("__DATA", "__go_buildinfo"); sec != nil {
+		dat, err := sec.Data()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+		}
+		if blob := search(dat); blob != nil {
+			return blob, nil
+		}
+	}
+	// older Go linkers put the build info at the start of the writable data
+	for _, sec := range f.Sections {
+		if sec.Flags.IsZerofill() || (sec.Seg != "__DATA" && sec.Seg != "__DATA_CONST") {
+			continue
+		}
+		dat, err := sec.Data()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+		}
+		if blob := search(dat); blob != nil {
+			return blob, nil
+		}
+	}
+	return nil, ErrGoBuildInfoNotFound
+}
+
+// GoBuildInfo returns the Go version, main module and dependencies embedded in a Go binary (like `go version -m`)
+func (f *File) GoBuildInfo() (*debug.BuildInfo, error) {
+	blob, err := f.findGoBuildInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	var vers, mod string
+	ptrSize := int(blob[14])
+	flags := blob[15]
+	if flags&goBuildInfoFlagInline != 0 {
+		dat := blob[goBuildInfoHeaderSize:]
+		if vers, dat = decodeGoString(dat); vers == "" {
+			return nil, fmt.Errorf("failed to read Go version from build info")
+		}
+		mod, _ = decodeGoString(dat)
+	} else {
+		var bo binary.ByteOrder = binary.LittleEndian
+		if flags&goBuildInfoFlagBE != 0 {
+			bo = binary.BigEndian
+		}
+		if ptrSize != 4 && ptrSize != 8 {
+			return nil, fmt.Errorf("invalid Go build info pointer size %d", ptrSize)
+		}
+		readPtr := func(b []byte) uint64 {
+			if ptrSize == 4 {
+				return uint64(bo.Uint32(b))
+			}
+			return bo.Uint64(b)
+		}
+		// the header holds pointers to the Go string headers (data pointer and length) of the version and module info
+		readString := func(addr uint64) (string, error) {
+			hdr := make([]byte, 2*ptrSize)
+			if _, err := f.vmr.ReadAtVMAddr(hdr, f.vma.Convert(addr)); err != nil {
+				return "", err
+			}
+			// the length is untrusted so bound it by the file data of the segment holding the string
+			saddr, n := f.vma.Convert(readPtr(hdr)), readPtr(hdr[ptrSize:])
+			seg := f.FindSegmentForVMAddr(saddr)
+			if seg == nil || seg.sr == nil || saddr-seg.Addr > seg.Filesz {
+				return "", fmt.Errorf("string data at %#x is not within a segment's file data", saddr)
+			}
+			if n > seg.Filesz-(saddr-seg.Addr) {
+				return "", fmt.Errorf("string length %#x at %#x extends past segment %s", n, saddr, seg.Name)
+			}
+			dat, err := saferio.ReadDataAt(seg.sr, n, int64(saddr-seg.Addr))
+			if err != nil {
+				return "", err
+			}
+			return string(dat), nil
+		}
+		if vers, err = readString(readPtr(blob[16:])); err != nil {
+			return nil, fmt.Errorf("failed to read Go version from build info: %v", err)
+		}
+		if mod, err = readString(readPtr(blob[16+ptrSize:])); err != nil {
+			return nil, fmt.Errorf("failed to read Go module info from build info: %v", err)
+		}
+	}
+
+	// the module info is wrapped in 16 byte sentinels
+	if len(mod) >= 33 && mod[len(mod)-17] == '\n' {
+		mod = mod[16 : len(mod)-16]
+	} else {
+		mod = ""
+	}
+
+	bi, err := debug.ParseBuildInfo(mod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Go build info: %v", err)
+	}
+	bi.GoVersion = vers
+
+	return bi, nil
+}
+
+// decodeGoString decodes a uvarint length-prefixed string
+func decodeGoString(dat []byte) (string, []byte) {
+	n, m := binary.Uvarint(dat)
+	if m <= 0 || n > uint64(len(dat)-m) {
+		return "", nil
+	}
+	return string(dat[m : m+int(n)]), dat[m+int(n):]
+}
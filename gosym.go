@@ -0,0 +1,132 @@
+package macho
+
+import (
+	"debug/gosym"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// ErrGoPclntabNotFound is returned from GoSymbols when the MachO has no Go pclntab
+var ErrGoPclntabNotFound = errors.New("Go pclntab not found")
+
+// goSymbolAddress returns the address of a Go runtime symbol (with or without the leading underscore)
+func (f *File) goSymbolAddress(name string) (uint64, bool) {
+	if f.Symtab == nil {
+		return 0, false
+	}
+	for _, sym := range f.symbols() {
+		if sym.Name == name || sym.Name == "_"+name {
+			return sym.Value, true
+		}
+	}
+	return 0, false
+}
+
+// goTable returns the parsed Go symbol table (cached)
+func (f *File) goTable() (*gosym.Table, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.gotab != nil {
+		return f.gotab, nil
+	}
+
+	var pclntab, symtab []byte
+	for _, sec := range f.Sections {
+		switch sec.Name {
+		case "__gopclntab":
+			dat, err := sec.Data()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+			}
+			pclntab = dat
+		case "__gosymtab":
+			dat, err := sec.Data()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+			}
+			symtab = dat
+		}
+	}
+	if pclntab == nil {
+		start, ok1 := f.goSymbolAddress("runtime.pclntab")
+		end, ok2 := f.goSymbolAddress("runtime.epclntab")
+		if !ok1 || !ok2 || end <= start {
+			return nil, ErrGoPclntabNotFound
+		}
+		pclntab = make([]byte, end-start)
+		if _, err := f.vmr.ReadAtVMAddr(pclntab, start); err != nil {
+			return nil, fmt.Errorf("failed to read runtime.pclntab: %v", err)
+		}
+	}
+
+	text, ok := f.goSymbolAddress("runtime.text")
+	if !ok {
+		if sec := f.Section("__TEXT", "__text"); sec != nil {
+			text = sec.Addr
+		}
+	}
+
+	tab, err := gosym.NewTable(symtab, gosym.NewLineTable(pclntab, text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Go pclntab: %v", err)
+	}
+	f.gotab = tab
+
+	return f.gotab, nil
+}
+
+// GoSymbols returns the Go functions recovered from the __gopclntab section (or runtime.pclntab symbol) which
+// works on stripped Go binaries; once loaded FindSymbolAddress, FindAddressSymbols and FunctionForAddress
+// fallback to them
+func (f *File) GoSymbols() ([]Symbol, error) {
+	tab, err := f.goTable()
+	if err != nil {
+		return nil, err
+	}
+	var sect uint8
+	for i, sec := range f.Sections {
+		if sec.Seg == "__TEXT" && sec.Name == "__text" {
+			sect = uint8(i + 1)
+		}
+	}
+	syms := make([]Symbol, 0, len(tab.Funcs))
+	for _, fn := range tab.Funcs {
+		syms = append(syms, Symbol{
+			Name:  fn.Name,
+			Type:  types.N_SECT | types.N_EXT,
+			Sect:  sect,
+			Value: fn.Entry,
+		})
+	}
+	return syms, nil
+}
+
+// loadedGoTable returns the Go symbol table if GoSymbols was called
+func (f *File) loadedGoTable() *gosym.Table {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.gotab
+}
+
+func (f *File) goSymbolForAddress(addr uint64) (*gosym.Func, bool) {
+	if tab := f.loadedGoTable(); tab != nil {
+		if fn := tab.PCToFunc(addr); fn != nil {
+			return fn, true
+		}
+	}
+	return nil, false
+}
+
+func (f *File) goSymbolByName(name string) (*gosym.Func, bool) {
+	if tab := f.loadedGoTable(); tab != nil {
+		for i, fn := range tab.Funcs {
+			if strings.EqualFold(fn.Name, name) {
+				return &tab.Funcs[i], true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,205 @@
+package macho
+
+import (
+	"fmt"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// An ImportedSymbol is a symbol imported from a dylib (see ImportedSymbolDetails)
+type ImportedSymbol struct {
+	Name    string
+	Ordinal int      // library ordinal (1 for the first dylib load command, or a types.BIND_SPECIAL_DYLIB_* ordinal)
+	Dylib   string   // path of the dylib (or the name of the special ordinal, e.g. "flat-namespace")
+	Weak    bool     // weak import (the symbol may be missing at runtime)
+	Binds   []uint64 // addresses of the pointers bound to the symbol
+}
+
+func (i ImportedSymbol) String() string {
+	var weak string
+	if i.Weak {
+		weak = " (weak)"
+	}
+	return fmt.Sprintf("%s from %s%s", i.Name, i.Dylib, weak)
+}
+
+// libraryOrdinalPath returns the path of the dylib with the library ordinal ord (or the name of the special ordinal)
+func (f *File) libraryOrdinalPath(ord int) string {
+	if dylibs := f.ImportedLibraries(); ord > 0 && ord <= len(dylibs) {
+		return dylibs[ord-1]
+	}
+	return f.LibraryOrdinalName(ord)
+}
+
+// importKey identifies an imported symbol (the same name may be imported from several dylibs)
+type importKey struct {
+	ord  int
+	name string
+}
+
+// ImportedSymbolDetails returns the symbols imported from dylibs with their dylib, weak import flag and
+// the addresses of the pointers bound to them (from the chained fixups, the dyld info binds or the symbol
+// pointer sections)
+func (f *File) ImportedSymbolDetails() ([]ImportedSymbol, error) {
+	var imps []ImportedSymbol
+	byKey := make(map[importKey]int)
+	add := func(name string, ord int, weak bool) int {
+		key := importKey{ord, name}
+		i, ok := byKey[key]
+		if !ok {
+			i = len(imps)
+			byKey[key] = i
+			imps = append(imps, ImportedSymbol{Name: name, Ordinal: ord})
+		}
+		imps[i].Weak = imps[i].Weak || weak
+		return i
+	}
+
+	if f.Symtab != nil && f.Dysymtab != nil {
+		syms, err := f.ImportedSymbols()
+		if err != nil {
+			return nil, err
+		}
+		for _, sym := range syms {
+			add(sym.Name, f.importOrdinal(sym), sym.Desc.IsWeakReferenced())
+		}
+	}
+
+	switch {
+	case f.HasDyldChainedFixups():
+		dcf, err := f.DyldChainedFixups()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dyld chained fixups: %v", err)
+		}
+		idx := make([]int, len(dcf.Imports))
+		for j, imp := range dcf.Imports {
+			idx[j] = add(imp.Name, imp.LibOrdinal(), imp.WeakImport())
+		}
+		for _, start := range dcf.Starts {
+			for _, bind := range start.Binds() {
+				if bind.Ordinal() >= uint64(len(dcf.Imports)) {
+					continue
+				}
+				addr, err := f.GetVMAddress(bind.Offset())
+				if err != nil {
+					return nil, fmt.Errorf("failed to get bind address: %v", err)
+				}
+				i := idx[bind.Ordinal()]
+				imps[i].Binds = append(imps[i].Binds, addr)
+			}
+		}
+	case f.DyldInfo() != nil || f.DyldInfoOnly() != nil:
+		binds, err := f.GetBindInfo()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bind info: %v", err)
+		}
+		for _, bind := range binds {
+			if bind.Kind == types.WEAK_KIND {
+				// weak definition coalescing binds have no dylib (they only apply to the imports of the same name)
+				for i := range imps {
+					if imps[i].Name == bind.Name {
+						imps[i].Binds = append(imps[i].Binds, bind.Start+bind.Offset)
+					}
+				}
+				continue
+			}
+			i := add(bind.Name, bind.Ordinal, bind.Flags&types.BIND_SYMBOL_FLAGS_WEAK_IMPORT != 0)
+			imps[i].Binds = append(imps[i].Binds, bind.Start+bind.Offset)
+		}
+	case f.Symtab != nil && f.Dysymtab != nil:
+		for _, sec := range f.Sections {
+			if !sec.IsSymbolPointers() {
+				continue
+			}
+			ptrSize := f.pointerSize()
+			for j := uint64(0); j < sec.Size/ptrSize; j++ {
+				sym, ok := f.indirectSymbol(uint64(sec.Reserved1) + j)
+				if !ok {
+					continue
+				}
+				if i, ok := byKey[importKey{f.importOrdinal(sym), sym.Name}]; ok {
+					imps[i].Binds = append(imps[i].Binds, sec.Addr+j*ptrSize)
+				}
+			}
+		}
+	default:
+		if len(imps) == 0 {
+			return nil, &FormatError{0, "missing symbol table", nil}
+		}
+	}
+
+	for i := range imps {
+		imps[i].Dylib = f.libraryOrdinalPath(imps[i].Ordinal)
+	}
+	return imps, nil
+}
+
+// importOrdinal returns the library ordinal the undefined symbol sym is imported from
+func (f *File) importOrdinal(sym Symbol) int {
+	if !f.Flags.TwoLevel() {
+		return types.BIND_SPECIAL_DYLIB_FLAT_LOOKUP
+	}
+	return symbolOrdinal(sym)
+}
+
+// An ImportedLibrary is a dylib dependency of a MachO (see ImportedLibraryDetails)
+type ImportedLibrary struct {
+	Path           string
+	Kind           types.LoadCmd // LC_LOAD_DYLIB, LC_LOAD_WEAK_DYLIB, LC_REEXPORT_DYLIB, LC_LOAD_UPWARD_DYLIB or LC_LAZY_LOAD_DYLIB
+	Ordinal        int           // library ordinal (1 for the first dylib dependency)
+	Timestamp      uint32
+	CurrentVersion types.Version
+	CompatVersion  types.Version
+}
+
+// Weak returns whether the dylib may be missing at runtime (LC_LOAD_WEAK_DYLIB)
+func (l ImportedLibrary) Weak() bool { return l.Kind == types.LC_LOAD_WEAK_DYLIB }
+
+// ReExport returns whether the dylib's symbols are re-exported (LC_REEXPORT_DYLIB)
+func (l ImportedLibrary) ReExport() bool { return l.Kind == types.LC_REEXPORT_DYLIB }
+
+// Upward returns whether the dylib is an upward dependency (LC_LOAD_UPWARD_DYLIB)
+func (l ImportedLibrary) Upward() bool { return l.Kind == types.LC_LOAD_UPWARD_DYLIB }
+
+// Lazy returns whether the dylib is loaded on first use (LC_LAZY_LOAD_DYLIB)
+func (l ImportedLibrary) Lazy() bool { return l.Kind == types.LC_LAZY_LOAD_DYLIB }
+
+func (l ImportedLibrary) String() string {
+	return fmt.Sprintf("%s (%s) (compatibility version %s, current version %s)", l.Path, l.Kind, l.CompatVersion, l.CurrentVersion)
+}
+
+// dependentDylib returns the Dylib of the dylib dependency load command l (nil if l isn't one)
+func dependentDylib(l Load) *Dylib {
+	switch v := l.(type) {
+	case *LoadDylib:
+		return &v.Dylib
+	case *WeakDylib:
+		return &v.Dylib
+	case *ReExportDylib:
+		return &v.Dylib
+	case *UpwardDylib:
+		return &v.Dylib
+	case *LazyLoadDylib:
+		return &v.Dylib
+	}
+	return nil
+}
+
+// ImportedLibraryDetails returns the dylib dependencies of f (in library ordinal order, see ImportedLibraries)
+// with their kind, versions and timestamps
+func (f *File) ImportedLibraryDetails() []ImportedLibrary {
+	var all []ImportedLibrary
+	for _, l := range f.Loads {
+		if d := dependentDylib(l); d != nil {
+			all = append(all, ImportedLibrary{
+				Path:           d.Name,
+				Kind:           d.LoadCmd,
+				Ordinal:        len(all) + 1,
+				Timestamp:      d.Timestamp,
+				CurrentVersion: d.CurrentVersion,
+				CompatVersion:  d.CompatVersion,
+			})
+		}
+	}
+	return all
+}
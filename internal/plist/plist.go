@@ -0,0 +1,177 @@
+// Package plist implements a minimal XML property list parser that also handles the IOKit flavor
+// (as used by the kernelcache __PRELINK_INFO) with ID/IDREF attributes and hex integers.
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Parse parses the XML property list dat. Dictionaries are returned as map[string]any, arrays as []any,
+// strings (and dates) as string, integers as uint64, reals as float64, booleans as bool and data as []byte
+func Parse(dat []byte) (any, error) {
+	p := &parser{
+		d:   xml.NewDecoder(bytes.NewReader(bytes.TrimRight(dat, "\x00"))),
+		ids: make(map[string]any),
+	}
+	for {
+		tok, err := p.d.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("plist has no value")
+			}
+			return nil, fmt.Errorf("failed to parse plist: %v", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return p.value(start)
+		}
+	}
+}
+
+type parser struct {
+	d   *xml.Decoder
+	ids map[string]any // the values with an ID attribute (referenced by IDREF attributes)
+}
+
+func attr(start xml.StartElement, name string) (string, bool) {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// value parses the value of the element start
+func (p *parser) value(start xml.StartElement) (any, error) {
+	if ref, ok := attr(start, "IDREF"); ok {
+		v, ok := p.ids[ref]
+		if !ok {
+			return nil, fmt.Errorf("plist IDREF %s references an unknown ID", ref)
+		}
+		return v, p.d.Skip()
+	}
+
+	var v any
+	var err error
+	switch start.Name.Local {
+	case "dict":
+		v, err = p.dict()
+	case "array":
+		v, err = p.array()
+	case "string", "date":
+		v, err = p.text()
+	case "integer":
+		var s string
+		if s, err = p.text(); err == nil {
+			v, err = parseInteger(strings.TrimSpace(s))
+		}
+	case "real":
+		var s string
+		if s, err = p.text(); err == nil {
+			v, err = strconv.ParseFloat(strings.TrimSpace(s), 64)
+		}
+	case "true", "false":
+		v, err = start.Name.Local == "true", p.d.Skip()
+	case "data":
+		var s string
+		if s, err = p.text(); err == nil {
+			v, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported plist element <%s>", start.Name.Local)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse plist <%s>: %v", start.Name.Local, err)
+	}
+	if id, ok := attr(start, "ID"); ok {
+		p.ids[id] = v
+	}
+	return v, nil
+}
+
+func (p *parser) dict() (map[string]any, error) {
+	m := make(map[string]any)
+	var key *string
+	for {
+		tok, err := p.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "key" {
+				k, err := p.text()
+				if err != nil {
+					return nil, err
+				}
+				key = &k
+				continue
+			}
+			if key == nil {
+				return nil, fmt.Errorf("dict value <%s> has no key", t.Name.Local)
+			}
+			v, err := p.value(t)
+			if err != nil {
+				return nil, err
+			}
+			m[*key] = v
+			key = nil
+		case xml.EndElement:
+			return m, nil
+		}
+	}
+}
+
+func (p *parser) array() ([]any, error) {
+	var a []any
+	for {
+		tok, err := p.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := p.value(t)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		case xml.EndElement:
+			return a, nil
+		}
+	}
+}
+
+// text returns the character data up to the end of the current element
+func (p *parser) text() (string, error) {
+	var sb strings.Builder
+	for {
+		tok, err := p.d.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			sb.Write(t)
+		case xml.StartElement:
+			return "", fmt.Errorf("unexpected element <%s>", t.Name.Local)
+		case xml.EndElement:
+			return sb.String(), nil
+		}
+	}
+}
+
+// parseInteger parses a (decimal or 0x prefixed hex, possibly negative) integer
+func parseInteger(s string) (uint64, error) {
+	if strings.HasPrefix(s, "-") {
+		i, err := strconv.ParseInt(s, 0, 64)
+		return uint64(i), err
+	}
+	return strconv.ParseUint(s, 0, 64)
+}
@@ -0,0 +1,124 @@
+package macho
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blacktop/go-macho/internal/plist"
+)
+
+// A Kext is a kext (kernel extension) in a kernelcache
+type Kext struct {
+	ID          string // CFBundleIdentifier
+	Name        string // CFBundleName
+	Version     string // CFBundleVersion
+	Path        string // _PrelinkBundlePath
+	LoadAddress uint64 // address of the kext's MachO header (zero for kexts without an executable)
+
+	offset uint64 // file offset of the kext's MachO header
+}
+
+func (k Kext) String() string {
+	if k.LoadAddress == 0 {
+		return fmt.Sprintf("%s (%s) (codeless)", k.ID, k.Version)
+	}
+	return fmt.Sprintf("%#016x %s (%s)", k.LoadAddress, k.ID, k.Version)
+}
+
+// prelinkInfo returns the kexts' info dictionaries from the __PRELINK_INFO plist
+func (f *File) prelinkInfo() ([]map[string]any, error) {
+	sec := f.Section("__PRELINK_INFO", "__info")
+	if sec == nil {
+		return nil, fmt.Errorf("no __PRELINK_INFO.__info section: %w", ErrMachOSectionNotFound)
+	}
+	dat := make([]byte, sec.Size)
+	if _, err := f.vmr.ReadAtVMAddr(dat, sec.Addr); err != nil {
+		return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+	}
+	v, err := plist.Parse(dat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s.%s plist: %v", sec.Seg, sec.Name, err)
+	}
+	info, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s plist is not a dictionary", sec.Seg, sec.Name)
+	}
+	arr, ok := info["_PrelinkInfoDictionary"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s plist has no _PrelinkInfoDictionary array", sec.Seg, sec.Name)
+	}
+	var dicts []map[string]any
+	for _, d := range arr {
+		if dict, ok := d.(map[string]any); ok {
+			dicts = append(dicts, dict)
+		}
+	}
+	return dicts, nil
+}
+
+// Kexts returns the kexts of a kernelcache, from its LC_FILESET_ENTRYs for MH_FILESET kernelcaches (with the
+// versions from its __PRELINK_INFO if it has one) or from the __PRELINK_INFO of prelinked kernelcaches
+func (f *File) Kexts() ([]Kext, error) {
+	infos, err := f.prelinkInfo()
+	fsets := f.FileSets()
+	if err != nil && len(fsets) == 0 {
+		return nil, err
+	}
+
+	var kexts []Kext
+	if len(fsets) > 0 {
+		byID := make(map[string]map[string]any)
+		for _, info := range infos {
+			if id, ok := info["CFBundleIdentifier"].(string); ok {
+				byID[id] = info
+			}
+		}
+		for _, fs := range fsets {
+			k := Kext{ID: fs.EntryID, LoadAddress: fs.Addr, offset: fs.FileOffset}
+			if info, ok := byID[fs.EntryID]; ok {
+				k.Name, _ = info["CFBundleName"].(string)
+				k.Version, _ = info["CFBundleVersion"].(string)
+				k.Path, _ = info["_PrelinkBundlePath"].(string)
+			}
+			kexts = append(kexts, k)
+		}
+		return kexts, nil
+	}
+
+	for _, info := range infos {
+		var k Kext
+		k.ID, _ = info["CFBundleIdentifier"].(string)
+		k.Name, _ = info["CFBundleName"].(string)
+		k.Version, _ = info["CFBundleVersion"].(string)
+		k.Path, _ = info["_PrelinkBundlePath"].(string)
+		k.LoadAddress, _ = info["_PrelinkExecutableLoadAddr"].(uint64)
+		if k.LoadAddress != 0 {
+			if k.offset, err = f.GetOffset(k.LoadAddress); err != nil {
+				return nil, fmt.Errorf("failed to get kext %s file offset: %v", k.ID, err)
+			}
+		}
+		kexts = append(kexts, k)
+	}
+	return kexts, nil
+}
+
+// GetKext returns the MachO of the kext with the bundle ID id in a kernelcache (see Kexts)
+func (f *File) GetKext(id string) (*File, error) {
+	kexts, err := f.Kexts()
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range kexts {
+		if strings.EqualFold(k.ID, id) {
+			if k.LoadAddress == 0 {
+				return nil, fmt.Errorf("kext %s has no executable", k.ID)
+			}
+			m, err := f.fileAt(k.offset)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse kext %s: %v", k.ID, err)
+			}
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("kernelcache does NOT contain kext %s", id)
+}
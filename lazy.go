@@ -0,0 +1,219 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/blacktop/go-macho/internal/saferio"
+	"github.com/blacktop/go-macho/types"
+)
+
+// readSymbols reads the symbols of the LC_SYMTAB hdr (at the load command offset)
+func (f *File) readSymbols(hdr *types.SymtabCmd, offset int64) ([]Symbol, error) {
+	if err := checkLimit("symbols", offset, hdr.Nsyms, f.limits.MaxSymbols); err != nil {
+		return nil, err
+	}
+	if err := checkLimit("string table bytes", offset, hdr.Strsize, f.limits.MaxStringTable); err != nil {
+		return nil, err
+	}
+	if err := f.checkTable("symbol table", uint64(hdr.Symoff), uint64(hdr.Nsyms), uint64(f.symbolSize())); err != nil {
+		return nil, err
+	}
+	if err := f.checkTable("string table", uint64(hdr.Stroff), uint64(hdr.Strsize), 1); err != nil {
+		return nil, err
+	}
+	strtab, err := saferio.ReadDataAt(f.cr, uint64(hdr.Strsize), int64(hdr.Stroff))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data at Stroff=%#x; %v", int64(hdr.Stroff), err)
+	}
+	symdat, err := saferio.ReadDataAt(f.cr, uint64(hdr.Nsyms)*uint64(f.symbolSize()), int64(hdr.Symoff))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data at Symoff=%#x; %v", int64(hdr.Symoff), err)
+	}
+	st, err := f.parseSymtab(symdat, strtab, nil, hdr, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parseSymtab: %v", err)
+	}
+	return st.Syms, nil
+}
+
+// readIndirectSymbols reads the indirect symbols of the LC_DYSYMTAB hdr
+func (f *File) readIndirectSymbols(hdr *types.DysymtabCmd) ([]uint32, error) {
+	if err := f.checkTable("indirect symbol table", uint64(hdr.Indirectsymoff), uint64(hdr.Nindirectsyms), 4); err != nil {
+		return nil, err
+	}
+	dat, err := saferio.ReadDataAt(f.cr, uint64(hdr.Nindirectsyms)*4, int64(hdr.Indirectsymoff))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data at Indirectsymoff @ %#x: %w", int64(hdr.Indirectsymoff), err)
+	}
+	x := make([]uint32, hdr.Nindirectsyms)
+	if err := binary.Read(bytes.NewReader(dat), f.ByteOrder, x); err != nil {
+		return nil, fmt.Errorf("failed to read Nindirectsyms: %v", err)
+	}
+	return x, nil
+}
+
+// Symbols returns the symbols of the LC_SYMTAB (reading them on first access if the File was opened with
+// FileConfig.Lazy) matching all of the filters, or nil if there is none
+func (f *File) Symbols(filters ...SymbolFilter) ([]Symbol, error) {
+	syms, err := f.loadSymbols()
+	if err != nil || len(filters) == 0 {
+		return syms, err
+	}
+	var matches []Symbol
+	for _, sym := range syms {
+		if matchFilters(f, sym, filters) {
+			matches = append(matches, sym)
+		}
+	}
+	return matches, nil
+}
+
+// loadSymbols returns the symbols of the LC_SYMTAB (reading them if they haven't been yet)
+func (f *File) loadSymbols() ([]Symbol, error) {
+	if f.Symtab == nil {
+		return nil, nil
+	}
+	f.lazyMu.Lock()
+	defer f.lazyMu.Unlock()
+	if f.lazySymtab {
+		syms, err := f.readSymbols(&f.Symtab.SymtabCmd, 0)
+		if err != nil {
+			return nil, err
+		}
+		f.Symtab.Syms = syms
+		f.lazySymtab = false
+	}
+	return f.Symtab.Syms, nil
+}
+
+// IndirectSymbols returns the indirect symbols of the LC_DYSYMTAB (reading them on first access if the File
+// was opened with FileConfig.Lazy), or nil if there is none
+func (f *File) IndirectSymbols() ([]uint32, error) {
+	if f.Dysymtab == nil {
+		return nil, nil
+	}
+	f.lazyMu.Lock()
+	defer f.lazyMu.Unlock()
+	if f.lazyIndirectSyms {
+		x, err := f.readIndirectSymbols(&f.Dysymtab.DysymtabCmd)
+		if err != nil {
+			return nil, err
+		}
+		f.Dysymtab.IndirectSyms = x
+		f.lazyIndirectSyms = false
+	}
+	return f.Dysymtab.IndirectSyms, nil
+}
+
+// ExternalRelocations returns the external relocations of the LC_DYSYMTAB (reading them on first access if
+// the File was opened with FileConfig.Lazy), or nil if there is none
+func (f *File) ExternalRelocations() ([]types.Reloc, error) {
+	if f.Dysymtab == nil {
+		return nil, nil
+	}
+	if err := f.loadDysymtabRelocs(); err != nil {
+		return nil, err
+	}
+	return f.Dysymtab.ExternalRelocs, nil
+}
+
+// LocalRelocations returns the local relocations of the LC_DYSYMTAB (reading them on first access if the
+// File was opened with FileConfig.Lazy), or nil if there is none
+func (f *File) LocalRelocations() ([]types.Reloc, error) {
+	if f.Dysymtab == nil {
+		return nil, nil
+	}
+	if err := f.loadDysymtabRelocs(); err != nil {
+		return nil, err
+	}
+	return f.Dysymtab.LocalRelocs, nil
+}
+
+// loadDysymtabRelocs reads the external and local relocations of the LC_DYSYMTAB if they haven't been yet
+func (f *File) loadDysymtabRelocs() error {
+	f.lazyMu.Lock()
+	defer f.lazyMu.Unlock()
+	if f.lazyDysymRelocs {
+		if err := f.readDysymtabRelocs(f.Dysymtab); err != nil {
+			return err
+		}
+		f.lazyDysymRelocs = false
+	}
+	return nil
+}
+
+// DylibTableOfContents returns the table of contents of the LC_DYSYMTAB (reading it on first access if the
+// File was opened with FileConfig.Lazy), or nil if there is none
+func (f *File) DylibTableOfContents() ([]types.DylibTableOfContents, error) {
+	if f.Dysymtab == nil {
+		return nil, nil
+	}
+	if err := f.loadDylibTables(); err != nil {
+		return nil, err
+	}
+	return f.Dysymtab.TOC, nil
+}
+
+// DylibModules returns the module table of the LC_DYSYMTAB (reading it on first access if the File was
+// opened with FileConfig.Lazy), or nil if there is none
+func (f *File) DylibModules() ([]DylibModule, error) {
+	if f.Dysymtab == nil {
+		return nil, nil
+	}
+	if err := f.loadDylibTables(); err != nil {
+		return nil, err
+	}
+	return f.Dysymtab.Modules, nil
+}
+
+// DylibReferences returns the reference symbol table of the LC_DYSYMTAB (reading it on first access if the
+// File was opened with FileConfig.Lazy), or nil if there is none
+func (f *File) DylibReferences() ([]types.DylibReference, error) {
+	if f.Dysymtab == nil {
+		return nil, nil
+	}
+	if err := f.loadDylibTables(); err != nil {
+		return nil, err
+	}
+	return f.Dysymtab.ExtRefSyms, nil
+}
+
+// loadDylibTables reads the dylib tables of the LC_DYSYMTAB if they haven't been yet
+func (f *File) loadDylibTables() error {
+	f.lazyMu.Lock()
+	defer f.lazyMu.Unlock()
+	if f.lazyDylibTables {
+		if err := f.readDylibTables(f.Dysymtab); err != nil {
+			return err
+		}
+		f.lazyDylibTables = false
+	}
+	return nil
+}
+
+// Relocations returns the relocations of the section sec (reading them on first access if the File was
+// opened with FileConfig.Lazy)
+func (f *File) Relocations(sec *types.Section) ([]types.Reloc, error) {
+	f.lazyMu.Lock()
+	defer f.lazyMu.Unlock()
+	if sec.Relocs == nil && sec.Nreloc > 0 {
+		if err := f.readRelocs(sec, f.cr); err != nil {
+			return nil, err
+		}
+	}
+	return sec.Relocs, nil
+}
+
+// symbols returns the symbols of the LC_SYMTAB (nil if there is none or they can't be read)
+func (f *File) symbols() []Symbol {
+	syms, _ := f.Symbols()
+	return syms
+}
+
+// indirectSymbols returns the indirect symbols of the LC_DYSYMTAB (nil if there is none or they can't be read)
+func (f *File) indirectSymbols() []uint32 {
+	x, _ := f.IndirectSymbols()
+	return x
+}
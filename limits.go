@@ -0,0 +1,66 @@
+package macho
+
+import (
+	"os"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// Limits caps the sizes of the tables NewFile reads to guard against hostile inputs (a zero limit is
+// unlimited). The tables are also always validated against the file size (when it is known).
+type Limits struct {
+	MaxSymbols     uint32 // max number of symbols in the LC_SYMTAB
+	MaxStringTable uint32 // max size of the LC_SYMTAB string table
+	MaxRelocs      uint32 // max number of relocations of a section
+	MaxCommandSize uint32 // max size of a load command
+}
+
+// WithLimits caps the sizes of the tables NewFile reads
+func WithLimits(limits Limits) Option {
+	return optionFunc(func(c *FileConfig) { c.Limits = limits })
+}
+
+// readerSize returns the size of r, or -1 if it is unknown
+func readerSize(r any) int64 {
+	switch r := r.(type) {
+	case interface{ Size() int64 }:
+		return r.Size()
+	case *os.File:
+		if fi, err := r.Stat(); err == nil {
+			return fi.Size()
+		}
+	}
+	return -1
+}
+
+// checkTable validates that the table of n entries of entsize bytes at the file offset off fits in the file
+// (and that its size doesn't overflow)
+func (f *File) checkTable(name string, off, n, entsize uint64) error {
+	if entsize > 0 && n > (1<<63-1)/entsize || off > 1<<63-1-n*entsize {
+		return &FormatError{int64(off), name + " size overflows", n}
+	}
+	if f.size >= 0 && off+n*entsize > uint64(f.size) {
+		return &FormatError{int64(off), name + " extends past the end of the file", n}
+	}
+	return nil
+}
+
+// checkLimit validates the count (or size) n of a table against its limit max
+func checkLimit(name string, off int64, n, max uint32) error {
+	if max > 0 && n > max {
+		return &FormatError{off, "too many " + name, n}
+	}
+	return nil
+}
+
+// isLinkEditDataCmd returns whether the load command cmd is a linkedit_data_command (with the file offset and
+// size of its __LINKEDIT data)
+func isLinkEditDataCmd(cmd types.LoadCmd) bool {
+	switch cmd {
+	case types.LC_CODE_SIGNATURE, types.LC_SEGMENT_SPLIT_INFO, types.LC_FUNCTION_STARTS, types.LC_DATA_IN_CODE,
+		types.LC_DYLIB_CODE_SIGN_DRS, types.LC_LINKER_OPTIMIZATION_HINT, types.LC_DYLD_EXPORTS_TRIE,
+		types.LC_DYLD_CHAINED_FIXUPS, types.LC_ATOM_INFO:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package macho
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/blacktop/go-macho/pkg/linkedit"
+	"github.com/blacktop/go-macho/types"
+)
+
+// linkeditBlob is a blob of __LINKEDIT data referenced by a load command (or section relocations)
+type linkeditBlob struct {
+	Name   string
+	Offset *uint32 // file offset field in the referring load command
+	Size   uint32  // in bytes
+	Align  uint32  // required alignment (0 for pointer alignment)
+}
+
+// linkeditBlobs returns all the __LINKEDIT blobs referenced by the load commands
+func (f *File) linkeditBlobs() []linkeditBlob {
+	var blobs []linkeditBlob
+	add := func(name string, off *uint32, size uint64) {
+		if size > 0 {
+			blobs = append(blobs, linkeditBlob{Name: name, Offset: off, Size: uint32(size)})
+		}
+	}
+
+	for _, sect := range f.Sections {
+		add(fmt.Sprintf("%s.%s relocations", sect.Seg, sect.Name), &sect.Reloff, uint64(sect.Nreloc)*8)
+	}
+
+	for _, l := range f.Loads {
+		switch l := l.(type) {
+		case *Symtab:
+			nlistSize := uint64(12)
+			if f.Magic == types.Magic64 {
+				nlistSize = 16
+			}
+			add("symbol table", &l.Symoff, uint64(l.Nsyms)*nlistSize)
+			add("string table", &l.Stroff, uint64(l.Strsize))
+		case *Dysymtab:
+			modtabSize := uint64(52)
+			if f.Magic == types.Magic64 {
+				modtabSize = 56
+			}
+			add("table of contents", &l.Tocoffset, uint64(l.Ntoc)*8)
+			add("module table", &l.Modtaboff, uint64(l.Nmodtab)*modtabSize)
+			add("external references", &l.Extrefsymoff, uint64(l.Nextrefsyms)*4)
+			add("indirect symbols", &l.Indirectsymoff, uint64(l.Nindirectsyms)*4)
+			add("external relocations", &l.Extreloff, uint64(l.Nextrel)*8)
+			add("local relocations", &l.Locreloff, uint64(l.Nlocrel)*8)
+		case *DyldInfo:
+			add("rebase info", &l.RebaseOff, uint64(l.RebaseSize))
+			add("bind info", &l.BindOff, uint64(l.BindSize))
+			add("weak bind info", &l.WeakBindOff, uint64(l.WeakBindSize))
+			add("lazy bind info", &l.LazyBindOff, uint64(l.LazyBindSize))
+			add("export info", &l.ExportOff, uint64(l.ExportSize))
+		case *DyldInfoOnly:
+			add("rebase info", &l.RebaseOff, uint64(l.RebaseSize))
+			add("bind info", &l.BindOff, uint64(l.BindSize))
+			add("weak bind info", &l.WeakBindOff, uint64(l.WeakBindSize))
+			add("lazy bind info", &l.LazyBindOff, uint64(l.LazyBindSize))
+			add("export info", &l.ExportOff, uint64(l.ExportSize))
+		case *TwolevelHints:
+			add(l.Command().String(), &l.Offset, uint64(l.NumHints)*4)
+		case *SplitInfo:
+			add(l.Command().String(), &l.Offset, uint64(l.Size))
+		case *DataInCode:
+			add(l.Command().String(), &l.Offset, uint64(l.Size))
+		case *FunctionStarts:
+			add(l.Command().String(), &l.Offset, uint64(l.Size))
+		case *DylibCodeSignDrs:
+			add(l.Command().String(), &l.Offset, uint64(l.Size))
+		case *LinkerOptimizationHint:
+			add(l.Command().String(), &l.Offset, uint64(l.Size))
+		case *DyldExportsTrie:
+			add(l.Command().String(), &l.Offset, uint64(l.Size))
+		case *DyldChainedFixups:
+			add(l.Command().String(), &l.Offset, uint64(l.Size))
+		case *AtomInfo:
+			add(l.Command().String(), &l.Offset, uint64(l.Size))
+		case *CodeSignature:
+			add(l.Command().String(), &l.Offset, uint64(l.Size))
+			if len(blobs) > 0 {
+				blobs[len(blobs)-1].Align = 16
+			}
+		}
+	}
+
+	return blobs
+}
+
+// linkeditData returns the current contents of the __LINKEDIT segment
+func (f *File) linkeditData() ([]byte, error) {
+	linkedit := f.Segment("__LINKEDIT")
+	if linkedit == nil {
+		return nil, fmt.Errorf("failed to find __LINKEDIT segment")
+	}
+	if f.ledata != nil && f.ledata.Len() > 0 {
+		return f.ledata.Bytes(), nil
+	}
+	dat := make([]byte, linkedit.Filesz)
+	if _, err := f.vmr.ReadAtVMAddr(dat, linkedit.Addr); err != nil {
+		return nil, fmt.Errorf("failed to read __LINKEDIT data: %v", err)
+	}
+	return dat, nil
+}
+
+// cacheLinkedit caches the __LINKEDIT data (for saving later) before it is moved or modified
+func (f *File) cacheLinkedit() error {
+	if f.ledata != nil && f.ledata.Len() > 0 {
+		return nil
+	}
+	dat, err := f.linkeditData()
+	if err != nil {
+		return err
+	}
+	f.ledata = bytes.NewBuffer(dat)
+	return nil
+}
+
+// linkeditLayout returns a layout of all the current __LINKEDIT blobs
+// (callers can replace the blobs' data before calling applyLinkeditLayout)
+func (f *File) linkeditLayout(start uint64) (*linkedit.Layout, error) {
+	seg := f.Segment("__LINKEDIT")
+	if seg == nil {
+		return nil, fmt.Errorf("failed to find __LINKEDIT segment")
+	}
+	dat, err := f.linkeditData()
+	if err != nil {
+		return nil, err
+	}
+
+	ptrSize := uint32(4)
+	if f.Magic == types.Magic64 {
+		ptrSize = 8
+	}
+	layout := linkedit.NewLayout(start, ptrSize)
+
+	covered := make([]bool, len(dat))
+	for _, blob := range f.linkeditBlobs() {
+		off := uint64(*blob.Offset)
+		if off < seg.Offset || off+uint64(blob.Size) > seg.Offset+uint64(len(dat)) {
+			continue // not in __LINKEDIT (i.e. MH_OBJECT relocations)
+		}
+		rel := off - seg.Offset
+		for i := rel; i < rel+uint64(blob.Size); i++ {
+			covered[i] = true
+		}
+		layout.Add(&linkedit.Blob{
+			Name:   blob.Name,
+			Offset: blob.Offset,
+			Data:   dat[rel : rel+uint64(blob.Size)],
+			Align:  blob.Align,
+		})
+	}
+	// don't silently drop data referenced by load commands we don't know about
+	for i, c := range covered {
+		if !c && dat[i] != 0 {
+			return nil, fmt.Errorf("__LINKEDIT contains unreferenced data at offset %#x", seg.Offset+uint64(i))
+		}
+	}
+
+	return layout, nil
+}
+
+// applyLinkeditLayout builds the layout, caching the new __LINKEDIT data for saving later and resizing the segment
+func (f *File) applyLinkeditLayout(layout *linkedit.Layout) error {
+	seg := f.Segment("__LINKEDIT")
+	if seg == nil {
+		return fmt.Errorf("failed to find __LINKEDIT segment")
+	}
+	dat, err := layout.Build()
+	if err != nil {
+		return fmt.Errorf("failed to layout __LINKEDIT: %v", err)
+	}
+	f.ledata = bytes.NewBuffer(dat)
+	seg.Offset = layout.Start
+	seg.Filesz = uint64(len(dat))
+	if memsz := pageAlign(seg.Filesz, f.pageSize()); memsz > seg.Memsz {
+		seg.Memsz = memsz
+	}
+	return nil
+}
+
+// LayoutLinkedit re-lays-out all the __LINKEDIT blobs (symbol and string tables, dyld info,
+// function starts, data-in-code, code signature, etc.) contiguously, patching every load command
+// that refers to them and resizing the __LINKEDIT segment to fit
+// NOTE: call Save or WriteTo to write out the modified MachO (which will need to be re-signed)
+func (f *File) LayoutLinkedit() error {
+	seg := f.Segment("__LINKEDIT")
+	if seg == nil {
+		return fmt.Errorf("failed to find __LINKEDIT segment")
+	}
+	layout, err := f.linkeditLayout(seg.Offset)
+	if err != nil {
+		return err
+	}
+	return f.applyLinkeditLayout(layout)
+}
+
+// moveLinkedit moves the __LINKEDIT segment (and all the blobs in it) by fileDelta in the file
+// and vmDelta in memory (used to make room for segments inserted before it)
+func (f *File) moveLinkedit(fileDelta, vmDelta uint64) error {
+	seg := f.Segment("__LINKEDIT")
+	if seg == nil {
+		return fmt.Errorf("failed to find __LINKEDIT segment")
+	}
+	layout, err := f.linkeditLayout(seg.Offset + fileDelta)
+	if err != nil {
+		return err
+	}
+	if err := f.applyLinkeditLayout(layout); err != nil {
+		return err
+	}
+	seg.Addr += vmDelta
+	f.resetAddrIndex()
+	return nil
+}
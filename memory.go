@@ -0,0 +1,125 @@
+package macho
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// NewFileFromMemory creates a new File for accessing a Mach-O image that is already mapped (e.g. by dyld) in
+// memory. The image's mach header is expected to be at position 0 in the ReaderAt and its segments at their
+// vmaddrs (relative to the __TEXT vmaddr), so file offsets (e.g. of the __LINKEDIT data) are translated through
+// the segments. Pointers are read as is (they are already fixed up) unless a VMAddrConverter.Converter is given.
+func NewFileFromMemory(r io.ReaderAt, opts ...Option) (*File, error) {
+	hdr, err := NewFile(r, OnlyLoadCommands(types.LC_SEGMENT, types.LC_SEGMENT_64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse in-memory MachO load commands: %v", err)
+	}
+	text := hdr.Segment("__TEXT")
+	if text == nil {
+		return nil, fmt.Errorf("in-memory MachO has no __TEXT segment")
+	}
+	vmr := types.NewMemoryVMReader(r, text.Addr, hdr.ByteOrder, int(hdr.pointerSize()))
+	return NewFileFromVMReader(vmr, text.Addr, opts...)
+}
+
+// NewFileFromVMReader creates a new File for accessing the Mach-O image whose mach header is at the virtual
+// address addr of vmr (e.g. a VMReader of a remote/attached process). All of its reads go through vmr with
+// its segments slid to addr (see NewFileFromMemory).
+func NewFileFromVMReader(vmr types.VMReader, addr uint64, opts ...Option) (*File, error) {
+	// first read the segments to lay out the image
+	hr := io.NewSectionReader(vmReaderAt{vmr}, int64(addr), 1<<63-1-int64(addr))
+	hdr, err := NewFile(hr, OnlyLoadCommands(types.LC_SEGMENT, types.LC_SEGMENT_64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse in-memory MachO load commands: %v", err)
+	}
+	text := hdr.Segment("__TEXT")
+	if text == nil {
+		return nil, fmt.Errorf("in-memory MachO has no __TEXT segment")
+	}
+	if slide := addr - text.Addr; slide != 0 {
+		vmr = &slidVMReader{VMReader: vmr, slide: slide}
+	}
+	var segs segmentLayout
+	for _, seg := range hdr.Segments() {
+		if seg.Filesz > 0 {
+			segs = append(segs, seg)
+		}
+	}
+
+	var cfg FileConfig
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	vma := types.VMAddrConverter{
+		PreferredLoadAddress: text.Addr,
+		Converter:            cfg.VMAddrConverter.Converter,
+		VMAddr2Offet:         segs.offset,
+		Offet2VMAddr:         segs.address,
+	}
+	if vma.Converter == nil {
+		vma.Converter = func(ptr uint64) uint64 { return ptr }
+	}
+	cfg.Offset = 0
+	cfg.SectionReader = types.NewVMMachoReader(vmr, &vma)
+	cfg.CacheReader = nil
+	cfg.VMAddrConverter = vma
+	f, err := NewFile(hr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	f.vmr = vmr
+	f.inMemory = true
+	return f, nil
+}
+
+// vmReaderAt is an io.ReaderAt of the virtual addresses of a VMReader
+type vmReaderAt struct {
+	types.VMReader
+}
+
+func (r vmReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return r.ReadAtVMAddr(p, uint64(off))
+}
+
+// slidVMReader is a VMReader of the (unslid) vmaddrs of an image slid by slide
+type slidVMReader struct {
+	types.VMReader
+	slide uint64
+}
+
+func (r *slidVMReader) ReadAtVMAddr(buf []byte, addr uint64) (int, error) {
+	return r.VMReader.ReadAtVMAddr(buf, addr+r.slide)
+}
+
+func (r *slidVMReader) ReadPointer(addr uint64) (uint64, error) {
+	return r.VMReader.ReadPointer(addr + r.slide)
+}
+
+func (r *slidVMReader) ReadCString(addr uint64) (string, error) {
+	return r.VMReader.ReadCString(addr + r.slide)
+}
+
+// segmentLayout translates between the file offsets and the addresses of an image's segments
+type segmentLayout []*Segment
+
+// address returns the address of the file offset off
+func (l segmentLayout) address(off uint64) (uint64, error) {
+	for _, seg := range l {
+		if seg.Offset <= off && off < seg.Offset+seg.Filesz {
+			return seg.Addr + off - seg.Offset, nil
+		}
+	}
+	return 0, fmt.Errorf("offset %#x is not in a segment", off)
+}
+
+// offset returns the file offset of the address addr
+func (l segmentLayout) offset(addr uint64) (uint64, error) {
+	for _, seg := range l {
+		if seg.Addr <= addr && addr < seg.Addr+seg.Filesz {
+			return seg.Offset + addr - seg.Addr, nil
+		}
+	}
+	return 0, fmt.Errorf("address %#x is not in a segment", addr)
+}
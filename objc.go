@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"unicode/utf16"
 	"unsafe"
 
 	"github.com/blacktop/go-macho/types"
@@ -126,27 +127,34 @@ func (f *File) GetObjCToc() objc.Toc {
 // GetObjCImageInfo returns the parsed __objc_imageinfo data
 func (f *File) GetObjCImageInfo() (*objc.ImageInfo, error) {
 	var imgInfo objc.ImageInfo
+	var sec *types.Section
 	for _, s := range f.Segments() {
 		if strings.HasPrefix(s.Name, "__DATA") {
-			if sec := f.Section(s.Name, "__objc_imageinfo"); sec != nil {
-				off, err := f.vma.GetOffset(sec.Addr)
-				if err != nil {
-					return nil, fmt.Errorf("failed to convert vmaddr: %v", err)
-				}
-				f.cr.Seek(int64(off), io.SeekStart)
-
-				dat := make([]byte, sec.Size)
-				if err := binary.Read(f.cr, f.ByteOrder, dat); err != nil {
-					return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
-				}
+			if sec = f.Section(s.Name, "__objc_imageinfo"); sec != nil {
+				break
+			}
+		}
+	}
+	if sec == nil && f.CPU == types.CPUI386 { // legacy ObjC1 runtime
+		sec = f.Section("__OBJC", "__image_info")
+	}
+	if sec != nil {
+		off, err := f.vma.GetOffset(sec.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert vmaddr: %v", err)
+		}
+		f.cr.Seek(int64(off), io.SeekStart)
 
-				if err := binary.Read(bytes.NewReader(dat), f.ByteOrder, &imgInfo); err != nil {
-					return nil, fmt.Errorf("failed to read %T: %v", imgInfo, err)
-				}
+		dat := make([]byte, sec.Size)
+		if err := binary.Read(f.cr, f.ByteOrder, dat); err != nil {
+			return nil, fmt.Errorf("failed to read %s.%s data: %v", sec.Seg, sec.Name, err)
+		}
 
-				return &imgInfo, nil
-			}
+		if err := binary.Read(bytes.NewReader(dat), f.ByteOrder, &imgInfo); err != nil {
+			return nil, fmt.Errorf("failed to read %T: %v", imgInfo, err)
 		}
+
+		return &imgInfo, nil
 	}
 	return nil, fmt.Errorf("macho does not contain __objc_imageinfo section: %w", ErrObjcSectionNotFound)
 }
@@ -1016,6 +1024,76 @@ func (f *File) GetObjCProtocols() ([]objc.Protocol, error) {
 	return protocols, nil
 }
 
+// DumpObjC returns class-dump style Objective-C declarations for all the protocols, classes and
+// categories in the MachO (verbose decodes the method, property and ivar type encodings and addrs
+// adds the addresses of the objects and method implementations)
+func (f *File) DumpObjC(verbose, addrs bool) (string, error) {
+	if !f.HasObjC() {
+		return "", fmt.Errorf("macho does not contain ObjC metadata: %w", ErrObjcSectionNotFound)
+	}
+
+	protos, err := f.GetObjCProtocols()
+	if err != nil {
+		return "", fmt.Errorf("failed to get protocols: %v", err)
+	}
+	classes, err := f.GetObjCClasses()
+	if err != nil {
+		return "", fmt.Errorf("failed to get classes: %v", err)
+	}
+	cats, err := f.GetObjCCategories()
+	if err != nil {
+		return "", fmt.Errorf("failed to get categories: %v", err)
+	}
+
+	var out strings.Builder
+	dump := func(obj interface {
+		String() string
+		Verbose() string
+		WithAddrs() string
+	}) {
+		switch {
+		case addrs:
+			out.WriteString(obj.WithAddrs())
+		case verbose:
+			out.WriteString(obj.Verbose())
+		default:
+			out.WriteString(obj.String())
+		}
+		out.WriteString("\n")
+	}
+
+	// forward declarations of the classes and protocols defined in the image
+	if len(classes) > 0 {
+		var names []string
+		for _, class := range classes {
+			names = append(names, class.Name)
+		}
+		out.WriteString(fmt.Sprintf("@class %s;\n", strings.Join(names, ", ")))
+	}
+	if len(protos) > 0 {
+		var names []string
+		for _, proto := range protos {
+			names = append(names, proto.Name)
+		}
+		out.WriteString(fmt.Sprintf("@protocol %s;\n", strings.Join(names, ", ")))
+	}
+	if len(classes) > 0 || len(protos) > 0 {
+		out.WriteString("\n")
+	}
+
+	for i := range protos {
+		dump(&protos[i])
+	}
+	for i := range classes {
+		dump(&classes[i])
+	}
+	for i := range cats {
+		dump(&cats[i])
+	}
+
+	return out.String(), nil
+}
+
 func (f *File) GetObjCMethods(vmaddr uint64) ([]objc.Method, error) {
 	if c, ok := f.GetObjC(vmaddr); ok {
 		return c.([]objc.Method), nil
@@ -1454,7 +1532,7 @@ func (f *File) GetObjCSelectorReferences() (map[uint64]*objc.Selector, error) {
 	return selRefs, nil
 }
 
-// GetCFStrings returns the Objective-C CFStrings
+// GetCFStrings returns the Objective-C CFStrings (both the 8-bit __cstring and UTF-16 __ustring backed ones)
 func (f *File) GetCFStrings() ([]objc.CFString, error) {
 	var err error
 	var cfstrings []objc.CFString
@@ -1472,20 +1550,38 @@ func (f *File) GetCFStrings() ([]objc.CFString, error) {
 
 			r := bytes.NewReader(dat)
 
-			cfstrings = make([]objc.CFString, int(sec.Size)/binary.Size(objc.CFString64Type{}))
-			for idx := range cfstrings {
-				if err := binary.Read(r, f.ByteOrder, &cfstrings[idx].CFString64Type); err != nil {
-					return nil, fmt.Errorf("failed to read %T structs: %v", cfstrings[idx].CFString64Type, err)
+			structSize := binary.Size(objc.CFString64Type{})
+			if !f.is64bit() {
+				structSize = binary.Size(objc.CFString32Type{})
+			}
+
+			cfs := make([]objc.CFString, int(sec.Size)/structSize)
+			for idx := range cfs {
+				if f.is64bit() {
+					if err := binary.Read(r, f.ByteOrder, &cfs[idx].CFString64Type); err != nil {
+						return nil, fmt.Errorf("failed to read %T structs: %v", cfs[idx].CFString64Type, err)
+					}
+				} else {
+					var cf32 objc.CFString32Type
+					if err := binary.Read(r, f.ByteOrder, &cf32); err != nil {
+						return nil, fmt.Errorf("failed to read %T structs: %v", cf32, err)
+					}
+					cfs[idx].CFString64Type = objc.CFString64Type{
+						IsaVMAddr: uint64(cf32.IsaVMAddr),
+						Info:      uint64(cf32.Info),
+						Data:      uint64(cf32.Data),
+						Length:    uint64(cf32.Length),
+					}
 				}
 			}
 
-			for idx := range cfstrings {
-				cfstrings[idx].IsaVMAddr = f.vma.Convert(cfstrings[idx].IsaVMAddr)
-				if bind, err := f.GetBindName(cfstrings[idx].IsaVMAddr); err == nil {
-					cfstrings[idx].ISA = bind
+			for idx := range cfs {
+				cfs[idx].IsaVMAddr = f.vma.Convert(cfs[idx].IsaVMAddr)
+				if bind, err := f.GetBindName(cfs[idx].IsaVMAddr); err == nil {
+					cfs[idx].ISA = bind
 				}
-				cfstrings[idx].Data = f.vma.Convert(cfstrings[idx].Data)
-				if cfstrings[idx].Data == 0 {
+				cfs[idx].Data = f.vma.Convert(cfs[idx].Data)
+				if cfs[idx].Data == 0 {
 					return nil, fmt.Errorf("unhandled cstring parse case where data is 0") // TODO: finish this
 					// uint64_t n_value;
 					// const char *symbol_name = get_symbol_64(offset + offsetof(struct cfstring64_t, characters), S, info, n_value);
@@ -1493,24 +1589,50 @@ func (f *File) GetCFStrings() ([]objc.CFString, error) {
 					//   return nullptr;
 					// cfs_characters = n_value;
 				}
-				cfstrings[idx].Name, err = f.GetCString(cfstrings[idx].Data)
+				if cfs[idx].IsUnicode() {
+					cfs[idx].Name, err = f.getUTF16String(cfs[idx].Data, cfs[idx].Length)
+				} else {
+					cfs[idx].Name, err = f.GetCString(cfs[idx].Data)
+				}
 				if err != nil {
 					return nil, fmt.Errorf("failed to read cstring: %v", err)
 				}
-				if c, ok := f.objc[cfstrings[idx].IsaVMAddr]; ok {
-					cfstrings[idx].Class = c.(*objc.Class)
-				}
-				cfstrings[idx].Address = sec.Addr + uint64(idx*binary.Size(objc.CFString64Type{}))
-				if err != nil {
-					return nil, fmt.Errorf("failed to calulate cfstring vmaddr: %v", err)
+				if c, ok := f.GetObjC(cfs[idx].IsaVMAddr); ok {
+					if class, ok := c.(*objc.Class); ok {
+						cfs[idx].Class = class
+					}
 				}
+				cfs[idx].Address = sec.Addr + uint64(idx*structSize)
 			}
+
+			cfstrings = append(cfstrings, cfs...)
 		}
 	}
 
 	return cfstrings, nil
 }
 
+// getUTF16String returns the UTF-16 string of length characters at the given virtual address
+func (f *File) getUTF16String(addr, length uint64) (string, error) {
+	// the untrusted length must fit in the section holding the string
+	sec := f.FindSectionForVMAddr(addr)
+	if sec == nil {
+		return "", fmt.Errorf("failed to find section containing UTF-16 string at address %#x", addr)
+	}
+	if length > (sec.Addr+sec.Size-addr)/2 {
+		return "", fmt.Errorf("UTF-16 string of length %d at address %#x extends past the end of section %s.%s", length, addr, sec.Seg, sec.Name)
+	}
+	dat := make([]byte, length*2)
+	if _, err := f.vmr.ReadAtVMAddr(dat, addr); err != nil {
+		return "", fmt.Errorf("failed to read UTF-16 string at address %#x: %v", addr, err)
+	}
+	chars := make([]uint16, length)
+	for i := range chars {
+		chars[i] = f.ByteOrder.Uint16(dat[i*2:])
+	}
+	return string(utf16.Decode(chars)), nil
+}
+
 // GetObjCIntObj parses the __objc_intobj section and returns a map of
 func (f *File) GetObjCIntegerObjects() (map[uint64]*objc.IntObj, error) {
 	if sec := f.Section("__TEXT", "__objc_intobj"); sec != nil {
@@ -1538,8 +1660,46 @@ func (f *File) GetObjCIntegerObjects() (map[uint64]*objc.IntObj, error) {
 	return nil, fmt.Errorf("macho does not contain __objc_intobj section: %w", ErrObjcSectionNotFound)
 }
 
-// GetObjCStubs returns the Objective-C stubs
+// parseObjCStubsARM64 decodes the arm64(e) __objc_stubs which all start by loading the selector
+// into x1 from its selref (adrp x1, selref@PAGE; ldr x1, [x1, selref@PAGEOFF]) before branching
+// to (or loading) _objc_msgSend
+func (f *File) parseObjCStubsARM64(addr uint64, dat []byte) (map[uint64]*objc.Stub, error) {
+	stubs := make(map[uint64]*objc.Stub)
+	for i := 0; i+8 <= len(dat); i += 4 {
+		adrp := f.ByteOrder.Uint32(dat[i:])
+		ldr := f.ByteOrder.Uint32(dat[i+4:])
+		if adrp&0x9f00001f != 0x90000001 || // adrp x1
+			ldr&0xffc003ff != 0xf9400021 { // ldr x1, [x1, #imm]
+			continue
+		}
+		pc := addr + uint64(i)
+		imm := int64((adrp>>5)&0x7ffff)<<2 | int64((adrp>>29)&3)
+		imm = imm << 43 >> 43 // sign extend 21 bits
+		selRef := uint64(int64(pc&^0xfff)+imm<<12) + uint64((ldr>>10)&0xfff)*8
+
+		selPtr, err := f.GetPointerAtAddress(selRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read selref at %#x for stub at %#x: %v", selRef, pc, err)
+		}
+		name, err := f.GetCString(selPtr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read selector name at %#x for stub at %#x: %v", selPtr, pc, err)
+		}
+		stubs[pc] = &objc.Stub{Name: name, SelectorRef: selRef}
+		i += 4
+	}
+	return stubs, nil
+}
+
+// GetObjCStubs returns the Objective-C stubs mapped by their address (parse decodes the
+// __objc_stubs section data, if it is nil the built-in arm64 stub decoder is used)
 func (f *File) GetObjCStubs(parse func(uint64, []byte) (map[uint64]*objc.Stub, error)) (map[uint64]*objc.Stub, error) {
+	if parse == nil {
+		if f.CPU != types.CPUArm64 {
+			return nil, fmt.Errorf("no built-in __objc_stubs decoder for %s", f.CPU)
+		}
+		parse = f.parseObjCStubsARM64
+	}
 	if sec := f.Section("__TEXT", "__objc_stubs"); sec != nil {
 		if err := f.cr.SeekToAddr(sec.Addr); err != nil {
 			return nil, fmt.Errorf("failed to seek to %s addr %#x: %v", sec.Name, sec.Addr, err)
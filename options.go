@@ -0,0 +1,120 @@
+package macho
+
+import (
+	"io"
+	"sync"
+
+	"github.com/blacktop/go-macho/internal/saferio"
+	"github.com/blacktop/go-macho/types"
+)
+
+// An Option configures how NewFile parses a MachO (a FileConfig is also an Option that replaces the whole
+// configuration)
+type Option interface {
+	apply(*FileConfig)
+}
+
+func (c FileConfig) apply(config *FileConfig) { *config = c }
+
+type optionFunc func(*FileConfig)
+
+func (fn optionFunc) apply(config *FileConfig) { fn(config) }
+
+// WithoutSections skips parsing the section headers of the segments
+func WithoutSections() Option {
+	return optionFunc(func(c *FileConfig) { c.NoSections = true })
+}
+
+// WithoutSymbols skips reading the symbols of the LC_SYMTAB
+func WithoutSymbols() Option {
+	return optionFunc(func(c *FileConfig) { c.NoSymbols = true })
+}
+
+// OnlyLoadCommands only parses the load commands cmds
+func OnlyLoadCommands(cmds ...types.LoadCmd) Option {
+	return optionFunc(func(c *FileConfig) { c.LoadIncluding = append(c.LoadIncluding, cmds...) })
+}
+
+// WithSectionCache caches the data of the last n sections read
+func WithSectionCache(n int) Option {
+	return optionFunc(func(c *FileConfig) { c.SectionCacheSize = n })
+}
+
+// Permissive records the load commands that fail to parse as warnings (see File.ParseWarnings) instead of
+// failing
+func Permissive() Option {
+	return optionFunc(func(c *FileConfig) { c.Permissive = true })
+}
+
+// WithDiagnosticHandler reports the parsing anomalies (e.g. unknown load commands) to h
+func WithDiagnosticHandler(h func(Diagnostic)) Option {
+	return optionFunc(func(c *FileConfig) { c.DiagnosticHandler = h })
+}
+
+// sectionReader returns a SectionReader of the size bytes of section data at the file offset off
+func (f *File) sectionReader(off, size int64) *io.SectionReader {
+	if f.sectionCache == nil {
+		return io.NewSectionReader(f.cr, off, size)
+	}
+	return io.NewSectionReader(&cachedSectionReader{c: f.sectionCache, off: off, size: size}, off, size)
+}
+
+type sectionKey struct {
+	off, size int64
+}
+
+// sectionCache is an LRU cache of the data of the last n sections read
+type sectionCache struct {
+	mu   sync.Mutex
+	r    io.ReaderAt
+	n    int
+	keys []sectionKey // least recently used first
+	data map[sectionKey][]byte
+}
+
+func newSectionCache(r io.ReaderAt, n int) *sectionCache {
+	return &sectionCache{r: r, n: n, data: make(map[sectionKey][]byte)}
+}
+
+func (c *sectionCache) get(key sectionKey) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, k := range c.keys {
+		if k == key {
+			c.keys = append(append(c.keys[:i:i], c.keys[i+1:]...), key)
+			return c.data[key], nil
+		}
+	}
+	dat, err := saferio.ReadDataAt(c.r, uint64(key.size), key.off)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.keys) == c.n {
+		delete(c.data, c.keys[0])
+		c.keys = c.keys[1:]
+	}
+	c.keys = append(c.keys, key)
+	c.data[key] = dat
+	return dat, nil
+}
+
+// cachedSectionReader reads the data of a section through a sectionCache
+type cachedSectionReader struct {
+	c         *sectionCache
+	off, size int64
+}
+
+func (r *cachedSectionReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < r.off || off >= r.off+r.size {
+		return r.c.r.ReadAt(p, off)
+	}
+	dat, err := r.c.get(sectionKey{r.off, r.size})
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, dat[off-r.off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
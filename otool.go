@@ -0,0 +1,91 @@
+package macho
+
+import (
+	"fmt"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// otoolVersion formats a X.Y.Z version like otool (the .Z is omitted when zero unless full is set)
+func otoolVersion(v types.Version, full bool) string {
+	x, y, z := uint32(v)>>16, (uint32(v)>>8)&0xff, uint32(v)&0xff
+	if z != 0 || full {
+		return fmt.Sprintf("%d.%d.%d", x, y, z)
+	}
+	return fmt.Sprintf("%d.%d", x, y)
+}
+
+// otoolSDK formats a LC_VERSION_MIN or LC_BUILD_VERSION sdk like otool
+func otoolSDK(v types.Version) string {
+	if v == 0 {
+		return "n/a"
+	}
+	return otoolVersion(v, false)
+}
+
+func otoolSourceVersion(sv types.SrcVersion) string {
+	v := sv.Components()
+	a, b, c, d, e := v.A, v.B, v.C, v.D, v.E
+	switch {
+	case e != 0:
+		return fmt.Sprintf("%d.%d.%d.%d.%d", a, b, c, d, e)
+	case d != 0:
+		return fmt.Sprintf("%d.%d.%d.%d", a, b, c, d)
+	case c != 0:
+		return fmt.Sprintf("%d.%d.%d", a, b, c)
+	}
+	return fmt.Sprintf("%d.%d", a, b)
+}
+
+// otoolSection formats a segment's section like `otool -l`
+func otoolSection(sec *types.Section, is64 bool) string {
+	addr := fmt.Sprintf("0x%08x", sec.Addr)
+	size := fmt.Sprintf("0x%08x", sec.Size)
+	if is64 {
+		addr = fmt.Sprintf("0x%016x", sec.Addr)
+		size = fmt.Sprintf("0x%016x", sec.Size)
+	}
+	return fmt.Sprintf(
+		"Section\n"+
+			"  sectname %s\n"+
+			"   segname %s\n"+
+			"      addr %s\n"+
+			"      size %s\n"+
+			"    offset %d\n"+
+			"     align 2^%d (%d)\n"+
+			"    reloff %d\n"+
+			"    nreloc %d\n"+
+			"     flags 0x%08x\n"+
+			" reserved1 %d\n"+
+			" reserved2 %d",
+		sec.Name, sec.Seg, addr, size, sec.Offset, sec.Align, 1<<sec.Align,
+		sec.Reloff, sec.Nreloc, uint32(sec.Flags), sec.Reserved1, sec.Reserved2)
+}
+
+// otoolLinkEditData formats a linkedit data load command like `otool -l`
+func otoolLinkEditData(l types.LinkEditDataCmd) string {
+	return fmt.Sprintf(
+		"      cmd %s\n"+
+			"  cmdsize %d\n"+
+			"  dataoff %d\n"+
+			" datasize %d",
+		l.LoadCmd, l.Len, l.Offset, l.Size)
+}
+
+// String returns the Mach header and load commands of the MachO in the (non-verbose) `otool -hl` format
+func (f *File) String() string {
+	return fmt.Sprintf(
+		"Mach header\n"+
+			"      magic  cputype cpusubtype  caps    filetype ncmds sizeofcmds      flags\n"+
+			" 0x%08x %7d %10d  0x%02x  %10d %5d %10d 0x%08x\n"+
+			"%s",
+		uint32(f.Magic),
+		int32(f.CPU),
+		int32(f.SubCPU.Base()),
+		uint32(f.SubCPU.Caps())>>24,
+		uint32(f.Type),
+		f.NCommands,
+		f.SizeCommands,
+		uint32(f.Flags),
+		f.Loads)
+}
@@ -0,0 +1,184 @@
+package codesign
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	oidSignedData         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidAttrMessageDigest  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttrSigningTime    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidAttrAppleCDHashes  = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 9, 1}
+	oidAttrAppleCDHashes2 = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 9, 2}
+)
+
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type cmsSignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo cmsContentInfo
+	Certificates     asn1.RawValue   `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue   `asn1:"optional,tag:1"`
+	SignerInfos      []cmsSignerInfo `asn1:"set"`
+}
+
+type cmsIssuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type cmsSignerInfo struct {
+	Version            int
+	SID                cmsIssuerAndSerial
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        []cmsAttribute `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      []cmsAttribute `asn1:"optional,tag:1"`
+}
+
+type cmsAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+type cmsCDHash struct {
+	Algorithm asn1.ObjectIdentifier
+	Hash      []byte
+}
+
+// CMSCDHash is a CodeDirectory hash (of a given digest algorithm) covered by the CMS signature
+type CMSCDHash struct {
+	Algorithm asn1.ObjectIdentifier `json:"algorithm,omitempty"`
+	Hash      []byte                `json:"hash,omitempty"`
+}
+
+// CMSInfo is the signing information decoded from a code signature's CMS blob
+type CMSInfo struct {
+	Certificates    []*x509.Certificate      `json:"-"`
+	DigestAlgorithm pkix.AlgorithmIdentifier `json:"-"`
+	SigningTime     time.Time                `json:"signing_time,omitempty"`
+	MessageDigest   []byte                   `json:"message_digest,omitempty"` // digest of the signed CodeDirectory
+	CDHashes        [][]byte                 `json:"cd_hashes,omitempty"`      // truncated CDHashes from the Apple cdhashes plist attribute
+	CDHashes2       []CMSCDHash              `json:"cd_hashes2,omitempty"`     // full CDHashes from the Apple cdhashes2 attribute
+}
+
+// Signer returns the leaf (signing) certificate or nil if there are no certificates
+func (c *CMSInfo) Signer() *x509.Certificate {
+	for _, cert := range c.Certificates {
+		if !cert.IsCA {
+			return cert
+		}
+	}
+	if len(c.Certificates) > 0 {
+		return c.Certificates[len(c.Certificates)-1]
+	}
+	return nil
+}
+
+// ParseCMSSignature parses the PKCS#7/CMS SignedData of the CSSLOT_CMS_SIGNATURE blob
+func ParseCMSSignature(data []byte) (*CMSInfo, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty CMS signature (ad-hoc signed)")
+	}
+
+	var ci cmsContentInfo
+	if _, err := asn1.Unmarshal(data, &ci); err != nil {
+		return nil, fmt.Errorf("failed to ASN.1 parse CMS content info: %v", err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("unsupported CMS content type %s", ci.ContentType)
+	}
+
+	var sd cmsSignedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("failed to ASN.1 parse CMS signed data: %v", err)
+	}
+
+	info := &CMSInfo{}
+
+	if len(sd.Certificates.Bytes) > 0 {
+		certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CMS certificates: %v", err)
+		}
+		info.Certificates = certs
+	}
+
+	if len(sd.SignerInfos) == 0 {
+		return info, nil
+	}
+
+	si := sd.SignerInfos[0]
+	info.DigestAlgorithm = si.DigestAlgorithm
+
+	for _, attr := range si.SignedAttrs {
+		switch {
+		case attr.Type.Equal(oidAttrMessageDigest):
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &info.MessageDigest); err != nil {
+				return nil, fmt.Errorf("failed to parse CMS message digest attribute: %v", err)
+			}
+		case attr.Type.Equal(oidAttrSigningTime):
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &info.SigningTime); err != nil {
+				return nil, fmt.Errorf("failed to parse CMS signing time attribute: %v", err)
+			}
+		case attr.Type.Equal(oidAttrAppleCDHashes):
+			var plist []byte
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &plist); err != nil {
+				return nil, fmt.Errorf("failed to parse CMS cdhashes attribute: %v", err)
+			}
+			hashes, err := parseCDHashesPlist(plist)
+			if err != nil {
+				return nil, err
+			}
+			info.CDHashes = hashes
+		case attr.Type.Equal(oidAttrAppleCDHashes2):
+			rest := attr.Values.Bytes
+			for len(rest) > 0 {
+				var h cmsCDHash
+				var err error
+				if rest, err = asn1.Unmarshal(rest, &h); err != nil {
+					return nil, fmt.Errorf("failed to parse CMS cdhashes2 attribute: %v", err)
+				}
+				info.CDHashes2 = append(info.CDHashes2, CMSCDHash(h))
+			}
+		}
+	}
+
+	return info, nil
+}
+
+func parseCDHashesPlist(dat []byte) ([][]byte, error) {
+	var plist struct {
+		Data []string `xml:"dict>array>data"`
+	}
+	if err := xml.Unmarshal(dat, &plist); err != nil {
+		return nil, fmt.Errorf("failed to parse CMS cdhashes plist: %v", err)
+	}
+	var hashes [][]byte
+	for _, d := range plist.Data {
+		h, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(d), ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode CMS cdhashes plist data: %v", err)
+		}
+		hashes = append(hashes, h)
+	}
+	return hashes, nil
+}
+
+// CMS returns the decoded CMS signature blob of the code signature
+func (cs *CodeSignature) CMS() (*CMSInfo, error) {
+	return ParseCMSSignature(cs.CMSSignature)
+}
@@ -0,0 +1,154 @@
+package codesign
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+
+// cmsAttr returns a CMS attribute of type typ with the single value val
+func cmsAttr(t *testing.T, typ asn1.ObjectIdentifier, val any) cmsAttribute {
+	t.Helper()
+	dat, err := asn1.Marshal(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmsAttribute{Type: typ, Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: dat}}
+}
+
+// cmsSignature returns a DER CMS SignedData signed (in name only) by a self-signed certificate
+func cmsSignature(t *testing.T, attrs []cmsAttribute) ([]byte, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "Developer ID Application: Test"},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(1<<32, 0),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digestAlg := pkix.AlgorithmIdentifier{Algorithm: oidSHA256}
+	sd, err := asn1.Marshal(cmsSignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlg},
+		EncapContentInfo: cmsContentInfo{ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certDER},
+		SignerInfos: []cmsSignerInfo{{
+			Version:            1,
+			SID:                cmsIssuerAndSerial{Issuer: asn1.RawValue{FullBytes: cert.RawIssuer}, SerialNumber: cert.SerialNumber},
+			DigestAlgorithm:    digestAlg,
+			SignedAttrs:        attrs,
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+			Signature:          []byte{1, 2, 3, 4},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci, err := asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{oidSignedData, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ci, cert
+}
+
+func TestParseCMSSignature(t *testing.T) {
+	digest := bytes.Repeat([]byte{0xaa}, 32)
+	signed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	plist := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>cdhashes</key><array>
+<data>
+ESIzRFVmd4iZqrvM3e7/ABEiM0Q=
+</data>
+</array></dict></plist>`)
+	hash2 := cmsCDHash{Algorithm: oidSHA256, Hash: bytes.Repeat([]byte{0xbb}, 32)}
+	dat, cert := cmsSignature(t, []cmsAttribute{
+		cmsAttr(t, oidAttrMessageDigest, digest),
+		cmsAttr(t, oidAttrSigningTime, signed),
+		cmsAttr(t, oidAttrAppleCDHashes, plist),
+		cmsAttr(t, oidAttrAppleCDHashes2, hash2),
+	})
+
+	info, err := ParseCMSSignature(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Certificates) != 1 || !info.Certificates[0].Equal(cert) || !info.Signer().Equal(cert) {
+		t.Errorf("Certificates = %v", info.Certificates)
+	}
+	if !info.DigestAlgorithm.Algorithm.Equal(oidSHA256) {
+		t.Errorf("DigestAlgorithm = %v, want %v", info.DigestAlgorithm.Algorithm, oidSHA256)
+	}
+	if !bytes.Equal(info.MessageDigest, digest) {
+		t.Errorf("MessageDigest = %x, want %x", info.MessageDigest, digest)
+	}
+	if !info.SigningTime.Equal(signed) {
+		t.Errorf("SigningTime = %v, want %v", info.SigningTime, signed)
+	}
+	want := [][]byte{{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, 0x00, 0x11, 0x22, 0x33, 0x44}}
+	if !reflect.DeepEqual(info.CDHashes, want) {
+		t.Errorf("CDHashes = %x, want %x", info.CDHashes, want)
+	}
+	if len(info.CDHashes2) != 1 || !info.CDHashes2[0].Algorithm.Equal(oidSHA256) || !bytes.Equal(info.CDHashes2[0].Hash, hash2.Hash) {
+		t.Errorf("CDHashes2 = %+v", info.CDHashes2)
+	}
+
+	// a signature without signed attributes
+	dat, _ = cmsSignature(t, nil)
+	if info, err := ParseCMSSignature(dat); err != nil || info.MessageDigest != nil || len(info.Certificates) != 1 {
+		t.Errorf("ParseCMSSignature() without attributes = %+v, %v", info, err)
+	}
+
+	if _, err := ParseCMSSignature(nil); err == nil {
+		t.Error("ParseCMSSignature() of an ad-hoc signature should fail")
+	}
+	other, _ := asn1.Marshal(cmsContentInfo{ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}})
+	if _, err := ParseCMSSignature(other); err == nil {
+		t.Error("ParseCMSSignature() of non SignedData content should fail")
+	}
+}
+
+func TestParseCMSSignatureTruncated(t *testing.T) {
+	dat, _ := cmsSignature(t, []cmsAttribute{
+		cmsAttr(t, oidAttrMessageDigest, bytes.Repeat([]byte{0xaa}, 32)),
+		cmsAttr(t, oidAttrSigningTime, time.Now()),
+	})
+	for n := 1; n < len(dat); n++ {
+		if _, err := ParseCMSSignature(dat[:n]); err == nil {
+			t.Fatalf("ParseCMSSignature() of the first %d of %d bytes should fail", n, len(dat))
+		}
+	}
+
+	// an attribute whose value isn't of the expected type
+	dat, _ = cmsSignature(t, []cmsAttribute{cmsAttr(t, oidAttrSigningTime, 42)})
+	if _, err := ParseCMSSignature(dat); err == nil {
+		t.Error("ParseCMSSignature() should fail for a malformed signing time")
+	}
+	dat, _ = cmsSignature(t, []cmsAttribute{cmsAttr(t, oidAttrAppleCDHashes, []byte("<plist><dict><array><data>!!</data></array></dict></plist>"))})
+	if _, err := ParseCMSSignature(dat); err == nil {
+		t.Error("ParseCMSSignature() should fail for malformed cdhashes plist data")
+	}
+}
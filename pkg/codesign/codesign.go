@@ -27,39 +27,55 @@ type CodeSignature struct {
 	LaunchConstraintsParent      []byte                `json:"launch_constraints_parent,omitempty"`
 	LaunchConstraintsResponsible []byte                `json:"launch_constraints_responsible,omitempty"`
 	LibraryConstraints           []byte                `json:"library_constraints,omitempty"`
+	Ticket                       []byte                `json:"ticket,omitempty"`
 	Errors                       []error               `json:"errors,omitempty"`
 }
 
-// MarshalJSON custom JSON marshaller for CodeSignature
-func (cs *CodeSignature) MarshalJSON() ([]byte, error) {
-	var (
-		err                         error
-		lcself, lcpar, lcresp, libc *types.LaunchContraints
-	)
+// LaunchConstraints are the decoded launch and library constraints of a code signature
+type LaunchConstraints struct {
+	Self        *types.LaunchContraints `json:"self,omitempty"`
+	Parent      *types.LaunchContraints `json:"parent,omitempty"`
+	Responsible *types.LaunchContraints `json:"responsible,omitempty"`
+	Library     *types.LaunchContraints `json:"library,omitempty"`
+}
+
+// LaunchConstraints decodes the launch (self/parent/responsible) and library constraint blobs
+func (cs *CodeSignature) LaunchConstraints() (*LaunchConstraints, error) {
+	var err error
+	lcs := &LaunchConstraints{}
 	if len(cs.LaunchConstraintsSelf) > 0 {
-		lcself, err = types.ParseLaunchContraints(cs.LaunchConstraintsSelf)
+		lcs.Self, err = types.ParseLaunchContraints(cs.LaunchConstraintsSelf)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse launch constraints (self): %w", err)
 		}
 	}
 	if len(cs.LaunchConstraintsParent) > 0 {
-		lcpar, err = types.ParseLaunchContraints(cs.LaunchConstraintsParent)
+		lcs.Parent, err = types.ParseLaunchContraints(cs.LaunchConstraintsParent)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse launch constraints (self): %w", err)
+			return nil, fmt.Errorf("failed to parse launch constraints (parent): %w", err)
 		}
 	}
 	if len(cs.LaunchConstraintsResponsible) > 0 {
-		lcresp, err = types.ParseLaunchContraints(cs.LaunchConstraintsResponsible)
+		lcs.Responsible, err = types.ParseLaunchContraints(cs.LaunchConstraintsResponsible)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse launch constraints (self): %w", err)
+			return nil, fmt.Errorf("failed to parse launch constraints (responsible): %w", err)
 		}
 	}
 	if len(cs.LibraryConstraints) > 0 {
-		libc, err = types.ParseLaunchContraints(cs.LibraryConstraints)
+		lcs.Library, err = types.ParseLaunchContraints(cs.LibraryConstraints)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse launch constraints (self): %w", err)
+			return nil, fmt.Errorf("failed to parse library constraints: %w", err)
 		}
 	}
+	return lcs, nil
+}
+
+// MarshalJSON custom JSON marshaller for CodeSignature
+func (cs *CodeSignature) MarshalJSON() ([]byte, error) {
+	lcs, err := cs.LaunchConstraints()
+	if err != nil {
+		return nil, err
+	}
 	return json.Marshal(&struct {
 		CodeDirectories              []types.CodeDirectory   `json:"code_directories,omitempty"`
 		Requirements                 []types.Requirement     `json:"requirements,omitempty"`
@@ -70,6 +86,7 @@ func (cs *CodeSignature) MarshalJSON() ([]byte, error) {
 		LaunchConstraintsParent      *types.LaunchContraints `json:"launch_constraints_parent,omitempty"`
 		LaunchConstraintsResponsible *types.LaunchContraints `json:"launch_constraints_responsible,omitempty"`
 		LibraryConstraints           *types.LaunchContraints `json:"library_constraints,omitempty"`
+		Ticket                       []byte                  `json:"ticket,omitempty"`
 		Errors                       []string                `json:"errors,omitempty"`
 	}{
 		CodeDirectories:              cs.CodeDirectories,
@@ -77,10 +94,11 @@ func (cs *CodeSignature) MarshalJSON() ([]byte, error) {
 		CMSSignature:                 cs.CMSSignature,
 		Entitlements:                 cs.Entitlements,
 		EntitlementsDER:              cs.EntitlementsDER,
-		LaunchConstraintsSelf:        lcself,
-		LaunchConstraintsParent:      lcpar,
-		LaunchConstraintsResponsible: lcresp,
-		LibraryConstraints:           libc,
+		LaunchConstraintsSelf:        lcs.Self,
+		LaunchConstraintsParent:      lcs.Parent,
+		LaunchConstraintsResponsible: lcs.Responsible,
+		LibraryConstraints:           lcs.Library,
+		Ticket:                       cs.Ticket,
 		Errors: func() []string {
 			var errs []string
 			for _, e := range cs.Errors {
@@ -118,6 +136,11 @@ func ParseCodeSignature(cmddat []byte) (*CodeSignature, error) {
 			if err != nil {
 				return nil, err
 			}
+			if cd.Header.Version < types.EARLIEST_VERSION {
+				cs.Errors = append(cs.Errors, fmt.Errorf("unsupported type or version of signature: %#x (too old)", cd.Header.Version))
+			} else if cd.Header.Version > types.COMPATIBILITY_LIMIT {
+				cs.Errors = append(cs.Errors, fmt.Errorf("unsupported type or version of signature: %#x (too new)", cd.Header.Version))
+			}
 			cs.CodeDirectories = append(cs.CodeDirectories, *cd)
 		case types.CSSLOT_REQUIREMENTS:
 			req := types.Requirement{}
@@ -137,11 +160,18 @@ func ParseCodeSignature(cmddat []byte) (*CodeSignature, error) {
 				if err := binary.Read(rqr, binary.BigEndian, &req.Requirements); err != nil {
 					return nil, err
 				}
-				detail, err := types.ParseRequirements(rqr, req.Requirements)
-				if err != nil {
-					return nil, err
+				if expr, err := types.ParseRequirement(rqr, req.Requirements); err != nil {
+					// keep the undecodable expression rather than failing the whole signature
+					req.Detail = fmt.Sprintf("failed to decode requirement: %v", err)
+					if int(req.Requirements.Offset) < len(reqData) {
+						req.Data = reqData[req.Requirements.Offset:]
+					}
+				} else {
+					req.Expression = expr
+					if req.Detail, err = types.RequirementDetail(req.Requirements.Type, expr); err != nil {
+						req.Detail = expr.String()
+					}
 				}
-				req.Detail = detail
 			} else {
 				req.Detail = "empty requirement set"
 			}
@@ -195,9 +225,23 @@ func ParseCodeSignature(cmddat []byte) (*CodeSignature, error) {
 		case types.CSSLOT_APPLICATION:
 			fallthrough // TODO 🤷‍♂️
 		case types.CSSLOT_IDENTIFICATIONSLOT:
-			fallthrough // TODO 🤷‍♂️
+			// TODO 🤷‍♂️
 		case types.CSSLOT_TICKETSLOT:
-			fallthrough // TODO 🤷‍♂️
+			// the stapled ticket isn't a blob (no big-endian length) so it extends to the next blob or the end of the SuperBlob
+			end := uint64(csBlob.Length)
+			if end > uint64(len(cmddat)) {
+				end = uint64(len(cmddat))
+			}
+			for _, idx := range csIndex {
+				if idx.Offset > index.Offset && uint64(idx.Offset) < end {
+					end = uint64(idx.Offset)
+				}
+			}
+			if uint64(index.Offset) >= end {
+				return nil, fmt.Errorf("invalid CSSLOT_TICKETSLOT offset: %#x", index.Offset)
+			}
+			cs.Ticket = make([]byte, end-uint64(index.Offset))
+			copy(cs.Ticket, cmddat[index.Offset:end])
 		case types.CSSLOT_LAUNCH_CONSTRAINT_SELF, types.CSSLOT_LAUNCH_CONSTRAINT_PARENT, types.CSSLOT_LAUNCH_CONSTRAINT_RESPONSIBLE, types.CSSLOT_LIBRARY_CONSTRAINT:
 			lcBlob := types.BlobHeader{}
 			if err := binary.Read(r, binary.BigEndian, &lcBlob); err != nil {
@@ -259,13 +303,6 @@ func parseCodeDirectory(r *bytes.Reader, offset uint32) (*types.CodeDirectory, e
 		cd.CDHash = fmt.Sprintf("unsupported code directory hash type %s, please notify author", cd.Header.HashType)
 	}
 
-	// Parse version
-	if cd.Header.Version < types.EARLIEST_VERSION {
-		fmt.Printf("unsupported type or version of signature: %#x (too old)\n", cd.Header.Version)
-	} else if cd.Header.Version > types.COMPATIBILITY_LIMIT {
-		fmt.Printf("unsupported type or version of signature: %#x (too new)\n", cd.Header.Version)
-	}
-
 	// SUPPORTS_SCATTER
 	if cd.Header.Version >= types.SUPPORTS_SCATTER {
 		r.Seek(int64(headoff), io.SeekStart)
@@ -0,0 +1,66 @@
+package codesign
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/blacktop/go-macho/pkg/codesign/types"
+)
+
+// requirementsSignature returns a code signature with a requirements set holding the designated requirement expr
+func requirementsSignature(t *testing.T, expr []uint32) []byte {
+	t.Helper()
+	var set bytes.Buffer
+	binary.Write(&set, binary.BigEndian, uint32(1))
+	binary.Write(&set, binary.BigEndian, types.Requirements{
+		Type:   types.DesignatedRequirementType,
+		Offset: uint32(binary.Size(types.RequirementsBlob{}) + binary.Size(types.Requirements{})),
+	})
+	binary.Write(&set, binary.BigEndian, types.RequirementsBlob{
+		Magic:  types.MAGIC_REQUIREMENT,
+		Length: uint32(binary.Size(types.RequirementsBlob{}) + binary.Size(expr)),
+		Data:   1,
+	})
+	binary.Write(&set, binary.BigEndian, expr)
+
+	sb := types.NewSuperBlob(types.MAGIC_EMBEDDED_SIGNATURE)
+	sb.AddBlob(types.CSSLOT_REQUIREMENTS, types.NewBlob(types.MAGIC_REQUIREMENTS, set.Bytes()))
+	var buf bytes.Buffer
+	if err := sb.Write(&buf, binary.BigEndian); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseCodeSignatureRequirements(t *testing.T) {
+	const opAnd, opIdent, opAppleAnchor = 6, 2, 3
+	tests := []struct {
+		name     string
+		expr     []uint32
+		want     string
+		wantData bool
+	}{
+		{"decoded", []uint32{opAnd, opIdent, 1, 'a' << 24, opAppleAnchor}, `identifier "a" and anchor apple`, false},
+		{"unknown opcode", []uint32{opAnd, opIdent, 1, 'a' << 24, 0x99}, `identifier "a" and /* exprOp(0x99) */`, false},
+		{"truncated", []uint32{opAnd, opIdent, 8}, "failed to decode requirement: failed to parse And operand: unexpected EOF", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := ParseCodeSignature(requirementsSignature(t, tt.expr))
+			if err != nil {
+				t.Fatalf("ParseCodeSignature() error = %v", err)
+			}
+			if len(cs.Requirements) != 1 {
+				t.Fatalf("ParseCodeSignature() requirements = %d, want 1", len(cs.Requirements))
+			}
+			req := cs.Requirements[0]
+			if req.Detail != tt.want {
+				t.Errorf("Detail = %s, want %s", req.Detail, tt.want)
+			}
+			if (req.Data != nil) != tt.wantData || (req.Expression != nil) == tt.wantData {
+				t.Errorf("Data = %x, Expression = %v", req.Data, req.Expression)
+			}
+		})
+	}
+}
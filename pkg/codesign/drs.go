@@ -0,0 +1,70 @@
+package codesign
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/blacktop/go-macho/pkg/codesign/types"
+)
+
+// DylibRequirement is the designated requirement ld copied from a linked dylib's code signature
+type DylibRequirement struct {
+	Index  uint32 `json:"index"`            // index of the dylib in the image's dylib load commands
+	Detail string `json:"detail,omitempty"` // the designated requirement (empty if the dylib was unsigned)
+}
+
+// ParseDylibCodeSignDRs parses the LC_DYLIB_CODE_SIGN_DRS data (a library dependency SuperBlob of requirements)
+func ParseDylibCodeSignDRs(dat []byte) ([]DylibRequirement, error) {
+	r := bytes.NewReader(dat)
+
+	var sb types.SbHeader
+	if err := binary.Read(r, binary.BigEndian, &sb); err != nil {
+		return nil, fmt.Errorf("failed to read library dependency blob header: %v", err)
+	}
+	if sb.Magic != types.MAGIC_LIBRARY_DEPENDENCY_BLOB {
+		return nil, fmt.Errorf("invalid library dependency blob magic: %s", sb.Magic)
+	}
+	if uint64(sb.Count)*uint64(binary.Size(types.BlobIndex{})) > uint64(r.Len()) {
+		return nil, fmt.Errorf("invalid library dependency blob count: %d", sb.Count)
+	}
+
+	index := make([]types.BlobIndex, sb.Count)
+	if err := binary.Read(r, binary.BigEndian, &index); err != nil {
+		return nil, fmt.Errorf("failed to read library dependency blob index: %v", err)
+	}
+
+	var drs []DylibRequirement
+	for _, idx := range index {
+		dr := DylibRequirement{Index: uint32(idx.Type)}
+		if idx.Offset == 0 { // no designated requirement for this dylib
+			drs = append(drs, dr)
+			continue
+		}
+		if uint64(idx.Offset)+uint64(binary.Size(types.RequirementsBlob{})) > uint64(len(dat)) {
+			return nil, fmt.Errorf("invalid requirement offset %#x for dylib %d", idx.Offset, idx.Type)
+		}
+		// a requirement blob is a header (magic, length, kind) followed by the expression
+		var req types.RequirementsBlob
+		if err := binary.Read(bytes.NewReader(dat[idx.Offset:]), binary.BigEndian, &req); err != nil {
+			return nil, fmt.Errorf("failed to read requirement blob for dylib %d: %v", idx.Type, err)
+		}
+		if req.Magic != types.MAGIC_REQUIREMENT {
+			return nil, fmt.Errorf("invalid requirement blob magic for dylib %d: %s", idx.Type, req.Magic)
+		}
+		if req.Length < uint32(binary.Size(req)) || uint64(idx.Offset)+uint64(req.Length) > uint64(len(dat)) {
+			return nil, fmt.Errorf("invalid requirement blob length for dylib %d: %#x", idx.Type, req.Length)
+		}
+		expr := dat[idx.Offset+uint32(binary.Size(req)) : idx.Offset+req.Length]
+		if len(expr) > 0 {
+			detail, err := types.ParseRequirements(bytes.NewReader(expr), types.Requirements{Type: types.DesignatedRequirementType})
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse requirement for dylib %d: %v", idx.Type, err)
+			}
+			dr.Detail = detail
+		}
+		drs = append(drs, dr)
+	}
+
+	return drs, nil
+}
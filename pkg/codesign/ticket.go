@@ -0,0 +1,39 @@
+package codesign
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+var notarizationTicketMagic = []byte("s8ch")
+
+// NotarizationTicket is a notarization ticket stapled into the code signature (CSSLOT_TICKETSLOT)
+type NotarizationTicket struct {
+	Magic   string `json:"magic"`
+	Version uint32 `json:"version"`
+	Raw     []byte `json:"raw,omitempty"` // the complete ticket as written by stapler(1)
+}
+
+// ParseNotarizationTicket does a basic sanity check of a stapled notarization ticket
+func ParseNotarizationTicket(dat []byte) (*NotarizationTicket, error) {
+	if len(dat) < 8 {
+		return nil, fmt.Errorf("notarization ticket too small: %d bytes", len(dat))
+	}
+	if !bytes.Equal(dat[:4], notarizationTicketMagic) {
+		return nil, fmt.Errorf("invalid notarization ticket magic: %#x", dat[:4])
+	}
+	return &NotarizationTicket{
+		Magic:   string(dat[:4]),
+		Version: binary.LittleEndian.Uint32(dat[4:]),
+		Raw:     dat,
+	}, nil
+}
+
+// NotarizationTicket returns the stapled notarization ticket of the code signature
+func (cs *CodeSignature) NotarizationTicket() (*NotarizationTicket, error) {
+	if len(cs.Ticket) == 0 {
+		return nil, fmt.Errorf("no notarization ticket stapled")
+	}
+	return ParseNotarizationTicket(cs.Ticket)
+}
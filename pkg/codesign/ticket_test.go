@@ -0,0 +1,57 @@
+package codesign
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/blacktop/go-macho/pkg/codesign/types"
+)
+
+func TestParseNotarizationTicket(t *testing.T) {
+	ticket := append([]byte("s8ch"), 1, 0, 0, 0, 0xde, 0xad, 0xbe, 0xef)
+	tk, err := ParseNotarizationTicket(ticket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.Magic != "s8ch" || tk.Version != 1 || !bytes.Equal(tk.Raw, ticket) {
+		t.Errorf("ParseNotarizationTicket() = %+v", tk)
+	}
+
+	for _, dat := range [][]byte{nil, ticket[:7], append([]byte("s9ch"), ticket[4:]...)} {
+		if _, err := ParseNotarizationTicket(dat); err == nil {
+			t.Errorf("ParseNotarizationTicket(%x) should fail", dat)
+		}
+	}
+}
+
+func TestCodeSignatureNotarizationTicket(t *testing.T) {
+	ticket := append([]byte("s8ch"), 1, 0, 0, 0, 0xde, 0xad, 0xbe, 0xef)
+
+	// the ticket (which has no blob header) is followed by an empty requirements set
+	const hdrSize = 12 + 2*8
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, []uint32{
+		uint32(types.MAGIC_EMBEDDED_SIGNATURE), uint32(hdrSize + len(ticket) + 12), 2,
+		uint32(types.CSSLOT_TICKETSLOT), hdrSize,
+		uint32(types.CSSLOT_REQUIREMENTS), uint32(hdrSize + len(ticket)),
+	})
+	buf.Write(ticket)
+	binary.Write(&buf, binary.BigEndian, []uint32{uint32(types.MAGIC_REQUIREMENTS), 12, 0})
+
+	cs, err := ParseCodeSignature(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk, err := cs.NotarizationTicket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tk.Raw, ticket) {
+		t.Errorf("NotarizationTicket().Raw = %x, want %x", tk.Raw, ticket)
+	}
+
+	if _, err := (&CodeSignature{}).NotarizationTicket(); err == nil {
+		t.Error("NotarizationTicket() should fail without a stapled ticket")
+	}
+}
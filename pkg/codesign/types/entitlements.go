@@ -0,0 +1,78 @@
+package types
+
+import (
+	"encoding/asn1"
+	"fmt"
+)
+
+type entitlementsDER struct {
+	Raw     asn1.RawContent
+	Version int64
+	Dict    asn1.RawValue
+}
+
+type entitlementsDERPair struct {
+	Raw asn1.RawContent
+	Key string `asn1:"utf8"`
+	Val asn1.RawValue
+}
+
+// ParseEntitlementsDER parses the ASN.1 DER entitlements blob data into a map
+func ParseEntitlementsDER(data []byte) (map[string]any, error) {
+	var ents entitlementsDER
+	if _, err := asn1.UnmarshalWithParams(data, &ents, typeAppl); err != nil {
+		return nil, fmt.Errorf("failed to ASN.1 parse DER entitlements: %v", err)
+	}
+	if ents.Version != 1 {
+		return nil, fmt.Errorf("unsupported DER entitlements version %d", ents.Version)
+	}
+	val, err := parseDERValue(ents.Dict)
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := val.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("DER entitlements root is not a dictionary")
+	}
+	return dict, nil
+}
+
+func parseDERValue(val asn1.RawValue) (any, error) {
+	switch {
+	case val.IsCompound && val.Class == asn1.ClassContextSpecific && val.Tag == 16: // dictionary
+		dict := make(map[string]any)
+		data := val.Bytes
+		for len(data) > 0 {
+			var pair entitlementsDERPair
+			var err error
+			data, err = asn1.Unmarshal(data, &pair)
+			if err != nil {
+				return nil, fmt.Errorf("failed to ASN.1 parse DER entitlements dictionary entry: %v", err)
+			}
+			dict[pair.Key], err = parseDERValue(pair.Val)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse DER entitlement '%s': %v", pair.Key, err)
+			}
+		}
+		return dict, nil
+	case val.IsCompound && val.Class == asn1.ClassUniversal && val.Tag == asn1.TagSequence: // array
+		array := make([]any, 0)
+		data := val.Bytes
+		for len(data) > 0 {
+			var elem asn1.RawValue
+			var err error
+			data, err = asn1.Unmarshal(data, &elem)
+			if err != nil {
+				return nil, fmt.Errorf("failed to ASN.1 parse DER entitlements array element: %v", err)
+			}
+			v, err := parseDERValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, v)
+		}
+		return array, nil
+	default:
+		return getValue(val)
+	}
+}
@@ -0,0 +1,99 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+// DER identifier octets of the CoreEntitlements encoding
+const (
+	derBool   = 0x01
+	derInt    = 0x02
+	derOctets = 0x04
+	derUTF8   = 0x0c
+	derArray  = 0x30 // SEQUENCE
+	derDict   = 0xb0 // [CONTEXT 16] (constructed)
+	derAppl   = 0x70 // [APPLICATION 16] (constructed)
+)
+
+// der returns the DER TLV of tag with the concatenated contents
+func der(tag byte, contents ...[]byte) []byte {
+	var body []byte
+	for _, c := range contents {
+		body = append(body, c...)
+	}
+	out := []byte{tag}
+	switch n := len(body); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	return append(out, body...)
+}
+
+func derStr(s string) []byte { return der(derUTF8, []byte(s)) }
+
+// derPair returns a dictionary entry of key and the DER value val
+func derPair(key string, val []byte) []byte { return der(derArray, derStr(key), val) }
+
+func TestParseEntitlementsDER(t *testing.T) {
+	dat := der(derAppl,
+		der(derInt, []byte{1}),
+		der(derDict,
+			derPair("com.apple.application-identifier", derStr("TEAMID.com.example.app")),
+			derPair("com.apple.security.app-sandbox", der(derBool, []byte{0xff})),
+			derPair("com.apple.developer.team-identifier", derStr("TEAMID")),
+			derPair("get-task-allow", der(derBool, []byte{0})),
+			derPair("keychain-access-groups", der(derArray, derStr("TEAMID.one"), derStr("TEAMID.two"))),
+			derPair("limit", der(derInt, []byte{0xff, 0x00})),
+			derPair("nested", der(derDict, derPair("empty", der(derArray)))),
+		),
+	)
+	ents, err := ParseEntitlementsDER(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"com.apple.application-identifier":    "TEAMID.com.example.app",
+		"com.apple.security.app-sandbox":      true,
+		"com.apple.developer.team-identifier": "TEAMID",
+		"get-task-allow":                      false,
+		"keychain-access-groups":              []any{"TEAMID.one", "TEAMID.two"},
+		"limit":                               int64(-256),
+		"nested":                              map[string]any{"empty": []any{}},
+	}
+	if !reflect.DeepEqual(ents, want) {
+		t.Errorf("ParseEntitlementsDER() = %#v, want %#v", ents, want)
+	}
+
+	for n := 0; n < len(dat); n++ {
+		if _, err := ParseEntitlementsDER(dat[:n]); err == nil {
+			t.Fatalf("ParseEntitlementsDER() of the first %d of %d bytes should fail", n, len(dat))
+		}
+	}
+}
+
+func TestParseEntitlementsDERMalformed(t *testing.T) {
+	version := der(derInt, []byte{1})
+	for _, tt := range []struct {
+		name string
+		dat  []byte
+	}{
+		{"version", der(derAppl, der(derInt, []byte{2}), der(derDict))},
+		{"array root", der(derAppl, version, der(derArray, derStr("a")))},
+		{"missing application tag", der(derArray, version, der(derDict))},
+		{"non string key", der(derAppl, version, der(derDict, der(derArray, der(derInt, []byte{1}), derStr("a"))))},
+		{"unsupported value", der(derAppl, version, der(derDict, derPair("a", der(derOctets, []byte{1, 2}))))},
+		{"invalid boolean", der(derAppl, version, der(derDict, derPair("a", der(derBool, []byte{1}))))},
+		{"non minimal integer", der(derAppl, version, der(derDict, derPair("a", der(derInt, []byte{0, 1}))))},
+		{"integer too large", der(derAppl, version, der(derDict, derPair("a", der(derInt, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}))))},
+		{"bad array element", der(derAppl, version, der(derDict, derPair("a", der(derArray, []byte{derUTF8, 5, 'a'}))))},
+	} {
+		if ents, err := ParseEntitlementsDER(tt.dat); err == nil {
+			t.Errorf("ParseEntitlementsDER() of %s = %v, should fail", tt.name, ents)
+		}
+	}
+}
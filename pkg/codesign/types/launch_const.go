@@ -102,6 +102,14 @@ func getValue(val asn1.RawValue) (any, error) {
 	}
 }
 
+// dictContents returns the properties of the dictionary val (or val itself if it isn't wrapped in one)
+func dictContents(val asn1.RawValue) []byte {
+	if val.IsCompound && val.Class == asn1.ClassContextSpecific {
+		return val.Bytes
+	}
+	return val.FullBytes
+}
+
 func parseReqs(data []byte) (req map[string]any, err error) {
 	var prop contraint
 
@@ -128,13 +136,13 @@ func parseReqs(data []byte) (req map[string]any, err error) {
 				switch prop.Key {
 				case "$and-array":
 					var andArray []asn1.RawValue
-					data, err = asn1.Unmarshal(prop.Val.FullBytes, &andArray)
+					_, err = asn1.Unmarshal(prop.Val.FullBytes, &andArray)
 					if err != nil {
 						return nil, fmt.Errorf("failed to ASN.1 parse launch contraint '$and-array' properties: %v", err)
 					}
 					req[prop.Key] = make([]any, 0, len(andArray))
 					for _, and := range andArray {
-						r, err := parseReqs(and.FullBytes)
+						r, err := parseReqs(dictContents(and))
 						if err != nil {
 							return nil, err
 						}
@@ -142,13 +150,13 @@ func parseReqs(data []byte) (req map[string]any, err error) {
 					}
 				case "$or-array":
 					var orArray []asn1.RawValue
-					data, err = asn1.Unmarshal(prop.Val.FullBytes, &orArray)
+					_, err = asn1.Unmarshal(prop.Val.FullBytes, &orArray)
 					if err != nil {
 						return nil, fmt.Errorf("failed to ASN.1 parse launch contraint '$or-array' properties: %v", err)
 					}
 					req[prop.Key] = make([]any, 0, len(orArray))
 					for _, or := range orArray {
-						r, err := parseReqs(or.FullBytes)
+						r, err := parseReqs(dictContents(or))
 						if err != nil {
 							return nil, err
 						}
@@ -156,7 +164,7 @@ func parseReqs(data []byte) (req map[string]any, err error) {
 					}
 				case "$query":
 					var query []Entitlement
-					data, err = asn1.Unmarshal(prop.Val.FullBytes, &query)
+					_, err = asn1.Unmarshal(prop.Val.FullBytes, &query)
 					if err != nil {
 						return nil, fmt.Errorf("failed to ASN.1 parse launch contraint '$query' properties: %v", err)
 					}
@@ -170,7 +178,7 @@ func parseReqs(data []byte) (req map[string]any, err error) {
 					}
 				case "$in":
 					var ins []asn1.RawValue
-					data, err = asn1.Unmarshal(prop.Val.FullBytes, &ins)
+					_, err = asn1.Unmarshal(prop.Val.FullBytes, &ins)
 					if err != nil {
 						return nil, fmt.Errorf("failed to ASN.1 parse launch contraint '$in' properties: %v", err)
 					}
@@ -183,7 +191,10 @@ func parseReqs(data []byte) (req map[string]any, err error) {
 						req[prop.Key] = append(req[prop.Key].([]any), val)
 					}
 				default:
-					return nil, fmt.Errorf("unsupported requirement key %s (notify author)", prop.Key)
+					req[prop.Key], err = parseDERValue(prop.Val)
+					if err != nil {
+						return nil, fmt.Errorf("failed to ASN.1 parse launch contraint '%s' property: %v", prop.Key, err)
+					}
 				}
 			}
 		} else {
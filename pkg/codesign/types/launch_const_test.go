@@ -0,0 +1,92 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+// launchConstraints returns DER launch constraints with the requirements reqs
+func launchConstraints(reqs ...[]byte) []byte {
+	return der(derAppl,
+		der(derInt, []byte{1}),
+		der(derDict,
+			derPair("ccat", der(derInt, []byte{0})),
+			derPair("comp", der(derInt, []byte{1})),
+			derPair("reqs", der(derDict, reqs...)),
+			derPair("vers", der(derInt, []byte{1})),
+		),
+	)
+}
+
+func TestParseLaunchContraints(t *testing.T) {
+	dat := launchConstraints(
+		derPair("team-identifier", derStr("TEAMID")),
+		derPair("is-init-proc", der(derBool, []byte{0xff})),
+		derPair("launch-type", der(derInt, []byte{2})),
+		derPair("$or-array", der(derArray,
+			der(derDict, derPair("signing-identifier", derStr("com.example.one"))),
+			der(derDict, derPair("signing-identifier", derStr("com.example.two"))),
+		)),
+		derPair("$and-array", der(derArray,
+			der(derDict, derPair("validation-category", der(derInt, []byte{1}))),
+		)),
+		derPair("signing-identifier", der(derDict, derPair("$in", der(derArray, derStr("a"), derStr("b"))))),
+		derPair("entitlements", der(derDict, derPair("$query", der(derArray,
+			der(derArray, der(derInt, []byte{1}), derStr("com.apple.private.example")),
+		)))),
+		derPair("info", der(derArray, derStr("x"), der(derInt, []byte{3}))),
+	)
+	lc, err := ParseLaunchContraints(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &LaunchContraints{
+		Count:   1,
+		CCAT:    0,
+		COMP:    1,
+		Version: 1,
+		Requirements: map[string]any{
+			"team-identifier": "TEAMID",
+			"is-init-proc":    true,
+			"launch-type":     int64(2),
+			"$or-array": []any{
+				map[string]any{"signing-identifier": "com.example.one"},
+				map[string]any{"signing-identifier": "com.example.two"},
+			},
+			"$and-array": []any{
+				map[string]any{"validation-category": int64(1)},
+			},
+			"signing-identifier": map[string]any{"$in": []any{"a", "b"}},
+			"entitlements":       map[string]any{"$query": [][]any{{int64(1), "com.apple.private.example"}}},
+			"info":               []any{"x", int64(3)},
+		},
+	}
+	if !reflect.DeepEqual(lc, want) {
+		t.Errorf("ParseLaunchContraints() = %#v, want %#v", lc, want)
+	}
+}
+
+func TestParseLaunchContraintsMalformed(t *testing.T) {
+	dat := launchConstraints(derPair("team-identifier", derStr("TEAMID")))
+	for n := 0; n < len(dat); n++ {
+		if _, err := ParseLaunchContraints(dat[:n]); err == nil {
+			t.Fatalf("ParseLaunchContraints() of the first %d of %d bytes should fail", n, len(dat))
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		dat  []byte
+	}{
+		{"unknown field", der(derAppl, der(derInt, []byte{1}), der(derDict, derPair("what", der(derInt, []byte{0}))))},
+		{"bad ccat", der(derAppl, der(derInt, []byte{1}), der(derDict, derPair("ccat", der(derInt, []byte{0, 0}))))},
+		{"unsupported value", launchConstraints(derPair("a", der(derOctets, []byte{1})))},
+		{"bad $in value", launchConstraints(derPair("a", der(derDict, derPair("$in", der(derArray, der(derOctets))))))},
+		{"bad $query", launchConstraints(derPair("a", der(derDict, derPair("$query", der(derArray, derStr("x"))))))},
+		{"bad $or-array element", launchConstraints(derPair("$or-array", der(derArray, der(derDict, derPair("a", der(derOctets))))))},
+	} {
+		if lc, err := ParseLaunchContraints(tt.dat); err == nil {
+			t.Errorf("ParseLaunchContraints() of %s = %+v, should fail", tt.name, lc)
+		}
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io"
 	"math"
-	"strings"
 
 	mtypes "github.com/blacktop/go-macho/types"
 )
@@ -17,7 +16,9 @@ import (
 type Requirement struct {
 	RequirementsBlob
 	Requirements
-	Detail string `json:"detail,omitempty"`
+	Detail     string           `json:"detail,omitempty"`
+	Expression *RequirementExpr `json:"expression,omitempty"`
+	Data       []byte           `json:"data,omitempty"` // raw expression when it could not be decoded
 }
 
 // RequirementsBlob object
@@ -108,7 +109,7 @@ const (
 )
 
 func (o exprOp) String() string {
-	return [...]string{
+	names := [...]string{
 		"False",
 		"True",
 		"Ident",
@@ -129,8 +130,11 @@ func (o exprOp) String() string {
 		"CertPolicy",
 		"NamedAnchor",
 		"NamedCode",
-		"exprOpCount",
-	}[0]
+	}
+	if o < exprOpCount {
+		return names[o]
+	}
+	return fmt.Sprintf("exprOp(%#x)", uint32(o))
 }
 
 type matchOp uint32
@@ -146,10 +150,16 @@ const (
 	matchGreaterThan                 // greater than (string with numeric comparison)
 	matchLessEqual                   // less or equal (string with numeric comparison)
 	matchGreaterEqual                // greater or equal (string with numeric comparison)
+	matchOn                          // on (timestamp comparison)
+	matchBefore                      // before (timestamp comparison)
+	matchAfter                       // after (timestamp comparison)
+	matchOnOrBefore                  // on or before (timestamp comparison)
+	matchOnOrAfter                   // on or after (timestamp comparison)
+	matchAbsent                      // not present (kCFNull)
 )
 
 func (o matchOp) String() string {
-	return [...]string{
+	names := [...]string{
 		"Exists",
 		"Equal",
 		"Contains",
@@ -159,7 +169,17 @@ func (o matchOp) String() string {
 		"GreaterThan",
 		"LessEqual",
 		"GreaterEqual",
-	}[0]
+		"On",
+		"Before",
+		"After",
+		"OnOrBefore",
+		"OnOrAfter",
+		"Absent",
+	}
+	if int(o) < len(names) {
+		return names[o]
+	}
+	return fmt.Sprintf("matchOp(%#x)", uint32(o))
 }
 
 const (
@@ -179,102 +199,24 @@ func getData(r *bytes.Reader) ([]byte, error) {
 	// 4 byte align length
 	alignedLength := uint32(mtypes.RoundUp(uint64(idLength), 4))
 
+	if uint64(alignedLength) > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
 	data := make([]byte, alignedLength)
 
-	_, err = r.Read(data)
-	if err != nil {
+	if _, err = io.ReadFull(r, data); err != nil {
 		return nil, err
 	}
 
 	return data[:idLength], nil
 }
 
-func getMatch(r *bytes.Reader) (string, error) {
-	var op matchOp
-	err := binary.Read(r, binary.BigEndian, &op)
-	if err != nil {
-		return "", err
-	}
-
-	switch op {
-	case matchExists:
-		return " /* exists */", nil
-	case matchEqual:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf(" = \"%s\"", data), nil
-	case matchContains:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf(" ~ %s", data), nil
-	case matchBeginsWith:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf(" = %s*", data), nil
-	case matchEndsWith:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf(" = *%s", data), nil
-	case matchLessThan:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf(" < %s", data), nil
-	case matchGreaterThan:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf(" >= %s", data), nil
-	case matchLessEqual:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf(" <= %s", data), nil
-	case matchGreaterEqual:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf(" > %s", data), nil
-	}
-	return "", fmt.Errorf("MATCH OPCODE %d NOT UNDERSTOOD", op)
-}
-
 const (
 	// certificate positions (within a standard certificate chain)
 	leafCert   int32 = 0  // index for leaf (first in chain)
 	anchorCert int32 = -1 // index for anchor (last in chain)
 )
 
-func getCertSlot(r *bytes.Reader) (string, error) {
-	var slot int32
-
-	err := binary.Read(r, binary.BigEndian, &slot)
-	if err != nil {
-		return "", err
-	}
-
-	switch slot {
-	case leafCert:
-		return "leaf", nil
-	case anchorCert:
-		return "root", nil
-	default:
-		return fmt.Sprintf("%d", slot), nil
-	}
-}
-
 const (
 	slPrimary = iota // syntax primary
 	slAnd            // conjunctive
@@ -336,227 +278,41 @@ func toOID(data []byte) string {
 	return oidStr
 }
 
-func evalExpression(r *bytes.Reader, syntaxLevel int) (string, error) {
-	var op exprOp
-
-	err := binary.Read(r, binary.BigEndian, &op)
-	if err != nil {
-		return "", err
-	}
-
-	switch op {
-	case opFalse:
-		return "never", nil
-	case opTrue:
-		return "always", nil
-	case opIdent:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf("identifier \"%s\"", data), nil
-	case opAppleAnchor:
-		return "anchor apple", nil
-	case opAppleGenericAnchor:
-		return "anchor apple generic", nil
-	case opAnchorHash:
-		slot, err := getCertSlot(r)
-		if err != nil {
-			return "", err
-		}
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		} // TODO data is a hashData str
-		return fmt.Sprintf("certificate %s = %s", slot, data), nil
-	case opInfoKeyValue:
-		dot, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		} // TODO dot is a dot str
-		return fmt.Sprintf("info[%s] = %s", dot, data), nil
-	case opAnd:
-		var out string
-		if syntaxLevel < slAnd {
-			out += "("
-		}
-		part, err := evalExpression(r, slAnd)
-		if err != nil {
-			return "", err
-		}
-		out += part
-		out += " and "
-		part, err = evalExpression(r, slAnd)
-		if err != nil {
-			return "", err
-		}
-		out += part
-		if syntaxLevel < slAnd {
-			out += ")"
-		}
-		return out, nil
-	case opOr:
-		var out string
-		if syntaxLevel < slOr {
-			out += "("
-		}
-		part, err := evalExpression(r, slOr)
-		if err != nil {
-			return "", err
-		}
-		out += part
-		out += " or "
-		part, err = evalExpression(r, slOr)
-		if err != nil {
-			return "", err
-		}
-		out += part
-		if syntaxLevel < slOr {
-			out += ")"
-		}
-		return out, nil
-	case opNot:
-		part, err := evalExpression(r, slPrimary)
-		if err != nil {
-			return "", err
-		}
-		return "! " + part, nil
-	case opCDHash:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		} // TODO data is a hashData str
-		return fmt.Sprintf(" cdhash %s", data), nil
-	case opInfoKeyField:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		match, err := getMatch(r)
-		if err != nil {
-			return "", err
-		} // TODO data is a dot str
-		return fmt.Sprintf("info[%s] %s", data, match), nil
-	case opEntitlementField:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		match, err := getMatch(r)
-		if err != nil {
-			return "", err
-		} // TODO data is a dot str
-		return fmt.Sprintf("entitlement[%s] %s", data, match), nil
-	case opCertField:
-		slot, err := getCertSlot(r)
-		if err != nil {
-			return "", err
-		}
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		match, err := getMatch(r)
-		if err != nil {
-			return "", err
-		} // TODO data is a dot str
-		return fmt.Sprintf("certificate %s[%s] %s", slot, data, match), nil
-	case opCertGeneric:
-		slot, err := getCertSlot(r)
-		if err != nil {
-			return "", err
-		}
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		match, err := getMatch(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf("certificate %s[field.%s] %s", slot, toOID(data), match), nil
-	case opCertPolicy:
-		slot, err := getCertSlot(r)
-		if err != nil {
-			return "", err
-		}
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		match, err := getMatch(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf("certificate %s [policy.%s] %s", slot, toOID(data), match), nil
-	case opTrustedCert:
-		slot, err := getCertSlot(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf("certificate %s trusted", slot), nil
-	case opTrustedCerts:
-		return "anchor trusted", nil
-	case opNamedAnchor:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf("anchor apple %s", string(data)), nil
-	case opNamedCode:
-		data, err := getData(r)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf("(%s)", string(data)), nil
-	default:
-		if (op & opGenericFalse) != 0 {
-			return fmt.Sprintf(" false /* opcode %d */", op & ^opFlagMask), nil
-		} else if (op & opGenericSkip) != 0 {
-			return fmt.Sprintf(" /* opcode %d */", op & ^opFlagMask), nil
-		}
-		return fmt.Sprintf("OPCODE %d NOT UNDERSTOOD (ending print)", op), nil
+// ParseRequirement parses the requirement expression of a requirements set entry into its AST
+//
+// Decoding stops at the first opcode that is not understood (and can't be skipped), the returned AST then
+// ends with a node for that opcode.
+func ParseRequirement(r *bytes.Reader, reqs Requirements) (*RequirementExpr, error) {
+	if _, err := r.Seek(int64(reqs.Offset), io.SeekStart); err != nil {
+		return nil, err
 	}
+	return decodeExpr(r)
 }
 
 // ParseRequirements parses the requirements set bytes
 func ParseRequirements(r *bytes.Reader, reqs Requirements) (string, error) {
-	// NOTE: codesign -d -r- MACHO (to display requirement sets)
-	r.Seek(int64(reqs.Offset), io.SeekStart)
+	expr, err := ParseRequirement(r, reqs)
+	if err != nil {
+		return "", err
+	}
+	return RequirementDetail(reqs.Type, expr)
+}
 
-	switch reqs.Type {
+// RequirementDetail returns the text of a decoded requirement of the given type (as shown by codesign -d -r-)
+func RequirementDetail(typ RequirementType, expr *RequirementExpr) (string, error) {
+	switch typ {
 	case HostRequirementType:
-		var reqSet []string
-		for {
-			rsPart, err := evalExpression(r, slTop)
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return "", err
-			}
-			reqSet = append(reqSet, rsPart)
-		}
-		return "host => " + strings.Join(reqSet, " "), nil
+		return "host => " + expr.String(), nil
+	case GuestRequirementType:
+		return "guest => " + expr.String(), nil
 	case DesignatedRequirementType:
-		var reqSet []string
-		for {
-			rsPart, err := evalExpression(r, slTop)
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return "", err
-			}
-			reqSet = append(reqSet, rsPart)
-		}
-		return strings.Join(reqSet, " "), nil
+		return expr.String(), nil
+	case LibraryRequirementType:
+		return "library => " + expr.String(), nil
+	case PluginRequirementType:
+		return "plugin => " + expr.String(), nil
 	default:
-		return "", fmt.Errorf("failed to dump requirements set; found unsupported codesign requirement type '%s', please notify author", reqs.Type)
+		return "", fmt.Errorf("failed to dump requirements set; found unsupported codesign requirement type '%s', please notify author", typ)
 	}
 }
 
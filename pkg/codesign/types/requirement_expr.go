@@ -0,0 +1,296 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errNotUnderstood stops decoding at an opcode whose operands can't be skipped
+var errNotUnderstood = errors.New("opcode not understood")
+
+// RequirementMatch is the match suffix of a requirement expression
+type RequirementMatch struct {
+	Op    string `json:"op"`
+	Value string `json:"value,omitempty"`
+}
+
+func (m *RequirementMatch) String() string {
+	switch m.Op {
+	case matchExists.String():
+		return " /* exists */"
+	case matchAbsent.String():
+		return " absent"
+	case matchEqual.String():
+		return fmt.Sprintf(" = %q", m.Value)
+	case matchContains.String():
+		return fmt.Sprintf(" ~ %q", m.Value)
+	case matchBeginsWith.String():
+		return fmt.Sprintf(" = %q", m.Value+"*")
+	case matchEndsWith.String():
+		return fmt.Sprintf(" = %q", "*"+m.Value)
+	case matchLessThan.String():
+		return fmt.Sprintf(" < %q", m.Value)
+	case matchGreaterThan.String():
+		return fmt.Sprintf(" > %q", m.Value)
+	case matchLessEqual.String():
+		return fmt.Sprintf(" <= %q", m.Value)
+	case matchGreaterEqual.String():
+		return fmt.Sprintf(" >= %q", m.Value)
+	case matchOn.String():
+		return fmt.Sprintf(" = timestamp %q", m.Value)
+	case matchBefore.String():
+		return fmt.Sprintf(" < timestamp %q", m.Value)
+	case matchAfter.String():
+		return fmt.Sprintf(" > timestamp %q", m.Value)
+	case matchOnOrBefore.String():
+		return fmt.Sprintf(" <= timestamp %q", m.Value)
+	case matchOnOrAfter.String():
+		return fmt.Sprintf(" >= timestamp %q", m.Value)
+	default:
+		return fmt.Sprintf(" /* %s */", m.Op)
+	}
+}
+
+// RequirementExpr is a node in the AST of a code requirement expression
+type RequirementExpr struct {
+	Op    string             `json:"op"`
+	Args  []*RequirementExpr `json:"args,omitempty"`  // operands of And, Or and Not
+	Cert  string             `json:"cert,omitempty"`  // certificate slot (leaf, root or chain index)
+	Key   string             `json:"key,omitempty"`   // identifier, info/entitlement key, certificate field/OID or anchor/code name
+	Value string             `json:"value,omitempty"` // legacy info key value
+	Hash  []byte             `json:"hash,omitempty"`  // anchor or CodeDirectory hash
+	Match *RequirementMatch  `json:"match,omitempty"`
+}
+
+// String returns the canonical csreq(1) text form of the requirement expression
+func (e *RequirementExpr) String() string {
+	return e.text(slTop)
+}
+
+func (e *RequirementExpr) text(syntaxLevel int) string {
+	switch e.Op {
+	case opFalse.String():
+		return "never"
+	case opTrue.String():
+		return "always"
+	case opIdent.String():
+		return fmt.Sprintf("identifier %q", e.Key)
+	case opAppleAnchor.String():
+		return "anchor apple"
+	case opAppleGenericAnchor.String():
+		return "anchor apple generic"
+	case opAnchorHash.String():
+		return fmt.Sprintf("certificate %s = H\"%x\"", e.Cert, e.Hash)
+	case opInfoKeyValue.String():
+		return fmt.Sprintf("info[%s] = %q", e.Key, e.Value)
+	case opAnd.String(), opOr.String():
+		level, sep := slAnd, " and "
+		if e.Op == opOr.String() {
+			level, sep = slOr, " or "
+		}
+		var parts []string
+		for _, arg := range e.Args {
+			parts = append(parts, arg.text(level))
+		}
+		if syntaxLevel < level {
+			return "(" + strings.Join(parts, sep) + ")"
+		}
+		return strings.Join(parts, sep)
+	case opNot.String():
+		if len(e.Args) == 0 {
+			return "! <missing>"
+		}
+		return "! " + e.Args[0].text(slPrimary)
+	case opCDHash.String():
+		return fmt.Sprintf("cdhash H\"%x\"", e.Hash)
+	case opInfoKeyField.String():
+		return fmt.Sprintf("info[%s]%s", e.Key, e.Match)
+	case opEntitlementField.String():
+		return fmt.Sprintf("entitlement[%s]%s", e.Key, e.Match)
+	case opCertField.String():
+		return fmt.Sprintf("certificate %s[%s]%s", e.Cert, e.Key, e.Match)
+	case opCertGeneric.String():
+		return fmt.Sprintf("certificate %s[field.%s]%s", e.Cert, e.Key, e.Match)
+	case opCertPolicy.String():
+		return fmt.Sprintf("certificate %s[policy.%s]%s", e.Cert, e.Key, e.Match)
+	case opTrustedCert.String():
+		return fmt.Sprintf("certificate %s trusted", e.Cert)
+	case opTrustedCerts.String():
+		return "anchor trusted"
+	case opNamedAnchor.String():
+		return fmt.Sprintf("anchor apple %s", e.Key)
+	case opNamedCode.String():
+		return fmt.Sprintf("(%s)", e.Key)
+	default:
+		return fmt.Sprintf("/* %s */", e.Op)
+	}
+}
+
+func parseCertSlot(r *bytes.Reader) (string, error) {
+	var slot int32
+	if err := binary.Read(r, binary.BigEndian, &slot); err != nil {
+		return "", err
+	}
+	switch slot {
+	case leafCert:
+		return "leaf", nil
+	case anchorCert:
+		return "root", nil
+	default:
+		return fmt.Sprintf("%d", slot), nil
+	}
+}
+
+func parseMatch(r *bytes.Reader) (*RequirementMatch, error) {
+	var op matchOp
+	if err := binary.Read(r, binary.BigEndian, &op); err != nil {
+		return nil, err
+	}
+	m := &RequirementMatch{Op: op.String()}
+	switch op {
+	case matchExists, matchAbsent:
+		return m, nil
+	case matchOn, matchBefore, matchAfter, matchOnOrBefore, matchOnOrAfter:
+		var ts int64 // CFAbsoluteTime
+		if err := binary.Read(r, binary.BigEndian, &ts); err != nil {
+			return nil, err
+		}
+		m.Value = fmt.Sprintf("%d", ts)
+		return m, nil
+	default:
+		if op > matchAbsent {
+			return m, errNotUnderstood
+		}
+		data, err := getData(r)
+		if err != nil {
+			return nil, err
+		}
+		m.Value = string(data)
+		return m, nil
+	}
+}
+
+// ParseRequirementExpr decodes a requirement expression program into its AST (see ParseRequirement)
+func ParseRequirementExpr(data []byte) (*RequirementExpr, error) {
+	return decodeExpr(bytes.NewReader(data))
+}
+
+// decodeExpr decodes an expression program keeping what was decoded before an opcode that is not understood
+func decodeExpr(r *bytes.Reader) (*RequirementExpr, error) {
+	e, err := parseExpr(r)
+	if errors.Is(err, errNotUnderstood) {
+		return e, nil
+	}
+	return e, err
+}
+
+// parseExpr decodes the expression at r; on errNotUnderstood the partially decoded expression is returned
+// along with the error so the caller can stop there
+func parseExpr(r *bytes.Reader) (*RequirementExpr, error) {
+	var op exprOp
+	if err := binary.Read(r, binary.BigEndian, &op); err != nil {
+		return nil, err
+	}
+
+	e := &RequirementExpr{Op: op.String()}
+
+	var err error
+	switch op {
+	case opFalse, opTrue, opAppleAnchor, opAppleGenericAnchor, opTrustedCerts:
+	case opIdent, opNamedAnchor, opNamedCode:
+		var data []byte
+		if data, err = getData(r); err != nil {
+			return nil, err
+		}
+		e.Key = string(data)
+	case opAnchorHash:
+		if e.Cert, err = parseCertSlot(r); err != nil {
+			return nil, err
+		}
+		if e.Hash, err = getData(r); err != nil {
+			return nil, err
+		}
+	case opCDHash:
+		if e.Hash, err = getData(r); err != nil {
+			return nil, err
+		}
+	case opInfoKeyValue:
+		var key, val []byte
+		if key, err = getData(r); err != nil {
+			return nil, err
+		}
+		if val, err = getData(r); err != nil {
+			return nil, err
+		}
+		e.Key, e.Value = string(key), string(val)
+	case opAnd, opOr:
+		for i := 0; i < 2; i++ {
+			arg, err := parseExpr(r)
+			if errors.Is(err, errNotUnderstood) {
+				e.Args = append(e.Args, arg)
+				return e, err
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to parse %s operand: %v", op, err)
+			}
+			e.Args = append(e.Args, arg)
+		}
+	case opNot:
+		arg, err := parseExpr(r)
+		if errors.Is(err, errNotUnderstood) {
+			e.Args = append(e.Args, arg)
+			return e, err
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse %s operand: %v", op, err)
+		}
+		e.Args = append(e.Args, arg)
+	case opInfoKeyField, opEntitlementField:
+		var key []byte
+		if key, err = getData(r); err != nil {
+			return nil, err
+		}
+		e.Key = string(key)
+		if e.Match, err = parseMatch(r); errors.Is(err, errNotUnderstood) {
+			return e, err
+		} else if err != nil {
+			return nil, err
+		}
+	case opCertField, opCertGeneric, opCertPolicy:
+		if e.Cert, err = parseCertSlot(r); err != nil {
+			return nil, err
+		}
+		var key []byte
+		if key, err = getData(r); err != nil {
+			return nil, err
+		}
+		if op == opCertField {
+			e.Key = string(key)
+		} else {
+			e.Key = toOID(key)
+		}
+		if e.Match, err = parseMatch(r); errors.Is(err, errNotUnderstood) {
+			return e, err
+		} else if err != nil {
+			return nil, err
+		}
+	case opTrustedCert:
+		if e.Cert, err = parseCertSlot(r); err != nil {
+			return nil, err
+		}
+	default:
+		if op&(opGenericFalse|opGenericSkip) == 0 {
+			return e, errNotUnderstood
+		}
+		// unknown opcodes with these flags have a size field so they can be skipped
+		if _, err := getData(r); err != nil {
+			return nil, err
+		}
+		if op&opGenericFalse != 0 {
+			e.Op = opFalse.String()
+		}
+	}
+
+	return e, nil
+}
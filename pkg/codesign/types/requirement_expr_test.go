@@ -0,0 +1,130 @@
+package types
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"testing"
+)
+
+// program assembles a requirement expression program from opcodes and operands
+func program(parts ...any) []byte {
+	var ops []uint32
+	for _, p := range parts {
+		switch v := p.(type) {
+		case exprOp:
+			ops = append(ops, uint32(v))
+		case matchOp:
+			ops = append(ops, uint32(v))
+		case uint32:
+			ops = append(ops, v)
+		case string:
+			ops = append(ops, encodeBytes([]byte(v))...)
+		case []byte:
+			ops = append(ops, encodeBytes(v)...)
+		}
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, ops)
+	return buf.Bytes()
+}
+
+func TestParseRequirementExpr(t *testing.T) {
+	oid := encodeOID(asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 2, 6})
+	hash := []byte{0xde, 0xad, 0xbe, 0xef}
+	tests := []struct {
+		name string
+		prog []byte
+		want string
+	}{
+		{"false", program(opFalse), "never"},
+		{"true", program(opTrue), "always"},
+		{"ident", program(opIdent, "com.apple.ls"), `identifier "com.apple.ls"`},
+		{"apple anchor", program(opAppleAnchor), "anchor apple"},
+		{"anchor hash", program(opAnchorHash, anchorCertIndex, hash), `certificate root = H"deadbeef"`},
+		{"info key value", program(opInfoKeyValue, "CFBundleName", "ls"), `info[CFBundleName] = "ls"`},
+		{"and", program(opAnd, opIdent, "a", opAppleAnchor), `identifier "a" and anchor apple`},
+		{"or", program(opOr, opIdent, "a", opIdent, "b"), `identifier "a" or identifier "b"`},
+		{"or in and", program(opAnd, opOr, opIdent, "a", opIdent, "b", opTrue), `(identifier "a" or identifier "b") and always`},
+		{"cdhash", program(opCDHash, hash), `cdhash H"deadbeef"`},
+		{"not", program(opNot, opTrue), "! always"},
+		{"info key field", program(opInfoKeyField, "CFBundleVersion", matchGreaterEqual, "2"), `info[CFBundleVersion] >= "2"`},
+		{"cert field", program(opCertField, leafCertIndex, "subject.CN", matchEqual, "Apple"), `certificate leaf[subject.CN] = "Apple"`},
+		{"trusted cert", program(opTrustedCert, uint32(1)), "certificate 1 trusted"},
+		{"trusted certs", program(opTrustedCerts), "anchor trusted"},
+		{"cert generic", program(opCertGeneric, uint32(1), oid, matchExists), "certificate 1[field.1.2.840.113635.100.6.2.6] /* exists */"},
+		{"apple generic anchor", program(opAppleGenericAnchor), "anchor apple generic"},
+		{"entitlement field", program(opEntitlementField, "com.apple.private", matchAbsent), "entitlement[com.apple.private] absent"},
+		{"cert policy", program(opCertPolicy, leafCertIndex, oid, matchExists), "certificate leaf[policy.1.2.840.113635.100.6.2.6] /* exists */"},
+		{"named anchor", program(opNamedAnchor, "beta"), "anchor apple beta"},
+		{"named code", program(opNamedCode, "platform"), "(platform)"},
+		{"skippable", program(opAnd, opGenericSkip|0x99, "xx", opTrue), "/* exprOp(0x40000099) */ and always"},
+		{"generic false", program(opGenericFalse|0x99, "xx"), "never"},
+		{"match timestamp", program(opInfoKeyField, "Date", matchBefore, uint32(0), uint32(42)), `info[Date] < timestamp "42"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := ParseRequirementExpr(tt.prog)
+			if err != nil {
+				t.Fatalf("ParseRequirementExpr() error = %v", err)
+			}
+			if got := e.String(); got != tt.want {
+				t.Errorf("ParseRequirementExpr() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRequirementExprNotUnderstood(t *testing.T) {
+	tests := []struct {
+		name string
+		prog []byte
+		want string
+	}{
+		{"opcode", program(opAnd, opIdent, "a", exprOp(0x99), opTrue), `identifier "a" and /* exprOp(0x99) */`},
+		{"not operand", program(opNot, exprOp(0x99)), "! /* exprOp(0x99) */"},
+		{"match opcode", program(opInfoKeyField, "k", matchOp(0x99), "v"), "info[k] /* matchOp(0x99) */"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := ParseRequirementExpr(tt.prog)
+			if err != nil {
+				t.Fatalf("ParseRequirementExpr() error = %v", err)
+			}
+			if got := e.String(); got != tt.want {
+				t.Errorf("ParseRequirementExpr() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRequirementExprTruncated(t *testing.T) {
+	prog := program(opAnd, opIdent, "com.apple.ls", opCertField, leafCertIndex, "subject.CN", matchEqual, "Apple")
+	for i := 0; i < len(prog); i++ {
+		if _, err := ParseRequirementExpr(prog[:i]); err == nil {
+			t.Errorf("ParseRequirementExpr(%d bytes) succeeded on a truncated program", i)
+		}
+	}
+}
+
+func TestCreateRequirementsRoundTrip(t *testing.T) {
+	blob, err := CreateRequirements("com.example.hello", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the requirements set offsets are relative to the blob, which starts with its 8 byte header
+	r := bytes.NewReader(append(make([]byte, binary.Size(BlobHeader{})), blob.Data...))
+	r.Seek(int64(binary.Size(BlobHeader{})+4), 0)
+	var reqs Requirements
+	if err := binary.Read(r, binary.BigEndian, &reqs); err != nil {
+		t.Fatal(err)
+	}
+	reqs.Offset += uint32(binary.Size(RequirementsBlob{}))
+	got, err := ParseRequirements(r, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `identifier "com.example.hello"`; got != want {
+		t.Errorf("ParseRequirements() = %s, want %s", got, want)
+	}
+}
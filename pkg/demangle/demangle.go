@@ -0,0 +1,39 @@
+// Package demangle demangles Itanium C++ (_Z...) and Swift ($s...) symbol names in pure Go
+package demangle
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNotMangled is returned for names that are not C++ or Swift mangled names
+var ErrNotMangled = errors.New("not a mangled name")
+
+// Demangle demangles a C++ or Swift symbol name (with or without the leading '_' MachO symbols have)
+func Demangle(name string) (string, error) {
+	switch {
+	case IsSwift(name):
+		return demangleSwift(name)
+	case IsItanium(name):
+		return demangleItanium(name)
+	}
+	return "", ErrNotMangled
+}
+
+// IsItanium reports whether name looks like an Itanium C++ mangled name (including block invocations)
+func IsItanium(name string) bool {
+	return strings.HasPrefix(name, "_Z") || strings.HasPrefix(name, "__Z") || strings.HasPrefix(name, "___Z")
+}
+
+// IsSwift reports whether name looks like a (Swift 4+) mangled Swift name
+func IsSwift(name string) bool {
+	return swiftPrefixLen(name) > 0
+}
+
+// Filter returns the demangled name or the original name if it can't be demangled
+func Filter(name string) string {
+	if s, err := Demangle(name); err == nil {
+		return s
+	}
+	return name
+}
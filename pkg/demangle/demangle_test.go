@@ -0,0 +1,155 @@
+package demangle
+
+import "testing"
+
+func TestDemangleItanium(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"_ZN4llvm3orc10OrcRiscv6416writeTrampolinesEPcNS0_12ExecutorAddrES3_j", "llvm::orc::OrcRiscv64::writeTrampolines(char*, llvm::orc::ExecutorAddr, llvm::orc::ExecutorAddr, unsigned int)"},
+		{"__ZNSt3__112basic_stringIcNS_11char_traitsIcEENS_9allocatorIcEEEC1ERKS5_", "std::__1::basic_string<char, std::__1::char_traits<char>, std::__1::allocator<char> >::basic_string(std::__1::basic_string<char, std::__1::char_traits<char>, std::__1::allocator<char> > const&)"},
+		{"_ZN4llvm12LoopInfoBaseINS_10BasicBlockENS_4LoopEEC1EOS3_", "llvm::LoopInfoBase<llvm::BasicBlock, llvm::Loop>::LoopInfoBase(llvm::LoopInfoBase<llvm::BasicBlock, llvm::Loop>&&)"},
+		{"_ZNKSs6lengthEv", "std::basic_string<char, std::char_traits<char>, std::allocator<char> >::length() const"},
+		{"_ZStlsISt11char_traitsIcEERSt13basic_ostreamIcT_ES5_PKc", "std::basic_ostream<char, std::char_traits<char> >& std::operator<< <std::char_traits<char> >(std::basic_ostream<char, std::char_traits<char> >&, char const*)"},
+		{"_ZN4llvm17getSipHash_2_4_64ENS_8ArrayRefIhEERA16_KhRA8_h", "llvm::getSipHash_2_4_64(llvm::ArrayRef<unsigned char>, unsigned char const (&) [16], unsigned char (&) [8])"},
+		{"_ZN10__cxxabiv111__terminateEPFvvE", "__cxxabiv1::__terminate(void (*)())"},
+		{"_Z3fooPFivEPA3_i", "foo(int (*)(), int (*) [3])"},
+		{"_ZN4llvm21RandomNumberGeneratorclEv", "llvm::RandomNumberGenerator::operator()()"},
+		{"_ZNK4llvm17DbgRecordParamRefINS_12DIExpressionEEcvbEv", "llvm::DbgRecordParamRef<llvm::DIExpression>::operator bool() const"},
+		{"_ZN12_GLOBAL__N_13fooEv", "(anonymous namespace)::foo()"},
+		{"_ZZ4mainENKUlvE_clEv", "main::{lambda()#1}::operator()() const"},
+		{"_ZZ3foovE1x", "foo()::x"},
+		{"_Z13UseCtxProfileB5cxx11", "UseCtxProfile[abi:cxx11]"},
+		{"_ZN3foo3barEv.cold.1", "foo::bar() [clone .cold.1]"},
+		{"_ZTV18GCEmptyBasicBlocks", "vtable for GCEmptyBasicBlocks"},
+		{"_ZTSN10__cxxabiv115__forced_unwindE", "typeinfo name for __cxxabiv1::__forced_unwind"},
+		{"_ZGVNSt10moneypunctIcLb0EE2idE", "guard variable for std::moneypunct<char, false>::id"},
+		{"_ZThn16_N4llvm19RTDyldMemoryManager6anchorEv", "non-virtual thunk to llvm::RTDyldMemoryManager::anchor()"},
+		{"_ZGTtNSt11logic_errorD0Ev", "transaction clone for std::logic_error::~logic_error()"},
+		{"___Z4main_block_invoke_2", "invocation function for block in main"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Demangle(tt.name)
+			if err != nil {
+				t.Fatalf("Demangle() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Demangle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDemangleSwift(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"$s4main3FooC3baryyF", "main.Foo.bar() -> ()"},
+		{"$s4main3FooCACycfC", "main.Foo.__allocating_init() -> main.Foo"},
+		{"$s4main3FooCfD", "main.Foo.__deallocating_deinit"},
+		{"$s4main3FooC1xSivg", "main.Foo.x.getter : Swift.Int"},
+		{"$s4main4TestC4nameSSvs", "main.Test.name.setter : Swift.String"},
+		{"$s4main3FooCMa", "type metadata accessor for main.Foo"},
+		{"$s4main3FooVMn", "nominal type descriptor for main.Foo"},
+		{"$sSiN", "type metadata for Swift.Int"},
+		{"$s4main3add1a1bS2i_SitF", "main.add(a: Swift.Int, b: Swift.Int) -> Swift.Int"},
+		{"$s4main3FooVAA1PAAMc", "protocol conformance descriptor for main.Foo : main.P in main"},
+		{"$s4main3FooVSQAAMc", "protocol conformance descriptor for main.Foo : Swift.Equatable in main"},
+		{"$s4main1fyyxAA1PRzlF", "main.f<A where A: main.P>(A) -> ()"},
+		{"_$s4main3FooV2eeoiySbAC_ACtFZ", "static main.Foo.== infix(main.Foo, main.Foo) -> Swift.Bool"},
+		{"_$s4main3FooC3baryyFTo", "@objc main.Foo.bar() -> ()"},
+		{"$sSaySiGD", "Swift.Array<Swift.Int>"},
+		{"$sSiSgD", "Swift.Optional<Swift.Int>"},
+		{"$s4main3FooC1xSivpMV", "property descriptor for main.Foo.x : Swift.Int"},
+		{"$s4main3fooyyFyycfU_", "closure #1 () -> () in main.foo() -> ()"},
+		{"$s4mainMXM", "module descriptor main"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Demangle(tt.name)
+			if err != nil {
+				t.Fatalf("Demangle() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Demangle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	for _, name := range []string{"_main", "_objc_msgSend", "_Znotmangled", "$s"} {
+		if got := Filter(name); got != name {
+			t.Errorf("Filter(%q) = %q, want it unchanged", name, got)
+		}
+	}
+}
+
+func TestDemangleSwiftMalformed(t *testing.T) {
+	for _, name := range []string{
+		"$s4main3fooyyFSyZfU_",
+		"_$s4main3fooyyFyycZfU_",
+		"$s4main3FooCfDMc",
+		"$sSaGD",
+		"$s4main1fyyxRzlF",
+	} {
+		if got := Filter(name); got != name {
+			t.Errorf("Filter(%q) = %q, want it unchanged", name, got)
+		}
+	}
+}
+
+func TestDemangleSwiftTruncated(t *testing.T) {
+	for _, name := range []string{
+		"$s4main3add1a1bS2i_SitF",
+		"$s4main1fyyxAA1PRzlF",
+		"$s4main3FooVAA1PAAMc",
+		"_$s4main3FooV2eeoiySbAC_ACtFZ",
+		"$s4main3fooyyFyycfU_",
+	} {
+		for i := len("$s"); i < len(name); i++ {
+			// must not panic, any result (including an error) is fine
+			Demangle(name[:i])
+		}
+	}
+}
+
+func TestDemangleSwiftMutated(t *testing.T) {
+	const alphabet = "0123456789_$ACDFGMNSVWXZabcfgilnpqstuvyz"
+	for _, name := range []string{
+		"$s4main3FooC3baryyF",
+		"$s4main3add1a1bS2i_SitF",
+		"$s4main1fyyxAA1PRzlF",
+		"$s4main3FooC1xSivpMV",
+		"$s4main3fooyyFyycfU_",
+	} {
+		for i := len("$s"); i < len(name); i++ {
+			for _, c := range []byte(alphabet) {
+				mutated := name[:i] + string(c) + name[i+1:]
+				if got := Filter(mutated); got == "" {
+					t.Errorf("Filter(%q) returned an empty name", mutated)
+				}
+			}
+		}
+	}
+}
+
+func FuzzDemangle(f *testing.F) {
+	for _, name := range []string{
+		"$s4main3add1a1bS2i_SitF",
+		"$s4main1fyyxAA1PRzlF",
+		"$s4main3fooyyFyycfU_",
+		"_ZN4llvm12LoopInfoBaseINS_10BasicBlockENS_4LoopEEC1EOS3_",
+		"___Z4main_block_invoke_2",
+	} {
+		f.Add(name)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		if s, err := Demangle(name); err != nil && s != "" {
+			t.Errorf("Demangle(%q) = %q with error %v", name, s, err)
+		}
+	})
+}
@@ -0,0 +1,1234 @@
+package demangle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Itanium C++ ABI demangler (https://itanium-cxx-abi.github.io/cxx-abi/abi.html#mangling)
+//
+// The demangled names are printed like c++filt does ("char const*", "std::vector<int, std::allocator<int> >").
+// Expressions (other than literals) in template arguments, decltype and vector types are not supported.
+
+// node is a node of the demangled name's AST (printed in two parts so that the
+// declarators of pointers to functions and arrays wrap around their pointee)
+type node interface {
+	printLeft(b *strings.Builder)
+	printRight(b *strings.Builder)
+}
+
+func printNode(b *strings.Builder, n node) {
+	n.printLeft(b)
+	n.printRight(b)
+}
+
+func nodeString(n node) string {
+	var b strings.Builder
+	printNode(&b, n)
+	return b.String()
+}
+
+// hasRHS reports whether the node prints anything after the declarator (i.e. it's a function or array type)
+func hasRHS(n node) bool {
+	switch n := n.(type) {
+	case *functionType, *arrayType:
+		return true
+	case *qualType:
+		return hasRHS(n.child)
+	case *pointerType:
+		return hasRHS(n.pointee)
+	case *referenceType:
+		return hasRHS(n.pointee)
+	case *ptrToMemberType:
+		return hasRHS(n.member)
+	}
+	return false
+}
+
+func hasArray(n node) bool {
+	switch n := n.(type) {
+	case *arrayType:
+		return true
+	case *qualType:
+		return hasArray(n.child)
+	}
+	return false
+}
+
+func hasFunction(n node) bool {
+	switch n := n.(type) {
+	case *functionType:
+		return true
+	case *qualType:
+		return hasFunction(n.child)
+	}
+	return false
+}
+
+type nameNode struct{ name string }
+
+func (n *nameNode) printLeft(b *strings.Builder)  { b.WriteString(n.name) }
+func (n *nameNode) printRight(b *strings.Builder) {}
+
+// stdSpecial is one of the special std:: substitutions (Sa, Sb, Ss, Si, So, Sd)
+// (printed fully expanded like c++filt does)
+type stdSpecial struct {
+	name string // i.e. std::basic_string<char, std::char_traits<char>, std::allocator<char> >
+	base string // i.e. basic_string (the constructor name)
+}
+
+func (n *stdSpecial) printLeft(b *strings.Builder)  { b.WriteString(n.name) }
+func (n *stdSpecial) printRight(b *strings.Builder) {}
+
+type nestedName struct{ qual, name node }
+
+func (n *nestedName) printLeft(b *strings.Builder) {
+	printNode(b, n.qual)
+	b.WriteString("::")
+	printNode(b, n.name)
+}
+func (n *nestedName) printRight(b *strings.Builder) {}
+
+type localName struct{ encoding, entity node }
+
+func (n *localName) printLeft(b *strings.Builder) {
+	if enc, ok := n.encoding.(*functionEncoding); ok && enc.ret != nil {
+		// c++filt doesn't print the return types of the functions of local names
+		printNode(b, &functionEncoding{name: enc.name, params: enc.params, quals: enc.quals})
+	} else {
+		printNode(b, n.encoding)
+	}
+	b.WriteString("::")
+	printNode(b, n.entity)
+}
+func (n *localName) printRight(b *strings.Builder) {}
+
+type templateArgs struct{ args []node }
+
+func (n *templateArgs) printLeft(b *strings.Builder) {
+	// keep "operator<" and "operator<<" apart from the template args
+	if strings.HasSuffix(b.String(), "<") {
+		b.WriteByte(' ')
+	}
+	b.WriteByte('<')
+	// like c++filt, there's no space before the '>' after a trailing empty pack (i.e. "A<B<int>>, >")
+	if !printList(b, n.args) && strings.HasSuffix(b.String(), ">") {
+		b.WriteByte(' ')
+	}
+	b.WriteByte('>')
+}
+func (n *templateArgs) printRight(b *strings.Builder) {}
+
+type nameWithTemplateArgs struct {
+	name node
+	args *templateArgs
+}
+
+func (n *nameWithTemplateArgs) printLeft(b *strings.Builder) {
+	printNode(b, n.name)
+	printNode(b, n.args)
+}
+func (n *nameWithTemplateArgs) printRight(b *strings.Builder) {}
+
+type ctorDtorName struct {
+	base string
+	dtor bool
+}
+
+func (n *ctorDtorName) printLeft(b *strings.Builder) {
+	if n.dtor {
+		b.WriteByte('~')
+	}
+	b.WriteString(n.base)
+}
+func (n *ctorDtorName) printRight(b *strings.Builder) {}
+
+type abiTagged struct {
+	base node
+	tag  string
+}
+
+func (n *abiTagged) printLeft(b *strings.Builder) {
+	printNode(b, n.base)
+	b.WriteString("[abi:" + n.tag + "]")
+}
+func (n *abiTagged) printRight(b *strings.Builder) {}
+
+type conversionOperator struct{ typ node }
+
+func (n *conversionOperator) printLeft(b *strings.Builder) {
+	b.WriteString("operator ")
+	printNode(b, n.typ)
+}
+func (n *conversionOperator) printRight(b *strings.Builder) {}
+
+type qualType struct {
+	child node
+	quals string // i.e. " const volatile"
+}
+
+func (n *qualType) printLeft(b *strings.Builder) {
+	n.child.printLeft(b)
+	if !hasRHS(n.child) {
+		b.WriteString(n.quals)
+	}
+}
+func (n *qualType) printRight(b *strings.Builder) {
+	n.child.printRight(b)
+	if hasRHS(n.child) {
+		b.WriteString(n.quals)
+	}
+}
+
+type pointerType struct{ pointee node }
+
+func (n *pointerType) printLeft(b *strings.Builder) {
+	n.pointee.printLeft(b)
+	if hasArray(n.pointee) {
+		b.WriteByte(' ')
+	}
+	if hasArray(n.pointee) || hasFunction(n.pointee) {
+		b.WriteByte('(')
+	}
+	b.WriteByte('*')
+}
+func (n *pointerType) printRight(b *strings.Builder) {
+	if hasArray(n.pointee) || hasFunction(n.pointee) {
+		b.WriteByte(')')
+	}
+	n.pointee.printRight(b)
+}
+
+type referenceType struct {
+	pointee node
+	rvalue  bool
+}
+
+func (n *referenceType) printLeft(b *strings.Builder) {
+	n.pointee.printLeft(b)
+	if hasArray(n.pointee) {
+		b.WriteByte(' ')
+	}
+	if hasArray(n.pointee) || hasFunction(n.pointee) {
+		b.WriteByte('(')
+	}
+	if n.rvalue {
+		b.WriteString("&&")
+	} else {
+		b.WriteByte('&')
+	}
+}
+func (n *referenceType) printRight(b *strings.Builder) {
+	if hasArray(n.pointee) || hasFunction(n.pointee) {
+		b.WriteByte(')')
+	}
+	n.pointee.printRight(b)
+}
+
+type ptrToMemberType struct{ class, member node }
+
+func (n *ptrToMemberType) printLeft(b *strings.Builder) {
+	n.member.printLeft(b)
+	if hasArray(n.member) || hasFunction(n.member) {
+		b.WriteByte('(')
+	} else {
+		b.WriteByte(' ')
+	}
+	printNode(b, n.class)
+	b.WriteString("::*")
+}
+func (n *ptrToMemberType) printRight(b *strings.Builder) {
+	if hasArray(n.member) || hasFunction(n.member) {
+		b.WriteByte(')')
+	}
+	n.member.printRight(b)
+}
+
+type functionType struct {
+	ret    node
+	params []node
+	quals  string // cv and ref qualifiers
+}
+
+func (n *functionType) printLeft(b *strings.Builder) {
+	n.ret.printLeft(b)
+	b.WriteByte(' ')
+}
+func (n *functionType) printRight(b *strings.Builder) {
+	b.WriteByte('(')
+	printList(b, n.params)
+	b.WriteByte(')')
+	n.ret.printRight(b)
+	b.WriteString(n.quals)
+}
+
+type arrayType struct {
+	base node
+	dim  string
+}
+
+func (n *arrayType) printLeft(b *strings.Builder) { n.base.printLeft(b) }
+func (n *arrayType) printRight(b *strings.Builder) {
+	if !strings.HasSuffix(b.String(), "]") {
+		b.WriteByte(' ')
+	}
+	b.WriteString("[" + n.dim + "]")
+	n.base.printRight(b)
+}
+
+type functionEncoding struct {
+	ret    node // nil if the return type isn't mangled
+	name   node
+	params []node
+	quals  string // cv and ref qualifiers
+}
+
+func (n *functionEncoding) printLeft(b *strings.Builder) {
+	if n.ret != nil {
+		n.ret.printLeft(b)
+		if !hasRHS(n.ret) {
+			b.WriteByte(' ')
+		}
+	}
+	printNode(b, n.name)
+}
+func (n *functionEncoding) printRight(b *strings.Builder) {
+	b.WriteByte('(')
+	printList(b, n.params)
+	b.WriteByte(')')
+	if n.ret != nil {
+		n.ret.printRight(b)
+	}
+	b.WriteString(n.quals)
+}
+
+type specialName struct {
+	prefix string
+	child  node
+}
+
+func (n *specialName) printLeft(b *strings.Builder) {
+	b.WriteString(n.prefix)
+	printNode(b, n.child)
+}
+func (n *specialName) printRight(b *strings.Builder) {}
+
+// packNode is a template argument pack (printed as a comma separated list when expanded)
+type packNode struct{ elems []node }
+
+func (n *packNode) printLeft(b *strings.Builder)  { printList(b, n.elems) }
+func (n *packNode) printRight(b *strings.Builder) {}
+
+// printList prints the nodes separated by commas (skipping empty pack expansions),
+// returning whether the last node was a skipped empty pack
+func printList(b *strings.Builder, nodes []node) bool {
+	first := true
+	skipped := false
+	for _, n := range nodes {
+		s := nodeString(n)
+		if _, ok := n.(*packNode); ok && s == "" {
+			skipped = !first
+			continue
+		}
+		if !first {
+			b.WriteString(", ")
+		}
+		b.WriteString(s)
+		first = false
+		skipped = false
+	}
+	return skipped
+}
+
+var builtinTypes = map[byte]string{
+	'v': "void",
+	'w': "wchar_t",
+	'b': "bool",
+	'c': "char",
+	'a': "signed char",
+	'h': "unsigned char",
+	's': "short",
+	't': "unsigned short",
+	'i': "int",
+	'j': "unsigned int",
+	'l': "long",
+	'm': "unsigned long",
+	'x': "long long",
+	'y': "unsigned long long",
+	'n': "__int128",
+	'o': "unsigned __int128",
+	'f': "float",
+	'd': "double",
+	'e': "long double",
+	'g': "__float128",
+	'z': "...",
+}
+
+var builtinDTypes = map[byte]string{
+	'd': "decimal64",
+	'e': "decimal128",
+	'f': "decimal32",
+	'h': "half",
+	'i': "char32_t",
+	's': "char16_t",
+	'u': "char8_t",
+	'a': "auto",
+	'c': "decltype(auto)",
+	'n': "decltype(nullptr)",
+}
+
+var operatorNames = map[string]string{
+	"nw": "new", "na": "new[]", "dl": "delete", "da": "delete[]",
+	"ps": "+", "ng": "-", "ad": "&", "de": "*", "co": "~",
+	"pl": "+", "mi": "-", "ml": "*", "dv": "/", "rm": "%",
+	"an": "&", "or": "|", "eo": "^", "aS": "=",
+	"pL": "+=", "mI": "-=", "mL": "*=", "dV": "/=", "rM": "%=",
+	"aN": "&=", "oR": "|=", "eO": "^=",
+	"ls": "<<", "rs": ">>", "lS": "<<=", "rS": ">>=",
+	"eq": "==", "ne": "!=", "lt": "<", "gt": ">", "le": "<=", "ge": ">=", "ss": "<=>",
+	"nt": "!", "aa": "&&", "oo": "||", "pp": "++", "mm": "--",
+	"cm": ",", "pm": "->*", "pt": "->", "cl": "()", "ix": "[]", "qu": "?",
+	"aw": "co_await",
+}
+
+var stdSpecials = map[byte]*stdSpecial{
+	'a': {"std::allocator", "allocator"},
+	'b': {"std::basic_string", "basic_string"},
+	's': {"std::basic_string<char, std::char_traits<char>, std::allocator<char> >", "basic_string"},
+	'i': {"std::basic_istream<char, std::char_traits<char> >", "basic_istream"},
+	'o': {"std::basic_ostream<char, std::char_traits<char> >", "basic_ostream"},
+	'd': {"std::basic_iostream<char, std::char_traits<char> >", "basic_iostream"},
+}
+
+// parseError aborts parsing (recovered by the Demangle entry points)
+type parseError struct{ msg string }
+
+// nameState is the state of the name of a function encoding needed to parse the rest of it
+type nameState struct {
+	quals                string // cv and ref qualifiers of member functions
+	endsWithTemplateArgs bool
+	ctorDtorConversion   bool
+}
+
+type itaniumParser struct {
+	s       string
+	pos     int
+	subs    []node
+	tparams []node // template arguments of the function being demangled (referenced by T_)
+}
+
+func (p *itaniumParser) fail(format string, args ...any) {
+	panic(parseError{fmt.Sprintf("%s at offset %d", fmt.Sprintf(format, args...), p.pos)})
+}
+
+func (p *itaniumParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *itaniumParser) peekAt(i int) byte {
+	if p.pos+i < len(p.s) {
+		return p.s[p.pos+i]
+	}
+	return 0
+}
+
+func (p *itaniumParser) consume(c byte) bool {
+	if p.peek() == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *itaniumParser) expect(c byte) {
+	if !p.consume(c) {
+		p.fail("expected '%c'", c)
+	}
+}
+
+func (p *itaniumParser) consumePrefix(prefix string) bool {
+	if strings.HasPrefix(p.s[p.pos:], prefix) {
+		p.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// parseNumber parses <number> ::= [n] <non-negative decimal integer>
+func (p *itaniumParser) parseNumber() string {
+	start := p.pos
+	p.consume('n')
+	if !isDigit(p.peek()) {
+		p.fail("expected number")
+	}
+	for isDigit(p.peek()) {
+		p.pos++
+	}
+	num := p.s[start:p.pos]
+	if num[0] == 'n' {
+		return "-" + num[1:]
+	}
+	return num
+}
+
+func (p *itaniumParser) parseUint() int {
+	if !isDigit(p.peek()) {
+		p.fail("expected number")
+	}
+	n := 0
+	for isDigit(p.peek()) {
+		n = n*10 + int(p.s[p.pos]-'0')
+		if n > len(p.s) {
+			p.fail("number too large")
+		}
+		p.pos++
+	}
+	return n
+}
+
+// parseSeqID parses the optional base 36 <seq-id> followed by '_' (returning 0 for "_" and seq-id+1 otherwise)
+func (p *itaniumParser) parseSeqID() int {
+	if p.consume('_') {
+		return 0
+	}
+	n := 0
+	for {
+		c := p.peek()
+		switch {
+		case isDigit(c):
+			n = n*36 + int(c-'0')
+		case c >= 'A' && c <= 'Z':
+			n = n*36 + int(c-'A') + 10
+		case c == '_':
+			p.pos++
+			return n + 1
+		default:
+			p.fail("invalid seq-id")
+		}
+		if n > len(p.s) {
+			p.fail("seq-id too large")
+		}
+		p.pos++
+	}
+}
+
+// parseEncoding parses <encoding> ::= <name> <bare-function-type> | <name> | <special-name>
+func (p *itaniumParser) parseEncoding() node {
+	if c := p.peek(); c == 'T' || c == 'G' {
+		return p.parseSpecialName()
+	}
+
+	saved := p.tparams
+	defer func() { p.tparams = saved }()
+
+	var st nameState
+	name := p.parseName(&st)
+	if p.pos >= len(p.s) || p.peek() == 'E' || p.peek() == '.' {
+		return name // data
+	}
+
+	enc := &functionEncoding{name: name, quals: st.quals}
+	if st.endsWithTemplateArgs && !st.ctorDtorConversion {
+		enc.ret = p.parseType()
+	}
+	if p.peek() == 'v' && (p.pos+1 == len(p.s) || p.peekAt(1) == 'E' || p.peekAt(1) == '.') {
+		p.pos++ // no parameters
+		return enc
+	}
+	for p.pos < len(p.s) && p.peek() != 'E' && p.peek() != '.' {
+		enc.params = append(enc.params, p.parseType())
+	}
+	return enc
+}
+
+// parseSpecialName parses the virtual tables, typeinfo, thunks and guard variables <special-name>s
+func (p *itaniumParser) parseSpecialName() node {
+	switch {
+	case p.consumePrefix("TV"):
+		return &specialName{"vtable for ", p.parseType()}
+	case p.consumePrefix("TT"):
+		return &specialName{"VTT for ", p.parseType()}
+	case p.consumePrefix("TI"):
+		return &specialName{"typeinfo for ", p.parseType()}
+	case p.consumePrefix("TS"):
+		return &specialName{"typeinfo name for ", p.parseType()}
+	case p.consumePrefix("TW"):
+		return &specialName{"TLS wrapper function for ", p.parseName(nil)}
+	case p.consumePrefix("TH"):
+		return &specialName{"TLS init function for ", p.parseName(nil)}
+	case p.consumePrefix("Tc"):
+		p.parseCallOffset()
+		p.parseCallOffset()
+		return &specialName{"covariant return thunk to ", p.parseEncoding()}
+	case p.consumePrefix("T"):
+		virtual := p.peek() == 'v'
+		p.parseCallOffset()
+		if virtual {
+			return &specialName{"virtual thunk to ", p.parseEncoding()}
+		}
+		return &specialName{"non-virtual thunk to ", p.parseEncoding()}
+	case p.consumePrefix("GV"):
+		return &specialName{"guard variable for ", p.parseName(nil)}
+	case p.consumePrefix("GR"):
+		name := p.parseName(nil)
+		if !p.consume('_') {
+			p.parseSeqID()
+		}
+		return &specialName{"reference temporary for ", name}
+	case p.consumePrefix("GTt"):
+		return &specialName{"transaction clone for ", p.parseEncoding()}
+	}
+	p.fail("unsupported special name")
+	return nil
+}
+
+// parseCallOffset parses <call-offset> ::= h <nv-offset> _ | v <v-offset> _
+func (p *itaniumParser) parseCallOffset() {
+	switch {
+	case p.consume('h'):
+		p.parseNumber()
+		p.expect('_')
+	case p.consume('v'):
+		p.parseNumber()
+		p.expect('_')
+		p.parseNumber()
+		p.expect('_')
+	default:
+		p.fail("invalid call offset")
+	}
+}
+
+// parseName parses <name> ::= <nested-name> | <local-name> | <unscoped-template-name> <template-args> | <unscoped-name>
+func (p *itaniumParser) parseName(st *nameState) node {
+	switch p.peek() {
+	case 'N':
+		return p.parseNestedName(st)
+	case 'Z':
+		return p.parseLocalName(st)
+	}
+
+	var name node
+	isSubst := false
+	if p.peek() == 'S' && p.peekAt(1) != 't' {
+		name = p.parseSubstitution()
+		isSubst = true
+	} else {
+		name = p.parseUnscopedName(st)
+	}
+	if p.peek() == 'I' {
+		if !isSubst {
+			p.subs = append(p.subs, name)
+		}
+		args := p.parseTemplateArgs(st != nil)
+		if st != nil {
+			st.endsWithTemplateArgs = true
+		}
+		return &nameWithTemplateArgs{name, args}
+	} else if isSubst {
+		p.fail("substitution is not a name")
+	}
+	return name
+}
+
+// parseUnscopedName parses <unscoped-name> ::= <unqualified-name> | St <unqualified-name>
+func (p *itaniumParser) parseUnscopedName(st *nameState) node {
+	if p.consumePrefix("St") {
+		return &nestedName{&nameNode{"std"}, p.parseUnqualifiedName(st, nil)}
+	}
+	return p.parseUnqualifiedName(st, nil)
+}
+
+// parseNestedName parses <nested-name> ::= N [<CV-qualifiers>] [<ref-qualifier>] <prefix> <unqualified-name> E
+func (p *itaniumParser) parseNestedName(st *nameState) node {
+	p.expect('N')
+	quals := p.parseCVQualifiers()
+	switch {
+	case p.consume('O'):
+		quals += " &&"
+	case p.consume('R'):
+		quals += " &"
+	}
+	if st != nil {
+		st.quals = quals
+	}
+
+	var soFar node
+	push := func(comp node) {
+		if soFar == nil {
+			soFar = comp
+		} else {
+			soFar = &nestedName{soFar, comp}
+		}
+		if st != nil {
+			st.endsWithTemplateArgs = false
+		}
+	}
+	for !p.consume('E') {
+		p.consume('L') // internal linkage
+		switch c := p.peek(); {
+		case c == 'T':
+			push(p.parseTemplateParam())
+		case c == 'I':
+			if soFar == nil {
+				p.fail("template args without a name")
+			}
+			args := p.parseTemplateArgs(st != nil)
+			soFar = &nameWithTemplateArgs{soFar, args}
+			if st != nil {
+				st.endsWithTemplateArgs = true
+			}
+		case c == 'S' && p.peekAt(1) == 't':
+			p.pos += 2
+			push(&nameNode{"std"})
+			continue // ::std:: isn't a substitution candidate
+		case c == 'S':
+			if soFar != nil {
+				p.fail("substitution in the middle of a nested name")
+			}
+			soFar = p.parseSubstitution()
+			continue // already a substitution
+		case c == 'D' && (p.peekAt(1) == 't' || p.peekAt(1) == 'T'):
+			p.fail("decltype is not supported")
+		case c == 0:
+			p.fail("unterminated nested name")
+		default:
+			if soFar == nil && (c == 'C' || c == 'D') {
+				p.fail("constructor without a class")
+			}
+			name := p.parseUnqualifiedName(st, soFar)
+			if soFar == nil {
+				soFar = name
+			} else {
+				soFar = &nestedName{soFar, name}
+			}
+			if st != nil {
+				st.endsWithTemplateArgs = false
+			}
+		}
+		p.subs = append(p.subs, soFar)
+	}
+	if soFar == nil || len(p.subs) == 0 {
+		p.fail("empty nested name")
+	}
+	p.subs = p.subs[:len(p.subs)-1]
+	return soFar
+}
+
+// parseLocalName parses <local-name> ::= Z <function encoding> E <entity name> [<discriminator>] | Z <function encoding> E s [<discriminator>]
+func (p *itaniumParser) parseLocalName(st *nameState) node {
+	p.expect('Z')
+	enc := p.parseEncoding()
+	p.expect('E')
+	if p.consume('s') {
+		p.parseDiscriminator()
+		return &localName{enc, &nameNode{"string literal"}}
+	}
+	if p.consume('d') {
+		if !p.consume('_') {
+			p.parseUint()
+			p.expect('_')
+		}
+	}
+	entity := p.parseName(st)
+	p.parseDiscriminator()
+	return &localName{enc, entity}
+}
+
+// parseDiscriminator parses <discriminator> ::= _ <digit> | __ <number> _
+func (p *itaniumParser) parseDiscriminator() {
+	if p.peek() != '_' {
+		return
+	}
+	if isDigit(p.peekAt(1)) {
+		p.pos += 2
+	} else if p.peekAt(1) == '_' {
+		p.pos += 2
+		p.parseUint()
+		p.expect('_')
+	}
+}
+
+// baseName returns the unqualified name of a class (used for constructor and destructor names)
+func baseName(n node) string {
+	switch n := n.(type) {
+	case *nestedName:
+		return baseName(n.name)
+	case *nameWithTemplateArgs:
+		return baseName(n.name)
+	case *abiTagged:
+		return baseName(n.base)
+	case *stdSpecial:
+		return n.base
+	case *nameNode:
+		if i := strings.LastIndex(n.name, "::"); i >= 0 && !strings.Contains(n.name, "<") {
+			return n.name[i+2:]
+		}
+		if i := strings.Index(n.name, "<"); i > 0 {
+			s := n.name[:i]
+			return s[strings.LastIndex(s, ":")+1:]
+		}
+		return n.name
+	}
+	return nodeString(n)
+}
+
+// parseUnqualifiedName parses <unqualified-name> ::= <operator-name> | <ctor-dtor-name> | <source-name> | <unnamed-type-name> [<abi-tags>]
+func (p *itaniumParser) parseUnqualifiedName(st *nameState, scope node) node {
+	if st != nil {
+		st.ctorDtorConversion = false
+	}
+	p.consume('L') // internal linkage
+
+	var name node
+	switch c := p.peek(); {
+	case isDigit(c):
+		name = p.parseSourceName()
+	case c == 'C' || (c == 'D' && p.peekAt(1) >= '0' && p.peekAt(1) <= '5'):
+		if scope == nil {
+			p.fail("constructor without a class")
+		}
+		p.pos++
+		inheriting := c == 'C' && p.consume('I')
+		if !isDigit(p.peek()) {
+			p.fail("invalid constructor/destructor name")
+		}
+		p.pos++
+		if inheriting {
+			p.parseType() // the base class
+		}
+		name = &ctorDtorName{baseName(scope), c == 'D'}
+		if st != nil {
+			st.ctorDtorConversion = true
+		}
+	case c == 'U':
+		name = p.parseUnnamedTypeName()
+	case c >= 'a' && c <= 'z':
+		name = p.parseOperatorName(st)
+	default:
+		p.fail("invalid unqualified name")
+	}
+
+	for p.consume('B') {
+		name = &abiTagged{name, p.parseSourceName().(*nameNode).name}
+	}
+	return name
+}
+
+// parseSourceName parses <source-name> ::= <positive length number> <identifier>
+func (p *itaniumParser) parseSourceName() node {
+	n := p.parseUint()
+	if n == 0 || p.pos+n > len(p.s) {
+		p.fail("invalid source name length")
+	}
+	id := p.s[p.pos : p.pos+n]
+	p.pos += n
+	if strings.HasPrefix(id, "_GLOBAL__N") {
+		return &nameNode{"(anonymous namespace)"}
+	}
+	return &nameNode{id}
+}
+
+// parseUnnamedTypeName parses <unnamed-type-name> ::= Ut [<number>] _ | Ul <lambda-sig> E [<number>] _
+func (p *itaniumParser) parseUnnamedTypeName() node {
+	switch {
+	case p.consumePrefix("Ut"):
+		n := 1
+		if !p.consume('_') {
+			n = p.parseUint() + 2
+			p.expect('_')
+		}
+		return &nameNode{fmt.Sprintf("{unnamed type#%d}", n)}
+	case p.consumePrefix("Ul"):
+		var params []node
+		if p.consumePrefix("vE") {
+			p.pos-- // no parameters
+		} else {
+			for p.peek() != 'E' && p.peek() != 0 {
+				params = append(params, p.parseType())
+			}
+		}
+		p.expect('E')
+		n := 1
+		if !p.consume('_') {
+			n = p.parseUint() + 2
+			p.expect('_')
+		}
+		var b strings.Builder
+		b.WriteString("{lambda(")
+		printList(&b, params)
+		b.WriteString(fmt.Sprintf(")#%d}", n))
+		return &nameNode{b.String()}
+	}
+	p.fail("unsupported unnamed type name")
+	return nil
+}
+
+// parseOperatorName parses <operator-name>
+func (p *itaniumParser) parseOperatorName(st *nameState) node {
+	if p.pos+2 > len(p.s) {
+		p.fail("invalid operator name")
+	}
+	code := p.s[p.pos : p.pos+2]
+	switch {
+	case code == "cv":
+		p.pos += 2
+		if st != nil {
+			st.ctorDtorConversion = true
+		}
+		return &conversionOperator{p.parseType()}
+	case code == "li":
+		p.pos += 2
+		return &nameNode{"operator\"\" " + p.parseSourceName().(*nameNode).name}
+	case code[0] == 'v' && isDigit(code[1]):
+		p.pos += 2
+		return &nameNode{"operator " + p.parseSourceName().(*nameNode).name}
+	}
+	op, ok := operatorNames[code]
+	if !ok {
+		p.fail("unknown operator %q", code)
+	}
+	p.pos += 2
+	if op[0] >= 'a' && op[0] <= 'z' {
+		return &nameNode{"operator " + op}
+	}
+	return &nameNode{"operator" + op}
+}
+
+// parseSubstitution parses <substitution> ::= S_ | S <seq-id> _ | Sa | Sb | Ss | Si | So | Sd
+func (p *itaniumParser) parseSubstitution() node {
+	p.expect('S')
+	if s, ok := stdSpecials[p.peek()]; ok {
+		p.pos++
+		return s
+	}
+	idx := p.parseSeqID()
+	if idx >= len(p.subs) {
+		p.fail("invalid substitution index %d", idx)
+	}
+	return p.subs[idx]
+}
+
+// parseTemplateParam parses <template-param> ::= T_ | T <number> _
+func (p *itaniumParser) parseTemplateParam() node {
+	p.expect('T')
+	idx := 0
+	if !p.consume('_') {
+		idx = p.parseUint() + 1
+		p.expect('_')
+	}
+	if idx >= len(p.tparams) {
+		p.fail("invalid template parameter index %d", idx)
+	}
+	return p.tparams[idx]
+}
+
+// parseTemplateArgs parses <template-args> ::= I <template-arg>+ E
+// (the args of the function's name are the template params referenced in its signature)
+func (p *itaniumParser) parseTemplateArgs(isFunctionName bool) *templateArgs {
+	p.expect('I')
+	var params []node
+	args := &templateArgs{}
+	for !p.consume('E') {
+		if p.pos >= len(p.s) {
+			p.fail("unterminated template args")
+		}
+		arg := p.parseTemplateArg()
+		args.args = append(args.args, arg)
+		params = append(params, arg)
+	}
+	if isFunctionName {
+		p.tparams = params
+	}
+	return args
+}
+
+// parseTemplateArg parses <template-arg> ::= <type> | X <expression> E | <expr-primary> | J <template-arg>* E
+func (p *itaniumParser) parseTemplateArg() node {
+	switch p.peek() {
+	case 'L':
+		return p.parseExprPrimary()
+	case 'J':
+		p.pos++
+		pack := &packNode{}
+		for !p.consume('E') {
+			if p.pos >= len(p.s) {
+				p.fail("unterminated template argument pack")
+			}
+			pack.elems = append(pack.elems, p.parseTemplateArg())
+		}
+		return pack
+	case 'X':
+		p.fail("template argument expressions are not supported")
+	}
+	return p.parseType()
+}
+
+// parseExprPrimary parses <expr-primary> ::= L <type> <value number> E | L <mangled-name> E
+func (p *itaniumParser) parseExprPrimary() node {
+	p.expect('L')
+	if p.consumePrefix("_Z") {
+		enc := p.parseEncoding()
+		p.expect('E')
+		return enc
+	}
+	if p.consumePrefix("Dn") {
+		p.consume('0')
+		p.expect('E')
+		return &nameNode{"nullptr"}
+	}
+	c := p.peek()
+	var lit string
+	switch c {
+	case 'b':
+		p.pos++
+		switch {
+		case p.consumePrefix("0E"):
+			return &nameNode{"false"}
+		case p.consumePrefix("1E"):
+			return &nameNode{"true"}
+		}
+		p.fail("invalid bool literal")
+	case 'i', 'j', 'l', 'm', 'x', 'y', 'n', 'o':
+		p.pos++
+		lit = p.parseNumber()
+		lit += map[byte]string{'j': "u", 'l': "l", 'm': "ul", 'x': "ll", 'y': "ull", 'n': "", 'o': ""}[c]
+		if c == 'n' || c == 'o' {
+			lit = "(" + builtinTypes[c] + ")" + lit
+		}
+	default:
+		typ := p.parseType()
+		start := p.pos
+		for p.peek() != 'E' && p.peek() != 0 {
+			p.pos++
+		}
+		val := p.s[start:p.pos]
+		if strings.HasPrefix(val, "n") {
+			val = "-" + val[1:]
+		}
+		lit = "(" + nodeString(typ) + ")" + val
+	}
+	p.expect('E')
+	return &nameNode{lit}
+}
+
+// parseCVQualifiers parses <CV-qualifiers> ::= [r] [V] [K]
+func (p *itaniumParser) parseCVQualifiers() string {
+	var quals string
+	restrict := p.consume('r')
+	volatile := p.consume('V')
+	if p.consume('K') {
+		quals += " const"
+	}
+	if volatile {
+		quals += " volatile"
+	}
+	if restrict {
+		quals += " restrict"
+	}
+	return quals
+}
+
+// parseType parses a <type> (adding it to the substitutions when it's substitutable)
+func (p *itaniumParser) parseType() node {
+	c := p.peek()
+	if name, ok := builtinTypes[c]; ok {
+		p.pos++
+		return &nameNode{name}
+	}
+
+	var result node
+	switch c {
+	case 'r', 'V', 'K':
+		quals := p.parseCVQualifiers()
+		child := p.parseType()
+		if fn, ok := child.(*functionType); ok {
+			// cv-qualified function types are abominable
+			result = &functionType{fn.ret, fn.params, fn.quals + quals}
+		} else {
+			result = &qualType{child, quals}
+		}
+	case 'u':
+		p.pos++
+		result = p.parseSourceName()
+	case 'D':
+		if name, ok := builtinDTypes[p.peekAt(1)]; ok {
+			p.pos += 2
+			return &nameNode{name}
+		}
+		switch p.peekAt(1) {
+		case 'p':
+			p.pos += 2
+			result = p.parseType() // pack expansion (packs print as comma separated lists)
+		case 'o', 'O', 'w', 'x':
+			if p.peekAt(1) != 'o' && p.peekAt(1) != 'x' {
+				p.fail("exception specifications are not supported")
+			}
+			p.pos += 2
+			result = p.parseFunctionType()
+		default:
+			p.fail("unsupported type")
+		}
+	case 'F':
+		result = p.parseFunctionType()
+	case 'A':
+		result = p.parseArrayType()
+	case 'M':
+		p.pos++
+		class := p.parseType()
+		member := p.parseType()
+		result = &ptrToMemberType{class, member}
+	case 'T':
+		if n := p.peekAt(1); n == 's' || n == 'u' || n == 'e' {
+			p.pos += 2
+			result = p.parseName(nil)
+			break
+		}
+		result = p.parseTemplateParam()
+		if p.peek() == 'I' {
+			p.subs = append(p.subs, result)
+			result = &nameWithTemplateArgs{result, p.parseTemplateArgs(false)}
+		}
+	case 'P':
+		p.pos++
+		result = &pointerType{p.parseType()}
+	case 'R', 'O':
+		p.pos++
+		ref := &referenceType{p.parseType(), c == 'O'}
+		// reference collapsing (i.e. T&& with T = int& is int&)
+		for {
+			inner, ok := ref.pointee.(*referenceType)
+			if !ok {
+				break
+			}
+			ref = &referenceType{inner.pointee, ref.rvalue && inner.rvalue}
+		}
+		result = ref
+	case 'C':
+		p.pos++
+		result = &qualType{p.parseType(), " _Complex"}
+	case 'G':
+		p.pos++
+		result = &qualType{p.parseType(), " _Imaginary"}
+	case 'S':
+		if p.peekAt(1) != 't' {
+			sub := p.parseSubstitution()
+			if p.peek() != 'I' {
+				return sub
+			}
+			result = &nameWithTemplateArgs{sub, p.parseTemplateArgs(false)}
+			break
+		}
+		result = p.parseName(nil)
+	case 'N', 'Z', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		result = p.parseName(nil)
+	default:
+		p.fail("unsupported type %q", c)
+	}
+
+	p.subs = append(p.subs, result)
+	return result
+}
+
+// parseFunctionType parses <function-type> ::= F [Y] <bare-function-type> [<ref-qualifier>] E
+func (p *itaniumParser) parseFunctionType() node {
+	p.expect('F')
+	p.consume('Y') // extern "C"
+	fn := &functionType{ret: p.parseType()}
+	for {
+		switch {
+		case p.consume('E'):
+			return fn
+		case p.consumePrefix("vE"):
+			return fn
+		case p.consumePrefix("RE"):
+			fn.quals += " &"
+			return fn
+		case p.consumePrefix("OE"):
+			fn.quals += " &&"
+			return fn
+		case p.pos >= len(p.s):
+			p.fail("unterminated function type")
+		}
+		fn.params = append(fn.params, p.parseType())
+	}
+}
+
+// parseArrayType parses <array-type> ::= A <positive dimension number> _ <element type> | A _ <element type>
+func (p *itaniumParser) parseArrayType() node {
+	p.expect('A')
+	var dim string
+	if !p.consume('_') {
+		if !isDigit(p.peek()) {
+			p.fail("array dimension expressions are not supported")
+		}
+		dim = p.parseNumber()
+		p.expect('_')
+	}
+	return &arrayType{p.parseType(), dim}
+}
+
+// demangleItanium demangles an Itanium C++ ABI mangled name
+func demangleItanium(name string) (demangled string, err error) {
+	mangled := name
+	var blockSuffix string
+	if strings.HasPrefix(mangled, "___Z") {
+		// clang block invocation functions (___Z3foov_block_invoke_2)
+		i := strings.LastIndex(mangled, "_block_invoke")
+		if i < 0 {
+			return "", fmt.Errorf("failed to demangle %s: unknown block name", name)
+		}
+		blockSuffix = mangled[i:]
+		mangled = mangled[2:i]
+	} else if strings.HasPrefix(mangled, "__Z") {
+		mangled = mangled[1:] // the MachO symbol '_' prefix
+	}
+	if !strings.HasPrefix(mangled, "_Z") {
+		return "", ErrNotMangled
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			ierr, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			demangled = ""
+			err = fmt.Errorf("failed to demangle %s: %s", name, ierr.msg)
+		}
+	}()
+
+	p := &itaniumParser{s: mangled, pos: 2}
+	enc := p.parseEncoding()
+	demangled = nodeString(enc)
+	if p.pos < len(p.s) {
+		if p.peek() != '.' {
+			p.fail("unexpected trailing characters")
+		}
+		// clone suffixes (i.e. .cold.1 or .constprop.0.isra.0)
+		for _, clone := range cloneSuffixes(p.s[p.pos:]) {
+			demangled += " [clone " + clone + "]"
+		}
+	}
+	if blockSuffix != "" {
+		demangled = "invocation function for block in " + demangled
+	}
+	return demangled, nil
+}
+
+// cloneSuffixes splits a suffix like .constprop.0.isra.0 into its clones (.constprop.0 and .isra.0)
+func cloneSuffixes(suffix string) []string {
+	var clones []string
+	for _, part := range strings.Split(suffix[1:], ".") {
+		if len(clones) > 0 && part != "" && isDigit(part[0]) {
+			clones[len(clones)-1] += "." + part
+		} else {
+			clones = append(clones, "."+part)
+		}
+	}
+	return clones
+}
@@ -0,0 +1,1479 @@
+package demangle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Swift demangler (https://github.com/apple/swift/blob/main/docs/ABI/Mangling.rst)
+//
+// Like the Swift runtime's demangler this is a stack machine: each operator pops its operands (nodes)
+// and pushes the resulting node. It supports the common symbols found in MachOs (nominal types,
+// functions, constructors, properties, subscripts, closures, metadata, descriptors, witness tables and
+// thunks); anything else (i.e. SIL function types, specializations or punycode identifiers) is an error.
+
+type swiftKind int
+
+const (
+	swiftGlobal swiftKind = iota
+	swiftModule
+	swiftIdentifier
+	swiftLocalDeclName
+	swiftPrivateDeclName
+	swiftInfixOperator
+	swiftPrefixOperator
+	swiftPostfixOperator
+	swiftClass
+	swiftStructure
+	swiftEnum
+	swiftProtocol
+	swiftTypeAlias
+	swiftExtension
+	swiftType
+	swiftFunction
+	swiftVariable
+	swiftSubscript
+	swiftAllocator
+	swiftConstructor
+	swiftDeallocator
+	swiftDestructor
+	swiftIVarInitializer
+	swiftIVarDestroyer
+	swiftExplicitClosure
+	swiftImplicitClosure
+	swiftAccessor // text is the accessor name (i.e. getter)
+	swiftStatic
+	swiftEmptyList
+	swiftFirstElementMarker
+	swiftVariadicMarker
+	swiftLabelList
+	swiftTuple
+	swiftTupleElement
+	swiftTupleElementName
+	swiftFunctionType
+	swiftArgumentTuple
+	swiftReturnType
+	swiftThrowsAnnotation
+	swiftAsyncAnnotation
+	swiftBoundGeneric
+	swiftTypeList
+	swiftTypeModifier // text is the modifier (i.e. inout)
+	swiftMetatype
+	swiftProtocolList
+	swiftGenericParam // text is the generic parameter's name (i.e. A)
+	swiftGenericSignature
+	swiftGenericParamCount
+	swiftConformanceRequirement
+	swiftSameTypeRequirement
+	swiftDependentGenericType
+	swiftProtocolConformance
+	swiftTypeMangling
+	swiftDescription      // text is the description of the child (i.e. "type metadata for ")
+	swiftLazyWitnessTable // text is the description of the type and conformance children
+	swiftProtocolWitness
+	swiftFunctionAttribute // text is the attribute (i.e. "@objc ")
+	swiftPartialApply
+)
+
+type swiftNode struct {
+	kind     swiftKind
+	text     string
+	index    int
+	children []*swiftNode
+}
+
+func (n *swiftNode) child(i int) *swiftNode {
+	if n == nil || i >= len(n.children) {
+		return nil
+	}
+	return n.children[i]
+}
+
+// mustChild returns the i'th child of n, failing the demangling if the (malformed) node tree doesn't have it
+func (n *swiftNode) mustChild(i int) *swiftNode {
+	if n == nil || i < 0 || i >= len(n.children) {
+		panic(parseError{"missing node"})
+	}
+	return n.children[i]
+}
+
+// childOf returns the first child of kind k
+func (n *swiftNode) childOf(k swiftKind) *swiftNode {
+	for _, c := range n.children {
+		if c.kind == k {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *swiftNode) add(children ...*swiftNode) *swiftNode {
+	for _, c := range children {
+		if c != nil {
+			n.children = append(n.children, c)
+		}
+	}
+	return n
+}
+
+func newSwiftNode(kind swiftKind, children ...*swiftNode) *swiftNode {
+	return (&swiftNode{kind: kind}).add(children...)
+}
+
+func newSwiftType(child *swiftNode) *swiftNode {
+	return newSwiftNode(swiftType, child)
+}
+
+func isSwiftContext(k swiftKind) bool {
+	switch k {
+	case swiftModule, swiftClass, swiftStructure, swiftEnum, swiftProtocol, swiftTypeAlias, swiftExtension,
+		swiftFunction, swiftVariable, swiftSubscript, swiftAllocator, swiftConstructor, swiftDeallocator,
+		swiftDestructor, swiftIVarInitializer, swiftIVarDestroyer, swiftExplicitClosure, swiftImplicitClosure,
+		swiftAccessor, swiftStatic:
+		return true
+	}
+	return false
+}
+
+func isSwiftEntity(k swiftKind) bool {
+	return k == swiftType || isSwiftContext(k)
+}
+
+func isSwiftDeclName(k swiftKind) bool {
+	switch k {
+	case swiftIdentifier, swiftLocalDeclName, swiftPrivateDeclName, swiftInfixOperator, swiftPrefixOperator, swiftPostfixOperator:
+		return true
+	}
+	return false
+}
+
+func isSwiftNominal(k swiftKind) bool {
+	switch k {
+	case swiftClass, swiftStructure, swiftEnum, swiftProtocol, swiftTypeAlias:
+		return true
+	}
+	return false
+}
+
+type swiftStandardType struct {
+	kind swiftKind
+	name string
+}
+
+// swiftStandardTypes are the Swift module types with single letter substitutions (S<letter>)
+var swiftStandardTypes = map[byte]swiftStandardType{
+	'A': {swiftStructure, "AutoreleasingUnsafeMutablePointer"},
+	'a': {swiftStructure, "Array"},
+	'b': {swiftStructure, "Bool"},
+	'D': {swiftStructure, "Dictionary"},
+	'd': {swiftStructure, "Double"},
+	'f': {swiftStructure, "Float"},
+	'h': {swiftStructure, "Set"},
+	'I': {swiftStructure, "DefaultIndices"},
+	'i': {swiftStructure, "Int"},
+	'J': {swiftStructure, "Character"},
+	'N': {swiftStructure, "ClosedRange"},
+	'n': {swiftStructure, "Range"},
+	'O': {swiftStructure, "ObjectIdentifier"},
+	'P': {swiftStructure, "UnsafePointer"},
+	'p': {swiftStructure, "UnsafeMutablePointer"},
+	'R': {swiftStructure, "UnsafeBufferPointer"},
+	'r': {swiftStructure, "UnsafeMutableBufferPointer"},
+	'S': {swiftStructure, "String"},
+	's': {swiftStructure, "Substring"},
+	'u': {swiftStructure, "UInt"},
+	'V': {swiftStructure, "UnsafeRawPointer"},
+	'v': {swiftStructure, "UnsafeMutableRawPointer"},
+	'W': {swiftStructure, "UnsafeRawBufferPointer"},
+	'w': {swiftStructure, "UnsafeMutableRawBufferPointer"},
+	'q': {swiftEnum, "Optional"},
+	'B': {swiftProtocol, "BinaryFloatingPoint"},
+	'E': {swiftProtocol, "Encodable"},
+	'e': {swiftProtocol, "Decodable"},
+	'F': {swiftProtocol, "FloatingPoint"},
+	'G': {swiftProtocol, "RandomNumberGenerator"},
+	'H': {swiftProtocol, "Hashable"},
+	'j': {swiftProtocol, "Numeric"},
+	'K': {swiftProtocol, "BidirectionalCollection"},
+	'k': {swiftProtocol, "RandomAccessCollection"},
+	'L': {swiftProtocol, "Comparable"},
+	'l': {swiftProtocol, "Collection"},
+	'M': {swiftProtocol, "MutableCollection"},
+	'm': {swiftProtocol, "RangeReplaceableCollection"},
+	'Q': {swiftProtocol, "Equatable"},
+	'T': {swiftProtocol, "Sequence"},
+	't': {swiftProtocol, "IteratorProtocol"},
+	'U': {swiftProtocol, "UnsignedInteger"},
+	'X': {swiftProtocol, "RangeExpression"},
+	'x': {swiftProtocol, "Strideable"},
+	'Y': {swiftProtocol, "RawRepresentable"},
+	'y': {swiftProtocol, "StringProtocol"},
+	'Z': {swiftProtocol, "SignedInteger"},
+	'z': {swiftProtocol, "BinaryInteger"},
+}
+
+// swiftConcurrencyTypes are the Swift module types with two letter substitutions (Sc<letter>)
+var swiftConcurrencyTypes = map[byte]swiftStandardType{
+	'A': {swiftProtocol, "Actor"},
+	'C': {swiftStructure, "CheckedContinuation"},
+	'c': {swiftStructure, "UnsafeContinuation"},
+	'E': {swiftStructure, "CancellationError"},
+	'e': {swiftStructure, "UnownedSerialExecutor"},
+	'F': {swiftProtocol, "Executor"},
+	'f': {swiftProtocol, "SerialExecutor"},
+	'G': {swiftStructure, "TaskGroup"},
+	'g': {swiftStructure, "ThrowingTaskGroup"},
+	'I': {swiftProtocol, "AsyncIteratorProtocol"},
+	'i': {swiftProtocol, "AsyncSequence"},
+	'J': {swiftStructure, "UnownedJob"},
+	'M': {swiftClass, "MainActor"},
+	'P': {swiftStructure, "TaskPriority"},
+	'S': {swiftStructure, "AsyncStream"},
+	's': {swiftStructure, "AsyncThrowingStream"},
+	'T': {swiftStructure, "Task"},
+	't': {swiftStructure, "UnsafeCurrentTask"},
+}
+
+// swiftMetadataDescriptions are the descriptions of the M<letter> metadata symbols of types
+var swiftMetadataDescriptions = map[byte]string{
+	'a': "type metadata accessor for ",
+	'D': "demangling cache variable for type metadata for ",
+	'f': "full type metadata for ",
+	'i': "type metadata instantiation function for ",
+	'I': "type metadata instantiation cache for ",
+	'l': "lazy cache variable for type metadata for ",
+	'L': "type metadata singleton initialization cache for ",
+	'm': "metaclass for ",
+	'n': "nominal type descriptor for ",
+	'o': "class metadata base offset for ",
+	'P': "generic type metadata pattern for ",
+	'r': "type metadata completion function for ",
+	'u': "method lookup function for ",
+	'U': "ObjC metadata update function for ",
+}
+
+// swiftAccessors are the names of the v<letter> accessors
+var swiftAccessors = map[string]string{
+	"g":  "getter",
+	"G":  "getter",
+	"s":  "setter",
+	"m":  "materializeForSet",
+	"w":  "willset",
+	"W":  "didset",
+	"r":  "read",
+	"M":  "modify",
+	"i":  "init",
+	"aO": "owningMutableAddressor",
+	"ao": "nativeOwningMutableAddressor",
+	"aP": "nativePinningMutableAddressor",
+	"au": "unsafeMutableAddressor",
+	"lO": "owningAddressor",
+	"lo": "nativeOwningAddressor",
+	"lp": "nativePinningAddressor",
+	"lu": "unsafeAddressor",
+}
+
+const swiftMaxWords = 26
+
+type swiftDemangler struct {
+	s     string
+	pos   int
+	stack []*swiftNode
+	subs  []*swiftNode
+	words []string
+}
+
+func (d *swiftDemangler) fail(format string, args ...any) {
+	panic(parseError{fmt.Sprintf("%s at offset %d", fmt.Sprintf(format, args...), d.pos)})
+}
+
+func (d *swiftDemangler) peek() byte {
+	if d.pos < len(d.s) {
+		return d.s[d.pos]
+	}
+	return 0
+}
+
+func (d *swiftDemangler) next() byte {
+	c := d.peek()
+	if c == 0 {
+		d.fail("unexpected end")
+	}
+	d.pos++
+	return c
+}
+
+func (d *swiftDemangler) consume(c byte) bool {
+	if d.peek() == c {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+func (d *swiftDemangler) push(n *swiftNode) {
+	d.stack = append(d.stack, n)
+}
+
+// pop pops the top node if it satisfies pred (or returns nil)
+func (d *swiftDemangler) pop(pred func(swiftKind) bool) *swiftNode {
+	if len(d.stack) == 0 {
+		return nil
+	}
+	n := d.stack[len(d.stack)-1]
+	if pred != nil && !pred(n.kind) {
+		return nil
+	}
+	d.stack = d.stack[:len(d.stack)-1]
+	return n
+}
+
+// popKind pops the top node if it's of kind k (or returns nil)
+func (d *swiftDemangler) popKind(k swiftKind) *swiftNode {
+	return d.pop(func(kind swiftKind) bool { return kind == k })
+}
+
+// must panics if a required operand is missing
+func (d *swiftDemangler) must(n *swiftNode, what string) *swiftNode {
+	if n == nil {
+		d.fail("missing %s", what)
+	}
+	return n
+}
+
+func (d *swiftDemangler) popType() *swiftNode {
+	return d.must(d.popKind(swiftType), "type")
+}
+
+func (d *swiftDemangler) popTypeAndGetChild() *swiftNode {
+	return d.popType().mustChild(0)
+}
+
+func (d *swiftDemangler) popModule() *swiftNode {
+	if ident := d.popKind(swiftIdentifier); ident != nil {
+		ident.kind = swiftModule
+		return ident
+	}
+	return d.popKind(swiftModule)
+}
+
+func (d *swiftDemangler) popContext() *swiftNode {
+	if mod := d.popModule(); mod != nil {
+		return mod
+	}
+	if typ := d.popKind(swiftType); typ != nil {
+		if !isSwiftContext(typ.mustChild(0).kind) {
+			d.fail("type is not a context")
+		}
+		return typ.mustChild(0)
+	}
+	return d.must(d.pop(isSwiftContext), "context")
+}
+
+func (d *swiftDemangler) popProtocol() *swiftNode {
+	if typ := d.popKind(swiftType); typ != nil {
+		if typ.mustChild(0).kind != swiftProtocol {
+			d.fail("type is not a protocol")
+		}
+		return typ
+	}
+	name := d.must(d.pop(isSwiftDeclName), "protocol name")
+	return newSwiftType(newSwiftNode(swiftProtocol, d.popContext(), name))
+}
+
+func (d *swiftDemangler) popProtocolConformance() *swiftNode {
+	sig := d.popKind(swiftGenericSignature)
+	module := d.popModule()
+	proto := d.popProtocol()
+	typ := d.popKind(swiftType)
+	if typ == nil {
+		d.fail("unsupported protocol conformance")
+	}
+	if sig != nil {
+		typ = newSwiftType(newSwiftNode(swiftDependentGenericType, sig, typ))
+	}
+	return newSwiftNode(swiftProtocolConformance, typ, proto, d.must(module, "conformance module"))
+}
+
+// demangleNatural parses a decimal number (returning -1 if there isn't one)
+func (d *swiftDemangler) demangleNatural() int {
+	if !isDigit(d.peek()) {
+		return -1
+	}
+	n := 0
+	for isDigit(d.peek()) {
+		n = n*10 + int(d.next()-'0')
+		if n > len(d.s) {
+			d.fail("number too large")
+		}
+	}
+	return n
+}
+
+// demangleIndex parses INDEX ::= '_' | NATURAL '_' (returning 0 or NATURAL+1)
+func (d *swiftDemangler) demangleIndex() int {
+	if d.consume('_') {
+		return 0
+	}
+	n := d.demangleNatural()
+	if n < 0 || !d.consume('_') {
+		d.fail("invalid index")
+	}
+	return n + 1
+}
+
+func (d *swiftDemangler) addSubstitution(n *swiftNode) {
+	d.subs = append(d.subs, n)
+}
+
+func (d *swiftDemangler) substitution(idx int) *swiftNode {
+	if idx < 0 || idx >= len(d.subs) {
+		d.fail("invalid substitution index %d", idx)
+	}
+	return d.subs[idx]
+}
+
+// demangleIdentifier parses an identifier (with optional word substitutions)
+func (d *swiftDemangler) demangleIdentifier() *swiftNode {
+	hasWordSubsts := false
+	if d.consume('0') {
+		if d.peek() == '0' {
+			d.fail("punycode identifiers are not supported")
+		}
+		hasWordSubsts = true
+	}
+	var ident strings.Builder
+	for {
+		for hasWordSubsts && isSwiftLetter(d.peek()) {
+			c := d.next()
+			var idx int
+			if isSwiftLower(c) {
+				idx = int(c - 'a')
+			} else {
+				idx = int(c - 'A')
+				hasWordSubsts = false // the last word substitution
+			}
+			if idx >= len(d.words) {
+				d.fail("invalid word substitution")
+			}
+			ident.WriteString(d.words[idx])
+		}
+		if d.consume('0') {
+			break
+		}
+		n := d.demangleNatural()
+		if n <= 0 || d.pos+n > len(d.s) {
+			d.fail("invalid identifier")
+		}
+		slice := d.s[d.pos : d.pos+n]
+		ident.WriteString(slice)
+		d.addWords(slice)
+		d.pos += n
+		if !hasWordSubsts {
+			break
+		}
+	}
+	if ident.Len() == 0 {
+		d.fail("empty identifier")
+	}
+	n := &swiftNode{kind: swiftIdentifier, text: ident.String()}
+	d.addSubstitution(n)
+	return n
+}
+
+func isSwiftLower(c byte) bool  { return c >= 'a' && c <= 'z' }
+func isSwiftUpper(c byte) bool  { return c >= 'A' && c <= 'Z' }
+func isSwiftLetter(c byte) bool { return isSwiftLower(c) || isSwiftUpper(c) }
+
+// addWords adds the words of an identifier (for later word substitutions)
+func (d *swiftDemangler) addWords(s string) {
+	isWordStart := func(c byte) bool { return !isDigit(c) && c != '_' && c != 0 }
+	isWordEnd := func(c, prev byte) bool {
+		return c == '_' || c == 0 || (!isSwiftUpper(prev) && isSwiftUpper(c))
+	}
+	start := -1
+	for i := 0; i <= len(s); i++ {
+		var c byte
+		if i < len(s) {
+			c = s[i]
+		}
+		if start >= 0 && isWordEnd(c, s[i-1]) {
+			if i-start >= 2 && len(d.words) < swiftMaxWords {
+				d.words = append(d.words, s[start:i])
+			}
+			start = -1
+		}
+		if start < 0 && isWordStart(c) {
+			start = i
+		}
+	}
+}
+
+// demangleOperator parses the next operator, returning its node
+func (d *swiftDemangler) demangleOperator() *swiftNode {
+	c := d.next()
+	switch c {
+	case 'A':
+		return d.demangleMultiSubstitutions()
+	case 'C':
+		return d.demangleAnyGenericType(swiftClass)
+	case 'D':
+		typ := d.popType()
+		return newSwiftNode(swiftTypeMangling, d.popFunctionParamLabels(typ), typ)
+	case 'E':
+		return d.demangleExtensionContext()
+	case 'F':
+		return d.demanglePlainFunction()
+	case 'G':
+		return d.demangleBoundGenericType()
+	case 'H':
+		return d.demangleConformanceRecord()
+	case 'K':
+		return newSwiftNode(swiftThrowsAnnotation)
+	case 'L':
+		return d.demangleLocalIdentifier()
+	case 'M':
+		return d.demangleMetatype()
+	case 'N':
+		return &swiftNode{kind: swiftDescription, text: "type metadata for ", children: []*swiftNode{d.popType()}}
+	case 'O':
+		return d.demangleAnyGenericType(swiftEnum)
+	case 'P':
+		return d.demangleAnyGenericType(swiftProtocol)
+	case 'R':
+		return d.demangleGenericRequirement()
+	case 'S':
+		return d.demangleStandardSubstitution()
+	case 'T':
+		return d.demangleThunk()
+	case 'V':
+		return d.demangleAnyGenericType(swiftStructure)
+	case 'W':
+		return d.demangleWitness()
+	case 'Y':
+		if d.consume('a') {
+			return newSwiftNode(swiftAsyncAnnotation)
+		}
+		d.fail("unsupported type annotation")
+	case 'Z':
+		return newSwiftNode(swiftStatic, d.must(d.pop(isSwiftEntity), "static entity"))
+	case 'a':
+		return d.demangleAnyGenericType(swiftTypeAlias)
+	case 'c':
+		return d.popFunctionType()
+	case 'd':
+		return newSwiftNode(swiftVariadicMarker)
+	case 'f':
+		return d.demangleFunctionEntity()
+	case 'h':
+		return newSwiftType(&swiftNode{kind: swiftTypeModifier, text: "__shared", children: []*swiftNode{d.popTypeAndGetChild()}})
+	case 'i':
+		return d.demangleSubscript()
+	case 'l':
+		return d.demangleGenericSignature(false)
+	case 'm':
+		return newSwiftType(newSwiftNode(swiftMetatype, d.popType()))
+	case 'n':
+		return newSwiftType(&swiftNode{kind: swiftTypeModifier, text: "__owned", children: []*swiftNode{d.popTypeAndGetChild()}})
+	case 'o':
+		return d.demangleOperatorIdentifier()
+	case 'p':
+		return d.demangleProtocolList()
+	case 'q':
+		return newSwiftType(d.demangleGenericParamIndex())
+	case 'r':
+		return d.demangleGenericSignature(true)
+	case 's':
+		return &swiftNode{kind: swiftModule, text: "Swift"}
+	case 't':
+		return d.popTuple()
+	case 'v':
+		return d.demangleAccessor(d.demangleEntity(swiftVariable))
+	case 'x':
+		return newSwiftType(swiftGenericParamType(0, 0))
+	case 'y':
+		return newSwiftNode(swiftEmptyList)
+	case 'z':
+		return newSwiftType(&swiftNode{kind: swiftTypeModifier, text: "inout", children: []*swiftNode{d.popTypeAndGetChild()}})
+	case '_':
+		return newSwiftNode(swiftFirstElementMarker)
+	default:
+		if isDigit(c) {
+			d.pos--
+			return d.demangleIdentifier()
+		}
+	}
+	d.fail("unsupported operator '%c'", c)
+	return nil
+}
+
+// demangleMultiSubstitutions parses A<substitution>: [count] lower-letter* [count] upper-letter | NATURAL _
+func (d *swiftDemangler) demangleMultiSubstitutions() *swiftNode {
+	repeat := -1
+	for {
+		c := d.next()
+		switch {
+		case isSwiftLower(c):
+			n := d.pushMultiSubstitutions(repeat, int(c-'a'))
+			d.push(n)
+			repeat = -1
+		case isSwiftUpper(c):
+			return d.pushMultiSubstitutions(repeat, int(c-'A'))
+		case c == '_':
+			return d.substitution(repeat + 27)
+		default:
+			d.pos--
+			if repeat = d.demangleNatural(); repeat < 0 {
+				d.fail("invalid substitution")
+			}
+		}
+	}
+}
+
+func (d *swiftDemangler) pushMultiSubstitutions(repeat, idx int) *swiftNode {
+	n := d.substitution(idx)
+	for ; repeat > 1; repeat-- {
+		d.push(n)
+	}
+	return n
+}
+
+// demangleStandardSubstitution parses S<letter> standard types (and So/SC modules and Sg optionals)
+func (d *swiftDemangler) demangleStandardSubstitution() *swiftNode {
+	switch d.peek() {
+	case 'o':
+		d.pos++
+		return &swiftNode{kind: swiftModule, text: "__C"}
+	case 'C':
+		d.pos++
+		return &swiftNode{kind: swiftModule, text: "__C_Synthesized"}
+	case 'g':
+		d.pos++
+		optional := newSwiftType(newSwiftNode(swiftEnum, &swiftNode{kind: swiftModule, text: "Swift"}, &swiftNode{kind: swiftIdentifier, text: "Optional"}))
+		n := newSwiftType(newSwiftNode(swiftBoundGeneric, optional, newSwiftNode(swiftTypeList, d.popType())))
+		d.addSubstitution(n)
+		return n
+	}
+	repeat := d.demangleNatural()
+	table := swiftStandardTypes
+	if d.consume('c') {
+		table = swiftConcurrencyTypes
+	}
+	std, ok := table[d.next()]
+	if !ok {
+		d.fail("unknown standard substitution")
+	}
+	n := newSwiftType(newSwiftNode(std.kind, &swiftNode{kind: swiftModule, text: "Swift"}, &swiftNode{kind: swiftIdentifier, text: std.name}))
+	for ; repeat > 1; repeat-- {
+		d.push(n)
+	}
+	return n
+}
+
+func (d *swiftDemangler) demangleAnyGenericType(kind swiftKind) *swiftNode {
+	name := d.must(d.pop(isSwiftDeclName), "type name")
+	n := newSwiftType(newSwiftNode(kind, d.popContext(), name))
+	d.addSubstitution(n)
+	return n
+}
+
+func (d *swiftDemangler) demangleExtensionContext() *swiftNode {
+	sig := d.popKind(swiftGenericSignature)
+	module := d.must(d.popModule(), "extension module")
+	typ := d.popTypeAndGetChild()
+	if !isSwiftNominal(typ.kind) && typ.kind != swiftBoundGeneric {
+		d.fail("invalid extended type")
+	}
+	return newSwiftNode(swiftExtension, module, typ, sig)
+}
+
+func (d *swiftDemangler) demangleLocalIdentifier() *swiftNode {
+	if d.consume('L') {
+		discriminator := d.must(d.popKind(swiftIdentifier), "private discriminator")
+		name := d.must(d.pop(isSwiftDeclName), "private name")
+		return newSwiftNode(swiftPrivateDeclName, discriminator, name)
+	}
+	index := d.demangleIndex()
+	name := d.must(d.pop(isSwiftDeclName), "local name")
+	return &swiftNode{kind: swiftLocalDeclName, index: index, children: []*swiftNode{name}}
+}
+
+func (d *swiftDemangler) demangleOperatorIdentifier() *swiftNode {
+	ident := d.must(d.popKind(swiftIdentifier), "operator name")
+	const opChars = "& @/= >    <*!|+?%-~   ^ ."
+	var op strings.Builder
+	for i := 0; i < len(ident.text); i++ {
+		c := ident.text[i]
+		if isSwiftLower(c) && opChars[c-'a'] != ' ' {
+			op.WriteByte(opChars[c-'a'])
+		} else {
+			op.WriteByte(c)
+		}
+	}
+	switch d.next() {
+	case 'i':
+		return &swiftNode{kind: swiftInfixOperator, text: op.String()}
+	case 'p':
+		return &swiftNode{kind: swiftPrefixOperator, text: op.String()}
+	case 'P':
+		return &swiftNode{kind: swiftPostfixOperator, text: op.String()}
+	}
+	d.fail("unsupported operator identifier")
+	return nil
+}
+
+func (d *swiftDemangler) demanglePlainFunction() *swiftNode {
+	sig := d.popKind(swiftGenericSignature)
+	typ := d.popFunctionType()
+	labels := d.popFunctionParamLabels(typ)
+	if sig != nil {
+		typ = newSwiftType(newSwiftNode(swiftDependentGenericType, sig, typ))
+	}
+	name := d.must(d.pop(isSwiftDeclName), "function name")
+	return newSwiftNode(swiftFunction, d.popContext(), name, labels, typ)
+}
+
+func (d *swiftDemangler) popFunctionType() *swiftNode {
+	fn := newSwiftNode(swiftFunctionType)
+	fn.add(d.popKind(swiftThrowsAnnotation))
+	fn.add(d.popKind(swiftAsyncAnnotation))
+	fn.add(d.popFunctionParams(swiftArgumentTuple))
+	fn.add(d.popFunctionParams(swiftReturnType))
+	return newSwiftType(fn)
+}
+
+func (d *swiftDemangler) popFunctionParams(kind swiftKind) *swiftNode {
+	var params *swiftNode
+	if d.popKind(swiftEmptyList) != nil {
+		params = newSwiftType(newSwiftNode(swiftTuple))
+	} else {
+		params = d.popType()
+	}
+	return newSwiftNode(kind, params)
+}
+
+// functionTypeOf returns the function type node of a (generic) function's type
+func functionTypeOf(typ *swiftNode) *swiftNode {
+	if typ == nil || typ.kind != swiftType {
+		return nil
+	}
+	fn := typ.mustChild(0)
+	if fn.kind == swiftDependentGenericType {
+		fn = fn.child(1).child(0)
+	}
+	if fn == nil || fn.kind != swiftFunctionType {
+		return nil
+	}
+	return fn
+}
+
+// popFunctionParamLabels pops the argument labels of a function (mangled before its type)
+func (d *swiftDemangler) popFunctionParamLabels(typ *swiftNode) *swiftNode {
+	if d.popKind(swiftEmptyList) != nil {
+		return newSwiftNode(swiftLabelList)
+	}
+	fn := functionTypeOf(typ)
+	if fn == nil {
+		return nil
+	}
+	params := fn.childOf(swiftArgumentTuple).child(0).child(0)
+	numParams := 1
+	if params.kind == swiftTuple {
+		numParams = len(params.children)
+	}
+	if numParams == 0 {
+		return nil
+	}
+	labels := newSwiftNode(swiftLabelList)
+	hasLabels := false
+	for i := 0; i < numParams; i++ {
+		label := d.pop(func(k swiftKind) bool { return k == swiftIdentifier || k == swiftFirstElementMarker })
+		if label == nil {
+			return nil
+		}
+		hasLabels = hasLabels || label.kind == swiftIdentifier
+		labels.children = append([]*swiftNode{label}, labels.children...)
+	}
+	if !hasLabels {
+		return nil
+	}
+	return labels
+}
+
+func (d *swiftDemangler) popTuple() *swiftNode {
+	tuple := newSwiftNode(swiftTuple)
+	if d.popKind(swiftEmptyList) == nil {
+		for {
+			first := d.popKind(swiftFirstElementMarker) != nil
+			elem := newSwiftNode(swiftTupleElement)
+			elem.add(d.popKind(swiftVariadicMarker))
+			if ident := d.popKind(swiftIdentifier); ident != nil {
+				elem.add(&swiftNode{kind: swiftTupleElementName, text: ident.text})
+			}
+			elem.add(d.popType())
+			tuple.children = append([]*swiftNode{elem}, tuple.children...)
+			if first {
+				break
+			}
+		}
+	}
+	return newSwiftType(tuple)
+}
+
+func (d *swiftDemangler) demangleBoundGenericType() *swiftNode {
+	var lists []*swiftNode
+	for {
+		list := newSwiftNode(swiftTypeList)
+		for typ := d.popKind(swiftType); typ != nil; typ = d.popKind(swiftType) {
+			list.children = append([]*swiftNode{typ}, list.children...)
+		}
+		lists = append(lists, list)
+		if d.popKind(swiftEmptyList) != nil {
+			break
+		}
+		if d.popKind(swiftFirstElementMarker) == nil {
+			d.fail("invalid bound generic type")
+		}
+	}
+	nominal := d.popType()
+	if !isSwiftNominal(nominal.mustChild(0).kind) {
+		d.fail("bound generic type is not nominal")
+	}
+	// only the innermost type's generic arguments are supported
+	for _, list := range lists[1:] {
+		if len(list.children) > 0 {
+			d.fail("nested bound generic types are not supported")
+		}
+	}
+	n := newSwiftType(newSwiftNode(swiftBoundGeneric, nominal, lists[0]))
+	d.addSubstitution(n)
+	return n
+}
+
+func (d *swiftDemangler) demangleProtocolList() *swiftNode {
+	list := newSwiftNode(swiftTypeList)
+	if d.popKind(swiftEmptyList) == nil {
+		for {
+			first := d.popKind(swiftFirstElementMarker) != nil
+			list.children = append([]*swiftNode{d.popProtocol()}, list.children...)
+			if first {
+				break
+			}
+		}
+	}
+	return newSwiftType(newSwiftNode(swiftProtocolList, list))
+}
+
+func swiftGenericParamType(depth, index int) *swiftNode {
+	var name []byte
+	for {
+		name = append(name, byte('A'+index%26))
+		index /= 26
+		if index == 0 {
+			break
+		}
+	}
+	if depth != 0 {
+		name = strconv.AppendInt(name, int64(depth), 10)
+	}
+	return &swiftNode{kind: swiftGenericParam, text: string(name)}
+}
+
+// demangleGenericParamIndex parses GENERIC-PARAM-INDEX ::= 'z' | INDEX | 'd' INDEX INDEX
+func (d *swiftDemangler) demangleGenericParamIndex() *swiftNode {
+	if d.consume('d') {
+		depth := d.demangleIndex() + 1
+		return swiftGenericParamType(depth, d.demangleIndex())
+	}
+	if d.consume('z') {
+		return swiftGenericParamType(0, 0)
+	}
+	return swiftGenericParamType(0, d.demangleIndex()+1)
+}
+
+func (d *swiftDemangler) demangleGenericSignature(hasParamCounts bool) *swiftNode {
+	sig := newSwiftNode(swiftGenericSignature)
+	if hasParamCounts {
+		for !d.consume('l') {
+			count := 0
+			if !d.consume('z') {
+				count = d.demangleIndex() + 1
+			}
+			sig.add(&swiftNode{kind: swiftGenericParamCount, index: count})
+		}
+	} else {
+		sig.add(&swiftNode{kind: swiftGenericParamCount, index: 1})
+	}
+	var reqs []*swiftNode
+	for req := d.pop(isSwiftRequirement); req != nil; req = d.pop(isSwiftRequirement) {
+		reqs = append([]*swiftNode{req}, reqs...)
+	}
+	return sig.add(reqs...)
+}
+
+func isSwiftRequirement(k swiftKind) bool {
+	return k == swiftConformanceRequirement || k == swiftSameTypeRequirement
+}
+
+// demangleGenericRequirement parses the protocol, base class and same type requirements on generic parameters
+func (d *swiftDemangler) demangleGenericRequirement() *swiftNode {
+	kind := swiftConformanceRequirement
+	baseClass := false
+	switch d.peek() {
+	case 'b':
+		baseClass = true
+		d.pos++
+	case 's':
+		kind = swiftSameTypeRequirement
+		d.pos++
+	case 'z', 'd', '_', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+	default:
+		d.fail("unsupported generic requirement")
+	}
+	param := newSwiftType(d.demangleGenericParamIndex())
+	if kind == swiftConformanceRequirement && !baseClass {
+		return newSwiftNode(kind, param, d.popProtocol())
+	}
+	return newSwiftNode(kind, param, d.popType())
+}
+
+func (d *swiftDemangler) demangleMetatype() *swiftNode {
+	c := d.next()
+	if desc, ok := swiftMetadataDescriptions[c]; ok {
+		return &swiftNode{kind: swiftDescription, text: desc, children: []*swiftNode{d.popType()}}
+	}
+	switch c {
+	case 'p':
+		return &swiftNode{kind: swiftDescription, text: "protocol descriptor for ", children: []*swiftNode{d.popProtocol()}}
+	case 'c':
+		return &swiftNode{kind: swiftDescription, text: "protocol conformance descriptor for ", children: []*swiftNode{d.popProtocolConformance()}}
+	case 'V':
+		return &swiftNode{kind: swiftDescription, text: "property descriptor for ", children: []*swiftNode{d.must(d.pop(isSwiftEntity), "property")}}
+	case 'q':
+		return &swiftNode{kind: swiftDescription, text: "method descriptor for ", children: []*swiftNode{d.must(d.pop(isSwiftEntity), "method")}}
+	case 'X':
+		switch d.next() {
+		case 'E':
+			return &swiftNode{kind: swiftDescription, text: "extension descriptor ", children: []*swiftNode{d.popContext()}}
+		case 'M':
+			return &swiftNode{kind: swiftDescription, text: "module descriptor ", children: []*swiftNode{d.must(d.popModule(), "module")}}
+		}
+	}
+	d.fail("unsupported metadata 'M%c'", c)
+	return nil
+}
+
+func (d *swiftDemangler) demangleConformanceRecord() *swiftNode {
+	switch d.next() {
+	case 'n':
+		return &swiftNode{kind: swiftDescription, text: "nominal type descriptor runtime record for ", children: []*swiftNode{d.popType()}}
+	case 'r':
+		return &swiftNode{kind: swiftDescription, text: "protocol descriptor runtime record for ", children: []*swiftNode{d.popProtocol()}}
+	case 'c':
+		return &swiftNode{kind: swiftDescription, text: "protocol conformance descriptor runtime record for ", children: []*swiftNode{d.popProtocolConformance()}}
+	}
+	d.fail("unsupported runtime record")
+	return nil
+}
+
+func (d *swiftDemangler) demangleWitness() *swiftNode {
+	switch c := d.next(); c {
+	case 'V':
+		return &swiftNode{kind: swiftDescription, text: "value witness table for ", children: []*swiftNode{d.popType()}}
+	case 'P':
+		return &swiftNode{kind: swiftDescription, text: "protocol witness table for ", children: []*swiftNode{d.popProtocolConformance()}}
+	case 'a':
+		return &swiftNode{kind: swiftDescription, text: "protocol witness table accessor for ", children: []*swiftNode{d.popProtocolConformance()}}
+	case 'l', 'L':
+		conf := d.popProtocolConformance()
+		desc := "lazy protocol witness table accessor for type "
+		if c == 'L' {
+			desc = "lazy protocol witness table cache variable for type "
+		}
+		return &swiftNode{kind: swiftLazyWitnessTable, text: desc, children: []*swiftNode{d.popType(), conf}}
+	}
+	d.fail("unsupported witness")
+	return nil
+}
+
+// demangleThunk parses the T<letter> thunks and function attributes
+func (d *swiftDemangler) demangleThunk() *swiftNode {
+	switch d.next() {
+	case 'A':
+		return newSwiftNode(swiftPartialApply)
+	case 'o':
+		return &swiftNode{kind: swiftFunctionAttribute, text: "@objc "}
+	case 'O':
+		return &swiftNode{kind: swiftFunctionAttribute, text: "@nonobjc "}
+	case 'D':
+		return &swiftNode{kind: swiftFunctionAttribute, text: "dynamic "}
+	case 'd':
+		return &swiftNode{kind: swiftFunctionAttribute, text: "direct "}
+	case 'V':
+		return &swiftNode{kind: swiftFunctionAttribute, text: "override "}
+	case 'm':
+		return &swiftNode{kind: swiftFunctionAttribute, text: "merged "}
+	case 'u':
+		return &swiftNode{kind: swiftFunctionAttribute, text: "async function pointer to "}
+	case 'j':
+		return &swiftNode{kind: swiftDescription, text: "dispatch thunk of ", children: []*swiftNode{d.must(d.pop(isSwiftEntity), "dispatched entity")}}
+	case 'q':
+		return &swiftNode{kind: swiftDescription, text: "method descriptor for ", children: []*swiftNode{d.must(d.pop(isSwiftEntity), "method")}}
+	case 'W':
+		entity := d.must(d.pop(isSwiftEntity), "witness")
+		return newSwiftNode(swiftProtocolWitness, d.popProtocolConformance(), entity)
+	}
+	d.fail("unsupported thunk")
+	return nil
+}
+
+func isSwiftFunctionAttribute(k swiftKind) bool {
+	return k == swiftFunctionAttribute || k == swiftPartialApply
+}
+
+// demangleEntity pops a variable's type, name and context
+func (d *swiftDemangler) demangleEntity(kind swiftKind) *swiftNode {
+	typ := d.popType()
+	labels := d.popFunctionParamLabels(typ)
+	name := d.must(d.pop(isSwiftDeclName), "name")
+	return newSwiftNode(kind, d.popContext(), name, labels, typ)
+}
+
+func (d *swiftDemangler) demangleSubscript() *swiftNode {
+	private := d.popKind(swiftPrivateDeclName)
+	typ := d.popType()
+	labels := d.popFunctionParamLabels(typ)
+	return d.demangleAccessor(newSwiftNode(swiftSubscript, d.popContext(), labels, typ, private))
+}
+
+func (d *swiftDemangler) demangleAccessor(storage *swiftNode) *swiftNode {
+	code := string(d.next())
+	if code == "p" {
+		return storage // the property or subscript itself
+	}
+	if code == "a" || code == "l" {
+		code += string(d.next())
+	}
+	name, ok := swiftAccessors[code]
+	if !ok {
+		d.fail("unsupported accessor")
+	}
+	return &swiftNode{kind: swiftAccessor, text: name, children: []*swiftNode{storage}}
+}
+
+func (d *swiftDemangler) demangleFunctionEntity() *swiftNode {
+	var kind swiftKind
+	switch d.next() {
+	case 'D':
+		kind = swiftDeallocator
+	case 'd':
+		kind = swiftDestructor
+	case 'E':
+		kind = swiftIVarDestroyer
+	case 'e':
+		kind = swiftIVarInitializer
+	case 'C':
+		kind = swiftAllocator
+	case 'c':
+		kind = swiftConstructor
+	case 'U':
+		kind = swiftExplicitClosure
+	case 'u':
+		kind = swiftImplicitClosure
+	case 'i':
+		return &swiftNode{kind: swiftDescription, text: "variable initialization expression of ", children: []*swiftNode{d.must(d.pop(isSwiftEntity), "variable")}}
+	default:
+		d.fail("unsupported function entity")
+	}
+	var typ, labels *swiftNode
+	index := -1
+	switch kind {
+	case swiftAllocator, swiftConstructor:
+		typ = d.popType()
+		labels = d.popFunctionParamLabels(typ)
+	case swiftExplicitClosure, swiftImplicitClosure:
+		index = d.demangleIndex()
+		typ = d.popKind(swiftType)
+	}
+	n := newSwiftNode(kind, d.popContext(), labels, typ)
+	n.index = index
+	return n
+}
+
+// swiftPrefixLen returns the length of the Swift mangling prefix of name (0 if it isn't a Swift symbol)
+func swiftPrefixLen(name string) int {
+	for _, prefix := range []string{"_$s", "$s", "_$S", "$S", "_$e", "$e", "_T0"} {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return len(prefix)
+		}
+	}
+	return 0
+}
+
+// demangleSwift demangles a Swift mangled symbol name
+func demangleSwift(name string) (demangled string, err error) {
+	prefix := swiftPrefixLen(name)
+	if prefix == 0 {
+		return "", ErrNotMangled
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			demangled = ""
+			err = fmt.Errorf("failed to demangle %s: %s", name, perr.msg)
+		}
+	}()
+
+	d := &swiftDemangler{s: name, pos: prefix}
+	for d.pos < len(d.s) {
+		if d.peek() == '.' {
+			break // i.e. .cold or .resume.0 suffixes
+		}
+		d.push(d.demangleOperator())
+	}
+
+	global := newSwiftNode(swiftGlobal)
+	parent := global
+	for attr := d.pop(isSwiftFunctionAttribute); attr != nil; attr = d.pop(isSwiftFunctionAttribute) {
+		parent.add(attr)
+		if attr.kind == swiftPartialApply {
+			parent = attr
+		}
+	}
+	if len(d.stack) == 0 {
+		d.fail("empty symbol")
+	}
+	for _, n := range d.stack {
+		if n.kind == swiftType {
+			n = n.mustChild(0)
+		}
+		parent.add(n)
+	}
+
+	var p swiftPrinter
+	p.print(global)
+	demangled = p.String()
+	if d.pos < len(d.s) {
+		demangled += " with unmangled suffix \"" + d.s[d.pos:] + "\""
+	}
+	return demangled, nil
+}
+
+type swiftPrinter struct {
+	strings.Builder
+}
+
+func (p *swiftPrinter) print(n *swiftNode) {
+	if n == nil {
+		panic(parseError{"missing node"})
+	}
+	switch n.kind {
+	case swiftGlobal:
+		for _, c := range n.children {
+			p.print(c)
+		}
+	case swiftFunctionAttribute:
+		p.WriteString(n.text)
+	case swiftPartialApply:
+		p.WriteString("partial apply forwarder")
+		if len(n.children) > 0 {
+			p.WriteString(" for ")
+			for _, c := range n.children {
+				p.print(c)
+			}
+		}
+	case swiftModule, swiftIdentifier:
+		p.WriteString(n.text)
+	case swiftLocalDeclName:
+		p.print(n.mustChild(0))
+		p.WriteString(fmt.Sprintf(" #%d", n.index+1))
+	case swiftPrivateDeclName:
+		p.WriteString("(")
+		p.print(n.mustChild(1))
+		p.WriteString(" in " + n.mustChild(0).text + ")")
+	case swiftInfixOperator:
+		p.WriteString(n.text + " infix")
+	case swiftPrefixOperator:
+		p.WriteString(n.text + " prefix")
+	case swiftPostfixOperator:
+		p.WriteString(n.text + " postfix")
+	case swiftType, swiftTypeMangling, swiftReturnType:
+		p.print(n.mustChild(len(n.children) - 1))
+	case swiftClass, swiftStructure, swiftEnum, swiftProtocol, swiftTypeAlias,
+		swiftFunction, swiftVariable, swiftSubscript, swiftAllocator, swiftConstructor, swiftDeallocator,
+		swiftDestructor, swiftIVarInitializer, swiftIVarDestroyer, swiftExplicitClosure, swiftImplicitClosure:
+		p.printEntity(n, false, "")
+	case swiftAccessor:
+		p.printEntity(n.mustChild(0), false, "."+n.text)
+	case swiftStatic:
+		p.WriteString("static ")
+		p.print(n.mustChild(0))
+	case swiftExtension:
+		p.WriteString("(extension in ")
+		p.print(n.mustChild(0))
+		p.WriteString("):")
+		p.print(n.mustChild(1))
+	case swiftTuple:
+		p.WriteString("(")
+		for i, c := range n.children {
+			if i > 0 {
+				p.WriteString(", ")
+			}
+			p.print(c)
+		}
+		p.WriteString(")")
+	case swiftTupleElement:
+		if name := n.childOf(swiftTupleElementName); name != nil {
+			p.WriteString(name.text + ": ")
+		}
+		p.print(n.childOf(swiftType))
+		if n.childOf(swiftVariadicMarker) != nil {
+			p.WriteString("...")
+		}
+	case swiftFunctionType:
+		p.printFunctionType(n, nil)
+	case swiftArgumentTuple:
+		p.printFunctionParameters(nil, n)
+	case swiftBoundGeneric:
+		p.print(n.mustChild(0))
+		p.WriteString("<")
+		for i, c := range n.mustChild(1).children {
+			if i > 0 {
+				p.WriteString(", ")
+			}
+			p.print(c)
+		}
+		p.WriteString(">")
+	case swiftTypeModifier:
+		p.WriteString(n.text + " ")
+		p.print(n.mustChild(0))
+	case swiftMetatype:
+		p.print(n.mustChild(0))
+		p.WriteString(".Type")
+	case swiftProtocolList:
+		protos := n.mustChild(0).children
+		if len(protos) == 0 {
+			p.WriteString("Any")
+		}
+		for i, c := range protos {
+			if i > 0 {
+				p.WriteString(" & ")
+			}
+			p.print(c)
+		}
+	case swiftGenericParam:
+		p.WriteString(n.text)
+	case swiftGenericSignature:
+		p.WriteString("<")
+		i := 0
+		for depth := 0; i < len(n.children) && n.mustChild(i).kind == swiftGenericParamCount; i, depth = i+1, depth+1 {
+			if depth > 0 {
+				p.WriteString("><")
+			}
+			for index := 0; index < n.mustChild(i).index; index++ {
+				if index > 0 {
+					p.WriteString(", ")
+				}
+				p.WriteString(swiftGenericParamType(depth, index).text)
+			}
+		}
+		if i < len(n.children) {
+			p.WriteString(" where ")
+			for j, req := range n.children[i:] {
+				if j > 0 {
+					p.WriteString(", ")
+				}
+				p.print(req)
+			}
+		}
+		p.WriteString(">")
+	case swiftConformanceRequirement:
+		p.print(n.mustChild(0))
+		p.WriteString(": ")
+		p.print(n.mustChild(1))
+	case swiftSameTypeRequirement:
+		p.print(n.mustChild(0))
+		p.WriteString(" == ")
+		p.print(n.mustChild(1))
+	case swiftDependentGenericType:
+		p.print(n.mustChild(0))
+		p.WriteString(" ")
+		p.print(n.mustChild(1))
+	case swiftProtocolConformance:
+		p.print(n.mustChild(0))
+		p.WriteString(" : ")
+		p.print(n.mustChild(1))
+		p.WriteString(" in ")
+		p.print(n.mustChild(2))
+	case swiftDescription:
+		p.WriteString(n.text)
+		p.print(n.mustChild(0))
+	case swiftLazyWitnessTable:
+		p.WriteString(n.text)
+		p.print(n.mustChild(0))
+		p.WriteString(" and conformance ")
+		p.print(n.mustChild(1))
+	case swiftProtocolWitness:
+		p.WriteString("protocol witness for ")
+		p.print(n.mustChild(1))
+		p.WriteString(" in conformance ")
+		p.print(n.mustChild(0))
+	default:
+		panic(parseError{fmt.Sprintf("unexpected node kind %d", n.kind)})
+	}
+}
+
+// printContext prints an entity's context (parenthesizing contexts with types like functions)
+func (p *swiftPrinter) printContext(ctx *swiftNode) {
+	switch ctx.kind {
+	case swiftModule, swiftExtension:
+		p.print(ctx)
+	default:
+		p.printEntity(ctx, true, "")
+	}
+}
+
+// printPostfixContext prints the context of a closure or local declaration (after "in")
+func (p *swiftPrinter) printPostfixContext(ctx *swiftNode) {
+	switch ctx.kind {
+	case swiftModule, swiftExtension:
+		p.print(ctx)
+	default:
+		p.printEntity(ctx, false, "")
+	}
+}
+
+// printEntity prints a declaration as ctx.name[extra] followed by its type (if it has one)
+func (p *swiftPrinter) printEntity(n *swiftNode, asContext bool, extra string) {
+	ctx := n.mustChild(0)
+	typ := n.childOf(swiftType)
+	if n.kind == swiftExplicitClosure || n.kind == swiftImplicitClosure {
+		if asContext {
+			p.WriteString("(")
+		}
+		if n.kind == swiftImplicitClosure {
+			p.WriteString("implicit ")
+		}
+		p.WriteString(fmt.Sprintf("closure #%d", n.index+1))
+		if typ != nil {
+			p.WriteString(" ")
+			p.print(typ)
+		}
+		p.WriteString(" in ")
+		p.printPostfixContext(ctx)
+		if asContext {
+			p.WriteString(")")
+		}
+		return
+	}
+
+	// functions (and local types) used as contexts are parenthesized
+	hasType := typ != nil && n.kind != swiftClass && n.kind != swiftStructure && n.kind != swiftEnum && n.kind != swiftProtocol && n.kind != swiftTypeAlias
+	name := n.child(1)
+	local := name != nil && name.kind == swiftLocalDeclName
+	if asContext && (hasType || local) {
+		p.WriteString("(")
+		defer p.WriteString(")")
+	}
+	if !local {
+		p.printContext(ctx)
+		p.WriteString(".")
+	}
+	switch n.kind {
+	case swiftSubscript:
+		p.WriteString("subscript")
+	case swiftAllocator:
+		p.WriteString("__allocating_init")
+	case swiftConstructor:
+		p.WriteString("init")
+	case swiftDeallocator:
+		p.WriteString("__deallocating_deinit")
+	case swiftDestructor:
+		p.WriteString("deinit")
+	case swiftIVarInitializer:
+		p.WriteString("__ivar_initializer")
+	case swiftIVarDestroyer:
+		p.WriteString("__ivar_destroyer")
+	default:
+		p.print(name)
+	}
+	p.WriteString(extra)
+	if local {
+		p.WriteString(" in ")
+		p.printPostfixContext(ctx)
+	}
+	if !hasType {
+		return
+	}
+	if n.kind == swiftVariable || extra != "" {
+		p.WriteString(" : ")
+		p.print(typ)
+		return
+	}
+	fn := typ.mustChild(0)
+	if fn.kind == swiftDependentGenericType {
+		p.print(fn.mustChild(0))
+		fn = fn.mustChild(1).mustChild(0)
+	}
+	if fn.kind != swiftFunctionType {
+		p.WriteString(" : ")
+		p.print(fn)
+		return
+	}
+	p.printFunctionType(fn, n.childOf(swiftLabelList))
+}
+
+func (p *swiftPrinter) printFunctionType(fn, labels *swiftNode) {
+	p.printFunctionParameters(labels, fn.childOf(swiftArgumentTuple))
+	if fn.childOf(swiftAsyncAnnotation) != nil {
+		p.WriteString(" async")
+	}
+	if fn.childOf(swiftThrowsAnnotation) != nil {
+		p.WriteString(" throws")
+	}
+	p.WriteString(" -> ")
+	p.print(fn.childOf(swiftReturnType))
+}
+
+func (p *swiftPrinter) printFunctionParameters(labels, args *swiftNode) {
+	params := args.mustChild(0).mustChild(0)
+	label := func(i int) string {
+		if labels == nil || i >= len(labels.children) {
+			return ""
+		}
+		if l := labels.mustChild(i); l.kind == swiftIdentifier {
+			return l.text + ": "
+		}
+		return "_: "
+	}
+	hasLabels := labels != nil && len(labels.children) > 0
+	if params.kind != swiftTuple {
+		p.WriteString("(")
+		if hasLabels {
+			p.WriteString(label(0))
+		}
+		p.print(params)
+		p.WriteString(")")
+		return
+	}
+	p.WriteString("(")
+	for i, param := range params.children {
+		if i > 0 {
+			p.WriteString(", ")
+		}
+		if hasLabels {
+			p.WriteString(label(i))
+			p.print(param.childOf(swiftType))
+			if param.childOf(swiftVariadicMarker) != nil {
+				p.WriteString("...")
+			}
+		} else {
+			p.print(param)
+		}
+	}
+	p.WriteString(")")
+}
@@ -0,0 +1,270 @@
+// Package deps walks the dylib dependency graph of a MachO, resolving the @rpath, @executable_path and
+// @loader_path install names of its dependencies the way dyld does.
+package deps
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blacktop/go-macho"
+	"github.com/blacktop/go-macho/types"
+)
+
+// Config configures how the dependencies are resolved
+type Config struct {
+	// Roots are the directories the absolute install names (i.e. /usr/lib/libfoo.dylib) are looked up in
+	// (e.g. an extracted iOS filesystem) in order; absolute install names are used as is if there are none
+	Roots []string
+	// FallbackPaths are the directories the leaf names of the libraries that can't otherwise be found are
+	// looked up in (like DYLD_FALLBACK_LIBRARY_PATH)
+	FallbackPaths []string
+}
+
+// Node is an image of the dependency graph
+type Node struct {
+	Path        string // resolved file path
+	InstallName string // LC_ID_DYLIB name (empty for executables)
+	Rpaths      []string
+	Deps        []*Dependency
+
+	cpu    types.CPU
+	subcpu types.CPUSubtype
+}
+
+// Dependency is a dylib load command of an image and the image it resolves to
+type Dependency struct {
+	macho.ImportedLibrary
+	Node  *Node    // nil if the dylib is missing (see Err)
+	Tried []string // the paths tried to resolve the dylib
+	Err   error    // why the dylib is missing
+}
+
+// Missing is a dylib dependency that couldn't be resolved
+type Missing struct {
+	Importer *Node
+	*Dependency
+}
+
+func (m Missing) String() string {
+	var weak string
+	if m.Weak() {
+		weak = " (weak)"
+	}
+	return fmt.Sprintf("%s: %s%s: %v", m.Importer.Path, m.Path, weak, m.Err)
+}
+
+// Graph is the dependency graph of a MachO (it may have cycles through upward dylibs)
+type Graph struct {
+	Root  *Node
+	Nodes []*Node // every image of the graph in the order they were loaded (starting with the Root)
+
+	conf Config
+	exec string           // @executable_path
+	byID map[string]*Node // images by resolved path
+}
+
+// ErrNotFound is the Dependency.Err of the dylibs that don't exist in any of the paths tried
+var ErrNotFound = errors.New("library not found")
+
+// Walk opens the MachO at path and recursively resolves and opens all of its dependencies
+func Walk(path string, conf Config) (*Graph, error) {
+	g := &Graph{
+		conf: conf,
+		exec: filepath.Dir(path),
+		byID: make(map[string]*Node),
+	}
+	root, err := g.open(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	g.Root = root
+	g.byID[realPath(path)] = root
+	g.walk(root, nil)
+	return g, nil
+}
+
+// walk resolves the dependencies of n where rpaths are the (expanded) LC_RPATHs of the images that loaded n
+func (g *Graph) walk(n *Node, rpaths []string) {
+	for _, rpath := range n.Rpaths {
+		if strings.HasPrefix(rpath, "@") {
+			rpaths = append(rpaths, g.expand(rpath, n))
+		} else {
+			rpaths = append(rpaths, g.rooted(rpath)...)
+		}
+	}
+	for _, dep := range n.Deps {
+		candidates := g.candidates(dep.ImportedLibrary.Path, n, rpaths)
+		dep.Err = ErrNotFound
+		for _, candidate := range candidates {
+			dep.Tried = append(dep.Tried, candidate)
+			if _, err := os.Stat(candidate); err != nil {
+				continue
+			}
+			key := realPath(candidate)
+			if node, ok := g.byID[key]; ok {
+				dep.Node, dep.Err = node, nil
+				break
+			}
+			node, err := g.open(candidate, n)
+			if err != nil { // dyld moves on to the next path (e.g. for a wrong architecture)
+				dep.Err = err
+				continue
+			}
+			g.byID[key] = node
+			dep.Node, dep.Err = node, nil
+			g.walk(node, rpaths[:len(rpaths):len(rpaths)])
+			break
+		}
+	}
+}
+
+// candidates returns the paths the install name name loaded by n may be at (in the order dyld tries them)
+func (g *Graph) candidates(name string, n *Node, rpaths []string) []string {
+	var paths []string
+	switch {
+	case strings.HasPrefix(name, "@rpath/"):
+		for _, rpath := range rpaths {
+			paths = append(paths, filepath.Join(rpath, strings.TrimPrefix(name, "@rpath/")))
+		}
+	case strings.HasPrefix(name, "@executable_path/"), strings.HasPrefix(name, "@loader_path/"):
+		paths = append(paths, g.expand(name, n))
+	default:
+		paths = append(paths, g.rooted(name)...)
+	}
+	for _, dir := range g.conf.FallbackPaths {
+		paths = append(paths, filepath.Join(dir, filepath.Base(name)))
+	}
+	return paths
+}
+
+// expand expands the @executable_path and @loader_path (the directory of n) prefixes of path
+func (g *Graph) expand(path string, n *Node) string {
+	switch {
+	case strings.HasPrefix(path, "@executable_path/"):
+		return filepath.Join(g.exec, strings.TrimPrefix(path, "@executable_path/"))
+	case strings.HasPrefix(path, "@loader_path/"):
+		return filepath.Join(filepath.Dir(n.Path), strings.TrimPrefix(path, "@loader_path/"))
+	}
+	return path
+}
+
+// rooted returns the paths of the absolute path in each of the Config.Roots
+func (g *Graph) rooted(path string) []string {
+	if !filepath.IsAbs(path) || len(g.conf.Roots) == 0 {
+		return []string{path}
+	}
+	var paths []string
+	for _, root := range g.conf.Roots {
+		paths = append(paths, filepath.Join(root, path))
+	}
+	return paths
+}
+
+// realPath returns the path with its symlinks evaluated (identifying an image loaded through different paths)
+func realPath(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	return filepath.Clean(path)
+}
+
+// open opens the MachO at path (the slice matching the architecture of its loader if it is a universal
+// binary) and adds it to the graph
+func (g *Graph) open(path string, loader *Node) (*Node, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	opts := macho.OnlyLoadCommands(
+		types.LC_ID_DYLIB,
+		types.LC_LOAD_DYLIB,
+		types.LC_LOAD_WEAK_DYLIB,
+		types.LC_REEXPORT_DYLIB,
+		types.LC_LOAD_UPWARD_DYLIB,
+		types.LC_LAZY_LOAD_DYLIB,
+		types.LC_RPATH,
+	)
+	var m *macho.File
+	ff, err := macho.NewFatFile(f)
+	switch {
+	case errors.Is(err, macho.ErrNotFat):
+		if m, err = macho.NewFile(f, opts); err != nil {
+			return nil, fmt.Errorf("failed to parse MachO %s: %v", path, err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to parse MachO %s: %v", path, err)
+	default:
+		fa, err := selectArch(ff, loader)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if m, err = macho.NewFile(io.NewSectionReader(f, int64(fa.Offset), int64(fa.Size)), opts); err != nil {
+			return nil, fmt.Errorf("failed to parse MachO %s slice %s: %v", path, fa.CPU, err)
+		}
+	}
+
+	n := &Node{Path: path, cpu: m.CPU, subcpu: m.SubCPU}
+	if id := m.DylibID(); id != nil {
+		n.InstallName = id.Name
+	}
+	for _, rpath := range m.Rpaths() {
+		n.Rpaths = append(n.Rpaths, rpath.Path)
+	}
+	for _, lib := range m.ImportedLibraryDetails() {
+		n.Deps = append(n.Deps, &Dependency{ImportedLibrary: lib})
+	}
+	g.Nodes = append(g.Nodes, n)
+	return n, nil
+}
+
+// selectArch returns the slice of ff matching the architecture of loader (the first slice for the root)
+func selectArch(ff *macho.FatFile, loader *Node) (*macho.FatArch, error) {
+	if loader == nil {
+		return &ff.Arches[0], nil
+	}
+	var match *macho.FatArch
+	for i, fa := range ff.Arches {
+		if fa.CPU != loader.cpu {
+			continue
+		}
+		if fa.SubCPU&types.CpuSubtypeMask == loader.subcpu&types.CpuSubtypeMask {
+			return &ff.Arches[i], nil
+		}
+		if match == nil {
+			match = &ff.Arches[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("universal binary has no %s slice", loader.cpu)
+	}
+	return match, nil
+}
+
+// Missing returns the dylib dependencies that couldn't be resolved (the weak ones may be missing at runtime)
+func (g *Graph) Missing() []Missing {
+	var missing []Missing
+	for _, n := range g.Nodes {
+		for _, dep := range n.Deps {
+			if dep.Node == nil {
+				missing = append(missing, Missing{Importer: n, Dependency: dep})
+			}
+		}
+	}
+	return missing
+}
+
+// Node returns the image of the graph at the (resolved) path or with the install name name
+func (g *Graph) Node(name string) *Node {
+	for _, n := range g.Nodes {
+		if n.Path == name || n.InstallName == name {
+			return n
+		}
+	}
+	return nil
+}
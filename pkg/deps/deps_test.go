@@ -0,0 +1,109 @@
+package deps
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/blacktop/go-macho"
+	"github.com/blacktop/go-macho/types"
+)
+
+// writeMachO builds an x86_64 MachO (a dylib if id is set) loading dylibs with the LC_RPATHs rpaths at path
+func writeMachO(t *testing.T, path, id string, dylibs, weak, rpaths []string) {
+	t.Helper()
+	typ := types.MH_EXECUTE
+	if id != "" {
+		typ = types.MH_DYLIB
+	}
+	b := macho.NewBuilder(typ, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.InstallName = id
+	b.HeaderPad = 0x200
+	b.AddSection("__TEXT", "__text", []byte{0xc3}, types.PURE_INSTRUCTIONS)
+	for _, dylib := range dylibs {
+		b.AddDylib(dylib)
+	}
+	if typ == types.MH_EXECUTE {
+		b.SetEntryPoint("__TEXT", "__text", 0)
+	}
+	dat, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := macho.NewFile(bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dylib := range weak {
+		if err := m.InsertDylib(dylib, true, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, rpath := range rpaths {
+		if err := m.AddRpath(rpath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, "app", "bin", "app")
+	lib := filepath.Join(dir, "app", "lib")
+	root := filepath.Join(dir, "root")
+
+	writeMachO(t, app, "", []string{"@rpath/libA.dylib", "/usr/lib/libSystem.B.dylib"}, []string{"@executable_path/../lib/libmissing.dylib"}, []string{"@executable_path/../lib"})
+	writeMachO(t, filepath.Join(lib, "libA.dylib"), "@rpath/libA.dylib", []string{"@loader_path/libB.dylib", "@rpath/libC.dylib"}, nil, nil)
+	writeMachO(t, filepath.Join(lib, "libB.dylib"), "@rpath/libB.dylib", []string{"/usr/lib/libSystem.B.dylib"}, nil, nil)
+	// libC is only found through the LC_RPATH of the executable and loads libA back (a cycle)
+	writeMachO(t, filepath.Join(lib, "libC.dylib"), "@rpath/libC.dylib", []string{"@loader_path/libA.dylib", "/usr/lib/libnope.dylib"}, nil, nil)
+	writeMachO(t, filepath.Join(root, "usr", "lib", "libSystem.B.dylib"), "/usr/lib/libSystem.B.dylib", nil, nil, nil)
+
+	g, err := Walk(app, Config{Roots: []string{root}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, n := range g.Nodes {
+		ids = append(ids, n.InstallName)
+	}
+	if want := []string{"", "@rpath/libA.dylib", "@rpath/libB.dylib", "/usr/lib/libSystem.B.dylib", "@rpath/libC.dylib"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Walk() nodes = %v, want %v", ids, want)
+	}
+	if g.Root.Path != app || len(g.Root.Deps) != 3 {
+		t.Fatalf("Walk() root = %+v", g.Root)
+	}
+	libA := g.Node("@rpath/libA.dylib")
+	if libA == nil || g.Root.Deps[0].Node != libA || libA.Path != filepath.Join(lib, "libA.dylib") {
+		t.Fatalf("Walk() @rpath/libA.dylib = %+v", libA)
+	}
+	libC := g.Node("@rpath/libC.dylib")
+	if libC == nil || libA.Deps[1].Node != libC || libC.Deps[0].Node != libA {
+		t.Errorf("Walk() @rpath/libC.dylib = %+v", libC)
+	}
+	if sys := g.Node("/usr/lib/libSystem.B.dylib"); sys == nil || g.Root.Deps[1].Node != sys || g.Node(filepath.Join(lib, "libB.dylib")).Deps[0].Node != sys {
+		t.Errorf("Walk() /usr/lib/libSystem.B.dylib = %+v", sys)
+	}
+
+	missing := g.Missing()
+	if len(missing) != 2 {
+		t.Fatalf("Missing() = %v", missing)
+	}
+	if m := missing[0]; m.Importer != g.Root || m.Path != "@executable_path/../lib/libmissing.dylib" || !m.Weak() || !errors.Is(m.Err, ErrNotFound) ||
+		!reflect.DeepEqual(m.Tried, []string{filepath.Join(lib, "libmissing.dylib")}) {
+		t.Errorf("Missing()[0] = %v (tried %v)", m, m.Tried)
+	}
+	if m := missing[1]; m.Importer != libC || m.Path != "/usr/lib/libnope.dylib" || m.Weak() ||
+		!reflect.DeepEqual(m.Tried, []string{filepath.Join(root, "usr", "lib", "libnope.dylib")}) {
+		t.Errorf("Missing()[1] = %v (tried %v)", m, m.Tried)
+	}
+}
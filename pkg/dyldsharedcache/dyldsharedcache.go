@@ -0,0 +1,550 @@
+// Package dyldsharedcache implements access to dyld shared caches (including split sub-caches) and the
+// images in them.
+package dyldsharedcache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blacktop/go-macho"
+	"github.com/blacktop/go-macho/internal/saferio"
+	"github.com/blacktop/go-macho/types"
+)
+
+// Cache is a dyld shared cache and its sub-caches
+type Cache struct {
+	CacheHeader
+	ByteOrder binary.ByteOrder
+	Mappings  []*Mapping // the mappings of the cache and all of its sub-caches (sorted by address)
+	Images    []*Image
+	SubCaches []*SubCache
+
+	locals  *localSymbols // nil if the cache has no (available) local symbols
+	closers []io.Closer
+}
+
+// SubCache is a sub-cache file of a split cache
+type SubCache struct {
+	SubCacheEntry
+	CacheHeader
+	Mappings []*Mapping
+}
+
+// Mapping is a region of a cache file mapped at Address
+type Mapping struct {
+	MappingAndSlideInfo
+	SlideInfo *SlideInfo // nil if the mapping isn't slid
+
+	r io.ReaderAt // the cache file containing the mapping
+}
+
+// ReadAt reads the mapping contents at offset off
+func (m *Mapping) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 || uint64(off) >= m.Size {
+		return 0, io.EOF
+	}
+	var err error
+	if uint64(off)+uint64(len(p)) > m.Size {
+		p = p[:m.Size-uint64(off)]
+		err = io.EOF
+	}
+	n, rerr := m.r.ReadAt(p, int64(m.FileOffset)+off)
+	if rerr != nil {
+		return n, rerr
+	}
+	return n, err
+}
+
+// Contains returns true if the mapping contains the address addr
+func (m *Mapping) Contains(addr uint64) bool {
+	return m.Address <= addr && addr < m.Address+m.Size
+}
+
+func (m *Mapping) String() string {
+	return fmt.Sprintf("%#x-%#x %s/%s fileoff: %#x", m.Address, m.Address+m.Size, m.InitProt, m.MaxProt, m.FileOffset)
+}
+
+// Image is an image (dylib) in the cache
+type Image struct {
+	ImageInfo
+	Name string
+
+	cache *Cache
+}
+
+// Open opens the dyld shared cache at name and its sub-caches (which are expected next to it) as well as
+// its .symbols file (if it has one and it is there)
+func Open(name string) (*Cache, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	closers := []io.Closer{f}
+	closeAll := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+
+	hdr, err := readHeader(f)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	entries, err := readSubCacheEntries(f, hdr)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	var subs []io.ReaderAt
+	for i, e := range entries {
+		suffix := e.Suffix()
+		if suffix == "" {
+			suffix = fmt.Sprintf(".%d", i+1)
+		}
+		sf, err := os.Open(name + suffix)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to open sub-cache %s: %v", filepath.Base(name+suffix), err)
+		}
+		closers = append(closers, sf)
+		subs = append(subs, sf)
+	}
+	if hdr.hasSymbolFile() {
+		sf, err := os.Open(name + ".symbols")
+		switch {
+		case err == nil:
+			closers = append(closers, sf)
+			subs = append(subs, sf)
+		case !errors.Is(err, os.ErrNotExist):
+			closeAll()
+			return nil, fmt.Errorf("failed to open symbols file %s: %v", filepath.Base(name+".symbols"), err)
+		}
+	}
+
+	c, err := NewCache(f, subs...)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	c.closers = closers
+	return c, nil
+}
+
+// NewCache creates a new Cache for accessing the dyld shared cache in r and its sub-caches
+// (in the order of the cache's sub-cache entries, optionally followed by its .symbols file)
+func NewCache(r io.ReaderAt, subCaches ...io.ReaderAt) (*Cache, error) {
+	c := &Cache{ByteOrder: binary.LittleEndian}
+
+	hdr, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	c.CacheHeader = *hdr
+	maps, err := c.readMappings(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	c.Mappings = append(c.Mappings, maps...)
+
+	entries, err := readSubCacheEntries(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	if len(subCaches) != len(entries) && (len(subCaches) != len(entries)+1 || !hdr.hasSymbolFile()) {
+		return nil, fmt.Errorf("cache has %d sub-caches (got %d)", len(entries), len(subCaches))
+	}
+	for i, e := range entries {
+		shdr, err := readHeader(subCaches[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sub-cache %d header: %v", i+1, err)
+		}
+		if shdr.UUID != e.UUID {
+			return nil, fmt.Errorf("sub-cache %d UUID %s does not match the cache's %s", i+1, shdr.UUID, e.UUID)
+		}
+		maps, err := c.readMappings(subCaches[i], shdr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sub-cache %d mappings: %v", i+1, err)
+		}
+		c.SubCaches = append(c.SubCaches, &SubCache{SubCacheEntry: e, CacheHeader: *shdr, Mappings: maps})
+		c.Mappings = append(c.Mappings, maps...)
+	}
+	sort.SliceStable(c.Mappings, func(i, j int) bool { return c.Mappings[i].Address < c.Mappings[j].Address })
+
+	switch {
+	case hdr.LocalSymbolsOffset != 0:
+		c.locals = &localSymbols{r: r, hdr: hdr, offset: hdr.LocalSymbolsOffset}
+	case len(subCaches) > len(entries):
+		sr := subCaches[len(entries)]
+		shdr, err := readHeader(sr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read symbols file header: %v", err)
+		}
+		if shdr.UUID != hdr.SymbolFileUUID {
+			return nil, fmt.Errorf("symbols file UUID %s does not match the cache's %s", shdr.UUID, hdr.SymbolFileUUID)
+		}
+		if shdr.LocalSymbolsOffset != 0 {
+			c.locals = &localSymbols{r: sr, hdr: shdr, offset: shdr.LocalSymbolsOffset}
+		}
+	}
+
+	off, count := uint64(hdr.ImagesOffset), hdr.ImagesCount
+	if hdr.MappingOffset <= offsetImages {
+		off, count = uint64(hdr.ImagesOffsetOld), hdr.ImagesCountOld
+	}
+	infos, err := readArray[ImageInfo](r, int64(off), uint64(count), c.ByteOrder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image infos: %v", err)
+	}
+	for _, info := range infos {
+		name, err := bufio.NewReader(io.NewSectionReader(r, int64(info.PathFileOffset), 1<<16)).ReadString('\x00')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image path at offset %#x: %v", info.PathFileOffset, err)
+		}
+		c.Images = append(c.Images, &Image{ImageInfo: info, Name: strings.TrimSuffix(name, "\x00"), cache: c})
+	}
+
+	return c, nil
+}
+
+// Close closes the cache files opened by Open
+func (c *Cache) Close() error {
+	var err error
+	for _, cl := range c.closers {
+		if cerr := cl.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	c.closers = nil
+	return err
+}
+
+// readHeader reads the (variable length) cache header
+func readHeader(r io.ReaderAt) (*CacheHeader, error) {
+	var magic [16]byte
+	if _, err := r.ReadAt(magic[:], 0); err != nil {
+		return nil, fmt.Errorf("failed to read cache magic: %v", err)
+	}
+	if !bytes.HasPrefix(magic[:], []byte("dyld_v")) {
+		return nil, fmt.Errorf("invalid dyld shared cache magic %q", strings.TrimRight(string(magic[:]), "\x00"))
+	}
+	var mappingOffset [4]byte
+	if _, err := r.ReadAt(mappingOffset[:], 16); err != nil {
+		return nil, fmt.Errorf("failed to read cache header: %v", err)
+	}
+	// the mappings follow the header so the mapping offset is the size of the header
+	size := uint64(binary.LittleEndian.Uint32(mappingOffset[:]))
+	if size > cacheHeaderSize {
+		size = cacheHeaderSize
+	}
+	dat := make([]byte, cacheHeaderSize)
+	if _, err := r.ReadAt(dat[:size], 0); err != nil {
+		return nil, fmt.Errorf("failed to read cache header: %v", err)
+	}
+	var hdr CacheHeader
+	if err := binary.Read(bytes.NewReader(dat), binary.LittleEndian, &hdr); err != nil {
+		return nil, fmt.Errorf("failed to parse cache header: %v", err)
+	}
+	return &hdr, nil
+}
+
+// readSubCacheEntries reads the sub-cache entries of the cache header hdr
+func readSubCacheEntries(r io.ReaderAt, hdr *CacheHeader) ([]SubCacheEntry, error) {
+	if hdr.MappingOffset <= offsetSubCacheArray || hdr.SubCacheArrayCount == 0 {
+		return nil, nil
+	}
+	if hdr.MappingOffset <= offsetCacheSubType { // dyld_subcache_entry_v1
+		v1, err := readArray[SubCacheEntryV1](r, int64(hdr.SubCacheArrayOffset), uint64(hdr.SubCacheArrayCount), binary.LittleEndian)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sub-cache entries: %v", err)
+		}
+		entries := make([]SubCacheEntry, len(v1))
+		for i, e := range v1 {
+			entries[i] = SubCacheEntry{UUID: e.UUID, CacheVMOffset: e.CacheVMOffset}
+		}
+		return entries, nil
+	}
+	entries, err := readArray[SubCacheEntry](r, int64(hdr.SubCacheArrayOffset), uint64(hdr.SubCacheArrayCount), binary.LittleEndian)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sub-cache entries: %v", err)
+	}
+	return entries, nil
+}
+
+// readArray reads count entries of type E at offset off of r (the untrusted count is bounded by the data
+// actually there rather than allocated up front)
+func readArray[E any](r io.ReaderAt, off int64, count uint64, bo binary.ByteOrder) ([]E, error) {
+	size := uint64(binary.Size(*new(E)))
+	if count > math.MaxInt64/size {
+		return nil, fmt.Errorf("too many entries (%d)", count)
+	}
+	dat, err := saferio.ReadDataAt(r, count*size, off)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]E, count)
+	if err := binary.Read(bytes.NewReader(dat), bo, entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// readMappings reads the mappings (and their slide info) of the cache file r
+func (c *Cache) readMappings(r io.ReaderAt, hdr *CacheHeader) ([]*Mapping, error) {
+	var maps []*Mapping
+	if hdr.MappingOffset > offsetMappingWithSlide && hdr.MappingWithSlideCount > 0 {
+		infos, err := readArray[MappingAndSlideInfo](r, int64(hdr.MappingWithSlideOffset), uint64(hdr.MappingWithSlideCount), c.ByteOrder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mappings: %v", err)
+		}
+		for _, info := range infos {
+			m := &Mapping{MappingAndSlideInfo: info, r: r}
+			if info.SlideInfoFileSize > 0 {
+				dat, err := saferio.ReadDataAt(r, info.SlideInfoFileSize, int64(info.SlideInfoFileOffset))
+				if err != nil {
+					return nil, fmt.Errorf("failed to read mapping %#x slide info: %v", info.Address, err)
+				}
+				si, err := parseSlideInfo(dat, c.ByteOrder)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse mapping %#x slide info: %v", info.Address, err)
+				}
+				m.SlideInfo = si
+			}
+			maps = append(maps, m)
+		}
+		return maps, nil
+	}
+
+	infos, err := readArray[MappingInfo](r, int64(hdr.MappingOffset), uint64(hdr.MappingCount), c.ByteOrder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mappings: %v", err)
+	}
+	for _, info := range infos {
+		maps = append(maps, &Mapping{MappingAndSlideInfo: MappingAndSlideInfo{
+			Address:    info.Address,
+			Size:       info.Size,
+			FileOffset: info.FileOffset,
+			MaxProt:    info.MaxProt,
+			InitProt:   info.InitProt,
+		}, r: r})
+	}
+	// older caches have a single slide info for the data mapping
+	if hdr.SlideInfoSizeUnused > 0 && len(maps) > 1 {
+		dat, err := saferio.ReadDataAt(r, hdr.SlideInfoSizeUnused, int64(hdr.SlideInfoOffsetUnused))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read slide info: %v", err)
+		}
+		si, err := parseSlideInfo(dat, c.ByteOrder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse slide info: %v", err)
+		}
+		maps[1].SlideInfo = si
+	}
+	return maps, nil
+}
+
+// MappingForAddr returns the mapping containing the address addr
+func (c *Cache) MappingForAddr(addr uint64) (*Mapping, error) {
+	i := sort.Search(len(c.Mappings), func(i int) bool { return c.Mappings[i].Address > addr })
+	if i > 0 && c.Mappings[i-1].Contains(addr) {
+		return c.Mappings[i-1], nil
+	}
+	return nil, fmt.Errorf("address %#x is not in a cache mapping", addr)
+}
+
+// ReadAtAddr reads len(buf) bytes of the cache at the (unslid) address addr (across mappings and sub-caches)
+func (c *Cache) ReadAtAddr(buf []byte, addr uint64) (int, error) {
+	var n int
+	for n < len(buf) {
+		m, err := c.MappingForAddr(addr + uint64(n))
+		if err != nil {
+			return n, err
+		}
+		rn, err := m.ReadAt(buf[n:], int64(addr+uint64(n)-m.Address))
+		n += rn
+		if err != nil && !(errors.Is(err, io.EOF) && rn > 0) {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// SlidePointer returns the (unslid) target of the pointer ptr stored at the (unslid) address addr, decoded
+// with the slide info of the mapping containing addr (ptr is returned as is if that mapping isn't slid)
+func (c *Cache) SlidePointer(addr, ptr uint64) uint64 {
+	if m, err := c.MappingForAddr(addr); err == nil && m.SlideInfo != nil {
+		return m.SlideInfo.SlidePointer(ptr)
+	}
+	return ptr
+}
+
+// Rebases returns the pointers rebased by the slide info of the mapping m
+func (c *Cache) Rebases(m *Mapping) ([]Rebase, error) {
+	if m.SlideInfo == nil {
+		return nil, nil
+	}
+	return m.SlideInfo.rebases(c, m, m.Address, m.Address+m.Size)
+}
+
+// Image returns the image whose path is (or ends with) name
+func (c *Cache) Image(name string) (*Image, error) {
+	for _, img := range c.Images {
+		if img.Name == name {
+			return img, nil
+		}
+	}
+	for _, img := range c.Images {
+		if filepath.Base(img.Name) == name {
+			return img, nil
+		}
+	}
+	return nil, fmt.Errorf("image %s is not in the cache", name)
+}
+
+// GetMacho returns the image's MachO. Its reads of file offsets are translated through its segments
+// into (unslid) cache addresses and its pointers are slid with the cache slide info
+func (i *Image) GetMacho() (*macho.File, error) {
+	return i.getMacho(false)
+}
+
+// getMacho returns the image's MachO (with its slid pointers read back unslid if unslide is set)
+func (i *Image) getMacho(unslide bool) (*macho.File, error) {
+	c := i.cache
+	hdr := io.NewSectionReader(addrReader{c}, int64(i.Address), 1<<63-1-int64(i.Address))
+	// first read the segments to translate the image's file offsets
+	m, err := macho.NewFile(hdr, macho.FileConfig{LoadIncluding: []types.LoadCmd{types.LC_SEGMENT, types.LC_SEGMENT_64}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image %s load commands: %v", i.Name, err)
+	}
+	r := &imageReader{cache: c, segs: m.Segments()}
+	text := m.Segment("__TEXT")
+	if text == nil {
+		return nil, fmt.Errorf("image %s has no __TEXT segment", i.Name)
+	}
+	r.addr = text.Addr
+	if unslide {
+		if err := r.unslide(); err != nil {
+			return nil, fmt.Errorf("failed to unslide image %s: %v", i.Name, err)
+		}
+	}
+	// the MachO only hands its converter pointer values (not where they are stored) so use the slide info
+	// of the mapping of the image's first slid segment
+	slide := func(ptr uint64) uint64 { return ptr }
+	for _, seg := range r.segs {
+		if mp, err := c.MappingForAddr(seg.Addr); err == nil && mp.SlideInfo != nil {
+			slide = mp.SlideInfo.SlidePointer
+			break
+		}
+	}
+	m, err = macho.NewFile(hdr, macho.FileConfig{
+		Offset:        int64(text.Offset),
+		SectionReader: r,
+		VMAddrConverter: types.VMAddrConverter{
+			PreferredLoadAddress: text.Addr,
+			Converter:            slide,
+			VMAddr2Offet:         r.offset,
+			Offet2VMAddr:         r.address,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image %s: %v", i.Name, err)
+	}
+	return m, nil
+}
+
+// addrReader is an io.ReaderAt reading at (unslid) cache addresses
+type addrReader struct {
+	c *Cache
+}
+
+func (r addrReader) ReadAt(p []byte, off int64) (int, error) {
+	return r.c.ReadAtAddr(p, uint64(off))
+}
+
+// imageReader is a types.MachoReader of an image's file offsets (translated through its segments into cache
+// addresses); its position is an address so it can also read any address in the cache
+type imageReader struct {
+	cache *Cache
+	segs  []*macho.Segment
+	addr  uint64
+
+	rebases []Rebase // the image's slid pointers (sorted by address) to read back unslid
+	ptrSize int
+}
+
+// address returns the address of the image file offset off
+func (r *imageReader) address(off uint64) (uint64, error) {
+	for _, seg := range r.segs {
+		if seg.Offset <= off && off < seg.Offset+seg.Filesz {
+			return seg.Addr + off - seg.Offset, nil
+		}
+	}
+	return 0, fmt.Errorf("offset %#x is not in a segment", off)
+}
+
+// offset returns the image file offset of the address addr
+func (r *imageReader) offset(addr uint64) (uint64, error) {
+	for _, seg := range r.segs {
+		if seg.Addr <= addr && addr < seg.Addr+seg.Filesz {
+			return seg.Offset + addr - seg.Addr, nil
+		}
+	}
+	return 0, fmt.Errorf("address %#x is not in a segment", addr)
+}
+
+func (r *imageReader) Read(p []byte) (int, error) {
+	n, err := r.ReadAtAddr(p, r.addr)
+	r.addr += uint64(n)
+	return n, err
+}
+
+func (r *imageReader) ReadAt(p []byte, off int64) (int, error) {
+	addr, err := r.address(uint64(off))
+	if err != nil {
+		return 0, err
+	}
+	return r.ReadAtAddr(p, addr)
+}
+
+func (r *imageReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		cur, err := r.offset(r.addr)
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(cur)
+	default:
+		return 0, fmt.Errorf("Seek: unsupported whence %d", whence)
+	}
+	addr, err := r.address(uint64(offset))
+	if err != nil {
+		return 0, err
+	}
+	r.addr = addr
+	return offset, nil
+}
+
+func (r *imageReader) SeekToAddr(addr uint64) error {
+	if _, err := r.cache.MappingForAddr(addr); err != nil {
+		return err
+	}
+	r.addr = addr
+	return nil
+}
+
+func (r *imageReader) ReadAtAddr(buf []byte, addr uint64) (int, error) {
+	n, err := r.cache.ReadAtAddr(buf, addr)
+	r.patch(buf[:n], addr)
+	return n, err
+}
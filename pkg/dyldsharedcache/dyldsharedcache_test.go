@@ -0,0 +1,429 @@
+package dyldsharedcache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/blacktop/go-macho"
+	"github.com/blacktop/go-macho/types"
+)
+
+func TestCacheHeaderLayout(t *testing.T) {
+	var hdr CacheHeader
+	for _, tt := range []struct {
+		name      string
+		got, want uintptr
+	}{
+		{"MappingWithSlideOffset", unsafe.Offsetof(hdr.MappingWithSlideOffset), offsetMappingWithSlide},
+		{"SubCacheArrayOffset", unsafe.Offsetof(hdr.SubCacheArrayOffset), offsetSubCacheArray},
+		{"SymbolFileUUID", unsafe.Offsetof(hdr.SymbolFileUUID), offsetSymbolFileUUID},
+		{"ImagesOffset", unsafe.Offsetof(hdr.ImagesOffset), offsetImages},
+		{"CacheSubType", unsafe.Offsetof(hdr.CacheSubType), offsetCacheSubType},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("offset of %s = %#x; want %#x", tt.name, tt.got, tt.want)
+		}
+	}
+	if size := binary.Size(hdr); size != cacheHeaderSize {
+		t.Errorf("size of CacheHeader = %#x; want %#x", size, cacheHeaderSize)
+	}
+}
+
+// buildCache builds a split cache (the main cache file and a .01 sub-cache with the __LINKEDIT) with a single
+// image whose __DATA pointers are slid with slide info v2
+func buildCache(t *testing.T) (main, sub []byte, text uint64, data *macho.BuilderSection) {
+	t.Helper()
+	b := macho.NewBuilder(types.MH_DYLIB, types.CPUAmd64, types.CPUSubtypeX8664All)
+	b.InstallName = "/usr/lib/libfoo.dylib"
+	b.AddSection("__TEXT", "__text", []byte{0x55, 0x48, 0x89, 0xe5, 0x5d, 0xc3, 0xc3, 0xc3}, types.PURE_INSTRUCTIONS)
+	data = b.AddSection("__DATA", "__data", make([]byte, 2*8), types.Regular)
+	data.Align = 3
+	b.AddSymbol("_foo", "__TEXT", "__text", 0, true)
+	dylib, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text = b.Segment("__TEXT").Sections[0].Addr
+	dataSeg := b.Segment("__DATA")
+	parsed, err := macho.NewFile(bytes.NewReader(dylib))
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkedit := parsed.Segment("__LINKEDIT")
+
+	// slide info v2 chain (the delta is in 4-byte units at bits 40-55)
+	const deltaMask = 0x00FFFF0000000000
+	binary.LittleEndian.PutUint64(dylib[data.Offset:], 2<<40|(text+4))
+	binary.LittleEndian.PutUint64(dylib[data.Offset+8:], text+6)
+	// mark it as a cache dylib (flags are at offset 24 of the mach_header_64)
+	binary.LittleEndian.PutUint32(dylib[24:], binary.LittleEndian.Uint32(dylib[24:])|uint32(types.DylibInCache))
+
+	bo := binary.LittleEndian
+	const dylibOffset = 0x4000
+	subUUID := types.UUID{1, 2, 3}
+
+	main = make([]byte, dylibOffset+len(dylib))
+	copy(main[dylibOffset:], dylib)
+	hdr := CacheHeader{
+		MappingOffset:          cacheHeaderSize,
+		MappingCount:           2,
+		UUID:                   types.UUID{0xca, 0xfe},
+		MappingWithSlideOffset: cacheHeaderSize,
+		MappingWithSlideCount:  2,
+		ImagesOffset:           0x300,
+		ImagesCount:            1,
+		SubCacheArrayOffset:    0x340,
+		SubCacheArrayCount:     1,
+	}
+	copy(hdr.Magic[:], "dyld_v1  x86_64")
+	var buf bytes.Buffer
+	binary.Write(&buf, bo, hdr)
+	binary.Write(&buf, bo, []MappingAndSlideInfo{
+		{Address: b.Segment("__TEXT").Addr, Size: b.Segment("__TEXT").Filesz, FileOffset: dylibOffset, MaxProt: 5, InitProt: 5},
+		{Address: dataSeg.Addr, Size: dataSeg.Filesz, FileOffset: dylibOffset + dataSeg.Offset,
+			SlideInfoFileOffset: 0x400, SlideInfoFileSize: 42, MaxProt: 3, InitProt: 3},
+	})
+	copy(main, buf.Bytes())
+	buf.Reset()
+	binary.Write(&buf, bo, ImageInfo{Address: b.Segment("__TEXT").Addr, PathFileOffset: 0x380})
+	copy(main[0x300:], buf.Bytes())
+	buf.Reset()
+	entry := SubCacheEntry{UUID: subUUID, CacheVMOffset: linkedit.Addr}
+	copy(entry.FileSuffix[:], ".01")
+	binary.Write(&buf, bo, entry)
+	copy(main[0x340:], buf.Bytes())
+	copy(main[0x380:], b.InstallName+"\x00")
+	buf.Reset()
+	binary.Write(&buf, bo, slideInfo2{
+		Version:          2,
+		PageSize:         0x1000,
+		PageStartsOffset: 40,
+		PageStartsCount:  1,
+		PageExtrasOffset: 42,
+		DeltaMask:        deltaMask,
+	})
+	binary.Write(&buf, bo, uint16((data.Addr-dataSeg.Addr)/4))
+	copy(main[0x400:], buf.Bytes())
+
+	sub = make([]byte, 0x1000+linkedit.Filesz)
+	copy(sub[0x1000:], dylib[linkedit.Offset:])
+	shdr := CacheHeader{
+		MappingOffset:          cacheHeaderSize,
+		MappingCount:           1,
+		UUID:                   subUUID,
+		MappingWithSlideOffset: cacheHeaderSize,
+		MappingWithSlideCount:  1,
+	}
+	copy(shdr.Magic[:], "dyld_v1  x86_64")
+	buf.Reset()
+	binary.Write(&buf, bo, shdr)
+	binary.Write(&buf, bo, MappingAndSlideInfo{Address: linkedit.Addr, Size: linkedit.Filesz, FileOffset: 0x1000, MaxProt: 1, InitProt: 1})
+	copy(sub, buf.Bytes())
+
+	return main, sub, text, data
+}
+
+func TestCache(t *testing.T) {
+	main, sub, text, data := buildCache(t)
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "dyld_shared_cache_x86_64")
+	if err := os.WriteFile(name, main, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCache(bytes.NewReader(main)); err == nil {
+		t.Errorf("NewCache() without its sub-cache should fail")
+	}
+	if _, err := Open(name); err == nil {
+		t.Errorf("Open() without its sub-cache should fail")
+	}
+	if err := os.WriteFile(name+".01", sub, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Arch() != "x86_64" || len(c.Mappings) != 3 || len(c.SubCaches) != 1 || c.SubCaches[0].Suffix() != ".01" {
+		t.Errorf("Open() = %s, %d mappings, %d sub-caches", c.CacheHeader, len(c.Mappings), len(c.SubCaches))
+	}
+	img, err := c.Image("libfoo.dylib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Name != "/usr/lib/libfoo.dylib" {
+		t.Errorf("Image() = %s", img.Name)
+	}
+
+	m, err := img.GetMacho()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := m.DylibID(); id == nil || id.Name != img.Name {
+		t.Errorf("GetMacho() LC_ID_DYLIB = %v", id)
+	}
+	if m.Symtab == nil || len(m.Symtab.Syms) == 0 || m.Symtab.Syms[0].Name != "_foo" || m.Symtab.Syms[0].Value != text {
+		t.Errorf("GetMacho() symbols = %v", m.Symtab)
+	}
+	dat, err := m.Section("__TEXT", "__text").Data()
+	if err != nil || !bytes.Equal(dat, []byte{0x55, 0x48, 0x89, 0xe5, 0x5d, 0xc3, 0xc3, 0xc3}) {
+		t.Errorf("GetMacho() __text = % x, %v", dat, err)
+	}
+	for i, want := range []uint64{text + 4, text + 6} {
+		addr := data.Addr + uint64(i*8)
+		if got, err := m.GetPointerAtAddress(addr); err != nil || got != want {
+			t.Errorf("GetPointerAtAddress(%#x) = %#x, %v; want %#x", addr, got, err, want)
+		}
+	}
+
+	mapping, err := c.MappingForAddr(data.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebases, err := c.Rebases(mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rebases) != 2 || rebases[0].Address != data.Addr || rebases[0].Target != text+4 ||
+		rebases[1].Address != data.Addr+8 || rebases[1].Target != text+6 {
+		t.Errorf("Rebases() = %v", rebases)
+	}
+	if _, err := c.MappingForAddr(1 << 62); err == nil {
+		t.Errorf("MappingForAddr() of an unmapped address should fail")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	main, sub, text, data := buildCache(t)
+	c, err := NewCache(bytes.NewReader(main), bytes.NewReader(sub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := c.Image("/usr/lib/libfoo.dylib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "libfoo.dylib")
+	if err := img.Extract(out); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := macho.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if m.Flags.DylibInCache() {
+		t.Errorf("Extract() kept MH_DYLIB_IN_CACHE")
+	}
+	var end uint64
+	for _, seg := range m.Segments() {
+		if seg.Offset < end || seg.Offset%0x1000 != 0 {
+			t.Errorf("Extract() segment %s offset = %#x", seg.Name, seg.Offset)
+		}
+		end = seg.Offset + seg.Filesz
+	}
+	if id := m.DylibID(); id == nil || id.Name != img.Name {
+		t.Errorf("Extract() LC_ID_DYLIB = %v", id)
+	}
+	if m.Symtab == nil || len(m.Symtab.Syms) != 1 || m.Symtab.Syms[0].Name != "_foo" || m.Symtab.Syms[0].Value != text {
+		t.Errorf("Extract() symbols = %v", m.Symtab)
+	}
+	dat, err := m.Section("__TEXT", "__text").Data()
+	if err != nil || !bytes.Equal(dat, []byte{0x55, 0x48, 0x89, 0xe5, 0x5d, 0xc3, 0xc3, 0xc3}) {
+		t.Errorf("Extract() __text = % x, %v", dat, err)
+	}
+	// the slid pointers are written back unslid
+	dat, err = m.Section("__DATA", "__data").Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []uint64{text + 4, text + 6} {
+		if got := binary.LittleEndian.Uint64(dat[i*8:]); got != want {
+			t.Errorf("Extract() pointer at %#x = %#x; want %#x", data.Addr+uint64(i*8), got, want)
+		}
+	}
+}
+
+// localSymbolsBlob returns a local symbols area (with 64-bit entries) with the local symbol _bar at addr for the
+// dylib at the offset dylibOffset (and one for another dylib)
+func localSymbolsBlob(dylibOffset, addr uint64) []byte {
+	var buf bytes.Buffer
+	bo := binary.LittleEndian
+	binary.Write(&buf, bo, LocalSymbolsInfo{
+		NlistOffset:   24 + 2*16,
+		NlistCount:    2,
+		StringsOffset: 24 + 2*16 + 2*16,
+		StringsSize:   16,
+		EntriesOffset: 24,
+		EntriesCount:  2,
+	})
+	binary.Write(&buf, bo, []LocalSymbolsEntry64{
+		{DylibOffset: dylibOffset + 0x10000, NlistStartIndex: 0, NlistCount: 1},
+		{DylibOffset: dylibOffset, NlistStartIndex: 1, NlistCount: 1},
+	})
+	binary.Write(&buf, bo, []types.Nlist64{
+		{Nlist: types.Nlist{Name: 1, Type: types.N_SECT, Sect: 1}, Value: 0x10000},
+		{Nlist: types.Nlist{Name: 6, Type: types.N_SECT, Sect: 1}, Value: addr},
+	})
+	buf.WriteString("\x00_baz\x00_bar\x00\x00\x00\x00\x00\x00")
+	return buf.Bytes()
+}
+
+func TestLocalSymbols(t *testing.T) {
+	main, sub, text, _ := buildCache(t)
+	var hdr CacheHeader
+	bo := binary.LittleEndian
+
+	check := func(c *Cache) {
+		t.Helper()
+		img, err := c.Image("libfoo.dylib")
+		if err != nil {
+			t.Fatal(err)
+		}
+		locals, err := img.LocalSymbols()
+		if err != nil || len(locals) != 1 || locals[0].Name != "_bar" || locals[0].Value != text+4 {
+			t.Fatalf("LocalSymbols() = %v, %v", locals, err)
+		}
+		syms, err := img.Symbols()
+		if err != nil || len(syms) != 2 || syms[0].Name != "_bar" || syms[1].Name != "_foo" {
+			t.Errorf("Symbols() = %v, %v", syms, err)
+		}
+		out := filepath.Join(t.TempDir(), "libfoo.dylib")
+		if err := img.Extract(out); err != nil {
+			t.Fatal(err)
+		}
+		m, err := macho.Open(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m.Close()
+		if m.Symtab == nil || len(m.Symtab.Syms) != 2 || m.Symtab.Syms[0].Name != "_bar" || m.Symtab.Syms[0].Value != text+4 {
+			t.Errorf("Extract() symbols = %v", m.Symtab)
+		}
+	}
+
+	// without local symbols
+	c, err := NewCache(bytes.NewReader(main), bytes.NewReader(sub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Images[0].LocalSymbols(); err != ErrNoLocalSymbols {
+		t.Errorf("LocalSymbols() error = %v; want %v", err, ErrNoLocalSymbols)
+	}
+	if syms, err := c.Images[0].Symbols(); err != nil || len(syms) != 1 {
+		t.Errorf("Symbols() = %v, %v", syms, err)
+	}
+
+	// in the cache
+	blob := localSymbolsBlob(0, text+4)
+	inCache := append(append([]byte{}, main...), blob...)
+	bo.PutUint64(inCache[unsafe.Offsetof(hdr.LocalSymbolsOffset):], uint64(len(main)))
+	bo.PutUint64(inCache[unsafe.Offsetof(hdr.LocalSymbolsSize):], uint64(len(blob)))
+	c, err = NewCache(bytes.NewReader(inCache), bytes.NewReader(sub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(c)
+
+	// in a .symbols file
+	symUUID := types.UUID{0x5e}
+	symHdr := CacheHeader{
+		MappingOffset:      cacheHeaderSize,
+		UUID:               symUUID,
+		LocalSymbolsOffset: cacheHeaderSize,
+		LocalSymbolsSize:   uint64(len(blob)),
+	}
+	copy(symHdr.Magic[:], "dyld_v1  x86_64")
+	var buf bytes.Buffer
+	binary.Write(&buf, bo, symHdr)
+	buf.Write(blob)
+	withSymbolFile := append([]byte{}, main...)
+	copy(withSymbolFile[unsafe.Offsetof(hdr.SymbolFileUUID):], symUUID[:])
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "dyld_shared_cache_x86_64")
+	for suffix, dat := range map[string][]byte{"": withSymbolFile, ".01": sub} {
+		if err := os.WriteFile(name+suffix, dat, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err = Open(name) // the .symbols file is optional
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Images[0].LocalSymbols(); err != ErrNoLocalSymbols {
+		t.Errorf("LocalSymbols() error = %v; want %v", err, ErrNoLocalSymbols)
+	}
+	c.Close()
+	if err := os.WriteFile(name+".symbols", buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err = Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	check(c)
+}
+
+func TestSlidePointer(t *testing.T) {
+	for _, tt := range []struct {
+		si   SlideInfo
+		ptr  uint64
+		want uint64
+	}{
+		{SlideInfo{Version: 2, DeltaMask: 0x00FFFF0000000000}, 0x0002_0001_8000_1000, 0x1_8000_1000},
+		{SlideInfo{Version: 2, DeltaMask: 0x00FFFF0000000000}, 0x0002_0000_0000_0000, 0},
+		{SlideInfo{Version: 3, ValueAdd: 0x1_8000_0000}, 0x0008_0001_8000_1000, 0x1_8000_1000},
+		{SlideInfo{Version: 3, ValueAdd: 0x1_8000_0000}, 1<<63 | 0x1000, 0x1_8000_1000},
+		{SlideInfo{Version: 3}, 0x12<<43 | 0x1_8000_1000, 0x1200_0001_8000_1000},
+		{SlideInfo{Version: 4, DeltaMask: 0xC0000000, ValueAdd: 0x1000_0000}, 0x4001_0000, 0x1001_0000},
+		{SlideInfo{Version: 4, DeltaMask: 0xC0000000, ValueAdd: 0x1000_0000}, 0x7ff0, 0x7ff0},
+		{SlideInfo{Version: 4, DeltaMask: 0xC0000000, ValueAdd: 0x1000_0000}, 0x3fff_fff0, 0xffff_fff0},
+		{SlideInfo{Version: 5, ValueAdd: 0x1_8000_0000}, 1<<52 | 0x1000, 0x1_8000_1000},
+		{SlideInfo{Version: 5, ValueAdd: 0x1_8000_0000}, 1<<63 | 1<<51 | 0x1000, 0x1_8000_1000},
+	} {
+		if got := tt.si.SlidePointer(tt.ptr); got != tt.want {
+			t.Errorf("SlideInfo v%d SlidePointer(%#x) = %#x; want %#x", tt.si.Version, tt.ptr, got, tt.want)
+		}
+	}
+}
+
+func TestCacheSlidePointer(t *testing.T) {
+	c := &Cache{Mappings: []*Mapping{
+		{MappingAndSlideInfo: MappingAndSlideInfo{Address: 0x1000, Size: 0x1000}},
+		{MappingAndSlideInfo: MappingAndSlideInfo{Address: 0x2000, Size: 0x1000}, SlideInfo: &SlideInfo{Version: 2, DeltaMask: 0x00FFFF0000000000}},
+		{MappingAndSlideInfo: MappingAndSlideInfo{Address: 0x3000, Size: 0x1000}, SlideInfo: &SlideInfo{Version: 3, ValueAdd: 0x1_8000_0000}},
+	}}
+	for _, tt := range []struct {
+		addr, ptr, want uint64
+	}{
+		{0x1008, 0x0002_0001_8000_1000, 0x0002_0001_8000_1000}, // not slid
+		{0x2008, 0x0002_0001_8000_1000, 0x1_8000_1000},
+		{0x3008, 1<<63 | 0x1000, 0x1_8000_1000}, // authenticated v3 pointers are offsets from ValueAdd
+		{0x5000, 1<<63 | 0x1000, 1<<63 | 0x1000},
+	} {
+		if got := c.SlidePointer(tt.addr, tt.ptr); got != tt.want {
+			t.Errorf("SlidePointer(%#x, %#x) = %#x; want %#x", tt.addr, tt.ptr, got, tt.want)
+		}
+	}
+}
+
+func TestCacheBadCounts(t *testing.T) {
+	main, sub, _, _ := buildCache(t)
+	var hdr CacheHeader
+	for _, off := range []uintptr{unsafe.Offsetof(hdr.ImagesCount), unsafe.Offsetof(hdr.MappingWithSlideCount), unsafe.Offsetof(hdr.SubCacheArrayCount)} {
+		bad := append([]byte(nil), main...)
+		binary.LittleEndian.PutUint32(bad[off:], 0xffffffff)
+		if _, err := NewCache(bytes.NewReader(bad), bytes.NewReader(sub)); err == nil {
+			t.Errorf("NewCache() with a count of 0xffffffff at %#x succeeded", off)
+		}
+	}
+}
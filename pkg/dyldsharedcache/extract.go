@@ -0,0 +1,74 @@
+package dyldsharedcache
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Extract writes the image to path as a standalone MachO: its segments are laid out at new (page aligned) file
+// offsets, its __LINKEDIT is rebuilt from its share of the cache's (exports, symbols, indirect symbols, function
+// starts and data-in-code, plus its local symbols if the cache has them) and its slid pointers are written
+// back as plain (unslid) addresses
+func (i *Image) Extract(path string) error {
+	locals, err := i.LocalSymbols()
+	if err != nil && !errors.Is(err, ErrNoLocalSymbols) {
+		return err
+	}
+	m, err := i.getMacho(true)
+	if err != nil {
+		return err
+	}
+	if !m.FileHeader.Flags.DylibInCache() {
+		return fmt.Errorf("image %s is not a cache dylib (MH_DYLIB_IN_CACHE is not set)", i.Name)
+	}
+	if m.Dysymtab == nil {
+		return fmt.Errorf("image %s has no LC_DYSYMTAB", i.Name)
+	}
+	if err := m.Export(path, nil, m.GetBaseAddress(), locals); err != nil {
+		return fmt.Errorf("failed to extract image %s: %v", i.Name, err)
+	}
+	return nil
+}
+
+// unslide collects the slid pointers in the image's segments so that they are read back unslid
+func (r *imageReader) unslide() error {
+	for _, seg := range r.segs {
+		for _, m := range r.cache.Mappings {
+			if m.SlideInfo == nil || m.Address >= seg.Addr+seg.Memsz || m.Address+m.Size <= seg.Addr {
+				continue
+			}
+			rebases, err := m.SlideInfo.rebases(r.cache, m, seg.Addr, seg.Addr+seg.Memsz)
+			if err != nil {
+				return err
+			}
+			for _, rb := range rebases {
+				if seg.Addr <= rb.Address && rb.Address < seg.Addr+seg.Memsz {
+					r.rebases = append(r.rebases, rb)
+				}
+			}
+			r.ptrSize = m.SlideInfo.pointerSize()
+		}
+	}
+	sort.Slice(r.rebases, func(i, j int) bool { return r.rebases[i].Address < r.rebases[j].Address })
+	return nil
+}
+
+// patch writes the unslid targets of the slid pointers in buf (read at the address addr)
+func (r *imageReader) patch(buf []byte, addr uint64) {
+	if len(r.rebases) == 0 {
+		return
+	}
+	end := addr + uint64(len(buf))
+	for i := sort.Search(len(r.rebases), func(i int) bool { return r.rebases[i].Address >= addr }); i < len(r.rebases); i++ {
+		rb := r.rebases[i]
+		if rb.Address+uint64(r.ptrSize) > end {
+			break
+		}
+		if r.ptrSize == 4 {
+			r.cache.ByteOrder.PutUint32(buf[rb.Address-addr:], uint32(rb.Target))
+		} else {
+			r.cache.ByteOrder.PutUint64(buf[rb.Address-addr:], rb.Target)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package dyldsharedcache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/blacktop/go-macho"
+	"github.com/blacktop/go-macho/types"
+)
+
+// ErrNoLocalSymbols is returned when the cache's local symbols are not available (e.g. its .symbols file is missing)
+var ErrNoLocalSymbols = errors.New("cache has no local symbols")
+
+// localSymbols is the local symbols area (dyld_cache_local_symbols_info) of the cache or of its .symbols file
+type localSymbols struct {
+	r      io.ReaderAt
+	hdr    *CacheHeader // the header of the file with the local symbols
+	offset uint64
+
+	info    *LocalSymbolsInfo
+	entries map[uint64]LocalSymbolsEntry64 // by dylib offset
+	strings []byte
+}
+
+// parse reads the local symbols info and entries
+func (ls *localSymbols) parse(bo binary.ByteOrder) error {
+	if ls.info != nil {
+		return nil
+	}
+	var info LocalSymbolsInfo
+	if err := binary.Read(io.NewSectionReader(ls.r, int64(ls.offset), int64(binary.Size(info))), bo, &info); err != nil {
+		return fmt.Errorf("failed to read local symbols info: %v", err)
+	}
+	sr := io.NewSectionReader(ls.r, int64(ls.offset)+int64(info.EntriesOffset), 1<<63-1-int64(ls.offset)-int64(info.EntriesOffset))
+	entries := make(map[uint64]LocalSymbolsEntry64, info.EntriesCount)
+	if ls.hdr.MappingOffset > offsetSymbolFileUUID {
+		es := make([]LocalSymbolsEntry64, info.EntriesCount)
+		if err := binary.Read(sr, bo, es); err != nil {
+			return fmt.Errorf("failed to read local symbols entries: %v", err)
+		}
+		for _, e := range es {
+			entries[e.DylibOffset] = e
+		}
+	} else {
+		es := make([]LocalSymbolsEntry, info.EntriesCount)
+		if err := binary.Read(sr, bo, es); err != nil {
+			return fmt.Errorf("failed to read local symbols entries: %v", err)
+		}
+		for _, e := range es {
+			entries[uint64(e.DylibOffset)] = LocalSymbolsEntry64{
+				DylibOffset:     uint64(e.DylibOffset),
+				NlistStartIndex: e.NlistStartIndex,
+				NlistCount:      e.NlistCount,
+			}
+		}
+	}
+	strs := make([]byte, info.StringsSize)
+	if _, err := ls.r.ReadAt(strs, int64(ls.offset)+int64(info.StringsOffset)); err != nil {
+		return fmt.Errorf("failed to read local symbols strings: %v", err)
+	}
+	ls.info, ls.entries, ls.strings = &info, entries, strs
+	return nil
+}
+
+// symbols reads the local symbols of the entry e
+func (ls *localSymbols) symbols(e LocalSymbolsEntry64, bo binary.ByteOrder) ([]macho.Symbol, error) {
+	if uint64(e.NlistStartIndex)+uint64(e.NlistCount) > uint64(ls.info.NlistCount) {
+		return nil, fmt.Errorf("local symbols %d-%d are out of range (count %d)",
+			e.NlistStartIndex, e.NlistStartIndex+e.NlistCount, ls.info.NlistCount)
+	}
+	size := int64(binary.Size(types.Nlist64{}))
+	if !ls.hdr.is64() {
+		size = int64(binary.Size(types.Nlist32{}))
+	}
+	dat := make([]byte, int64(e.NlistCount)*size)
+	if _, err := ls.r.ReadAt(dat, int64(ls.offset)+int64(ls.info.NlistOffset)+int64(e.NlistStartIndex)*size); err != nil {
+		return nil, fmt.Errorf("failed to read local symbols: %v", err)
+	}
+	r := bytes.NewReader(dat)
+	syms := make([]macho.Symbol, 0, e.NlistCount)
+	for i := uint32(0); i < e.NlistCount; i++ {
+		var n types.Nlist
+		var value uint64
+		if ls.hdr.is64() {
+			var n64 types.Nlist64
+			if err := binary.Read(r, bo, &n64); err != nil {
+				return nil, fmt.Errorf("failed to read local symbol %d: %v", i, err)
+			}
+			n, value = n64.Nlist, n64.Value
+		} else {
+			var n32 types.Nlist32
+			if err := binary.Read(r, bo, &n32); err != nil {
+				return nil, fmt.Errorf("failed to read local symbol %d: %v", i, err)
+			}
+			n, value = n32.Nlist, uint64(n32.Value)
+		}
+		if uint64(n.Name) >= uint64(len(ls.strings)) {
+			return nil, fmt.Errorf("local symbol %d name offset %#x is out of range", i, n.Name)
+		}
+		name := ls.strings[n.Name:]
+		if end := bytes.IndexByte(name, 0); end >= 0 {
+			name = name[:end]
+		}
+		syms = append(syms, macho.Symbol{Name: string(name), Type: n.Type, Sect: n.Sect, Desc: n.Desc, Value: value})
+	}
+	return syms, nil
+}
+
+// LocalSymbols returns the image's local symbols (which are stripped from its symbol table in the cache)
+func (i *Image) LocalSymbols() ([]macho.Symbol, error) {
+	c := i.cache
+	if c.locals == nil {
+		return nil, ErrNoLocalSymbols
+	}
+	if err := c.locals.parse(c.ByteOrder); err != nil {
+		return nil, err
+	}
+	// the dylib offset is the image's offset from the start of the (main) cache
+	e, ok := c.locals.entries[i.Address-c.Mappings[0].Address]
+	if !ok {
+		return nil, nil
+	}
+	syms, err := c.locals.symbols(e, c.ByteOrder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image %s local symbols: %v", i.Name, err)
+	}
+	return syms, nil
+}
+
+// Symbols returns the image's local symbols (if the cache has them) followed by the symbols of its symbol table
+func (i *Image) Symbols() ([]macho.Symbol, error) {
+	syms, err := i.LocalSymbols()
+	if err != nil && !errors.Is(err, ErrNoLocalSymbols) {
+		return nil, err
+	}
+	m, err := i.GetMacho()
+	if err != nil {
+		return nil, err
+	}
+	if m.Symtab != nil {
+		for _, sym := range m.Symtab.Syms {
+			if sym.Name == "<redacted>" { // the placeholder for the stripped local symbols
+				continue
+			}
+			syms = append(syms, sym)
+		}
+	}
+	return syms, nil
+}
@@ -0,0 +1,203 @@
+package dyldsharedcache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/blacktop/go-macho/pkg/fixupchains"
+)
+
+// SlideInfo is the slide info of a mapping (versions 2 through 5)
+type SlideInfo struct {
+	Version    uint32
+	PageSize   uint32
+	PageStarts []uint16
+	PageExtras []uint16 // versions 2 and 4
+	DeltaMask  uint64   // versions 2 and 4
+	ValueAdd   uint64   // the auth_value_add for version 3
+}
+
+// Rebase is a pointer rebased by the slide info
+type Rebase struct {
+	Address uint64 // (unslid) address of the pointer
+	Raw     uint64 // the pointer as stored in the cache
+	Target  uint64 // (unslid) target of the pointer
+}
+
+func (r Rebase) String() string {
+	return fmt.Sprintf("%#x: raw: %#016x, target: %#x", r.Address, r.Raw, r.Target)
+}
+
+func parseSlideInfo(dat []byte, bo binary.ByteOrder) (*SlideInfo, error) {
+	if len(dat) < 4 {
+		return nil, fmt.Errorf("slide info is too small")
+	}
+	r := bytes.NewReader(dat)
+	si := &SlideInfo{Version: bo.Uint32(dat)}
+	switch si.Version {
+	case 2, 4:
+		var hdr slideInfo2
+		if err := binary.Read(r, bo, &hdr); err != nil {
+			return nil, fmt.Errorf("failed to read slide info v%d: %v", si.Version, err)
+		}
+		si.PageSize = hdr.PageSize
+		si.DeltaMask = hdr.DeltaMask
+		si.ValueAdd = hdr.ValueAdd
+		si.PageStarts = make([]uint16, hdr.PageStartsCount)
+		if _, err := r.Seek(int64(hdr.PageStartsOffset), 0); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(r, bo, si.PageStarts); err != nil {
+			return nil, fmt.Errorf("failed to read slide info v%d page starts: %v", si.Version, err)
+		}
+		si.PageExtras = make([]uint16, hdr.PageExtrasCount)
+		if _, err := r.Seek(int64(hdr.PageExtrasOffset), 0); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(r, bo, si.PageExtras); err != nil {
+			return nil, fmt.Errorf("failed to read slide info v%d page extras: %v", si.Version, err)
+		}
+	case 3, 5:
+		var hdr slideInfo3
+		if err := binary.Read(r, bo, &hdr); err != nil {
+			return nil, fmt.Errorf("failed to read slide info v%d: %v", si.Version, err)
+		}
+		si.PageSize = hdr.PageSize
+		si.ValueAdd = hdr.ValueAdd
+		si.PageStarts = make([]uint16, hdr.PageStartsCount)
+		if err := binary.Read(r, bo, si.PageStarts); err != nil {
+			return nil, fmt.Errorf("failed to read slide info v%d page starts: %v", si.Version, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported slide info version %d", si.Version)
+	}
+	if si.PageSize == 0 {
+		return nil, fmt.Errorf("slide info v%d has a zero page size", si.Version)
+	}
+	return si, nil
+}
+
+// SlidePointer returns the (unslid) target of the pointer ptr as stored in the cache
+func (si *SlideInfo) SlidePointer(ptr uint64) uint64 {
+	switch si.Version {
+	case 2:
+		if value := ptr &^ si.DeltaMask; value != 0 {
+			return value + si.ValueAdd
+		}
+		return 0
+	case 3:
+		p, _ := fixupchains.DecodePointer(fixupchains.DYLD_CHAINED_PTR_ARM64E, ptr)
+		if p.Auth != nil {
+			return p.Target + si.ValueAdd
+		}
+		// the plain rebases are vmaddrs with the high8 bits at 43-50 (instead of the top byte)
+		return ptr&0x0007F80000000000<<13 | ptr&0x000007FFFFFFFFFF
+	case 4:
+		value := uint32(ptr &^ si.DeltaMask)
+		switch {
+		case value&0xFFFF8000 == 0: // small positive non-pointer
+		case value&0x3FFF8000 == 0x3FFF8000: // small negative non-pointer
+			value |= 0xC0000000
+		default:
+			value += uint32(si.ValueAdd)
+		}
+		return uint64(value)
+	case 5:
+		p, _ := fixupchains.DecodePointer(fixupchains.DYLD_CHAINED_PTR_ARM64E_SHARED_CACHE, ptr)
+		return p.Target + si.ValueAdd
+	}
+	return ptr
+}
+
+// pointerSize returns the size of the pointers rebased by the slide info
+func (si *SlideInfo) pointerSize() int {
+	if si.Version == 4 {
+		return 4
+	}
+	return 8
+}
+
+// rebases walks the chains of the slide info of the mapping m in the pages overlapping [from, to)
+func (si *SlideInfo) rebases(c *Cache, m *Mapping, from, to uint64) ([]Rebase, error) {
+	var rebases []Rebase
+	ptrSize := si.pointerSize()
+	buf := make([]byte, ptrSize)
+	read := func(addr uint64) (uint64, error) {
+		if _, err := m.ReadAt(buf, int64(addr-m.Address)); err != nil {
+			return 0, fmt.Errorf("failed to read rebase at %#x: %v", addr, err)
+		}
+		if ptrSize == 4 {
+			return uint64(c.ByteOrder.Uint32(buf)), nil
+		}
+		return c.ByteOrder.Uint64(buf), nil
+	}
+
+	// walkChain walks the chain starting at offset (in bytes) in the page
+	walkChain := func(page uint64, offset uint64) error {
+		for {
+			addr := page + offset
+			raw, err := read(addr)
+			if err != nil {
+				return err
+			}
+			rebases = append(rebases, Rebase{Address: addr, Raw: raw, Target: si.SlidePointer(raw)})
+			var delta uint64
+			switch si.Version {
+			case 2, 4:
+				delta = (raw & si.DeltaMask) >> (uint(bits.TrailingZeros64(si.DeltaMask)) - 2)
+			case 3:
+				delta = (raw >> 51 & 0x7FF) * 8
+			case 5:
+				delta = (raw >> 52 & 0x7FF) * 8
+			}
+			if delta == 0 {
+				return nil
+			}
+			offset += delta
+			if offset >= uint64(si.PageSize) {
+				return fmt.Errorf("rebase chain at %#x runs off its page", addr)
+			}
+		}
+	}
+
+	for i, start := range si.PageStarts {
+		page := m.Address + uint64(i)*uint64(si.PageSize)
+		if page+uint64(si.PageSize) <= from || page >= to {
+			continue
+		}
+		switch si.Version {
+		case 2, 4:
+			if start == DYLD_CACHE_SLIDE_PAGE_ATTR_NO_REBASE {
+				continue
+			}
+			if start&DYLD_CACHE_SLIDE_PAGE_ATTR_EXTRA == 0 {
+				if err := walkChain(page, uint64(start&^DYLD_CACHE_SLIDE_PAGE_ATTRS)*4); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			for j := int(start &^ DYLD_CACHE_SLIDE_PAGE_ATTRS); ; j++ {
+				if j >= len(si.PageExtras) {
+					return nil, fmt.Errorf("page %d extras index %d is out of range", i, j)
+				}
+				extra := si.PageExtras[j]
+				if err := walkChain(page, uint64(extra&^DYLD_CACHE_SLIDE_PAGE_ATTRS)*4); err != nil {
+					return nil, err
+				}
+				if extra&DYLD_CACHE_SLIDE_PAGE_ATTR_END != 0 {
+					break
+				}
+			}
+		case 3, 5:
+			if start == DYLD_CACHE_SLIDE_V3_PAGE_ATTR_NO_REBASE {
+				continue
+			}
+			if err := walkChain(page, uint64(start)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rebases, nil
+}
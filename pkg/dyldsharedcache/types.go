@@ -0,0 +1,234 @@
+package dyldsharedcache
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// CacheHeader is the dyld_cache_header (older caches have shorter headers, the missing fields are zero)
+type CacheHeader struct {
+	Magic                     [16]byte   // e.g. "dyld_v1   arm64e"
+	MappingOffset             uint32     // file offset to first dyld_cache_mapping_info
+	MappingCount              uint32     // number of dyld_cache_mapping_info entries
+	ImagesOffsetOld           uint32     // UNUSED: moved to ImagesOffset
+	ImagesCountOld            uint32     // UNUSED: moved to ImagesCount
+	DyldBaseAddress           uint64     // base address of dyld when cache was built
+	CodeSignatureOffset       uint64     // file offset of code signature blob
+	CodeSignatureSize         uint64     // size of code signature blob (zero means to end of file)
+	SlideInfoOffsetUnused     uint64     // unused. Used to be file offset of kernel slid info
+	SlideInfoSizeUnused       uint64     // unused. Used to be size of kernel slid info
+	LocalSymbolsOffset        uint64     // file offset of where local symbols are stored
+	LocalSymbolsSize          uint64     // size of local symbols information
+	UUID                      types.UUID // unique value for each shared cache file
+	CacheType                 uint64     // 0 for development, 1 for production, 2 for multi-cache
+	BranchPoolsOffset         uint32     // file offset to table of uint64_t pool addresses
+	BranchPoolsCount          uint32     // number of uint64_t entries
+	DyldInCacheMH             uint64     // (unslid) address of mach_header of dyld in cache
+	DyldInCacheEntry          uint64     // (unslid) address of entry point (_dyld_start) of dyld in cache
+	ImagesTextOffset          uint64     // file offset to first dyld_cache_image_text_info
+	ImagesTextCount           uint64     // number of dyld_cache_image_text_info entries
+	PatchInfoAddr             uint64     // (unslid) address of dyld_cache_patch_info
+	PatchInfoSize             uint64     // size of all of the patch information pointed to via the dyld_cache_patch_info
+	OtherImageGroupAddrUnused uint64     // unused
+	OtherImageGroupSizeUnused uint64     // unused
+	ProgClosuresAddr          uint64     // (unslid) address of list of program launch closures
+	ProgClosuresSize          uint64     // size of list of program launch closures
+	ProgClosuresTrieAddr      uint64     // (unslid) address of trie of indexes into program launch closures
+	ProgClosuresTrieSize      uint64     // size of trie of indexes into program launch closures
+	Platform                  types.Platform
+	FormatVersion             uint32 // bitfield: formatVersion:8, dylibsExpectedOnDisk:1, simulator:1, locallyBuiltCache:1, builtFromChainedFixups:1
+	SharedRegionStart         uint64 // base load address of cache if not slid
+	SharedRegionSize          uint64 // overall size required to map the cache and all subCaches, if any
+	MaxSlide                  uint64 // runtime slide of cache can be between zero and this value
+	DylibsImageArrayAddr      uint64 // (unslid) address of ImageArray for dylibs in this cache
+	DylibsImageArraySize      uint64 // size of ImageArray for dylibs in this cache
+	DylibsTrieAddr            uint64 // (unslid) address of trie of indexes of all cached dylibs
+	DylibsTrieSize            uint64 // size of trie of cached dylib paths
+	OtherImageArrayAddr       uint64 // (unslid) address of ImageArray for dylibs and bundles with dlopen closures
+	OtherImageArraySize       uint64 // size of ImageArray for dylibs and bundles with dlopen closures
+	OtherTrieAddr             uint64 // (unslid) address of trie of indexes of all dylibs and bundles with dlopen closures
+	OtherTrieSize             uint64 // size of trie of dylibs and bundles with dlopen closures
+	MappingWithSlideOffset    uint32 // file offset to first dyld_cache_mapping_and_slide_info
+	MappingWithSlideCount     uint32 // number of dyld_cache_mapping_and_slide_info entries
+	DylibsPBLStateArrayAddr   uint64 // unused
+	DylibsPBLSetAddr          uint64 // (unslid) address of PrebuiltLoaderSet of all cached dylibs
+	ProgramsPBLSetPoolAddr    uint64 // (unslid) address of pool of PrebuiltLoaderSet for each program
+	ProgramsPBLSetPoolSize    uint64 // size of pool of PrebuiltLoaderSet for each program
+	ProgramTrieAddr           uint64 // (unslid) address of trie mapping program path to PrebuiltLoaderSet
+	ProgramTrieSize           uint32
+	OsVersion                 uint32     // OS Version of dylibs in this cache for the main platform
+	AltPlatform               uint32     // e.g. iOSMac on macOS
+	AltOsVersion              uint32     // e.g. 14.0 for iOSMac
+	SwiftOptsOffset           uint64     // VM offset from cache_header* to Swift optimizations header
+	SwiftOptsSize             uint64     // size of Swift optimizations header
+	SubCacheArrayOffset       uint32     // file offset to first dyld_subcache_entry
+	SubCacheArrayCount        uint32     // number of subCache entries
+	SymbolFileUUID            types.UUID // unique value for the shared cache file containing unmapped local symbols
+	RosettaReadOnlyAddr       uint64     // (unslid) address of the start of where Rosetta can add read-only/executable data
+	RosettaReadOnlySize       uint64     // maximum size of the Rosetta read-only/executable region
+	RosettaReadWriteAddr      uint64     // (unslid) address of the start of where Rosetta can add read-write data
+	RosettaReadWriteSize      uint64     // maximum size of the Rosetta read-write region
+	ImagesOffset              uint32     // file offset to first dyld_cache_image_info
+	ImagesCount               uint32     // number of dyld_cache_image_info entries
+	CacheSubType              uint32     // 0 for development, 1 for production, when cacheType is multi-cache(2)
+	Padding2                  uint32
+	ObjcOptsOffset            uint64 // VM offset from cache_header* to ObjC optimizations header
+	ObjcOptsSize              uint64 // size of ObjC optimizations header
+	CacheAtlasOffset          uint64 // VM offset from cache_header* to embedded cache atlas for process introspection
+	CacheAtlasSize            uint64 // size of embedded cache atlas
+	DynamicDataOffset         uint64 // VM offset from cache_header* to the location of dyld_cache_dynamic_data_header
+	DynamicDataMaxSize        uint64 // maximum size of space reserved from dynamic data
+}
+
+// field offsets used to check which fields a (shorter) header has
+const (
+	offsetMappingWithSlide = 0x138
+	offsetSubCacheArray    = 0x188
+	offsetSymbolFileUUID   = 0x190
+	offsetImages           = 0x1c0
+	offsetCacheSubType     = 0x1c8
+	cacheHeaderSize        = 0x200
+)
+
+// Arch returns the architecture of the cache (from its magic)
+func (h CacheHeader) Arch() string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimRight(string(h.Magic[:]), "\x00"), "dyld_v1"))
+}
+
+// is64 returns true if the cache is of a 64-bit architecture
+func (h CacheHeader) is64() bool {
+	arch := h.Arch()
+	return arch != "arm64_32" && (strings.HasPrefix(arch, "arm64") || strings.HasPrefix(arch, "x86_64"))
+}
+
+// hasSymbolFile returns true if the cache's local symbols are in a separate .symbols file
+func (h CacheHeader) hasSymbolFile() bool {
+	return h.MappingOffset > offsetSymbolFileUUID && h.SymbolFileUUID != types.UUID{}
+}
+
+func (h CacheHeader) String() string {
+	return fmt.Sprintf("magic: %s, uuid: %s, platform: %s, mappings: %d, images: %d, subcaches: %d",
+		strings.TrimRight(string(h.Magic[:]), "\x00"),
+		h.UUID,
+		h.Platform,
+		h.MappingCount,
+		h.ImagesCount,
+		h.SubCacheArrayCount,
+	)
+}
+
+// MappingInfo is the dyld_cache_mapping_info
+type MappingInfo struct {
+	Address    uint64
+	Size       uint64
+	FileOffset uint64
+	MaxProt    types.VmProtection
+	InitProt   types.VmProtection
+}
+
+// MappingAndSlideInfo is the dyld_cache_mapping_and_slide_info
+type MappingAndSlideInfo struct {
+	Address             uint64
+	Size                uint64
+	FileOffset          uint64
+	SlideInfoFileOffset uint64
+	SlideInfoFileSize   uint64
+	Flags               MappingFlag
+	MaxProt             types.VmProtection
+	InitProt            types.VmProtection
+}
+
+// MappingFlag are the dyld_cache_mapping_and_slide_info flags
+type MappingFlag uint64
+
+const (
+	DYLD_CACHE_MAPPING_AUTH_DATA  MappingFlag = 1 << 0
+	DYLD_CACHE_MAPPING_DIRTY_DATA MappingFlag = 1 << 1
+	DYLD_CACHE_MAPPING_CONST_DATA MappingFlag = 1 << 2
+	DYLD_CACHE_MAPPING_TEXT_STUBS MappingFlag = 1 << 3
+	DYLD_CACHE_DYNAMIC_CONFIG     MappingFlag = 1 << 4
+)
+
+// ImageInfo is the dyld_cache_image_info
+type ImageInfo struct {
+	Address        uint64
+	ModTime        uint64
+	Inode          uint64
+	PathFileOffset uint32
+	Pad            uint32
+}
+
+// SubCacheEntryV1 is the dyld_subcache_entry_v1 (the sub-cache files are named <cache>.1, <cache>.2, ...)
+type SubCacheEntryV1 struct {
+	UUID          types.UUID
+	CacheVMOffset uint64
+}
+
+// SubCacheEntry is the dyld_subcache_entry
+type SubCacheEntry struct {
+	UUID          types.UUID
+	CacheVMOffset uint64
+	FileSuffix    [32]byte
+}
+
+// Suffix returns the file name suffix of the sub-cache (i.e. .01)
+func (e SubCacheEntry) Suffix() string {
+	return strings.TrimRight(string(e.FileSuffix[:]), "\x00")
+}
+
+// LocalSymbolsInfo is the dyld_cache_local_symbols_info (its offsets are relative to it)
+type LocalSymbolsInfo struct {
+	NlistOffset   uint32 // offset into this chunk of nlist entries
+	NlistCount    uint32 // count of nlist entries
+	StringsOffset uint32 // offset into this chunk of string pool
+	StringsSize   uint32 // byte count of string pool
+	EntriesOffset uint32 // offset into this chunk of array of dyld_cache_local_symbols_entry
+	EntriesCount  uint32 // number of elements in dyld_cache_local_symbols_entry array
+}
+
+// LocalSymbolsEntry is the dyld_cache_local_symbols_entry (used by caches without a symbol file UUID)
+type LocalSymbolsEntry struct {
+	DylibOffset     uint32 // offset in cache file of start of dylib
+	NlistStartIndex uint32 // start index of locals for this dylib
+	NlistCount      uint32 // number of local symbols for this dylib
+}
+
+// LocalSymbolsEntry64 is the dyld_cache_local_symbols_entry_64
+type LocalSymbolsEntry64 struct {
+	DylibOffset     uint64 // offset in cache buffer of start of dylib
+	NlistStartIndex uint32 // start index of locals for this dylib
+	NlistCount      uint32 // number of local symbols for this dylib
+}
+
+// SlideInfo versions 2 and 4 (dyld_cache_slide_info2 and dyld_cache_slide_info4)
+type slideInfo2 struct {
+	Version          uint32
+	PageSize         uint32
+	PageStartsOffset uint32
+	PageStartsCount  uint32
+	PageExtrasOffset uint32
+	PageExtrasCount  uint32
+	DeltaMask        uint64 // which (contiguous) set of bits contains the delta to the next rebase location
+	ValueAdd         uint64
+}
+
+// SlideInfo versions 3 and 5 (dyld_cache_slide_info3 and dyld_cache_slide_info5) followed by the page starts
+type slideInfo3 struct {
+	Version         uint32
+	PageSize        uint32
+	PageStartsCount uint32
+	Pad             uint32
+	ValueAdd        uint64 // auth_value_add for version 3
+}
+
+const (
+	DYLD_CACHE_SLIDE_PAGE_ATTRS          = 0xC000 // high bits of uint16_t are flags
+	DYLD_CACHE_SLIDE_PAGE_ATTR_EXTRA     = 0x8000 // index is into extras array (not starts array)
+	DYLD_CACHE_SLIDE_PAGE_ATTR_NO_REBASE = 0x4000 // page has no rebasing
+	DYLD_CACHE_SLIDE_PAGE_ATTR_END       = 0x8000 // last chain entry for page
+
+	DYLD_CACHE_SLIDE_V3_PAGE_ATTR_NO_REBASE = 0xFFFF // page has no rebasing
+	DYLD_CACHE_SLIDE_V5_PAGE_ATTR_NO_REBASE = 0xFFFF // page has no rebasing
+)
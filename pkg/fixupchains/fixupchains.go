@@ -152,26 +152,22 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 			if err := binary.Read(dcf.sr, dcf.bo, &dcPtr); err != nil {
 				return err
 			}
-			dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtr32CacheRebase{
-				Pointer: dcPtr,
-				Fixup:   fixupLocation,
-			})
-			if Generic32Next(dcPtr) == 0 {
+			rebase := DyldChainedPtr32CacheRebase{Pointer: dcPtr, Fixup: fixupLocation}
+			dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, rebase)
+			if rebase.Next() == 0 {
 				chainEnd = true
 			}
-			next += Generic32Next(dcPtr) * stride(pointerFormat)
+			next += uint64(rebase.Next()) * stride(pointerFormat)
 		case DYLD_CHAINED_PTR_32_FIRMWARE:
 			if err := binary.Read(dcf.sr, dcf.bo, &dcPtr); err != nil {
 				return err
 			}
-			dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtr32FirmwareRebase{
-				Pointer: dcPtr,
-				Fixup:   fixupLocation,
-			})
-			if Generic32Next(dcPtr) == 0 {
+			rebase := DyldChainedPtr32FirmwareRebase{Pointer: dcPtr, Fixup: fixupLocation}
+			dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, rebase)
+			if rebase.Next() == 0 {
 				chainEnd = true
 			}
-			next += Generic32Next(dcPtr) * stride(pointerFormat)
+			next += uint64(rebase.Next()) * stride(pointerFormat)
 		case DYLD_CHAINED_PTR_64: // target is vmaddr
 			if err := binary.Read(dcf.sr, dcf.bo, &dcPtr64); err != nil {
 				return err
@@ -320,8 +316,9 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 			if err := binary.Read(dcf.sr, dcf.bo, &dcPtr64); err != nil {
 				return err
 			}
+			// NOTE: the rebases use the regular arm64e layout, only the binds have 24-bit ordinals
 			if !DcpArm64eIsBind(dcPtr64) && !DcpArm64eIsAuth(dcPtr64) {
-				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtrArm64eRebase24{
+				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtrArm64eRebase{
 					Pointer: dcPtr64,
 					Fixup:   fixupLocation,
 				})
@@ -330,7 +327,7 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 				bind.Import = dcf.Imports[bind.Ordinal()].Name
 				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, bind)
 			} else if !DcpArm64eIsBind(dcPtr64) && DcpArm64eIsAuth(dcPtr64) {
-				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtrArm64eAuthRebase24{
+				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtrArm64eAuthRebase{
 					Pointer: dcPtr64,
 					Fixup:   fixupLocation,
 				})
@@ -343,6 +340,26 @@ func (dcf *DyldChainedFixups) walkDcFixupChain(segIdx int, pageIndex uint16, off
 				chainEnd = true
 			}
 			next += DcpArm64eNext(dcPtr64) * stride(pointerFormat)
+		case DYLD_CHAINED_PTR_ARM64E_SHARED_CACHE: // stride 8, regular/auth targets both vm offsets
+			if err := binary.Read(dcf.sr, dcf.bo, &dcPtr64); err != nil {
+				return err
+			}
+			if DcpArm64eIsAuth(dcPtr64) {
+				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtrArm64eSharedCacheAuthRebase{
+					Pointer: dcPtr64,
+					Fixup:   fixupLocation,
+				})
+			} else {
+				dcf.Starts[segIdx].Fixups = append(dcf.Starts[segIdx].Fixups, DyldChainedPtrArm64eSharedCacheRebase{
+					Pointer: dcPtr64,
+					Fixup:   fixupLocation,
+				})
+			}
+			n := types.ExtractBits(dcPtr64, 52, 11)
+			if n == 0 {
+				chainEnd = true
+			}
+			next += n * stride(pointerFormat)
 		default:
 			return fmt.Errorf("unknown pointer format %#04X", dcf.Starts[segIdx].DyldChainedStartsInSegment.PointerFormat)
 		}
@@ -400,111 +417,135 @@ func (dcf *DyldChainedFixups) parseImports() error {
 	return nil
 }
 
-func (dcf *DyldChainedFixups) IsRebase(addr, preferredLoadAddress uint64) (uint64, bool) {
-	var targetRuntimeOffset uint64
-	switch dcf.PointerFormat {
-	case DYLD_CHAINED_PTR_ARM64E:
-		fallthrough
-	case DYLD_CHAINED_PTR_ARM64E_USERLAND:
-		fallthrough
-	case DYLD_CHAINED_PTR_ARM64E_USERLAND24:
-		fallthrough
-	case DYLD_CHAINED_PTR_ARM64E_KERNEL:
-		fallthrough
-	case DYLD_CHAINED_PTR_ARM64E_FIRMWARE:
-		if DcpArm64eIsBind(addr) {
-			return 0, false
+// Pointer is a decoded chained fixup pointer
+type Pointer struct {
+	Format  DCPtrKind
+	IsBind  bool
+	Target  uint64 // rebase target (with the high8 bits); a vmaddr if VMAddr is set else a runtime offset
+	VMAddr  bool   // the rebase target is a vmaddr (not an offset from the image/cache base)
+	Ordinal uint64 // bind import ordinal
+	Addend  int64  // bind addend
+	Next    uint64 // byte delta to the next fixup in the chain (0 ends the chain)
+	Auth    *PtrAuth
+	// CacheLevel is the kernel cache level (mach_header index) the target is relative to
+	CacheLevel uint64
+}
+
+// RuntimeOffset returns the rebase target as an offset from preferredLoadAddress
+func (p Pointer) RuntimeOffset(preferredLoadAddress uint64) uint64 {
+	if p.VMAddr {
+		return p.Target - preferredLoadAddress
+	}
+	return p.Target
+}
+
+func (p Pointer) String() string {
+	var auth string
+	if p.Auth != nil {
+		auth = ", " + p.Auth.String()
+	}
+	if p.IsBind {
+		return fmt.Sprintf("bind (ordinal: %d, addend: %d%s)", p.Ordinal, p.Addend, auth)
+	}
+	return fmt.Sprintf("rebase (target: %#x, vmaddr: %t%s)", p.Target, p.VMAddr, auth)
+}
+
+// DecodePointer decodes the raw chained fixup pointer ptr of the given pointer format
+func DecodePointer(format DCPtrKind, ptr uint64) (Pointer, error) {
+	p := Pointer{Format: format}
+	switch format {
+	case DYLD_CHAINED_PTR_ARM64E, DYLD_CHAINED_PTR_ARM64E_KERNEL, DYLD_CHAINED_PTR_ARM64E_USERLAND,
+		DYLD_CHAINED_PTR_ARM64E_FIRMWARE, DYLD_CHAINED_PTR_ARM64E_USERLAND24:
+		p.Next = DcpArm64eNext(ptr) * stride(format)
+		p.IsBind = DcpArm64eIsBind(ptr)
+		if auth, ok := DcpArm64ePtrAuth(ptr); ok {
+			p.Auth = &auth
 		}
-		if DcpArm64eIsAuth(addr) {
-			return DyldChainedPtrArm64eAuthRebase{Pointer: addr}.Target(), true
+		switch {
+		case p.IsBind && format == DYLD_CHAINED_PTR_ARM64E_USERLAND24:
+			p.Ordinal = DyldChainedPtrArm64eBind24{Pointer: ptr}.Ordinal()
+			if p.Auth == nil {
+				p.Addend = DyldChainedPtrArm64eBind24{Pointer: ptr}.SignExtendedAddend()
+			}
+		case p.IsBind:
+			p.Ordinal = DyldChainedPtrArm64eBind{Pointer: ptr}.Ordinal()
+			if p.Auth == nil {
+				p.Addend = DyldChainedPtrArm64eBind{Pointer: ptr}.SignExtendedAddend()
+			}
+		case p.Auth != nil: // auth rebase targets are always vm offsets
+			p.Target = DyldChainedPtrArm64eAuthRebase{Pointer: ptr}.Target()
+		default:
+			p.Target = DyldChainedPtrArm64eRebase{Pointer: ptr}.UnpackTarget()
+			p.VMAddr = format == DYLD_CHAINED_PTR_ARM64E || format == DYLD_CHAINED_PTR_ARM64E_FIRMWARE
 		}
-		if DcpArm64eIsRebase(addr) {
-			targetRuntimeOffset = DyldChainedPtrArm64eRebase{Pointer: addr}.UnpackTarget()
-			if (dcf.PointerFormat == DYLD_CHAINED_PTR_ARM64E) || (dcf.PointerFormat == DYLD_CHAINED_PTR_ARM64E_USERLAND24) || (dcf.PointerFormat == DYLD_CHAINED_PTR_ARM64E_FIRMWARE) {
-				targetRuntimeOffset -= preferredLoadAddress
-			}
-			return targetRuntimeOffset, true
+	case DYLD_CHAINED_PTR_ARM64E_SHARED_CACHE:
+		p.Next = types.ExtractBits(ptr, 52, 11) * stride(format)
+		if DcpArm64eIsAuth(ptr) {
+			rebase := DyldChainedPtrArm64eSharedCacheAuthRebase{Pointer: ptr}
+			p.Target = rebase.Target()
+			p.Auth = &PtrAuth{Diversity: uint16(rebase.Diversity()), AddrDiv: rebase.AddrDiv() != 0}
+			if rebase.IsDataKey() {
+				p.Auth.Key = 2 // only the A keys are supported
+			}
+		} else {
+			rebase := DyldChainedPtrArm64eSharedCacheRebase{Pointer: ptr}
+			p.Target = rebase.High8()<<56 | rebase.Target()
 		}
-		return 0, false
 	case DYLD_CHAINED_PTR_64, DYLD_CHAINED_PTR_64_OFFSET:
-		if Generic64IsBind(addr) {
-			return targetRuntimeOffset, false
+		p.Next = Generic64Next(ptr) * stride(format)
+		if Generic64IsBind(ptr) {
+			p.IsBind = true
+			p.Ordinal = DyldChainedPtr64Bind{Pointer: ptr}.Ordinal()
+			p.Addend = int64(DyldChainedPtr64Bind{Pointer: ptr}.Addend())
+		} else {
+			p.Target = DyldChainedPtr64Rebase{Pointer: ptr}.UnpackedTarget()
+			p.VMAddr = format == DYLD_CHAINED_PTR_64
 		}
-		targetRuntimeOffset = DyldChainedPtr64Rebase{Pointer: addr}.UnpackedTarget()
-		if dcf.PointerFormat == DYLD_CHAINED_PTR_64 || dcf.PointerFormat == DYLD_CHAINED_PTR_64_OFFSET {
-			targetRuntimeOffset -= preferredLoadAddress
-		}
-		return targetRuntimeOffset, true
 	case DYLD_CHAINED_PTR_64_KERNEL_CACHE, DYLD_CHAINED_PTR_X86_64_KERNEL_CACHE:
-		targetRuntimeOffset = DyldChainedPtr64KernelCacheRebase{Pointer: addr}.Target()
-		return targetRuntimeOffset, true
+		rebase := DyldChainedPtr64KernelCacheRebase{Pointer: ptr}
+		p.Next = rebase.Next() * stride(format)
+		p.Target = rebase.Target()
+		p.CacheLevel = rebase.CacheLevel()
+		if rebase.IsAuth() == 1 {
+			p.Auth = &PtrAuth{Key: rebase.Key(), Diversity: uint16(rebase.Diversity()), AddrDiv: rebase.AddrDiv() != 0}
+		}
 	case DYLD_CHAINED_PTR_32:
-		if Generic32IsBind(uint32(addr)) {
-			return targetRuntimeOffset, false
+		p.Next = Generic32Next(uint32(ptr)) * stride(format)
+		if Generic32IsBind(uint32(ptr)) {
+			p.IsBind = true
+			p.Ordinal = DyldChainedPtr32Bind{Pointer: uint32(ptr)}.Ordinal()
+			p.Addend = int64(DyldChainedPtr32Bind{Pointer: uint32(ptr)}.Addend())
+		} else {
+			p.Target = DyldChainedPtr32Rebase{Pointer: uint32(ptr)}.Target()
+			p.VMAddr = true
 		}
-		targetRuntimeOffset = uint64(DyldChainedPtr32Rebase{Pointer: uint32(addr)}.Target()) - preferredLoadAddress
-		return targetRuntimeOffset, true
+	case DYLD_CHAINED_PTR_32_CACHE:
+		p.Next = uint64(DyldChainedPtr32CacheRebase{Pointer: uint32(ptr)}.Next()) * stride(format)
+		p.Target = DyldChainedPtr32CacheRebase{Pointer: uint32(ptr)}.Target()
 	case DYLD_CHAINED_PTR_32_FIRMWARE:
-		targetRuntimeOffset = uint64(DyldChainedPtr32FirmwareRebase{Pointer: uint32(addr)}.Target()) - preferredLoadAddress
-		return targetRuntimeOffset, true
+		p.Next = uint64(DyldChainedPtr32FirmwareRebase{Pointer: uint32(ptr)}.Next()) * stride(format)
+		p.Target = DyldChainedPtr32FirmwareRebase{Pointer: uint32(ptr)}.Target()
+		p.VMAddr = true
 	default:
-		return 0, false
+		return Pointer{}, fmt.Errorf("unknown pointer format %#04X", format)
 	}
+	return p, nil
 }
 
-func (dcf *DyldChainedFixups) IsBind(addr uint64) (*DcfImport, int64, bool) {
-	if len(dcf.Imports) == 0 {
-		return nil, 0, false
+// IsRebase returns the runtime offset of the target of the chained rebase addr
+func (dcf *DyldChainedFixups) IsRebase(addr, preferredLoadAddress uint64) (uint64, bool) {
+	p, err := DecodePointer(dcf.PointerFormat, addr)
+	if err != nil || p.IsBind {
+		return 0, false
 	}
+	return p.RuntimeOffset(preferredLoadAddress), true
+}
 
-	switch dcf.PointerFormat {
-	case DYLD_CHAINED_PTR_ARM64E:
-		fallthrough
-	case DYLD_CHAINED_PTR_ARM64E_USERLAND:
-		fallthrough
-	case DYLD_CHAINED_PTR_ARM64E_USERLAND24:
-		fallthrough
-	case DYLD_CHAINED_PTR_ARM64E_KERNEL:
-		fallthrough
-	case DYLD_CHAINED_PTR_ARM64E_FIRMWARE:
-		if !DcpArm64eIsBind(addr) {
-			return nil, 0, false
-		}
-		if DcpArm64eIsAuth(addr) { // is auth-bind
-			if dcf.PointerFormat == DYLD_CHAINED_PTR_ARM64E_USERLAND24 {
-				return &dcf.Imports[DyldChainedPtrArm64eAuthBind24{Pointer: addr}.Ordinal()], 0, true
-			}
-			return &dcf.Imports[DyldChainedPtrArm64eAuthBind{Pointer: addr}.Ordinal()], 0, true
-		}
-		if dcf.PointerFormat == DYLD_CHAINED_PTR_ARM64E_USERLAND24 {
-			return &dcf.Imports[DyldChainedPtrArm64eAuthBind24{Pointer: addr}.Ordinal()], DyldChainedPtrArm64eBind{Pointer: addr}.SignExtendedAddend(), true
-		}
-		ord := DyldChainedPtrArm64eAuthBind{Pointer: addr}.Ordinal()
-		if ord > uint64(len(dcf.Imports)-1) {
-			return nil, 0, false
-		}
-		return &dcf.Imports[ord], DyldChainedPtrArm64eBind{Pointer: addr}.SignExtendedAddend(), true
-	case DYLD_CHAINED_PTR_64, DYLD_CHAINED_PTR_64_OFFSET:
-		if !Generic64IsBind(addr) {
-			return nil, 0, false
-		}
-		ord := DyldChainedPtr64Bind{Pointer: addr}.Ordinal()
-		if ord > uint64(len(dcf.Imports)-1) {
-			return nil, 0, false
-		}
-		return &dcf.Imports[ord], int64(DyldChainedPtr64Bind{Pointer: addr}.Addend()), true
-	case DYLD_CHAINED_PTR_32:
-		if !Generic32IsBind(uint32(addr)) {
-			return nil, 0, false
-		}
-		ord := DyldChainedPtr32Bind{Pointer: uint32(addr)}.Ordinal()
-		if ord > uint64(len(dcf.Imports)-1) {
-			return nil, 0, false
-		}
-		return &dcf.Imports[ord], int64(DyldChainedPtr32Bind{Pointer: uint32(addr)}.Addend()), true
-	case DYLD_CHAINED_PTR_64_KERNEL_CACHE, DYLD_CHAINED_PTR_X86_64_KERNEL_CACHE:
-		return nil, 0, false
-	default:
+// IsBind returns the import and addend of the chained bind addr
+func (dcf *DyldChainedFixups) IsBind(addr uint64) (*DcfImport, int64, bool) {
+	p, err := DecodePointer(dcf.PointerFormat, addr)
+	if err != nil || !p.IsBind || p.Ordinal >= uint64(len(dcf.Imports)) {
 		return nil, 0, false
 	}
+	return &dcf.Imports[p.Ordinal], p.Addend, true
 }
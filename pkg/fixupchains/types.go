@@ -123,6 +123,8 @@ func stride(pointerFormat DCPtrKind) uint64 {
 	case DYLD_CHAINED_PTR_ARM64E_USERLAND:
 		fallthrough
 	case DYLD_CHAINED_PTR_ARM64E_USERLAND24:
+		fallthrough
+	case DYLD_CHAINED_PTR_ARM64E_SHARED_CACHE:
 		return uint64(8)
 	case DYLD_CHAINED_PTR_ARM64E_KERNEL:
 		fallthrough
@@ -208,6 +210,35 @@ func KeyName(keyVal uint64) string {
 	return name[keyVal]
 }
 
+// PtrAuth is the pointer authentication info of an authenticated arm64e chained pointer
+type PtrAuth struct {
+	Key       uint64 // 0-3 (IA, IB, DA or DB)
+	Diversity uint16 // extra discriminator
+	AddrDiv   bool   // the discriminator is blended with the address of the pointer
+}
+
+// KeyName returns the key name (i.e. IA)
+func (p PtrAuth) KeyName() string {
+	return KeyName(p.Key)
+}
+
+func (p PtrAuth) String() string {
+	return fmt.Sprintf("key: %s, addrDiv: %t, diversity: %#04x", p.KeyName(), p.AddrDiv, p.Diversity)
+}
+
+// DcpArm64ePtrAuth returns the pointer authentication info of an arm64e chained pointer (rebase or bind),
+// ok is false if the pointer isn't authenticated
+func DcpArm64ePtrAuth(ptr uint64) (auth PtrAuth, ok bool) {
+	if !DcpArm64eIsAuth(ptr) {
+		return PtrAuth{}, false
+	}
+	return PtrAuth{
+		Key:       types.ExtractBits(ptr, 49, 2),
+		Diversity: uint16(types.ExtractBits(ptr, 32, 16)),
+		AddrDiv:   types.ExtractBits(ptr, 48, 1) != 0,
+	}, true
+}
+
 // DYLD_CHAINED_PTR_ARM64E
 type DyldChainedPtrArm64eRebase struct {
 	Fixup   uint64
@@ -0,0 +1,96 @@
+package linkedit
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Blob is a chunk of __LINKEDIT data referenced by a load command
+type Blob struct {
+	Name   string
+	Offset *uint32 // file offset field of the referring load command (patched by Layout.Build)
+	Data   []byte
+	Align  uint32 // alignment of the blob's file offset (0 uses the layout's alignment)
+}
+
+// Layout lays out __LINKEDIT blobs contiguously (in their original order) and patches the
+// file offsets in the load commands that refer to them
+type Layout struct {
+	Start uint64 // file offset of the __LINKEDIT segment
+	Align uint32 // default blob alignment (the pointer size)
+
+	blobs []*Blob
+}
+
+// NewLayout returns a new Layout for a __LINKEDIT segment at file offset start
+func NewLayout(start uint64, align uint32) *Layout {
+	return &Layout{Start: start, Align: align}
+}
+
+// Add adds a blob to the layout
+func (l *Layout) Add(b *Blob) {
+	l.blobs = append(l.blobs, b)
+}
+
+// Blobs returns the blobs in the layout
+func (l *Layout) Blobs() []*Blob {
+	return l.blobs
+}
+
+func align(off, a uint64) uint64 {
+	if a > 1 && off%a != 0 {
+		off += a - off%a
+	}
+	return off
+}
+
+// Build lays out the blobs, patches their load command offsets and returns the new __LINKEDIT data
+// NOTE: blobs that shared the same data (same offset and size) still share it after the layout
+func (l *Layout) Build() ([]byte, error) {
+	blobs := make([]*Blob, len(l.blobs))
+	copy(blobs, l.blobs)
+	for _, b := range blobs {
+		if b.Offset == nil {
+			return nil, fmt.Errorf("linkedit blob %s has no offset", b.Name)
+		}
+	}
+	// keep the original order (i.e. the code signature stays last)
+	sort.SliceStable(blobs, func(i, j int) bool {
+		return *blobs[i].Offset < *blobs[j].Offset
+	})
+
+	type blobKey struct {
+		offset uint32
+		size   int
+	}
+	placed := make(map[blobKey]uint32)
+	newOffsets := make([]uint32, len(blobs))
+
+	var out []byte
+	for i, b := range blobs {
+		key := blobKey{*b.Offset, len(b.Data)}
+		if off, ok := placed[key]; ok {
+			newOffsets[i] = off
+			continue
+		}
+		a := b.Align
+		if a == 0 {
+			a = l.Align
+		}
+		pos := align(l.Start+uint64(len(out)), uint64(a)) - l.Start
+		if end := l.Start + pos + uint64(len(b.Data)); end > 1<<32-1 {
+			return nil, fmt.Errorf("linkedit blob %s at %#x is beyond the 32-bit file offset limit", b.Name, l.Start+pos)
+		}
+		out = append(out, make([]byte, pos-uint64(len(out)))...)
+		out = append(out, b.Data...)
+		newOffsets[i] = uint32(l.Start + pos)
+		placed[key] = newOffsets[i]
+	}
+	out = append(out, make([]byte, align(uint64(len(out)), uint64(l.Align))-uint64(len(out)))...)
+
+	for i, b := range blobs {
+		*b.Offset = newOffsets[i]
+	}
+
+	return out, nil
+}
@@ -0,0 +1,329 @@
+package unwind
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+const (
+	UNWIND_SECTION_VERSION          = 1
+	UNWIND_SECOND_LEVEL_REGULAR     = 2
+	UNWIND_SECOND_LEVEL_COMPRESSED  = 3
+	UNWIND_INFO_COMPRESSED_OFFSET   = 0x00FFFFFF // mask of a compressed entry's function offset
+	UNWIND_INFO_COMPRESSED_ENCODING = 0xFF000000 // mask of a compressed entry's encoding index
+)
+
+// Encoding is a compact unwind encoding
+type Encoding uint32
+
+const (
+	UNWIND_IS_NOT_FUNCTION_START Encoding = 0x80000000
+	UNWIND_HAS_LSDA              Encoding = 0x40000000
+	UNWIND_PERSONALITY_MASK      Encoding = 0x30000000
+	UNWIND_MODE_MASK             Encoding = 0x0F000000 // (architecture specific)
+
+	UNWIND_X86_MODE_EBP_FRAME    Encoding = 0x01000000
+	UNWIND_X86_MODE_STACK_IMMD   Encoding = 0x02000000
+	UNWIND_X86_MODE_STACK_IND    Encoding = 0x03000000
+	UNWIND_X86_MODE_DWARF        Encoding = 0x04000000
+	UNWIND_X86_64_MODE_RBP_FRAME Encoding = 0x01000000
+	UNWIND_X86_64_MODE_DWARF     Encoding = 0x04000000
+	UNWIND_ARM64_MODE_FRAMELESS  Encoding = 0x02000000
+	UNWIND_ARM64_MODE_DWARF      Encoding = 0x03000000
+	UNWIND_ARM64_MODE_FRAME      Encoding = 0x04000000
+	UNWIND_ARM_MODE_FRAME        Encoding = 0x01000000
+	UNWIND_ARM_MODE_FRAME_D      Encoding = 0x02000000
+	UNWIND_ARM_MODE_DWARF        Encoding = 0x04000000
+
+	UNWIND_DWARF_SECTION_OFFSET Encoding = 0x00FFFFFF // offset of the FDE in __eh_frame (for the DWARF modes)
+)
+
+func (e Encoding) IsNotFunctionStart() bool { return e&UNWIND_IS_NOT_FUNCTION_START != 0 }
+func (e Encoding) HasLSDA() bool            { return e&UNWIND_HAS_LSDA != 0 }
+
+// PersonalityIndex returns the 1-based index into the personality array (0 is no personality)
+func (e Encoding) PersonalityIndex() int { return int((e & UNWIND_PERSONALITY_MASK) >> 28) }
+
+// Mode returns the architecture specific unwind mode
+func (e Encoding) Mode() Encoding { return e & UNWIND_MODE_MASK }
+
+// IsDWARF returns true if the function's unwind info is the __eh_frame FDE at DWARFOffset
+func (e Encoding) IsDWARF(cpu types.CPU) bool {
+	switch cpu {
+	case types.CPUArm64, types.CPUArm6432:
+		return e.Mode() == UNWIND_ARM64_MODE_DWARF
+	case types.CPUAmd64:
+		return e.Mode() == UNWIND_X86_64_MODE_DWARF
+	case types.CPUI386:
+		return e.Mode() == UNWIND_X86_MODE_DWARF
+	case types.CPUArm:
+		return e.Mode() == UNWIND_ARM_MODE_DWARF
+	}
+	return false
+}
+
+// DWARFOffset returns the offset of the function's FDE in the __eh_frame section (for the DWARF modes)
+func (e Encoding) DWARFOffset() uint32 { return uint32(e & UNWIND_DWARF_SECTION_OFFSET) }
+
+// ModeString returns the name of the unwind mode for the given CPU
+func (e Encoding) ModeString(cpu types.CPU) string {
+	if e == 0 {
+		return "none"
+	}
+	switch cpu {
+	case types.CPUArm64, types.CPUArm6432:
+		switch e.Mode() {
+		case UNWIND_ARM64_MODE_FRAMELESS:
+			return "frameless"
+		case UNWIND_ARM64_MODE_DWARF:
+			return "dwarf"
+		case UNWIND_ARM64_MODE_FRAME:
+			return "frame"
+		}
+	case types.CPUAmd64, types.CPUI386:
+		switch e.Mode() {
+		case UNWIND_X86_64_MODE_RBP_FRAME:
+			return "frame"
+		case UNWIND_X86_MODE_STACK_IMMD:
+			return "frameless (immediate stack size)"
+		case UNWIND_X86_MODE_STACK_IND:
+			return "frameless (indirect stack size)"
+		case UNWIND_X86_64_MODE_DWARF:
+			return "dwarf"
+		}
+	case types.CPUArm:
+		switch e.Mode() {
+		case UNWIND_ARM_MODE_FRAME:
+			return "frame"
+		case UNWIND_ARM_MODE_FRAME_D:
+			return "frame (with d-registers)"
+		case UNWIND_ARM_MODE_DWARF:
+			return "dwarf"
+		}
+	}
+	return fmt.Sprintf("mode %#x", uint32(e.Mode()>>24))
+}
+
+func (e Encoding) String() string {
+	var flags []string
+	if e.IsNotFunctionStart() {
+		flags = append(flags, "not-function-start")
+	}
+	if e.HasLSDA() {
+		flags = append(flags, "lsda")
+	}
+	if e.PersonalityIndex() > 0 {
+		flags = append(flags, fmt.Sprintf("personality=%d", e.PersonalityIndex()))
+	}
+	if len(flags) == 0 {
+		return fmt.Sprintf("%#08x", uint32(e))
+	}
+	return fmt.Sprintf("%#08x (%s)", uint32(e), strings.Join(flags, ", "))
+}
+
+// CompactUnwindHeader is the header of the __TEXT,__unwind_info section
+type CompactUnwindHeader struct {
+	Version                           uint32
+	CommonEncodingsArraySectionOffset uint32
+	CommonEncodingsArrayCount         uint32
+	PersonalityArraySectionOffset     uint32
+	PersonalityArrayCount             uint32
+	IndexSectionOffset                uint32
+	IndexCount                        uint32
+}
+
+// CompactUnwindIndexEntry is a first level index entry (the last one is a sentinel holding the end of the last function)
+type CompactUnwindIndexEntry struct {
+	FunctionOffset                uint32
+	SecondLevelPagesSectionOffset uint32
+	LSDAIndexArraySectionOffset   uint32
+}
+
+type lsdaIndexEntry struct {
+	FunctionOffset uint32
+	LSDAOffset     uint32
+}
+
+type regularPageHeader struct {
+	Kind            uint32
+	EntryPageOffset uint16
+	EntryCount      uint16
+}
+
+type compressedPageHeader struct {
+	Kind                uint32
+	EntryPageOffset     uint16
+	EntryCount          uint16
+	EncodingsPageOffset uint16
+	EncodingsCount      uint16
+}
+
+// Entry is a function's compact unwind info
+type Entry struct {
+	Start       uint64 // function start address
+	End         uint64 // function end address (the start of the next entry)
+	Encoding    Encoding
+	Personality uint64 // address of the personality function pointer (0 if none)
+	LSDA        uint64 // address of the language specific data area (0 if none)
+}
+
+func (e Entry) String() string {
+	s := fmt.Sprintf("%#016x-%#016x encoding=%s", e.Start, e.End, e.Encoding)
+	if e.Personality != 0 {
+		s += fmt.Sprintf(" personality=%#x", e.Personality)
+	}
+	if e.LSDA != 0 {
+		s += fmt.Sprintf(" lsda=%#x", e.LSDA)
+	}
+	return s
+}
+
+// CompactUnwindInfo is a parsed __TEXT,__unwind_info section
+type CompactUnwindInfo struct {
+	CompactUnwindHeader
+	CommonEncodings []Encoding
+	Personalities   []uint32 // image offsets of the personality function pointers
+	Index           []CompactUnwindIndexEntry
+	Entries         []Entry
+}
+
+// ParseCompactUnwind parses the contents of the __TEXT,__unwind_info section of an image loaded at base
+// (all the offsets in compact unwind info are relative to the image's mach header)
+func ParseCompactUnwind(dat []byte, bo binary.ByteOrder, base uint64) (*CompactUnwindInfo, error) {
+	var info CompactUnwindInfo
+
+	read := func(off uint32, data any) error {
+		if uint64(off) > uint64(len(dat)) {
+			return fmt.Errorf("offset %#x is past the end of the unwind info", off)
+		}
+		return binary.Read(bytes.NewReader(dat[off:]), bo, data)
+	}
+
+	if err := read(0, &info.CompactUnwindHeader); err != nil {
+		return nil, fmt.Errorf("failed to read compact unwind header: %v", err)
+	}
+	if info.Version != UNWIND_SECTION_VERSION {
+		return nil, fmt.Errorf("unsupported compact unwind version %d", info.Version)
+	}
+	if uint64(info.CommonEncodingsArrayCount)+uint64(info.PersonalityArrayCount)+uint64(info.IndexCount)*3 > uint64(len(dat))/4 {
+		return nil, fmt.Errorf("compact unwind header array counts are larger than the unwind info")
+	}
+	if info.CommonEncodingsArrayCount > 0 {
+		info.CommonEncodings = make([]Encoding, info.CommonEncodingsArrayCount)
+		if err := read(info.CommonEncodingsArraySectionOffset, info.CommonEncodings); err != nil {
+			return nil, fmt.Errorf("failed to read common encodings: %v", err)
+		}
+	}
+	if info.PersonalityArrayCount > 0 {
+		info.Personalities = make([]uint32, info.PersonalityArrayCount)
+		if err := read(info.PersonalityArraySectionOffset, info.Personalities); err != nil {
+			return nil, fmt.Errorf("failed to read personalities: %v", err)
+		}
+	}
+	if info.IndexCount == 0 {
+		return &info, nil
+	}
+	info.Index = make([]CompactUnwindIndexEntry, info.IndexCount)
+	if err := read(info.IndexSectionOffset, info.Index); err != nil {
+		return nil, fmt.Errorf("failed to read first level index: %v", err)
+	}
+
+	// the LSDA index arrays of all the pages are contiguous
+	lsdas := make(map[uint32]uint32)
+	if first, last := info.Index[0].LSDAIndexArraySectionOffset, info.Index[len(info.Index)-1].LSDAIndexArraySectionOffset; last > first {
+		if uint64(last) > uint64(len(dat)) {
+			return nil, fmt.Errorf("LSDA index (%#x-%#x) is past the end of the unwind info", first, last)
+		}
+		entries := make([]lsdaIndexEntry, (last-first)/uint32(binary.Size(lsdaIndexEntry{})))
+		if err := read(first, entries); err != nil {
+			return nil, fmt.Errorf("failed to read LSDA index: %v", err)
+		}
+		for _, e := range entries {
+			lsdas[e.FunctionOffset] = e.LSDAOffset
+		}
+	}
+
+	addEntry := func(funcOffset uint32, enc Encoding) {
+		e := Entry{Start: base + uint64(funcOffset), Encoding: enc}
+		if idx := enc.PersonalityIndex(); idx > 0 && idx <= len(info.Personalities) {
+			e.Personality = base + uint64(info.Personalities[idx-1])
+		}
+		if enc.HasLSDA() {
+			if lsda, ok := lsdas[funcOffset]; ok {
+				e.LSDA = base + uint64(lsda)
+			}
+		}
+		info.Entries = append(info.Entries, e)
+	}
+
+	for _, idx := range info.Index[:len(info.Index)-1] {
+		if idx.SecondLevelPagesSectionOffset == 0 {
+			continue
+		}
+		var kind uint32
+		if err := read(idx.SecondLevelPagesSectionOffset, &kind); err != nil {
+			return nil, fmt.Errorf("failed to read second level page kind: %v", err)
+		}
+		switch kind {
+		case UNWIND_SECOND_LEVEL_REGULAR:
+			var page regularPageHeader
+			if err := read(idx.SecondLevelPagesSectionOffset, &page); err != nil {
+				return nil, fmt.Errorf("failed to read regular second level page header: %v", err)
+			}
+			entries := make([]struct {
+				FunctionOffset uint32
+				Encoding       Encoding
+			}, page.EntryCount)
+			if err := read(idx.SecondLevelPagesSectionOffset+uint32(page.EntryPageOffset), entries); err != nil {
+				return nil, fmt.Errorf("failed to read regular second level page entries: %v", err)
+			}
+			for _, e := range entries {
+				addEntry(e.FunctionOffset, e.Encoding)
+			}
+		case UNWIND_SECOND_LEVEL_COMPRESSED:
+			var page compressedPageHeader
+			if err := read(idx.SecondLevelPagesSectionOffset, &page); err != nil {
+				return nil, fmt.Errorf("failed to read compressed second level page header: %v", err)
+			}
+			entries := make([]uint32, page.EntryCount)
+			if err := read(idx.SecondLevelPagesSectionOffset+uint32(page.EntryPageOffset), entries); err != nil {
+				return nil, fmt.Errorf("failed to read compressed second level page entries: %v", err)
+			}
+			encodings := make([]Encoding, page.EncodingsCount)
+			if err := read(idx.SecondLevelPagesSectionOffset+uint32(page.EncodingsPageOffset), encodings); err != nil {
+				return nil, fmt.Errorf("failed to read compressed second level page encodings: %v", err)
+			}
+			for _, e := range entries {
+				encIdx := int(e&UNWIND_INFO_COMPRESSED_ENCODING) >> 24
+				var enc Encoding
+				switch {
+				case encIdx < len(info.CommonEncodings):
+					enc = info.CommonEncodings[encIdx]
+				case encIdx-len(info.CommonEncodings) < len(encodings):
+					enc = encodings[encIdx-len(info.CommonEncodings)]
+				default:
+					return nil, fmt.Errorf("compressed entry encoding index %d is out of range", encIdx)
+				}
+				addEntry(idx.FunctionOffset+(e&UNWIND_INFO_COMPRESSED_OFFSET), enc)
+			}
+		default:
+			return nil, fmt.Errorf("unknown second level page kind %d", kind)
+		}
+	}
+
+	sort.SliceStable(info.Entries, func(i, j int) bool { return info.Entries[i].Start < info.Entries[j].Start })
+	for i := range info.Entries {
+		if i+1 < len(info.Entries) {
+			info.Entries[i].End = info.Entries[i+1].Start
+		} else {
+			info.Entries[i].End = base + uint64(info.Index[len(info.Index)-1].FunctionOffset)
+		}
+	}
+
+	return &info, nil
+}
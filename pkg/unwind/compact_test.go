@@ -0,0 +1,59 @@
+package unwind
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+func TestParseCompactUnwind(t *testing.T) {
+	put := func(b []byte, vals ...uint32) {
+		for i, v := range vals {
+			binary.LittleEndian.PutUint32(b[i*4:], v)
+		}
+	}
+	dat := make([]byte, 0x100)
+	// header: 1 common encoding @0x1c, 1 personality @0x20, 3 index entries @0x24
+	put(dat, UNWIND_SECTION_VERSION, 0x1c, 1, 0x20, 1, 0x24, 3)
+	put(dat[0x1c:], 0x01000000)         // common encoding
+	put(dat[0x20:], 0x2000)             // personality pointer (image offset)
+	put(dat[0x24:], 0x1000, 0x60, 0x48) // index 0 -> compressed page
+	put(dat[0x30:], 0x1100, 0x80, 0x50) // index 1 -> regular page
+	put(dat[0x3c:], 0x1200, 0, 0x50)    // sentinel
+	put(dat[0x48:], 0x1010, 0x3000)     // LSDA index entry
+	binary.LittleEndian.PutUint32(dat[0x60:], UNWIND_SECOND_LEVEL_COMPRESSED)
+	binary.LittleEndian.PutUint16(dat[0x64:], 12) // entry page offset
+	binary.LittleEndian.PutUint16(dat[0x66:], 2)  // entry count
+	binary.LittleEndian.PutUint16(dat[0x68:], 20) // encodings page offset
+	binary.LittleEndian.PutUint16(dat[0x6a:], 1)  // encodings count
+	put(dat[0x6c:], 0x00000000, 0x01000010)       // entries: (common 0, +0), (page 1, +0x10)
+	put(dat[0x74:], 0x51000000)                   // page encoding: lsda + personality 1
+	binary.LittleEndian.PutUint32(dat[0x80:], UNWIND_SECOND_LEVEL_REGULAR)
+	binary.LittleEndian.PutUint16(dat[0x84:], 8) // entry page offset
+	binary.LittleEndian.PutUint16(dat[0x86:], 1) // entry count
+	put(dat[0x88:], 0x1100, 0x04000040)          // dwarf FDE at 0x40
+
+	info, err := ParseCompactUnwind(dat, binary.LittleEndian, 0x100000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Start: 0x100001000, End: 0x100001010, Encoding: 0x01000000},
+		{Start: 0x100001010, End: 0x100001100, Encoding: 0x51000000, Personality: 0x100002000, LSDA: 0x100003000},
+		{Start: 0x100001100, End: 0x100001200, Encoding: 0x04000040},
+	}
+	if !reflect.DeepEqual(info.Entries, want) {
+		t.Errorf("ParseCompactUnwind() entries = %+v, want %+v", info.Entries, want)
+	}
+	if enc := info.Entries[2].Encoding; !enc.IsDWARF(types.CPUAmd64) || enc.DWARFOffset() != 0x40 {
+		t.Errorf("encoding %s should be a DWARF FDE at 0x40", enc)
+	}
+
+	// an LSDA index that ends past the unwind info must not be allocated
+	put(dat[0x3c:], 0x1200, 0, 0xfffffff0)
+	if _, err := ParseCompactUnwind(dat, binary.LittleEndian, 0x100000000); err == nil {
+		t.Error("ParseCompactUnwind() succeeded with an LSDA index past the end of the unwind info")
+	}
+}
@@ -0,0 +1,400 @@
+package unwind
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/blacktop/go-macho/pkg/trie"
+)
+
+// PointerEncoding is a DW_EH_PE pointer encoding
+type PointerEncoding uint8
+
+const (
+	DW_EH_PE_absptr  PointerEncoding = 0x00
+	DW_EH_PE_uleb128 PointerEncoding = 0x01
+	DW_EH_PE_udata2  PointerEncoding = 0x02
+	DW_EH_PE_udata4  PointerEncoding = 0x03
+	DW_EH_PE_udata8  PointerEncoding = 0x04
+	DW_EH_PE_sleb128 PointerEncoding = 0x09
+	DW_EH_PE_sdata2  PointerEncoding = 0x0a
+	DW_EH_PE_sdata4  PointerEncoding = 0x0b
+	DW_EH_PE_sdata8  PointerEncoding = 0x0c
+
+	DW_EH_PE_pcrel   PointerEncoding = 0x10
+	DW_EH_PE_textrel PointerEncoding = 0x20
+	DW_EH_PE_datarel PointerEncoding = 0x30
+	DW_EH_PE_funcrel PointerEncoding = 0x40
+	DW_EH_PE_aligned PointerEncoding = 0x50
+
+	DW_EH_PE_indirect PointerEncoding = 0x80
+	DW_EH_PE_omit     PointerEncoding = 0xff
+)
+
+func (e PointerEncoding) Format() PointerEncoding      { return e & 0x0f }
+func (e PointerEncoding) Application() PointerEncoding { return e & 0x70 }
+func (e PointerEncoding) IsIndirect() bool             { return e != DW_EH_PE_omit && e&DW_EH_PE_indirect != 0 }
+
+// CIE is an __eh_frame Common Information Entry
+type CIE struct {
+	Offset                uint64 // offset in __eh_frame
+	Version               uint8
+	Augmentation          string
+	CodeAlignmentFactor   uint64
+	DataAlignmentFactor   int64
+	ReturnAddressRegister uint64
+	AugmentationData      []byte
+	LSDAEncoding          PointerEncoding
+	PersonalityEncoding   PointerEncoding
+	FDEEncoding           PointerEncoding
+	Personality           uint64 // address of the personality function (or of a pointer to it if PersonalityEncoding.IsIndirect())
+	SignalFrame           bool
+	Instructions          []byte // initial CFA instructions
+}
+
+// FDE is an __eh_frame Frame Description Entry
+type FDE struct {
+	Offset           uint64 // offset in __eh_frame
+	CIE              *CIE
+	PCBegin          uint64
+	PCEnd            uint64
+	LSDA             uint64 // address of the language specific data area (or of a pointer to it if CIE.LSDAEncoding.IsIndirect())
+	AugmentationData []byte
+	Instructions     []byte // CFA instructions
+}
+
+func (f FDE) String() string {
+	s := fmt.Sprintf("%#016x-%#016x fde=%#x cie=%#x", f.PCBegin, f.PCEnd, f.Offset, f.CIE.Offset)
+	if f.LSDA != 0 {
+		s += fmt.Sprintf(" lsda=%#x", f.LSDA)
+	}
+	return s
+}
+
+// EHFrame is a parsed __TEXT,__eh_frame section
+type EHFrame struct {
+	CIEs []*CIE
+	FDEs []*FDE
+}
+
+// FDEForOffset returns the FDE at offset off in __eh_frame (i.e. a compact unwind DWARF mode encoding's DWARFOffset)
+func (e *EHFrame) FDEForOffset(off uint64) *FDE {
+	for _, fde := range e.FDEs {
+		if fde.Offset == off {
+			return fde
+		}
+	}
+	return nil
+}
+
+// FDEForAddress returns the FDE of the function containing addr
+func (e *EHFrame) FDEForAddress(addr uint64) *FDE {
+	for _, fde := range e.FDEs {
+		if fde.PCBegin <= addr && addr < fde.PCEnd {
+			return fde
+		}
+	}
+	return nil
+}
+
+type ehFrameParser struct {
+	dat     []byte
+	bo      binary.ByteOrder
+	addr    uint64 // __eh_frame address
+	ptrSize int
+}
+
+// readPointer reads a pointer of encoding enc at offset off, that must end before end (the end of its record),
+// returning the offset after it
+func (p *ehFrameParser) readPointer(off, end uint64, enc PointerEncoding) (uint64, uint64, error) {
+	if enc == DW_EH_PE_omit {
+		return 0, off, nil
+	}
+	if end > uint64(len(p.dat)) || off > end {
+		return 0, 0, fmt.Errorf("offset %#x is past the end of its record", off)
+	}
+	r := bytes.NewReader(p.dat[off:end])
+	var val uint64
+	switch enc.Format() {
+	case DW_EH_PE_absptr:
+		if p.ptrSize == 8 {
+			var v uint64
+			if err := binary.Read(r, p.bo, &v); err != nil {
+				return 0, 0, err
+			}
+			val = v
+		} else {
+			var v uint32
+			if err := binary.Read(r, p.bo, &v); err != nil {
+				return 0, 0, err
+			}
+			val = uint64(v)
+		}
+	case DW_EH_PE_uleb128:
+		v, err := trie.ReadUleb128(r)
+		if err != nil {
+			return 0, 0, err
+		}
+		val = v
+	case DW_EH_PE_sleb128:
+		v, err := readSleb128(r)
+		if err != nil {
+			return 0, 0, err
+		}
+		val = uint64(v)
+	case DW_EH_PE_udata2, DW_EH_PE_sdata2:
+		var v uint16
+		if err := binary.Read(r, p.bo, &v); err != nil {
+			return 0, 0, err
+		}
+		val = uint64(v)
+		if enc.Format() == DW_EH_PE_sdata2 {
+			val = uint64(int16(v))
+		}
+	case DW_EH_PE_udata4, DW_EH_PE_sdata4:
+		var v uint32
+		if err := binary.Read(r, p.bo, &v); err != nil {
+			return 0, 0, err
+		}
+		val = uint64(v)
+		if enc.Format() == DW_EH_PE_sdata4 {
+			val = uint64(int32(v))
+		}
+	case DW_EH_PE_udata8, DW_EH_PE_sdata8:
+		if err := binary.Read(r, p.bo, &val); err != nil {
+			return 0, 0, err
+		}
+	default:
+		return 0, 0, fmt.Errorf("unsupported pointer encoding format %#x", uint8(enc))
+	}
+	switch enc.Application() {
+	case DW_EH_PE_absptr:
+	case DW_EH_PE_pcrel:
+		val += p.addr + off
+	default:
+		return 0, 0, fmt.Errorf("unsupported pointer encoding application %#x", uint8(enc))
+	}
+	if p.ptrSize == 4 {
+		val &= 0xffffffff
+	}
+	return val, end - uint64(r.Len()), nil
+}
+
+// ParseEHFrame parses the contents of the __TEXT,__eh_frame section at address addr
+func ParseEHFrame(dat []byte, bo binary.ByteOrder, addr uint64, ptrSize int) (*EHFrame, error) {
+	p := &ehFrameParser{dat: dat, bo: bo, addr: addr, ptrSize: ptrSize}
+	eh := &EHFrame{}
+	cies := make(map[uint64]*CIE)
+
+	for off := uint64(0); off+4 <= uint64(len(dat)); {
+		start := off
+		length := uint64(bo.Uint32(dat[off:]))
+		off += 4
+		if length == 0 { // terminator
+			break
+		}
+		if length == 0xffffffff {
+			if off+8 > uint64(len(dat)) {
+				return nil, fmt.Errorf("truncated extended length at offset %#x", start)
+			}
+			length = bo.Uint64(dat[off:])
+			off += 8
+		}
+		end := off + length
+		if end > uint64(len(dat)) || length < 4 {
+			return nil, fmt.Errorf("entry at offset %#x has bad length %#x", start, length)
+		}
+		idOff := off
+		id := uint64(bo.Uint32(dat[off:]))
+		off += 4
+
+		if id == 0 {
+			cie, err := p.parseCIE(start, off, end)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse CIE at offset %#x: %v", start, err)
+			}
+			cies[start] = cie
+			eh.CIEs = append(eh.CIEs, cie)
+		} else {
+			if id > idOff {
+				return nil, fmt.Errorf("FDE at offset %#x has bad CIE pointer %#x", start, id)
+			}
+			cie, ok := cies[idOff-id]
+			if !ok {
+				return nil, fmt.Errorf("FDE at offset %#x refers to missing CIE at offset %#x", start, idOff-id)
+			}
+			fde, err := p.parseFDE(cie, start, off, end)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse FDE at offset %#x: %v", start, err)
+			}
+			eh.FDEs = append(eh.FDEs, fde)
+		}
+		off = end
+	}
+
+	return eh, nil
+}
+
+func (p *ehFrameParser) parseCIE(start, off, end uint64) (*CIE, error) {
+	cie := &CIE{
+		Offset:              start,
+		LSDAEncoding:        DW_EH_PE_omit,
+		PersonalityEncoding: DW_EH_PE_omit,
+		FDEEncoding:         DW_EH_PE_absptr,
+	}
+	dat := p.dat[:end]
+	r := bytes.NewReader(dat[off:])
+
+	var err error
+	if cie.Version, err = r.ReadByte(); err != nil {
+		return nil, err
+	}
+	aug, err := readCString(r)
+	if err != nil {
+		return nil, err
+	}
+	cie.Augmentation = aug
+	if strings.HasPrefix(aug, "eh") {
+		if _, err := r.Seek(int64(p.ptrSize), io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+	if cie.CodeAlignmentFactor, err = trie.ReadUleb128(r); err != nil {
+		return nil, err
+	}
+	if cie.DataAlignmentFactor, err = readSleb128(r); err != nil {
+		return nil, err
+	}
+	if cie.Version == 1 {
+		ra, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		cie.ReturnAddressRegister = uint64(ra)
+	} else if cie.ReturnAddressRegister, err = trie.ReadUleb128(r); err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(aug, "z") {
+		augLen, err := trie.ReadUleb128(r)
+		if err != nil {
+			return nil, err
+		}
+		augOff := end - uint64(r.Len())
+		if augLen > end-augOff {
+			return nil, fmt.Errorf("augmentation data length %#x is too large", augLen)
+		}
+		cie.AugmentationData = dat[augOff : augOff+augLen]
+		aoff := augOff
+		for _, c := range aug[1:] {
+			switch c {
+			case 'L':
+				if aoff >= end {
+					return nil, fmt.Errorf("truncated augmentation data")
+				}
+				cie.LSDAEncoding = PointerEncoding(dat[aoff])
+				aoff++
+			case 'P':
+				if aoff >= end {
+					return nil, fmt.Errorf("truncated augmentation data")
+				}
+				cie.PersonalityEncoding = PointerEncoding(dat[aoff])
+				if cie.Personality, aoff, err = p.readPointer(aoff+1, augOff+augLen, cie.PersonalityEncoding); err != nil {
+					return nil, fmt.Errorf("failed to read personality: %v", err)
+				}
+			case 'R':
+				if aoff >= end {
+					return nil, fmt.Errorf("truncated augmentation data")
+				}
+				cie.FDEEncoding = PointerEncoding(dat[aoff])
+				aoff++
+			case 'S':
+				cie.SignalFrame = true
+			case 'B': // arm64e pointer authentication B key
+			default:
+				return nil, fmt.Errorf("unknown augmentation %q", aug)
+			}
+		}
+		cie.Instructions = dat[augOff+augLen : end]
+	} else {
+		cie.Instructions = dat[end-uint64(r.Len()) : end]
+	}
+
+	return cie, nil
+}
+
+func (p *ehFrameParser) parseFDE(cie *CIE, start, off, end uint64) (*FDE, error) {
+	fde := &FDE{Offset: start, CIE: cie}
+	dat := p.dat[:end]
+
+	var err error
+	if fde.PCBegin, off, err = p.readPointer(off, end, cie.FDEEncoding); err != nil {
+		return nil, fmt.Errorf("failed to read pc begin: %v", err)
+	}
+	var pcRange uint64
+	if pcRange, off, err = p.readPointer(off, end, cie.FDEEncoding&0x0f); err != nil { // the range is not relative
+		return nil, fmt.Errorf("failed to read pc range: %v", err)
+	}
+	fde.PCEnd = fde.PCBegin + pcRange
+
+	if strings.HasPrefix(cie.Augmentation, "z") {
+		r := bytes.NewReader(dat[off:])
+		augLen, err := trie.ReadUleb128(r)
+		if err != nil {
+			return nil, err
+		}
+		augOff := end - uint64(r.Len())
+		if augLen > end-augOff {
+			return nil, fmt.Errorf("augmentation data length %#x is too large", augLen)
+		}
+		fde.AugmentationData = dat[augOff : augOff+augLen]
+		if augLen > 0 && cie.LSDAEncoding != DW_EH_PE_omit {
+			if fde.LSDA, _, err = p.readPointer(augOff, augOff+augLen, cie.LSDAEncoding); err != nil {
+				return nil, fmt.Errorf("failed to read LSDA: %v", err)
+			}
+		}
+		off = augOff + augLen
+	}
+	fde.Instructions = dat[off:end]
+
+	return fde, nil
+}
+
+func readCString(r *bytes.Reader) (string, error) {
+	var sb strings.Builder
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if c == 0 {
+			return sb.String(), nil
+		}
+		sb.WriteByte(c)
+	}
+}
+
+// readSleb128 reads a DWARF SLEB128 value (sign extended from the final byte)
+func readSleb128(r *bytes.Reader) (int64, error) {
+	var result int64
+	var shift uint
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if shift < 64 {
+			result |= int64(b&0x7f) << shift
+		}
+		shift += 7
+		if b&0x80 == 0 {
+			if shift < 64 && b&0x40 != 0 {
+				result |= -1 << shift
+			}
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package unwind
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestParseEHFrame(t *testing.T) {
+	const addr = 0x100001000
+	var dat []byte
+	u32 := func(v uint32) {
+		dat = binary.LittleEndian.AppendUint32(dat, v)
+	}
+	// CIE "zPLR" with a pcrel|indirect|sdata4 personality, and pcrel|sdata4 LSDA and FDE pointers
+	u32(24)
+	u32(0)
+	dat = append(dat, 1, 'z', 'P', 'L', 'R', 0, 1, 0x78, 16, 7, 0x9b)
+	u32(uint32(0x2000 - 0x13)) // personality pointer at addr+0x2000 (the pointer is at offset 0x13)
+	dat = append(dat, 0x1b, 0x1b, 0x0c, 0x07, 0x08)
+	// FDE
+	u32(20)
+	u32(uint32(len(dat)))     // CIE pointer
+	u32(uint32(0x100 - 0x24)) // pc begin: addr+0x100 (the pointer is at offset 0x24)
+	u32(0x40)                 // pc range
+	dat = append(dat, 4)
+	u32(uint32(0x3000 - 0x2d)) // LSDA: addr+0x3000 (the pointer is at offset 0x2d)
+	dat = append(dat, 0, 0, 0)
+	u32(0) // terminator
+
+	eh, err := ParseEHFrame(dat, binary.LittleEndian, addr, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eh.CIEs) != 1 || len(eh.FDEs) != 1 {
+		t.Fatalf("ParseEHFrame() = %d CIEs and %d FDEs, want 1 and 1", len(eh.CIEs), len(eh.FDEs))
+	}
+	cie := eh.CIEs[0]
+	if cie.Augmentation != "zPLR" || cie.DataAlignmentFactor != -8 || cie.ReturnAddressRegister != 16 ||
+		cie.Personality != addr+0x2000 || !cie.PersonalityEncoding.IsIndirect() {
+		t.Errorf("bad CIE %+v", cie)
+	}
+	fde := eh.FDEForAddress(addr + 0x120)
+	if fde == nil || fde.PCBegin != addr+0x100 || fde.PCEnd != addr+0x140 || fde.LSDA != addr+0x3000 || fde.CIE != cie {
+		t.Errorf("bad FDE %+v", fde)
+	}
+	if eh.FDEForOffset(0x1c) != fde {
+		t.Errorf("FDEForOffset(0x1c) = %v, want %v", eh.FDEForOffset(0x1c), fde)
+	}
+}
+
+func TestParseEHFrameTruncatedPersonality(t *testing.T) {
+	var dat []byte
+	u32 := func(v uint32) {
+		dat = binary.LittleEndian.AppendUint32(dat, v)
+	}
+	// CIE "zP" whose augmentation data (one byte) only holds the absptr personality encoding, the 8 byte
+	// pointer itself would be read from the following record
+	u32(16)
+	u32(0)
+	dat = append(dat, 1, 'z', 'P', 0, 1, 0x78, 16, 1, 0x00)
+	dat = append(dat, 0, 0, 0)
+	u32(12)
+	u32(0)
+	dat = append(dat, 1, 0, 1, 0x78, 16, 0, 0, 0)
+	u32(0) // terminator
+
+	if _, err := ParseEHFrame(dat, binary.LittleEndian, 0x1000, 8); err == nil {
+		t.Error("ParseEHFrame() read a personality pointer past the end of its augmentation data")
+	}
+}
+
+func TestReadSleb128(t *testing.T) {
+	tests := []struct {
+		enc  []byte
+		want int64
+	}{
+		{[]byte{0x00}, 0},
+		{[]byte{0x02}, 2},
+		{[]byte{0x7f}, -1},
+		{[]byte{0x78}, -8},
+		{[]byte{0x3f}, 63},
+		{[]byte{0x40}, -64},
+		{[]byte{0xff, 0x00}, 127},
+		{[]byte{0x81, 0x7f}, -127},
+		{[]byte{0x80, 0x7f}, -128},
+		{[]byte{0xc0, 0x00}, 64}, // 0x40 set on a continuation byte must not sign extend
+		{[]byte{0xc0, 0xbb, 0x78}, -123456},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x7f}, math.MinInt64},
+	}
+	for _, tt := range tests {
+		got, err := readSleb128(bytes.NewReader(tt.enc))
+		if err != nil {
+			t.Errorf("readSleb128(% x) error = %v", tt.enc, err)
+		} else if got != tt.want {
+			t.Errorf("readSleb128(% x) = %d, want %d", tt.enc, got, tt.want)
+		}
+	}
+	if _, err := readSleb128(bytes.NewReader([]byte{0x80, 0x80})); err == nil {
+		t.Error("readSleb128() of a truncated value succeeded")
+	}
+}
@@ -0,0 +1,85 @@
+package macho
+
+import (
+	"fmt"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// Perms returns the read, write and execute permissions of the initial protection of the segment
+func (s *SegmentHeader) Perms() (read, write, execute bool) {
+	return s.Prot.Read(), s.Prot.Write(), s.Prot.Execute()
+}
+
+// ProtectionIssueKind is the kind of a ProtectionIssue
+type ProtectionIssueKind uint8
+
+const (
+	WritableExecutable    ProtectionIssueKind = iota + 1 // mapped both writable and executable
+	MaxWritableExecutable                                // its maximum protection allows remapping it writable and executable
+	ProtExceedsMax                                       // its initial protection isn't allowed by its maximum protection
+)
+
+func (k ProtectionIssueKind) String() string {
+	switch k {
+	case WritableExecutable:
+		return "writable and executable"
+	case MaxWritableExecutable:
+		return "max protection is writable and executable"
+	case ProtExceedsMax:
+		return "protection exceeds max protection"
+	}
+	return fmt.Sprintf("ProtectionIssueKind(%d)", uint8(k))
+}
+
+// A ProtectionIssue is a segment (or a code section of a writable and executable segment) with a risky
+// protection found by CheckWX
+type ProtectionIssue struct {
+	Segment string
+	Section string // set for the code sections (i.e. the i386 __IMPORT.__jump_table) of WX segments
+	Kind    ProtectionIssueKind
+	Prot    types.VmProtection // initial protection of the segment
+	Maxprot types.VmProtection // maximum protection of the segment
+}
+
+func (i ProtectionIssue) String() string {
+	name := i.Segment
+	if i.Section != "" {
+		name += "." + i.Section
+	}
+	return fmt.Sprintf("%s: %s (max %s): %s", name, i.Prot, i.Maxprot, i.Kind)
+}
+
+// CheckWX returns the segments (and their code sections) that are both writable and executable, may be
+// remapped so or whose initial protection exceeds their maximum protection. The protections of object files
+// aren't used so they have no issues.
+func (f *File) CheckWX() []ProtectionIssue {
+	if f.Type == types.MH_OBJECT {
+		return nil
+	}
+	wx := func(p types.VmProtection) bool { return p.Write() && p.Execute() }
+
+	var issues []ProtectionIssue
+	for _, seg := range f.Segments() {
+		issue := ProtectionIssue{Segment: seg.Name, Prot: seg.Prot, Maxprot: seg.Maxprot}
+		if wx(seg.Prot) {
+			issue.Kind = WritableExecutable
+			issues = append(issues, issue)
+			for _, sec := range seg.Sections() {
+				if sec.HasInstructions() || sec.IsSymbolStubs() || sec.Flags.IsSelfModifyingCode() {
+					secIssue := issue
+					secIssue.Section = sec.Name
+					issues = append(issues, secIssue)
+				}
+			}
+		} else if wx(seg.Maxprot) {
+			issue.Kind = MaxWritableExecutable
+			issues = append(issues, issue)
+		}
+		if seg.Prot&^seg.Maxprot != 0 {
+			issue.Kind = ProtExceedsMax
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
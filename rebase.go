@@ -0,0 +1,451 @@
+package macho
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/blacktop/go-macho/pkg/fixupchains"
+	"github.com/blacktop/go-macho/types"
+)
+
+// segmentContent returns the (modifiable) contents of the segment, caching them for saving later
+func (f *File) segmentContent(seg *Segment) ([]byte, error) {
+	if content, ok := f.segdata[seg.Name]; ok {
+		return content, nil
+	}
+	content := make([]byte, seg.Filesz)
+	if _, err := f.sr.ReadAt(content, int64(seg.Offset)); err != nil {
+		return nil, fmt.Errorf("failed to read segment %s data: %v", seg.Name, err)
+	}
+	if f.segdata == nil {
+		f.segdata = make(map[string][]byte)
+	}
+	f.segdata[seg.Name] = content
+	return content, nil
+}
+
+// slidePointer adds slide to the pointer (of size bytes) at file offset off
+func (f *File) slidePointer(off uint64, size int, slide int64) error {
+	for _, seg := range f.Segments() {
+		if seg.Name == "__LINKEDIT" || off < seg.Offset || off+uint64(size) > seg.Offset+seg.Filesz {
+			continue
+		}
+		content, err := f.segmentContent(seg)
+		if err != nil {
+			return err
+		}
+		b := content[off-seg.Offset:]
+		if size == 8 {
+			f.ByteOrder.PutUint64(b, uint64(int64(f.ByteOrder.Uint64(b))+slide))
+		} else {
+			f.ByteOrder.PutUint32(b, uint32(int64(f.ByteOrder.Uint32(b))+slide))
+		}
+		return nil
+	}
+	return fmt.Errorf("pointer at offset %#x is not in a segment", off)
+}
+
+// slideChainedFixups slides the targets of the chained fixup rebases that are vmaddrs
+// (the targets of the other pointer formats are offsets from the image base and are unchanged)
+func (f *File) slideChainedFixups(slide int64) error {
+	dcf, err := f.DyldChainedFixups()
+	if err != nil {
+		return err
+	}
+
+	type rebase struct {
+		off     uint64
+		size    int
+		pointer uint64
+	}
+	var rebases []rebase
+	for _, start := range dcf.Starts {
+		for _, fixup := range start.Fixups {
+			var target, mask uint64
+			var r rebase
+			switch fx := fixup.(type) {
+			case fixupchains.DyldChainedPtr64Rebase:
+				if start.PointerFormat != fixupchains.DYLD_CHAINED_PTR_64 {
+					continue // DYLD_CHAINED_PTR_64_OFFSET
+				}
+				mask = 1<<36 - 1
+				r = rebase{off: fx.Fixup, size: 8, pointer: fx.Pointer}
+			case fixupchains.DyldChainedPtrArm64eRebase:
+				if start.PointerFormat != fixupchains.DYLD_CHAINED_PTR_ARM64E &&
+					start.PointerFormat != fixupchains.DYLD_CHAINED_PTR_ARM64E_FIRMWARE {
+					continue // DYLD_CHAINED_PTR_ARM64E_USERLAND(24) and DYLD_CHAINED_PTR_ARM64E_KERNEL
+				}
+				mask = 1<<43 - 1
+				r = rebase{off: fx.Fixup, size: 8, pointer: fx.Pointer}
+			case fixupchains.DyldChainedPtr32Rebase:
+				mask = 1<<26 - 1
+				r = rebase{off: fx.Fixup, size: 4, pointer: uint64(fx.Pointer)}
+			case fixupchains.DyldChainedPtr32FirmwareRebase:
+				mask = 1<<26 - 1
+				r = rebase{off: fx.Fixup, size: 4, pointer: uint64(fx.Pointer)}
+			default:
+				continue
+			}
+			target = uint64(int64(r.pointer&mask) + slide)
+			if target&^mask != 0 {
+				return fmt.Errorf("slid chained fixup target %#x at offset %#x doesn't fit in pointer format %d", target, r.off, start.PointerFormat)
+			}
+			r.pointer = r.pointer&^mask | target
+			rebases = append(rebases, r)
+		}
+	}
+
+	for _, r := range rebases {
+		for _, seg := range f.Segments() {
+			if seg.Name == "__LINKEDIT" || r.off < seg.Offset || r.off+uint64(r.size) > seg.Offset+seg.Filesz {
+				continue
+			}
+			content, err := f.segmentContent(seg)
+			if err != nil {
+				return err
+			}
+			if r.size == 8 {
+				f.ByteOrder.PutUint64(content[r.off-seg.Offset:], r.pointer)
+			} else {
+				f.ByteOrder.PutUint32(content[r.off-seg.Offset:], uint32(r.pointer))
+			}
+		}
+	}
+
+	return nil
+}
+
+// slideSymbols slides the values of the symbols defined in sections
+func (f *File) slideSymbols(slide int64) error {
+	if f.Symtab == nil || f.Symtab.Nsyms == 0 {
+		return nil
+	}
+	linkedit := f.Segment("__LINKEDIT")
+	if linkedit == nil {
+		return fmt.Errorf("failed to find __LINKEDIT segment")
+	}
+	if _, err := f.Symbols(); err != nil {
+		return err
+	}
+	if err := f.cacheLinkedit(); err != nil {
+		return err
+	}
+	dat := f.ledata.Bytes()
+	nlists, err := f.readNlists(f.Symtab, dat, linkedit.Offset)
+	if err != nil {
+		return err
+	}
+	nlistSize := uint64(f.symbolSize())
+	for i, n := range nlists {
+		if n.Type.IsDebugSym() {
+			if n.Sect == types.NO_SECT {
+				continue
+			}
+		} else if !n.Type.IsDefinedInSection() {
+			continue
+		}
+		b := dat[uint64(f.Symtab.Symoff)-linkedit.Offset+uint64(i)*nlistSize:]
+		if f.Magic == types.Magic64 {
+			f.ByteOrder.PutUint64(b[8:], uint64(int64(n.Value)+slide))
+		} else {
+			f.ByteOrder.PutUint32(b[8:], uint32(int64(n.Value)+slide))
+		}
+		if i < len(f.Symtab.Syms) {
+			f.Symtab.Syms[i].Value = uint64(int64(f.Symtab.Syms[i].Value) + slide)
+		}
+	}
+	f.resetSymbolIndex()
+	return nil
+}
+
+// slideThreadState slides the pc in the thread state of the LC_UNIXTHREAD entry point
+func (f *File) slideThreadState(t *Thread, slide int64) {
+	for _, thread := range t.Threads {
+		var pc, size int
+		switch {
+		case f.CPU == types.CPUAmd64 && thread.Flavor == types.X86_THREAD_STATE64:
+			pc, size = 16, 8 // rip
+		case f.CPU == types.CPUI386 && thread.Flavor == types.X86_THREAD_STATE32:
+			pc, size = 10, 4 // eip
+		case f.CPU == types.CPUArm64 && thread.Flavor == types.ARM_THREAD_STATE64:
+			pc, size = 32, 8
+		case f.CPU == types.CPUArm && thread.Flavor == types.ARM_THREAD_STATE:
+			pc, size = 15, 4
+		default:
+			continue
+		}
+		if len(thread.Data) < (pc+1)*size {
+			continue
+		}
+		b := thread.Data[pc*size:]
+		if size == 8 {
+			f.ByteOrder.PutUint64(b, uint64(int64(f.ByteOrder.Uint64(b))+slide))
+		} else {
+			f.ByteOrder.PutUint32(b, uint32(int64(f.ByteOrder.Uint32(b))+slide))
+		}
+	}
+}
+
+// Rebase statically slides the MachO by slide bytes. It slides the segment and section addresses,
+// the LC_UNIXTHREAD entry point, LC_ROUTINES init address, the section symbols and all rebase locations
+// (classic dyld info rebase opcodes or chained fixups whose targets are vmaddrs).
+// NOTE: the export trie, LC_MAIN entry point, function starts and chained fixups with vm offset targets
+// are relative to the image base and so are unchanged. Call Save or WriteTo to write out the slid MachO
+// (which will need to be re-signed)
+func (f *File) Rebase(slide int64) error {
+	if slide == 0 {
+		return nil
+	}
+	if f.Type == types.MH_OBJECT {
+		return fmt.Errorf("rebasing %s files is not supported", f.Type)
+	}
+	if f.Dysymtab != nil && f.Dysymtab.Nlocrel > 0 && !f.HasFixups() && f.DyldInfo() == nil {
+		return fmt.Errorf("rebasing MachOs with local relocations is not supported")
+	}
+
+	// validate the new addresses before changing anything
+	var pagezero *Segment
+	for _, seg := range f.Segments() {
+		if seg.Name == "__PAGEZERO" && seg.Addr == 0 && seg.Filesz == 0 {
+			pagezero = seg
+			continue
+		}
+		if addr := int64(seg.Addr) + slide; addr < 0 || (f.Magic != types.Magic64 && addr > 1<<32-1) {
+			return fmt.Errorf("slid segment %s address %#x is out of range", seg.Name, addr)
+		}
+	}
+
+	// slide the pointers (reading the rebase locations with the original segment addresses)
+	if f.HasDyldChainedFixups() {
+		if err := f.slideChainedFixups(slide); err != nil {
+			return fmt.Errorf("failed to slide chained fixups: %v", err)
+		}
+	} else if dinfo := f.DyldInfo(); dinfo != nil || f.DyldInfoOnly() != nil {
+		linkedit := f.Segment("__LINKEDIT")
+		dat, err := f.linkeditData()
+		if err != nil {
+			return err
+		}
+		var off, size uint32
+		if dinfo != nil {
+			off, size = dinfo.RebaseOff, dinfo.RebaseSize
+		} else {
+			off, size = f.DyldInfoOnly().RebaseOff, f.DyldInfoOnly().RebaseSize
+		}
+		if size > 0 {
+			if uint64(off) < linkedit.Offset || uint64(off+size)-linkedit.Offset > uint64(len(dat)) {
+				return fmt.Errorf("rebase info is outside of __LINKEDIT")
+			}
+			rebases, err := f.parseRebase(bytes.NewReader(dat[uint64(off)-linkedit.Offset : uint64(off+size)-linkedit.Offset]))
+			if err != nil {
+				return fmt.Errorf("failed to parse rebase info: %v", err)
+			}
+			for _, r := range rebases {
+				ptrSize := int(f.pointerSize())
+				switch r.Type {
+				case types.REBASE_TYPE_TEXT_ABSOLUTE32:
+					ptrSize = 4
+				case types.REBASE_TYPE_TEXT_PCREL32:
+					continue // relative to the pointer's address which slides too
+				}
+				if err := f.slidePointer(f.Segment(r.Segment).Offset+r.Offset, ptrSize, slide); err != nil {
+					return fmt.Errorf("failed to slide rebase %s: %v", r, err)
+				}
+			}
+		}
+	}
+
+	if err := f.slideSymbols(slide); err != nil {
+		return fmt.Errorf("failed to slide symbols: %v", err)
+	}
+
+	for _, l := range f.Loads {
+		switch l := l.(type) {
+		case *UnixThread:
+			f.slideThreadState(&l.Thread, slide)
+		case *Routines:
+			l.InitAddress = uint32(int64(l.InitAddress) + slide)
+		case *Routines64:
+			l.InitAddress = uint64(int64(l.InitAddress) + slide)
+		}
+	}
+
+	lowest := uint64(1<<64 - 1)
+	for _, seg := range f.Segments() {
+		if seg == pagezero {
+			continue
+		}
+		if seg.Addr < lowest {
+			lowest = seg.Addr
+		}
+		seg.Addr = uint64(int64(seg.Addr) + slide)
+	}
+	for _, sect := range f.Sections {
+		sect.Addr = uint64(int64(sect.Addr) + slide)
+	}
+	f.resetAddrIndex()
+	// keep __PAGEZERO covering everything below the image
+	if pagezero != nil && pagezero.Memsz == lowest {
+		pagezero.Memsz = uint64(int64(lowest) + slide)
+	}
+
+	return nil
+}
+
+// BindResolver returns the value of the import name (from dylib) plus addend to write to its bind slots
+type BindResolver func(name, dylib string, addend int64) uint64
+
+// RebasedImage returns the memory image of the MachO as loaded at its preferred load address plus slide.
+// The segments (except __PAGEZERO) are laid out at their vm addresses starting at the lowest segment address
+// and zero filled past their file size. All rebase locations and chained fixup rebases are fixed up and the
+// non-lazy bind slots are set to the value returned by the optional resolve callback (by default binds to
+// symbols of this image are resolved and the others are zeroed).
+// NOTE: authenticated arm64e pointers are written unsigned
+func (f *File) RebasedImage(slide uint64, resolve ...BindResolver) ([]byte, error) {
+	if f.Dysymtab != nil && f.Dysymtab.Nlocrel > 0 && !f.HasFixups() && f.DyldInfo() == nil && slide != 0 {
+		return nil, fmt.Errorf("rebasing MachOs with local relocations is not supported")
+	}
+
+	var segs []*Segment
+	base, end := uint64(1<<64-1), uint64(0)
+	for _, seg := range f.Segments() {
+		if seg.Name == "__PAGEZERO" && seg.Addr == 0 && seg.Filesz == 0 {
+			continue
+		}
+		if seg.Addr < base {
+			base = seg.Addr
+		}
+		if seg.Addr+seg.Memsz > end {
+			end = seg.Addr + seg.Memsz
+		}
+		segs = append(segs, seg)
+	}
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("MachO has no segments")
+	}
+
+	image := make([]byte, end-base)
+	for _, seg := range segs {
+		dst := image[seg.Addr-base : seg.Addr-base+seg.Memsz]
+		if content, ok := f.segdata[seg.Name]; ok {
+			copy(dst, content)
+			continue
+		}
+		if seg.Filesz < seg.Memsz {
+			dst = dst[:seg.Filesz]
+		}
+		if _, err := f.cr.ReadAt(dst, int64(seg.Offset)); err != nil {
+			return nil, fmt.Errorf("failed to read segment %s data: %v", seg.Name, err)
+		}
+	}
+
+	slot := func(addr uint64, size int) ([]byte, error) {
+		if addr < base || addr+uint64(size) > end {
+			return nil, fmt.Errorf("pointer at address %#x is not in a segment", addr)
+		}
+		return image[addr-base:], nil
+	}
+	put := func(addr uint64, size int, value uint64) error {
+		b, err := slot(addr, size)
+		if err != nil {
+			return err
+		}
+		if size == 8 {
+			f.ByteOrder.PutUint64(b, value)
+		} else {
+			f.ByteOrder.PutUint32(b, uint32(value))
+		}
+		return nil
+	}
+	self := f.LibraryOrdinalName(types.BIND_SPECIAL_DYLIB_SELF)
+	bind := func(name, dylib string, addend int64) uint64 {
+		if len(resolve) > 0 && resolve[0] != nil {
+			return resolve[0](name, dylib, addend)
+		}
+		if dylib == self {
+			if addr, err := f.FindSymbolAddress(name); err == nil {
+				return uint64(int64(addr)+addend) + slide
+			}
+		}
+		return 0
+	}
+
+	loadAddress := f.preferredLoadAddress()
+	if f.HasDyldChainedFixups() {
+		dcf, err := f.DyldChainedFixups()
+		if err != nil {
+			return nil, err
+		}
+		for _, start := range dcf.Starts {
+			size := 8
+			switch start.PointerFormat {
+			case fixupchains.DYLD_CHAINED_PTR_32, fixupchains.DYLD_CHAINED_PTR_32_CACHE, fixupchains.DYLD_CHAINED_PTR_32_FIRMWARE:
+				size = 4
+			}
+			for _, fixup := range start.Fixups {
+				addr, err := f.GetVMAddress(fixup.Offset())
+				if err != nil {
+					return nil, fmt.Errorf("failed to get address of chained fixup at offset %#x: %v", fixup.Offset(), err)
+				}
+				p, err := fixupchains.DecodePointer(start.PointerFormat, fixup.Raw())
+				if err != nil {
+					return nil, err
+				}
+				var value uint64
+				switch {
+				case p.IsBind:
+					if p.Ordinal >= uint64(len(dcf.Imports)) {
+						return nil, fmt.Errorf("chained bind at address %#x has out of range ordinal %d", addr, p.Ordinal)
+					}
+					imp := dcf.Imports[p.Ordinal]
+					value = bind(imp.Name, f.LibraryOrdinalName(imp.Import.LibOrdinal()), p.Addend+int64(imp.Import.Addend()))
+				case start.PointerFormat == fixupchains.DYLD_CHAINED_PTR_32 && p.Target > uint64(start.MaxValidPointer):
+					// a non-pointer value co-opted into the chain
+					value = p.Target - (0x04000000+uint64(start.MaxValidPointer))/2
+				default:
+					value = p.RuntimeOffset(loadAddress) + loadAddress + slide
+				}
+				if err := put(addr, size, value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	} else if f.DyldInfo() != nil || f.DyldInfoOnly() != nil {
+		rebases, err := f.GetRebaseInfo()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rebase info: %v", err)
+		}
+		for _, r := range rebases {
+			size := int(f.pointerSize())
+			switch r.Type {
+			case types.REBASE_TYPE_TEXT_ABSOLUTE32:
+				size = 4
+			case types.REBASE_TYPE_TEXT_PCREL32:
+				continue // relative to the pointer's address which slides too
+			}
+			b, err := slot(r.Start+r.Offset, size)
+			if err != nil {
+				return nil, err
+			}
+			if size == 8 {
+				f.ByteOrder.PutUint64(b, f.ByteOrder.Uint64(b)+slide)
+			} else {
+				f.ByteOrder.PutUint32(b, f.ByteOrder.Uint32(b)+uint32(slide))
+			}
+		}
+		binds, err := f.GetBindInfo()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bind info: %v", err)
+		}
+		for _, b := range binds {
+			if b.Kind != types.BIND_KIND {
+				continue // lazy binds point to their (rebased) stub helpers until first use
+			}
+			if err := put(b.Start+b.Offset, int(f.pointerSize()), bind(b.Name, b.Dylib, b.Addend)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return image, nil
+}
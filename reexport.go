@@ -0,0 +1,157 @@
+package macho
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/blacktop/go-macho/internal/saferio"
+	"github.com/blacktop/go-macho/pkg/trie"
+	"github.com/blacktop/go-macho/types"
+)
+
+// ErrExportNotFound is returned by ResolveExport when none of the images of the re-export chain export the symbol
+var ErrExportNotFound = errors.New("symbol is not exported")
+
+// A DylibResolver opens the dependent dylib with the install name (e.g. from an extracted filesystem or a dyld
+// shared cache)
+type DylibResolver func(installName string) (*File, error)
+
+// ResolveExport looks up the export of symbol following its re-exports (EXPORT_SYMBOL_FLAGS_REEXPORT entries
+// and the symbols of LC_REEXPORT_DYLIB dylibs) through the dylibs opened by resolve to the image that defines
+// it. The returned export is the one of the defining image (named as it is in that image) with FoundInDylib
+// set to the install name of the defining image.
+func (f *File) ResolveExport(symbol string, resolve DylibResolver) (*trie.TrieExport, error) {
+	r := &exportResolver{
+		resolve: resolve,
+		images:  make(map[string]*File),
+		seen:    make(map[string]bool),
+	}
+	var name string
+	if id := f.DylibID(); id != nil {
+		name = id.Name
+	}
+	return r.find(f, name, symbol)
+}
+
+// exportResolver follows the re-export chains of ResolveExport
+type exportResolver struct {
+	resolve DylibResolver
+	images  map[string]*File // the dylibs opened by install name
+	seen    map[string]bool  // the install name and symbol pairs already looked up (guards against cycles)
+}
+
+// open returns the dylib with the install name (opening it with the resolver the first time)
+func (r *exportResolver) open(name string) (*File, error) {
+	if m, ok := r.images[name]; ok {
+		return m, nil
+	}
+	m, err := r.resolve(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dylib %s: %w", name, err)
+	}
+	r.images[name] = m
+	return m, nil
+}
+
+// find returns the export of symbol from f (with the install name name) or from the dylibs it re-exports
+func (r *exportResolver) find(f *File, name, symbol string) (*trie.TrieExport, error) {
+	key := name + "\x00" + symbol
+	if r.seen[key] {
+		return nil, ErrExportNotFound
+	}
+	r.seen[key] = true
+
+	exp, err := f.findExport(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if exp != nil {
+		if !exp.Flags.ReExport() {
+			exp.FoundInDylib = name
+			return exp, nil
+		}
+		dylibs := f.ImportedLibraries()
+		if exp.Other == 0 || exp.Other > uint64(len(dylibs)) {
+			return nil, fmt.Errorf("re-export of %s has an invalid library ordinal %d", symbol, exp.Other)
+		}
+		target := exp.ReExport
+		if target == "" {
+			target = symbol
+		}
+		m, err := r.open(dylibs[exp.Other-1])
+		if err != nil {
+			return nil, err
+		}
+		res, err := r.find(m, dylibs[exp.Other-1], target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s re-exported from %s: %w", symbol, dylibs[exp.Other-1], err)
+		}
+		return res, nil
+	}
+
+	// the symbols of the re-exported dylibs (i.e. the sub-libraries of an umbrella framework)
+	for _, lib := range f.ImportedLibraryDetails() {
+		if !lib.ReExport() {
+			continue
+		}
+		m, err := r.open(lib.Path)
+		if err != nil {
+			return nil, err
+		}
+		res, err := r.find(m, lib.Path, symbol)
+		if err == nil {
+			return res, nil
+		}
+		if !errors.Is(err, ErrExportNotFound) {
+			return nil, err
+		}
+	}
+	return nil, ErrExportNotFound
+}
+
+// exportTrie returns the export trie of the LC_DYLD_EXPORTS_TRIE or LC_DYLD_INFO(_ONLY) (nil if there is none)
+func (f *File) exportTrie() ([]byte, error) {
+	var off, size uint32
+	if dxt := f.DyldExportsTrie(); dxt != nil {
+		off, size = dxt.Offset, dxt.Size
+	} else if dinfo := f.DyldInfo(); dinfo != nil {
+		off, size = dinfo.ExportOff, dinfo.ExportSize
+	} else if dinfo := f.DyldInfoOnly(); dinfo != nil {
+		off, size = dinfo.ExportOff, dinfo.ExportSize
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	dat, err := saferio.ReadDataAt(f.cr, uint64(size), int64(off))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export trie at offset=%#x; %v", off, err)
+	}
+	return dat, nil
+}
+
+// findExport returns the export of symbol from the export trie (or the external symbols of images without
+// one), or nil if f doesn't export it
+func (f *File) findExport(symbol string) (*trie.TrieExport, error) {
+	dat, err := f.exportTrie()
+	if err != nil {
+		return nil, err
+	}
+	if dat != nil {
+		r := bytes.NewReader(dat)
+		if _, err := trie.WalkTrie(r, symbol); err != nil {
+			return nil, nil // not in the trie
+		}
+		exp, err := trie.ReadExport(r, symbol, f.preferredLoadAddress())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s export: %v", symbol, err)
+		}
+		return exp, nil
+	}
+	for _, sym := range f.symbols() {
+		if sym.Name == symbol && sym.Type.IsExternalSym() && sym.Type.IsDefinedInSection() && !sym.Type.IsPrivateExternalSym() {
+			return &trie.TrieExport{Name: sym.Name, Flags: types.EXPORT_SYMBOL_FLAGS_KIND_REGULAR, Address: sym.Value}, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,180 @@
+package macho
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash"
+
+	"github.com/blacktop/go-macho/internal/saferio"
+	ctypes "github.com/blacktop/go-macho/pkg/codesign/types"
+)
+
+// CodeSignatureMismatch is a CodeDirectory hash slot that doesn't match the file's contents
+type CodeSignatureMismatch struct {
+	CodeDirectory int    // index into the code signature's CodeDirectories
+	Special       bool   // true if Slot is a special slot (i.e. a hash of another code signature blob)
+	Slot          uint32 // code page index or special slot type
+	Offset        uint64 // file offset of the code page
+	Expected      []byte
+	Actual        []byte
+}
+
+func (m CodeSignatureMismatch) String() string {
+	if m.Special {
+		return fmt.Sprintf("CodeDirectory %d: special slot %d (%s) hash mismatch: expected %x, got %x",
+			m.CodeDirectory, m.Slot, ctypes.SlotType(m.Slot), m.Expected, m.Actual)
+	}
+	return fmt.Sprintf("CodeDirectory %d: page %d @ %#x hash mismatch: expected %x, got %x",
+		m.CodeDirectory, m.Slot, m.Offset, m.Expected, m.Actual)
+}
+
+func codeDirectoryHasher(cd *ctypes.CodeDirectory) (hash.Hash, error) {
+	switch cd.Header.HashType {
+	case ctypes.HASHTYPE_SHA1:
+		return sha1.New(), nil
+	case ctypes.HASHTYPE_SHA256, ctypes.HASHTYPE_SHA256_TRUNCATED:
+		return sha256.New(), nil
+	case ctypes.HASHTYPE_SHA384:
+		return sha512.New384(), nil
+	case ctypes.HASHTYPE_SHA512:
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported code directory hash type %s", cd.Header.HashType)
+	}
+}
+
+// codeSignatureBlobs returns the raw blobs of the embedded code signature SuperBlob indexed by slot type
+func (f *File) codeSignatureBlobs() (map[ctypes.SlotType][]byte, error) {
+	cs := f.CodeSignature()
+	if cs == nil {
+		return nil, fmt.Errorf("no LC_CODE_SIGNATURE found")
+	}
+	dat, err := saferio.ReadDataAt(f.cr, uint64(cs.Size), int64(cs.Offset))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read code signature data at offset=%#x: %v", cs.Offset, err)
+	}
+
+	r := bytes.NewReader(dat)
+	var sb ctypes.SbHeader
+	if err := binary.Read(r, binary.BigEndian, &sb); err != nil {
+		return nil, fmt.Errorf("failed to read code signature SuperBlob header: %v", err)
+	}
+	if uint64(sb.Count)*uint64(binary.Size(ctypes.BlobIndex{})) > uint64(r.Len()) {
+		return nil, fmt.Errorf("invalid code signature SuperBlob count: %d", sb.Count)
+	}
+	index := make([]ctypes.BlobIndex, sb.Count)
+	if err := binary.Read(r, binary.BigEndian, &index); err != nil {
+		return nil, fmt.Errorf("failed to read code signature SuperBlob index: %v", err)
+	}
+
+	blobs := make(map[ctypes.SlotType][]byte, len(index))
+	for _, idx := range index {
+		if idx.Type == ctypes.CSSLOT_TICKETSLOT {
+			continue // the stapled ticket isn't a blob and isn't covered by the CodeDirectory
+		}
+		if uint64(idx.Offset)+8 > uint64(len(dat)) {
+			return nil, fmt.Errorf("invalid %s blob offset: %#x", idx.Type, idx.Offset)
+		}
+		length := binary.BigEndian.Uint32(dat[idx.Offset+4:])
+		if uint64(idx.Offset)+uint64(length) > uint64(len(dat)) {
+			return nil, fmt.Errorf("invalid %s blob length: %#x", idx.Type, length)
+		}
+		blobs[idx.Type] = dat[idx.Offset : idx.Offset+length]
+	}
+
+	return blobs, nil
+}
+
+// VerifyCodeSignaturePages recomputes the hashes of every code page and embedded special slot
+// and compares them against each CodeDirectory in the code signature.
+// NOTE: it returns all the mismatching slots (an empty result means the signature's hashes are intact)
+func (f *File) VerifyCodeSignaturePages() ([]CodeSignatureMismatch, error) {
+	cs := f.CodeSignature()
+	if cs == nil {
+		return nil, fmt.Errorf("no LC_CODE_SIGNATURE found")
+	}
+
+	blobs, err := f.codeSignatureBlobs()
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []CodeSignatureMismatch
+
+	for i := range cs.CodeDirectories {
+		cd := &cs.CodeDirectories[i]
+
+		h, err := codeDirectoryHasher(cd)
+		if err != nil {
+			return nil, err
+		}
+		if int(cd.Header.HashSize) > h.Size() {
+			return nil, fmt.Errorf("invalid code directory hash size %d for %s", cd.Header.HashSize, cd.Header.HashType)
+		}
+		digest := func(data []byte) []byte {
+			h.Reset()
+			h.Write(data)
+			return h.Sum(nil)[:cd.Header.HashSize]
+		}
+
+		// special slots (only the ones embedded in the signature can be checked)
+		for _, slot := range cd.SpecialSlots {
+			if bytes.Equal(slot.Hash, make([]byte, len(slot.Hash))) {
+				continue // not bound
+			}
+			blob, ok := blobs[ctypes.SlotType(slot.Index)]
+			if !ok {
+				continue // external (e.g. Info.plist or resource directory)
+			}
+			if actual := digest(blob); !bytes.Equal(actual, slot.Hash) {
+				mismatches = append(mismatches, CodeSignatureMismatch{
+					CodeDirectory: i,
+					Special:       true,
+					Slot:          slot.Index,
+					Expected:      slot.Hash,
+					Actual:        actual,
+				})
+			}
+		}
+
+		// code pages
+		codeLimit := cd.CodeLimit
+		if codeLimit == 0 {
+			codeLimit = uint64(cd.Header.CodeLimit)
+		}
+		pageSize := codeLimit
+		if cd.Header.PageSize > 0 {
+			pageSize = uint64(1) << cd.Header.PageSize
+		}
+		for _, slot := range cd.CodeSlots {
+			off := uint64(slot.Index) * pageSize
+			if off >= codeLimit {
+				return nil, fmt.Errorf("code slot %d is beyond the code limit %#x", slot.Index, codeLimit)
+			}
+			size := pageSize
+			if off+size > codeLimit {
+				size = codeLimit - off
+			}
+			page := make([]byte, size)
+			if n, err := f.cr.ReadAt(page, int64(off)); err != nil && uint64(n) != size {
+				// a truncated file hashes whatever is left
+				page = page[:n]
+			}
+			if actual := digest(page); !bytes.Equal(actual, slot.Hash) {
+				mismatches = append(mismatches, CodeSignatureMismatch{
+					CodeDirectory: i,
+					Slot:          slot.Index,
+					Offset:        off,
+					Expected:      slot.Hash,
+					Actual:        actual,
+				})
+			}
+		}
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,307 @@
+package macho
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/blacktop/go-macho/pkg/codesign"
+	"github.com/blacktop/go-macho/types"
+)
+
+// StripMode selects which symbols StripSymbols removes
+type StripMode int
+
+const (
+	// StripDebug removes only the debugging (N_STAB) symbols (like strip -S)
+	StripDebug StripMode = iota
+	// StripLocal removes the debugging and local symbols (like strip -x)
+	StripLocal
+	// StripAllButExported removes all symbols except the exported, undefined and
+	// dynamically referenced ones (like strip)
+	StripAllButExported
+)
+
+func (m StripMode) String() string {
+	switch m {
+	case StripDebug:
+		return "debug"
+	case StripLocal:
+		return "local"
+	case StripAllButExported:
+		return "all-but-exported"
+	default:
+		return fmt.Sprintf("StripMode(%d)", int(m))
+	}
+}
+
+// keep reports whether the symbol n survives stripping in mode m
+func (m StripMode) keep(n types.Nlist64) bool {
+	if n.Type.IsDebugSym() {
+		return false
+	}
+	switch m {
+	case StripDebug:
+		return true
+	case StripLocal:
+		return n.Type.IsExternalSym()
+	case StripAllButExported:
+		if n.Type.IsUndefinedSym() || n.Desc.IsReferencedDynamically() {
+			return true
+		}
+		return n.Type.IsExternalSym() && !n.Type.IsPrivateExternalSym()
+	}
+	return true
+}
+
+// readNlists reads the raw nlist entries of the symbol table from the __LINKEDIT data
+func (f *File) readNlists(symtab *Symtab, dat []byte, base uint64) ([]types.Nlist64, error) {
+	nlistSize := uint64(12)
+	if f.Magic == types.Magic64 {
+		nlistSize = 16
+	}
+	start := uint64(symtab.Symoff) - base
+	if uint64(symtab.Symoff) < base || start+uint64(symtab.Nsyms)*nlistSize > uint64(len(dat)) {
+		return nil, fmt.Errorf("symbol table is outside of __LINKEDIT")
+	}
+	nlists := make([]types.Nlist64, symtab.Nsyms)
+	for i := range nlists {
+		b := dat[start+uint64(i)*nlistSize:]
+		nlists[i].Name = f.ByteOrder.Uint32(b[0:])
+		nlists[i].Type = types.NType(b[4])
+		nlists[i].Sect = b[5]
+		nlists[i].Desc = types.NDescType(f.ByteOrder.Uint16(b[6:]))
+		if f.Magic == types.Magic64 {
+			nlists[i].Value = f.ByteOrder.Uint64(b[8:])
+		} else {
+			nlists[i].Value = uint64(f.ByteOrder.Uint32(b[8:]))
+		}
+	}
+	return nlists, nil
+}
+
+// StripSymbols removes symbols from the symbol table (see StripMode), regenerating the
+// nlist and string tables, fixing the dysymtab symbol ranges, indirect symbols and external
+// relocations and re-laying-out __LINKEDIT. If the MachO is signed it is re-signed with resign (e.g. an ad-hoc
+// codesign.Config); resign may only be nil for unsigned MachOs (use RemoveSignature first to leave it unsigned).
+// NOTE: call Save or WriteTo to write out the stripped MachO
+func (f *File) StripSymbols(mode StripMode, resign *codesign.Config) error {
+	if f.Type == types.MH_OBJECT {
+		return fmt.Errorf("stripping %s files is not supported (relocations refer to symbols by index)", f.Type)
+	}
+	if f.CodeSignature() != nil && resign == nil {
+		return fmt.Errorf("stripping symbols invalidates the code signature: a codesign.Config is required to re-sign it")
+	}
+	symtab := f.Symtab
+	if symtab == nil {
+		return fmt.Errorf("failed to find LC_SYMTAB load command")
+	}
+	if symtab.Nsyms == 0 {
+		return nil
+	}
+	dysymtab := f.Dysymtab
+	if dysymtab != nil && (dysymtab.Ntoc > 0 || dysymtab.Nmodtab > 0 || dysymtab.Nextrefsyms > 0) {
+		return fmt.Errorf("stripping MachOs with a table of contents, module table or external references is not supported")
+	}
+
+	linkedit := f.Segment("__LINKEDIT")
+	if linkedit == nil {
+		return fmt.Errorf("failed to find __LINKEDIT segment")
+	}
+	// load the (lazily parsed) tables before they are rewritten
+	if _, err := f.Symbols(); err != nil {
+		return err
+	}
+	if _, err := f.IndirectSymbols(); err != nil {
+		return err
+	}
+	dat, err := f.linkeditData()
+	if err != nil {
+		return err
+	}
+	nlists, err := f.readNlists(symtab, dat, linkedit.Offset)
+	if err != nil {
+		return err
+	}
+	if uint64(symtab.Stroff) < linkedit.Offset || uint64(symtab.Stroff+symtab.Strsize)-linkedit.Offset > uint64(len(dat)) {
+		return fmt.Errorf("string table is outside of __LINKEDIT")
+	}
+	strtab := dat[uint64(symtab.Stroff)-linkedit.Offset : uint64(symtab.Stroff+symtab.Strsize)-linkedit.Offset]
+
+	// regenerate the string table (ld64 starts it with " \x00" so index 1 is the empty string)
+	newStrtab := []byte{' ', 0}
+	strs := make(map[string]uint32)
+	addString := func(strx uint32) uint32 {
+		if strx == 0 || strx >= uint32(len(strtab)) {
+			return 0
+		}
+		s := cstring(strtab[strx:])
+		if idx, ok := strs[s]; ok {
+			return idx
+		}
+		idx := uint32(len(newStrtab))
+		newStrtab = append(newStrtab, s...)
+		newStrtab = append(newStrtab, 0)
+		strs[s] = idx
+		return idx
+	}
+
+	remap := make([]int64, len(nlists)) // old symbol index -> new symbol index (-1 if stripped)
+	var kept []types.Nlist64
+	var syms []Symbol
+	for i, n := range nlists {
+		if !mode.keep(n) {
+			remap[i] = -1
+			continue
+		}
+		remap[i] = int64(len(kept))
+		n.Name = addString(n.Name)
+		if n.Type.IsIndirectSym() { // n_value is the string table index of the indirect symbol's name
+			n.Value = uint64(addString(uint32(n.Value)))
+		}
+		kept = append(kept, n)
+		if i < len(symtab.Syms) {
+			syms = append(syms, symtab.Syms[i])
+		}
+	}
+	nlistSize, ptrSize := 12, 4
+	if f.Magic == types.Magic64 {
+		nlistSize, ptrSize = 16, 8
+	}
+	for len(newStrtab)%ptrSize != 0 {
+		newStrtab = append(newStrtab, 0)
+	}
+	newSymtab := make([]byte, len(kept)*nlistSize)
+	for i, n := range kept {
+		if f.Magic == types.Magic64 {
+			n.Put64(newSymtab[i*nlistSize:], f.ByteOrder)
+		} else {
+			n32 := types.Nlist32{Nlist: n.Nlist, Value: uint32(n.Value)}
+			n32.Put32(newSymtab[i*nlistSize:], f.ByteOrder)
+		}
+	}
+
+	// the new blobs are keyed by the load command offset field that refers to them
+	newData := map[*uint32][]byte{
+		&symtab.Symoff: newSymtab,
+		&symtab.Stroff: newStrtab,
+	}
+
+	var newDysymtab types.DysymtabCmd
+	var indirectSyms []uint32
+	if dysymtab != nil {
+		newDysymtab = dysymtab.DysymtabCmd
+		indirectSyms = dysymtab.IndirectSyms
+		// recount the local, external defined and undefined symbol ranges
+		count := func(first, n uint32) (uint32, uint32) {
+			newFirst := uint32(0)
+			for i := int64(first); i < int64(len(remap)); i++ {
+				if remap[i] >= 0 {
+					newFirst = uint32(remap[i])
+					break
+				}
+				newFirst = uint32(len(kept))
+			}
+			var newN uint32
+			for i := first; i < first+n && int(i) < len(remap); i++ {
+				if remap[i] >= 0 {
+					newN++
+				}
+			}
+			return newFirst, newN
+		}
+		newDysymtab.Ilocalsym, newDysymtab.Nlocalsym = count(dysymtab.Ilocalsym, dysymtab.Nlocalsym)
+		newDysymtab.Iextdefsym, newDysymtab.Nextdefsym = count(dysymtab.Iextdefsym, dysymtab.Nextdefsym)
+		newDysymtab.Iundefsym, newDysymtab.Nundefsym = count(dysymtab.Iundefsym, dysymtab.Nundefsym)
+
+		// stripped local symbols referenced by stubs and pointers become INDIRECT_SYMBOL_LOCAL
+		if dysymtab.Nindirectsyms > 0 {
+			start := uint64(dysymtab.Indirectsymoff) - linkedit.Offset
+			if uint64(dysymtab.Indirectsymoff) < linkedit.Offset || start+uint64(dysymtab.Nindirectsyms)*4 > uint64(len(dat)) {
+				return fmt.Errorf("indirect symbol table is outside of __LINKEDIT")
+			}
+			indirect := make([]byte, dysymtab.Nindirectsyms*4)
+			indirectSyms = make([]uint32, dysymtab.Nindirectsyms)
+			for i := range indirectSyms {
+				idx := f.ByteOrder.Uint32(dat[start+uint64(i)*4:])
+				if idx&(types.INDIRECT_SYMBOL_LOCAL|types.INDIRECT_SYMBOL_ABS) == 0 && int(idx) < len(remap) {
+					if remap[idx] >= 0 {
+						idx = uint32(remap[idx])
+					} else if n := nlists[idx]; !n.Type.IsExternalSym() || n.Type.IsPrivateExternalSym() {
+						idx = types.INDIRECT_SYMBOL_LOCAL
+						if n.Type.IsAbsoluteSym() {
+							idx |= types.INDIRECT_SYMBOL_ABS
+						}
+					} else {
+						return fmt.Errorf("indirect symbol %d refers to stripped external symbol %d", i, idx)
+					}
+				}
+				indirectSyms[i] = idx
+				f.ByteOrder.PutUint32(indirect[i*4:], idx)
+			}
+			newData[&dysymtab.Indirectsymoff] = indirect
+		}
+
+		// external relocations refer to (undefined) symbols by index
+		if dysymtab.Nextrel > 0 {
+			start := uint64(dysymtab.Extreloff) - linkedit.Offset
+			if uint64(dysymtab.Extreloff) < linkedit.Offset || start+uint64(dysymtab.Nextrel)*8 > uint64(len(dat)) {
+				return fmt.Errorf("external relocations are outside of __LINKEDIT")
+			}
+			extrel := make([]byte, dysymtab.Nextrel*8)
+			copy(extrel, dat[start:])
+			for i := 0; i < len(extrel); i += 8 {
+				if f.ByteOrder.Uint32(extrel[i:])&(1<<31) != 0 { // scattered
+					continue
+				}
+				info := f.ByteOrder.Uint32(extrel[i+4:])
+				var symnum uint32
+				if f.ByteOrder == binary.BigEndian {
+					symnum = info >> 8
+				} else {
+					symnum = info & (1<<24 - 1)
+				}
+				if int(symnum) >= len(remap) || remap[symnum] < 0 {
+					return fmt.Errorf("external relocation %d refers to stripped symbol %d", i/8, symnum)
+				}
+				if f.ByteOrder == binary.BigEndian {
+					info = uint32(remap[symnum])<<8 | info&0xff
+				} else {
+					info = uint32(remap[symnum]) | info&^(1<<24-1)
+				}
+				f.ByteOrder.PutUint32(extrel[i+4:], info)
+			}
+			newData[&dysymtab.Extreloff] = extrel
+		}
+	}
+
+	layout, err := f.linkeditLayout(linkedit.Offset)
+	if err != nil {
+		return err
+	}
+	for _, blob := range layout.Blobs() {
+		if d, ok := newData[blob.Offset]; ok {
+			blob.Data = d
+		}
+	}
+	symtab.Nsyms = uint32(len(kept))
+	symtab.Strsize = uint32(len(newStrtab))
+	symtab.Syms = syms
+	f.resetSymbolIndex()
+	if dysymtab != nil {
+		dysymtab.DysymtabCmd = newDysymtab
+		dysymtab.IndirectSyms = indirectSyms
+	}
+	if err := f.applyLinkeditLayout(layout); err != nil {
+		return err
+	}
+	// __LINKEDIT shrunk
+	linkedit.Memsz = pageAlign(linkedit.Filesz, f.pageSize())
+
+	if f.CodeSignature() != nil {
+		if err := f.CodeSign(resign); err != nil {
+			return fmt.Errorf("failed to re-sign: %v", err)
+		}
+	}
+
+	return nil
+}
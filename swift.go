@@ -2319,12 +2319,12 @@ func (f *File) getTypeImportInfo(addr uint64) (string, error) {
 		case strings.HasPrefix(s, SymbolNamespace):
 			namespace := strings.TrimPrefix(s, string(SymbolNamespace))
 			if namespace != CTypedef {
-				fmt.Printf("unknown import info symbol namespace (please notify the author): %s\n", namespace)
+				f.diagnoseAddr(addr, fmt.Errorf("unknown import info symbol namespace (please notify the author): %s", namespace))
 			}
 		case strings.HasPrefix(s, RelatedEntityName):
 			entity := strings.TrimPrefix(s, string(RelatedEntityName))
 			if entity != "e" {
-				fmt.Printf("unknown import info related entity (please notify the author): %s\n", entity)
+				f.diagnoseAddr(addr, fmt.Errorf("unknown import info related entity (please notify the author): %s", entity))
 			}
 		}
 	}
@@ -0,0 +1,237 @@
+package macho
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/blacktop/go-dwarf"
+	"github.com/blacktop/go-macho/types"
+)
+
+// ErrNoSourceLine is returned from SourceLine when the address is not covered by the DWARF line tables
+var ErrNoSourceLine = errors.New("no DWARF line information for address")
+
+type lineRow struct {
+	addr   uint64
+	file   string
+	line   int
+	endSeq bool
+}
+
+// funcRange is the address range of a DW_TAG_subprogram (depth 0) or DW_TAG_inlined_subroutine
+type funcRange struct {
+	low      uint64
+	high     uint64
+	name     string
+	depth    int    // inlining depth
+	callFile string // call site of the inlined subroutine
+	callLine int
+}
+
+// lineIndex is the DWARF line table, subprogram and inlined subroutine ranges of a MachO sorted by address
+type lineIndex struct {
+	rows  []lineRow
+	funcs []funcRange
+}
+
+// dieName returns the name of a DIE, following DW_AT_abstract_origin and DW_AT_specification
+func dieName(d *dwarf.Data, e *dwarf.Entry) string {
+	for i := 0; i < 8 && e != nil; i++ { // bound the chain in case of malformed DWARF
+		if name, ok := e.Val(dwarf.AttrName).(string); ok {
+			return name
+		}
+		off, ok := e.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset)
+		if !ok {
+			if off, ok = e.Val(dwarf.AttrSpecification).(dwarf.Offset); !ok {
+				return ""
+			}
+		}
+		r := d.Reader()
+		r.Seek(off)
+		next, err := r.Next()
+		if err != nil {
+			return ""
+		}
+		e = next
+	}
+	return ""
+}
+
+func newLineIndex(d *dwarf.Data) (*lineIndex, error) {
+	var idx lineIndex
+	var files []*dwarf.LineFile
+	var inlined []bool // whether each open DIE is an inlined subroutine
+
+	depth := func() (n int) {
+		for _, in := range inlined {
+			if in {
+				n++
+			}
+		}
+		return n
+	}
+
+	r := d.Reader()
+	for {
+		e, err := r.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read DWARF entry: %v", err)
+		}
+		if e == nil {
+			break
+		}
+		if e.Tag == 0 {
+			if len(inlined) > 0 {
+				inlined = inlined[:len(inlined)-1]
+			}
+			continue
+		}
+		switch e.Tag {
+		case dwarf.TagCompileUnit:
+			inlined = inlined[:0]
+			files = nil
+			lr, err := d.LineReader(e)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read DWARF line table: %v", err)
+			}
+			if lr == nil {
+				break
+			}
+			var le dwarf.LineEntry
+			for {
+				if err := lr.Next(&le); err != nil {
+					break
+				}
+				row := lineRow{addr: le.Address, line: le.Line, endSeq: le.EndSequence}
+				if le.File != nil {
+					row.file = le.File.Name
+				}
+				idx.rows = append(idx.rows, row)
+			}
+			files = lr.Files()
+		case dwarf.TagSubprogram, dwarf.TagInlinedSubroutine:
+			ranges, err := d.Ranges(e)
+			if err != nil || len(ranges) == 0 {
+				break
+			}
+			fr := funcRange{name: dieName(d, e)}
+			if e.Tag == dwarf.TagInlinedSubroutine {
+				fr.depth = depth() + 1
+				if i, ok := e.Val(dwarf.AttrCallFile).(int64); ok && i >= 0 && int(i) < len(files) && files[i] != nil {
+					fr.callFile = files[i].Name
+				}
+				if line, ok := e.Val(dwarf.AttrCallLine).(int64); ok {
+					fr.callLine = int(line)
+				}
+			}
+			for _, rng := range ranges {
+				fr.low, fr.high = rng[0], rng[1]
+				idx.funcs = append(idx.funcs, fr)
+			}
+		}
+		if e.Children {
+			inlined = append(inlined, e.Tag == dwarf.TagInlinedSubroutine)
+		}
+	}
+
+	// an end_sequence row sorts before a row starting a sequence at the same address
+	sort.SliceStable(idx.rows, func(i, j int) bool {
+		if idx.rows[i].addr == idx.rows[j].addr {
+			return idx.rows[i].endSeq && !idx.rows[j].endSeq
+		}
+		return idx.rows[i].addr < idx.rows[j].addr
+	})
+	sort.SliceStable(idx.funcs, func(i, j int) bool {
+		return idx.funcs[i].low < idx.funcs[j].low
+	})
+
+	return &idx, nil
+}
+
+func (idx *lineIndex) row(addr uint64) (lineRow, bool) {
+	i := sort.Search(len(idx.rows), func(i int) bool { return idx.rows[i].addr > addr })
+	if i == 0 || idx.rows[i-1].endSeq {
+		return lineRow{}, false
+	}
+	return idx.rows[i-1], true
+}
+
+// function returns the subprogram and the inlined subroutines containing addr (outermost first)
+func (idx *lineIndex) function(addr uint64) []funcRange {
+	var fns []funcRange
+	i := sort.Search(len(idx.funcs), func(i int) bool { return idx.funcs[i].low > addr })
+	for i--; i >= 0; i-- {
+		if addr < idx.funcs[i].high {
+			fns = append(fns, idx.funcs[i])
+			if idx.funcs[i].depth == 0 {
+				break // inlined subroutines are nested in their subprogram
+			}
+		}
+	}
+	sort.SliceStable(fns, func(i, j int) bool { return fns[i].depth < fns[j].depth })
+	return fns
+}
+
+func (f *File) getLineIndex() (*lineIndex, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.lines != nil {
+		return f.lines, nil
+	}
+	d, err := f.DWARF()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DWARF: %v", err)
+	}
+	if f.lines, err = newLineIndex(d); err != nil {
+		return nil, err
+	}
+	return f.lines, nil
+}
+
+// SourceLine returns the source file, line and function name of the instruction at addr from the DWARF line tables
+// (if addr is in an inlined function, fn is the inlined function; see SourceFrames for the inline call stack)
+func (f *File) SourceLine(addr uint64) (file string, line int, fn string, err error) {
+	frames, err := f.SourceFrames(addr)
+	if err != nil {
+		return "", 0, "", err
+	}
+	return frames[0].File, frames[0].Line, frames[0].Function, nil
+}
+
+// SourceFrames returns the inline call stack of the instruction at addr (innermost to outermost) from the DWARF
+// line tables and DW_TAG_inlined_subroutine DIEs; each outer frame is located at the call site of the inner frame
+func (f *File) SourceFrames(addr uint64) ([]types.SourceFrame, error) {
+	idx, err := f.getLineIndex()
+	if err != nil {
+		return nil, err
+	}
+	row, ok := idx.row(addr)
+	if !ok {
+		return nil, fmt.Errorf("%w %#x", ErrNoSourceLine, addr)
+	}
+
+	fns := idx.function(addr)
+	if len(fns) == 0 || fns[0].depth != 0 {
+		// no DW_TAG_subprogram covers addr so fallback to the symbol table
+		var name string
+		if sym, err := f.FunctionForAddress(addr); err == nil {
+			name = sym.Name
+		}
+		fns = append([]funcRange{{name: name}}, fns...)
+	}
+
+	frames := make([]types.SourceFrame, 0, len(fns))
+	file, line := row.file, row.line
+	for i := len(fns) - 1; i >= 0; i-- {
+		frames = append(frames, types.SourceFrame{
+			Function: fns[i].name,
+			File:     file,
+			Line:     line,
+			Inlined:  i > 0,
+		})
+		file, line = fns[i].callFile, fns[i].callLine
+	}
+
+	return frames, nil
+}
@@ -0,0 +1,76 @@
+package macho
+
+// A SymbolFilter selects the symbols returned by File.Symbols
+type SymbolFilter func(f *File, sym Symbol) bool
+
+func matchFilters(f *File, sym Symbol, filters []SymbolFilter) bool {
+	for _, filter := range filters {
+		if !filter(f, sym) {
+			return false
+		}
+	}
+	return true
+}
+
+// Not selects the symbols NOT selected by filter
+func Not(filter SymbolFilter) SymbolFilter {
+	return func(f *File, sym Symbol) bool { return !filter(f, sym) }
+}
+
+// Any selects the symbols selected by any of the filters
+func Any(filters ...SymbolFilter) SymbolFilter {
+	return func(f *File, sym Symbol) bool {
+		for _, filter := range filters {
+			if filter(f, sym) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// External selects the external (N_EXT) symbols
+func External() SymbolFilter {
+	return func(f *File, sym Symbol) bool { return !sym.Type.IsDebugSym() && sym.Type.IsExternalSym() }
+}
+
+// PrivateExternal selects the private external (N_PEXT) symbols
+func PrivateExternal() SymbolFilter {
+	return func(f *File, sym Symbol) bool { return !sym.Type.IsDebugSym() && sym.Type.IsPrivateExternalSym() }
+}
+
+// Debug selects the debugging (N_STAB) symbols
+func Debug() SymbolFilter {
+	return func(f *File, sym Symbol) bool { return sym.Type.IsDebugSym() }
+}
+
+// Undefined selects the undefined (imported) symbols
+func Undefined() SymbolFilter {
+	return func(f *File, sym Symbol) bool {
+		return !sym.Type.IsDebugSym() && (sym.Type.IsUndefinedSym() || sym.Type.IsPreboundUndefinedSym())
+	}
+}
+
+// InSection selects the symbols defined in the section segment.section
+func InSection(segment, section string) SymbolFilter {
+	return func(f *File, sym Symbol) bool {
+		sec := sym.Section(f)
+		return sec != nil && sec.Seg == segment && sec.Name == section
+	}
+}
+
+// LibraryOrdinal selects the undefined symbols bound to the library with the (two-level namespace) ordinal
+// ord (1 for the first dylib load command, or types.SELF_LIBRARY_ORDINAL, DYNAMIC_LOOKUP_ORDINAL or
+// EXECUTABLE_ORDINAL)
+func LibraryOrdinal(ord uint16) SymbolFilter {
+	return func(f *File, sym Symbol) bool {
+		return Undefined()(f, sym) && sym.Desc.GetLibraryOrdinal() == ord
+	}
+}
+
+// WeakDefinition selects the weak definitions (N_WEAK_DEF)
+func WeakDefinition() SymbolFilter {
+	return func(f *File, sym Symbol) bool {
+		return !sym.Type.IsDebugSym() && !Undefined()(f, sym) && sym.Desc.IsWeakDefintion()
+	}
+}
@@ -0,0 +1,81 @@
+package macho
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// A SymbolQuery selects the symbols returned by FindSymbols (a symbol must match all of the set fields)
+type SymbolQuery struct {
+	Name      string         // exact name
+	Prefix    string         // name prefix
+	Glob      string         // name glob pattern (see path.Match)
+	Regexp    *regexp.Regexp // name regular expression
+	Defined   bool           // only symbols defined in the MachO (not undefined or debug symbols)
+	Undefined bool           // only undefined (imported) symbols
+	External  bool           // only external (non-debug N_EXT) symbols
+}
+
+func (q SymbolQuery) match(sym Symbol) (bool, error) {
+	if q.Prefix != "" && !strings.HasPrefix(sym.Name, q.Prefix) {
+		return false, nil
+	}
+	if q.Glob != "" {
+		if ok, err := path.Match(q.Glob, sym.Name); err != nil || !ok {
+			return false, err
+		}
+	}
+	if q.Regexp != nil && !q.Regexp.MatchString(sym.Name) {
+		return false, nil
+	}
+	if (q.Defined || q.Undefined || q.External) && sym.Type.IsDebugSym() {
+		return false, nil
+	}
+	undefined := sym.Type.IsUndefinedSym() || sym.Type.IsPreboundUndefinedSym()
+	if q.Defined && undefined || q.Undefined && !undefined {
+		return false, nil
+	}
+	if q.External && !sym.Type.IsExternalSym() {
+		return false, nil
+	}
+	return true, nil
+}
+
+// FindSymbols returns the symbols of the symtab (in symtab order) matching the query q
+func (f *File) FindSymbols(q SymbolQuery) ([]Symbol, error) {
+	if f.Symtab == nil {
+		return nil, &FormatError{0, "missing symbol table", nil}
+	}
+	if q.Glob != "" {
+		if _, err := path.Match(q.Glob, ""); err != nil {
+			return nil, fmt.Errorf("failed to parse glob %q: %v", q.Glob, err)
+		}
+	}
+
+	syms := f.symbols()
+	if q.Name != "" {
+		// only the symbols with the name (found through the symbol index)
+		idx := f.getSymbolIndex()
+		i, ok := idx.byName[q.Name]
+		if !ok {
+			return nil, nil
+		}
+		syms = idx.syms[i:]
+	}
+	var matches []Symbol
+	for _, sym := range syms {
+		if q.Name != "" && sym.Name != q.Name {
+			continue
+		}
+		ok, err := q.match(sym)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matches = append(matches, sym)
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,325 @@
+package macho
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/blacktop/go-macho/pkg/trie"
+	"github.com/blacktop/go-macho/types"
+)
+
+const (
+	tbdKeyWidth   = 17 // column the values of the TAPI keys are aligned to
+	tbdLineLength = 80
+)
+
+// tbdTargets returns the TAPI targets (i.e. arm64e-macos) of the MachO
+func (f *File) tbdTargets() []string {
+	arch := f.Architecture()
+	var targets []string
+	for _, p := range f.Platforms() {
+		targets = append(targets, arch+"-"+p.TBDName())
+	}
+	if len(targets) == 0 {
+		targets = append(targets, arch+"-macos")
+	}
+	return targets
+}
+
+// tbdVersion returns the version without trailing zero components (i.e. 1.0.0 is 1)
+func tbdVersion(v types.Version) string {
+	s := v.String()
+	for strings.HasSuffix(s, ".0") {
+		s = strings.TrimSuffix(s, ".0")
+	}
+	return s
+}
+
+// tbdQuote single quotes the string if it isn't a plain YAML scalar
+func tbdQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	for i, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c == '.' || c == '-' && i > 0) {
+			return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+		}
+	}
+	return s
+}
+
+// tbdList writes a YAML flow sequence wrapping it at tbdLineLength
+func tbdList(w *bufio.Writer, indent, key string, items []string) {
+	prefix := fmt.Sprintf("%s%-*s[ ", indent, tbdKeyWidth, key+":")
+	if len(items) == 0 {
+		fmt.Fprintf(w, "%s]\n", prefix)
+		return
+	}
+	col := len(prefix)
+	w.WriteString(prefix)
+	for i, item := range items {
+		item = tbdQuote(item)
+		if i < len(items)-1 {
+			item += ","
+		}
+		if i > 0 {
+			if col+1+len(item) > tbdLineLength {
+				w.WriteString("\n" + strings.Repeat(" ", len(prefix)))
+				col = len(prefix)
+			} else {
+				w.WriteString(" ")
+				col++
+			}
+		}
+		w.WriteString(item)
+		col += len(item)
+	}
+	w.WriteString(" ]\n")
+}
+
+// tbdSection is a list of targets and their symbols (keyed by the TAPI symbol kind)
+type tbdSection struct {
+	targets []string
+	symbols map[string][]string
+}
+
+var tbdSymbolKinds = []string{"symbols", "objc-classes", "objc-eh-types", "objc-ivars", "weak-symbols", "thread-local-symbols"}
+
+// tbdGroup groups the symbols by the set of targets they are exported from
+func tbdGroup(targets []string, syms map[string]map[string]map[string]bool) []tbdSection {
+	var sections []tbdSection
+	index := make(map[string]int)
+	for kind := range syms {
+		for name, in := range syms[kind] {
+			var ts []string
+			for _, t := range targets {
+				if in[t] {
+					ts = append(ts, t)
+				}
+			}
+			key := strings.Join(ts, ",")
+			i, ok := index[key]
+			if !ok {
+				i = len(sections)
+				index[key] = i
+				sections = append(sections, tbdSection{targets: ts, symbols: make(map[string][]string)})
+			}
+			sections[i].symbols[kind] = append(sections[i].symbols[kind], name)
+		}
+	}
+	order := make(map[string]int)
+	for i, t := range targets {
+		order[t] = i
+	}
+	sort.SliceStable(sections, func(i, j int) bool {
+		a, b := sections[i].targets, sections[j].targets
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return order[a[k]] < order[b[k]]
+			}
+		}
+		return len(a) > len(b)
+	})
+	for _, sec := range sections {
+		for _, names := range sec.symbols {
+			sort.Strings(names)
+		}
+	}
+	return sections
+}
+
+type tbdSymbol struct {
+	name        string
+	weak        bool
+	threadLocal bool
+	reexport    bool
+}
+
+// tbdExports returns the exported symbols of the MachO from the exports trie (or the symbol table)
+func (f *File) tbdExports() ([]tbdSymbol, error) {
+	var exps []trie.TrieExport
+	var err error
+	if f.DyldExportsTrie() != nil {
+		exps, err = f.DyldExports()
+	} else if exps, err = f.GetExports(); errors.Is(err, ErrMachODyldInfoNotFound) {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exports: %v", err)
+	}
+
+	var syms []tbdSymbol
+	if exps != nil {
+		for _, exp := range exps {
+			syms = append(syms, tbdSymbol{
+				name:        exp.Name,
+				weak:        exp.Flags.WeakDefinition(),
+				threadLocal: exp.Flags.ThreadLocal(),
+				reexport:    exp.Flags.ReExport(),
+			})
+		}
+		return syms, nil
+	}
+	if f.Symtab != nil {
+		for _, sym := range f.symbols() {
+			if sym.Type.IsExternalSym() && sym.Type.IsDefinedInSection() && !sym.Type.IsDebugSym() && !sym.Type.IsPrivateExternalSym() {
+				name := sym.Name
+				if strings.HasPrefix(name, "OBJC_IVAR_$_") {
+					name = "_" + name // parseSymtab strips the leading underscore of names containing a '.'
+				}
+				syms = append(syms, tbdSymbol{name: name, weak: sym.Desc.IsWeakDefintion()})
+			}
+		}
+	}
+	return syms, nil
+}
+
+// tbdKind returns the TAPI symbol kind and name of an exported symbol
+func tbdKind(sym tbdSymbol) (string, string) {
+	switch {
+	case strings.HasPrefix(sym.name, "_OBJC_CLASS_$_"):
+		return "objc-classes", strings.TrimPrefix(sym.name, "_OBJC_CLASS_$_")
+	case strings.HasPrefix(sym.name, "_OBJC_METACLASS_$_"):
+		return "objc-classes", strings.TrimPrefix(sym.name, "_OBJC_METACLASS_$_")
+	case strings.HasPrefix(sym.name, "_OBJC_EHTYPE_$_"):
+		return "objc-eh-types", strings.TrimPrefix(sym.name, "_OBJC_EHTYPE_$_")
+	case strings.HasPrefix(sym.name, "_OBJC_IVAR_$_"):
+		return "objc-ivars", strings.TrimPrefix(sym.name, "_OBJC_IVAR_$_")
+	case sym.weak:
+		return "weak-symbols", sym.name
+	case sym.threadLocal:
+		return "thread-local-symbols", sym.name
+	}
+	return "symbols", sym.name
+}
+
+// WriteTBD writes a TAPI v4 text-based dylib stub (.tbd) of the dylib to w
+func (f *File) WriteTBD(w io.Writer) error {
+	return WriteTBD(w, f)
+}
+
+// WriteTBD writes a TAPI v4 text-based dylib stub (.tbd) of the dylibs (i.e. the slices of a universal dylib) to w
+func WriteTBD(w io.Writer, dylibs ...*File) error {
+	if len(dylibs) == 0 {
+		return fmt.Errorf("failed to write tbd: no dylibs")
+	}
+	id := dylibs[0].DylibID()
+	if id == nil {
+		return fmt.Errorf("failed to write tbd: MachO has no LC_ID_DYLIB")
+	}
+
+	var targets []string
+	var uuids [][2]string
+	exports := make(map[string]map[string]map[string]bool)   // kind -> name -> target
+	reexports := make(map[string]map[string]map[string]bool) // kind -> name -> target
+	libs := make(map[string]map[string]map[string]bool)      // "libraries" -> path -> target
+	flatNamespace, notAppExtensionSafe := false, false
+	add := func(m map[string]map[string]map[string]bool, kind, name, target string) {
+		if m[kind] == nil {
+			m[kind] = make(map[string]map[string]bool)
+		}
+		if m[kind][name] == nil {
+			m[kind][name] = make(map[string]bool)
+		}
+		m[kind][name][target] = true
+	}
+
+	for _, m := range dylibs {
+		if mid := m.DylibID(); mid == nil || mid.Name != id.Name {
+			return fmt.Errorf("failed to write tbd: all dylibs must have the install name %s", id.Name)
+		}
+		syms, err := m.tbdExports()
+		if err != nil {
+			return err
+		}
+		flatNamespace = flatNamespace || !m.Flags.TwoLevel()
+		notAppExtensionSafe = notAppExtensionSafe || !m.Flags.AppExtensionSafe()
+		for _, target := range m.tbdTargets() {
+			for _, t := range targets {
+				if t == target {
+					return fmt.Errorf("failed to write tbd: duplicate target %s", target)
+				}
+			}
+			targets = append(targets, target)
+			if u := m.UUID(); u != nil {
+				uuids = append(uuids, [2]string{target, u.UUID.String()})
+			}
+			for _, sym := range syms {
+				kind, name := tbdKind(sym)
+				if sym.reexport {
+					add(reexports, kind, name, target)
+				} else {
+					add(exports, kind, name, target)
+				}
+			}
+			for _, l := range m.Loads {
+				if re, ok := l.(*ReExportDylib); ok {
+					add(libs, "libraries", re.Name, target)
+				}
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	key := func(k string) string { return fmt.Sprintf("%-*s", tbdKeyWidth, k+":") }
+
+	bw.WriteString("--- !tapi-tbd\n")
+	bw.WriteString(key("tbd-version") + "4\n")
+	tbdList(bw, "", "targets", targets)
+	if len(uuids) > 0 {
+		bw.WriteString("uuids:\n")
+		for _, u := range uuids {
+			fmt.Fprintf(bw, "  - %s%s\n", key("target"), u[0])
+			fmt.Fprintf(bw, "    %s%s\n", key("value"), u[1])
+		}
+	}
+	var flags []string
+	if flatNamespace {
+		flags = append(flags, "flat_namespace")
+	}
+	if notAppExtensionSafe {
+		flags = append(flags, "not_app_extension_safe")
+	}
+	if len(flags) > 0 {
+		tbdList(bw, "", "flags", flags)
+	}
+	fmt.Fprintf(bw, "%s'%s'\n", key("install-name"), strings.ReplaceAll(id.Name, "'", "''"))
+	if v := tbdVersion(id.CurrentVersion); v != "1" {
+		fmt.Fprintf(bw, "%s%s\n", key("current-version"), v)
+	}
+	if v := tbdVersion(id.CompatVersion); v != "1" {
+		fmt.Fprintf(bw, "%s%s\n", key("compatibility-version"), v)
+	}
+
+	if len(libs) > 0 {
+		bw.WriteString("reexported-libraries:\n")
+		for _, sec := range tbdGroup(targets, libs) {
+			tbdList(bw, "  - ", "targets", sec.targets)
+			tbdList(bw, "    ", "libraries", sec.symbols["libraries"])
+		}
+	}
+	for _, list := range []struct {
+		name string
+		syms map[string]map[string]map[string]bool
+	}{{"exports", exports}, {"reexports", reexports}} {
+		if len(list.syms) == 0 {
+			continue
+		}
+		bw.WriteString(list.name + ":\n")
+		for _, sec := range tbdGroup(targets, list.syms) {
+			tbdList(bw, "  - ", "targets", sec.targets)
+			for _, kind := range tbdSymbolKinds {
+				if names := sec.symbols[kind]; len(names) > 0 {
+					tbdList(bw, "    ", kind, names)
+				}
+			}
+		}
+	}
+	bw.WriteString("...\n")
+
+	return bw.Flush()
+}
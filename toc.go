@@ -159,19 +159,12 @@ func (t *FileTOC) MarshalJSON() ([]byte, error) {
 
 type loads []Load
 
-// LoadsString returns a string representation of all the MachO's load commands
+// String returns all the MachO's load commands in the `otool -l` format
 func (ls loads) String() string {
 	var loadsStr string
 	for i, l := range ls {
-		if sg, ok := l.(*Segment); ok {
-			loadsStr += fmt.Sprintf("%03d: %s\n", i, sg)
-			for _, sc := range sg.sections {
-				loadsStr += fmt.Sprintf("%s\n", sc)
-			}
-		} else {
-			if l != nil {
-				loadsStr += fmt.Sprintf("%03d: %-28s%s\n", i, l.Command(), l.String())
-			}
+		if l != nil {
+			loadsStr += fmt.Sprintf("Load command %d\n%s\n", i, l)
 		}
 	}
 	return loadsStr
@@ -3,6 +3,8 @@ package types
 //go:generate stringer -type=LoadCmd -output commands_string.go
 
 import (
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -425,6 +427,18 @@ type ThreadState struct {
 	Data   []byte       // thread state for this flavor
 }
 
+func (t ThreadState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Flavor uint32 `json:"flavor"`
+		Count  uint32 `json:"count"`
+		Data   string `json:"data,omitempty"`
+	}{
+		Flavor: uint32(t.Flavor),
+		Count:  t.Count,
+		Data:   hex.EncodeToString(t.Data),
+	})
+}
+
 /*
  * ThreadCmd contain machine-specific data structures suitable for
  * use in the thread state primitives.  The machine specific data structures
@@ -735,6 +749,7 @@ type DylibModule64 struct {
  */
 // isym:24, /* index into the symbol table */
 // flags:8; /* flags to indicate the type of reference */
+// (stored in the big-endian bitfield layout, i.e. isym<<8|flags)
 type DylibReference uint32
 
 func (d DylibReference) SymIndex() uint32 {
@@ -769,12 +784,24 @@ type TwolevelHintsCmd struct {
  * primary library.  The table of contents index is an index into the
  * library's table of contents.  This is used as the starting point of the
  * binary search or a directed linear search.
+ *
+ * A TwolevelHint holds the bitfields in their little endian layout (isub_image in the low byte).
  */
 type TwolevelHint uint32
 
+// NewTwolevelHint returns the hint for the raw twolevel_hint value read with the byte order o.
+// The isub_image:8/itoc:24 bitfields start at the high bits on big endian targets (i.e. PPC),
+// so those are swapped like cctools' swap_twolevel_hint
+func NewTwolevelHint(raw uint32, o binary.ByteOrder) TwolevelHint {
+	if o == binary.BigEndian {
+		return TwolevelHint(raw>>24 | (raw&0xffffff)<<8)
+	}
+	return TwolevelHint(raw)
+}
+
 // SubImageIndex index into the sub images
 func (t TwolevelHint) SubImageIndex() uint8 {
-	return uint8(t & 0xf)
+	return uint8(t & 0xff)
 }
 
 // TableOfContentsIndex index into the table of contents
@@ -1,33 +1,38 @@
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // A CPU is a Mach-O cpu type.
 type CPU uint32
 
 const (
 	cpuArchMask = 0xff000000 //  mask for architecture bits
-	cpuArch64   = 0x01000000 // 64 bit ABI
 	cpuArch6432 = 0x02000000 // ABI for 64-bit hardware with 32-bit types; LP32
 )
 
+// CPUArch64 is the 64 bit ABI bit of a CPU type (CPU_ARCH_ABI64)
+const CPUArch64 CPU = 0x01000000
+
 const (
 	CPUVax     CPU = 1
 	CPUMC680x0 CPU = 6
 	CPUX86     CPU = 7
 	CPUI386    CPU = CPUX86 /* compatibility */
-	CPUAmd64   CPU = CPUX86 | cpuArch64
+	CPUAmd64   CPU = CPUX86 | CPUArch64
 	CPUMips    CPU = 8
 	CPUMc98000 CPU = 10
 	CPUHppa    CPU = 11
 	CPUArm     CPU = 12
-	CPUArm64   CPU = CPUArm | cpuArch64
+	CPUArm64   CPU = CPUArm | CPUArch64
 	CPUArm6432 CPU = CPUArm | cpuArch6432
 	CPUMc88000 CPU = 13
 	CPUSparc   CPU = 14
 	CPUI860    CPU = 15
 	CPUPpc     CPU = 18
-	CPUPpc64   CPU = CPUPpc | cpuArch64
+	CPUPpc64   CPU = CPUPpc | CPUArch64
 )
 
 var cpuStrings = []IntName{
@@ -251,27 +256,68 @@ var cpuSubtypeArm64Strings = []IntName{
 	{uint32(CPUSubtypeArm6432V8), "v8"},
 }
 
+// Base returns the subtype without its capability bits
+func (st CPUSubtype) Base() CPUSubtype {
+	return st & CpuSubtypeMask
+}
+
+// Caps returns the capability bits (CPU_SUBTYPE_LIB64, the arm64e ptrauth ABI, ...) of the subtype
+func (st CPUSubtype) Caps() CPUSubtype {
+	return st & CpuSubtypeFeatureMask
+}
+
+// IsLib64 returns true if the CPU_SUBTYPE_LIB64 capability (a 64-bit executable) is set
+func (st CPUSubtype) IsLib64(cpu CPU) bool {
+	return !(cpu == CPUArm64 && st.Base() == CPUSubtypeArm64E) && st&CpuSubtypeLib64 != 0
+}
+
+// Capabilities returns the capability bits of the subtype the way otool prints them (i.e. LIB64 or USR00)
 func (st CPUSubtype) Capabilities(cpu CPU) string {
-	switch cpu {
-	case CPUArm64:
-		caps := st & CpuSubtypeFeatureMask
-		if caps > 0 {
-			if (st & CpuSubtypeMask) == CpuSubtypeLib64 { // lib64
-				return "LIB64"
-			} else if (st & CpuSubtypeMask) == CPUSubtypeArm64E { // arm64e
-				if (caps & CpuSubtypeArm64eKernelAbiMask) == 0 {
-					return fmt.Sprintf("USR%02d", ((caps & CpuSubtypeArm64ePtrAuthMask) >> 24))
-				}
-				return fmt.Sprintf("KER%02d", ((caps & CpuSubtypeArm64ePtrAuthMask) >> 24))
-			} else { // arm64 (v8/all)
-				if (caps & CpuSubtypeArm64eKernelAbiMask) == 0 {
-					return fmt.Sprintf("USR%02d", ((caps & CpuSubtypeArm64PtrAuthMask) >> 24))
-				}
-				return fmt.Sprintf("KER%02d", ((caps & CpuSubtypeArm64PtrAuthMask) >> 24))
-			}
+	caps := st.Caps()
+	if caps == 0 {
+		return ""
+	}
+	switch {
+	case cpu == CPUArm64 && st.Base() == CPUSubtypeArm64E:
+		if (caps & CpuSubtypeArm64eKernelAbiMask) == 0 {
+			return fmt.Sprintf("USR%02d", ((caps & CpuSubtypeArm64ePtrAuthMask) >> 24))
+		}
+		return fmt.Sprintf("KER%02d", ((caps & CpuSubtypeArm64ePtrAuthMask) >> 24))
+	case caps == CpuSubtypeLib64:
+		return "LIB64"
+	case cpu == CPUArm64: // arm64 (v8/all)
+		if (caps & CpuSubtypeArm64eKernelAbiMask) == 0 {
+			return fmt.Sprintf("USR%02d", ((caps & CpuSubtypeArm64PtrAuthMask) >> 24))
 		}
+		return fmt.Sprintf("KER%02d", ((caps & CpuSubtypeArm64PtrAuthMask) >> 24))
+	}
+	return fmt.Sprintf("0x%02x", uint32(caps)>>24)
+}
+
+// PtrAuthABIVersion returns the arm64e pointer authentication ABI version and whether it is the kernel ABI
+// (ok is false if the subtype isn't arm64e or predates the versioned ABI)
+func (st CPUSubtype) PtrAuthABIVersion(cpu CPU) (version uint8, kernel bool, ok bool) {
+	if cpu != CPUArm64 || st&CpuSubtypeMask != CPUSubtypeArm64E || st&CpuSubtypeArm64eVersionedAbiMask == 0 {
+		return 0, false, false
+	}
+	return uint8((st & CpuSubtypeArm64ePtrAuthMask) >> 24), st&CpuSubtypeArm64eKernelAbiMask != 0, true
+}
+
+// StripPAC removes the pointer authentication code (and top byte tag) of a signed arm64e pointer assuming
+// a 47-bit virtual address space (see StripPACBits)
+func StripPAC(ptr uint64) uint64 {
+	return StripPACBits(ptr, 47)
+}
+
+// StripPACBits removes the pointer authentication code of a signed pointer (like the XPAC instruction) by
+// sign extending bit 55, which selects between userland and kernel addresses, over the bits above the
+// vaBits-bit virtual address (i.e. 39 for iOS userland)
+func StripPACBits(ptr uint64, vaBits uint) uint64 {
+	mask := uint64(1)<<vaBits - 1
+	if ptr&(1<<55) != 0 {
+		return ptr | ^mask
 	}
-	return ""
+	return ptr & mask
 }
 
 func (st CPUSubtype) String(cpu CPU) string {
@@ -290,6 +336,61 @@ func (st CPUSubtype) String(cpu CPU) string {
 	return "UNKNOWN"
 }
 
+// ArchName returns the architecture name used by the Apple toolchain (i.e. x86_64, arm64e or armv7s)
+func (st CPUSubtype) ArchName(cpu CPU) string {
+	switch cpu {
+	case CPUI386:
+		return "i386"
+	case CPUAmd64:
+		if st&CpuSubtypeMask == CPUSubtypeX86_64H {
+			return "x86_64h"
+		}
+		return "x86_64"
+	case CPUArm:
+		switch st & CpuSubtypeMask {
+		case CPUSubtypeArmV4T:
+			return "armv4t"
+		case CPUSubtypeArmV5Tej:
+			return "armv5"
+		case CPUSubtypeArmXscale:
+			return "xscale"
+		case CPUSubtypeArmV6:
+			return "armv6"
+		case CPUSubtypeArmV6M:
+			return "armv6m"
+		case CPUSubtypeArmV7:
+			return "armv7"
+		case CPUSubtypeArmV7F:
+			return "armv7f"
+		case CPUSubtypeArmV7S:
+			return "armv7s"
+		case CPUSubtypeArmV7K:
+			return "armv7k"
+		case CPUSubtypeArmV7M:
+			return "armv7m"
+		case CPUSubtypeArmV7Em:
+			return "armv7em"
+		case CPUSubtypeArmV8:
+			return "armv8"
+		case CPUSubtypeArmV8M:
+			return "armv8m"
+		}
+		return "arm"
+	case CPUArm64:
+		if st&CpuSubtypeMask == CPUSubtypeArm64E {
+			return "arm64e"
+		}
+		return "arm64"
+	case CPUArm6432:
+		return "arm64_32"
+	case CPUPpc:
+		return "ppc"
+	case CPUPpc64:
+		return "ppc64"
+	}
+	return strings.ToLower(cpu.String())
+}
+
 func (st CPUSubtype) GoString(cpu CPU) string {
 	switch cpu {
 	case CPUI386:
@@ -117,6 +117,7 @@ type Bind struct {
 	Section string
 	Start   uint64
 	Offset  uint64
+	Ordinal int // library ordinal (or a BIND_SPECIAL_DYLIB_* ordinal)
 	Dylib   string
 	Value   uint64
 }
@@ -218,7 +219,7 @@ func (k SplitInfoKind) String() string {
 	case DYLD_CACHE_ADJ_V2_THREADED_POINTER_64:
 		return "threaded_pointer_64"
 	default:
-		return fmt.Sprintf("unknown kind %#02x", k)
+		return fmt.Sprintf("unknown kind %#02x", uint64(k))
 	}
 
 }
@@ -85,6 +85,57 @@ func (h *FileHeader) String() string {
 		h.Flags,
 	)
 }
+
+// Architecture returns the Apple architecture name of the image (i.e. arm64e, x86_64h or armv7k, see
+// CPUSubtype.ArchName)
+func (h *FileHeader) Architecture() string {
+	return h.SubCPU.ArchName(h.CPU)
+}
+
+// IsArm64e returns true if the image is arm64e (i.e. uses pointer authentication)
+func (h *FileHeader) IsArm64e() bool {
+	return h.CPU == CPUArm64 && h.SubCPU.Base() == CPUSubtypeArm64E
+}
+
+// PtrAuthABIVersion returns the arm64e pointer authentication ABI version of the image and whether it is the
+// kernel ABI (see CPUSubtype.PtrAuthABIVersion)
+func (h *FileHeader) PtrAuthABIVersion() (version uint8, kernel bool, ok bool) {
+	return h.SubCPU.PtrAuthABIVersion(h.CPU)
+}
+
+// IsPIE returns true if the image is a position independent executable (MH_PIE)
+func (h *FileHeader) IsPIE() bool { return h.Flags.PIE() }
+
+// IsTwoLevel returns true if the image uses the two-level namespace bindings (MH_TWOLEVEL)
+func (h *FileHeader) IsTwoLevel() bool { return h.Flags.TwoLevel() }
+
+// AllowsStackExecution returns true if the stacks of the image are executable (MH_ALLOW_STACK_EXECUTION)
+func (h *FileHeader) AllowsStackExecution() bool { return h.Flags.AllowStackExecution() }
+
+// HasNoHeapExecution returns true if the heap of the image isn't executable (MH_NO_HEAP_EXECUTION)
+func (h *FileHeader) HasNoHeapExecution() bool { return h.Flags.NoHeapExecution() }
+
+// HasNoReexportedDylibs returns true if the dylib doesn't re-export any dylib (MH_NO_REEXPORTED_DYLIBS)
+func (h *FileHeader) HasNoReexportedDylibs() bool { return h.Flags.NoReexportedDylibs() }
+
+// IsAppExtensionSafe returns true if the dylib is safe to use in app extensions (MH_APP_EXTENSION_SAFE)
+func (h *FileHeader) IsAppExtensionSafe() bool { return h.Flags.AppExtensionSafe() }
+
+// IsDylibInCache returns true if the dylib is part of a dyld shared cache (MH_DYLIB_IN_CACHE)
+func (h *FileHeader) IsDylibInCache() bool { return h.Flags.DylibInCache() }
+
+// SetFlag sets (or clears) the header flag flag (written out by File.WriteTo and File.Save)
+func (h *FileHeader) SetFlag(flag HeaderFlag, set bool) { h.Flags.Set(flag, set) }
+
+// SetPIE sets (or clears) MH_PIE
+func (h *FileHeader) SetPIE(set bool) { h.SetFlag(PIE, set) }
+
+// SetAllowStackExecution sets (or clears) MH_ALLOW_STACK_EXECUTION
+func (h *FileHeader) SetAllowStackExecution(set bool) { h.SetFlag(AllowStackExecution, set) }
+
+// SetNoHeapExecution sets (or clears) MH_NO_HEAP_EXECUTION
+func (h *FileHeader) SetNoHeapExecution(set bool) { h.SetFlag(NoHeapExecution, set) }
+
 func (h *FileHeader) Print(printer func(h *FileHeader) string) string {
 	return printer(h)
 }
@@ -264,7 +315,7 @@ func (f *HeaderFlag) Set(flag HeaderFlag, set bool) {
 	if set {
 		*f = (*f | flag)
 	} else {
-		*f = (*f ^ flag)
+		*f = (*f &^ flag)
 	}
 }
 
@@ -164,10 +164,25 @@ func (f ImageInfoFlag) String() string {
 	)
 }
 
+// SwiftABIVersion returns the Swift ABI version the image was compiled with (0 if it doesn't contain Swift)
+func (f ImageInfoFlag) SwiftABIVersion() uint8 {
+	return uint8((f & SwiftUnstableVersionMask) >> SwiftUnstableVersionMaskShift)
+}
+
+// SwiftCompilerVersion returns the major and minor version of the Swift compiler that built the image
+// (only set by Swift 5.0 and later compilers)
+func (f ImageInfoFlag) SwiftCompilerVersion() (major, minor uint8) {
+	v := uint16((f & SwiftStableVersionMask) >> SwiftStableVersionMaskShift)
+	return uint8(v >> 8), uint8(v)
+}
+
+// HasSwiftStableABI returns true if the image was compiled with the stable Swift ABI (Swift 5 or later)
+func (f ImageInfoFlag) HasSwiftStableABI() bool {
+	return f.SwiftABIVersion() >= 7
+}
+
 func (f ImageInfoFlag) SwiftVersion() string {
-	// TODO: I noticed there is some flags higher than swift version
-	// (Console has 84019008, which is a version of 0x502)
-	swiftVersion := (f >> 8) & 0xff
+	swiftVersion := f.SwiftABIVersion()
 	if swiftVersion != 0 {
 		switch swiftVersion {
 		case 1:
@@ -183,8 +198,14 @@ func (f ImageInfoFlag) SwiftVersion() string {
 		case 6:
 			return "Swift 4.1/4.2"
 		case 7:
+			if major, minor := f.SwiftCompilerVersion(); major != 0 {
+				return fmt.Sprintf("Swift %d.%d", major, minor)
+			}
 			return "Swift 5 or later"
 		default:
+			if major, minor := f.SwiftCompilerVersion(); major != 0 {
+				return fmt.Sprintf("Swift %d.%d (ABI version %d)", major, minor, swiftVersion)
+			}
 			return fmt.Sprintf("Unknown future Swift version: %d", swiftVersion)
 		}
 	}
@@ -203,7 +224,7 @@ func (i ImageInfo) IsDyldPreoptimized() bool {
 }
 
 func (i ImageInfo) HasSwift() bool {
-	return (i.Flags>>8)&0xff != 0
+	return i.Flags.SwiftABIVersion() != 0
 }
 
 const (
@@ -400,6 +421,21 @@ type CFString64Type struct {
 	Length    uint64 // number of non-NULL characters in above
 }
 
+// CFString32Type object in a 32-bit MachO file
+type CFString32Type struct {
+	IsaVMAddr uint32 // class_t * (32-bit pointer)
+	Info      uint32 // flag bits
+	Data      uint32 // char * (32-bit pointer)
+	Length    uint32 // number of non-NULL characters in above
+}
+
+const cfStringIsUnicode = 0x10 // __kCFIsUnicode info bit (the characters are UTF-16 in __ustring)
+
+// IsUnicode returns true if the CFString's characters are UTF-16 (from the __ustring section)
+func (c CFString64Type) IsUnicode() bool {
+	return c.Info&cfStringIsUnicode != 0
+}
+
 const (
 	FAST_IS_SWIFT_LEGACY = 1 << 0 // < 5
 	FAST_IS_SWIFT_STABLE = 1 << 1 // 5.X
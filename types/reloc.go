@@ -4,6 +4,8 @@
 
 package types
 
+import "fmt"
+
 //go:generate stringer -type=RelocTypeGeneric,RelocTypeX86_64,RelocTypeARM,RelocTypeARM64 -output reloc_string.go
 
 type RelocTypeGeneric int
@@ -56,17 +58,33 @@ func (r RelocTypeARM) GoString() string { return "macho." + r.String() }
 type RelocTypeARM64 int
 
 const (
-	ARM64_RELOC_UNSIGNED            RelocTypeARM64 = 0
-	ARM64_RELOC_SUBTRACTOR          RelocTypeARM64 = 1
-	ARM64_RELOC_BRANCH26            RelocTypeARM64 = 2
-	ARM64_RELOC_PAGE21              RelocTypeARM64 = 3
-	ARM64_RELOC_PAGEOFF12           RelocTypeARM64 = 4
-	ARM64_RELOC_GOT_LOAD_PAGE21     RelocTypeARM64 = 5
-	ARM64_RELOC_GOT_LOAD_PAGEOFF12  RelocTypeARM64 = 6
-	ARM64_RELOC_POINTER_TO_GOT      RelocTypeARM64 = 7
-	ARM64_RELOC_TLVP_LOAD_PAGE21    RelocTypeARM64 = 8
-	ARM64_RELOC_TLVP_LOAD_PAGEOFF12 RelocTypeARM64 = 9
-	ARM64_RELOC_ADDEND              RelocTypeARM64 = 10
+	ARM64_RELOC_UNSIGNED              RelocTypeARM64 = 0
+	ARM64_RELOC_SUBTRACTOR            RelocTypeARM64 = 1
+	ARM64_RELOC_BRANCH26              RelocTypeARM64 = 2
+	ARM64_RELOC_PAGE21                RelocTypeARM64 = 3
+	ARM64_RELOC_PAGEOFF12             RelocTypeARM64 = 4
+	ARM64_RELOC_GOT_LOAD_PAGE21       RelocTypeARM64 = 5
+	ARM64_RELOC_GOT_LOAD_PAGEOFF12    RelocTypeARM64 = 6
+	ARM64_RELOC_POINTER_TO_GOT        RelocTypeARM64 = 7
+	ARM64_RELOC_TLVP_LOAD_PAGE21      RelocTypeARM64 = 8
+	ARM64_RELOC_TLVP_LOAD_PAGEOFF12   RelocTypeARM64 = 9
+	ARM64_RELOC_ADDEND                RelocTypeARM64 = 10
+	ARM64_RELOC_AUTHENTICATED_POINTER RelocTypeARM64 = 11
 )
 
 func (r RelocTypeARM64) GoString() string { return "macho." + r.String() }
+
+// RelocType returns the relocation type typ of the CPU cpu as a RelocTypeX86_64, RelocTypeARM, RelocTypeARM64
+// or RelocTypeGeneric (for i386 and the other CPUs)
+func RelocType(cpu CPU, typ uint8) fmt.Stringer {
+	switch cpu {
+	case CPUAmd64:
+		return RelocTypeX86_64(typ)
+	case CPUArm:
+		return RelocTypeARM(typ)
+	case CPUArm64, CPUArm6432:
+		return RelocTypeARM64(typ)
+	default:
+		return RelocTypeGeneric(typ)
+	}
+}
@@ -93,11 +93,12 @@ func _() {
 	_ = x[ARM64_RELOC_TLVP_LOAD_PAGE21-8]
 	_ = x[ARM64_RELOC_TLVP_LOAD_PAGEOFF12-9]
 	_ = x[ARM64_RELOC_ADDEND-10]
+	_ = x[ARM64_RELOC_AUTHENTICATED_POINTER-11]
 }
 
-const _RelocTypeARM64_name = "ARM64_RELOC_UNSIGNEDARM64_RELOC_SUBTRACTORARM64_RELOC_BRANCH26ARM64_RELOC_PAGE21ARM64_RELOC_PAGEOFF12ARM64_RELOC_GOT_LOAD_PAGE21ARM64_RELOC_GOT_LOAD_PAGEOFF12ARM64_RELOC_POINTER_TO_GOTARM64_RELOC_TLVP_LOAD_PAGE21ARM64_RELOC_TLVP_LOAD_PAGEOFF12ARM64_RELOC_ADDEND"
+const _RelocTypeARM64_name = "ARM64_RELOC_UNSIGNEDARM64_RELOC_SUBTRACTORARM64_RELOC_BRANCH26ARM64_RELOC_PAGE21ARM64_RELOC_PAGEOFF12ARM64_RELOC_GOT_LOAD_PAGE21ARM64_RELOC_GOT_LOAD_PAGEOFF12ARM64_RELOC_POINTER_TO_GOTARM64_RELOC_TLVP_LOAD_PAGE21ARM64_RELOC_TLVP_LOAD_PAGEOFF12ARM64_RELOC_ADDENDARM64_RELOC_AUTHENTICATED_POINTER"
 
-var _RelocTypeARM64_index = [...]uint16{0, 20, 42, 62, 80, 101, 128, 158, 184, 212, 243, 261}
+var _RelocTypeARM64_index = [...]uint16{0, 20, 42, 62, 80, 101, 128, 158, 184, 212, 243, 261, 294}
 
 func (i RelocTypeARM64) String() string {
 	if i < 0 || i >= RelocTypeARM64(len(_RelocTypeARM64_index)-1) {
@@ -126,6 +126,16 @@ const (
 	InitFuncOffsets                 SectionFlag = 0x16 /* 32-bit offsets to initializers */
 )
 
+// Type returns the section type (S_REGULAR, S_ZEROFILL, ...) part of the flags
+func (t SectionFlag) Type() SectionFlag {
+	return t & SectionType
+}
+
+// Attrs returns the section attributes (S_ATTR_*) part of the flags
+func (t SectionFlag) Attrs() SectionFlag {
+	return t & SectionAttributes
+}
+
 func (t SectionFlag) IsRegular() bool {
 	return (t & SectionType) == Regular
 }
@@ -425,6 +435,53 @@ type SectionHeader struct {
 	Type      uint8
 }
 
+// SectionType returns the section type (S_REGULAR, S_ZEROFILL, ...) of the section's flags
+func (s *SectionHeader) SectionType() SectionFlag {
+	return s.Flags.Type()
+}
+
+// Attributes returns the section attributes (S_ATTR_*) of the section's flags
+func (s *SectionHeader) Attributes() SectionFlag {
+	return s.Flags.Attrs()
+}
+
+// IsZerofill returns true if the section has no data in the file (S_ZEROFILL, S_GB_ZEROFILL or
+// S_THREAD_LOCAL_ZEROFILL)
+func (s *SectionHeader) IsZerofill() bool {
+	return s.Flags.IsZerofill() || s.Flags.IsGbZerofill() || s.Flags.IsThreadLocalZerofill()
+}
+
+// IsCstringLiterals returns true if the section only contains C strings (S_CSTRING_LITERALS)
+func (s *SectionHeader) IsCstringLiterals() bool {
+	return s.Flags.IsCstringLiterals()
+}
+
+// IsSymbolStubs returns true if the section only contains symbol stubs (S_SYMBOL_STUBS) of Reserved2 bytes
+func (s *SectionHeader) IsSymbolStubs() bool {
+	return s.Flags.IsSymbolStubs()
+}
+
+// IsSymbolPointers returns true if the section only contains (lazy or non-lazy) symbol pointers
+func (s *SectionHeader) IsSymbolPointers() bool {
+	return s.Flags.IsNonLazySymbolPointers() || s.Flags.IsLazySymbolPointers() || s.Flags.IsLazyDylibSymbolPointers()
+}
+
+// IsPureInstructions returns true if the section only contains machine instructions (S_ATTR_PURE_INSTRUCTIONS)
+func (s *SectionHeader) IsPureInstructions() bool {
+	return s.Flags.IsPureInstructions()
+}
+
+// HasInstructions returns true if the section contains machine instructions (S_ATTR_PURE_INSTRUCTIONS or
+// S_ATTR_SOME_INSTRUCTIONS)
+func (s *SectionHeader) HasInstructions() bool {
+	return s.Flags.IsPureInstructions() || s.Flags.IsSomeInstructions()
+}
+
+// IsDebug returns true if the section contains debug info (S_ATTR_DEBUG)
+func (s *SectionHeader) IsDebug() bool {
+	return s.Flags.IsDebug()
+}
+
 // A Reloc represents a Mach-O relocation.
 type Reloc struct {
 	Addr  uint32
@@ -439,6 +496,11 @@ type Reloc struct {
 	Scattered bool
 }
 
+// TypeString returns the name of the relocation type for the CPU cpu (i.e. X86_64_RELOC_BRANCH)
+func (r Reloc) TypeString(cpu CPU) string {
+	return RelocType(cpu, r.Type).String()
+}
+
 func (r *Reloc) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Addr      uint32 `json:"addr"`
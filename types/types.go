@@ -4,10 +4,13 @@ package types
 
 import (
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math/bits"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -56,11 +59,37 @@ func (u UUID) IsNull() bool {
 	return u == [16]byte{0}
 }
 
+// Equal returns true if u and o are the same UUID
+func (u UUID) Equal(o UUID) bool {
+	return u == o
+}
+
+// Bytes returns the raw bytes of the UUID
+func (u UUID) Bytes() []byte {
+	b := make([]byte, len(u))
+	copy(b, u[:])
+	return b
+}
+
 func (u UUID) String() string {
 	return fmt.Sprintf("%02X%02X%02X%02X-%02X%02X-%02X%02X-%02X%02X-%02X%02X%02X%02X%02X%02X",
 		u[0], u[1], u[2], u[3], u[4], u[5], u[6], u[7], u[8], u[9], u[10], u[11], u[12], u[13], u[14], u[15])
 }
 
+// ParseUUID parses the UUID s in either case with or without the dashes (i.e. as printed by dwarfdump --uuid,
+// in crash reports or in breakpad symbol files without the trailing age)
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	h := strings.ReplaceAll(s, "-", "")
+	if len(h) != 2*len(u) {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(h)); err != nil {
+		return u, fmt.Errorf("invalid UUID %q: %v", s, err)
+	}
+	return u, nil
+}
+
 // Platform is a macho platform object
 type Platform uint32
 
@@ -92,6 +121,78 @@ const (
 	ANY Platform = 0xFFFFFFFF // PLATFORM_ANY
 )
 
+// IsSimulator returns true if the platform is one of the simulators
+func (p Platform) IsSimulator() bool {
+	switch p {
+	case iOsSimulator, tvOsSimulator, watchOsSimulator, visionOsSimulator:
+		return true
+	}
+	return false
+}
+
+// IsMacCatalyst returns true if the platform is Mac Catalyst (iOS apps on macOS)
+func (p Platform) IsMacCatalyst() bool { return p == macCatalyst }
+
+// IsDriverKit returns true if the platform is DriverKit
+func (p Platform) IsDriverKit() bool { return p == Driverkit }
+
+// VersionMinPlatform returns the platform of the legacy LC_VERSION_MIN_* load command cmd (or Unknown). They
+// don't have simulator variants so the simulator platform is returned if simulator is set (i.e. for x86 images).
+func VersionMinPlatform(cmd LoadCmd, simulator bool) Platform {
+	switch cmd {
+	case LC_VERSION_MIN_MACOSX:
+		return macOS
+	case LC_VERSION_MIN_IPHONEOS:
+		if simulator {
+			return iOsSimulator
+		}
+		return iOS
+	case LC_VERSION_MIN_TVOS:
+		if simulator {
+			return tvOsSimulator
+		}
+		return tvOS
+	case LC_VERSION_MIN_WATCHOS:
+		if simulator {
+			return watchOsSimulator
+		}
+		return watchOS
+	}
+	return Unknown
+}
+
+// TBDName returns the platform name used in TAPI text-based dylib stub (.tbd) targets
+func (p Platform) TBDName() string {
+	switch p {
+	case macOS:
+		return "macos"
+	case iOS:
+		return "ios"
+	case tvOS:
+		return "tvos"
+	case watchOS:
+		return "watchos"
+	case bridgeOS:
+		return "bridgeos"
+	case macCatalyst:
+		return "maccatalyst"
+	case iOsSimulator:
+		return "ios-simulator"
+	case tvOsSimulator:
+		return "tvos-simulator"
+	case watchOsSimulator:
+		return "watchos-simulator"
+	case Driverkit:
+		return "driverkit"
+	case visionOS:
+		return "xros"
+	case visionOsSimulator:
+		return "xros-simulator"
+	default:
+		return "unknown"
+	}
+}
+
 func GetPlatformByName(name string) (Platform, error) {
 	switch strings.ToLower(name) {
 	case "macos":
@@ -177,15 +278,75 @@ func (v *Version) Set(version string) error {
 	return nil
 }
 
+// SrcVersion is a source version packed as A.B.C.D.E (a24.b10.c10.d10.e10), so versions compare like
+// integers
 type SrcVersion uint64
 
+// SrcVersionComponents are the components of a SrcVersion
+type SrcVersionComponents struct {
+	A uint32 // 24 bits
+	B uint16 // 10 bits
+	C uint16 // 10 bits
+	D uint16 // 10 bits
+	E uint16 // 10 bits
+}
+
+// Pack returns the SrcVersion of the components (an error if one doesn't fit its bits)
+func (c SrcVersionComponents) Pack() (SrcVersion, error) {
+	if c.A >= 1<<24 || c.B >= 1<<10 || c.C >= 1<<10 || c.D >= 1<<10 || c.E >= 1<<10 {
+		return 0, fmt.Errorf("source version %d.%d.%d.%d.%d is out of range", c.A, c.B, c.C, c.D, c.E)
+	}
+	return SrcVersion(uint64(c.A)<<40 | uint64(c.B)<<30 | uint64(c.C)<<20 | uint64(c.D)<<10 | uint64(c.E)), nil
+}
+
+// Components returns the A.B.C.D.E components of the version
+func (sv SrcVersion) Components() SrcVersionComponents {
+	return SrcVersionComponents{
+		A: uint32(sv >> 40),
+		B: uint16((sv >> 30) & 0x3ff),
+		C: uint16((sv >> 20) & 0x3ff),
+		D: uint16((sv >> 10) & 0x3ff),
+		E: uint16(sv & 0x3ff),
+	}
+}
+
+// Compare returns -1, 0 or 1 if sv is older than, the same as or newer than o
+func (sv SrcVersion) Compare(o SrcVersion) int {
+	switch {
+	case sv < o:
+		return -1
+	case sv > o:
+		return 1
+	}
+	return 0
+}
+
 func (sv SrcVersion) String() string {
-	a := sv >> 40
-	b := (sv >> 30) & 0x3ff
-	c := (sv >> 20) & 0x3ff
-	d := (sv >> 10) & 0x3ff
-	e := sv & 0x3ff
-	return fmt.Sprintf("%d.%d.%d.%d.%d", a, b, c, d, e)
+	c := sv.Components()
+	return fmt.Sprintf("%d.%d.%d.%d.%d", c.A, c.B, c.C, c.D, c.E)
+}
+
+// Set parses the source version (A[.B[.C[.D[.E]]]] with the missing components 0)
+func (sv *SrcVersion) Set(version string) error {
+	parts := strings.Split(version, ".")
+	if len(parts) > 5 {
+		return errors.New("invalid source version")
+	}
+	var nums [5]uint64
+	for i, part := range parts {
+		bitSize := 10
+		if i == 0 {
+			bitSize = 24
+		}
+		n, err := strconv.ParseUint(part, 10, bitSize)
+		if err != nil {
+			return fmt.Errorf("invalid source version %s: %v", version, err)
+		}
+		nums[i] = n
+	}
+	v, _ := SrcVersionComponents{A: uint32(nums[0]), B: uint16(nums[1]), C: uint16(nums[2]), D: uint16(nums[3]), E: uint16(nums[4])}.Pack()
+	*sv = v
+	return nil
 }
 
 type Tool uint32
@@ -270,6 +431,108 @@ type Function struct {
 	Name      string
 	StartAddr uint64
 	EndAddr   uint64
+	Thumb     bool // 32-bit ARM Thumb code (the start address has the low bit cleared)
+}
+
+// FunctionSource is the set of sources a recovered function was found in
+type FunctionSource uint8
+
+const (
+	FunctionSourceFunctionStarts FunctionSource = 1 << iota // LC_FUNCTION_STARTS
+	FunctionSourceSymtab                                    // symbol table
+	FunctionSourceExports                                   // export trie
+	FunctionSourceCompactUnwind                             // __TEXT,__unwind_info
+	FunctionSourceEHFrame                                   // __TEXT,__eh_frame FDE
+)
+
+func (s FunctionSource) String() string {
+	var srcs []string
+	for _, src := range []struct {
+		flag FunctionSource
+		name string
+	}{
+		{FunctionSourceFunctionStarts, "function_starts"},
+		{FunctionSourceSymtab, "symtab"},
+		{FunctionSourceExports, "exports"},
+		{FunctionSourceCompactUnwind, "unwind_info"},
+		{FunctionSourceEHFrame, "eh_frame"},
+	} {
+		if s&src.flag != 0 {
+			srcs = append(srcs, src.name)
+		}
+	}
+	return strings.Join(srcs, "|")
+}
+
+// RecoveredFunction is a function found by merging the function boundary sources of a MachO
+type RecoveredFunction struct {
+	Function
+	Sources FunctionSource
+}
+
+// Confidence returns the number of sources the function was found in
+func (f RecoveredFunction) Confidence() int {
+	return bits.OnesCount8(uint8(f.Sources))
+}
+
+// IndirectSymbol is an indirect symbol table entry (the symbol of a symbol stub or pointer)
+type IndirectSymbol struct {
+	Address uint64 // address of the stub or pointer
+	Section string
+	Index   uint32 // index into the symbol table (or INDIRECT_SYMBOL_LOCAL and/or INDIRECT_SYMBOL_ABS)
+	Name    string
+}
+
+// IsLocal returns true if the stub or pointer is for a local (stripped) symbol
+func (s IndirectSymbol) IsLocal() bool {
+	return s.Index&INDIRECT_SYMBOL_LOCAL != 0
+}
+
+// IsAbsolute returns true if the stub or pointer is for an absolute symbol
+func (s IndirectSymbol) IsAbsolute() bool {
+	return s.Index&INDIRECT_SYMBOL_ABS != 0
+}
+
+// SymbolPointer is a non-lazy or lazy symbol pointer section slot (i.e. a __got or __la_symbol_ptr entry)
+type SymbolPointer struct {
+	Address uint64 // address of the slot
+	Section string
+	Name    string // name of the symbol the slot is bound to ("" for INDIRECT_SYMBOL_LOCAL slots)
+	Dylib   string // dylib the symbol is imported from
+	Lazy    bool
+}
+
+// Stub is a symbol stub (i.e. __stubs or __auth_stubs) or lazy binding stub helper entry
+type Stub struct {
+	Address uint64
+	Size    uint64
+	Section string
+	Name    string // name of the symbol the stub ultimately calls
+	Dylib   string // dylib the symbol is imported from
+}
+
+// Interpose is an __interpose section entry (dyld replaces calls to Replacee with calls to Replacement)
+type Interpose struct {
+	Address     uint64 // address of the entry
+	Replacement uint64
+	Replacee    uint64 // (0 when it is bound to an imported symbol)
+	Name        string // name of the replacee symbol
+	Dylib       string // dylib the replacee is imported from
+}
+
+// SourceFrame is a source location of a symbolicated address (see File.SourceFrames)
+type SourceFrame struct {
+	Function string
+	File     string
+	Line     int
+	Inlined  bool // the function was inlined into the next (outer) frame
+}
+
+func (f SourceFrame) String() string {
+	if f.Inlined {
+		return fmt.Sprintf("%s (%s:%d) [inlined]", f.Function, filepath.Base(f.File), f.Line)
+	}
+	return fmt.Sprintf("%s (%s:%d)", f.Function, filepath.Base(f.File), f.Line)
 }
 
 /*
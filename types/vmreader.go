@@ -0,0 +1,173 @@
+package types
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxCStringLen is the maximum length of a c-string read by ReadCString (guards against reading
+// runaway strings from corrupt or malicious binaries)
+const MaxCStringLen = 0x10000
+
+// ReadCString reads a NUL-terminated string of at most MaxCStringLen bytes from r
+func ReadCString(r io.Reader) (string, error) {
+	s, err := bufio.NewReader(io.LimitReader(r, MaxCStringLen+1)).ReadString('\x00')
+	if err != nil {
+		if err == io.EOF && len(s) > MaxCStringLen {
+			return "", fmt.Errorf("string is longer than %d bytes", MaxCStringLen)
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(s, "\x00"), nil
+}
+
+// VMReader reads the virtual memory of a MachO (backed by its file, by memory it is mapped in or by a
+// remote/attached process)
+type VMReader interface {
+	// ReadAtVMAddr reads len(buf) bytes at the virtual address addr
+	ReadAtVMAddr(buf []byte, addr uint64) (int, error)
+	// ReadPointer reads the (raw) pointer at the virtual address addr
+	ReadPointer(addr uint64) (uint64, error)
+	// ReadCString reads the NUL-terminated string at the virtual address addr
+	ReadCString(addr uint64) (string, error)
+}
+
+// vmReaderAt is an io.ReaderAt of the virtual addresses of a VMReader
+type vmReaderAt func(buf []byte, addr uint64) (int, error)
+
+func (r vmReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return r(p, uint64(off))
+}
+
+func readPointer(read vmReaderAt, addr uint64, bo binary.ByteOrder, size int) (uint64, error) {
+	var buf [8]byte
+	if _, err := read(buf[:size], addr); err != nil {
+		return 0, fmt.Errorf("failed to read pointer at %#x: %v", addr, err)
+	}
+	if size == 4 {
+		return uint64(bo.Uint32(buf[:])), nil
+	}
+	return bo.Uint64(buf[:]), nil
+}
+
+func readCString(read vmReaderAt, addr uint64) (string, error) {
+	s, err := ReadCString(io.NewSectionReader(read, int64(addr), MaxCStringLen+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read string at %#x: %v", addr, err)
+	}
+	return s, nil
+}
+
+// FileVMReader is a VMReader backed by a MachO file (through the address translation of its MachoReader)
+type FileVMReader struct {
+	r           MachoReader
+	bo          binary.ByteOrder
+	pointerSize int
+}
+
+// NewFileVMReader returns a FileVMReader reading through r the pointers of pointerSize bytes in byte order bo
+func NewFileVMReader(r MachoReader, bo binary.ByteOrder, pointerSize int) *FileVMReader {
+	return &FileVMReader{r: r, bo: bo, pointerSize: pointerSize}
+}
+
+func (r *FileVMReader) ReadAtVMAddr(buf []byte, addr uint64) (int, error) {
+	return r.r.ReadAtAddr(buf, addr)
+}
+
+func (r *FileVMReader) ReadPointer(addr uint64) (uint64, error) {
+	return readPointer(r.ReadAtVMAddr, addr, r.bo, r.pointerSize)
+}
+
+func (r *FileVMReader) ReadCString(addr uint64) (string, error) {
+	return readCString(r.ReadAtVMAddr, addr)
+}
+
+// MemoryVMReader is a VMReader backed by memory (e.g. a dump of a mapped image or the address space of a
+// process) where the virtual address Base is at position 0 of the underlying ReaderAt
+type MemoryVMReader struct {
+	Base uint64
+
+	r           io.ReaderAt
+	bo          binary.ByteOrder
+	pointerSize int
+}
+
+// NewMemoryVMReader returns a MemoryVMReader of r (with the address base at its position 0) reading the pointers
+// of pointerSize bytes in byte order bo
+func NewMemoryVMReader(r io.ReaderAt, base uint64, bo binary.ByteOrder, pointerSize int) *MemoryVMReader {
+	return &MemoryVMReader{Base: base, r: r, bo: bo, pointerSize: pointerSize}
+}
+
+func (r *MemoryVMReader) ReadAtVMAddr(buf []byte, addr uint64) (int, error) {
+	if addr < r.Base {
+		return 0, fmt.Errorf("address %#x is below the base address %#x", addr, r.Base)
+	}
+	return r.r.ReadAt(buf, int64(addr-r.Base))
+}
+
+func (r *MemoryVMReader) ReadPointer(addr uint64) (uint64, error) {
+	return readPointer(r.ReadAtVMAddr, addr, r.bo, r.pointerSize)
+}
+
+func (r *MemoryVMReader) ReadCString(addr uint64) (string, error) {
+	return readCString(r.ReadAtVMAddr, addr)
+}
+
+// VMMachoReader is a MachoReader of a VMReader: its reads of file offsets are translated into virtual
+// addresses with a VMAddrConverter and its position is a virtual address
+type VMMachoReader struct {
+	vmr  VMReader
+	vma  *VMAddrConverter
+	addr uint64
+}
+
+// NewVMMachoReader returns a VMMachoReader reading from vmr (translating file offsets with vma)
+func NewVMMachoReader(vmr VMReader, vma *VMAddrConverter) *VMMachoReader {
+	return &VMMachoReader{vmr: vmr, vma: vma}
+}
+
+func (r *VMMachoReader) Read(p []byte) (int, error) {
+	n, err := r.vmr.ReadAtVMAddr(p, r.addr)
+	r.addr += uint64(n)
+	return n, err
+}
+
+func (r *VMMachoReader) ReadAt(p []byte, off int64) (int, error) {
+	addr, err := r.vma.GetVMAddress(uint64(off))
+	if err != nil {
+		return 0, err
+	}
+	return r.vmr.ReadAtVMAddr(p, addr)
+}
+
+func (r *VMMachoReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		cur, err := r.vma.GetOffset(r.addr)
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(cur)
+	default:
+		return 0, fmt.Errorf("Seek: unsupported whence %d", whence)
+	}
+	addr, err := r.vma.GetVMAddress(uint64(offset))
+	if err != nil {
+		return 0, err
+	}
+	r.addr = addr
+	return offset, nil
+}
+
+func (r *VMMachoReader) SeekToAddr(addr uint64) error {
+	r.addr = addr
+	return nil
+}
+
+func (r *VMMachoReader) ReadAtAddr(buf []byte, addr uint64) (int, error) {
+	return r.vmr.ReadAtVMAddr(buf, addr)
+}